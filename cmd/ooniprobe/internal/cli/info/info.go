@@ -5,6 +5,9 @@ import (
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 )
 
 func init() {
@@ -15,13 +18,24 @@ func init() {
 }
 
 type doinfoconfig struct {
-	Logger      log.Interface
-	NewProbeCLI func() (ooni.ProbeCLI, error)
+	LoadStatsReport func(home string) (*enginenetx.StatsReport, error)
+	Logger          log.Interface
+	NewProbeCLI     func() (ooni.ProbeCLI, error)
 }
 
 var defaultconfig = doinfoconfig{
-	Logger:      log.Log,
-	NewProbeCLI: root.NewProbeCLI,
+	LoadStatsReport: loadStatsReport,
+	Logger:          log.Log,
+	NewProbeCLI:     root.NewProbeCLI,
+}
+
+// loadStatsReport loads the stats about the tactics used to reach the OONI backend.
+func loadStatsReport(home string) (*enginenetx.StatsReport, error) {
+	kvStore, err := kvstore.NewFS(utils.EngineDir(home))
+	if err != nil {
+		return nil, err
+	}
+	return enginenetx.LoadStatsReport(kvStore)
 }
 
 func doinfo(config doinfoconfig) error {
@@ -32,5 +46,23 @@ func doinfo(config doinfoconfig) error {
 	}
 	config.Logger.WithFields(log.Fields{"path": probeCLI.Home()}).Info("Home")
 	config.Logger.WithFields(log.Fields{"path": probeCLI.TempDir()}).Info("TempDir")
+
+	// the stats do not exist until we have communicated with the OONI backend
+	// hence we do not consider failing to load them an error
+	report, err := config.LoadStatsReport(probeCLI.Home())
+	if err != nil {
+		config.Logger.Debugf("cannot load network stats: %s", err)
+		return nil
+	}
+	for _, epnt := range report.DomainEndpoints {
+		for _, tactic := range epnt.Tactics {
+			config.Logger.WithFields(log.Fields{
+				"domain_endpoint": epnt.DomainEndpoint,
+				"last_updated":    tactic.LastUpdated,
+				"success_rate":    tactic.SuccessRate,
+				"tactic":          tactic.Summary,
+			}).Info("NetworkStats")
+		}
+	}
 	return nil
 }
@@ -3,10 +3,12 @@ package info
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
 )
 
 func TestNewProbeCLIFailed(t *testing.T) {
@@ -42,7 +44,26 @@ func TestSuccess(t *testing.T) {
 		FakeHome:    "fakehome",
 		FakeTempDir: "faketempdir",
 	}
+	lastUpdated := time.Date(2024, 4, 16, 10, 0, 0, 0, time.UTC)
 	err := doinfo(doinfoconfig{
+		LoadStatsReport: func(home string) (*enginenetx.StatsReport, error) {
+			if home != "fakehome" {
+				t.Fatal("invalid home")
+			}
+			report := &enginenetx.StatsReport{
+				DomainEndpoints: []*enginenetx.StatsReportDomainEndpoint{{
+					DomainEndpoint: "api.ooni.io:443",
+					Tactics: []*enginenetx.StatsReportTactic{{
+						Summary:      "162.55.247.208:443 sni=www.example.com verify=api.ooni.io",
+						CountStarted: 4,
+						CountSuccess: 1,
+						SuccessRate:  0.25,
+						LastUpdated:  lastUpdated,
+					}},
+				}},
+			}
+			return report, nil
+		},
 		NewProbeCLI: func() (ooni.ProbeCLI, error) {
 			return cli, nil
 		},
@@ -54,7 +75,7 @@ func TestSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(handler.FakeEntries) != 2 {
+	if len(handler.FakeEntries) != 3 {
 		t.Fatal("invalid number of log entries")
 	}
 	entry := handler.FakeEntries[0]
@@ -77,4 +98,56 @@ func TestSuccess(t *testing.T) {
 	if entry.Fields["path"].(string) != "faketempdir" {
 		t.Fatal("invalid path")
 	}
+	entry = handler.FakeEntries[2]
+	if entry.Level != log.InfoLevel {
+		t.Fatal("invalid log level")
+	}
+	if entry.Message != "NetworkStats" {
+		t.Fatal("invalid .Message")
+	}
+	if entry.Fields["domain_endpoint"].(string) != "api.ooni.io:443" {
+		t.Fatal("invalid domain_endpoint")
+	}
+	if entry.Fields["tactic"].(string) != "162.55.247.208:443 sni=www.example.com verify=api.ooni.io" {
+		t.Fatal("invalid tactic")
+	}
+	if entry.Fields["success_rate"].(float64) != 0.25 {
+		t.Fatal("invalid success_rate")
+	}
+	if !entry.Fields["last_updated"].(time.Time).Equal(lastUpdated) {
+		t.Fatal("invalid last_updated")
+	}
+}
+
+func TestLoadStatsReportFailed(t *testing.T) {
+	handler := &oonitest.FakeLoggerHandler{}
+	cli := &oonitest.FakeProbeCLI{
+		FakeHome:    "fakehome",
+		FakeTempDir: "faketempdir",
+	}
+	err := doinfo(doinfoconfig{
+		LoadStatsReport: func(home string) (*enginenetx.StatsReport, error) {
+			return nil, errors.New("mocked error")
+		},
+		NewProbeCLI: func() (ooni.ProbeCLI, error) {
+			return cli, nil
+		},
+		Logger: &log.Logger{
+			Handler: handler,
+			Level:   log.DebugLevel,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handler.FakeEntries) != 3 {
+		t.Fatal("invalid number of log entries")
+	}
+	entry := handler.FakeEntries[2]
+	if entry.Level != log.DebugLevel {
+		t.Fatal("invalid log level")
+	}
+	if entry.Message != "cannot load network stats: mocked error" {
+		t.Fatal("invalid .Message")
+	}
 }
@@ -51,6 +51,11 @@ func (r *ContextAwareSystemResolver) LookupNS(ctx context.Context, domain string
 	return r.wrap(ctx).LookupNS(ctx, domain)
 }
 
+// LookupRecords implements model.Resolver.
+func (r *ContextAwareSystemResolver) LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	return r.wrap(ctx).LookupRecords(ctx, domain, qtype)
+}
+
 // Network implements model.Resolver.
 func (r *ContextAwareSystemResolver) Network() string {
 	return r.R.Network()
@@ -124,6 +129,14 @@ func (r *resolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, erro
 	return out, err
 }
 
+// LookupRecords implements model.Resolver
+func (r *resolver) LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	r.updateCounterBytesSent(domain, 1)
+	out, err := r.Resolver.LookupRecords(ctx, domain, qtype)
+	r.updateCounterBytesRecv(err)
+	return out, err
+}
+
 // Network implements model.Resolver
 func (r *resolver) Network() string {
 	return r.Resolver.Network()
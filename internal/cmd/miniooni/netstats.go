@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/must"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/spf13/cobra"
+)
+
+// netStatsOptions contains the options of the netstats subcommand.
+type netStatsOptions struct {
+	Export bool
+	Prune  bool
+	Reset  bool
+}
+
+// registerNetStats registers the netstats subcommand
+func registerNetStats(rootCmd *cobra.Command, globalOptions *Options) {
+	var options netStatsOptions
+	subCmd := &cobra.Command{
+		Use:   "netstats",
+		Short: "Shows, prunes, or resets the stats about the tactics used to reach the OONI backend",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			netStatsMain(globalOptions, &options)
+		},
+	}
+	rootCmd.AddCommand(subCmd)
+	flags := subCmd.Flags()
+	flags.BoolVar(
+		&options.Export,
+		"export",
+		false,
+		"print a JSON report without IP addresses that you can attach to bug reports",
+	)
+	flags.BoolVar(
+		&options.Prune,
+		"prune",
+		false,
+		"remove old and excess entries from the stats",
+	)
+	flags.BoolVar(
+		&options.Reset,
+		"reset",
+		false,
+		"remove all the entries from the stats",
+	)
+	subCmd.MarkFlagsMutuallyExclusive("export", "prune", "reset")
+}
+
+func netStatsMain(globalOptions *Options, options *netStatsOptions) {
+	homeDir := gethomedir(globalOptions.HomeDir)
+	runtimex.Assert(homeDir != "", "home directory is empty")
+	kvStore, err := kvstore.NewFS(path.Join(homeDir, ".miniooni", "engine"))
+	runtimex.PanicOnError(err, "cannot create engine directory")
+
+	switch {
+	case options.Prune:
+		runtimex.PanicOnError(enginenetx.PruneStats(kvStore), "cannot prune the stats")
+		log.Info("pruned the stats")
+
+	case options.Reset:
+		runtimex.PanicOnError(enginenetx.ResetStats(kvStore), "cannot reset the stats")
+		log.Info("reset the stats")
+
+	case options.Export:
+		report, err := enginenetx.LoadStatsReport(kvStore)
+		runtimex.PanicOnError(err, "cannot load the stats")
+		data := must.MarshalAndIndentJSON(report.Redacted(), "", "  ")
+		must.Fprintf(os.Stdout, "%s\n", data)
+
+	default:
+		report, err := enginenetx.LoadStatsReport(kvStore)
+		runtimex.PanicOnError(err, "cannot load the stats")
+		report.WriteText(os.Stdout)
+	}
+}
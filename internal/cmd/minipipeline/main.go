@@ -31,13 +31,16 @@ var (
 
 	// osExitFn allows overwriting os.Exit in tests
 	osExitFn = os.Exit
+
+	// traceFlag is the -trace flag
+	traceFlag = flag.Bool("trace", false, "print the measurement's analysis decision trace")
 )
 
 func main() {
 	flag.Parse()
 	if *helpFlag || *measurementFlag == "" {
 		fmt.Fprintf(os.Stderr, "\n")
-		fmt.Fprintf(os.Stderr, "usage: %s -measurement <file> [-destdir <dir>] [-prefix <prefix>]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "usage: %s -measurement <file> [-destdir <dir>] [-prefix <prefix>] [-trace]\n", filepath.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Mini measurement processing pipeline to reprocess recent probe measurements\n")
 		fmt.Fprintf(os.Stderr, "and align results calculation with ooni/data.\n")
@@ -50,6 +53,9 @@ func main() {
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Use -prefix <prefix> to add <prefix> in front of the generated files names.\n")
 		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Use -trace to print the ordered list of rules that the probe evaluated to\n")
+		fmt.Fprintf(os.Stderr, "determine whether there is blocking, when the measurement contains it.\n")
+		fmt.Fprintf(os.Stderr, "\n")
 		osExitFn(1)
 	}
 
@@ -57,6 +63,11 @@ func main() {
 	var parsed minipipeline.WebMeasurement
 	must.UnmarshalJSON(must.ReadFile(*measurementFlag), &parsed)
 
+	// print the decision trace
+	if *traceFlag && !parsed.TestKeys.IsNone() {
+		parsed.TestKeys.Unwrap().XAnalysisTrace.WriteText(os.Stdout)
+	}
+
 	// generate and write observations
 	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
 	observationsPath := filepath.Join(*destdirFlag, *prefixFlag+"observations.json")
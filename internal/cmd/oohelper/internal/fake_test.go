@@ -60,6 +60,10 @@ func (c FakeResolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, e
 	return nil, errors.New("not implemented")
 }
 
+func (c FakeResolver) LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	return nil, errors.New("not implemented")
+}
+
 var _ model.Resolver = FakeResolver{}
 
 type FakeTransport struct {
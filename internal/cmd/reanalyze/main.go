@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivitylte"
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/minipipeline"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+var (
+	// formatFlag is the -format flag
+	formatFlag = flag.String("format", "jsonl", "output format: csv or jsonl")
+
+	// helpFlag is the -help flag
+	helpFlag = flag.Bool("help", false, "Show the help message")
+
+	// measurementsFlag is the -measurements flag
+	measurementsFlag = flag.String("measurements", "", "directory containing the measurements to reanalyze")
+
+	// onlyChangedFlag is the -only-changed flag
+	onlyChangedFlag = flag.Bool("only-changed", false, "only write the measurements whose verdict changed")
+
+	// osCreateFn allows overwriting os.Create in tests
+	osCreateFn = func(name string) (io.WriteCloser, error) {
+		return os.Create(name)
+	}
+
+	// osExitFn allows overwriting os.Exit in tests
+	osExitFn = os.Exit
+
+	// outputFlag is the -output flag
+	outputFlag = flag.String("output", "", "file where to write the verdicts")
+)
+
+// measurement contains the measurement fields we need to compare verdicts.
+type measurement struct {
+	Input                string `json:"input"`
+	MeasurementStartTime string `json:"measurement_start_time"`
+	ReportID             string `json:"report_id"`
+	TestName             string `json:"test_name"`
+	TestVersion          string `json:"test_version"`
+	TestKeys             struct {
+		Accessible    optional.Value[bool] `json:"accessible"`
+		Blocking      any                  `json:"blocking"`
+		BlockingFlags int64                `json:"x_blocking_flags"`
+	} `json:"test_keys"`
+}
+
+// verdict compares the verdict stored inside a measurement with the
+// verdict produced by the current analysis algorithm.
+type verdict struct {
+	File                 string `json:"file"`
+	Input                string `json:"input"`
+	ReportID             string `json:"report_id"`
+	MeasurementStartTime string `json:"measurement_start_time"`
+	OldBlocking          string `json:"old_blocking"`
+	NewBlocking          string `json:"new_blocking"`
+	OldAccessible        string `json:"old_accessible"`
+	NewAccessible        string `json:"new_accessible"`
+	OldBlockingFlags     int64  `json:"old_blocking_flags"`
+	NewBlockingFlags     int64  `json:"new_blocking_flags"`
+	Changed              bool   `json:"changed"`
+}
+
+// csvHeader contains the names of the CSV columns.
+var csvHeader = []string{
+	"file",
+	"input",
+	"report_id",
+	"measurement_start_time",
+	"old_blocking",
+	"new_blocking",
+	"old_accessible",
+	"new_accessible",
+	"old_blocking_flags",
+	"new_blocking_flags",
+	"changed",
+}
+
+// csvRecord returns the CSV columns for the verdict.
+func (v *verdict) csvRecord() []string {
+	return []string{
+		v.File,
+		v.Input,
+		v.ReportID,
+		v.MeasurementStartTime,
+		v.OldBlocking,
+		v.NewBlocking,
+		v.OldAccessible,
+		v.NewAccessible,
+		strconv.FormatInt(v.OldBlockingFlags, 10),
+		strconv.FormatInt(v.NewBlockingFlags, 10),
+		strconv.FormatBool(v.Changed),
+	}
+}
+
+// blockingString converts the value of the blocking test key to string.
+func blockingString(value any) string {
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// accessibleString converts the value of the accessible test key to string.
+func accessibleString(value optional.Value[bool]) string {
+	if value.IsNone() {
+		return "null"
+	}
+	return strconv.FormatBool(value.Unwrap())
+}
+
+// isWebConnectivityLTE returns whether the given test version is v0.5 or later, which
+// means that the measurement was collected by Web Connectivity LTE. We cannot meaningfully
+// reanalyze v0.4 measurements because they lack most of the events the analysis uses.
+func isWebConnectivityLTE(testVersion string) bool {
+	v := strings.Split(testVersion, ".")
+	if len(v) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(v[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(v[1])
+	if err != nil {
+		return false
+	}
+	return major > 0 || minor >= 5
+}
+
+// errNotWebConnectivityLTE indicates that we cannot reanalyze a Web Connectivity
+// measurement because it was not collected by Web Connectivity LTE.
+var errNotWebConnectivityLTE = errors.New("not a Web Connectivity LTE measurement")
+
+// reanalyze computes the verdict of the given raw measurement using the current
+// analysis algorithm. We return a nil verdict and a nil error for measurements
+// that are not Web Connectivity measurements and [errNotWebConnectivityLTE] for
+// Web Connectivity measurements with test_version < 0.5.
+func reanalyze(lookupper model.GeoIPASNLookupper, filename string, rawMsmt []byte) (*verdict, error) {
+	var old measurement
+	if err := json.Unmarshal(rawMsmt, &old); err != nil {
+		return nil, err
+	}
+	if old.TestName != "web_connectivity" {
+		return nil, nil
+	}
+	if !isWebConnectivityLTE(old.TestVersion) {
+		return nil, errNotWebConnectivityLTE
+	}
+
+	var webMeasurement minipipeline.WebMeasurement
+	if err := json.Unmarshal(rawMsmt, &webMeasurement); err != nil {
+		return nil, err
+	}
+	container, err := minipipeline.IngestWebMeasurement(lookupper, &webMeasurement)
+	if err != nil {
+		return nil, err
+	}
+	tk := webconnectivitylte.Reanalyze(lookupper, container, model.DiscardLogger)
+
+	v := &verdict{
+		File:                 filename,
+		Input:                old.Input,
+		ReportID:             old.ReportID,
+		MeasurementStartTime: old.MeasurementStartTime,
+		OldBlocking:          blockingString(old.TestKeys.Blocking),
+		NewBlocking:          blockingString(tk.Blocking),
+		OldAccessible:        accessibleString(old.TestKeys.Accessible),
+		NewAccessible:        accessibleString(tk.Accessible),
+		OldBlockingFlags:     old.TestKeys.BlockingFlags,
+		NewBlockingFlags:     tk.BlockingFlags,
+	}
+	v.Changed = v.OldBlocking != v.NewBlocking || v.OldAccessible != v.NewAccessible ||
+		v.OldBlockingFlags != v.NewBlockingFlags
+	return v, nil
+}
+
+// reanalyzeFile reanalyzes all the measurements inside the given file, which should
+// contain a measurement per line. We also return the number of Web Connectivity measurements
+// we skipped because of their test_version and the number of lines we could not reanalyze
+// (e.g., because they are not valid JSON), which do not prevent reanalyzing the other lines.
+func reanalyzeFile(lookupper model.GeoIPASNLookupper, filename string) ([]*verdict, int, int, error) {
+	filep, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer filep.Close()
+	var (
+		verdicts []*verdict
+		skipped  int
+		failed   int
+	)
+	reader := bufio.NewReader(filep)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, 0, err
+		}
+		if rawMsmt := bytes.TrimSpace(line); len(rawMsmt) > 0 {
+			v, err := reanalyze(lookupper, filename, rawMsmt)
+			switch {
+			case errors.Is(err, errNotWebConnectivityLTE):
+				skipped++
+			case err != nil:
+				failed++
+			case v != nil:
+				verdicts = append(verdicts, v)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return verdicts, skipped, failed, nil
+		}
+	}
+}
+
+// verdictsWriter writes verdicts using a specific format.
+type verdictsWriter interface {
+	Write(v *verdict) error
+	Flush() error
+}
+
+// jsonlVerdictsWriter writes verdicts as JSONL.
+type jsonlVerdictsWriter struct {
+	encoder *json.Encoder
+}
+
+// Write implements verdictsWriter.
+func (w *jsonlVerdictsWriter) Write(v *verdict) error {
+	return w.encoder.Encode(v)
+}
+
+// Flush implements verdictsWriter.
+func (w *jsonlVerdictsWriter) Flush() error {
+	return nil
+}
+
+// csvVerdictsWriter writes verdicts as CSV.
+type csvVerdictsWriter struct {
+	writer *csv.Writer
+}
+
+// Write implements verdictsWriter.
+func (w *csvVerdictsWriter) Write(v *verdict) error {
+	return w.writer.Write(v.csvRecord())
+}
+
+// Flush implements verdictsWriter.
+func (w *csvVerdictsWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// newVerdictsWriter creates a new verdictsWriter for the given format.
+func newVerdictsWriter(format string, w io.Writer) (verdictsWriter, error) {
+	switch format {
+	case "jsonl":
+		return &jsonlVerdictsWriter{json.NewEncoder(w)}, nil
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvVerdictsWriter{writer}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *helpFlag || *measurementsFlag == "" || *outputFlag == "" {
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "usage: %s -measurements <dir> -output <file> [-format csv|jsonl] [-only-changed]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Reanalyzes previously collected Web Connectivity measurements using the\n")
+		fmt.Fprintf(os.Stderr, "current minipipeline and analysis algorithm, to evaluate analysis changes\n")
+		fmt.Fprintf(os.Stderr, "using historical data.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Walks the <dir> provided using -measurements <dir> (e.g., the ooniprobe\n")
+		fmt.Fprintf(os.Stderr, "results directory) and reads all the *.json and *.jsonl files containing\n")
+		fmt.Fprintf(os.Stderr, "a measurement per line, skipping the lines we cannot reanalyze. For each Web\n")
+		fmt.Fprintf(os.Stderr, "Connectivity measurement, writes the old and new blocking, accessible, and\n")
+		fmt.Fprintf(os.Stderr, "x_blocking_flags values into the <file> provided using -output <file>.\n")
+		fmt.Fprintf(os.Stderr, "We skip Web Connectivity measurements with test_version < 0.5, which\n")
+		fmt.Fprintf(os.Stderr, "lack most of the events that the current analysis algorithm uses.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Use -format csv|jsonl to select the output format (default: jsonl).\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Use -only-changed to only write the measurements whose verdict changed.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		osExitFn(1)
+	}
+
+	// create the output file and writer
+	filep := runtimex.Try1(osCreateFn(*outputFlag))
+	defer filep.Close()
+	writer := runtimex.Try1(newVerdictsWriter(*formatFlag, filep))
+
+	// walk the measurements directory and reanalyze
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+	var total, changed, skipped, failed int
+	err := filepath.WalkDir(*measurementsFlag, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".json" && ext != ".jsonl" {
+			return nil
+		}
+		verdicts, skippedCount, failedCount, err := reanalyzeFile(lookupper, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: cannot reanalyze %s: %s\n", path, err.Error())
+			return nil
+		}
+		skipped += skippedCount
+		failed += failedCount
+		for _, v := range verdicts {
+			total++
+			if v.Changed {
+				changed++
+			}
+			if *onlyChangedFlag && !v.Changed {
+				continue
+			}
+			if err := writer.Write(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	runtimex.Try0(err)
+	runtimex.Try0(writer.Flush())
+	fmt.Fprintf(os.Stderr, "reanalyzed %d measurements: %d verdicts changed\n", total, changed)
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %d Web Connectivity measurements with test_version < 0.5\n", skipped)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "failed to reanalyze %d lines (e.g., because they are not valid JSON)\n", failed)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/must"
+)
+
+// bufferCloser is a [*bytes.Buffer] with a Close method.
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+// Close implements io.Closer.
+func (bc *bufferCloser) Close() error {
+	return nil
+}
+
+// runMainWithFormat runs main using the given format and returns the output.
+func runMainWithFormat(t *testing.T, format string) string {
+	*formatFlag = format
+	*measurementsFlag = filepath.Join("testdata", "measurements")
+	*onlyChangedFlag = false
+	*outputFlag = "verdicts.out"
+	output := &bufferCloser{}
+	osCreateFn = func(name string) (io.WriteCloser, error) {
+		if name != "verdicts.out" {
+			t.Fatal("unexpected output file name", name)
+		}
+		return output, nil
+	}
+	main()
+	return output.String()
+}
+
+func TestMainJSONL(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(runMainWithFormat(t, "jsonl")), "\n")
+
+	// make sure we only reanalyzed the Web Connectivity LTE measurement
+	if len(lines) != 1 {
+		t.Fatal("expected one line, got", len(lines))
+	}
+	var v verdict
+	must.UnmarshalJSON([]byte(lines[0]), &v)
+
+	// make sure we've got the expected old verdict
+	if v.Input != "https://nexa.polito.it/" {
+		t.Fatal("unexpected input", v.Input)
+	}
+	if v.OldBlocking != "false" || v.OldAccessible != "true" || v.OldBlockingFlags != 32 {
+		t.Fatal("unexpected old verdict", v.OldBlocking, v.OldAccessible, v.OldBlockingFlags)
+	}
+}
+
+func TestMainCSV(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(runMainWithFormat(t, "csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatal("expected the header and one record, got", len(records))
+	}
+	if len(records[0]) != len(csvHeader) || records[0][0] != "file" {
+		t.Fatal("unexpected header", records[0])
+	}
+	if records[1][1] != "https://nexa.polito.it/" {
+		t.Fatal("unexpected input", records[1][1])
+	}
+}
+
+func TestMainUsage(t *testing.T) {
+	// reconfigure the global options for main
+	*measurementsFlag = ""
+	*outputFlag = ""
+	osCreateFn = func(name string) (io.WriteCloser, error) {
+		panic(errors.New("osCreateFn"))
+	}
+	osExitFn = func(code int) {
+		panic(fmt.Errorf("osExit: %d", code))
+	}
+
+	// run the main function
+	var err error
+	func() {
+		// intercept panic caused by osExit or other panics
+		defer func() {
+			if r := recover(); r != nil {
+				err = r.(error)
+			}
+		}()
+
+		// run the main function with the given args
+		main()
+	}()
+
+	// make sure we've got the expected error
+	if err == nil || err.Error() != "osExit: 1" {
+		t.Fatal("expected", "os.Exit: 1", "got", err)
+	}
+}
+
+func TestIsWebConnectivityLTE(t *testing.T) {
+	cases := map[string]bool{
+		"":       false,
+		"0.4.3":  false,
+		"0.5.0":  true,
+		"0.5.30": true,
+		"1.0.0":  true,
+		"x.5.0":  false,
+		"0.x.0":  false,
+	}
+	for version, expect := range cases {
+		t.Run(version, func(t *testing.T) {
+			if got := isWebConnectivityLTE(version); got != expect {
+				t.Fatal("expected", expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestReanalyzeFile(t *testing.T) {
+	t.Run("we keep going after lines we cannot reanalyze", func(t *testing.T) {
+		// create a file containing an invalid line followed by the testdata
+		data := must.ReadFile(filepath.Join("testdata", "measurements", "report.jsonl"))
+		filename := filepath.Join(t.TempDir(), "report.jsonl")
+		must.WriteFile(filename, append([]byte("{\"test_name\":\n"), data...), 0600)
+
+		lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+		verdicts, skipped, failed, err := reanalyzeFile(lookupper, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(verdicts) != 1 {
+			t.Fatal("expected one verdict, got", len(verdicts))
+		}
+		if skipped != 1 {
+			t.Fatal("expected one skipped measurement, got", skipped)
+		}
+		if failed != 1 {
+			t.Fatal("expected one failed line, got", failed)
+		}
+	})
+
+	t.Run("with a nonexistent file", func(t *testing.T) {
+		lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+		verdicts, _, _, err := reanalyzeFile(lookupper, filepath.Join("testdata", "nonexistent.jsonl"))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if verdicts != nil {
+			t.Fatal("expected nil verdicts")
+		}
+	})
+}
+
+func TestNewVerdictsWriter(t *testing.T) {
+	t.Run("with unsupported format", func(t *testing.T) {
+		writer, err := newVerdictsWriter("xml", io.Discard)
+		if err == nil || err.Error() != "unsupported output format: xml" {
+			t.Fatal("unexpected error", err)
+		}
+		if writer != nil {
+			t.Fatal("expected nil writer")
+		}
+	})
+}
@@ -2,7 +2,8 @@ package enginenetx
 
 //
 // bridges policy - a policy where we treat some IP addresses as special for
-// some domains, bypassing DNS lookups and using custom SNIs
+// some domains, bypassing DNS lookups and using custom SNIs, and where we
+// possibly use domain fronting through CDNs configured by the user
 //
 
 import (
@@ -22,28 +23,79 @@ import (
 // This is v2 of the bridgesPolicy because the previous implementation
 // incorporated mixing logic, while now the mixing happens outside
 // of this policy, thus giving us much more flexibility.
-type bridgesPolicyV2 struct{}
+type bridgesPolicyV2 struct {
+	// Fronts OPTIONALLY maps a domain to the CDN fronts to use for
+	// generating domain fronting tactics for such a domain.
+	Fronts map[string][]*bridgesFront
+}
+
+// bridgesFront describes how to reach a domain using domain fronting through a CDN.
+//
+// We generate a domain fronting tactic for each address and SNI, where we verify the
+// certificate using the SNI, i.e., a front domain served by the CDN, and we send requests
+// using the HTTP host, i.e., the backend behind the CDN.
+type bridgesFront struct {
+	// Addresses contains the IP addresses of the CDN to use.
+	Addresses []string
+
+	// ECHConfigList is the OPTIONAL serialized ECHConfigList to use for encrypting
+	// the ClientHello, which is serialized as a base64 string in JSON.
+	ECHConfigList []byte `json:",omitempty"`
+
+	// HTTPHost is the OPTIONAL HTTP host to use for requests. When empty, we use the
+	// domain for which we're generating tactics, which is fine when the CDN routes
+	// requests for such a domain to the backend.
+	HTTPHost string `json:",omitempty"`
+
+	// SNIs contains the front domains to use as the SNI.
+	SNIs []string
+}
 
 var _ httpsDialerPolicy = &bridgesPolicyV2{}
 
 // LookupTactics implements httpsDialerPolicy.
 func (p *bridgesPolicyV2) LookupTactics(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
-	return bridgesTacticsForDomain(domain, port)
+	return bridgesTacticsForDomain(domain, port, p.Fronts[domain])
 }
 
-func bridgesTacticsForDomain(domain, port string) <-chan *httpsDialerTactic {
+func bridgesTacticsForDomain(domain, port string, fronts []*bridgesFront) <-chan *httpsDialerTactic {
 	out := make(chan *httpsDialerTactic)
 
 	go func() {
 		defer close(out) // tell the parent when we're done
 
+		// start with the domain fronting tactics, which we only have when the
+		// user configured them, such that they are tried first
+		for _, front := range fronts {
+			if front == nil {
+				continue
+			}
+			httpHost := front.HTTPHost
+			if httpHost == "" {
+				httpHost = domain
+			}
+			for _, ipAddr := range front.Addresses {
+				for _, sni := range front.SNIs {
+					out <- &httpsDialerTactic{
+						Address:        ipAddr,
+						ECHConfigList:  front.ECHConfigList,
+						HTTPHost:       httpHost,
+						InitialDelay:   0, // set when dialing
+						Port:           port,
+						SNI:            sni,
+						VerifyHostname: sni,
+					}
+				}
+			}
+		}
+
 		// we currently only have bridges for api.ooni.io
 		if domain != "api.ooni.io" {
 			return
 		}
 
 		for _, ipAddr := range bridgesAddrs() {
-			for _, sni := range bridgesDomainsInRandomOrder() {
+			for idx, sni := range bridgesDomainsInRandomOrder() {
 				out <- &httpsDialerTactic{
 					Address:        ipAddr,
 					InitialDelay:   0, // set when dialing
@@ -51,6 +103,21 @@ func bridgesTacticsForDomain(domain, port string) <-chan *httpsDialerTactic {
 					SNI:            sni,
 					VerifyHostname: domain,
 				}
+
+				// follow the first TCP tactic with its QUIC counterpart, such that we
+				// race a QUIC handshake alongside the TCP ones in case the TCP/443 traffic
+				// is being throttled, while still giving TCP, which works most of the
+				// time, a head start of one happy eyeballs delay
+				if idx == 0 {
+					out <- &httpsDialerTactic{
+						Address:        ipAddr,
+						InitialDelay:   0, // set when dialing
+						Port:           port,
+						SNI:            sni,
+						Transport:      httpsDialerTransportQUIC,
+						VerifyHostname: domain,
+					}
+				}
 			}
 		}
 	}()
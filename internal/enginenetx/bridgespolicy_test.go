@@ -3,6 +3,8 @@ package enginenetx
 import (
 	"context"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestBridgesPolicyV2(t *testing.T) {
@@ -26,9 +28,21 @@ func TestBridgesPolicyV2(t *testing.T) {
 
 		tactics := p.LookupTactics(context.Background(), "api.ooni.io", "443")
 
-		var count int
+		var (
+			count, countQUIC int
+			previous         *httpsDialerTactic
+		)
 		for tactic := range tactics {
 			count++
+			if tactic.isQUIC() {
+				countQUIC++
+
+				// the QUIC tactic must follow its TCP counterpart
+				if previous == nil || previous.isQUIC() || previous.SNI != tactic.SNI {
+					t.Fatal("the QUIC tactic should follow its TCP counterpart")
+				}
+			}
+			previous = tactic
 
 			// for each generated tactic, make sure we're getting the
 			// expected value for each of the fields
@@ -57,5 +71,91 @@ func TestBridgesPolicyV2(t *testing.T) {
 		if count <= 0 {
 			t.Fatal("expected to see at least one tactic")
 		}
+
+		if countQUIC != 1 {
+			t.Fatal("expected to see exactly one QUIC tactic")
+		}
+	})
+
+	t.Run("with fronts for the api.ooni.io domain", func(t *testing.T) {
+		p := &bridgesPolicyV2{
+			Fronts: map[string][]*bridgesFront{
+				"api.ooni.io": {nil, {
+					Addresses:     []string{"151.101.1.1", "151.101.65.1"},
+					ECHConfigList: []byte{0, 4, 1, 2, 3, 4},
+					HTTPHost:      "ooni-api.cdn.example",
+					SNIs:          []string{"www.example.com"},
+				}, {
+					Addresses: []string{"104.16.0.1"},
+					SNIs:      []string{"www.example.org"},
+				}},
+			},
+		}
+
+		tactics := p.LookupTactics(context.Background(), "api.ooni.io", "443")
+
+		var got []*httpsDialerTactic
+		for tactic := range tactics {
+			got = append(got, tactic)
+		}
+
+		// the fronting tactics should come first and we should also see the bridges
+		expect := []*httpsDialerTactic{{
+			Address:        "151.101.1.1",
+			ECHConfigList:  []byte{0, 4, 1, 2, 3, 4},
+			HTTPHost:       "ooni-api.cdn.example",
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "www.example.com",
+		}, {
+			Address:        "151.101.65.1",
+			ECHConfigList:  []byte{0, 4, 1, 2, 3, 4},
+			HTTPHost:       "ooni-api.cdn.example",
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "www.example.com",
+		}, {
+			Address:        "104.16.0.1",
+			HTTPHost:       "api.ooni.io", // the default is the domain
+			Port:           "443",
+			SNI:            "www.example.org",
+			VerifyHostname: "www.example.org",
+		}}
+		if len(got) <= len(expect) {
+			t.Fatal("expected to see also the bridges tactics")
+		}
+		if diff := cmp.Diff(expect, got[:len(expect)]); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with fronts for domains for which we don't have bridges", func(t *testing.T) {
+		p := &bridgesPolicyV2{
+			Fronts: map[string][]*bridgesFront{
+				"www.example.com": {{
+					Addresses: []string{"104.16.0.1"},
+					HTTPHost:  "backend.example.com",
+					SNIs:      []string{"www.example.org"},
+				}},
+			},
+		}
+
+		tactics := p.LookupTactics(context.Background(), "www.example.com", "443")
+
+		var got []*httpsDialerTactic
+		for tactic := range tactics {
+			got = append(got, tactic)
+		}
+
+		expect := []*httpsDialerTactic{{
+			Address:        "104.16.0.1",
+			HTTPHost:       "backend.example.com",
+			Port:           "443",
+			SNI:            "www.example.org",
+			VerifyHostname: "www.example.org",
+		}}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
 	})
 }
@@ -74,3 +74,38 @@ func filterAssignInitialDelays(input <-chan *httpsDialerTactic) <-chan *httpsDia
 	}()
 	return output
 }
+
+// filterOnlyKeepQUICTactics only keeps the tactics using QUIC.
+//
+// This function returns a channel where we emit the edited
+// tactics, and which we clone when we're done.
+func filterOnlyKeepQUICTactics(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
+	output := make(chan *httpsDialerTactic)
+	go func() {
+		defer close(output)
+		for tx := range input {
+			if tx.isQUIC() {
+				output <- tx
+			}
+		}
+	}()
+	return output
+}
+
+// filterOnlyKeepFrontingTactics only keeps the TCP tactics using domain
+// fronting with the given HTTP host.
+//
+// This function returns a channel where we emit the edited
+// tactics, and which we clone when we're done.
+func filterOnlyKeepFrontingTactics(input <-chan *httpsDialerTactic, httpHost string) <-chan *httpsDialerTactic {
+	output := make(chan *httpsDialerTactic)
+	go func() {
+		defer close(output)
+		for tx := range input {
+			if tx.isFronting() && tx.HTTPHost == httpHost {
+				output <- tx
+			}
+		}
+	}()
+	return output
+}
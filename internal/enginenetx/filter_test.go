@@ -109,3 +109,44 @@ func TestFilterAssignInitalDelays(t *testing.T) {
 		t.Fatal("expected to see at least one entry")
 	}
 }
+
+func TestFilterOnlyKeepFrontingTactics(t *testing.T) {
+	inputs := []*httpsDialerTactic{{
+		Address:        "130.192.91.211",
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "api.ooni.io",
+	}, {
+		Address:        "151.101.1.1",
+		HTTPHost:       "ooni-api.cdn.example",
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "www.example.com",
+	}, {
+		Address:        "151.101.1.1",
+		HTTPHost:       "ooni-api.cdn.example",
+		Port:           "443",
+		SNI:            "www.example.com",
+		Transport:      httpsDialerTransportQUIC, // we don't use fronting with QUIC
+		VerifyHostname: "www.example.com",
+	}, {
+		Address:        "104.16.0.1",
+		HTTPHost:       "ooni-api.other-cdn.example",
+		Port:           "443",
+		SNI:            "www.example.org",
+		VerifyHostname: "www.example.org",
+	}}
+
+	expect := []*httpsDialerTactic{
+		inputs[1],
+	}
+
+	var output []*httpsDialerTactic
+	for tx := range filterOnlyKeepFrontingTactics(streamTacticsFromSlice(inputs), "ooni-api.cdn.example") {
+		output = append(output, tx)
+	}
+
+	if diff := cmp.Diff(expect, output); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,35 @@
+package enginenetx
+
+//
+// Domain fronting - sending requests through a CDN using an HTTP
+// host different from the SNI when a domain fronting tactic wins
+//
+
+import (
+	"context"
+	"net"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// httpsDialerFrontedTLSDialer adapts an [*httpsDialer] to be a [model.TLSDialer]
+// that only uses the domain fronting tactics with the given HTTP host.
+type httpsDialerFrontedTLSDialer struct {
+	hd       *httpsDialer
+	httpHost string
+}
+
+var _ model.TLSDialer = &httpsDialerFrontedTLSDialer{}
+
+// DialTLSContext implements model.TLSDialer.
+func (fd *httpsDialerFrontedTLSDialer) DialTLSContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	return fd.hd.DialFrontedTLSContext(ctx, address, fd.httpHost)
+}
+
+// CloseIdleConnections implements model.TLSDialer.
+//
+// We only close the conns established by domain fronting tactics, since the
+// QUIC conns belong to the HTTP/3 transport (see [httpsDialerQUICDialer]).
+func (fd *httpsDialerFrontedTLSDialer) CloseIdleConnections() {
+	fd.hd.closeIdleFrontedConns()
+}
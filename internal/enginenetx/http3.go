@@ -0,0 +1,37 @@
+package enginenetx
+
+//
+// HTTP/3 - routing requests over HTTP/3 when a QUIC tactic wins
+//
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/quic-go/quic-go"
+)
+
+// httpsDialerQUICDialer adapts an [*httpsDialer] to be a [model.QUICDialer].
+//
+// We ignore the TLS and QUIC configs passed by the HTTP/3 transport because
+// the [*httpsDialer] builds them using the selected tactic.
+type httpsDialerQUICDialer struct {
+	hd *httpsDialer
+}
+
+var _ model.QUICDialer = &httpsDialerQUICDialer{}
+
+// DialContext implements model.QUICDialer.
+func (qd *httpsDialerQUICDialer) DialContext(
+	ctx context.Context, address string, tlsConfig *tls.Config, quicConfig *quic.Config) (model.QUICConn, error) {
+	return qd.hd.DialQUICContext(ctx, address)
+}
+
+// CloseIdleConnections implements model.QUICDialer.
+//
+// We only close the saved QUIC conns because the TLS conns belong to
+// the TCP transport, which takes care of closing them.
+func (qd *httpsDialerQUICDialer) CloseIdleConnections() {
+	qd.hd.closeIdleQUICConns()
+}
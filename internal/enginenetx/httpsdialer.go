@@ -2,12 +2,15 @@ package enginenetx
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,6 +19,17 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/quic-go/quic-go"
+)
+
+// These are the transports that an [*httpsDialerTactic] may use.
+const (
+	// httpsDialerTransportTCP means that we use TCP and TLS, which is the default
+	// transport used when the tactic's Transport field is empty.
+	httpsDialerTransportTCP = "tcp"
+
+	// httpsDialerTransportQUIC means that we use QUIC and, therefore, HTTP/3.
+	httpsDialerTransportQUIC = "quic"
 )
 
 // httpsDialerTactic is a tactic to establish a TLS connection.
@@ -23,6 +37,17 @@ type httpsDialerTactic struct {
 	// Address is the IPv4/IPv6 address for dialing.
 	Address string
 
+	// ECHConfigList is the OPTIONAL serialized ECHConfigList to use for
+	// encrypting the ClientHello, in which case the SNI is only sent inside
+	// the encrypted ClientHello. We only use this field for TCP tactics.
+	ECHConfigList []byte `json:",omitempty"`
+
+	// HTTPHost is the OPTIONAL HTTP Host to use for requests sent using a conn
+	// established by this tactic. When set, this is a domain fronting tactic where
+	// the SNI and the VerifyHostname are typically those of a CDN front domain and
+	// the HTTP Host is the backend behind the CDN. We only use this field for TCP tactics.
+	HTTPHost string `json:",omitempty"`
+
 	// InitialDelay is the time in nanoseconds after which
 	// you would like to start this policy.
 	InitialDelay time.Duration
@@ -33,6 +58,11 @@ type httpsDialerTactic struct {
 	// SNI is the TLS ServerName to send over the wire.
 	SNI string
 
+	// Transport is the OPTIONAL transport to use, which is one of the
+	// httpsDialerTransportXXX constants. When empty, we use TCP, which
+	// keeps compatibility with tactics previously serialized on disk.
+	Transport string `json:",omitempty"`
+
 	// VerifyHostname is the hostname using during
 	// the X.509 certificate verification.
 	VerifyHostname string
@@ -44,13 +74,27 @@ var _ fmt.Stringer = &httpsDialerTactic{}
 func (dt *httpsDialerTactic) Clone() *httpsDialerTactic {
 	return &httpsDialerTactic{
 		Address:        dt.Address,
+		ECHConfigList:  slices.Clone(dt.ECHConfigList),
+		HTTPHost:       dt.HTTPHost,
 		InitialDelay:   dt.InitialDelay,
 		Port:           dt.Port,
 		SNI:            dt.SNI,
+		Transport:      dt.Transport,
 		VerifyHostname: dt.VerifyHostname,
 	}
 }
 
+// isQUIC returns whether this tactic uses the QUIC transport.
+func (dt *httpsDialerTactic) isQUIC() bool {
+	return dt.Transport == httpsDialerTransportQUIC
+}
+
+// isFronting returns whether this tactic uses domain fronting, which
+// we only support for TCP tactics.
+func (dt *httpsDialerTactic) isFronting() bool {
+	return dt.HTTPHost != "" && !dt.isQUIC()
+}
+
 // String implements fmt.Stringer.
 func (dt *httpsDialerTactic) String() string {
 	return string(runtimex.Try1(json.Marshal(dt)))
@@ -68,18 +112,39 @@ func (dt *httpsDialerTactic) String() string {
 //
 // - VerifyHostname
 //
+// - Transport
+//
+// - HTTPHost
+//
+// - ECHConfigList
+//
 // The returned string contains the above fields separated by space with
-// `sni=` before the SNI and `verify=` before the verify hostname.
+// `sni=` before the SNI and `verify=` before the verify hostname. We only
+// append `transport=quic` for QUIC tactics, `host=` followed by the HTTP host
+// for domain fronting tactics, and `ech=` followed by a short hash of the
+// ECHConfigList for ECH tactics, such that the summary of the plain TCP
+// tactics is the same it was before we introduced these tactics.
 //
 // We should be careful not to change this format unless we also change the
 // format version used by user policies and by the state management.
 func (dt *httpsDialerTactic) tacticSummaryKey() string {
-	return fmt.Sprintf(
+	summary := fmt.Sprintf(
 		"%v sni=%v verify=%v",
 		net.JoinHostPort(dt.Address, dt.Port),
 		dt.SNI,
 		dt.VerifyHostname,
 	)
+	if dt.isQUIC() {
+		summary += " transport=" + httpsDialerTransportQUIC
+	}
+	if dt.isFronting() {
+		summary += " host=" + dt.HTTPHost
+	}
+	if len(dt.ECHConfigList) > 0 {
+		digest := sha256.Sum256(dt.ECHConfigList)
+		summary += " ech=" + hex.EncodeToString(digest[:4])
+	}
+	return summary
 }
 
 // domainEndpointKey returns a string consisting of the domain endpoint only.
@@ -110,6 +175,7 @@ type httpsDialerEventsHandler interface {
 	OnStarting(tactic *httpsDialerTactic)
 	OnTCPConnectError(ctx context.Context, tactic *httpsDialerTactic, err error)
 	OnTLSHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error)
+	OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error)
 	OnTLSVerifyError(tactic *httpsDialerTactic, err error)
 	OnSuccess(tactic *httpsDialerTactic)
 }
@@ -120,19 +186,37 @@ type httpsDialerEventsHandler interface {
 //
 // This dialer MAY use an happy-eyeballs-like policy where we may try several IP addresses,
 // including IPv4 and IPv6, and dialing tactics in parallel.
+//
+// When a QUIC tactic wins, DialTLSContext returns [errHTTPSDialerUseHTTP3] and saves
+// the QUIC connection, which DialQUICContext returns to the HTTP/3 transport.
+//
+// Likewise, when a domain fronting tactic wins, DialTLSContext returns an
+// [*httpsDialerUseFrontingError] and saves the TLS connection, which
+// DialFrontedTLSContext returns to the transport using the fronted HTTP host.
 type httpsDialer struct {
+	// frontedConns maps an endpoint and an HTTP host to the TLS connection established
+	// by a domain fronting tactic winning in DialTLSContext and not yet used.
+	frontedConns map[string]model.TLSConn
+
 	// idGenerator is the ID generator.
 	idGenerator *atomic.Int64
 
 	// logger is the logger to use.
 	logger model.Logger
 
+	// mu provides mutual exclusion for frontedConns and quicConns.
+	mu sync.Mutex
+
 	// netx is the [*netxlite.Netx] to use.
 	netx *netxlite.Netx
 
 	// policy defines the dialing policy to use.
 	policy httpsDialerPolicy
 
+	// quicConns maps an endpoint to the QUIC connection established by a
+	// QUIC tactic winning in DialTLSContext and not yet used.
+	quicConns map[string]model.QUICConn
+
 	// rootCAs contains the root certificate pool we should use.
 	rootCAs *x509.CertPool
 
@@ -166,10 +250,13 @@ func newHTTPSDialer(
 			Prefix: "httpsDialer: ",
 			Logger: logger,
 		},
-		netx:    netx,
-		policy:  policy,
-		rootCAs: netx.MaybeCustomUnderlyingNetwork().Get().DefaultCertPool(),
-		stats:   stats,
+		frontedConns: map[string]model.TLSConn{},
+		mu:           sync.Mutex{},
+		netx:         netx,
+		policy:       policy,
+		quicConns:    map[string]model.QUICConn{},
+		rootCAs:      netx.MaybeCustomUnderlyingNetwork().Get().DefaultCertPool(),
+		stats:        stats,
 	}
 }
 
@@ -177,7 +264,35 @@ var _ model.TLSDialer = &httpsDialer{}
 
 // CloseIdleConnections implements model.TLSDialer.
 func (hd *httpsDialer) CloseIdleConnections() {
-	// nothing
+	hd.closeIdleQUICConns()
+	hd.closeIdleFrontedConns()
+}
+
+// closeIdleQUICConns closes the QUIC conns established by QUIC tactics that
+// won in DialTLSContext and that the HTTP/3 transport did not use.
+func (hd *httpsDialer) closeIdleQUICConns() {
+	defer hd.mu.Unlock()
+	hd.mu.Lock()
+	for endpoint, qconn := range hd.quicConns {
+		httpsDialerCloseQUICConn(qconn)
+		delete(hd.quicConns, endpoint)
+	}
+}
+
+// closeIdleFrontedConns closes the TLS conns established by domain fronting
+// tactics that won in DialTLSContext and that the transport did not use.
+func (hd *httpsDialer) closeIdleFrontedConns() {
+	defer hd.mu.Unlock()
+	hd.mu.Lock()
+	for key, conn := range hd.frontedConns {
+		_ = conn.Close()
+		delete(hd.frontedConns, key)
+	}
+}
+
+// httpsDialerCloseQUICConn closes the given QUIC conn.
+func httpsDialerCloseQUICConn(qconn model.QUICConn) {
+	_ = qconn.CloseWithError(0, "")
 }
 
 // httpsDialerErrorOrConn contains either an error or a valid conn.
@@ -185,10 +300,32 @@ type httpsDialerErrorOrConn struct {
 	// Conn is the established TLS conn or nil.
 	Conn model.TLSConn
 
+	// QUICConn is the established QUIC conn or nil.
+	QUICConn model.QUICConn
+
+	// Tactic is the tactic that established the conn or nil.
+	Tactic *httpsDialerTactic
+
 	// Err is the error or nil.
 	Err error
 }
 
+// errHTTPSDialerUseHTTP3 indicates that a QUIC tactic won and that, therefore, the
+// caller should send the request using HTTP/3 rather than HTTP/1.1 or HTTP/2.
+var errHTTPSDialerUseHTTP3 = errors.New("httpsDialer: a QUIC tactic won: use HTTP/3")
+
+// httpsDialerUseFrontingError indicates that a domain fronting tactic won and that,
+// therefore, the caller should send the request using the given HTTP host.
+type httpsDialerUseFrontingError struct {
+	// HTTPHost is the HTTP host to use.
+	HTTPHost string
+}
+
+// Error implements error.
+func (err *httpsDialerUseFrontingError) Error() string {
+	return "httpsDialer: a domain fronting tactic won: use HTTP host " + err.HTTPHost
+}
+
 // errDNSNoAnswer is the error returned when we have no tactic to try
 var errDNSNoAnswer = netxlite.NewErrWrapper(
 	netxlite.ClassifyResolverError,
@@ -197,12 +334,122 @@ var errDNSNoAnswer = netxlite.NewErrWrapper(
 )
 
 // DialTLSContext implements model.TLSDialer.
+//
+// When a QUIC tactic wins, this method saves the QUIC conn and returns [errHTTPSDialerUseHTTP3].
+//
+// When a domain fronting tactic wins, this method saves the TLS conn and
+// returns an [*httpsDialerUseFrontingError].
 func (hd *httpsDialer) DialTLSContext(ctx context.Context, network string, endpoint string) (net.Conn, error) {
 	hostname, port, err := net.SplitHostPort(endpoint)
 	if err != nil {
 		return nil, err
 	}
+	result := hd.dial(ctx, hostname, port, httpsDialerFilterTactics)
+	switch {
+	case result.Err != nil:
+		return nil, result.Err
+
+	case result.QUICConn != nil:
+		hd.saveQUICConn(endpoint, result.QUICConn)
+		return nil, errHTTPSDialerUseHTTP3
+
+	case result.Tactic.isFronting():
+		hd.saveFrontedConn(endpoint, result.Tactic.HTTPHost, result.Conn)
+		return nil, &httpsDialerUseFrontingError{HTTPHost: result.Tactic.HTTPHost}
+
+	default:
+		return result.Conn, nil
+	}
+}
+
+// DialFrontedTLSContext returns the TLS conn saved by DialTLSContext for the given endpoint
+// and HTTP host, if any, and otherwise establishes a new TLS conn using only the domain
+// fronting tactics using the given HTTP host.
+func (hd *httpsDialer) DialFrontedTLSContext(ctx context.Context, endpoint, httpHost string) (model.TLSConn, error) {
+	if conn := hd.popFrontedConn(endpoint, httpHost); conn != nil {
+		return conn, nil
+	}
+	hostname, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	result := hd.dial(ctx, hostname, port, func(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
+		return httpsDialerFilterTactics(filterOnlyKeepFrontingTactics(filterOutNilTactics(input), httpHost))
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return result.Conn, nil
+}
 
+// DialQUICContext returns the QUIC conn saved by DialTLSContext for the given endpoint,
+// if any, and otherwise establishes a new QUIC conn using only the QUIC tactics.
+func (hd *httpsDialer) DialQUICContext(ctx context.Context, endpoint string) (model.QUICConn, error) {
+	if qconn := hd.popQUICConn(endpoint); qconn != nil {
+		return qconn, nil
+	}
+	hostname, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	result := hd.dial(ctx, hostname, port, func(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
+		return httpsDialerFilterTactics(filterOnlyKeepQUICTactics(filterOutNilTactics(input)))
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return result.QUICConn, nil
+}
+
+// saveQUICConn saves the QUIC conn for the given endpoint, closing the
+// previously saved QUIC conn for such an endpoint, if any.
+func (hd *httpsDialer) saveQUICConn(endpoint string, qconn model.QUICConn) {
+	defer hd.mu.Unlock()
+	hd.mu.Lock()
+	if previous := hd.quicConns[endpoint]; previous != nil {
+		httpsDialerCloseQUICConn(previous)
+	}
+	hd.quicConns[endpoint] = qconn
+}
+
+// popQUICConn returns and forgets the QUIC conn saved for the given endpoint or nil.
+func (hd *httpsDialer) popQUICConn(endpoint string) model.QUICConn {
+	defer hd.mu.Unlock()
+	hd.mu.Lock()
+	qconn := hd.quicConns[endpoint]
+	delete(hd.quicConns, endpoint)
+	return qconn
+}
+
+// saveFrontedConn saves the TLS conn for the given endpoint and HTTP host, closing
+// the previously saved TLS conn for such an endpoint and HTTP host, if any.
+func (hd *httpsDialer) saveFrontedConn(endpoint, httpHost string, conn model.TLSConn) {
+	defer hd.mu.Unlock()
+	hd.mu.Lock()
+	key := endpoint + " host=" + httpHost
+	if previous := hd.frontedConns[key]; previous != nil {
+		_ = previous.Close()
+	}
+	hd.frontedConns[key] = conn
+}
+
+// popFrontedConn returns and forgets the TLS conn saved for the given endpoint and HTTP host or nil.
+func (hd *httpsDialer) popFrontedConn(endpoint, httpHost string) model.TLSConn {
+	defer hd.mu.Unlock()
+	hd.mu.Lock()
+	key := endpoint + " host=" + httpHost
+	conn := hd.frontedConns[key]
+	delete(hd.frontedConns, key)
+	return conn
+}
+
+// dial races the tactics for the given hostname and port, which we filter using the
+// given function, and returns the winning TLS conn or QUIC conn, or an error.
+func (hd *httpsDialer) dial(
+	ctx context.Context,
+	hostname, port string,
+	filter func(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic,
+) *httpsDialerErrorOrConn {
 	// TODO(bassosimone): this code should be refactored using the same
 	// pattern used by `./internal/httpclientx` to perform attempts faster
 	// in case there is an initial early failure.
@@ -219,7 +466,7 @@ func (hd *httpsDialer) DialTLSContext(ctx context.Context, network string, endpo
 
 	// The emitter will emit tactics and then close the channel when done. We spawn 16 workers
 	// that handle tactics in parallel and post results on the collector channel.
-	emitter := httpsDialerFilterTactics(hd.policy.LookupTactics(ctx, hostname, port))
+	emitter := filter(hd.policy.LookupTactics(ctx, hostname, port))
 	collector := make(chan *httpsDialerErrorOrConn)
 	joiner := make(chan any)
 	const parallelism = 16
@@ -230,6 +477,9 @@ func (hd *httpsDialer) DialTLSContext(ctx context.Context, network string, endpo
 	// wait until all goroutines have joined
 	var (
 		connv     = []model.TLSConn{}
+		tacticv   = []*httpsDialerTactic{}
+		quicv     = []*httpsDialerErrorOrConn{}
+		quicFirst = false
 		errorv    = []error{}
 		numJoined = 0
 	)
@@ -245,15 +495,40 @@ func (hd *httpsDialer) DialTLSContext(ctx context.Context, network string, endpo
 				continue
 			}
 
-			// Save the conn
-			connv = append(connv, result.Conn)
+			// Save the conn and remember whether QUIC won
+			if result.QUICConn != nil {
+				quicFirst = quicFirst || len(connv) <= 0
+				quicv = append(quicv, result)
+			} else {
+				connv = append(connv, result.Conn)
+				tacticv = append(tacticv, result.Tactic)
+			}
 
 			// Interrupt other concurrent dialing attempts
 			cancel()
 		}
 	}
 
-	return httpsDialerReduceResult(connv, errorv)
+	// Prefer the QUIC conn when it was the first to be established
+	if len(quicv) >= 1 && quicFirst {
+		for _, c := range connv {
+			_ = c.Close()
+		}
+		for _, result := range quicv[1:] {
+			httpsDialerCloseQUICConn(result.QUICConn)
+		}
+		return quicv[0]
+	}
+	for _, result := range quicv {
+		httpsDialerCloseQUICConn(result.QUICConn)
+	}
+
+	// Note that httpsDialerReduceResult returns the first conn, if any
+	tlsConn, err := httpsDialerReduceResult(connv, errorv)
+	if err != nil {
+		return &httpsDialerErrorOrConn{Err: err}
+	}
+	return &httpsDialerErrorOrConn{Conn: tlsConn, Tactic: tacticv[0]}
 }
 
 // httpsDialerWorkerZeroTime contains the zero time used when dialing. We set this
@@ -338,11 +613,16 @@ func (hd *httpsDialer) worker(
 			Logger: hd.logger,
 		}
 
-		// perform the actual dial
+		// perform the actual dial using the tactic's transport
+		if tactic.isQUIC() {
+			qconn, err := hd.dialQUIC(ctx, prefixLogger, t0, tactic)
+			writer <- &httpsDialerErrorOrConn{QUICConn: qconn, Tactic: tactic, Err: err}
+			continue
+		}
 		conn, err := hd.dialTLS(ctx, prefixLogger, t0, tactic)
 
 		// send results to the parent
-		writer <- &httpsDialerErrorOrConn{Conn: conn, Err: err}
+		writer <- &httpsDialerErrorOrConn{Conn: conn, Tactic: tactic, Err: err}
 	}
 }
 
@@ -385,6 +665,12 @@ func (hd *httpsDialer) dialTLS(
 		ServerName:         tactic.SNI,
 	}
 
+	// possibly encrypt the ClientHello, which requires TLS v1.3
+	if len(tactic.ECHConfigList) > 0 {
+		tlsConfig.EncryptedClientHelloConfigList = tactic.ECHConfigList
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
 	// create handshaker and establish a TLS connection
 	ol = logx.NewOperationLogger(
 		logger,
@@ -422,6 +708,69 @@ func (hd *httpsDialer) dialTLS(
 	return tlsConn, nil
 }
 
+// dialQUIC performs the actual QUIC dial.
+func (hd *httpsDialer) dialQUIC(
+	ctx context.Context,
+	logger model.Logger,
+	t0 *httpsDialerWorkerZeroTime,
+	tactic *httpsDialerTactic,
+) (model.QUICConn, error) {
+	// honor happy-eyeballs delays and wait for the tactic to be ready to run
+	if err := httpsDialerTacticWaitReady(ctx, t0, tactic); err != nil {
+		return nil, err
+	}
+
+	// for debugging let the user know which tactic is ready
+	logger.Infof("tactic '%+v' is ready", tactic)
+
+	// tell the observer that we're starting
+	hd.stats.OnStarting(tactic)
+
+	// create TLS configuration
+	endpoint := net.JoinHostPort(tactic.Address, tactic.Port)
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // #nosec G402 - we verify at end of func
+		NextProtos:         []string{"h3"},
+		RootCAs:            hd.rootCAs,
+		ServerName:         tactic.SNI,
+	}
+
+	// create dialer and establish a QUIC connection
+	ol := logx.NewOperationLogger(
+		logger,
+		"QUICHandshake with %s SNI=%s ALPN=%v",
+		endpoint,
+		tlsConfig.ServerName,
+		tlsConfig.NextProtos,
+	)
+	dialer := hd.netx.NewQUICDialerWithoutResolver(hd.netx.NewUDPListener(), logger)
+	qconn, err := dialer.DialContext(ctx, endpoint, tlsConfig, &quic.Config{})
+	ol.Stop(err)
+
+	// handle handshake error
+	if err != nil {
+		hd.stats.OnQUICHandshakeError(ctx, tactic, err)
+		return nil, err
+	}
+
+	// verify the certificate chain
+	ol = logx.NewOperationLogger(logger, "TLSVerifyCertificateChain %s", tactic.VerifyHostname)
+	err = httpsDialerVerifyConnectionState(tactic.VerifyHostname, qconn.ConnectionState().TLS, hd.rootCAs)
+	ol.Stop(err)
+
+	// handle verification error
+	if err != nil {
+		hd.stats.OnTLSVerifyError(tactic, err)
+		httpsDialerCloseQUICConn(qconn)
+		return nil, err
+	}
+
+	// make sure the observer knows it worked
+	hd.stats.OnSuccess(tactic)
+
+	return qconn, nil
+}
+
 // httpsDialerWaitReady waits for the given delay to expire or the context to be canceled. If the
 // delay is zero or negative, we immediately return nil. We also return nil when the delay expires. We
 // return the context error if the context expires.
@@ -456,6 +805,12 @@ var errEmptyVerifyHostname = errors.New("empty VerifyHostname")
 
 // httpsDialerVerifyCertificateChain verifies the certificate chain with the given hostname.
 func httpsDialerVerifyCertificateChain(hostname string, conn model.TLSConn, rootCAs *x509.CertPool) error {
+	return httpsDialerVerifyConnectionState(hostname, conn.ConnectionState(), rootCAs)
+}
+
+// httpsDialerVerifyConnectionState verifies the certificate chain inside the given
+// TLS connection state, which may also come from a QUIC conn, with the given hostname.
+func httpsDialerVerifyConnectionState(hostname string, state tls.ConnectionState, rootCAs *x509.CertPool) error {
 	// This code comes from the example in the Go source tree that shows
 	// how to override certificate verification and which is advertised
 	// as follows:
@@ -478,7 +833,6 @@ func httpsDialerVerifyCertificateChain(hostname string, conn model.TLSConn, root
 		return errEmptyVerifyHostname
 	}
 
-	state := conn.ConnectionState()
 	opts := x509.VerifyOptions{
 		DNSName:       hostname, // note: here we're using the real hostname
 		Intermediates: x509.NewCertPool(),
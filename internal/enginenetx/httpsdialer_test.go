@@ -2,9 +2,13 @@ package enginenetx
 
 import (
 	"context"
+	"crypto/ecdh"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/binary"
 	"errors"
+	"net"
 	"net/url"
 	"testing"
 	"time"
@@ -55,6 +59,11 @@ func (*httpsDialerCancelingContextStatsTracker) OnTLSHandshakeError(ctx context.
 	// nothing
 }
 
+// OnQUICHandshakeError implements httpsDialerEventsHandler.
+func (*httpsDialerCancelingContextStatsTracker) OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error) {
+	// nothing
+}
+
 // OnTLSVerifyError implements httpsDialerEventsHandler.
 func (*httpsDialerCancelingContextStatsTracker) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	// nothing
@@ -476,6 +485,55 @@ func TestHTTPSDialerTactic(t *testing.T) {
 			t.Fatal(diff)
 		}
 	})
+
+	t.Run("String with QUIC", func(t *testing.T) {
+		expected := `{"Address":"162.55.247.208","InitialDelay":150000000,"Port":"443","SNI":"www.example.com","Transport":"quic","VerifyHostname":"api.ooni.io"}`
+		ldt := &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			InitialDelay:   150 * time.Millisecond,
+			Port:           "443",
+			SNI:            "www.example.com",
+			Transport:      httpsDialerTransportQUIC,
+			VerifyHostname: "api.ooni.io",
+		}
+		got := ldt.String()
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("Summary with domain fronting and ECH", func(t *testing.T) {
+		expected := `151.101.1.1:443 sni=www.example.com verify=www.example.com host=ooni-api.cdn.example ech=2e39f750`
+		ldt := &httpsDialerTactic{
+			Address:        "151.101.1.1",
+			ECHConfigList:  []byte{0, 4, 1, 2, 3, 4},
+			HTTPHost:       "ooni-api.cdn.example",
+			InitialDelay:   150 * time.Millisecond,
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "www.example.com",
+		}
+		got := ldt.tacticSummaryKey()
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("Summary with QUIC", func(t *testing.T) {
+		expected := `162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic`
+		ldt := &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			InitialDelay:   150 * time.Millisecond,
+			Port:           "443",
+			SNI:            "www.example.com",
+			Transport:      httpsDialerTransportQUIC,
+			VerifyHostname: "api.ooni.io",
+		}
+		got := ldt.tacticSummaryKey()
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
 }
 
 // QA using the host network
@@ -525,6 +583,89 @@ func TestHTTPSDialerHostNetworkQA(t *testing.T) {
 		}
 		tlsConn.Close()
 	})
+
+	t.Run("a tactic with ECHConfigList encrypts the ClientHello", func(t *testing.T) {
+		ca := netem.MustNewCA()
+
+		// create the ECH keys and the server using them
+		echKey := runtimex.Try1(ecdh.X25519().GenerateKey(rand.Reader))
+		echConfig := httpsDialerTestMarshalECHConfig(echKey.PublicKey().Bytes(), "public.example.com")
+		serverConfig := ca.MustNewServerTLSConfig("server.local", "public.example.com")
+		serverConfig.EncryptedClientHelloKeys = []tls.EncryptedClientHelloKey{{
+			Config:     echConfig,
+			PrivateKey: echKey.Bytes(),
+		}}
+		listener := runtimex.Try1(tls.Listen("tcp", "127.0.0.1:0", serverConfig))
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.(*tls.Conn).Handshake()
+				_ = conn.Close()
+			}
+		}()
+
+		tproxy := &netxlite.DefaultTProxy{}
+		_, port := runtimex.Try2(net.SplitHostPort(listener.Addr().String()))
+
+		httpsDialer := newHTTPSDialer(
+			log.Log,
+			&netxlite.Netx{Underlying: &mocks.UnderlyingNetwork{
+				MockDefaultCertPool: func() *x509.CertPool {
+					return ca.DefaultCertPool() // just override the CA
+				},
+				MockDialTimeout: tproxy.DialTimeout,
+				MockDialContext: tproxy.DialContext,
+			}},
+			&mocksPolicy{
+				MockLookupTactics: func(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+					return streamTacticsFromSlice([]*httpsDialerTactic{{
+						Address: "127.0.0.1",
+						// an ECHConfigList is a list of ECHConfig prefixed by its length
+						ECHConfigList:  append([]byte{0, byte(len(echConfig))}, echConfig...),
+						Port:           port,
+						SNI:            "server.local",
+						VerifyHostname: "server.local",
+					}})
+				},
+			},
+			&nullStatsManager{},
+		)
+
+		tlsConn, err := httpsDialer.DialTLSContext(context.Background(), "tcp", net.JoinHostPort("server.local", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tlsConn.Close()
+		if !tlsConn.(model.TLSConn).ConnectionState().ECHAccepted {
+			t.Fatal("expected the server to accept ECH")
+		}
+	})
+}
+
+// httpsDialerTestMarshalECHConfig marshals an ECHConfig using X25519, HKDF-SHA256, and
+// AES-128-GCM, following the format described by draft-ietf-tls-esni-18.
+func httpsDialerTestMarshalECHConfig(publicKey []byte, publicName string) []byte {
+	var contents []byte
+	contents = append(contents, 1)                             // config_id
+	contents = binary.BigEndian.AppendUint16(contents, 0x0020) // kem_id: DHKEM(X25519, HKDF-SHA256)
+	contents = binary.BigEndian.AppendUint16(contents, uint16(len(publicKey)))
+	contents = append(contents, publicKey...)
+	contents = binary.BigEndian.AppendUint16(contents, 4)      // cipher_suites length
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001) // kdf_id: HKDF-SHA256
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001) // aead_id: AES-128-GCM
+	contents = append(contents, 0)                             // maximum_name_length
+	contents = append(contents, byte(len(publicName)))
+	contents = append(contents, publicName...)
+	contents = binary.BigEndian.AppendUint16(contents, 0) // extensions
+
+	var config []byte
+	config = binary.BigEndian.AppendUint16(config, 0xfe0d) // version
+	config = binary.BigEndian.AppendUint16(config, uint16(len(contents)))
+	return append(config, contents...)
 }
 
 func TestHTTPSDialerVerifyCertificateChain(t *testing.T) {
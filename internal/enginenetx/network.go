@@ -96,7 +96,7 @@ func NewNetwork(
 	// Create manager for keeping track of statistics. This implies creating a background
 	// goroutine that we'll need to close when we're done.
 	const trimInterval = 30 * time.Second
-	stats := newStatsManager(kvStore, logger, trimInterval)
+	stats := newStatsManager(kvStore, logger, trimInterval, statsDefaultHalfLife)
 
 	// Create a TLS dialer ONLY used for dialing TLS connections. This dialer will use
 	// happy-eyeballs and possibly custom policies for dialing TLS connections.
@@ -129,6 +129,22 @@ func NewNetwork(
 		netxlite.HTTPTransportOptionProxyURL(proxyURL),
 	)
 
+	// When there is no proxy, the httpsDialer may race QUIC and domain fronting tactics,
+	// hence we need to be ready to route requests over HTTP/3 when a QUIC tactic wins
+	// and to use the fronted HTTP host when a domain fronting tactic wins.
+	if proxyURL == nil {
+		txp = &networkHTTPTransport{
+			TCP:   txp,
+			HTTP3: netxlite.NewHTTP3Transport(logger, &httpsDialerQUICDialer{httpsDialer}, nil),
+			NewFronted: func(httpHost string) model.HTTPTransport {
+				return netxlite.NewHTTPTransportWithOptions(
+					logger, dialer, &httpsDialerFrontedTLSDialer{httpsDialer, httpHost},
+					netxlite.HTTPTransportOptionDisableCompression(false),
+				)
+			},
+		}
+	}
+
 	// Make sure we count the bytes sent and received as part of the session
 	txp = bytecounter.WrapHTTPTransport(txp, counter)
 
@@ -154,12 +170,36 @@ func newHTTPSDialerPolicy(
 		return &dnsPolicy{logger, resolver}
 	}
 
+	// attempt to load a user-provided dialing policy, which may also
+	// contain the fronts to use for domain fronting
+	primary, err := newUserPolicyV2(kvStore)
+
+	// on success, use the user-provided fronts in the bridges policy
+	var userFronts map[string][]*bridgesFront
+	if err == nil {
+		userFronts = primary.Root.Fronts
+	}
+	bridges := &bridgesPolicyV2{Fronts: userFronts}
+	var bridgesOrRemote httpsDialerPolicy = bridges
+
+	// attempt to load the tactics signed by the backend, which we received
+	// using the check-in API, and, on success, interleave them with the bridges
+	// while merging their fronts with the user-provided fronts
+	if remote, err := newRemotePolicy(kvStore); err == nil {
+		bridges.Fronts = remotePolicyMergeFronts(remote.Root.Fronts, userFronts)
+		bridgesOrRemote = &mixPolicyInterleave{
+			Primary:  remote,
+			Fallback: bridges,
+			Factor:   3,
+		}
+	}
+
 	// create a policy interleaving stats policies and bridges policies
 	statsOrBridges := &mixPolicyInterleave{
 		Primary: &statsPolicyV2{
 			Stats: stats,
 		},
-		Fallback: &bridgesPolicyV2{},
+		Fallback: bridgesOrRemote,
 		Factor:   3,
 	}
 
@@ -177,9 +217,6 @@ func newHTTPSDialerPolicy(
 		Factor:   3,
 	}
 
-	// attempt to load a user-provided dialing policy
-	primary, err := newUserPolicyV2(kvStore)
-
 	// on error, just use composed
 	if err != nil {
 		return composed
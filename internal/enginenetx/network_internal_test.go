@@ -316,6 +316,28 @@ func TestNewHTTPSDialerPolicyTypes(t *testing.T) {
 			extraChecks: verifyUserPolicyChain,
 		},
 
+		{
+			name: "when there is no proxy URL and there is a user policy with fronts",
+			kvStore: func() model.KeyValueStore {
+				store := &kvstore.Memory{}
+				runtimex.Try0(store.Set(userPolicyKey, []byte(`{
+					"Fronts": {"api.ooni.io": [{"Addresses": ["104.16.0.1"], "SNIs": ["www.example.org"]}]},
+					"Version": 3
+				}`)))
+				return store
+			},
+			proxyURL:   nil,
+			expectType: "*enginenetx.mixPolicyEitherOr",
+			extraChecks: func(t *testing.T, root httpsDialerPolicy) {
+				verifyUserPolicyChain(t, root)
+				composed := root.(*mixPolicyEitherOr).Fallback.(*mixPolicyInterleave)
+				bridges := composed.Fallback.(*mixPolicyInterleave).Fallback.(*bridgesPolicyV2)
+				if len(bridges.Fronts["api.ooni.io"]) != 1 {
+					t.Fatal("expected the bridges policy to use the user-provided fronts")
+				}
+			},
+		},
+
 		{
 			name: "when there is no proxy URL and there is no user policy",
 			kvStore: func() model.KeyValueStore {
@@ -325,6 +347,51 @@ func TestNewHTTPSDialerPolicyTypes(t *testing.T) {
 			expectType:  "*enginenetx.mixPolicyInterleave",
 			extraChecks: verifyNoUserPolicyChain,
 		},
+
+		{
+			name: "when there is no proxy URL and there are remote tactics",
+			kvStore: func() model.KeyValueStore {
+				priv := remotePolicyTestUseKey(t)
+				store := &kvstore.Memory{}
+				runtimex.Try0(store.Set(userPolicyKey, []byte(`{
+					"Fronts": {"api.ooni.io": [{"Addresses": ["104.16.0.1"], "SNIs": ["www.example.org"]}]},
+					"Version": 3
+				}`)))
+				tactics := remotePolicyTestSign(priv, []byte(`{
+					"Fronts": {
+						"api.ooni.io": [{"Addresses": ["104.16.0.2"], "SNIs": ["www.example.com"]}],
+						"ooni.org": [{"Addresses": ["104.16.0.3"], "SNIs": ["www.example.com"]}]
+					},
+					"Expire": "2100-01-01T00:00:00Z",
+					"Serial": 1,
+					"Version": 3
+				}`))
+				runtimex.Try0(StoreCheckInTactics(store, tactics))
+				return store
+			},
+			proxyURL:   nil,
+			expectType: "*enginenetx.mixPolicyEitherOr",
+			extraChecks: func(t *testing.T, root httpsDialerPolicy) {
+				eitherOrPolicy := root.(*mixPolicyEitherOr)
+				_ = eitherOrPolicy.Primary.(*userPolicyV2)
+				composed := eitherOrPolicy.Fallback.(*mixPolicyInterleave)
+				verifyDNSExtChain(t, composed.Primary.(*testHelpersPolicy))
+				statsOrBridges := composed.Fallback.(*mixPolicyInterleave)
+				_ = statsOrBridges.Primary.(*statsPolicyV2)
+				bridgesOrRemote := statsOrBridges.Fallback.(*mixPolicyInterleave)
+				if bridgesOrRemote.Factor != 3 {
+					t.Fatal("expected .Factory to be 3")
+				}
+				_ = bridgesOrRemote.Primary.(*remotePolicy)
+				bridges := bridgesOrRemote.Fallback.(*bridgesPolicyV2)
+				if diff := cmp.Diff([]string{"104.16.0.1"}, bridges.Fronts["api.ooni.io"][0].Addresses); diff != "" {
+					t.Fatal("expected the user-provided fronts to take precedence", diff)
+				}
+				if diff := cmp.Diff([]string{"104.16.0.3"}, bridges.Fronts["ooni.org"][0].Addresses); diff != "" {
+					t.Fatal("expected the bridges policy to use the remote fronts", diff)
+				}
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -443,7 +510,7 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 				},
 			},
 			domain:                 "api.ooni.io",
-			totalExpectedEntries:   152,
+			totalExpectedEntries:   153,
 			initialExpectedEntries: nil,
 		},
 
@@ -459,7 +526,7 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 				},
 			},
 			domain:               "api.ooni.io",
-			totalExpectedEntries: 154,
+			totalExpectedEntries: 155,
 			initialExpectedEntries: []*httpsDialerTactic{{
 				Address:        "130.192.91.211",
 				InitialDelay:   0,
@@ -659,7 +726,7 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Create manager for keeping track of statistics. This implies creating a background
 			// goroutine that we'll need to close when we're done.
-			stats := newStatsManager(tc.kvStore(), model.DiscardLogger, 24*time.Hour)
+			stats := newStatsManager(tc.kvStore(), model.DiscardLogger, 24*time.Hour, statsDefaultHalfLife)
 			defer stats.Close()
 
 			// Create a new HTTPS dialer policy.
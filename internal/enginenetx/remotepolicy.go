@@ -0,0 +1,235 @@
+package enginenetx
+
+//
+// remote policy - the possibility of loading tactics signed by the backend,
+// which we receive via the check-in API and store in the key-value store,
+// such that the backend can push new bridges without releasing new apps
+//
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// remotePolicy is an [httpsDialerPolicy] incorporating verbatim the signed
+// tactics we received from the backend using the check-in API.
+//
+// The signed payload uses the same format of the user policy, extended
+// with the fields of [remotePolicyRoot] used to expire and order payloads.
+type remotePolicy struct {
+	// Root is the root of the remote policy loaded from the kvstore.
+	Root *userPolicyRoot
+}
+
+// remotePolicyRoot is the root of the signed payload.
+type remotePolicyRoot struct {
+	// userPolicyRoot contains the tactics and the fronts.
+	userPolicyRoot
+
+	// Expire is the time after which we stop using the tactics.
+	Expire time.Time
+
+	// Serial is a number the backend increments every time it signs a new
+	// payload, such that we never replace the stored payload with an older one.
+	Serial int64
+}
+
+// newRemotePolicy attempts to construct a remote policy. The typical error case is the one
+// in which there's no remotePolicyKey in the key-value store because the check-in API did
+// not return any signed tactics. We verify the signature again when loading, such that we
+// only use tactics that were signed by the backend, and we refuse to use expired tactics.
+func newRemotePolicy(kvStore model.KeyValueStore) (*remotePolicy, error) {
+	// attempt to load and verify the signed tactics
+	root, err := remotePolicyLoad(kvStore)
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure the tactics did not expire in the meanwhile
+	if err := remotePolicyCheckExpire(root, time.Now()); err != nil {
+		return nil, err
+	}
+
+	out := &remotePolicy{Root: &root.userPolicyRoot}
+	return out, nil
+}
+
+var _ httpsDialerPolicy = &remotePolicy{}
+
+// LookupTactics implements httpsDialerPolicy.
+func (rp *remotePolicy) LookupTactics(ctx context.Context, domain string, port string) <-chan *httpsDialerTactic {
+	// the lookup algorithm is the same of the user policy
+	child := &userPolicyV2{Root: rp.Root}
+	return child.LookupTactics(ctx, domain, port)
+}
+
+// StoreCheckInTactics verifies the signed tactics returned by the check-in API
+// and, on success, stores them into the given key-value store, such that the
+// next [*Network] we create would use them. We refuse to store expired tactics
+// and tactics whose serial is lower than the serial of the stored tactics, while
+// we leave the stored tactics untouched when the serial is the same. When the
+// check-in API does not return any tactics, this function deletes the stored
+// tactics, such that the backend can withdraw previously pushed tactics.
+func StoreCheckInTactics(kvStore model.KeyValueStore, tactics *model.OOAPICheckInResultTactics) error {
+	// the check-in API does not return any tactics by default
+	if tactics == nil {
+		return kvStore.Delete(remotePolicyKey)
+	}
+
+	// make sure we do not store tactics not signed by the backend
+	root, err := remotePolicyVerify(tactics)
+	if err != nil {
+		return err
+	}
+
+	// make sure we do not store expired tactics
+	if err := remotePolicyCheckExpire(root, time.Now()); err != nil {
+		return err
+	}
+
+	// make sure we do not replace the stored tactics with older tactics, which
+	// we skip when the stored tactics are missing or not valid anymore
+	if prev, err := remotePolicyLoad(kvStore); err == nil {
+		if root.Serial < prev.Serial {
+			err := fmt.Errorf(
+				"%s: %w: stored=%d got=%d",
+				remotePolicyKey,
+				errRemotePolicyOlderSerial,
+				prev.Serial,
+				root.Serial,
+			)
+			return err
+		}
+		if root.Serial == prev.Serial {
+			return nil
+		}
+	}
+
+	// store the signed tactics into the key-value store
+	data, err := json.Marshal(tactics)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	return kvStore.Set(remotePolicyKey, data)
+}
+
+// remotePolicyLoad loads the signed tactics from the kvstore and returns the parsed
+// payload after verifying the signature. It does not check whether the tactics expired.
+func remotePolicyLoad(kvStore model.KeyValueStore) (*remotePolicyRoot, error) {
+	// attempt to read the signed tactics from the kvstore
+	data, err := kvStore.Get(remotePolicyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// attempt to parse the signed tactics
+	var tactics model.OOAPICheckInResultTactics
+	if err := json.Unmarshal(data, &tactics); err != nil {
+		return nil, err
+	}
+
+	// verify the signature and parse the payload
+	return remotePolicyVerify(&tactics)
+}
+
+// remotePolicyVerify verifies the signature of the given tactics and returns the parsed payload.
+func remotePolicyVerify(tactics *model.OOAPICheckInResultTactics) (*remotePolicyRoot, error) {
+	// make sure the backend signed the payload
+	if !remotePolicyVerifySignature(tactics) {
+		return nil, errRemotePolicyInvalidSignature
+	}
+
+	// attempt to parse the signed payload
+	var root remotePolicyRoot
+	if err := json.Unmarshal(tactics.Payload, &root); err != nil {
+		return nil, err
+	}
+
+	// make sure the version is OK
+	if root.Version != userPolicyVersion {
+		err := fmt.Errorf(
+			"%s: %w: expected=%d got=%d",
+			remotePolicyKey,
+			errUserPolicyWrongVersion,
+			userPolicyVersion,
+			root.Version,
+		)
+		return nil, err
+	}
+
+	return &root, nil
+}
+
+// remotePolicyVerifySignature returns whether any of the trusted public keys
+// verifies the signature of the given tactics.
+func remotePolicyVerifySignature(tactics *model.OOAPICheckInResultTactics) bool {
+	for _, key := range remotePolicyPublicKeys {
+		if ed25519.Verify(key, tactics.Payload, tactics.Signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// remotePolicyCheckExpire returns an error if the given payload expired at the given
+// time. A payload without expiration is expired, such that the backend must always
+// explicitly choose how long we should keep using the tactics it signed.
+func remotePolicyCheckExpire(root *remotePolicyRoot, now time.Time) error {
+	if !now.Before(root.Expire) {
+		err := fmt.Errorf(
+			"%s: %w: expire=%s",
+			remotePolicyKey,
+			errRemotePolicyExpired,
+			root.Expire.Format(time.RFC3339),
+		)
+		return err
+	}
+	return nil
+}
+
+// remotePolicyKey is the kvstore key used to retrieve the signed tactics.
+const remotePolicyKey = "remotebridges.state"
+
+// errRemotePolicyInvalidSignature means that the backend did not sign the tactics.
+var errRemotePolicyInvalidSignature = errors.New("invalid remote policy signature")
+
+// errRemotePolicyExpired means that the signed tactics expired.
+var errRemotePolicyExpired = errors.New("expired remote policy")
+
+// errRemotePolicyOlderSerial means that the signed tactics are older than the stored ones.
+var errRemotePolicyOlderSerial = errors.New("remote policy serial older than the stored one")
+
+// remotePolicyPublicKeys contains the ed25519 public keys we trust for signing tactics.
+//
+// The OONI backend team generates the key pairs and keeps the private keys offline,
+// such that neither this repository nor the check-in servers contain them. The list
+// is empty until the backend team provides us with the production key, therefore we
+// currently reject all the signed tactics and remote tactics are disabled.
+//
+// To rotate a key, the backend team adds the new public key to this list and, once
+// the releases trusting the new key have replaced the ones that don't, starts signing
+// with the new private key, after which we remove the old public key. Note that older
+// releases keep trusting the keys embedded at build time, therefore a compromised key
+// remains a risk for them until they are updated.
+var remotePolicyPublicKeys = []ed25519.PublicKey{}
+
+// remotePolicyMergeFronts returns the fronts to use for domain fronting, where
+// the fronts configured by the user take precedence over the remote fronts.
+func remotePolicyMergeFronts(remote, user map[string][]*bridgesFront) map[string][]*bridgesFront {
+	if len(remote) <= 0 {
+		return user
+	}
+	out := make(map[string][]*bridgesFront)
+	for domain, fronts := range remote {
+		out[domain] = fronts
+	}
+	for domain, fronts := range user {
+		out[domain] = fronts
+	}
+	return out
+}
@@ -0,0 +1,364 @@
+package enginenetx
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// remotePolicyTestUseKey replaces the remote policy public keys with a
+// freshly generated key for the duration of the test and returns the
+// corresponding private key used to sign tactics.
+func remotePolicyTestUseKey(t *testing.T) ed25519.PrivateKey {
+	pub, priv := runtimex.Try2(ed25519.GenerateKey(nil))
+	orig := remotePolicyPublicKeys
+	remotePolicyPublicKeys = []ed25519.PublicKey{pub}
+	t.Cleanup(func() {
+		remotePolicyPublicKeys = orig
+	})
+	return priv
+}
+
+// remotePolicyTestSign signs the given payload using the given private key.
+func remotePolicyTestSign(priv ed25519.PrivateKey, payload []byte) *model.OOAPICheckInResultTactics {
+	return &model.OOAPICheckInResultTactics{
+		Payload:   payload,
+		Signature: ed25519.Sign(priv, payload),
+	}
+}
+
+func TestRemotePolicy(t *testing.T) {
+	// remoteRoot is the remote policy root used by most tests
+	remoteRoot := &remotePolicyRoot{
+		userPolicyRoot: userPolicyRoot{
+			DomainEndpoints: map[string][]*httpsDialerTactic{
+				"api.ooni.io:443": {{
+					Address:        "162.55.247.208",
+					InitialDelay:   0,
+					Port:           "443",
+					SNI:            "www.example.com",
+					VerifyHostname: "api.ooni.io",
+				}},
+			},
+			Fronts: map[string][]*bridgesFront{
+				"api.ooni.io": {{
+					Addresses: []string{"104.16.0.1"},
+					SNIs:      []string{"www.example.org"},
+				}},
+			},
+			Version: userPolicyVersion,
+		},
+		Expire: time.Now().Add(24 * time.Hour),
+		Serial: 10,
+	}
+	remotePayload := runtimex.Try1(json.Marshal(remoteRoot))
+
+	// remotePayloadWith returns a payload derived from remoteRoot after applying the given function
+	remotePayloadWith := func(fx func(root *remotePolicyRoot)) []byte {
+		root := *remoteRoot
+		fx(&root)
+		return runtimex.Try1(json.Marshal(&root))
+	}
+
+	t.Run("StoreCheckInTactics", func(t *testing.T) {
+		t.Run("deletes the stored tactics when there are no tactics", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)))
+			if err := StoreCheckInTactics(kvStore, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := kvStore.Get(remotePolicyKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+
+		t.Run("does not store tactics with an invalid signature", func(t *testing.T) {
+			_ = remotePolicyTestUseKey(t)
+			_, otherPriv := runtimex.Try2(ed25519.GenerateKey(nil))
+			kvStore := &kvstore.Memory{}
+			err := StoreCheckInTactics(kvStore, remotePolicyTestSign(otherPriv, remotePayload))
+			if !errors.Is(err, errRemotePolicyInvalidSignature) {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err := kvStore.Get(remotePolicyKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+
+		t.Run("does not store tactics without trusted public keys", func(t *testing.T) {
+			// Note: we do not call remotePolicyTestUseKey here because we want
+			// to make sure that the keys we ship disable the remote tactics.
+			_, priv := runtimex.Try2(ed25519.GenerateKey(nil))
+			kvStore := &kvstore.Memory{}
+			err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload))
+			if !errors.Is(err, errRemotePolicyInvalidSignature) {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err := kvStore.Get(remotePolicyKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+
+		t.Run("does not store tactics with an invalid payload", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, []byte(`{`)))
+			if err == nil || err.Error() != "unexpected end of JSON input" {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err := kvStore.Get(remotePolicyKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+
+		t.Run("does not store tactics with the wrong version", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, []byte(`{"Version":1}`)))
+			if !errors.Is(err, errUserPolicyWrongVersion) {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err := kvStore.Get(remotePolicyKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+
+		t.Run("does not store expired tactics", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			payload := remotePayloadWith(func(root *remotePolicyRoot) {
+				root.Expire = time.Now().Add(-time.Hour)
+			})
+			err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, payload))
+			if !errors.Is(err, errRemotePolicyExpired) {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err := kvStore.Get(remotePolicyKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+
+		t.Run("does not store tactics without expiration", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			payload := remotePayloadWith(func(root *remotePolicyRoot) {
+				root.Expire = time.Time{}
+			})
+			err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, payload))
+			if !errors.Is(err, errRemotePolicyExpired) {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err := kvStore.Get(remotePolicyKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+
+		t.Run("does not replace the stored tactics with older tactics", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)))
+			stored := runtimex.Try1(kvStore.Get(remotePolicyKey))
+			payload := remotePayloadWith(func(root *remotePolicyRoot) {
+				root.Serial = 9
+			})
+			err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, payload))
+			if !errors.Is(err, errRemotePolicyOlderSerial) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(stored, runtimex.Try1(kvStore.Get(remotePolicyKey))); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("leaves the stored tactics untouched given the same serial", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)))
+			stored := runtimex.Try1(kvStore.Get(remotePolicyKey))
+			payload := remotePayloadWith(func(root *remotePolicyRoot) {
+				root.Fronts = nil
+			})
+			if err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, payload)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(stored, runtimex.Try1(kvStore.Get(remotePolicyKey))); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("replaces the stored tactics with newer tactics", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)))
+			payload := remotePayloadWith(func(root *remotePolicyRoot) {
+				root.Fronts = nil
+				root.Serial = 11
+			})
+			if err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, payload)); err != nil {
+				t.Fatal(err)
+			}
+			policy := runtimex.Try1(newRemotePolicy(kvStore))
+			if len(policy.Root.Fronts) != 0 {
+				t.Fatal("expected the newer tactics")
+			}
+		})
+
+		t.Run("replaces stored tactics that are not valid anymore", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(kvStore.Set(remotePolicyKey, []byte(`{`)))
+			if err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := newRemotePolicy(kvStore); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		t.Run("stores valid tactics", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			kvStore := &kvstore.Memory{}
+			if err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)); err != nil {
+				t.Fatal(err)
+			}
+			policy, err := newRemotePolicy(kvStore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(&remoteRoot.userPolicyRoot, policy.Root); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("stores tactics signed with any of the trusted keys", func(t *testing.T) {
+			_ = remotePolicyTestUseKey(t)
+			pub, priv := runtimex.Try2(ed25519.GenerateKey(nil))
+			remotePolicyPublicKeys = append(remotePolicyPublicKeys, pub)
+			kvStore := &kvstore.Memory{}
+			if err := StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := newRemotePolicy(kvStore); err != nil {
+				t.Fatal(err)
+			}
+		})
+	})
+
+	t.Run("newRemotePolicy", func(t *testing.T) {
+		t.Run("when there are no stored tactics", func(t *testing.T) {
+			policy, err := newRemotePolicy(&kvstore.Memory{})
+			if !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+			if policy != nil {
+				t.Fatal("expected nil policy")
+			}
+		})
+
+		t.Run("when the stored tactics are not valid JSON", func(t *testing.T) {
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(kvStore.Set(remotePolicyKey, []byte(`{`)))
+			policy, err := newRemotePolicy(kvStore)
+			if err == nil || err.Error() != "unexpected end of JSON input" {
+				t.Fatal("unexpected error", err)
+			}
+			if policy != nil {
+				t.Fatal("expected nil policy")
+			}
+		})
+
+		t.Run("when the stored tactics expired", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			payload := remotePayloadWith(func(root *remotePolicyRoot) {
+				root.Expire = time.Now().Add(-time.Hour)
+			})
+			kvStore := &kvstore.Memory{}
+			tactics := remotePolicyTestSign(priv, payload)
+			runtimex.Try0(kvStore.Set(remotePolicyKey, runtimex.Try1(json.Marshal(tactics))))
+			policy, err := newRemotePolicy(kvStore)
+			if !errors.Is(err, errRemotePolicyExpired) {
+				t.Fatal("unexpected error", err)
+			}
+			if policy != nil {
+				t.Fatal("expected nil policy")
+			}
+		})
+
+		t.Run("when the stored tactics have been tampered with", func(t *testing.T) {
+			priv := remotePolicyTestUseKey(t)
+			tactics := remotePolicyTestSign(priv, remotePayload)
+			tactics.Payload = []byte(`{"Version":3}`)
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(kvStore.Set(remotePolicyKey, runtimex.Try1(json.Marshal(tactics))))
+			policy, err := newRemotePolicy(kvStore)
+			if !errors.Is(err, errRemotePolicyInvalidSignature) {
+				t.Fatal("unexpected error", err)
+			}
+			if policy != nil {
+				t.Fatal("expected nil policy")
+			}
+		})
+	})
+
+	t.Run("LookupTactics", func(t *testing.T) {
+		priv := remotePolicyTestUseKey(t)
+		kvStore := &kvstore.Memory{}
+		runtimex.Try0(StoreCheckInTactics(kvStore, remotePolicyTestSign(priv, remotePayload)))
+		policy := runtimex.Try1(newRemotePolicy(kvStore))
+
+		t.Run("with an entry for the domain endpoint", func(t *testing.T) {
+			var got []*httpsDialerTactic
+			for tactic := range policy.LookupTactics(context.Background(), "api.ooni.io", "443") {
+				got = append(got, tactic)
+			}
+			if diff := cmp.Diff(remoteRoot.DomainEndpoints["api.ooni.io:443"], got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("without an entry for the domain endpoint", func(t *testing.T) {
+			var got []*httpsDialerTactic
+			for tactic := range policy.LookupTactics(context.Background(), "www.example.com", "443") {
+				got = append(got, tactic)
+			}
+			if len(got) != 0 {
+				t.Fatal("expected zero tactics")
+			}
+		})
+	})
+
+	t.Run("remotePolicyMergeFronts", func(t *testing.T) {
+		remote := map[string][]*bridgesFront{
+			"api.ooni.io": {{Addresses: []string{"104.16.0.1"}, SNIs: []string{"www.example.org"}}},
+			"ooni.org":    {{Addresses: []string{"104.16.0.2"}, SNIs: []string{"www.example.org"}}},
+		}
+		user := map[string][]*bridgesFront{
+			"api.ooni.io": {{Addresses: []string{"151.101.1.1"}, SNIs: []string{"www.example.com"}}},
+		}
+
+		t.Run("without remote fronts", func(t *testing.T) {
+			if diff := cmp.Diff(user, remotePolicyMergeFronts(nil, user)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("with remote and user fronts", func(t *testing.T) {
+			expect := map[string][]*bridgesFront{
+				"api.ooni.io": user["api.ooni.io"],
+				"ooni.org":    remote["ooni.org"],
+			}
+			if diff := cmp.Diff(expect, remotePolicyMergeFronts(remote, user)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+}
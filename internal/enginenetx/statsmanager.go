@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"sort"
 	"sync"
@@ -44,12 +45,20 @@ func (*nullStatsManager) OnTLSHandshakeError(ctx context.Context, tactic *httpsD
 	// nothing
 }
 
+// OnQUICHandshakeError implements httpsDialerEventsHandler.
+func (*nullStatsManager) OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error) {
+	// nothing
+}
+
 // OnTLSVerifyError implements httpsDialerEventsHandler.
 func (*nullStatsManager) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	// nothing
 }
 
 // statsTactic keeps stats about an [*httpsDialerTactic].
+//
+// Because the tactic's transport is part of its summary key, we keep
+// distinct stats, and hence success rates, for each transport.
 type statsTactic struct {
 	// CountStarted counts the number of operations we started.
 	CountStarted int64
@@ -69,9 +78,27 @@ type statsTactic struct {
 	// CountTLSVerificationError counts the number of TLS verification errors.
 	CountTLSVerificationError int64
 
+	// CountQUICHandshakeError counts the number of QUIC handshake errors.
+	CountQUICHandshakeError int64 `json:",omitempty"`
+
+	// CountQUICHandshakeInterrupt counts the number of interrupted QUIC handshakes.
+	CountQUICHandshakeInterrupt int64 `json:",omitempty"`
+
 	// CountSuccess counts the number of successes.
 	CountSuccess int64
 
+	// DecayedCountStarted is like CountStarted except that the weight of
+	// each operation halves every half-life, such that we give more importance
+	// to the recent operations when computing the success rate.
+	DecayedCountStarted float64 `json:",omitempty"`
+
+	// DecayedCountSuccess is like DecayedCountStarted but for successes.
+	DecayedCountSuccess float64 `json:",omitempty"`
+
+	// DecayedLatency is the average latency of the successful operations
+	// where we weigh each latency like we weigh DecayedCountSuccess.
+	DecayedLatency time.Duration `json:",omitempty"`
+
 	// HistoTCPConnectError contains an histogram of TCP connect errors.
 	HistoTCPConnectError map[string]int64
 
@@ -81,6 +108,9 @@ type statsTactic struct {
 	// HistoTLSVerificationError contains an histogram of TLS verification errors.
 	HistoTLSVerificationError map[string]int64
 
+	// HistoQUICHandshakeError contains an histogram of QUIC handshake errors.
+	HistoQUICHandshakeError map[string]int64 `json:",omitempty"`
+
 	// LastUpdated is the last time we updated this record.
 	LastUpdated time.Time
 
@@ -100,6 +130,25 @@ func statsNilSafeSuccessRate(t *statsTactic) (rate float64) {
 	return
 }
 
+// statsNilSafeScore is a convenience function for computing the time-decayed success
+// rate, which falls back to the success rate when the time-decayed counters are zero
+// (e.g., because a user edited the stats on disk) and returns zero when t is nil.
+func statsNilSafeScore(t *statsTactic) (score float64) {
+	if t != nil && t.DecayedCountStarted > 0 {
+		return t.DecayedCountSuccess / t.DecayedCountStarted
+	}
+	return statsNilSafeSuccessRate(t)
+}
+
+// statsNilSafeDecayedLatency is a convenience function for getting the .DecayedLatency
+// field that takes into account the case where t is nil.
+func statsNilSafeDecayedLatency(t *statsTactic) (output time.Duration) {
+	if t != nil {
+		output = t.DecayedLatency
+	}
+	return
+}
+
 // statsNilSafeLastUpdated is a convenience function for getting the .LastUpdated
 // field that takes into account the case where t is nil.
 func statsNilSafeLastUpdated(t *statsTactic) (output time.Time) {
@@ -124,11 +173,17 @@ func statsNilSafeCountSuccess(t *statsTactic) (output int64) {
 //
 // The sorting criteria takes into account:
 //
-// 1. the success rate; or
+// 1. the time-decayed success rate; or
+//
+// 2. the time-decayed latency, when we know it for both entries; or
+//
+// 3. the number of successes; or
 //
-// 2. the last updated time; or
+// 4. the last updated time.
 //
-// 3. the number of successes.
+// We use the time-decayed success rate because, with the cumulative success
+// rate, a tactic that worked many times in the past but is blocked now would
+// be tried first for a long time before we notice it is blocked.
 //
 // The predicate allows to further restrict the returned list.
 //
@@ -148,8 +203,12 @@ func statsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(
 
 	// now let's sort work in place
 	sort.SliceStable(work, func(i, j int) bool {
-		if statsNilSafeSuccessRate(work[i]) > statsNilSafeSuccessRate(work[j]) {
-			return true
+		if scoreI, scoreJ := statsNilSafeScore(work[i]), statsNilSafeScore(work[j]); scoreI != scoreJ {
+			return scoreI > scoreJ
+		}
+		latencyI, latencyJ := statsNilSafeDecayedLatency(work[i]), statsNilSafeDecayedLatency(work[j])
+		if latencyI > 0 && latencyJ > 0 && latencyI != latencyJ {
+			return latencyI < latencyJ
 		}
 		if statsNilSafeCountSuccess(work[i]) > statsNilSafeCountSuccess(work[j]) {
 			return true
@@ -198,19 +257,41 @@ func (st *statsTactic) Clone() *statsTactic {
 	// here we're using safe functions to clone the original struct considering
 	// that a user can edit the content on disk freely introducing nulls.
 	return &statsTactic{
-		CountStarted:               st.CountStarted,
-		CountTCPConnectError:       st.CountTCPConnectError,
-		CountTCPConnectInterrupt:   st.CountTCPConnectInterrupt,
-		CountTLSHandshakeError:     st.CountTLSHandshakeError,
-		CountTLSHandshakeInterrupt: st.CountTLSHandshakeInterrupt,
-		CountTLSVerificationError:  st.CountTLSVerificationError,
-		CountSuccess:               st.CountSuccess,
-		HistoTCPConnectError:       statsMaybeCloneMapStringInt64(st.HistoTCPConnectError),
-		HistoTLSHandshakeError:     statsMaybeCloneMapStringInt64(st.HistoTLSHandshakeError),
-		HistoTLSVerificationError:  statsMaybeCloneMapStringInt64(st.HistoTLSVerificationError),
-		LastUpdated:                st.LastUpdated,
-		Tactic:                     statsMaybeCloneTactic(st.Tactic),
+		CountStarted:                st.CountStarted,
+		CountTCPConnectError:        st.CountTCPConnectError,
+		CountTCPConnectInterrupt:    st.CountTCPConnectInterrupt,
+		CountTLSHandshakeError:      st.CountTLSHandshakeError,
+		CountTLSHandshakeInterrupt:  st.CountTLSHandshakeInterrupt,
+		CountTLSVerificationError:   st.CountTLSVerificationError,
+		CountQUICHandshakeError:     st.CountQUICHandshakeError,
+		CountQUICHandshakeInterrupt: st.CountQUICHandshakeInterrupt,
+		CountSuccess:                st.CountSuccess,
+		DecayedCountStarted:         st.DecayedCountStarted,
+		DecayedCountSuccess:         st.DecayedCountSuccess,
+		DecayedLatency:              st.DecayedLatency,
+		HistoTCPConnectError:        statsMaybeCloneMapStringInt64(st.HistoTCPConnectError),
+		HistoTLSHandshakeError:      statsMaybeCloneMapStringInt64(st.HistoTLSHandshakeError),
+		HistoTLSVerificationError:   statsMaybeCloneMapStringInt64(st.HistoTLSVerificationError),
+		HistoQUICHandshakeError:     statsMaybeCloneMapStringInt64(st.HistoQUICHandshakeError),
+		LastUpdated:                 st.LastUpdated,
+		Tactic:                      statsMaybeCloneTactic(st.Tactic),
+	}
+}
+
+// statsTacticDecay decays the time-decayed counters according to the time elapsed since
+// the .LastUpdated field and then sets .LastUpdated to now. With a zero .LastUpdated, the
+// weight of the previous operations becomes zero, which is what we want.
+//
+// We call this function every time we update the stats, such that the time-decayed
+// counters always refer to the .LastUpdated time.
+func statsTacticDecay(st *statsTactic, now time.Time, halfLife time.Duration) {
+	runtimex.Assert(halfLife > 0, "passed non-positive halfLife")
+	if elapsed := now.Sub(st.LastUpdated); elapsed > 0 {
+		weight := math.Exp2(-float64(elapsed) / float64(halfLife))
+		st.DecayedCountStarted *= weight
+		st.DecayedCountSuccess *= weight
 	}
+	st.LastUpdated = now
 }
 
 // statsDomainEndpoint contains stats associated with a domain endpoint.
@@ -275,7 +356,11 @@ func statsDomainEndpointPruneEntries(input *statsDomainEndpoint) *statsDomainEnd
 }
 
 // statsContainerVersion is the current version of [statsContainer].
-const statsContainerVersion = 5
+const statsContainerVersion = 6
+
+// statsContainerVersionWithoutDecay is the version of [statsContainer] that
+// did not include the time-decayed counters, which we can migrate.
+const statsContainerVersionWithoutDecay = 5
 
 // statsContainer is the root container for the stats.
 //
@@ -314,6 +399,27 @@ func statsContainerPruneEntries(input *statsContainer) (output *statsContainer)
 	return
 }
 
+// statsContainerMigrateFromVersionWithoutDecay migrates in place a [*statsContainer] that
+// lacks the time-decayed counters by initializing them using the cumulative counters. The
+// first update of each tactic will decay them according to their .LastUpdated time.
+func statsContainerMigrateFromVersionWithoutDecay(container *statsContainer) {
+	for _, stats := range container.DomainEndpoints {
+		// We serialize this data to disk, so we need to account for the case
+		// where a user has manually edited the JSON to add a nil value
+		if stats == nil {
+			continue
+		}
+		for _, st := range stats.Tactics {
+			if st == nil {
+				continue
+			}
+			st.DecayedCountStarted = float64(st.CountStarted)
+			st.DecayedCountSuccess = float64(st.CountSuccess)
+		}
+	}
+	container.Version = statsContainerVersion
+}
+
 // GetStatsTacticLocked returns the tactic record for the given [*statsTactic] instance.
 //
 // As the name implies, this function MUST be called while holding the [*statsManager] mutex.
@@ -371,6 +477,9 @@ type statsManager struct {
 	// container is the container container for stats
 	container *statsContainer
 
+	// halfLife is the time after which the weight of an operation halves.
+	halfLife time.Duration
+
 	// kvStore is the key-value store we're using
 	kvStore model.KeyValueStore
 
@@ -384,6 +493,10 @@ type statsManager struct {
 	// by the background goroutine that prunes.
 	pruned chan any
 
+	// started maps each tactic we're using to the time when we started
+	// using it, which allows us to compute the latency on success.
+	started map[*httpsDialerTactic]time.Time
+
 	// wg tells us when the background goroutine joined.
 	wg *sync.WaitGroup
 }
@@ -408,6 +521,11 @@ func loadStatsContainer(kvStore model.KeyValueStore) (*statsContainer, error) {
 		return nil, err
 	}
 
+	// migrate the previous version, if needed
+	if container.Version == statsContainerVersionWithoutDecay {
+		statsContainerMigrateFromVersionWithoutDecay(&container)
+	}
+
 	// make sure the version is OK
 	if container.Version != statsContainerVersion {
 		err := fmt.Errorf(
@@ -425,9 +543,21 @@ func loadStatsContainer(kvStore model.KeyValueStore) (*statsContainer, error) {
 	return pruned, nil
 }
 
+// statsDefaultHalfLife is the default half-life of the time-decayed counters.
+const statsDefaultHalfLife = 24 * time.Hour
+
 // newStatsManager constructs a new instance of [*statsManager].
-func newStatsManager(kvStore model.KeyValueStore, logger model.Logger, trimInterval time.Duration) *statsManager {
+//
+// The halfLife argument is the time after which the weight of an operation
+// halves when computing the time-decayed success rate of a tactic.
+func newStatsManager(
+	kvStore model.KeyValueStore,
+	logger model.Logger,
+	trimInterval time.Duration,
+	halfLife time.Duration,
+) *statsManager {
 	runtimex.Assert(trimInterval > 0, "passed non-positive trimInterval")
+	runtimex.Assert(halfLife > 0, "passed non-positive halfLife")
 
 	root, err := loadStatsContainer(kvStore)
 	if err != nil {
@@ -440,10 +570,12 @@ func newStatsManager(kvStore model.KeyValueStore, logger model.Logger, trimInter
 		cancel:    cancel,
 		closeOnce: sync.Once{},
 		container: root,
+		halfLife:  halfLife,
 		kvStore:   kvStore,
 		logger:    logger,
 		mu:        sync.Mutex{},
 		pruned:    make(chan any),
+		started:   map[*httpsDialerTactic]time.Time{},
 		wg:        &sync.WaitGroup{},
 	}
 
@@ -470,25 +602,31 @@ func (mt *statsManager) OnStarting(tactic *httpsDialerTactic) {
 	record, found := mt.container.GetStatsTacticLocked(tactic)
 	if !found {
 		record = &statsTactic{
-			CountStarted:               0,
-			CountTCPConnectError:       0,
-			CountTCPConnectInterrupt:   0,
-			CountTLSHandshakeError:     0,
-			CountTLSHandshakeInterrupt: 0,
-			CountTLSVerificationError:  0,
-			CountSuccess:               0,
-			HistoTCPConnectError:       map[string]int64{},
-			HistoTLSHandshakeError:     map[string]int64{},
-			HistoTLSVerificationError:  map[string]int64{},
-			LastUpdated:                time.Time{},
-			Tactic:                     tactic.Clone(), // avoid storing the original
+			CountStarted:                0,
+			CountTCPConnectError:        0,
+			CountTCPConnectInterrupt:    0,
+			CountTLSHandshakeError:      0,
+			CountTLSHandshakeInterrupt:  0,
+			CountTLSVerificationError:   0,
+			CountQUICHandshakeError:     0,
+			CountQUICHandshakeInterrupt: 0,
+			CountSuccess:                0,
+			HistoTCPConnectError:        map[string]int64{},
+			HistoTLSHandshakeError:      map[string]int64{},
+			HistoTLSVerificationError:   map[string]int64{},
+			HistoQUICHandshakeError:     map[string]int64{},
+			LastUpdated:                 time.Time{},
+			Tactic:                      tactic.Clone(), // avoid storing the original
 		}
 		mt.container.SetStatsTacticLocked(tactic, record)
 	}
 
 	// update stats
+	now := time.Now()
+	statsTacticDecay(record, now, mt.halfLife)
 	record.CountStarted++
-	record.LastUpdated = time.Now()
+	record.DecayedCountStarted++
+	mt.started[tactic] = now
 }
 
 func statsSafeIncrementMapStringInt64(input *map[string]int64, value string) {
@@ -505,6 +643,9 @@ func (mt *statsManager) OnTCPConnectError(ctx context.Context, tactic *httpsDial
 	defer mt.mu.Unlock()
 	mt.mu.Lock()
 
+	// we're not going to compute the latency for this tactic
+	delete(mt.started, tactic)
+
 	// get the record
 	record, found := mt.container.GetStatsTacticLocked(tactic)
 	if !found {
@@ -513,7 +654,7 @@ func (mt *statsManager) OnTCPConnectError(ctx context.Context, tactic *httpsDial
 	}
 
 	// update stats
-	record.LastUpdated = time.Now()
+	statsTacticDecay(record, time.Now(), mt.halfLife)
 	if ctx.Err() != nil {
 		record.CountTCPConnectInterrupt++
 		return
@@ -530,6 +671,9 @@ func (mt *statsManager) OnTLSHandshakeError(ctx context.Context, tactic *httpsDi
 	defer mt.mu.Unlock()
 	mt.mu.Lock()
 
+	// we're not going to compute the latency for this tactic
+	delete(mt.started, tactic)
+
 	// get the record
 	record, found := mt.container.GetStatsTacticLocked(tactic)
 	if !found {
@@ -538,7 +682,7 @@ func (mt *statsManager) OnTLSHandshakeError(ctx context.Context, tactic *httpsDi
 	}
 
 	// update stats
-	record.LastUpdated = time.Now()
+	statsTacticDecay(record, time.Now(), mt.halfLife)
 	if ctx.Err() != nil {
 		record.CountTLSHandshakeInterrupt++
 		return
@@ -549,12 +693,43 @@ func (mt *statsManager) OnTLSHandshakeError(ctx context.Context, tactic *httpsDi
 	statsSafeIncrementMapStringInt64(&record.HistoTLSHandshakeError, err.Error())
 }
 
+// OnQUICHandshakeError implements httpsDialerEventsHandler.
+func (mt *statsManager) OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error) {
+	// get exclusive access
+	defer mt.mu.Unlock()
+	mt.mu.Lock()
+
+	// we're not going to compute the latency for this tactic
+	delete(mt.started, tactic)
+
+	// get the record
+	record, found := mt.container.GetStatsTacticLocked(tactic)
+	if !found {
+		mt.logger.Warnf("statsManager.OnQUICHandshakeError: not found: %+v", tactic)
+		return
+	}
+
+	// update stats
+	statsTacticDecay(record, time.Now(), mt.halfLife)
+	if ctx.Err() != nil {
+		record.CountQUICHandshakeInterrupt++
+		return
+	}
+
+	runtimex.Assert(err != nil, "OnQUICHandshakeError passed a nil error")
+	record.CountQUICHandshakeError++
+	statsSafeIncrementMapStringInt64(&record.HistoQUICHandshakeError, err.Error())
+}
+
 // OnTLSVerifyError implements httpsDialerEventsHandler.
 func (mt *statsManager) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	// get exclusive access
 	defer mt.mu.Unlock()
 	mt.mu.Lock()
 
+	// we're not going to compute the latency for this tactic
+	delete(mt.started, tactic)
+
 	// get the record
 	record, found := mt.container.GetStatsTacticLocked(tactic)
 	if !found {
@@ -566,7 +741,7 @@ func (mt *statsManager) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	runtimex.Assert(err != nil, "OnTLSVerifyError passed a nil error")
 	record.CountTLSVerificationError++
 	statsSafeIncrementMapStringInt64(&record.HistoTLSVerificationError, err.Error())
-	record.LastUpdated = time.Now()
+	statsTacticDecay(record, time.Now(), mt.halfLife)
 }
 
 // OnSuccess implements httpsDialerEventsHandler.
@@ -575,6 +750,10 @@ func (mt *statsManager) OnSuccess(tactic *httpsDialerTactic) {
 	defer mt.mu.Unlock()
 	mt.mu.Lock()
 
+	// obtain the time when we started using the tactic
+	started, hasStarted := mt.started[tactic]
+	delete(mt.started, tactic)
+
 	// get the record
 	record, found := mt.container.GetStatsTacticLocked(tactic)
 	if !found {
@@ -583,8 +762,16 @@ func (mt *statsManager) OnSuccess(tactic *httpsDialerTactic) {
 	}
 
 	// update stats
+	now := time.Now()
+	statsTacticDecay(record, now, mt.halfLife)
 	record.CountSuccess++
-	record.LastUpdated = time.Now()
+	if hasStarted {
+		// update the weighted average latency before incrementing the weight
+		latency := float64(now.Sub(started))
+		weight := record.DecayedCountSuccess
+		record.DecayedLatency = time.Duration((float64(record.DecayedLatency)*weight + latency) / (weight + 1))
+	}
+	record.DecayedCountSuccess++
 }
 
 // Close implements io.Closer
@@ -93,6 +93,7 @@ func TestNetworkCollectsStats(t *testing.T) {
 				CountTLSHandshakeError:    0,
 				CountTLSVerificationError: 0,
 				CountSuccess:              0,
+				DecayedCountStarted:       1, // minus the decay while running the test
 				HistoTCPConnectError: map[string]int64{
 					"connection_refused": 1,
 				},
@@ -144,6 +145,7 @@ func TestNetworkCollectsStats(t *testing.T) {
 				CountTLSHandshakeError:    1,
 				CountTLSVerificationError: 0,
 				CountSuccess:              0,
+				DecayedCountStarted:       1, // minus the decay while running the test
 				HistoTCPConnectError:      map[string]int64{},
 				HistoTLSHandshakeError: map[string]int64{
 					"connection_reset": 1,
@@ -192,6 +194,7 @@ func TestNetworkCollectsStats(t *testing.T) {
 				CountTLSHandshakeError:    0,
 				CountTLSVerificationError: 1,
 				CountSuccess:              0,
+				DecayedCountStarted:       1, // minus the decay while running the test
 				HistoTCPConnectError:      map[string]int64{},
 				HistoTLSHandshakeError:    map[string]int64{},
 				HistoTLSVerificationError: map[string]int64{
@@ -284,6 +287,7 @@ func TestNetworkCollectsStats(t *testing.T) {
 
 			diffOptions := []cmp.Option{
 				cmpopts.IgnoreFields(statsTactic{}, "LastUpdated"),
+				cmpopts.EquateApprox(0, 0.001),
 			}
 			if diff := cmp.Diff(tc.expectStats, tactic, diffOptions...); diff != "" {
 				t.Fatal(diff)
@@ -331,7 +335,7 @@ func TestLoadStatsContainer(t *testing.T) {
 		input: func() []byte {
 			return []byte(`{"Version":1}`)
 		},
-		expectErr:  "httpsdialerstats.state: wrong stats container version: expected=5 got=1",
+		expectErr:  "httpsdialerstats.state: wrong stats container version: expected=6 got=1",
 		expectRoot: nil,
 	}, {
 		name: "on success including correct entries pruning",
@@ -502,6 +506,66 @@ func TestLoadStatsContainer(t *testing.T) {
 			},
 			Version: statsContainerVersion,
 		},
+	}, {
+		name: "on success with the version without time-decayed counters",
+		input: func() []byte {
+			root := &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io": {
+								CountStarted:           4,
+								CountTLSHandshakeError: 1,
+								CountSuccess:           3,
+								HistoTLSHandshakeError: map[string]int64{
+									"generic_timeout_error": 1,
+								},
+								LastUpdated: fourtyFiveMinutesAgo,
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									InitialDelay:   0,
+									Port:           "443",
+									SNI:            "www.example.com",
+									VerifyHostname: "api.ooni.io",
+								},
+							},
+							"162.55.247.208:443 sni=www.example.xyz verify=api.ooni.io": nil, // should be skipped because nil
+						},
+					},
+					"www.kerneltrap.org:443": nil, // this whole entry should be skipped because it's nil
+				},
+				Version: statsContainerVersionWithoutDecay,
+			}
+			return runtimex.Try1(json.Marshal(root))
+		},
+		expectErr: "",
+		expectRoot: &statsContainer{
+			DomainEndpoints: map[string]*statsDomainEndpoint{
+				"api.ooni.io:443": {
+					Tactics: map[string]*statsTactic{
+						"162.55.247.208:443 sni=www.example.com verify=api.ooni.io": {
+							CountStarted:           4,
+							CountTLSHandshakeError: 1,
+							CountSuccess:           3,
+							DecayedCountStarted:    4,
+							DecayedCountSuccess:    3,
+							HistoTLSHandshakeError: map[string]int64{
+								"generic_timeout_error": 1,
+							},
+							LastUpdated: fourtyFiveMinutesAgo,
+							Tactic: &httpsDialerTactic{
+								Address:        "162.55.247.208",
+								InitialDelay:   0,
+								Port:           "443",
+								SNI:            "www.example.com",
+								VerifyHostname: "api.ooni.io",
+							},
+						},
+					},
+				},
+			},
+			Version: statsContainerVersion,
+		},
 	}}
 
 	for _, tc := range cases {
@@ -729,6 +793,165 @@ func TestStatsManagerCallbacks(t *testing.T) {
 			},
 		},
 
+		// When QUIC handshake fails and the reason is a canceled context
+		{
+			name: "OnQUICHandshakeError with ctx.Error() != nil",
+			initialRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted: 1,
+								LastUpdated:  fourtyFiveMinutesAgo,
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									InitialDelay:   0,
+									Port:           "443",
+									SNI:            "www.example.com",
+									Transport:      "quic",
+									VerifyHostname: "api.ooni.io",
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+			do: func(stats *statsManager) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel() // immediately!
+
+				tactic := &httpsDialerTactic{
+					Address:        "162.55.247.208",
+					InitialDelay:   0,
+					Port:           "443",
+					SNI:            "www.example.com",
+					Transport:      "quic",
+					VerifyHostname: "api.ooni.io",
+				}
+				err := errors.New("generic_timeout_error")
+
+				stats.OnQUICHandshakeError(ctx, tactic, err)
+			},
+			expectWarnf: 0,
+			expectRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted:                1,
+								CountQUICHandshakeInterrupt: 1,
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									InitialDelay:   0,
+									Port:           "443",
+									SNI:            "www.example.com",
+									Transport:      "quic",
+									VerifyHostname: "api.ooni.io",
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+		},
+
+		// When QUIC handshake fails for a reason other than a canceled context
+		{
+			name: "OnQUICHandshakeError with ctx.Error() == nil",
+			initialRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted: 1,
+								LastUpdated:  fourtyFiveMinutesAgo,
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									InitialDelay:   0,
+									Port:           "443",
+									SNI:            "www.example.com",
+									Transport:      "quic",
+									VerifyHostname: "api.ooni.io",
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+			do: func(stats *statsManager) {
+				ctx := context.Background()
+
+				tactic := &httpsDialerTactic{
+					Address:        "162.55.247.208",
+					InitialDelay:   0,
+					Port:           "443",
+					SNI:            "www.example.com",
+					Transport:      "quic",
+					VerifyHostname: "api.ooni.io",
+				}
+				err := errors.New("generic_timeout_error")
+
+				stats.OnQUICHandshakeError(ctx, tactic, err)
+			},
+			expectWarnf: 0,
+			expectRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted:            1,
+								CountQUICHandshakeError: 1,
+								HistoQUICHandshakeError: map[string]int64{
+									"generic_timeout_error": 1,
+								},
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									InitialDelay:   0,
+									Port:           "443",
+									SNI:            "www.example.com",
+									Transport:      "quic",
+									VerifyHostname: "api.ooni.io",
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+		},
+
+		// When QUIC handshake fails and we don't already have a policy record
+		{
+			name: "OnQUICHandshakeError when we are missing the stats record for the domain",
+			initialRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{},
+				Version:         statsContainerVersion,
+			},
+			do: func(stats *statsManager) {
+				ctx := context.Background()
+
+				tactic := &httpsDialerTactic{
+					Address:        "162.55.247.208",
+					InitialDelay:   0,
+					Port:           "443",
+					SNI:            "www.example.com",
+					Transport:      "quic",
+					VerifyHostname: "api.ooni.io",
+				}
+				err := errors.New("generic_timeout_error")
+
+				stats.OnQUICHandshakeError(ctx, tactic, err)
+			},
+			expectWarnf: 1,
+			expectRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{},
+				Version:         statsContainerVersion,
+			},
+		},
+
 		// When TLS verification fails and we don't already have a policy record
 		{
 			name: "OnTLSVerifyError when we are missing the stats record for the domain",
@@ -799,7 +1022,7 @@ func TestStatsManagerCallbacks(t *testing.T) {
 
 			// create the stats manager
 			const trimInterval = 30 * time.Second
-			stats := newStatsManager(kvStore, logger, trimInterval)
+			stats := newStatsManager(kvStore, logger, trimInterval, statsDefaultHalfLife)
 			defer stats.Close()
 
 			// invoke the proper stats callback
@@ -922,7 +1145,7 @@ func TestStatsManagerLookupTactics(t *testing.T) {
 
 	// create the stats manager
 	const trimInterval = 30 * time.Second
-	stats := newStatsManager(kvStore, log.Log, trimInterval)
+	stats := newStatsManager(kvStore, log.Log, trimInterval, statsDefaultHalfLife)
 	defer stats.Close()
 
 	t.Run("when we're searching for a domain endpoint we know about", func(t *testing.T) {
@@ -1094,6 +1317,222 @@ func TestStatsNilSafeSuccessRate(t *testing.T) {
 	})
 }
 
+func TestStatsNilSafeScore(t *testing.T) {
+	t.Run("with nil entry", func(t *testing.T) {
+		var st *statsTactic
+		if statsNilSafeScore(st) != 0 {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("with non-nil entry without time-decayed counters", func(t *testing.T) {
+		st := &statsTactic{
+			CountStarted: 10,
+			CountSuccess: 5,
+		}
+		if statsNilSafeScore(st) != 0.5 {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("with non-nil entry with time-decayed counters", func(t *testing.T) {
+		st := &statsTactic{
+			CountStarted:        10,
+			CountSuccess:        5,
+			DecayedCountStarted: 2,
+			DecayedCountSuccess: 0.5,
+		}
+		if statsNilSafeScore(st) != 0.25 {
+			t.Fatal("unexpected result")
+		}
+	})
+}
+
+func TestStatsNilSafeDecayedLatency(t *testing.T) {
+	t.Run("with nil entry", func(t *testing.T) {
+		var st *statsTactic
+		if statsNilSafeDecayedLatency(st) != 0 {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("with non-nil entry", func(t *testing.T) {
+		st := &statsTactic{
+			DecayedLatency: time.Second,
+		}
+		if statsNilSafeDecayedLatency(st) != time.Second {
+			t.Fatal("unexpected result")
+		}
+	})
+}
+
+func TestStatsTacticDecay(t *testing.T) {
+	now := time.Now()
+
+	t.Run("when a half-life has elapsed", func(t *testing.T) {
+		st := &statsTactic{
+			DecayedCountStarted: 4,
+			DecayedCountSuccess: 2,
+			DecayedLatency:      time.Second,
+			LastUpdated:         now.Add(-statsDefaultHalfLife),
+		}
+		statsTacticDecay(st, now, statsDefaultHalfLife)
+		expect := &statsTactic{
+			DecayedCountStarted: 2,
+			DecayedCountSuccess: 1,
+			DecayedLatency:      time.Second, // we only weigh latencies
+			LastUpdated:         now,
+		}
+		if diff := cmp.Diff(expect, st); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the last updated time is zero", func(t *testing.T) {
+		st := &statsTactic{
+			DecayedCountStarted: 4,
+			DecayedCountSuccess: 2,
+		}
+		statsTacticDecay(st, now, statsDefaultHalfLife)
+		if st.DecayedCountStarted != 0 || st.DecayedCountSuccess != 0 {
+			t.Fatal("expected the counters to be zero")
+		}
+		if !st.LastUpdated.Equal(now) {
+			t.Fatal("expected the last updated time to be now")
+		}
+	})
+
+	t.Run("when the last updated time is in the future", func(t *testing.T) {
+		// this could happen if the clock jumps backwards
+		st := &statsTactic{
+			DecayedCountStarted: 4,
+			DecayedCountSuccess: 2,
+			LastUpdated:         now.Add(time.Hour),
+		}
+		statsTacticDecay(st, now, statsDefaultHalfLife)
+		if st.DecayedCountStarted != 4 || st.DecayedCountSuccess != 2 {
+			t.Fatal("expected the counters to be unchanged")
+		}
+		if !st.LastUpdated.Equal(now) {
+			t.Fatal("expected the last updated time to be now")
+		}
+	})
+}
+
+// Make sure that OnStarting and OnSuccess update the time-decayed counters
+// and the time-decayed latency, while errors only decay the counters.
+func TestStatsManagerTimeDecay(t *testing.T) {
+	tactic := &httpsDialerTactic{
+		Address:        "162.55.247.208",
+		InitialDelay:   0,
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "api.ooni.io",
+	}
+
+	// createStatsManager creates a stats manager containing stats for
+	// the tactic that we last updated one half-life ago
+	createStatsManager := func() *statsManager {
+		container := &statsContainer{
+			DomainEndpoints: map[string]*statsDomainEndpoint{
+				"api.ooni.io:443": {
+					Tactics: map[string]*statsTactic{
+						tactic.tacticSummaryKey(): {
+							CountStarted:        4,
+							CountSuccess:        4,
+							DecayedCountStarted: 4,
+							DecayedCountSuccess: 4,
+							DecayedLatency:      3 * time.Second,
+							LastUpdated:         time.Now().Add(-statsDefaultHalfLife),
+							Tactic:              tactic.Clone(),
+						},
+					},
+				},
+			},
+			Version: statsContainerVersion,
+		}
+		kvStore := &kvstore.Memory{}
+		runtimex.Try0(kvStore.Set(statsKey, runtimex.Try1(json.Marshal(container))))
+		return newStatsManager(kvStore, model.DiscardLogger, 30*time.Second, statsDefaultHalfLife)
+	}
+
+	// getStats returns the stats for the tactic
+	getStats := func(stats *statsManager) *statsTactic {
+		tactics, good := stats.LookupTactics("api.ooni.io", "443")
+		if !good || len(tactics) != 1 {
+			t.Fatal("expected a single tactic")
+		}
+		return tactics[0]
+	}
+
+	t.Run("on success", func(t *testing.T) {
+		stats := createStatsManager()
+		defer stats.Close()
+
+		stats.OnStarting(tactic)
+		stats.OnSuccess(tactic)
+
+		st := getStats(stats)
+		if st.CountStarted != 5 || st.CountSuccess != 5 {
+			t.Fatal("unexpected cumulative counters", st.CountStarted, st.CountSuccess)
+		}
+		// 4 halved plus the new operation
+		if diff := cmp.Diff(3.0, st.DecayedCountStarted, cmpopts.EquateApprox(0, 0.001)); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff(3.0, st.DecayedCountSuccess, cmpopts.EquateApprox(0, 0.001)); diff != "" {
+			t.Fatal(diff)
+		}
+		// the previous latency weighs two and the new ~zero latency weighs one
+		if st.DecayedLatency < 1900*time.Millisecond || st.DecayedLatency > 2100*time.Millisecond {
+			t.Fatal("unexpected latency", st.DecayedLatency)
+		}
+		if len(stats.started) != 0 {
+			t.Fatal("expected to forget about the started tactic")
+		}
+	})
+
+	t.Run("on failure", func(t *testing.T) {
+		stats := createStatsManager()
+		defer stats.Close()
+
+		stats.OnStarting(tactic)
+		stats.OnTCPConnectError(context.Background(), tactic, errors.New("connection_refused"))
+
+		st := getStats(stats)
+		// 4 halved plus the new operation
+		if diff := cmp.Diff(3.0, st.DecayedCountStarted, cmpopts.EquateApprox(0, 0.001)); diff != "" {
+			t.Fatal(diff)
+		}
+		// 4 halved
+		if diff := cmp.Diff(2.0, st.DecayedCountSuccess, cmpopts.EquateApprox(0, 0.001)); diff != "" {
+			t.Fatal(diff)
+		}
+		if st.DecayedLatency != 3*time.Second {
+			t.Fatal("unexpected latency", st.DecayedLatency)
+		}
+		if len(stats.started) != 0 {
+			t.Fatal("expected to forget about the started tactic")
+		}
+	})
+
+	t.Run("on success without having started", func(t *testing.T) {
+		stats := createStatsManager()
+		defer stats.Close()
+
+		stats.OnSuccess(tactic)
+
+		st := getStats(stats)
+		// 4 halved plus the new operation
+		if diff := cmp.Diff(3.0, st.DecayedCountSuccess, cmpopts.EquateApprox(0, 0.001)); diff != "" {
+			t.Fatal(diff)
+		}
+		if st.DecayedLatency != 3*time.Second {
+			t.Fatal("unexpected latency", st.DecayedLatency)
+		}
+	})
+}
+
 func TestStatsNilSafeLastUpdated(t *testing.T) {
 	t.Run("with nil entry", func(t *testing.T) {
 		var st *statsTactic
@@ -1261,6 +1700,72 @@ func TestStatsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(t
 	}
 }
 
+func TestStatsDefensivelySortTacticsWithTimeDecay(t *testing.T) {
+	now := time.Now()
+
+	// newTactic returns a new tactic using the given SNI.
+	newTactic := func(sni string) *httpsDialerTactic {
+		return &httpsDialerTactic{
+			Address:        "130.192.91.211",
+			InitialDelay:   0,
+			Port:           "443",
+			SNI:            sni,
+			VerifyHostname: "shelob.polito.it",
+		}
+	}
+
+	expect := []*statsTactic{
+
+		// this one should be first because its time-decayed success rate is
+		// the highest and its latency is lower than the one of the second entry
+		{
+			CountStarted:        10,
+			CountSuccess:        8,
+			DecayedCountStarted: 2,
+			DecayedCountSuccess: 2,
+			DecayedLatency:      100 * time.Millisecond,
+			LastUpdated:         now.Add(-5 * time.Second),
+			Tactic:              newTactic("www.repubblica.it"),
+		},
+
+		// this one should be second because it has the same time-decayed
+		// success rate but a higher time-decayed latency
+		{
+			CountStarted:        10,
+			CountSuccess:        10,
+			DecayedCountStarted: 2,
+			DecayedCountSuccess: 2,
+			DecayedLatency:      300 * time.Millisecond,
+			LastUpdated:         now.Add(-5 * time.Second),
+			Tactic:              newTactic("www.ilpost.it"),
+		},
+
+		// this one should be last because it recently failed, even though
+		// it worked many times in the past and hence has a very high success rate
+		{
+			CountStarted:        103,
+			CountSuccess:        100,
+			DecayedCountStarted: 3.1,
+			DecayedCountSuccess: 0.1,
+			DecayedLatency:      50 * time.Millisecond,
+			LastUpdated:         now.Add(-2 * time.Second),
+			Tactic:              newTactic("www.polito.it"),
+		},
+	}
+
+	input := []*statsTactic{expect[2], expect[1], expect[0]}
+
+	got := statsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(
+		input, func(st *statsTactic) bool {
+			return true
+		},
+	)
+
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestStatsDomainEndpointPruneEntries(t *testing.T) {
 	t.Run("rejects tactics with empty summary, nil tactics and with nil .Tactics", func(t *testing.T) {
 		input := &statsDomainEndpoint{
@@ -1582,7 +2087,7 @@ func TestStatsContainerPruneEntries(t *testing.T) {
 func TestStatsManagerTrimEntriesConcurrently(t *testing.T) {
 	// start stats manager that trims very frequently
 	store := &kvstore.Memory{}
-	sm := newStatsManager(store, model.DiscardLogger, 1*time.Second)
+	sm := newStatsManager(store, model.DiscardLogger, 1*time.Second, statsDefaultHalfLife)
 
 	// obtain exclusive access
 	sm.mu.Lock()
@@ -1624,7 +2129,7 @@ func TestStatsManagerTrimEntriesConcurrently(t *testing.T) {
 
 	// now check what actually ended up being written; note that we expect
 	// to see empty domain endpoints because we added a too old entry
-	expectedData := []byte(`{"DomainEndpoints":{},"Version":5}`)
+	expectedData := []byte(`{"DomainEndpoints":{},"Version":6}`)
 	data, err := store.Get(statsKey)
 	if err != nil {
 		t.Fatal(err)
@@ -8,8 +8,10 @@ import (
 
 	"github.com/apex/log"
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
@@ -133,7 +135,7 @@ func TestStatsPolicyV2(t *testing.T) {
 		}
 
 		const trimInterval = 30 * time.Second
-		return newStatsManager(kvStore, log.Log, trimInterval)
+		return newStatsManager(kvStore, log.Log, trimInterval, statsDefaultHalfLife)
 	}
 
 	t.Run("when we have relevant stats", func(t *testing.T) {
@@ -277,3 +279,107 @@ func TestStatsPolicyFilterStatsTactics(t *testing.T) {
 		}
 	})
 }
+
+// This test simulates a tactic that worked many times in the past and becomes
+// blocked, showing that, thanks to the time-decayed success rate, we quickly
+// recover by trying first another tactic that works.
+func TestStatsPolicyV2RecoversAfterBlockingNetemQA(t *testing.T) {
+	threeDaysAgo := time.Now().Add(-72 * time.Hour)
+
+	// the tactic that is going to be blocked had more successes in the past
+	blockedStats := &statsTactic{
+		CountStarted:        50,
+		CountSuccess:        50,
+		DecayedCountStarted: 50,
+		DecayedCountSuccess: 50,
+		LastUpdated:         threeDaysAgo,
+		Tactic: &httpsDialerTactic{
+			Address:        netemx.AddressApiOONIIo,
+			InitialDelay:   0,
+			Port:           "443",
+			SNI:            "www.repubblica.it",
+			VerifyHostname: "api.ooni.io",
+		},
+	}
+	workingStats := &statsTactic{
+		CountStarted:        10,
+		CountSuccess:        8,
+		DecayedCountStarted: 10,
+		DecayedCountSuccess: 8,
+		LastUpdated:         threeDaysAgo,
+		Tactic: &httpsDialerTactic{
+			Address:        netemx.AddressApiOONIIo,
+			InitialDelay:   0,
+			Port:           "443",
+			SNI:            "www.kernel.org",
+			VerifyHostname: "api.ooni.io",
+		},
+	}
+
+	// create the stats manager using the above stats
+	container := &statsContainer{
+		DomainEndpoints: map[string]*statsDomainEndpoint{
+			"api.ooni.io:443": {
+				Tactics: map[string]*statsTactic{
+					blockedStats.Tactic.tacticSummaryKey(): blockedStats,
+					workingStats.Tactic.tacticSummaryKey(): workingStats,
+				},
+			},
+		},
+		Version: statsContainerVersion,
+	}
+	kvStore := &kvstore.Memory{}
+	runtimex.Try0(kvStore.Set(statsKey, runtimex.Try1(json.Marshal(container))))
+	stats := newStatsManager(kvStore, log.Log, 30*time.Second, statsDefaultHalfLife)
+	defer stats.Close()
+
+	// create the QA environment where the SNI of the first tactic is now blocked
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+	env.DPIEngine().AddRule(&netem.DPIResetTrafficForTLSSNI{
+		Logger: log.Log,
+		SNI:    "www.repubblica.it",
+	})
+
+	// create the dialer using the stats policy
+	policy := &statsPolicyV2{Stats: stats}
+	netx := &netxlite.Netx{Underlying: &netxlite.NetemUnderlyingNetworkAdapter{UNet: env.ClientStack}}
+	dialer := newHTTPSDialer(log.Log, netx, policy, stats)
+	defer dialer.CloseIdleConnections()
+
+	// firstSNI returns the SNI of the first tactic emitted by the policy
+	firstSNI := func() string {
+		for tactic := range policy.LookupTactics(context.Background(), "api.ooni.io", "443") {
+			return tactic.SNI
+		}
+		return ""
+	}
+
+	// before dialing, we would try the blocked tactic first
+	if sni := firstSNI(); sni != "www.repubblica.it" {
+		t.Fatal("unexpected first SNI before dialing", sni)
+	}
+
+	// dial once, such that the blocked tactic fails and the working one succeeds
+	conn, err := dialer.DialTLSContext(context.Background(), "tcp", "api.ooni.io:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// after a single failure, we try the working tactic first
+	if sni := firstSNI(); sni != "www.kernel.org" {
+		t.Fatal("unexpected first SNI after dialing", sni)
+	}
+
+	// whereas with the cumulative success rate we would still try the
+	// blocked tactic first, until it fails about a dozen more times
+	tactics, _ := stats.LookupTactics("api.ooni.io", "443")
+	rates := map[string]float64{}
+	for _, st := range tactics {
+		rates[st.Tactic.SNI] = statsNilSafeSuccessRate(st)
+	}
+	if rates["www.repubblica.it"] <= rates["www.kernel.org"] {
+		t.Fatal("expected the blocked tactic to have a higher cumulative success rate", rates)
+	}
+}
@@ -0,0 +1,265 @@
+package enginenetx
+
+//
+// Stats report - inspecting, pruning, and resetting the stats that
+// the [*statsManager] persists inside the key-value store
+//
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
+)
+
+// StatsReport is a human readable view of the stats about the tactics that
+// a [*Network] used for establishing connections, which we persist inside
+// the key-value store and use to choose which tactics to use first.
+type StatsReport struct {
+	// DomainEndpoints contains the domain endpoints sorted alphabetically.
+	DomainEndpoints []*StatsReportDomainEndpoint
+
+	// Version is the version of the stats data format.
+	Version int
+}
+
+// StatsReportDomainEndpoint contains stats about a domain endpoint.
+type StatsReportDomainEndpoint struct {
+	// DomainEndpoint is the domain endpoint (e.g., "api.ooni.io:443").
+	DomainEndpoint string
+
+	// Tactics contains the tactics sorted by descending time-decayed success rate.
+	Tactics []*StatsReportTactic
+}
+
+// StatsReportTactic contains stats about a tactic.
+type StatsReportTactic struct {
+	// Summary summarizes the tactic's features (e.g., address, SNI).
+	Summary string
+
+	// CountStarted counts the number of operations we started.
+	CountStarted int64
+
+	// CountSuccess counts the number of successes.
+	CountSuccess int64
+
+	// SuccessRate is the ratio between CountSuccess and CountStarted.
+	SuccessRate float64
+
+	// DecayedSuccessRate is the time-decayed success rate, which we
+	// use to choose which tactics to use first.
+	DecayedSuccessRate float64
+
+	// DecayedLatency is the time-decayed average latency of successes.
+	DecayedLatency time.Duration `json:",omitempty"`
+
+	// LastUpdated is the last time we updated the stats.
+	LastUpdated time.Time
+
+	// HistoTCPConnectError contains an histogram of TCP connect errors.
+	HistoTCPConnectError map[string]int64 `json:",omitempty"`
+
+	// HistoTLSHandshakeError contains an histogram of TLS handshake errors.
+	HistoTLSHandshakeError map[string]int64 `json:",omitempty"`
+
+	// HistoTLSVerificationError contains an histogram of TLS verification errors.
+	HistoTLSVerificationError map[string]int64 `json:",omitempty"`
+
+	// HistoQUICHandshakeError contains an histogram of QUIC handshake errors.
+	HistoQUICHandshakeError map[string]int64 `json:",omitempty"`
+
+	// tactic is the underlying tactic, which we use for redacting.
+	tactic *httpsDialerTactic
+}
+
+// LoadStatsReport loads the stats from the given [model.KeyValueStore] and returns
+// a [*StatsReport], after removing old and excess entries like [*Network] does. When
+// the key-value store does not contain any stats yet, we return an empty report.
+func LoadStatsReport(kvStore model.KeyValueStore) (*StatsReport, error) {
+	container, err := statsReportLoadContainer(kvStore)
+	if err != nil {
+		return nil, err
+	}
+	return newStatsReport(container), nil
+}
+
+// statsReportLoadContainer is like loadStatsContainer except that it returns an
+// empty [*statsContainer] when the key-value store does not contain any stats, which
+// is what happens when we have never used a [*Network] with this key-value store.
+func statsReportLoadContainer(kvStore model.KeyValueStore) (*statsContainer, error) {
+	container, err := loadStatsContainer(kvStore)
+	if errors.Is(err, kvstore.ErrNoSuchKey) {
+		return newStatsContainer(), nil
+	}
+	return container, err
+}
+
+// newStatsReport creates a [*StatsReport] from a DEEP COPY of the given [*statsContainer].
+func newStatsReport(container *statsContainer) *StatsReport {
+	report := &StatsReport{
+		DomainEndpoints: []*StatsReportDomainEndpoint{},
+		Version:         container.Version,
+	}
+	for domainEpnt, stats := range container.DomainEndpoints {
+		// be defensive because a user could have edited the stats on disk
+		if stats == nil {
+			continue
+		}
+		tactics := []*statsTactic{}
+		for _, tactic := range stats.Tactics {
+			tactics = append(tactics, tactic)
+		}
+		entry := &StatsReportDomainEndpoint{
+			DomainEndpoint: domainEpnt,
+			Tactics:        []*StatsReportTactic{},
+		}
+		tactics = statsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(
+			tactics, func(*statsTactic) bool { return true })
+		for _, st := range tactics {
+			entry.Tactics = append(entry.Tactics, &StatsReportTactic{
+				Summary:                   st.Tactic.tacticSummaryKey(),
+				CountStarted:              st.CountStarted,
+				CountSuccess:              st.CountSuccess,
+				SuccessRate:               statsNilSafeSuccessRate(st),
+				DecayedSuccessRate:        statsNilSafeScore(st),
+				DecayedLatency:            st.DecayedLatency,
+				LastUpdated:               st.LastUpdated,
+				HistoTCPConnectError:      statsMaybeCloneMapStringInt64(st.HistoTCPConnectError),
+				HistoTLSHandshakeError:    statsMaybeCloneMapStringInt64(st.HistoTLSHandshakeError),
+				HistoTLSVerificationError: statsMaybeCloneMapStringInt64(st.HistoTLSVerificationError),
+				HistoQUICHandshakeError:   statsMaybeCloneMapStringInt64(st.HistoQUICHandshakeError),
+				tactic:                    statsMaybeCloneTactic(st.Tactic),
+			})
+		}
+		report.DomainEndpoints = append(report.DomainEndpoints, entry)
+	}
+	sort.SliceStable(report.DomainEndpoints, func(i, j int) bool {
+		return report.DomainEndpoints[i].DomainEndpoint < report.DomainEndpoints[j].DomainEndpoint
+	})
+	return report
+}
+
+// Redacted returns a DEEP COPY of the [*StatsReport] without the IP addresses, which
+// may reveal the user's location, such that it is possible to attach the returned
+// report to bug reports. We keep everything else (e.g., SNIs, counters).
+func (r *StatsReport) Redacted() *StatsReport {
+	output := &StatsReport{
+		DomainEndpoints: []*StatsReportDomainEndpoint{},
+		Version:         r.Version,
+	}
+	for _, inputEpnt := range r.DomainEndpoints {
+		outputEpnt := &StatsReportDomainEndpoint{
+			DomainEndpoint: inputEpnt.DomainEndpoint,
+			Tactics:        []*StatsReportTactic{},
+		}
+		for _, input := range inputEpnt.Tactics {
+			tactic := statsMaybeCloneTactic(input.tactic)
+			summary := scrubber.ScrubString(input.Summary)
+			if tactic != nil {
+				tactic.Address = "[scrubbed]"
+				summary = tactic.tacticSummaryKey()
+			}
+			outputEpnt.Tactics = append(outputEpnt.Tactics, &StatsReportTactic{
+				Summary:                   summary,
+				CountStarted:              input.CountStarted,
+				CountSuccess:              input.CountSuccess,
+				SuccessRate:               input.SuccessRate,
+				DecayedSuccessRate:        input.DecayedSuccessRate,
+				DecayedLatency:            input.DecayedLatency,
+				LastUpdated:               input.LastUpdated,
+				HistoTCPConnectError:      statsReportScrubHisto(input.HistoTCPConnectError),
+				HistoTLSHandshakeError:    statsReportScrubHisto(input.HistoTLSHandshakeError),
+				HistoTLSVerificationError: statsReportScrubHisto(input.HistoTLSVerificationError),
+				HistoQUICHandshakeError:   statsReportScrubHisto(input.HistoQUICHandshakeError),
+				tactic:                    tactic,
+			})
+		}
+		output.DomainEndpoints = append(output.DomainEndpoints, outputEpnt)
+	}
+	return output
+}
+
+// statsReportScrubHisto returns a copy of the histogram where we have removed the
+// IP addresses from the errors, which happens for errors that we cannot classify.
+func statsReportScrubHisto(input map[string]int64) (output map[string]int64) {
+	// distinguish and preserve nil versus empty
+	if input == nil {
+		return
+	}
+	output = make(map[string]int64)
+	for key, value := range input {
+		output[scrubber.ScrubString(key)] += value
+	}
+	return
+}
+
+// WriteText writes a human readable representation of the [*StatsReport] to the given writer.
+func (r *StatsReport) WriteText(w io.Writer) {
+	for _, epnt := range r.DomainEndpoints {
+		fmt.Fprintf(w, "%s\n", epnt.DomainEndpoint)
+		for _, tactic := range epnt.Tactics {
+			fmt.Fprintf(w, "    %s\n", tactic.Summary)
+			fmt.Fprintf(
+				w, "        success rate: %.2f (%d/%d)\n",
+				tactic.SuccessRate, tactic.CountSuccess, tactic.CountStarted,
+			)
+			fmt.Fprintf(w, "        decayed success rate: %.2f\n", tactic.DecayedSuccessRate)
+			if tactic.DecayedLatency > 0 {
+				fmt.Fprintf(w, "        decayed latency: %s\n", tactic.DecayedLatency)
+			}
+			fmt.Fprintf(w, "        last updated: %s\n", tactic.LastUpdated.UTC().Format(time.RFC3339))
+			statsReportWriteHisto(w, "tcp connect errors", tactic.HistoTCPConnectError)
+			statsReportWriteHisto(w, "tls handshake errors", tactic.HistoTLSHandshakeError)
+			statsReportWriteHisto(w, "tls verification errors", tactic.HistoTLSVerificationError)
+			statsReportWriteHisto(w, "quic handshake errors", tactic.HistoQUICHandshakeError)
+		}
+	}
+}
+
+// statsReportWriteHisto writes a non-empty histogram sorted by error.
+func statsReportWriteHisto(w io.Writer, name string, histo map[string]int64) {
+	if len(histo) <= 0 {
+		return
+	}
+	fmt.Fprintf(w, "        %s:\n", name)
+	keys := make([]string, 0, len(histo))
+	for key := range histo {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "            %s: %d\n", key, histo[key])
+	}
+}
+
+// PruneStats removes old and excess entries from the stats stored inside the given
+// [model.KeyValueStore], like [*Network] does when it loads and saves the stats.
+//
+// The [*Network] overwrites the stats when closing, so you should not invoke this
+// function while a [*Network] using the same [model.KeyValueStore] is running.
+//
+// When the key-value store does not contain any stats yet, we store empty stats.
+func PruneStats(kvStore model.KeyValueStore) error {
+	// note that loadStatsContainer prunes the stats
+	container, err := statsReportLoadContainer(kvStore)
+	if err != nil {
+		return err
+	}
+	return kvStore.Set(statsKey, runtimex.Try1(json.Marshal(container)))
+}
+
+// ResetStats replaces the stats stored inside the given [model.KeyValueStore] with
+// empty stats, which is useful to start again exploring which tactics work.
+//
+// The [*Network] overwrites the stats when closing, so you should not invoke this
+// function while a [*Network] using the same [model.KeyValueStore] is running.
+func ResetStats(kvStore model.KeyValueStore) error {
+	return kvStore.Set(statsKey, runtimex.Try1(json.Marshal(newStatsContainer())))
+}
@@ -0,0 +1,303 @@
+package enginenetx
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestStatsReport(t *testing.T) {
+	lastUpdated := time.Date(2024, 4, 16, 10, 0, 0, 0, time.UTC)
+
+	// newContainer returns a container whose entries we would not prune
+	// when pretending that the current time is lastUpdated
+	newContainer := func() *statsContainer {
+		return &statsContainer{
+			DomainEndpoints: map[string]*statsDomainEndpoint{
+				"www.example.com:443": {
+					Tactics: map[string]*statsTactic{
+						"93.184.216.34:443 sni=www.example.com verify=www.example.com": {
+							CountStarted: 1,
+							CountSuccess: 1,
+							LastUpdated:  lastUpdated,
+							Tactic: &httpsDialerTactic{
+								Address:        "93.184.216.34",
+								Port:           "443",
+								SNI:            "www.example.com",
+								VerifyHostname: "www.example.com",
+							},
+						},
+					},
+				},
+				"api.ooni.io:443": {
+					Tactics: map[string]*statsTactic{
+						"162.55.247.208:443 sni=www.example.org verify=api.ooni.io": {
+							CountStarted:         4,
+							CountSuccess:         1,
+							CountTCPConnectError: 3,
+							HistoTCPConnectError: map[string]int64{
+								"connection_refused":                   2,
+								"unknown_failure: dial 162.55.247.208": 1,
+							},
+							LastUpdated: lastUpdated,
+							Tactic: &httpsDialerTactic{
+								Address:        "162.55.247.208",
+								Port:           "443",
+								SNI:            "www.example.org",
+								VerifyHostname: "api.ooni.io",
+							},
+						},
+						"162.55.247.208:443 sni=www.example.com verify=api.ooni.io": {
+							CountStarted:        2,
+							CountSuccess:        2,
+							DecayedCountStarted: 1.5,
+							DecayedCountSuccess: 1.5,
+							DecayedLatency:      250 * time.Millisecond,
+							LastUpdated:         lastUpdated,
+							Tactic: &httpsDialerTactic{
+								Address:        "162.55.247.208",
+								Port:           "443",
+								SNI:            "www.example.com",
+								VerifyHostname: "api.ooni.io",
+							},
+						},
+					},
+				},
+				"nil.example.com:443": nil,
+			},
+			Version: statsContainerVersion,
+		}
+	}
+
+	t.Run("newStatsReport", func(t *testing.T) {
+		report := newStatsReport(newContainer())
+		expect := &StatsReport{
+			DomainEndpoints: []*StatsReportDomainEndpoint{{
+				DomainEndpoint: "api.ooni.io:443",
+				Tactics: []*StatsReportTactic{{
+					Summary:            "162.55.247.208:443 sni=www.example.com verify=api.ooni.io",
+					CountStarted:       2,
+					CountSuccess:       2,
+					SuccessRate:        1,
+					DecayedSuccessRate: 1,
+					DecayedLatency:     250 * time.Millisecond,
+					LastUpdated:        lastUpdated,
+				}, {
+					Summary:            "162.55.247.208:443 sni=www.example.org verify=api.ooni.io",
+					CountStarted:       4,
+					CountSuccess:       1,
+					SuccessRate:        0.25,
+					DecayedSuccessRate: 0.25,
+					LastUpdated:        lastUpdated,
+					HistoTCPConnectError: map[string]int64{
+						"connection_refused":                   2,
+						"unknown_failure: dial 162.55.247.208": 1,
+					},
+				}},
+			}, {
+				DomainEndpoint: "www.example.com:443",
+				Tactics: []*StatsReportTactic{{
+					Summary:            "93.184.216.34:443 sni=www.example.com verify=www.example.com",
+					CountStarted:       1,
+					CountSuccess:       1,
+					SuccessRate:        1,
+					DecayedSuccessRate: 1,
+					LastUpdated:        lastUpdated,
+				}},
+			}},
+			Version: statsContainerVersion,
+		}
+		if diff := cmp.Diff(expect, report, cmpopts.IgnoreUnexported(StatsReportTactic{})); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("newStatsReport returns a deep copy", func(t *testing.T) {
+		container := newContainer()
+		report := newStatsReport(container)
+		stats := container.DomainEndpoints["api.ooni.io:443"].Tactics["162.55.247.208:443 sni=www.example.org verify=api.ooni.io"]
+		stats.HistoTCPConnectError["connection_refused"]++
+		stats.Tactic.Address = "130.192.91.211"
+		tactic := report.DomainEndpoints[0].Tactics[1]
+		if value := tactic.HistoTCPConnectError["connection_refused"]; value != 2 {
+			t.Fatal("the report shares the histogram with the container", value)
+		}
+		if tactic.tactic.Address != "162.55.247.208" {
+			t.Fatal("the report shares the tactic with the container")
+		}
+	})
+
+	t.Run("Redacted", func(t *testing.T) {
+		report := newStatsReport(newContainer()).Redacted()
+		data := string(runtimex.Try1(json.Marshal(report)))
+		for _, address := range []string{"162.55.247.208", "93.184.216.34"} {
+			if strings.Contains(data, address) {
+				t.Fatal("the redacted report contains", address)
+			}
+		}
+		expect := []string{
+			"[scrubbed]:443 sni=www.example.com verify=api.ooni.io",
+			"[scrubbed]:443 sni=www.example.org verify=api.ooni.io",
+		}
+		var got []string
+		for _, tactic := range report.DomainEndpoints[0].Tactics {
+			got = append(got, tactic.Summary)
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+		expectHisto := map[string]int64{
+			"connection_refused":               2,
+			"unknown_failure: dial [scrubbed]": 1,
+		}
+		if diff := cmp.Diff(expectHisto, report.DomainEndpoints[0].Tactics[1].HistoTCPConnectError); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("Redacted without the underlying tactic", func(t *testing.T) {
+		// this is what happens when we redact a report we unmarshaled from JSON
+		report := &StatsReport{
+			DomainEndpoints: []*StatsReportDomainEndpoint{{
+				DomainEndpoint: "api.ooni.io:443",
+				Tactics: []*StatsReportTactic{{
+					Summary: "162.55.247.208:443 sni=www.example.com verify=api.ooni.io",
+				}},
+			}},
+		}
+		got := report.Redacted().DomainEndpoints[0].Tactics[0].Summary
+		if got != "[scrubbed] sni=www.example.com verify=api.ooni.io" {
+			t.Fatal("unexpected summary", got)
+		}
+	})
+
+	t.Run("WriteText", func(t *testing.T) {
+		var sb strings.Builder
+		newStatsReport(newContainer()).WriteText(&sb)
+		expect := strings.Join([]string{
+			"api.ooni.io:443",
+			"    162.55.247.208:443 sni=www.example.com verify=api.ooni.io",
+			"        success rate: 1.00 (2/2)",
+			"        decayed success rate: 1.00",
+			"        decayed latency: 250ms",
+			"        last updated: 2024-04-16T10:00:00Z",
+			"    162.55.247.208:443 sni=www.example.org verify=api.ooni.io",
+			"        success rate: 0.25 (1/4)",
+			"        decayed success rate: 0.25",
+			"        last updated: 2024-04-16T10:00:00Z",
+			"        tcp connect errors:",
+			"            connection_refused: 2",
+			"            unknown_failure: dial 162.55.247.208: 1",
+			"www.example.com:443",
+			"    93.184.216.34:443 sni=www.example.com verify=www.example.com",
+			"        success rate: 1.00 (1/1)",
+			"        decayed success rate: 1.00",
+			"        last updated: 2024-04-16T10:00:00Z",
+			"",
+		}, "\n")
+		if diff := cmp.Diff(expect, sb.String()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("LoadStatsReport", func(t *testing.T) {
+		t.Run("when there are no stats", func(t *testing.T) {
+			report, err := LoadStatsReport(&kvstore.Memory{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.DomainEndpoints) != 0 {
+				t.Fatal("expected zero domain endpoints")
+			}
+			if report.Version != statsContainerVersion {
+				t.Fatal("unexpected version", report.Version)
+			}
+		})
+
+		t.Run("when we cannot parse the stats", func(t *testing.T) {
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(kvStore.Set(statsKey, []byte("{")))
+			report, err := LoadStatsReport(kvStore)
+			if err == nil || err.Error() != "unexpected end of JSON input" {
+				t.Fatal("unexpected error", err)
+			}
+			if report != nil {
+				t.Fatal("expected nil report")
+			}
+		})
+
+		t.Run("when there are stats", func(t *testing.T) {
+			container := newContainer()
+			for _, stats := range container.DomainEndpoints {
+				if stats == nil {
+					continue
+				}
+				for _, tactic := range stats.Tactics {
+					tactic.LastUpdated = time.Now() // otherwise we would prune them
+				}
+			}
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(kvStore.Set(statsKey, runtimex.Try1(json.Marshal(container))))
+			report, err := LoadStatsReport(kvStore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.DomainEndpoints) != 2 {
+				t.Fatal("expected two domain endpoints")
+			}
+		})
+	})
+
+	t.Run("PruneStats", func(t *testing.T) {
+		t.Run("when there are no stats", func(t *testing.T) {
+			kvStore := &kvstore.Memory{}
+			if err := PruneStats(kvStore); err != nil {
+				t.Fatal(err)
+			}
+			container := runtimex.Try1(loadStatsContainer(kvStore))
+			if diff := cmp.Diff(newStatsContainer(), container); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("when there are stats", func(t *testing.T) {
+			// all the entries are older than one week, so we should prune them
+			kvStore := &kvstore.Memory{}
+			runtimex.Try0(kvStore.Set(statsKey, runtimex.Try1(json.Marshal(newContainer()))))
+			if err := PruneStats(kvStore); err != nil {
+				t.Fatal(err)
+			}
+			container := runtimex.Try1(loadStatsContainer(kvStore))
+			if diff := cmp.Diff(newStatsContainer(), container); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+
+	t.Run("ResetStats", func(t *testing.T) {
+		container := newContainer()
+		for _, stats := range container.DomainEndpoints {
+			if stats == nil {
+				continue
+			}
+			for _, tactic := range stats.Tactics {
+				tactic.LastUpdated = time.Now() // otherwise we would prune them
+			}
+		}
+		kvStore := &kvstore.Memory{}
+		runtimex.Try0(kvStore.Set(statsKey, runtimex.Try1(json.Marshal(container))))
+		if err := ResetStats(kvStore); err != nil {
+			t.Fatal(err)
+		}
+		report := runtimex.Try1(LoadStatsReport(kvStore))
+		if len(report.DomainEndpoints) != 0 {
+			t.Fatal("expected zero domain endpoints")
+		}
+	})
+}
@@ -61,6 +61,7 @@ func (p *testHelpersPolicy) LookupTactics(ctx context.Context, domain, port stri
 					InitialDelay:   0, // set when dialing
 					Port:           tactic.Port,
 					SNI:            sni,
+					Transport:      tactic.Transport,
 					VerifyHostname: tactic.VerifyHostname,
 				}
 			}
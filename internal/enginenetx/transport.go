@@ -0,0 +1,228 @@
+package enginenetx
+
+//
+// Network HTTP transport - routing requests over HTTP/3 or through
+// a CDN depending on the tactic that won when dialing
+//
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// networkHTTPTransport is the [model.HTTPTransport] used by [*Network] when
+// there is no proxy. By default, we send requests using the TCP transport.
+//
+// When the [*httpsDialer] tells us that a QUIC tactic won, we remember that we should be
+// using HTTP/3 for the endpoint and retry the request using the HTTP/3 transport.
+//
+// When the [*httpsDialer] tells us that a domain fronting tactic won, we remember the
+// HTTP host to use for the endpoint and retry the request using such an HTTP host and a
+// transport that only uses the domain fronting tactics with such an HTTP host.
+//
+// The zero value is invalid; please, init MANDATORY fields.
+type networkHTTPTransport struct {
+	// TCP is the MANDATORY transport using HTTP/1.1 or HTTP/2.
+	TCP model.HTTPTransport
+
+	// HTTP3 is the MANDATORY transport using HTTP/3.
+	HTTP3 model.HTTPTransport
+
+	// NewFronted is the MANDATORY factory for the transport to use
+	// when sending requests using the given fronted HTTP host.
+	NewFronted func(httpHost string) model.HTTPTransport
+
+	// fronted maps a fronted HTTP host to its transport.
+	fronted map[string]model.HTTPTransport
+
+	// fronting maps an endpoint to the fronted HTTP host to use.
+	fronting map[string]string
+
+	// mu provides mutual exclusion for fronted, fronting, and useHTTP3.
+	mu sync.Mutex
+
+	// useHTTP3 contains the endpoints for which we should use HTTP/3.
+	useHTTP3 map[string]bool
+}
+
+var _ model.HTTPTransport = &networkHTTPTransport{}
+
+// CloseIdleConnections implements model.HTTPTransport.
+func (txp *networkHTTPTransport) CloseIdleConnections() {
+	txp.TCP.CloseIdleConnections()
+	txp.HTTP3.CloseIdleConnections()
+	for _, fronted := range txp.frontedTransports() {
+		fronted.CloseIdleConnections()
+	}
+}
+
+// Network implements model.HTTPTransport.
+func (txp *networkHTTPTransport) Network() string {
+	return txp.TCP.Network()
+}
+
+// RoundTrip implements model.HTTPTransport.
+func (txp *networkHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := networkHTTPTransportEndpoint(req)
+
+	// use HTTP/3 if a QUIC tactic previously won for this endpoint and forget
+	// about using HTTP/3 on failure such that we race all the tactics again
+	if txp.shouldUseHTTP3(endpoint) {
+		resp, err := txp.HTTP3.RoundTrip(req)
+		if err != nil {
+			txp.setUseHTTP3(endpoint, false)
+		}
+		return resp, err
+	}
+
+	// likewise, use domain fronting if a domain fronting tactic previously won
+	if httpHost := txp.frontingHost(endpoint); httpHost != "" {
+		resp, err := txp.frontedTransport(httpHost).RoundTrip(networkHTTPTransportFrontRequest(req, httpHost))
+		if err != nil {
+			txp.setFrontingHost(endpoint, "")
+		}
+		return resp, err
+	}
+
+	resp, err := txp.TCP.RoundTrip(req)
+	if err == nil {
+		return resp, nil
+	}
+
+	var frontingErr *httpsDialerUseFrontingError
+	switch {
+	case errors.Is(err, errHTTPSDialerUseHTTP3):
+		// a QUIC tactic won: retry with HTTP/3, which reuses the QUIC conn
+		// established by the [*httpsDialer], provided we can rewind the body
+		newReq, err := networkHTTPTransportRewindRequest(req, err)
+		if err != nil {
+			return nil, err
+		}
+		txp.setUseHTTP3(endpoint, true)
+		return txp.HTTP3.RoundTrip(newReq)
+
+	case errors.As(err, &frontingErr):
+		// a domain fronting tactic won: retry using the fronted HTTP host, which
+		// reuses the TLS conn established by the [*httpsDialer]
+		newReq, err := networkHTTPTransportRewindRequest(req, err)
+		if err != nil {
+			return nil, err
+		}
+		txp.setFrontingHost(endpoint, frontingErr.HTTPHost)
+		fronted := txp.frontedTransport(frontingErr.HTTPHost)
+		return fronted.RoundTrip(networkHTTPTransportFrontRequest(newReq, frontingErr.HTTPHost))
+
+	default:
+		return nil, err
+	}
+}
+
+func (txp *networkHTTPTransport) shouldUseHTTP3(endpoint string) bool {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	return txp.useHTTP3[endpoint]
+}
+
+func (txp *networkHTTPTransport) setUseHTTP3(endpoint string, value bool) {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	if txp.useHTTP3 == nil {
+		txp.useHTTP3 = make(map[string]bool)
+	}
+	if !value {
+		delete(txp.useHTTP3, endpoint)
+		return
+	}
+	txp.useHTTP3[endpoint] = true
+}
+
+func (txp *networkHTTPTransport) frontingHost(endpoint string) string {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	return txp.fronting[endpoint]
+}
+
+func (txp *networkHTTPTransport) setFrontingHost(endpoint, httpHost string) {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	if txp.fronting == nil {
+		txp.fronting = make(map[string]string)
+	}
+	if httpHost == "" {
+		delete(txp.fronting, endpoint)
+		return
+	}
+	txp.fronting[endpoint] = httpHost
+}
+
+// frontedTransport returns the transport for the given fronted HTTP host, creating
+// it the first time, such that its connections pool only contains conns established
+// by the domain fronting tactics using the given HTTP host.
+func (txp *networkHTTPTransport) frontedTransport(httpHost string) model.HTTPTransport {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	if txp.fronted == nil {
+		txp.fronted = make(map[string]model.HTTPTransport)
+	}
+	fronted := txp.fronted[httpHost]
+	if fronted == nil {
+		fronted = txp.NewFronted(httpHost)
+		txp.fronted[httpHost] = fronted
+	}
+	return fronted
+}
+
+func (txp *networkHTTPTransport) frontedTransports() (out []model.HTTPTransport) {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	for _, fronted := range txp.fronted {
+		out = append(out, fronted)
+	}
+	return
+}
+
+// networkHTTPTransportEndpoint returns the endpoint the request is for, which is
+// the same endpoint that the TCP transport passes to DialTLSContext.
+func networkHTTPTransportEndpoint(req *http.Request) string {
+	if req.URL.Port() != "" {
+		return req.URL.Host
+	}
+	port := "80"
+	if req.URL.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(req.URL.Hostname(), port)
+}
+
+// networkHTTPTransportRewindRequest returns a copy of the request whose body, if any,
+// we obtain using GetBody, which the [*http.Request] constructors set for common bodies.
+//
+// When we cannot rewind the body, we return the given original error.
+func networkHTTPTransportRewindRequest(req *http.Request, origErr error) (*http.Request, error) {
+	newReq := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return newReq, nil
+	}
+	if req.GetBody == nil {
+		return nil, origErr
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	newReq.Body = body
+	return newReq, nil
+}
+
+// networkHTTPTransportFrontRequest returns a shallow copy of the request using the
+// given HTTP host, while the URL still refers to the original endpoint, such that the
+// fronted transport dials for the original endpoint using domain fronting tactics.
+func networkHTTPTransportFrontRequest(req *http.Request, httpHost string) *http.Request {
+	newReq := req.WithContext(req.Context())
+	newReq.Host = httpHost
+	return newReq
+}
@@ -0,0 +1,394 @@
+package enginenetx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/quic-go/quic-go"
+)
+
+// QA using netem: when TCP is blocked, a QUIC tactic wins and we use HTTP/3
+func TestNetworkHTTPTransportNetemQA(t *testing.T) {
+	// create the QA environment
+	env := netemx.MustNewScenario([]*netemx.ScenarioDomainAddresses{{
+		Domains: []string{
+			"www.example.com",
+		},
+		Addresses: []string{
+			"93.184.216.34",
+		},
+		Role:             netemx.ScenarioRoleWebServer,
+		ServerNameMain:   "www.example.com",
+		WebServerFactory: netemx.ExampleWebPageHandlerFactory(),
+	}})
+	defer env.Close()
+
+	// make sure that TCP connections to the web server fail
+	env.DPIEngine().AddRule(&netem.DPICloseConnectionForServerEndpoint{
+		Logger:          log.Log,
+		ServerIPAddress: "93.184.216.34",
+		ServerPort:      443,
+	})
+
+	// create a policy emitting a TCP tactic and a QUIC tactic
+	policy := &mocksPolicy{
+		MockLookupTactics: func(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+			return streamTacticsFromSlice([]*httpsDialerTactic{{
+				Address:        "93.184.216.34",
+				Port:           port,
+				SNI:            domain,
+				VerifyHostname: domain,
+			}, {
+				Address:        "93.184.216.34",
+				Port:           port,
+				SNI:            domain,
+				Transport:      httpsDialerTransportQUIC,
+				VerifyHostname: domain,
+			}})
+		},
+	}
+
+	// create the dialer and the transport
+	netx := &netxlite.Netx{Underlying: &netxlite.NetemUnderlyingNetworkAdapter{UNet: env.ClientStack}}
+	hd := newHTTPSDialer(log.Log, netx, policy, &nullStatsManager{})
+	txp := &networkHTTPTransport{
+		TCP: netxlite.NewHTTPTransportWithOptions(
+			log.Log, netx.NewDialerWithoutResolver(log.Log), hd,
+		),
+		HTTP3: netxlite.NewHTTP3Transport(log.Log, &httpsDialerQUICDialer{hd}, nil),
+	}
+	defer txp.CloseIdleConnections()
+
+	// send two requests and make sure both of them use HTTP/3
+	for idx := 0; idx < 2; idx++ {
+		req := runtimex.Try1(http.NewRequest("GET", "https://www.example.com/", nil))
+		resp, err := txp.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+		if resp.ProtoMajor != 3 {
+			t.Fatal("expected HTTP/3, got", resp.Proto)
+		}
+		if !txp.shouldUseHTTP3("www.example.com:443") {
+			t.Fatal("expected to remember that we should use HTTP/3")
+		}
+	}
+}
+
+// QA using netem: when a domain fronting tactic wins we use the fronted HTTP host
+func TestNetworkHTTPTransportFrontingNetemQA(t *testing.T) {
+	// create the QA environment where the web server records the HTTP hosts
+	var (
+		mu    sync.Mutex
+		hosts []string
+	)
+	env := netemx.MustNewScenario([]*netemx.ScenarioDomainAddresses{{
+		Domains: []string{
+			"www.example.com",
+		},
+		Addresses: []string{
+			"93.184.216.34",
+		},
+		Role:           netemx.ScenarioRoleWebServer,
+		ServerNameMain: "www.example.com",
+		WebServerFactory: netemx.HTTPHandlerFactoryFunc(
+			func(env netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					hosts = append(hosts, r.Host)
+					mu.Unlock()
+					w.WriteHeader(http.StatusNoContent)
+				})
+			}),
+	}})
+	defer env.Close()
+
+	// create a bridges policy using the web server as the CDN
+	policy := &bridgesPolicyV2{
+		Fronts: map[string][]*bridgesFront{
+			"api.ooni.io": {{
+				Addresses: []string{"93.184.216.34"},
+				HTTPHost:  "backend.example.com",
+				SNIs:      []string{"www.example.com"},
+			}},
+		},
+	}
+
+	// create the dialer and the transport
+	netx := &netxlite.Netx{Underlying: &netxlite.NetemUnderlyingNetworkAdapter{UNet: env.ClientStack}}
+	hd := newHTTPSDialer(log.Log, netx, policy, &nullStatsManager{})
+	dialer := netx.NewDialerWithoutResolver(log.Log)
+	txp := &networkHTTPTransport{
+		TCP:   netxlite.NewHTTPTransportWithOptions(log.Log, dialer, hd),
+		HTTP3: netxlite.NewHTTP3Transport(log.Log, &httpsDialerQUICDialer{hd}, nil),
+		NewFronted: func(httpHost string) model.HTTPTransport {
+			return netxlite.NewHTTPTransportWithOptions(log.Log, dialer, &httpsDialerFrontedTLSDialer{hd, httpHost})
+		},
+	}
+	defer txp.CloseIdleConnections()
+
+	// send two requests and make sure both of them use the fronted HTTP host
+	for idx := 0; idx < 2; idx++ {
+		req := runtimex.Try1(http.NewRequest("GET", "https://api.ooni.io/api/v1/test-list/urls", nil))
+		resp, err := txp.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+		if txp.frontingHost("api.ooni.io:443") != "backend.example.com" {
+			t.Fatal("expected to remember the fronted HTTP host")
+		}
+	}
+	if diff := cmp.Diff([]string{"backend.example.com", "backend.example.com"}, hosts); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestNetworkHTTPTransport(t *testing.T) {
+	t.Run("we use TCP unless a QUIC tactic won", func(t *testing.T) {
+		expect := &http.Response{StatusCode: 200}
+		txp := &networkHTTPTransport{
+			TCP: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					return expect, nil
+				},
+			},
+			HTTP3: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					panic("should not be called")
+				},
+			},
+		}
+		req := runtimex.Try1(http.NewRequest("GET", "https://www.example.com/", nil))
+		resp, err := txp.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp != expect {
+			t.Fatal("unexpected response")
+		}
+	})
+
+	t.Run("we retry using HTTP/3 rewinding the body", func(t *testing.T) {
+		var body []byte
+		txp := &networkHTTPTransport{
+			TCP: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					return nil, errHTTPSDialerUseHTTP3
+				},
+			},
+			HTTP3: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					body = runtimex.Try1(io.ReadAll(req.Body))
+					return &http.Response{StatusCode: 200}, nil
+				},
+			},
+		}
+		req := runtimex.Try1(http.NewRequest("POST", "https://www.example.com/", strings.NewReader("abc")))
+		if _, err := txp.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("abc", string(body)); diff != "" {
+			t.Fatal(diff)
+		}
+		if !txp.shouldUseHTTP3("www.example.com:443") {
+			t.Fatal("expected to remember that we should use HTTP/3")
+		}
+	})
+
+	t.Run("we fail if we cannot rewind the body", func(t *testing.T) {
+		txp := &networkHTTPTransport{
+			TCP: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					return nil, errHTTPSDialerUseHTTP3
+				},
+			},
+			HTTP3: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					panic("should not be called")
+				},
+			},
+		}
+		req := runtimex.Try1(http.NewRequest("POST", "https://www.example.com/", io.NopCloser(strings.NewReader("abc"))))
+		resp, err := txp.RoundTrip(req)
+		if !errors.Is(err, errHTTPSDialerUseHTTP3) {
+			t.Fatal("unexpected error", err)
+		}
+		if resp != nil {
+			t.Fatal("expected nil response")
+		}
+	})
+
+	t.Run("we retry using the fronted HTTP host", func(t *testing.T) {
+		var (
+			gotHost  string
+			gotURL   string
+			factoryv []string
+		)
+		txp := &networkHTTPTransport{
+			TCP: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					return nil, &httpsDialerUseFrontingError{HTTPHost: "backend.example.com"}
+				},
+			},
+			HTTP3: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					panic("should not be called")
+				},
+			},
+			NewFronted: func(httpHost string) model.HTTPTransport {
+				factoryv = append(factoryv, httpHost)
+				return &mocks.HTTPTransport{
+					MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+						gotHost, gotURL = req.Host, req.URL.String()
+						return &http.Response{StatusCode: 200}, nil
+					},
+				}
+			},
+		}
+		for idx := 0; idx < 2; idx++ {
+			req := runtimex.Try1(http.NewRequest("GET", "https://api.ooni.io/", nil))
+			if _, err := txp.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff("backend.example.com", gotHost); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff("https://api.ooni.io/", gotURL); diff != "" {
+				t.Fatal(diff)
+			}
+		}
+		if diff := cmp.Diff([]string{"backend.example.com"}, factoryv); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we forget about the fronted HTTP host when it fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		txp := &networkHTTPTransport{
+			TCP: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					panic("should not be called")
+				},
+			},
+			HTTP3: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					panic("should not be called")
+				},
+			},
+			NewFronted: func(httpHost string) model.HTTPTransport {
+				return &mocks.HTTPTransport{
+					MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+						return nil, expected
+					},
+				}
+			},
+		}
+		txp.setFrontingHost("api.ooni.io:443", "backend.example.com")
+		req := runtimex.Try1(http.NewRequest("GET", "https://api.ooni.io/", nil))
+		if _, err := txp.RoundTrip(req); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if txp.frontingHost("api.ooni.io:443") != "" {
+			t.Fatal("expected to forget about the fronted HTTP host")
+		}
+	})
+
+	t.Run("we forget about HTTP/3 when it fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		txp := &networkHTTPTransport{
+			TCP: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					panic("should not be called")
+				},
+			},
+			HTTP3: &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					return nil, expected
+				},
+			},
+		}
+		txp.setUseHTTP3("www.example.com:443", true)
+		req := runtimex.Try1(http.NewRequest("GET", "https://www.example.com/", nil))
+		if _, err := txp.RoundTrip(req); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if txp.shouldUseHTTP3("www.example.com:443") {
+			t.Fatal("expected to forget that we should use HTTP/3")
+		}
+	})
+}
+
+func TestNetworkHTTPTransportEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"https://www.example.com/":      "www.example.com:443",
+		"http://www.example.com/":       "www.example.com:80",
+		"https://www.example.com:4443/": "www.example.com:4443",
+		"https://[::1]/":                "[::1]:443",
+	}
+	for input, expect := range cases {
+		t.Run(input, func(t *testing.T) {
+			req := runtimex.Try1(http.NewRequest("GET", input, nil))
+			if got := networkHTTPTransportEndpoint(req); got != expect {
+				t.Fatal("expected", expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestHTTPSDialerQUICDialerCloseIdleConnections(t *testing.T) {
+	hd := newHTTPSDialer(log.Log, &netxlite.Netx{}, &mocksPolicy{}, &nullStatsManager{})
+
+	var quicClosed, tlsClosed bool
+	hd.saveQUICConn("162.55.247.208:443", &mocks.QUICConn{
+		MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+			quicClosed = true
+			return nil
+		},
+	})
+	hd.saveFrontedConn("151.101.1.1:443", "api.ooni.io", &mocks.TLSConn{
+		Conn: mocks.Conn{
+			MockClose: func() error {
+				tlsClosed = true
+				return nil
+			},
+		},
+	})
+
+	// the QUIC dialer must only close the QUIC conns
+	qd := &httpsDialerQUICDialer{hd}
+	qd.CloseIdleConnections()
+	if !quicClosed {
+		t.Fatal("expected the QUIC conn to be closed")
+	}
+	if tlsClosed {
+		t.Fatal("expected the fronted TLS conn not to be closed")
+	}
+
+	// the TLS dialer closes all the remaining conns
+	hd.CloseIdleConnections()
+	if !tlsClosed {
+		t.Fatal("expected the fronted TLS conn to be closed")
+	}
+}
@@ -3,7 +3,8 @@ package enginenetx
 //
 // user policy - the possibility of loading a user policy from a JSON
 // document named `bridges.conf` in $OONI_HOME/engine that contains
-// a specific policy for TLS dialing for specific endpoints.
+// a specific policy for TLS dialing for specific endpoints, as well as
+// the CDN fronts to use for domain fronting.
 //
 // This policy helps a lot with exploration and experimentation.
 //
@@ -109,6 +110,10 @@ type userPolicyRoot struct {
 	// DomainEndpoints maps each domain endpoint to its policies.
 	DomainEndpoints map[string][]*httpsDialerTactic
 
+	// Fronts OPTIONALLY maps each domain to the CDN fronts that
+	// the [*bridgesPolicyV2] uses for domain fronting.
+	Fronts map[string][]*bridgesFront `json:",omitempty"`
+
 	// Version is the data structure version.
 	Version int
 }
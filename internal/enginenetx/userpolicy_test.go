@@ -140,6 +140,36 @@ func TestUserPolicyV2(t *testing.T) {
 					Version: userPolicyVersion,
 				},
 			},
+		}, {
+			name: "with serialized policy containing fronts",
+			key:  userPolicyKey,
+			input: []byte(`{
+				// fronts are described declaratively and ECHConfigList is base64
+				"Fronts": {
+					"api.ooni.io": [{
+						"Addresses": ["151.101.1.1"],
+						"ECHConfigList": "AAQBAgME",
+						"HTTPHost": "ooni-api.cdn.example",
+						"SNIs": ["www.example.com"],
+					}],
+				},
+				"Version": 3,
+			}`),
+			expectErr: "",
+			expectedPolicy: &userPolicyV2{
+				Root: &userPolicyRoot{
+					DomainEndpoints: nil,
+					Fronts: map[string][]*bridgesFront{
+						"api.ooni.io": {{
+							Addresses:     []string{"151.101.1.1"},
+							ECHConfigList: []byte{0, 4, 1, 2, 3, 4},
+							HTTPHost:      "ooni-api.cdn.example",
+							SNIs:          []string{"www.example.com"},
+						}},
+					},
+					Version: userPolicyVersion,
+				},
+			},
 		}}
 
 		for _, tc := range cases {
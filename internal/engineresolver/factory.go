@@ -2,6 +2,7 @@ package engineresolver
 
 import (
 	"errors"
+	"net"
 	"net/url"
 
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
@@ -14,8 +15,12 @@ import (
 // child resolver using HTTP/3 with a proxy URL.
 var errCannotUseHTTP3WithAProxyURL = errors.New("cannot use HTTP/3 with a proxy URL")
 
+// errCannotUseDoQWithAProxyURL means we cannot construct a new
+// child resolver using DNS-over-QUIC with a proxy URL.
+var errCannotUseDoQWithAProxyURL = errors.New("cannot use DNS-over-QUIC with a proxy URL")
+
 // errUnsupportedResolverScheme means we don't support the
-// given resolver scheme. We only support https, http and system.
+// given resolver scheme. We only support https, http, doq and system.
 var errUnsupportedResolverScheme = errors.New("unsupported resolver scheme")
 
 // newChildResolver constructs a new child resolver.
@@ -24,7 +29,7 @@ var errUnsupportedResolverScheme = errors.New("unsupported resolver scheme")
 //
 // - logger is the MANDATORY logger;
 //
-// - URL is the MANDATORY URL to use (a DoH URL or system:///);
+// - URL is the MANDATORY URL to use (a DoH URL, a DoQ URL or system:///);
 //
 // - http3Enabled indicates whether to use HTTP/3;
 //
@@ -32,8 +37,8 @@ var errUnsupportedResolverScheme = errors.New("unsupported resolver scheme")
 //
 // - proxyURL is the OPTIONAL proxy URL.
 //
-// Using a proxy URL is incompatible with using HTTP/3 and this
-// factory will return an error if that happens.
+// Using a proxy URL is incompatible with using HTTP/3 or DNS-over-QUIC
+// and this factory will return an error if that happens.
 //
 // This function returns a model.Resolver or an error.
 func newChildResolver(
@@ -56,6 +61,11 @@ func newChildResolver(
 	switch parsed.Scheme {
 	case "http", "https": // http is here for testing
 		reso = newChildResolverHTTPS(logger, URL, http3Enabled, counter, proxyURL)
+	case "doq":
+		if proxyURL != nil {
+			return nil, errCannotUseDoQWithAProxyURL
+		}
+		reso = newChildResolverQUIC(logger, parsed, counter)
 	case "system":
 		netx := &netxlite.Netx{}
 		reso = bytecounter.MaybeWrapSystemResolver(
@@ -99,3 +109,25 @@ func newChildResolverHTTPS(
 	wrapped := netxlite.WrapResolver(logger, underlying)
 	return wrapped
 }
+
+// newChildResolverQUIC is like newChildResolver but assumes that
+// we already know that the URL scheme is doq. When the URL does not
+// contain a port, we use the default DoQ port, i.e., 853.
+//
+// Because we lack a byte counter for QUIC connections, we use the same
+// approximate byte counting strategy we use for the system resolver.
+func newChildResolverQUIC(
+	logger model.Logger,
+	URL *url.URL,
+	counter *bytecounter.Counter,
+) model.Resolver {
+	endpoint := URL.Host
+	if URL.Port() == "" {
+		endpoint = net.JoinHostPort(URL.Hostname(), "853")
+	}
+	netx := &netxlite.Netx{}
+	return bytecounter.MaybeWrapSystemResolver(
+		netx.NewParallelDNSOverQUICResolver(logger, endpoint),
+		counter, // handles correctly the case where counter is nil
+	)
+}
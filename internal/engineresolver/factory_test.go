@@ -117,6 +117,59 @@ func Test_newChildResolver(t *testing.T) {
 		}
 	})
 
+	t.Run("we cannot create a DNS-over-QUIC resolver with a proxy URL", func(t *testing.T) {
+		reso, err := newChildResolver(
+			model.DiscardLogger,
+			"doq://dns.adguard-dns.com",
+			false,
+			bytecounter.New(),
+			&url.URL{}, // even an empty URL is enough
+		)
+		if !errors.Is(err, errCannotUseDoQWithAProxyURL) {
+			t.Fatal("unexpected error", err)
+		}
+		if reso != nil {
+			t.Fatal("expected nil resolver here")
+		}
+	})
+
+	t.Run("for DNS-over-QUIC resolvers", func(t *testing.T) {
+		t.Run("we use the default port when the URL has no port", func(t *testing.T) {
+			reso, err := newChildResolver(
+				model.DiscardLogger,
+				"doq://dns.adguard-dns.com",
+				false,
+				bytecounter.New(),
+				nil,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reso.Network() != "doq" {
+				t.Fatal("unexpected network", reso.Network())
+			}
+			if reso.Address() != "dns.adguard-dns.com:853" {
+				t.Fatal("unexpected address", reso.Address())
+			}
+		})
+
+		t.Run("we honour the port when the URL has a port", func(t *testing.T) {
+			reso, err := newChildResolver(
+				model.DiscardLogger,
+				"doq://dns.adguard-dns.com:8853",
+				false,
+				bytecounter.New(),
+				nil,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reso.Address() != "dns.adguard-dns.com:8853" {
+				t.Fatal("unexpected address", reso.Address())
+			}
+		})
+	})
+
 	t.Run("for HTTPS resolvers", func(t *testing.T) {
 
 		t.Run("the returned resolver wraps errors", func(t *testing.T) {
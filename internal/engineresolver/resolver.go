@@ -93,6 +93,11 @@ func (r *Resolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, erro
 	return nil, errLookupNotImplemented
 }
 
+// LookupRecords implements Resolver.LookupRecords.
+func (r *Resolver) LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	return nil, errLookupNotImplemented
+}
+
 // ErrLookupHost indicates that LookupHost failed.
 var ErrLookupHost = errors.New("sessionresolver: LookupHost failed")
 
@@ -17,15 +17,21 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
 	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
 	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/targetloading"
 )
 
 const (
 	testName      = "dnscheck"
-	testVersion   = "0.9.2"
+	testVersion   = "0.12.0"
 	defaultDomain = "example.org"
+
+	// lateRepliesTimeout is the time during which we wait for duplicate
+	// responses after the first DNS-over-UDP response.
+	lateRepliesTimeout = 500 * time.Millisecond
 )
 
 // Endpoints keeps track of repeatedly measured endpoints.
@@ -75,6 +81,7 @@ func (e *Endpoints) maybeRegister(resolverURL string) {
 type Config struct {
 	DefaultAddrs  string `json:"default_addrs" ooni:"default addresses for domain"`
 	Domain        string `json:"domain" ooni:"domain to resolve using the specified resolver"`
+	EDNS0Options  string `json:"edns0_options" ooni:"space-separated EDNS0 options to send (e.g. 'ecs=203.0.113.0/24 cookie nsid')"`
 	HTTP3Enabled  bool   `json:"http3_enabled" ooni:"use http3 instead of http/1.1 or http2"`
 	HTTPHost      string `json:"http_host" ooni:"force using specific HTTP Host header"`
 	TLSServerName string `json:"tls_server_name" ooni:"force TLS to using a specific SNI in Client Hello"`
@@ -85,6 +92,7 @@ type Config struct {
 type TestKeys struct {
 	DefaultAddrs     string                        `json:"x_default_addrs"`
 	Domain           string                        `json:"domain"`
+	EDNS0Options     string                        `json:"x_edns0_options,omitempty"`
 	HTTP3Enabled     bool                          `json:"x_http3_enabled,omitempty"`
 	HTTPHost         string                        `json:"x_http_host,omitempty"`
 	TLSServerName    string                        `json:"x_tls_server_name,omitempty"`
@@ -93,6 +101,11 @@ type TestKeys struct {
 	Bootstrap        *urlgetter.TestKeys           `json:"bootstrap"`
 	BootstrapFailure *string                       `json:"bootstrap_failure"`
 	Lookups          map[string]urlgetter.TestKeys `json:"lookups"`
+
+	// DNSInjection indicates that some duplicate responses, which we attach to
+	// the corresponding lookups' queries, contain addresses different from the ones
+	// in the first response, which is how DNS injection typically manifests itself.
+	DNSInjection bool `json:"x_dns_injection"`
 }
 
 // Measurer performs the measurement.
@@ -116,6 +129,7 @@ func (m *Measurer) ExperimentVersion() string {
 var (
 	ErrInputRequired        = targetloading.ErrInputRequired
 	ErrInvalidInputType     = targetloading.ErrInvalidInputType
+	ErrInvalidEDNS0Options  = errors.New("the EDNS0 options are invalid")
 	ErrInvalidURL           = errors.New("the input URL is invalid")
 	ErrUnsupportedURLScheme = errors.New("unsupported URL scheme")
 )
@@ -151,6 +165,7 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	}
 	tk.DefaultAddrs = config.DefaultAddrs
 	tk.Domain = domain
+	tk.EDNS0Options = config.EDNS0Options
 	tk.HTTP3Enabled = config.HTTP3Enabled
 	tk.HTTPHost = config.HTTPHost
 	tk.TLSServerName = config.TLSServerName
@@ -166,12 +181,18 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		return fmt.Errorf("%w: %s", ErrInvalidURL, err.Error())
 	}
 	switch URL.Scheme {
-	case "https", "dot", "udp", "tcp":
+	case "https", "dot", "doq", "udp", "tcp":
 		// all good
 	default:
 		return ErrUnsupportedURLScheme
 	}
 
+	// 3.1. parse the EDNS0 options to include into the queries
+	edns0Options, err := netxlite.ParseDNSEDNS0Options(config.EDNS0Options)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEDNS0Options, err.Error())
+	}
+
 	// Implementation note: we must not return an error from now now. Returning an
 	// error means that we don't have a measurement to submit.
 
@@ -238,15 +259,50 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		m.Endpoints.maybeSleep(resolverURL, sess.Logger())
 	}
 
-	// 8. perform all the required resolutions
+	// 8. perform all the required resolutions using the configured EDNS0
+	// options, which we deliberately do not use for the bootstrap, and
+	// listening in the background for duplicate DNS-over-UDP responses,
+	// which typically indicate that the responses have been injected
+	trace := measurexlite.NewTrace(0, begin)
+	ctx = netxlite.ContextWithDNSEDNS0Options(ctx, edns0Options...)
+	ctx = netxlite.ContextWithDNSOverUDPDuplicatesWindow(ctx, lateRepliesTimeout)
+	ctx = netxlite.ContextWithTrace(ctx, trace)
 	for output := range Collect(ctx, multi, inputs, sess.Logger()) {
 		resolverURL := output.Input.Config.ResolverURL
 		tk.Lookups[resolverURL] = output.TestKeys
 		m.Endpoints.maybeRegister(resolverURL)
 	}
+
+	// 9. attach the duplicate responses to the queries and flag injection
+	tk.DNSInjection = dnsAttachDuplicatesAndCheckInjection(
+		tk.Lookups, trace.DelayedDNSResponseWithTimeout(ctx, lateRepliesTimeout))
 	return nil
 }
 
+// dnsAttachDuplicatesAndCheckInjection attaches the duplicate responses to the
+// queries of the lookups and returns whether any of them was injected.
+func dnsAttachDuplicatesAndCheckInjection(
+	lookups map[string]urlgetter.TestKeys, duplicates []*model.ArchivalDNSLookupResult) bool {
+	// Implementation note: urlgetter does not set the transaction ID, which
+	// is therefore zero like the index of the trace we use for collecting
+	// duplicates, so we match duplicates using the resolver address and the
+	// query type. Each duplicate belongs to one of the lookups we performed,
+	// so we do not expect any duplicate to remain unattached.
+	var queries []*model.ArchivalDNSLookupResult
+	for _, lookup := range lookups {
+		for idx := range lookup.Queries {
+			queries = append(queries, &lookup.Queries[idx])
+		}
+	}
+	_ = measurexlite.AttachDNSDuplicateResponses(queries, duplicates...)
+	for _, query := range queries {
+		if measurexlite.DNSLookupResultHasInjection(query) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Measurer) lookupHost(ctx context.Context, hostname string, r model.Resolver) ([]string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
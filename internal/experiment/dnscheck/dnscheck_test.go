@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -45,7 +47,7 @@ func TestExperimentNameAndVersion(t *testing.T) {
 	if measurer.ExperimentName() != "dnscheck" {
 		t.Error("unexpected experiment name")
 	}
-	if measurer.ExperimentVersion() != "0.9.2" {
+	if measurer.ExperimentVersion() != "0.12.0" {
 		t.Error("unexpected experiment version")
 	}
 }
@@ -117,6 +119,23 @@ func TestDNSCheckFailsWithUnsupportedProtocol(t *testing.T) {
 	}
 }
 
+func TestDNSCheckFailsWithInvalidEDNS0Options(t *testing.T) {
+	measurer := NewExperimentMeasurer()
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: &model.Measurement{Input: "dot://one.one.one.one"},
+		Session:     newsession(),
+		Target: &Target{
+			URL:    "dot://one.one.one.one",
+			Config: &Config{EDNS0Options: "ecs=antani"},
+		},
+	}
+	err := measurer.Run(context.Background(), args)
+	if !errors.Is(err, ErrInvalidEDNS0Options) {
+		t.Fatal("expected invalid EDNS0 options error")
+	}
+}
+
 func TestWithCancelledContext(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // immediately cancel the context
@@ -178,6 +197,64 @@ func TestMakeResolverURL(t *testing.T) {
 	}
 }
 
+func TestDNSAttachDuplicatesAndCheckInjection(t *testing.T) {
+	newQuery := func(qtype string, addrs ...string) model.ArchivalDNSLookupResult {
+		var answers []model.ArchivalDNSAnswer
+		for _, addr := range addrs {
+			answers = append(answers, model.ArchivalDNSAnswer{AnswerType: "A", IPv4: addr})
+		}
+		return model.ArchivalDNSLookupResult{
+			Answers:         answers,
+			QueryType:       qtype,
+			ResolverAddress: "8.8.8.8:53",
+		}
+	}
+	newLookups := func() map[string]urlgetter.TestKeys {
+		return map[string]urlgetter.TestKeys{
+			"udp://8.8.8.8:53": {
+				Queries: []tracex.DNSQueryEntry{newQuery("A", "93.184.216.34", "93.184.216.35")},
+			},
+		}
+	}
+
+	t.Run("without duplicates", func(t *testing.T) {
+		lookups := newLookups()
+		if dnsAttachDuplicatesAndCheckInjection(lookups, nil) {
+			t.Fatal("expected false")
+		}
+		if lookups["udp://8.8.8.8:53"].Queries[0].DuplicateResponses != nil {
+			t.Fatal("expected no duplicate responses")
+		}
+	})
+
+	t.Run("with duplicates containing the same addresses", func(t *testing.T) {
+		lookups := newLookups()
+		dup := newQuery("A", "93.184.216.35", "93.184.216.34")
+		if dnsAttachDuplicatesAndCheckInjection(lookups, []*model.ArchivalDNSLookupResult{&dup}) {
+			t.Fatal("expected false")
+		}
+		if len(lookups["udp://8.8.8.8:53"].Queries[0].DuplicateResponses) != 1 {
+			t.Fatal("expected to see the duplicate response attached to the query")
+		}
+	})
+
+	t.Run("with duplicates containing different addresses", func(t *testing.T) {
+		lookups := newLookups()
+		dup := newQuery("A", "10.10.34.35")
+		if !dnsAttachDuplicatesAndCheckInjection(lookups, []*model.ArchivalDNSLookupResult{&dup}) {
+			t.Fatal("expected true")
+		}
+	})
+
+	t.Run("with duplicates for another query type", func(t *testing.T) {
+		lookups := newLookups()
+		dup := newQuery("AAAA")
+		if dnsAttachDuplicatesAndCheckInjection(lookups, []*model.ArchivalDNSLookupResult{&dup}) {
+			t.Fatal("expected false")
+		}
+	})
+}
+
 func TestDNSCheckValid(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skip test in short mode")
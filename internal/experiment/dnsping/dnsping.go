@@ -20,7 +20,7 @@ import (
 
 const (
 	testName    = "dnsping"
-	testVersion = "0.4.0"
+	testVersion = "0.5.0"
 )
 
 // Config contains the experiment configuration.
@@ -28,6 +28,9 @@ type Config struct {
 	// Delay is the delay between each repetition (in milliseconds).
 	Delay int64 `ooni:"number of milliseconds to wait before sending each ping"`
 
+	// DNSSEC requests DNSSEC records and validates the chain of trust.
+	DNSSEC bool `ooni:"request DNSSEC records and validate the chain of trust"`
+
 	// Domains is the space-separated list of domains to measure.
 	Domains string `ooni:"space-separated list of domains to measure"`
 
@@ -171,7 +174,8 @@ func (m *Measurer) dnsRoundTrip(ctx context.Context, index int64, zeroTime time.
 	// Shall we, otherwise, pre-resolve the domain name to IP addresses once and for all? In such
 	// a case, shall we use all the available IP addresses or just some of them?
 	dialer := netxlite.NewDialerWithStdlibResolver(logger)
-	resolver := trace.NewParallelUDPResolver(logger, dialer, address)
+	resolver := trace.NewParallelUDPResolverWithOptions(
+		logger, dialer, address, netxlite.ParallelResolverOptionDNSSEC(m.config.DNSSEC))
 
 	// perform the lookup proper
 	ol := logx.NewOperationLogger(logger, "DNSPing #%d %s %s", index, address, domain)
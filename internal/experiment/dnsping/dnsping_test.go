@@ -51,7 +51,7 @@ func TestMeasurer_run(t *testing.T) {
 		if m.ExperimentName() != "dnsping" {
 			t.Fatal("invalid experiment name")
 		}
-		if m.ExperimentVersion() != "0.4.0" {
+		if m.ExperimentVersion() != "0.5.0" {
 			t.Fatal("invalid experiment version")
 		}
 		ctx := context.Background()
@@ -137,6 +137,57 @@ func TestMeasurer_run(t *testing.T) {
 		})
 	})
 
+	t.Run("with netem: with DNSSEC: expect unsigned answers", func(t *testing.T) {
+		// create a new test environment
+		env := netemx.MustNewQAEnv(netemx.QAEnvOptionNetStack("8.8.8.8", &netemx.DNSOverUDPServerFactory{}))
+		defer env.Close()
+
+		// we use the same configuration for all resolvers
+		env.AddRecordToAllResolvers(
+			"example.com",
+			"example.com", // CNAME
+			"93.184.216.34",
+		)
+
+		env.Do(func() {
+			m := NewExperimentMeasurer(Config{
+				DNSSEC:      true,
+				Domains:     "example.com",
+				Delay:       1, // millisecond
+				Repetitions: 1,
+			})
+			meas := &model.Measurement{
+				Input: model.MeasurementInput("udp://8.8.8.8:53"),
+			}
+			args := &model.ExperimentArgs{
+				Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+				Measurement: meas,
+				Session: &mocks.Session{
+					MockLogger: func() model.Logger { return model.DiscardLogger },
+				},
+			}
+			if err := m.Run(context.Background(), args); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			tk, _ := (meas.TestKeys).(*TestKeys)
+			var found bool
+			for _, p := range tk.Pings {
+				if p.Query.QueryType != "A" {
+					continue
+				}
+				found = true
+				// the netem resolvers do not sign their answers
+				if p.Query.DNSSECStatus != netxlite.DNSSECStatusUnsigned {
+					t.Fatal("unexpected DNSSEC status", p.Query.DNSSECStatus)
+				}
+			}
+			if !found {
+				t.Fatal("expected to see an A ping")
+			}
+		})
+	})
+
 	t.Run("with netem: with DNS spoofing: expect to see delayed responses", func(t *testing.T) {
 		// create a new test environment
 		env := netemx.MustNewQAEnv(netemx.QAEnvOptionNetStack("8.8.8.8", &netemx.DNSOverUDPServerFactory{}))
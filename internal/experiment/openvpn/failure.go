@@ -0,0 +1,53 @@
+package openvpn
+
+//
+// Classifying OpenVPN handshake errors
+//
+
+import (
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// These failures indicate that the OpenVPN handshake failed. Without them, the
+// errors returned by minivpn would be reported as generic unknown failures.
+const (
+	FailureOpenVPNHandshake        = "openvpn_handshake_error"
+	FailureOpenVPNHandshakeBadCA   = "openvpn_handshake_bad_ca"
+	FailureOpenVPNHandshakeTimeout = "openvpn_handshake_timeout"
+)
+
+// openVPNHandshakeErrorPrefix is the prefix of the errors emitted by minivpn
+// when the OpenVPN handshake fails. We need to match strings because minivpn
+// defines its errors inside internal packages we cannot import.
+const openVPNHandshakeErrorPrefix = "openvpn handshake error: "
+
+// classifyOpenVPNError is a [netxlite.ErrorClassifier] for OpenVPN handshake errors.
+func classifyOpenVPNError(err error) string {
+	s := err.Error()
+	if !strings.HasPrefix(s, openVPNHandshakeErrorPrefix) {
+		return ""
+	}
+	switch reason := strings.TrimPrefix(s, openVPNHandshakeErrorPrefix); {
+	case reason == "tls timeout":
+		return FailureOpenVPNHandshakeTimeout
+	case strings.HasPrefix(reason, "bad ca conf"):
+		return FailureOpenVPNHandshakeBadCA
+	default:
+		return FailureOpenVPNHandshake
+	}
+}
+
+func init() {
+	// Note: registering globally is fine because only minivpn emits these errors
+	// and we run after the builtin classification, such that, e.g., the context
+	// errors wrapped by minivpn are still classified as usual.
+	netxlite.RegisterErrorClassifier(
+		netxlite.ErrorClassifierPriorityBuiltin,
+		classifyOpenVPNError,
+		FailureOpenVPNHandshake,
+		FailureOpenVPNHandshakeBadCA,
+		FailureOpenVPNHandshakeTimeout,
+	)
+}
@@ -0,0 +1,67 @@
+package openvpn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestClassifyOpenVPNError(t *testing.T) {
+	// errCannotHandshake mimics the error returned by minivpn
+	errCannotHandshake := errors.New("openvpn handshake error")
+
+	t.Run("we map the known errors", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			expect string
+		}{{
+			err:    fmt.Errorf("%w: %s", errCannotHandshake, "tls timeout"),
+			expect: FailureOpenVPNHandshakeTimeout,
+		}, {
+			err:    fmt.Errorf("%w: %s", errCannotHandshake, "bad ca conf: cannot parse ca cert"),
+			expect: FailureOpenVPNHandshakeBadCA,
+		}, {
+			err:    fmt.Errorf("%w: %s", errCannotHandshake, "something else"),
+			expect: FailureOpenVPNHandshake,
+		}}
+		for _, tt := range tests {
+			if got := classifyOpenVPNError(tt.err); got != tt.expect {
+				t.Fatal("expected", tt.expect, "got", got)
+			}
+		}
+	})
+
+	t.Run("we return an empty string for other errors", func(t *testing.T) {
+		if got := classifyOpenVPNError(errors.New("tls timeout")); got != "" {
+			t.Fatal("expected empty string, got", got)
+		}
+	})
+
+	t.Run("netxlite uses our classifier after the builtin classification", func(t *testing.T) {
+		err := fmt.Errorf("%w: %s", errCannotHandshake, "tls timeout")
+		if got := netxlite.ClassifyGenericError(err); got != FailureOpenVPNHandshakeTimeout {
+			t.Fatal("unexpected failure", got)
+		}
+		err = fmt.Errorf("%w: %w", errCannotHandshake, context.DeadlineExceeded)
+		if got := netxlite.ClassifyGenericError(err); got != netxlite.FailureGenericTimeoutError {
+			t.Fatal("unexpected failure", got)
+		}
+	})
+
+	t.Run("netxlite lists our failures", func(t *testing.T) {
+		failures := netxlite.FailureStrings()
+		for _, failure := range []string{
+			FailureOpenVPNHandshake,
+			FailureOpenVPNHandshakeBadCA,
+			FailureOpenVPNHandshakeTimeout,
+		} {
+			if !slices.Contains(failures, failure) {
+				t.Fatal("missing failure", failure)
+			}
+		}
+	})
+}
@@ -17,7 +17,7 @@ import (
 
 const (
 	testName        = "openvpn"
-	testVersion     = "0.1.6"
+	testVersion     = "0.1.7"
 	openVPNProtocol = "openvpn"
 )
 
@@ -0,0 +1,65 @@
+package tlsmiddlebox
+
+//
+// Classifying TLS alerts sent by middleboxes
+//
+
+import (
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// These failures indicate that we received a TLS alert. Middleboxes sometimes
+// interrupt the TLS handshake by sending alerts, so we want to know which alert
+// we received rather than seeing a generic unknown failure.
+const (
+	FailureSSLAlertAccessDenied      = "ssl_alert_access_denied"
+	FailureSSLAlertDecodeError       = "ssl_alert_decode_error"
+	FailureSSLAlertHandshakeFailure  = "ssl_alert_handshake_failure"
+	FailureSSLAlertIllegalParameter  = "ssl_alert_illegal_parameter"
+	FailureSSLAlertInternalError     = "ssl_alert_internal_error"
+	FailureSSLAlertProtocolVersion   = "ssl_alert_protocol_version"
+	FailureSSLAlertUnexpectedMessage = "ssl_alert_unexpected_message"
+)
+
+// tlsAlertFailures maps the suffix of the error emitted by crypto/tls
+// and by uTLS when receiving a TLS alert to the corresponding failure.
+var tlsAlertFailures = map[string]string{
+	"remote error: tls: access denied":                  FailureSSLAlertAccessDenied,
+	"remote error: tls: error decoding message":         FailureSSLAlertDecodeError,
+	"remote error: tls: handshake failure":              FailureSSLAlertHandshakeFailure,
+	"remote error: tls: illegal parameter":              FailureSSLAlertIllegalParameter,
+	"remote error: tls: internal error":                 FailureSSLAlertInternalError,
+	"remote error: tls: protocol version not supported": FailureSSLAlertProtocolVersion,
+	"remote error: tls: unexpected message":             FailureSSLAlertUnexpectedMessage,
+}
+
+// maybeClassifyTLSAlert replaces the unknown failure of the given handshake with the
+// failure corresponding to the TLS alert we received, if any. We do this here rather than
+// registering a classifier with netxlite, since netxlite classifiers are process-wide and
+// we do not want to change the failures emitted by other experiments.
+func maybeClassifyTLSAlert(handshake *model.ArchivalTLSOrQUICHandshakeResult) {
+	if handshake == nil || handshake.Failure == nil {
+		return
+	}
+	if failure := classifyTLSAlert(*handshake.Failure); failure != "" {
+		handshake.Failure = &failure
+	}
+}
+
+// classifyTLSAlert returns the failure corresponding to the TLS alert contained in the
+// given unknown failure or an empty string. We only consider unknown failures, such that
+// the builtin netxlite classification always takes precedence.
+func classifyTLSAlert(failure string) string {
+	if !strings.HasPrefix(failure, netxlite.FailureUnknown) {
+		return ""
+	}
+	for suffix, alert := range tlsAlertFailures {
+		if strings.HasSuffix(failure, suffix) {
+			return alert
+		}
+	}
+	return ""
+}
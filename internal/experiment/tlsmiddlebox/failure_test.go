@@ -0,0 +1,92 @@
+package tlsmiddlebox
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestClassifyTLSAlert(t *testing.T) {
+	t.Run("we map each known alert", func(t *testing.T) {
+		for suffix, failure := range tlsAlertFailures {
+			if got := classifyTLSAlert("unknown_failure: " + suffix); got != failure {
+				t.Fatal("expected", failure, "got", got)
+			}
+		}
+	})
+
+	t.Run("we return an empty string for other alerts", func(t *testing.T) {
+		if got := classifyTLSAlert("unknown_failure: remote error: tls: close notify"); got != "" {
+			t.Fatal("expected empty string, got", got)
+		}
+	})
+
+	t.Run("we return an empty string for classified failures", func(t *testing.T) {
+		if got := classifyTLSAlert(netxlite.FailureSSLFailedHandshake); got != "" {
+			t.Fatal("expected empty string, got", got)
+		}
+	})
+
+	t.Run("netxlite does not know about our failures", func(t *testing.T) {
+		err := errors.New("remote error: tls: handshake failure")
+		if got := netxlite.ClassifyTLSHandshakeError(err); got == FailureSSLAlertHandshakeFailure {
+			t.Fatal("unexpected failure", got)
+		}
+	})
+}
+
+func TestMaybeClassifyTLSAlert(t *testing.T) {
+	t.Run("with nil handshake", func(t *testing.T) {
+		maybeClassifyTLSAlert(nil) // should not crash
+	})
+
+	t.Run("without failure", func(t *testing.T) {
+		handshake := &model.ArchivalTLSOrQUICHandshakeResult{}
+		maybeClassifyTLSAlert(handshake)
+		if handshake.Failure != nil {
+			t.Fatal("expected nil failure")
+		}
+	})
+
+	t.Run("with a server only supporting TLS 1.3", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(200)
+		}))
+		server.TLS = &tls.Config{MinVersion: tls.VersionTLS13}
+		server.StartTLS()
+		defer server.Close()
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		trace := measurexlite.NewTrace(0, time.Now())
+		thx := trace.NewTLSHandshakerStdlib(model.DiscardLogger)
+		config := &tls.Config{
+			InsecureSkipVerify: true,
+			MaxVersion:         tls.VersionTLS12,
+			ServerName:         "example.com",
+		}
+		tlsConn, err := thx.Handshake(context.Background(), conn, config)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if tlsConn != nil {
+			t.Fatal("expected nil conn")
+		}
+		handshake := trace.FirstTLSHandshakeOrNil()
+		maybeClassifyTLSAlert(handshake)
+		if handshake.Failure == nil || *handshake.Failure != FailureSSLAlertProtocolVersion {
+			t.Fatal("unexpected failure", handshake.Failure)
+		}
+	})
+}
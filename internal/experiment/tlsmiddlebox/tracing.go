@@ -103,7 +103,9 @@ func (m *Measurer) handshakeWithTTL(ctx context.Context, index int64, zeroTime t
 	// 4. reset the TTL value to ensure that conn closes successfully
 	// Note: Do not check for errors here
 	_ = setConnTTL(conn, 64)
-	iteration := newIterationFromHandshake(ttl, nil, soErr, trace.FirstTLSHandshakeOrNil())
+	handshake := trace.FirstTLSHandshakeOrNil()
+	maybeClassifyTLSAlert(handshake)
+	iteration := newIterationFromHandshake(ttl, nil, soErr, handshake)
 	tr.addIterations(iteration)
 }
 
@@ -16,11 +16,13 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 const (
 	testName    = "tlsping"
-	testVersion = "0.2.1"
+	testVersion = "0.3.0"
 )
 
 // Config contains the experiment configuration.
@@ -31,6 +33,10 @@ type Config struct {
 	// Delay is the delay between each repetition (in milliseconds).
 	Delay int64 `ooni:"number of milliseconds to wait before sending each ping"`
 
+	// Fingerprint is the OPTIONAL name of the uTLS fingerprint to use. When
+	// empty, we perform the TLS handshake using the standard library.
+	Fingerprint string `ooni:"name of the uTLS fingerprint to use (e.g., chrome)"`
+
 	// Repetitions is the number of repetitions for each ping.
 	Repetitions int64 `ooni:"number of times to repeat the measurement"`
 
@@ -52,6 +58,15 @@ func (c *Config) delay() time.Duration {
 	return time.Second
 }
 
+// clientHelloID returns the ClientHelloID to use or nil if we should
+// use the standard library to perform the TLS handshake.
+func (c *Config) clientHelloID() (*utls.ClientHelloID, error) {
+	if c.Fingerprint == "" {
+		return nil, nil
+	}
+	return netxlite.NewUTLSClientHelloIDFromName(c.Fingerprint)
+}
+
 func (c *Config) repetitions() int64 {
 	if c.Repetitions > 0 {
 		return c.Repetitions
@@ -129,6 +144,9 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	if parsed.Port() == "" {
 		return errMissingPort
 	}
+	if _, err := m.config.clientHelloID(); err != nil {
+		return err
+	}
 	tk := new(TestKeys)
 	measurement.TestKeys = tk
 	out := make(chan *SinglePing)
@@ -180,7 +198,7 @@ func (m *Measurer) tlsConnectAndHandshake(ctx context.Context, index int64,
 		return sp
 	}
 	defer conn.Close()
-	thx := trace.NewTLSHandshakerStdlib(logger)
+	thx := m.newTLSHandshaker(trace, logger)
 	// See https://github.com/ooni/probe/issues/2413 to understand
 	// why we're using nil to force netxlite to use the cached
 	// default Mozilla cert pool.
@@ -196,6 +214,16 @@ func (m *Measurer) tlsConnectAndHandshake(ctx context.Context, index int64,
 	return sp
 }
 
+// newTLSHandshaker creates the TLS handshaker, which uses uTLS when
+// the user has configured a fingerprint and the stdlib otherwise.
+func (m *Measurer) newTLSHandshaker(trace *measurexlite.Trace, logger model.Logger) model.TLSHandshaker {
+	id, _ := m.config.clientHelloID() // we've already validated the fingerprint inside Run
+	if id == nil {
+		return trace.NewTLSHandshakerStdlib(logger)
+	}
+	return trace.NewTLSHandshakerUTLS(logger, id)
+}
+
 // NewExperimentMeasurer creates a new ExperimentMeasurer.
 func NewExperimentMeasurer(config Config) model.ExperimentMeasurer {
 	return &Measurer{config: config}
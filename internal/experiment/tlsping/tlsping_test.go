@@ -40,39 +40,45 @@ const (
 	SNI    = "blocked.com"
 )
 
+// runHelperWithConfig runs the experiment with the given input and config.
+func runHelperWithConfig(ctx context.Context, t *testing.T, input string,
+	config Config) (*model.Measurement, model.ExperimentMeasurer, error) {
+	m := NewExperimentMeasurer(config)
+
+	if m.ExperimentName() != "tlsping" {
+		t.Fatal("invalid experiment name")
+	}
+	if m.ExperimentVersion() != "0.3.0" {
+		t.Fatal("invalid experiment version")
+	}
+
+	meas := &model.Measurement{
+		Input: model.MeasurementInput(input),
+	}
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger { return model.DiscardLogger },
+	}
+	callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
+	args := &model.ExperimentArgs{
+		Callbacks:   callbacks,
+		Measurement: meas,
+		Session:     sess,
+	}
+
+	err := m.Run(ctx, args)
+
+	return meas, m, err
+}
+
 func TestMeasurerRun(t *testing.T) {
 	// runHelper is an helper function to run this set of tests.
 	runHelper := func(ctx context.Context, input string) (*model.Measurement, model.ExperimentMeasurer, error) {
-		m := NewExperimentMeasurer(Config{
+		return runHelperWithConfig(ctx, t, input, Config{
 			ALPN:        "http/1.1",
 			Delay:       1, // millisecond
 			Repetitions: NPINGS,
 			SNI:         SNI,
 		})
-
-		if m.ExperimentName() != "tlsping" {
-			t.Fatal("invalid experiment name")
-		}
-		if m.ExperimentVersion() != "0.2.1" {
-			t.Fatal("invalid experiment version")
-		}
-
-		meas := &model.Measurement{
-			Input: model.MeasurementInput(input),
-		}
-		sess := &mocks.Session{
-			MockLogger: func() model.Logger { return model.DiscardLogger },
-		}
-		callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
-		args := &model.ExperimentArgs{
-			Callbacks:   callbacks,
-			Measurement: meas,
-			Session:     sess,
-		}
-
-		err := m.Run(ctx, args)
-
-		return meas, m, err
 	}
 
 	t.Run("with empty input", func(t *testing.T) {
@@ -253,6 +259,59 @@ func TestMeasurerRun(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("with unknown uTLS fingerprint", func(t *testing.T) {
+		_, _, err := runHelperWithConfig(context.Background(), t, "tlshandshake://8.8.8.8:443", Config{
+			Fingerprint: "netscape",
+		})
+		if !errors.Is(err, netxlite.ErrUnknownUTLSFingerprint) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with netem: with uTLS fingerprint: expect success", func(t *testing.T) {
+		// create a new test environment
+		env := netemx.MustNewQAEnv(netemx.QAEnvOptionNetStack(
+			"8.8.8.8",
+			&netemx.HTTPSecureServerFactory{
+				Factory:          netemx.ExampleWebPageHandlerFactory(),
+				Ports:            []int{443},
+				ServerNameMain:   SNI,
+				ServerNameExtras: []string{},
+			},
+		))
+		defer env.Close()
+
+		env.Do(func() {
+			meas, _, err := runHelperWithConfig(context.Background(), t, "tlshandshake://8.8.8.8:443", Config{
+				ALPN:        "http/1.1",
+				Delay:       1, // millisecond
+				Fingerprint: "chrome",
+				Repetitions: NPINGS,
+				SNI:         SNI,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			tk, _ := (meas.TestKeys).(*TestKeys)
+			if len(tk.Pings) != NPINGS {
+				t.Fatal("unexpected number of pings")
+			}
+
+			for _, p := range tk.Pings {
+				if p.TLSHandshake == nil {
+					t.Fatal("TLSHandshake should not be nil")
+				}
+				if p.TLSHandshake.Failure != nil {
+					t.Fatal("unexpected error", *p.TLSHandshake.Failure)
+				}
+				if p.TLSHandshake.Fingerprint != "chrome" {
+					t.Fatal("unexpected fingerprint", p.TLSHandshake.Fingerprint)
+				}
+			}
+		})
+	})
 }
 
 func TestConfig_sni(t *testing.T) {
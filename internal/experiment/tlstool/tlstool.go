@@ -19,18 +19,21 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
 	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 const (
 	testName    = "tlstool"
-	testVersion = "0.1.0"
+	testVersion = "0.2.0"
 )
 
 // Config contains the experiment configuration.
 type Config struct {
-	Delay int64  `ooni:"Milliseconds to wait between writes"`
-	SNI   string `ooni:"Force using the specified SNI"`
+	Delay       int64  `ooni:"Milliseconds to wait between writes"`
+	Fingerprint string `ooni:"Use the named uTLS fingerprint (e.g. 'chrome')"`
+	SNI         string `ooni:"Force using the specified SNI"`
 }
 
 // TestKeys contains the experiment results.
@@ -83,6 +86,11 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	measurement := args.Measurement
 	sess := args.Session
 
+	clientHelloID, err := m.clientHelloID()
+	if err != nil {
+		return err
+	}
+
 	// TODO(bassosimone): wondering whether this experiment should
 	// actually be merged with sniblocking instead?
 	tk := new(TestKeys)
@@ -93,9 +101,10 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		// TODO(bassosimone): here we actually want to use urlgetter
 		// if possible and collect standard test keys.
 		err := m.run(ctx, runConfig{
-			address:   address,
-			logger:    sess.Logger(),
-			newDialer: meth.newDialer,
+			address:       address,
+			clientHelloID: clientHelloID,
+			logger:        sess.Logger(),
+			newDialer:     meth.newDialer,
 		})
 		percent := float64(idx) / float64(len(allMethods))
 		callbacks.OnProgress(percent, fmt.Sprintf("%s: %+v", meth.name, err))
@@ -116,9 +125,10 @@ func (m Measurer) newDialer(logger model.Logger) model.Dialer {
 }
 
 type runConfig struct {
-	address   string
-	logger    model.Logger
-	newDialer func(internal.DialerConfig) internal.Dialer
+	address       string
+	clientHelloID *utls.ClientHelloID
+	logger        model.Logger
+	newDialer     func(internal.DialerConfig) internal.Dialer
 }
 
 func (m Measurer) run(ctx context.Context, config runConfig) error {
@@ -128,9 +138,10 @@ func (m Measurer) run(ctx context.Context, config runConfig) error {
 		SNI:    m.pattern(config.address),
 	})
 	tdialer := netx.NewTLSDialer(netx.Config{
-		Dialer:    dialer,
-		Logger:    config.logger,
-		TLSConfig: m.tlsConfig(),
+		Dialer:           dialer,
+		Logger:           config.logger,
+		TLSClientHelloID: config.clientHelloID,
+		TLSConfig:        m.tlsConfig(),
 	})
 	conn, err := tdialer.DialTLSContext(ctx, "tcp", config.address)
 	if err != nil {
@@ -140,6 +151,15 @@ func (m Measurer) run(ctx context.Context, config runConfig) error {
 	return nil
 }
 
+// clientHelloID returns the ClientHelloID to use or nil if we
+// should use the standard library for the TLS handshake.
+func (m Measurer) clientHelloID() (*utls.ClientHelloID, error) {
+	if m.config.Fingerprint == "" {
+		return nil, nil
+	}
+	return netxlite.NewUTLSClientHelloIDFromName(m.config.Fingerprint)
+}
+
 func (m Measurer) tlsConfig() *tls.Config {
 	if m.config.SNI != "" {
 		return &tls.Config{ServerName: m.config.SNI} // #nosec G402 - we need to use a large TLS versions range for measuring
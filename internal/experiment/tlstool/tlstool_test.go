@@ -2,12 +2,14 @@ package tlstool_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/internal/experiment/tlstool"
 	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 func TestMeasurerExperimentNameVersion(t *testing.T) {
@@ -15,7 +17,7 @@ func TestMeasurerExperimentNameVersion(t *testing.T) {
 	if measurer.ExperimentName() != "tlstool" {
 		t.Fatal("unexpected ExperimentName")
 	}
-	if measurer.ExperimentVersion() != "0.1.0" {
+	if measurer.ExperimentVersion() != "0.2.0" {
 		t.Fatal("unexpected ExperimentVersion")
 	}
 }
@@ -70,3 +72,39 @@ func TestRunWithCancelledContext(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestRunWithFingerprint(t *testing.T) {
+	ctx := context.Background()
+	measurer := tlstool.NewExperimentMeasurer(tlstool.Config{
+		Fingerprint: "firefox",
+	})
+	measurement := new(model.Measurement)
+	measurement.Input = "dns.google:853"
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: measurement,
+		Session:     &mockable.Session{},
+	}
+	err := measurer.Run(ctx, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunWithUnknownFingerprint(t *testing.T) {
+	ctx := context.Background()
+	measurer := tlstool.NewExperimentMeasurer(tlstool.Config{
+		Fingerprint: "netscape",
+	})
+	measurement := new(model.Measurement)
+	measurement.Input = "dns.google:853"
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: measurement,
+		Session:     &mockable.Session{},
+	}
+	err := measurer.Run(ctx, args)
+	if !errors.Is(err, netxlite.ErrUnknownUTLSFingerprint) {
+		t.Fatal("unexpected error", err)
+	}
+}
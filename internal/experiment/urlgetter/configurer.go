@@ -94,6 +94,13 @@ func (c Configurer) NewConfiguration() (Configuration, error) {
 	}
 	configuration.HTTPConfig.TLSConfig.InsecureSkipVerify = c.Config.NoTLSVerify
 	configuration.HTTPConfig.TLSConfig.RootCAs = c.Config.CertPool
+	if c.Config.TLSFingerprint != "" {
+		id, err := netxlite.NewUTLSClientHelloIDFromName(c.Config.TLSFingerprint)
+		if err != nil {
+			return configuration, err
+		}
+		configuration.HTTPConfig.TLSClientHelloID = id
+	}
 	// configure proxy
 	configuration.HTTPConfig.ProxyURL = c.ProxyURL
 	return configuration, nil
@@ -11,6 +11,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
 	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 func TestConfigurerNewConfigurationVanilla(t *testing.T) {
@@ -670,6 +671,39 @@ func TestConfigurerNewConfigurationTLSvInvalid(t *testing.T) {
 	}
 }
 
+func TestConfigurerNewConfigurationTLSFingerprint(t *testing.T) {
+	saver := new(tracex.Saver)
+	configurer := urlgetter.Configurer{
+		Config: urlgetter.Config{
+			TLSFingerprint: "firefox",
+		},
+		Logger: log.Log,
+		Saver:  saver,
+	}
+	configuration, err := configurer.NewConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configuration.HTTPConfig.TLSClientHelloID != &utls.HelloFirefox_Auto {
+		t.Fatal("invalid TLSClientHelloID")
+	}
+}
+
+func TestConfigurerNewConfigurationTLSFingerprintInvalid(t *testing.T) {
+	saver := new(tracex.Saver)
+	configurer := urlgetter.Configurer{
+		Config: urlgetter.Config{
+			TLSFingerprint: "netscape",
+		},
+		Logger: log.Log,
+		Saver:  saver,
+	}
+	_, err := configurer.NewConfiguration()
+	if !errors.Is(err, netxlite.ErrUnknownUTLSFingerprint) {
+		t.Fatalf("not the error we expected: %+v", err)
+	}
+}
+
 func TestConfigurerNewConfigurationProxyURL(t *testing.T) {
 	URL, _ := url.Parse("socks5://127.0.0.1:9050")
 	saver := new(tracex.Saver)
@@ -41,6 +41,7 @@ type Config struct {
 	NoTLSVerify       bool   `ooni:"Disable TLS verification"`
 	RejectDNSBogons   bool   `ooni:"Fail DNS lookup if response contains bogons"`
 	ResolverURL       string `ooni:"URL describing the resolver to use"`
+	TLSFingerprint    string `ooni:"Use the named uTLS fingerprint (e.g. 'chrome')"`
 	TLSServerName     string `ooni:"Force TLS to using a specific SNI in Client Hello"`
 	TLSVersion        string `ooni:"Force specific TLS version (e.g. 'TLSv1.3')"`
 	Tunnel            string `ooni:"Run experiment over a tunnel, e.g. psiphon"`
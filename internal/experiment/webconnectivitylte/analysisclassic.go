@@ -8,6 +8,7 @@ package webconnectivitylte
 //
 
 import (
+	"fmt"
 	"net"
 
 	"github.com/ooni/probe-cli/v3/internal/geoipx"
@@ -25,26 +26,46 @@ func analysisEngineClassic(tk *TestKeys, logger model.Logger) {
 	tk.analysisClassic(model.GeoIPASNLookupperFunc(geoipx.LookupASN), logger)
 }
 
-func (tk *TestKeys) analysisClassic(lookupper model.GeoIPASNLookupper, _ model.Logger) {
+func (tk *TestKeys) analysisClassic(lookupper model.GeoIPASNLookupper, logger model.Logger) {
 	// Since we run after all tasks have completed (or so we assume) we're
 	// not going to use any form of locking here.
 
 	// 1. produce web observations
 	container := minipipeline.NewWebObservationsContainer()
+	container.ProbeCC = tk.probeCC
 	container.IngestDNSLookupEvents(lookupper, tk.Queries...)
 	container.IngestTCPConnectEvents(lookupper, tk.TCPConnect...)
 	container.IngestTLSHandshakeEvents(tk.TLSHandshakes...)
 	container.IngestHTTPRoundTripEvents(tk.Requests...)
+	container.IngestHappyEyeballsEvents(tk.HappyEyeballs...)
+
+	// ingest the results of fetching the webpage using every endpoint, if any
+	if fetches := tk.EndpointFetches; fetches != nil {
+		container.IngestEndpointFetchQUICHandshakeEvents(lookupper, fetches.QUICHandshakes...)
+		container.IngestEndpointFetchHTTPRoundTripEvents(fetches.Requests...)
+	}
 
 	// be defensive in case the control request or response are not defined
-	if tk.ControlRequest != nil && tk.Control != nil {
-		// Implementation note: the only error that can happen here is when the input
-		// doesn't parse as a URL, which should have caused measurer.go to fail
+	//
+	// Implementation note: the only error that can happen here is when the input
+	// doesn't parse as a URL, which should have caused measurer.go to fail
+	switch {
+	case tk.ControlRequest != nil && len(tk.Controls) > 0:
+		// we queried several THs, so we merge their responses
+		runtimex.Try0(container.IngestControlResults(tk.ControlRequest, tk.Controls...))
+	case tk.ControlRequest != nil && tk.Control != nil:
 		runtimex.Try0(container.IngestControlMessages(tk.ControlRequest, tk.Control))
 	}
 
+	tk.analysisClassicWebObservations(lookupper, container, logger)
+}
+
+// analysisClassicWebObservations computes the analysis test keys using the given
+// web observations container, which contains all the observations we collected.
+func (tk *TestKeys) analysisClassicWebObservations(lookupper model.GeoIPASNLookupper,
+	container *minipipeline.WebObservationsContainer, logger model.Logger) {
 	// 2. compute extended analysis flags
-	analysisExtMain(lookupper, tk, container)
+	analysisExtMain(lookupper, tk, container, logger)
 
 	// 3. filter observations to only include results collected by the
 	// system resolver, which approximates v0.4's results
@@ -55,6 +76,13 @@ func (tk *TestKeys) analysisClassic(lookupper model.GeoIPASNLookupper, _ model.L
 
 	// 5. determine the DNS consistency
 	tk.DNSConsistency = analysisClassicDNSConsistency(woa)
+	tk.AnalysisTrace.Add("classic.dns_consistency", map[string]any{
+		"dns_lookup_unexpected_failure":           woa.DNSLookupUnexpectedFailure.Keys(),
+		"dns_lookup_success_with_invalid_addrs":   woa.DNSLookupSuccessWithInvalidAddressesClassic.Keys(),
+		"dns_lookup_success_with_valid_addr":      woa.DNSLookupSuccessWithValidAddressClassic.Keys(),
+		"dns_lookup_success_with_blockpage_addrs": woa.DNSLookupSuccessWithBlockpageFingerprint.Keys(),
+		"dns_lookup_expected_failure":             woa.DNSLookupExpectedFailure.Keys(),
+	}, tk.DNSConsistency.UnwrapOr("null"))
 
 	// 6. set DNSExperimentFailure
 	if !woa.DNSExperimentFailure.IsNone() && woa.DNSExperimentFailure.Unwrap() != "" {
@@ -67,17 +95,42 @@ func (tk *TestKeys) analysisClassic(lookupper model.GeoIPASNLookupper, _ model.L
 
 	// 8. compute blocking & accessible
 	analysisClassicComputeBlockingAccessible(woa, tk)
+
+	// 9. determine whether blocking is confirmed or based on heuristics
+	tk.BlockingType = analysisClassicBlockingType(tk)
+	tk.AnalysisTrace.Add("classic.blocking_type", map[string]any{
+		"blocking_flags":         tk.BlockingFlags,
+		"blockpage_fingerprints": tk.BlockpageFingerprints,
+	}, tk.BlockingType.UnwrapOr("null"))
+}
+
+// analysisClassicBlockingType returns "confirmed" when we matched a known blockpage
+// fingerprint, "heuristic" when we think there's blocking without matching any known
+// fingerprint, and None when we think there's no blocking.
+func analysisClassicBlockingType(tk *TestKeys) optional.Value[string] {
+	switch {
+	case len(tk.BlockpageFingerprints) > 0:
+		return optional.Some("confirmed")
+
+	case tk.BlockingFlags&^AnalysisBlockingFlagSuccess != 0:
+		return optional.Some("heuristic")
+
+	default:
+		return optional.None[string]()
+	}
 }
 
 func analysisClassicDNSConsistency(woa *minipipeline.WebAnalysis) optional.Value[string] {
 	switch {
 	case woa.DNSLookupUnexpectedFailure.Len() <= 0 && // no unexpected failures; and
 		woa.DNSLookupSuccessWithInvalidAddressesClassic.Len() <= 0 && // no invalid addresses; and
+		woa.DNSLookupSuccessWithBlockpageFingerprint.Len() <= 0 && // no known blockpage addrs; and
 		(woa.DNSLookupSuccessWithValidAddressClassic.Len() > 0 || // good addrs; or
 			woa.DNSLookupExpectedFailure.Len() > 0): // expected failures
 		return optional.Some("consistent")
 
 	case woa.DNSLookupSuccessWithInvalidAddressesClassic.Len() > 0 || // unexpected addrs; or
+		woa.DNSLookupSuccessWithBlockpageFingerprint.Len() > 0 || // known blockpage addrs; or
 		woa.DNSLookupUnexpectedFailure.Len() > 0 || // unexpected failures; or
 		(woa.DNSLookupSuccess.Len() > 0 && // successful lookups; and
 			!woa.ControlExpectations.IsNone() && // we have control info; and
@@ -93,6 +146,9 @@ func (tk *TestKeys) setHTTPDiffValues(woa *minipipeline.WebAnalysis) {
 	hds := newAnalysisHTTPDiffStatus(woa)
 	tk.BodyProportion = hds.BodyProportion.UnwrapOr(0)
 	tk.BodyLengthMatch = hds.BodyLengthMatch
+	tk.BodySimilarity = hds.BodySimilarity
+	tk.BodySimilarityMatch = hds.BodySimilarityMatch
+	tk.BodyComparison = analysisHTTPDiffBodyComparison(hds)
 	tk.HeadersMatch = hds.HeadersMatch
 	tk.StatusCodeMatch = hds.StatusCodeMatch
 	tk.TitleMatch = hds.TitleMatch
@@ -116,6 +172,9 @@ type analysisClassicTestKeysProxy interface {
 
 	// setWebsiteDown sets the test keys for a down website.
 	setWebsiteDown()
+
+	// traceDecision records that the given rule determined blocking and accessible.
+	traceDecision(rule string, inputs map[string]any)
 }
 
 var _ analysisClassicTestKeysProxy = &TestKeys{}
@@ -130,6 +189,11 @@ func (tk *TestKeys) bodyLengthMatch() optional.Value[bool] {
 	return tk.BodyLengthMatch
 }
 
+// bodySimilarityMatch implements analysisHTTPDiffValuesProvider.
+func (tk *TestKeys) bodySimilarityMatch() optional.Value[bool] {
+	return tk.BodySimilarityMatch
+}
+
 // headersMatch implements analysisHTTPDiffValuesProvider.
 func (tk *TestKeys) headersMatch() optional.Value[bool] {
 	return tk.HeadersMatch
@@ -226,26 +290,38 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 		// successful response whose status code is like 2xx, 4xx, or 5xx.
 		if !entry.HTTPResponseIsFinal.IsNone() && entry.HTTPResponseIsFinal.Unwrap() {
 
-			// 1.1. Handle the case of succesful response over TLS.
+			// 1.1. Handle the case of a cleartext response matching a known blockpage
+			// that the control did not match, which is conclusive.
+			if minipipeline.HTTPResponseBlockpageFingerprintIsConclusive(entry) {
+				tk.setBlockingString("http-diff")
+				tk.traceDecision("classic.blocking.1.1", minipipeline.DecisionTraceInputsFromObservation(entry))
+				return
+			}
+
+			// 1.2. Handle the case of succesful response over TLS.
 			if !entry.TLSHandshakeFailure.IsNone() && entry.TLSHandshakeFailure.Unwrap() == "" {
 				tk.setBlockingFalse()
+				tk.traceDecision("classic.blocking.1.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
-			// 1.2. Handle the case of missing HTTP control.
+			// 1.3. Handle the case of missing HTTP control.
 			if entry.ControlHTTPFailure.IsNone() {
 				tk.setBlockingNil()
+				tk.traceDecision("classic.blocking.1.3", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
-			// 1.3. Figure out whether the measurement and the control are close enough.
+			// 1.4. Figure out whether the measurement and the control are close enough.
 			if !tk.httpDiff() {
 				tk.setBlockingFalse()
+				tk.traceDecision("classic.blocking.1.4", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
-			// 1.4. There's something different in the two responses.
+			// 1.5. There's something different in the two responses.
 			tk.setBlockingString("http-diff")
+			tk.traceDecision("classic.blocking.1.5", minipipeline.DecisionTraceInputsFromObservation(entry))
 			return
 		}
 
@@ -258,6 +334,7 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 			if entry.ControlHTTPFailure.IsNone() {
 				tk.setBlockingNil()
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.2.1", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
@@ -265,12 +342,14 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 			if entry.ControlHTTPFailure.Unwrap() != "" {
 				tk.setWebsiteDown()
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.2.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
 			// 2.3. Handle the case where just the probe failed.
 			tk.setBlockingString("http-failure")
 			tk.setHTTPExperimentFailure(entry.Failure)
+			tk.traceDecision("classic.blocking.2.3", minipipeline.DecisionTraceInputsFromObservation(entry))
 			return
 		}
 
@@ -289,12 +368,14 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 				if entry.ControlHTTPFailure.IsNone() {
 					tk.setBlockingNil()
 					tk.setHTTPExperimentFailure(entry.Failure)
+					tk.traceDecision("classic.blocking.3.1.1", minipipeline.DecisionTraceInputsFromObservation(entry))
 					return
 				}
 
 				// 3.1.2. Otherwise, if the control worked, that's blocking.
 				tk.setBlockingString("http-failure")
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.3.1.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
@@ -302,12 +383,14 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 			if entry.ControlTLSHandshakeFailure.Unwrap() != "" {
 				tk.setWebsiteDown()
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.3.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
 			// 3.3. Handle the case where just the probe failed.
 			tk.setBlockingString("http-failure")
 			tk.setHTTPExperimentFailure(entry.Failure)
+			tk.traceDecision("classic.blocking.3.3", minipipeline.DecisionTraceInputsFromObservation(entry))
 			return
 		}
 
@@ -323,12 +406,14 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 				if entry.ControlHTTPFailure.IsNone() {
 					tk.setBlockingNil()
 					tk.setHTTPExperimentFailure(entry.Failure)
+					tk.traceDecision("classic.blocking.4.1.1", minipipeline.DecisionTraceInputsFromObservation(entry))
 					return
 				}
 
 				// 4.1.2. Otherwise, if the control worked, that's blocking.
 				tk.setBlockingString("http-failure")
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.4.1.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
@@ -336,12 +421,14 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 			if entry.ControlTCPConnectFailure.Unwrap() != "" {
 				tk.setWebsiteDown()
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.4.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
 			// 4.3. Handle the case where just the probe failed.
 			tk.setBlockingString("tcp_ip")
 			tk.setHTTPExperimentFailure(entry.Failure)
+			tk.traceDecision("classic.blocking.4.3", minipipeline.DecisionTraceInputsFromObservation(entry))
 			return
 		}
 
@@ -357,12 +444,14 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 				if entry.ControlHTTPFailure.IsNone() {
 					tk.setBlockingFalse()
 					tk.setHTTPExperimentFailure(entry.Failure)
+					tk.traceDecision("classic.blocking.5.1.1", minipipeline.DecisionTraceInputsFromObservation(entry))
 					return
 				}
 
 				// 5.1.2. Otherwise, if the control worked, that's blocking.
 				tk.setBlockingString("dns")
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.5.1.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
@@ -370,6 +459,7 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 			if entry.ControlDNSLookupFailure.Unwrap() != "" {
 				tk.setWebsiteDown()
 				tk.setHTTPExperimentFailure(entry.Failure)
+				tk.traceDecision("classic.blocking.5.2", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
@@ -380,12 +470,14 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 				!entry.ControlDNSResolvedAddrs.IsNone() &&
 				entry.ControlDNSResolvedAddrs.Unwrap().Len() <= 0 {
 				tk.setWebsiteDown()
+				tk.traceDecision("classic.blocking.5.3", minipipeline.DecisionTraceInputsFromObservation(entry))
 				return
 			}
 
 			// 5.4. Handle the case where just the probe failed.
 			tk.setBlockingString("dns")
 			tk.setHTTPExperimentFailure(entry.Failure)
+			tk.traceDecision("classic.blocking.5.4", minipipeline.DecisionTraceInputsFromObservation(entry))
 			return
 		}
 
@@ -401,6 +493,7 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 			analysisContainsOnlyLoopbackAddrs(entry.DNSResolvedAddrs.Unwrap()) &&
 			!analysisContainsOnlyLoopbackAddrs(entry.ControlDNSResolvedAddrs.Unwrap()) {
 			tk.setBlockingString("dns")
+			tk.traceDecision("classic.blocking.6", minipipeline.DecisionTraceInputsFromObservation(entry))
 			return
 		}
 
@@ -414,9 +507,26 @@ func analysisClassicComputeBlockingAccessible(woa *minipipeline.WebAnalysis, tk
 			analysisContainsOnlyLoopbackAddrs(entry.DNSResolvedAddrs.Unwrap()) &&
 			analysisContainsOnlyLoopbackAddrs(entry.ControlDNSResolvedAddrs.Unwrap()) {
 			tk.setWebsiteDown()
+			tk.traceDecision("classic.blocking.7", minipipeline.DecisionTraceInputsFromObservation(entry))
 			return
 		}
 	}
+
+	// 8. none of the above rules matched, so we leave blocking and accessible unset.
+	tk.traceDecision("classic.blocking.none", map[string]any{"observations": len(woa.Linear)})
+}
+
+// traceDecision implements analysisClassicTestKeysProxy.
+func (tk *TestKeys) traceDecision(rule string, inputs map[string]any) {
+	blocking := "null"
+	if tk.Blocking != nil {
+		blocking = fmt.Sprintf("%v", tk.Blocking)
+	}
+	accessible := "null"
+	if !tk.Accessible.IsNone() {
+		accessible = fmt.Sprintf("%v", tk.Accessible.Unwrap())
+	}
+	tk.AnalysisTrace.Add(rule, inputs, fmt.Sprintf("blocking=%s accessible=%s", blocking, accessible))
 }
 
 // analysisContainsOnlyLoopbackAddrs returns true iff the given set contains one or
@@ -10,6 +10,8 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/minipipeline"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/optional"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/tailscale/hujson"
 )
@@ -129,3 +131,87 @@ func TestAnalysisClassicContainsOnlyLoopbackAddrs(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalysisClassicBlockingType(t *testing.T) {
+	type testcase struct {
+		name   string
+		tk     *TestKeys
+		expect string
+	}
+
+	cases := []testcase{{
+		name: "with matched blockpage fingerprints",
+		tk: &TestKeys{
+			BlockingFlags:         AnalysisBlockingFlagDNSBlocking,
+			BlockpageFingerprints: []string{"dns_ir_002"},
+		},
+		expect: "confirmed",
+	}, {
+		name: "with blocking flags and without fingerprints",
+		tk: &TestKeys{
+			BlockingFlags:         AnalysisBlockingFlagTCPIPBlocking,
+			BlockpageFingerprints: []string{},
+		},
+		expect: "heuristic",
+	}, {
+		name: "with success",
+		tk: &TestKeys{
+			BlockingFlags:         AnalysisBlockingFlagSuccess,
+			BlockpageFingerprints: []string{},
+		},
+		expect: "",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := analysisClassicBlockingType(tc.tk).UnwrapOr(""); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestAnalysisClassicComputeBlockingAccessibleTrace(t *testing.T) {
+	type testcase struct {
+		name          string
+		linear        []*minipipeline.WebObservation
+		expectRule    string
+		expectOutcome string
+	}
+
+	cases := []testcase{{
+		name: "with a TCP connect failure and a successful control",
+		linear: []*minipipeline.WebObservation{{
+			TagDepth:                 optional.Some[int64](0),
+			Type:                     minipipeline.WebObservationTypeTCPConnect,
+			Failure:                  optional.Some(netxlite.FailureConnectionRefused),
+			TransactionID:            3,
+			ControlTCPConnectFailure: optional.Some(""),
+		}},
+		expectRule:    "classic.blocking.4.3",
+		expectOutcome: "blocking=tcp_ip accessible=false",
+	}, {
+		name:          "without any observation",
+		linear:        []*minipipeline.WebObservation{},
+		expectRule:    "classic.blocking.none",
+		expectOutcome: "blocking=null accessible=null",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tk := NewTestKeys()
+			woa := &minipipeline.WebAnalysis{Linear: tc.linear}
+			analysisClassicComputeBlockingAccessible(woa, tk)
+			if len(tk.AnalysisTrace) != 1 {
+				t.Fatal("expected a single trace entry, got", len(tk.AnalysisTrace))
+			}
+			entry := tk.AnalysisTrace[0]
+			if entry.Rule != tc.expectRule {
+				t.Fatal("expected", tc.expectRule, "got", entry.Rule)
+			}
+			if entry.Outcome != tc.expectOutcome {
+				t.Fatal("expected", tc.expectOutcome, "got", entry.Outcome)
+			}
+		})
+	}
+}
@@ -75,4 +75,25 @@ const (
 	// AnalysisDNSFlagUnexpectedAddrs indicates the TH resolved
 	// different addresses from the probe
 	AnalysisDNSFlagUnexpectedAddrs
+
+	// AnalysisDNSFlagInjection indicates that we received duplicate
+	// DNS-over-UDP responses with different addresses for the same
+	// query, which is how DNS injection typically manifests itself
+	AnalysisDNSFlagInjection
+)
+
+const (
+	// AnalysisTLSFlagCertificateDiffersFromControl indicates that the probe saw
+	// a leaf certificate public key different from the one seen by the TH
+	AnalysisTLSFlagCertificateDiffersFromControl = 1 << iota
+)
+
+const (
+	// AnalysisEndpointFetchFlagUnexpectedFailure indicates that fetching the webpage
+	// using a specific endpoint failed while the TH could fetch the webpage
+	AnalysisEndpointFetchFlagUnexpectedFailure = 1 << iota
+
+	// AnalysisEndpointFetchFlagHTTPDiff indicates that the response we fetched using
+	// a specific endpoint differs from the TH's final response
+	AnalysisEndpointFetchFlagHTTPDiff
 )
@@ -16,13 +16,15 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
-// analysisExtMain computes the extended analysis.
+// analysisExtMain computes the extended analysis and emits informational
+// messages describing it using the given logger.
 //
 // This function MUTATES the [*TestKeys].
 func analysisExtMain(
 	lookupper model.GeoIPASNLookupper,
 	tk *TestKeys,
 	container *minipipeline.WebObservationsContainer,
+	logger model.Logger,
 ) {
 	// compute the web analysis
 	analysis := minipipeline.AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
@@ -45,17 +47,48 @@ func analysisExtMain(
 	// HTTP success analysis (i.e., only if we manage to get an HTTP response)
 	analysisExtHTTPFinalResponse(tk, analysis, &info)
 
+	// redirect chain analysis (i.e., whether the probe diverged from the control
+	// while following redirects, which helps to explain the final response)
+	analysisExtRedirectChain(tk, analysis, &info)
+
+	// blockpage fingerprints analysis (i.e., whether we matched known blockpages,
+	// which allows us to say that blocking is confirmed)
+	analysisExtBlockpageFingerprints(tk, analysis, &info)
+
+	// endpoint fetches analysis (i.e., whether fetching the webpage using every
+	// endpoint reveals blocking affecting only some endpoints)
+	analysisExtEndpointFetches(tk, analysis, &info)
+
 	// handle the cases where the probe and the TH both failed, which we can confidently
 	// only evaluate for DNS, TCP, and TLS during the 0-th redirect.
 	analysisExtExpectedFailures(tk, analysis, &info)
 
-	// print the content of the analysis only if there's some content to print
+	// control disagreements analysis (i.e., whether the test helpers we queried in
+	// parallel disagree with each other, which makes the control less reliable)
+	analysisExtControlDisagreements(tk, container, &info)
+
+	// log the content of the analysis only if there's some content to log
 	if content := info.String(); content != "" {
-		fmt.Printf("\n")
-		fmt.Printf("Extended Analysis\n")
-		fmt.Printf("-----------------\n")
-		fmt.Printf("%s", content)
-		fmt.Printf("\n\n")
+		logger.Infof("Extended Analysis\n-----------------\n%s", strings.TrimSuffix(content, "\n"))
+	}
+}
+
+func analysisExtControlDisagreements(
+	tk *TestKeys, container *minipipeline.WebObservationsContainer, info io.Writer) {
+	var observations []*minipipeline.WebObservation
+	observations = append(observations, container.DNSLookupFailures...)
+	observations = append(observations, container.DNSLookupSuccesses...)
+	for _, obs := range container.KnownTCPEndpoints {
+		observations = append(observations, obs)
+	}
+	for _, obs := range container.EndpointFetches {
+		observations = append(observations, obs)
+	}
+	for _, obs := range observations {
+		tk.ControlDisagreements |= obs.ControlDisagreements.UnwrapOr(0)
+	}
+	if tk.ControlDisagreements != 0 {
+		fmt.Fprintf(info, "- the test helpers disagree with each other: %d\n", tk.ControlDisagreements)
 	}
 }
 
@@ -75,20 +108,32 @@ func analysisExtDNS(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Wr
 		// See, for example, polito.it, which has addrs 192.168.59.6 and 192.168.40.1, as of
 		// 2024-01-24. Clearly a misconfiguration and bogons, but it can happen.
 		tk.DNSFlags |= AnalysisFlagDNSBogon
+		analysisExtTrace(tk, "ext.dns.bogon", failures, "dns_bogon")
 		fmt.Fprintf(info, "- transactions with bogon IP addrs: %s\n", failures.String())
 	}
 
 	if failures := analysis.DNSLookupUnexpectedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagDNSBlocking
 		tk.DNSFlags |= AnalysisDNSFlagUnexpectedFailure
+		analysisExtTrace(tk, "ext.dns.unexpected_failure", failures, "dns_blocking")
 		fmt.Fprintf(info, "- transactions with unexpected DNS lookup failures: %s\n", failures.String())
 	}
 
 	if failures := analysis.DNSLookupSuccessWithInvalidAddresses; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagDNSBlocking
 		tk.DNSFlags |= AnalysisDNSFlagUnexpectedAddrs
+		analysisExtTrace(tk, "ext.dns.unexpected_addrs", failures, "dns_blocking")
 		fmt.Fprintf(info, "- transactions with invalid IP addrs: %s\n", failures.String())
 	}
+
+	// Implementation note: we do not set AnalysisBlockingFlagDNSBlocking here because
+	// we only know that someone injected responses, while the above checks, which use
+	// the control, determine whether the addresses we used are actually invalid.
+	if injected := analysis.DNSLookupInjection; injected.Len() > 0 {
+		tk.DNSFlags |= AnalysisDNSFlagInjection
+		analysisExtTrace(tk, "ext.dns.injection", injected, "dns_injection")
+		fmt.Fprintf(info, "- transactions with injected DNS responses: %s\n", injected.String())
+	}
 }
 
 func analysisExtEndpointFailure(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
@@ -102,18 +147,30 @@ func analysisExtEndpointFailure(tk *TestKeys, analysis *minipipeline.WebAnalysis
 	// TCP analysis
 	if failures := analysis.TCPConnectUnexpectedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagTCPIPBlocking
+		analysisExtTrace(tk, "ext.tcp.unexpected_failure", failures, "tcp_ip_blocking")
 		fmt.Fprintf(info, "- transactions with unexpected TCP connect failures: %s\n", failures.String())
 	}
 
 	// TLS analysis
 	if failures := analysis.TLSHandshakeUnexpectedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagTLSBlocking
+		analysisExtTrace(tk, "ext.tls.unexpected_failure", failures, "tls_blocking")
 		fmt.Fprintf(info, "- transactions with unexpected TLS handshake failures: %s\n", failures.String())
 	}
 
+	// Implementation note: we do not set AnalysisBlockingFlagTLSBlocking here because
+	// a server may legitimately use several keys, therefore we only flag the anomaly
+	// and leave it to further analysis to determine whether there is a TLS MITM.
+	if differs := analysis.TLSHandshakeCertificateDiffersFromControl; differs.Len() > 0 {
+		tk.TLSFlags |= AnalysisTLSFlagCertificateDiffersFromControl
+		analysisExtTrace(tk, "ext.tls.certificate_differs_from_control", differs, "tls_certificate_differs")
+		fmt.Fprintf(info, "- transactions whose certificate differs from the control: %s\n", differs.String())
+	}
+
 	// HTTP failure analysis
 	if failures := analysis.HTTPRoundTripUnexpectedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagHTTPBlocking
+		analysisExtTrace(tk, "ext.http.unexpected_failure", failures, "http_blocking")
 		fmt.Fprintf(info, "- transactions with unexpected HTTP round trip failures: %s\n", failures.String())
 	}
 }
@@ -128,6 +185,9 @@ func analysisExtHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalys
 			"- there is no control information to compare to the final response (transaction: %d)\n",
 			success.Unwrap(),
 		)
+		tk.AnalysisTrace.Add("ext.final_response.tcp_without_control", map[string]any{
+			"transaction_id": success.Unwrap(),
+		}, "unknown")
 		return
 	}
 
@@ -138,6 +198,9 @@ func analysisExtHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalys
 		fmt.Fprintf(info, "- the final response (transaction: %d) uses TLS: automatic success\n", success.Unwrap())
 		tk.NullNullFlags |= AnalysisFlagNullNullSuccessfulHTTPS
 		tk.BlockingFlags |= AnalysisBlockingFlagSuccess
+		tk.AnalysisTrace.Add("ext.final_response.tls_without_control", map[string]any{
+			"transaction_id": success.Unwrap(),
+		}, "success")
 		return
 	}
 
@@ -147,6 +210,9 @@ func analysisExtHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalys
 	if success := analysis.HTTPFinalResponseSuccessTLSWithControl; !success.IsNone() {
 		fmt.Fprintf(info, "- the final response (transaction: %d) uses TLS: automatic success\n", success.Unwrap())
 		tk.BlockingFlags |= AnalysisBlockingFlagSuccess
+		tk.AnalysisTrace.Add("ext.final_response.tls_with_control", map[string]any{
+			"transaction_id": success.Unwrap(),
+		}, "success")
 		return
 	}
 
@@ -156,17 +222,79 @@ func analysisExtHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalys
 	if success := analysis.HTTPFinalResponseSuccessTCPWithControl; !success.IsNone() {
 		txID := success.Unwrap()
 		hds := newAnalysisHTTPDiffStatus(analysis)
+		inputs := analysisHTTPDiffTraceInputs(hds)
+		inputs["transaction_id"] = txID
 		if analysisHTTPDiffAlgorithm(hds) {
 			tk.BlockingFlags |= AnalysisBlockingFlagHTTPDiff
+			tk.AnalysisTrace.Add("ext.final_response.tcp_with_control", inputs, "http_diff")
 			fmt.Fprintf(info, "- the final response (transaction: %d) differs from the control response\n", txID)
 			return
 		}
 		fmt.Fprintf(info, "- the final response (transaction: %d) matches the control response\n", txID)
 		tk.BlockingFlags |= AnalysisBlockingFlagSuccess
+		tk.AnalysisTrace.Add("ext.final_response.tcp_with_control", inputs, "success")
 		return
 	}
 }
 
+func analysisExtRedirectChain(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
+	// Implementation note: we do not set any flag here because the final response
+	// analysis already determines whether there is blocking. We just point out at which
+	// redirect the probe's responses started differing from the control's ones.
+	if divergence := analysis.HTTPRedirectChainDivergence; divergence.Len() > 0 {
+		tk.AnalysisTrace.Add("ext.redirect_chain.divergence", map[string]any{
+			"transactions":        divergence.Keys(),
+			"first_divergent_hop": analysis.HTTPRedirectChainFirstDivergentHop.UnwrapOr(0),
+		}, "informational")
+		fmt.Fprintf(
+			info, "- transactions diverging from the control's redirect chain starting at redirect %d: %s\n",
+			analysis.HTTPRedirectChainFirstDivergentHop.UnwrapOr(0), divergence.String(),
+		)
+	}
+}
+
+func analysisExtEndpointFetches(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
+	// Implementation note: we do not set BlockingFlags here because these fetches
+	// do not follow redirects, so we only flag the anomalies and leave it to further
+	// analysis to determine whether there is blocking affecting specific endpoints.
+	if failures := analysis.HTTPEndpointFetchUnexpectedFailure; failures.Len() > 0 {
+		tk.EndpointFetchFlags |= AnalysisEndpointFetchFlagUnexpectedFailure
+		analysisExtTrace(tk, "ext.endpoint_fetch.unexpected_failure", failures, "endpoint_fetch_failure")
+		fmt.Fprintf(info, "- endpoint fetches with unexpected failures: %s\n", failures.String())
+	}
+
+	if diffs := analysis.HTTPEndpointFetchDiff; diffs.Len() > 0 {
+		tk.EndpointFetchFlags |= AnalysisEndpointFetchFlagHTTPDiff
+		analysisExtTrace(tk, "ext.endpoint_fetch.http_diff", diffs, "endpoint_fetch_http_diff")
+		fmt.Fprintf(info, "- endpoint fetches with HTTP diff: %s\n", diffs.String())
+	}
+}
+
+func analysisExtBlockpageFingerprints(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
+	tk.BlockpageDBVersion = minipipeline.DefaultFingerprintDB.Version
+
+	// note: unlike bogons, DNS addresses matching a fingerprint are known to
+	// be used by censors to redirect users to blockpages
+	if matches := analysis.DNSLookupSuccessWithBlockpageFingerprint; matches.Len() > 0 {
+		tk.BlockingFlags |= AnalysisBlockingFlagDNSBlocking
+		analysisExtTrace(tk, "ext.blockpage.dns", matches, "dns_blocking")
+		fmt.Fprintf(info, "- transactions with known blockpage IP addrs: %s\n", matches.String())
+	}
+
+	// note: like classic.blocking.1.1, this set only contains cleartext responses
+	// the control did not match (see minipipeline.HTTPResponseBlockpageFingerprintIsConclusive)
+	if matches := analysis.HTTPRoundTripWithBlockpageFingerprint; matches.Len() > 0 {
+		tk.BlockingFlags |= AnalysisBlockingFlagHTTPDiff
+		analysisExtTrace(tk, "ext.blockpage.http", matches, "http_diff")
+		fmt.Fprintf(info, "- transactions with known blockpage responses: %s\n", matches.String())
+	}
+
+	if fingerprints := analysis.BlockpageFingerprints; fingerprints.Len() > 0 {
+		tk.BlockpageFingerprints = fingerprints.Keys()
+		fmt.Fprintf(info, "- matched blockpage fingerprints: %s\n", fingerprints.String())
+	}
+}
+
 func analysisExtRedirectErrors(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
 	// Implementation note: we care about cases in which we don't have a final response
 	// to compare to and we have unexplained failures. We define "unexplained failure" a
@@ -208,6 +336,7 @@ func analysisExtRedirectErrors(tk *TestKeys, analysis *minipipeline.WebAnalysis,
 	// DNS lookups or endpoints failing in different ways here
 	if failures := analysis.DNSLookupUnexplainedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagDNSBlocking
+		analysisExtTrace(tk, "ext.redirect_errors.dns", failures, "dns_blocking")
 		fmt.Fprintf(
 			info, "- transactions with unexplained DNS lookup failures and successful control: %s\n",
 			failures.String(),
@@ -216,6 +345,7 @@ func analysisExtRedirectErrors(tk *TestKeys, analysis *minipipeline.WebAnalysis,
 
 	if failures := analysis.TCPConnectUnexplainedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagTCPIPBlocking
+		analysisExtTrace(tk, "ext.redirect_errors.tcp", failures, "tcp_ip_blocking")
 		fmt.Fprintf(
 			info, "- transactions with unexplained TCP connect failures and successful control: %s\n",
 			failures.String(),
@@ -224,6 +354,7 @@ func analysisExtRedirectErrors(tk *TestKeys, analysis *minipipeline.WebAnalysis,
 
 	if failures := analysis.TLSHandshakeUnexplainedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagTLSBlocking
+		analysisExtTrace(tk, "ext.redirect_errors.tls", failures, "tls_blocking")
 		fmt.Fprintf(
 			info, "- transactions with unexplained TLS handshake failures and successful control: %s\n",
 			failures.String(),
@@ -232,6 +363,7 @@ func analysisExtRedirectErrors(tk *TestKeys, analysis *minipipeline.WebAnalysis,
 
 	if failures := analysis.HTTPRoundTripUnexplainedFailure; failures.Len() > 0 {
 		tk.BlockingFlags |= AnalysisBlockingFlagHTTPBlocking
+		analysisExtTrace(tk, "ext.redirect_errors.http", failures, "http_blocking")
 		fmt.Fprintf(
 			info, "- transactions with unexplained HTTP round trip failures and successful control: %s\n",
 			failures.String(),
@@ -260,6 +392,7 @@ func analysisExtExpectedFailures(tk *TestKeys, analysis *minipipeline.WebAnalysi
 	// on Android's getaddrinfo behavior.
 	if expected := analysis.DNSLookupExpectedFailure; expected.Len() > 0 {
 		tk.NullNullFlags |= AnalysisFlagNullNullExpectedDNSLookupFailure
+		analysisExtTrace(tk, "ext.expected_failures.dns", expected, "expected_failure")
 		fmt.Fprintf(
 			info, "- transactions with expected DNS lookup failures: %s\n",
 			expected.String(),
@@ -272,6 +405,7 @@ func analysisExtExpectedFailures(tk *TestKeys, analysis *minipipeline.WebAnalysi
 	// See also https://github.com/ooni/probe/issues/2299.
 	if expected := analysis.TCPConnectExpectedFailure; expected.Len() > 0 {
 		tk.NullNullFlags |= AnalysisFlagNullNullExpectedTCPConnectFailure
+		analysisExtTrace(tk, "ext.expected_failures.tcp", expected, "expected_failure")
 		fmt.Fprintf(
 			info, "- transactions with expected TCP connect failures: %s\n",
 			expected.String(),
@@ -281,6 +415,7 @@ func analysisExtExpectedFailures(tk *TestKeys, analysis *minipipeline.WebAnalysi
 	// See https://github.com/ooni/probe/issues/2300.
 	if expected := analysis.TLSHandshakeExpectedFailure; expected.Len() > 0 {
 		tk.NullNullFlags |= AnalysisFlagNullNullExpectedTLSHandshakeFailure
+		analysisExtTrace(tk, "ext.expected_failures.tls", expected, "expected_failure")
 		fmt.Fprintf(
 			info, "- transactions with expected TLS handshake failures: %s\n",
 			expected.String(),
@@ -303,6 +438,7 @@ func analysisExtExpectedFailures(tk *TestKeys, analysis *minipipeline.WebAnalysi
 		expect := analysis.ControlExpectations.Unwrap()
 		if expect.DNSAddresses.Len() <= 0 && analysis.DNSLookupSuccess.Len() > 0 {
 			tk.NullNullFlags |= AnalysisFlagNullNullUnexpectedDNSLookupSuccess
+			analysisExtTrace(tk, "ext.expected_failures.dns_success", analysis.DNSLookupSuccess, "unexpected_success")
 			fmt.Fprintf(
 				info, "- transactions that unexpectedly resolved IP addresses: %s\n",
 				analysis.DNSLookupSuccess.String(),
@@ -310,3 +446,9 @@ func analysisExtExpectedFailures(tk *TestKeys, analysis *minipipeline.WebAnalysi
 		}
 	}
 }
+
+// analysisExtTrace records into the decision trace that the given rule
+// matched the given transactions and led to the given outcome.
+func analysisExtTrace(tk *TestKeys, rule string, transactions minipipeline.Set[int64], outcome string) {
+	tk.AnalysisTrace.Add(rule, map[string]any{"transactions": transactions.Keys()}, outcome)
+}
@@ -0,0 +1,141 @@
+package webconnectivitylte
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/minipipeline"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+func TestAnalysisExtDNSInjection(t *testing.T) {
+	t.Run("without injection", func(t *testing.T) {
+		tk := NewTestKeys()
+		analysis := &minipipeline.WebAnalysis{}
+		var info strings.Builder
+		analysisExtDNS(tk, analysis, &info)
+		if tk.DNSFlags&AnalysisDNSFlagInjection != 0 {
+			t.Fatal("did not expect the injection flag")
+		}
+	})
+
+	t.Run("with injection", func(t *testing.T) {
+		tk := NewTestKeys()
+		analysis := &minipipeline.WebAnalysis{}
+		analysis.DNSLookupInjection.Add(1)
+		var info strings.Builder
+		analysisExtDNS(tk, analysis, &info)
+		if tk.DNSFlags&AnalysisDNSFlagInjection == 0 {
+			t.Fatal("expected the injection flag")
+		}
+		if tk.BlockingFlags&AnalysisBlockingFlagDNSBlocking != 0 {
+			t.Fatal("did not expect the DNS blocking flag")
+		}
+		if !strings.Contains(info.String(), "injected DNS responses: [1]") {
+			t.Fatal("unexpected info", info.String())
+		}
+	})
+
+	t.Run("Finalize attaches the duplicate responses to the queries", func(t *testing.T) {
+		tk := NewTestKeys()
+		query := &model.ArchivalDNSLookupResult{
+			Engine:          "udp",
+			QueryType:       "A",
+			ResolverAddress: "8.8.8.8:53",
+			TransactionID:   1,
+		}
+		dup := &model.ArchivalDNSLookupResult{
+			Engine:          "udp",
+			QueryType:       "A",
+			ResolverAddress: "8.8.8.8:53",
+			TransactionID:   1,
+		}
+		tk.Queries = []*model.ArchivalDNSLookupResult{query}
+		tk.DNSDuplicateResponses = []*model.ArchivalDNSLookupResult{dup}
+		tk.Finalize(model.DiscardLogger)
+		if diff := cmp.Diff([]*model.ArchivalDNSLookupResult{dup}, query.DuplicateResponses); diff != "" {
+			t.Fatal(diff)
+		}
+		if len(tk.DNSDuplicateResponses) != 0 {
+			t.Fatal("expected no unattached duplicate responses")
+		}
+	})
+}
+
+func TestAnalysisExtControlDisagreements(t *testing.T) {
+	t.Run("without disagreements", func(t *testing.T) {
+		tk := NewTestKeys()
+		container := minipipeline.NewWebObservationsContainer()
+		container.DNSLookupSuccesses = append(container.DNSLookupSuccesses, &minipipeline.WebObservation{})
+		var info strings.Builder
+		analysisExtControlDisagreements(tk, container, &info)
+		if tk.ControlDisagreements != 0 {
+			t.Fatal("expected zero, got", tk.ControlDisagreements)
+		}
+		if info.Len() != 0 {
+			t.Fatal("expected no info, got", info.String())
+		}
+	})
+
+	t.Run("with disagreements", func(t *testing.T) {
+		tk := NewTestKeys()
+		container := minipipeline.NewWebObservationsContainer()
+		container.DNSLookupSuccesses = append(container.DNSLookupSuccesses, &minipipeline.WebObservation{
+			ControlDisagreements: optional.Some[int64](minipipeline.ControlDisagreementDNSAddrs),
+		})
+		container.KnownTCPEndpoints[1] = &minipipeline.WebObservation{
+			ControlDisagreements: optional.Some[int64](minipipeline.ControlDisagreementTCPConnect),
+		}
+		var info strings.Builder
+		analysisExtControlDisagreements(tk, container, &info)
+		expect := int64(minipipeline.ControlDisagreementDNSAddrs | minipipeline.ControlDisagreementTCPConnect)
+		if tk.ControlDisagreements != expect {
+			t.Fatal("expected", expect, "got", tk.ControlDisagreements)
+		}
+		if info.Len() == 0 {
+			t.Fatal("expected info")
+		}
+	})
+}
+
+func TestAnalysisExtEndpointFetches(t *testing.T) {
+	t.Run("without anomalies", func(t *testing.T) {
+		tk := NewTestKeys()
+		var info strings.Builder
+		analysisExtEndpointFetches(tk, &minipipeline.WebAnalysis{}, &info)
+		if tk.EndpointFetchFlags != 0 {
+			t.Fatal("expected zero, got", tk.EndpointFetchFlags)
+		}
+		if tk.BlockingFlags != 0 {
+			t.Fatal("expected zero, got", tk.BlockingFlags)
+		}
+		if info.Len() != 0 {
+			t.Fatal("expected no info, got", info.String())
+		}
+	})
+
+	t.Run("with unexpected failures and diffs", func(t *testing.T) {
+		tk := NewTestKeys()
+		analysis := &minipipeline.WebAnalysis{
+			HTTPEndpointFetchUnexpectedFailure: minipipeline.NewSet[int64](1),
+			HTTPEndpointFetchDiff:              minipipeline.NewSet[int64](2),
+		}
+		var info strings.Builder
+		analysisExtEndpointFetches(tk, analysis, &info)
+		expect := int64(AnalysisEndpointFetchFlagUnexpectedFailure | AnalysisEndpointFetchFlagHTTPDiff)
+		if tk.EndpointFetchFlags != expect {
+			t.Fatal("expected", expect, "got", tk.EndpointFetchFlags)
+		}
+		if tk.BlockingFlags != 0 {
+			t.Fatal("expected zero, got", tk.BlockingFlags)
+		}
+		if len(tk.AnalysisTrace) != 2 {
+			t.Fatal("expected two trace entries, got", len(tk.AnalysisTrace))
+		}
+		if info.Len() == 0 {
+			t.Fatal("expected info")
+		}
+	})
+}
@@ -11,11 +11,13 @@ import (
 
 // analysisHTTPDiffStatus contains the status relevant to compute HTTP diff.
 type analysisHTTPDiffStatus struct {
-	BodyProportion  optional.Value[float64] `json:"body_proportion"`
-	BodyLengthMatch optional.Value[bool]    `json:"body_length_match"`
-	HeadersMatch    optional.Value[bool]    `json:"headers_match"`
-	StatusCodeMatch optional.Value[bool]    `json:"status_code_match"`
-	TitleMatch      optional.Value[bool]    `json:"title_match"`
+	BodyProportion      optional.Value[float64] `json:"body_proportion"`
+	BodyLengthMatch     optional.Value[bool]    `json:"body_length_match"`
+	BodySimilarity      optional.Value[float64] `json:"x_body_similarity"`
+	BodySimilarityMatch optional.Value[bool]    `json:"x_body_similarity_match"`
+	HeadersMatch        optional.Value[bool]    `json:"headers_match"`
+	StatusCodeMatch     optional.Value[bool]    `json:"status_code_match"`
+	TitleMatch          optional.Value[bool]    `json:"title_match"`
 }
 
 // newAnalysisHTTPDiffStatus constructs a new [*analysisHTTPDiffStatus].
@@ -30,6 +32,22 @@ func newAnalysisHTTPDiffStatus(analysis *minipipeline.WebAnalysis) *analysisHTTP
 		hds.BodyLengthMatch = optional.Some(value)
 	}
 
+	// BodySimilarity & BodySimilarityMatch
+	//
+	// Implementation note: identical bodies may lack a simhash when they do not
+	// contain any word, so we check whether the SHA-256 matches first.
+	const bodySimilarityThreshold = 0.85
+	switch {
+	case analysis.HTTPFinalResponseDiffBodySHA256Match.UnwrapOr(false):
+		hds.BodySimilarity = optional.Some(1.0)
+		hds.BodySimilarityMatch = optional.Some(true)
+
+	case !analysis.HTTPFinalResponseDiffBodySimilarity.IsNone():
+		hds.BodySimilarity = analysis.HTTPFinalResponseDiffBodySimilarity
+		value := hds.BodySimilarity.Unwrap() >= bodySimilarityThreshold
+		hds.BodySimilarityMatch = optional.Some(value)
+	}
+
 	// HeadersMatch
 	if !analysis.HTTPFinalResponseDiffUncommonHeadersIntersection.IsNone() {
 		value := len(analysis.HTTPFinalResponseDiffUncommonHeadersIntersection.Unwrap()) > 0
@@ -53,6 +71,7 @@ func newAnalysisHTTPDiffStatus(analysis *minipipeline.WebAnalysis) *analysisHTTP
 
 type analysisHTTPDiffValuesProvider interface {
 	bodyLengthMatch() optional.Value[bool]
+	bodySimilarityMatch() optional.Value[bool]
 	headersMatch() optional.Value[bool]
 	statusCodeMatch() optional.Value[bool]
 	titleMatch() optional.Value[bool]
@@ -65,6 +84,11 @@ func (hds *analysisHTTPDiffStatus) bodyLengthMatch() optional.Value[bool] {
 	return hds.BodyLengthMatch
 }
 
+// bodySimilarityMatch implements analysisHTTPDiffValuesProvider.
+func (hds *analysisHTTPDiffStatus) bodySimilarityMatch() optional.Value[bool] {
+	return hds.BodySimilarityMatch
+}
+
 // headersMatch implements analysisHTTPDiffValuesProvider.
 func (hds *analysisHTTPDiffStatus) headersMatch() optional.Value[bool] {
 	return hds.HeadersMatch
@@ -80,11 +104,46 @@ func (hds *analysisHTTPDiffStatus) titleMatch() optional.Value[bool] {
 	return hds.TitleMatch
 }
 
+const (
+	// analysisHTTPDiffBodyComparisonLength means we compared the body lengths.
+	analysisHTTPDiffBodyComparisonLength = "body_length"
+
+	// analysisHTTPDiffBodyComparisonSimilarity means we compared the body similarity.
+	analysisHTTPDiffBodyComparisonSimilarity = "body_similarity"
+)
+
+// analysisHTTPDiffBodyComparison returns which body comparison [analysisHTTPDiffAlgorithm]
+// uses, which is the similarity when we know how similar the bodies are and otherwise
+// the body length as in v0.4. We return None when we could not compare the bodies.
+func analysisHTTPDiffBodyComparison(p analysisHTTPDiffValuesProvider) optional.Value[string] {
+	switch {
+	case !p.bodySimilarityMatch().IsNone():
+		return optional.Some(analysisHTTPDiffBodyComparisonSimilarity)
+	case !p.bodyLengthMatch().IsNone():
+		return optional.Some(analysisHTTPDiffBodyComparisonLength)
+	default:
+		return optional.None[string]()
+	}
+}
+
 // analysisHTTPDiffAlgorithm returns whether there's an HTTP diff
+//
+// When we know how similar the bodies are, we use the similarity in place of
+// the body length, which allows us to flag blockpages having a length similar to
+// the one of the original webpage and to avoid flagging dynamic webpages. Because
+// this makes the verdict differ from v0.4, we record the comparison we used using
+// the x_body_comparison test key (see [analysisHTTPDiffBodyComparison]).
 func analysisHTTPDiffAlgorithm(p analysisHTTPDiffValuesProvider) bool {
 	if !p.statusCodeMatch().IsNone() && p.statusCodeMatch().Unwrap() {
-		if !p.bodyLengthMatch().IsNone() && p.bodyLengthMatch().Unwrap() {
-			return false
+		switch analysisHTTPDiffBodyComparison(p).UnwrapOr("") {
+		case analysisHTTPDiffBodyComparisonSimilarity:
+			if p.bodySimilarityMatch().Unwrap() {
+				return false
+			}
+		case analysisHTTPDiffBodyComparisonLength:
+			if p.bodyLengthMatch().Unwrap() {
+				return false
+			}
 		}
 		if !p.headersMatch().IsNone() && p.headersMatch().Unwrap() {
 			return false
@@ -96,3 +155,25 @@ func analysisHTTPDiffAlgorithm(p analysisHTTPDiffValuesProvider) bool {
 	}
 	return true
 }
+
+// analysisHTTPDiffTraceInputs returns the values used by [analysisHTTPDiffAlgorithm]
+// for inclusion into the decision trace, skipping the values that are None.
+func analysisHTTPDiffTraceInputs(p analysisHTTPDiffValuesProvider) map[string]any {
+	inputs := map[string]any{}
+	values := map[string]optional.Value[bool]{
+		"body_length_match":     p.bodyLengthMatch(),
+		"body_similarity_match": p.bodySimilarityMatch(),
+		"headers_match":         p.headersMatch(),
+		"status_code_match":     p.statusCodeMatch(),
+		"title_match":           p.titleMatch(),
+	}
+	for key, value := range values {
+		if !value.IsNone() {
+			inputs[key] = value.Unwrap()
+		}
+	}
+	if comparison := analysisHTTPDiffBodyComparison(p); !comparison.IsNone() {
+		inputs["body_comparison"] = comparison.Unwrap()
+	}
+	return inputs
+}
@@ -0,0 +1,82 @@
+package webconnectivitylte
+
+import (
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/minipipeline"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+func TestAnalysisHTTPDiffAlgorithm(t *testing.T) {
+	type testcase struct {
+		name       string
+		analysis   *minipipeline.WebAnalysis
+		expect     bool
+		comparison string
+	}
+
+	cases := []testcase{{
+		name: "with matching body length and without hashes",
+		analysis: &minipipeline.WebAnalysis{
+			HTTPFinalResponseDiffBodyProportionFactor: optional.Some(0.9),
+			HTTPFinalResponseDiffStatusCodeMatch:      optional.Some(true),
+		},
+		expect:     false,
+		comparison: analysisHTTPDiffBodyComparisonLength,
+	}, {
+		name: "with a blockpage having a length similar to the webpage",
+		analysis: &minipipeline.WebAnalysis{
+			HTTPFinalResponseDiffBodyProportionFactor: optional.Some(0.9),
+			HTTPFinalResponseDiffBodySHA256Match:      optional.Some(false),
+			HTTPFinalResponseDiffBodySimilarity:       optional.Some(0.5),
+			HTTPFinalResponseDiffStatusCodeMatch:      optional.Some(true),
+		},
+		expect:     true,
+		comparison: analysisHTTPDiffBodyComparisonSimilarity,
+	}, {
+		name: "with a dynamic webpage having a different length",
+		analysis: &minipipeline.WebAnalysis{
+			HTTPFinalResponseDiffBodyProportionFactor: optional.Some(0.5),
+			HTTPFinalResponseDiffBodySHA256Match:      optional.Some(false),
+			HTTPFinalResponseDiffBodySimilarity:       optional.Some(0.9),
+			HTTPFinalResponseDiffStatusCodeMatch:      optional.Some(true),
+		},
+		expect:     false,
+		comparison: analysisHTTPDiffBodyComparisonSimilarity,
+	}, {
+		name: "with identical bodies lacking a simhash",
+		analysis: &minipipeline.WebAnalysis{
+			HTTPFinalResponseDiffBodySHA256Match: optional.Some(true),
+			HTTPFinalResponseDiffStatusCodeMatch: optional.Some(true),
+		},
+		expect:     false,
+		comparison: analysisHTTPDiffBodyComparisonSimilarity,
+	}, {
+		name: "with different status codes",
+		analysis: &minipipeline.WebAnalysis{
+			HTTPFinalResponseDiffBodySHA256Match: optional.Some(true),
+			HTTPFinalResponseDiffStatusCodeMatch: optional.Some(false),
+		},
+		expect:     true,
+		comparison: analysisHTTPDiffBodyComparisonSimilarity,
+	}, {
+		name: "without any information about the bodies",
+		analysis: &minipipeline.WebAnalysis{
+			HTTPFinalResponseDiffStatusCodeMatch: optional.Some(true),
+		},
+		expect:     true,
+		comparison: "",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hds := newAnalysisHTTPDiffStatus(tc.analysis)
+			if got := analysisHTTPDiffAlgorithm(hds); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+			if got := analysisHTTPDiffBodyComparison(hds).UnwrapOr(""); got != tc.comparison {
+				t.Fatal("expected", tc.comparison, "got", got)
+			}
+		})
+	}
+}
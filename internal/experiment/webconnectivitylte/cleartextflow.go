@@ -65,6 +65,14 @@ type CleartextFlow struct {
 	// CookieJar contains the OPTIONAL cookie jar, used for redirects.
 	CookieJar http.CookieJar
 
+	// EnableECH OPTIONALLY enables ECH when following redirects.
+	EnableECH bool
+
+	// FetchAllEndpoints OPTIONALLY allows this flow to fetch the webpage
+	// without following redirects when the PrioSelector does not allow it
+	// to fetch, in which case we save the results in EndpointFetches.
+	FetchAllEndpoints bool
+
 	// FollowRedirects is OPTIONAL and instructs this flow
 	// to follow HTTP redirects (if any).
 	FollowRedirects bool
@@ -145,10 +153,15 @@ func (t *CleartextFlow) Run(parentCtx context.Context, index int64) error {
 
 	alpn := "" // no ALPN because we're not using TLS
 
-	// Determine whether we're allowed to fetch the webpage
+	// Determine whether we're allowed to fetch the webpage, which we always
+	// do when fetching using all the endpoints but without following redirects
+	endpointFetch := false
 	if t.PrioSelector == nil || !t.PrioSelector.permissionToFetch(t.Address) {
-		ol.Stop("stop after TCP connect")
-		return errNotPermittedToFetch
+		if !t.FetchAllEndpoints {
+			ol.Stop("stop after TCP connect")
+			return errNotPermittedToFetch
+		}
+		endpointFetch = true
 	}
 
 	// create HTTP transport
@@ -184,6 +197,7 @@ func (t *CleartextFlow) Run(parentCtx context.Context, index int64) error {
 		httpTransport,
 		httpReq,
 		trace,
+		endpointFetch,
 	)
 	if err != nil {
 		ol.Stop(err)
@@ -191,7 +205,9 @@ func (t *CleartextFlow) Run(parentCtx context.Context, index int64) error {
 	}
 
 	// if enabled, follow possible redirects
-	t.maybeFollowRedirects(parentCtx, httpResp)
+	if !endpointFetch {
+		t.maybeFollowRedirects(parentCtx, httpResp)
+	}
 
 	// ignore the response body
 	_ = httpRespBody
@@ -252,7 +268,8 @@ func (t *CleartextFlow) newHTTPRequest(ctx context.Context) (*http.Request, erro
 
 // httpTransaction runs the HTTP transaction and saves the results.
 func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, alpn string,
-	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace) (*http.Response, []byte, error) {
+	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace,
+	endpointFetch bool) (*http.Response, []byte, error) {
 	const maxbody = 1 << 19
 	started := trace.TimeSince(trace.ZeroTime())
 
@@ -282,7 +299,7 @@ func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, a
 	}
 	if err == nil && httpRedirectIsRedirect(resp) {
 		err = httpValidateRedirect(resp)
-		if err == nil && t.FollowRedirects && !t.NumRedirects.CanFollowOneMoreRedirect() {
+		if err == nil && t.FollowRedirects && !endpointFetch && !t.NumRedirects.CanFollowOneMoreRedirect() {
 			err = ErrTooManyRedirects
 		}
 	}
@@ -316,6 +333,12 @@ func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, a
 		trace.Tags()...,
 	)
 
+	// Implementation note: we save the endpoint fetches separately to keep the
+	// requests compatible with Web Connectivity v0.4.
+	if endpointFetch {
+		t.TestKeys.AppendEndpointFetchRequests(ev)
+		return resp, body, err
+	}
 	t.TestKeys.PrependRequests(ev)
 	return resp, body, err
 }
@@ -341,6 +364,8 @@ func (t *CleartextFlow) maybeFollowRedirects(ctx context.Context, resp *http.Res
 			URL:                     location,
 			ZeroTime:                t.ZeroTime,
 			WaitGroup:               t.WaitGroup,
+			EnableECH:               t.EnableECH,
+			FetchAllEndpoints:       t.FetchAllEndpoints,
 			Referer:                 resp.Request.URL.String(),
 			Session:                 nil, // no need to issue another control request
 			TestHelpers:             nil, // ditto
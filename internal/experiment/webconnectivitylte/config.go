@@ -7,4 +7,26 @@ package webconnectivitylte
 // Config contains webconnectivity experiment configuration.
 type Config struct {
 	DNSOverUDPResolver string
+
+	// EnableECH enables fetching the ECH config from the domain's HTTPS record
+	// and using it for Encrypted Client Hello during TLS handshakes. Because a
+	// rejected ECH causes the handshake to fail, this is disabled by default.
+	EnableECH bool
+
+	// FetchAllEndpoints enables fetching the webpage using every reachable endpoint
+	// rather than just the one with the highest priority, as well as using HTTP/3 for
+	// endpoints advertising it with Alt-Svc. We save these additional fetches inside
+	// the x_endpoint_fetches test key, which does not follow redirects. We fetch
+	// using every TCP endpoint for http:// URLs and using every TLS endpoint for
+	// https:// URLs. We do not fetch using the TLS endpoints of http:// URLs because
+	// we would be fetching a different URL, which we cannot compare with the
+	// control response. Because this mode generates more traffic, it is disabled
+	// by default.
+	FetchAllEndpoints bool
+
+	// NumTestHelpers is the number of test helpers to query in parallel. When
+	// this value is greater than one, we save all the responses and the analysis
+	// marks disagreements between test helpers. Otherwise, we query a single
+	// test helper and only try the next one if the previous one failed.
+	NumTestHelpers int64
 }
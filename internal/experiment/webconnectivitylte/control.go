@@ -2,6 +2,7 @@ package webconnectivitylte
 
 import (
 	"context"
+	"errors"
 	"net"
 	"net/url"
 	"sync"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
 	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
@@ -42,6 +44,12 @@ type Control struct {
 	// Logger is the MANDATORY logger to use.
 	Logger model.Logger
 
+	// NumTestHelpers is the OPTIONAL number of test helpers to query in
+	// parallel. When this value is greater than one, we query the given number
+	// of test helpers in parallel and save all their results. Otherwise, we
+	// query the test helpers one after the other until one succeeds.
+	NumTestHelpers int64
+
 	// PrioSelector is the OPTIONAL priority selector to use to determine
 	// whether we will be allowed to fetch the webpage.
 	PrioSelector *prioritySelector
@@ -109,7 +117,16 @@ func (c *Control) Run(parentCtx context.Context) {
 	)
 
 	// issue the control request and wait for the response
-	cresp, idx, err := webconnectivityalgo.CallWebConnectivityTestHelper(opCtx, creq, c.TestHelpers, c.Session)
+	var (
+		cresp *webconnectivity.ControlResponse
+		idx   int
+		err   error
+	)
+	if c.NumTestHelpers > 1 {
+		cresp, idx, err = c.callTestHelpersInParallel(opCtx, creq)
+	} else {
+		cresp, idx, err = webconnectivityalgo.CallWebConnectivityTestHelper(opCtx, creq, c.TestHelpers, c.Session)
+	}
 	if err != nil {
 		// make sure error is wrapped
 		err = netxlite.NewTopLevelGenericErrWrapper(err)
@@ -132,6 +149,46 @@ func (c *Control) Run(parentCtx context.Context) {
 	c.maybeStartExtraMeasurements(parentCtx, cresp.DNS.Addrs)
 }
 
+// callTestHelpersInParallel queries NumTestHelpers test helpers in parallel and saves
+// all their results into the test keys. It returns the first successful response in test
+// helpers order along with its index, such that the rest of the code can use it as the
+// main control response, or all the errors that occurred when all test helpers failed.
+func (c *Control) callTestHelpersInParallel(
+	ctx context.Context, creq *webconnectivity.ControlRequest) (*webconnectivity.ControlResponse, int, error) {
+	results := webconnectivityalgo.CallWebConnectivityTestHelpersInParallel(
+		ctx, creq, c.TestHelpers, int(c.NumTestHelpers), c.Session)
+
+	var (
+		controls = []*model.THResult{}
+		cresp    *webconnectivity.ControlResponse
+		errorv   []error
+		idx      int
+	)
+	for _, result := range results {
+		controls = append(controls, &model.THResult{
+			TestHelper: &c.TestHelpers[result.Index],
+			Failure:    measurexlite.NewFailure(result.Err), // wraps the error
+			Response:   result.Response,
+		})
+		if result.Err != nil {
+			errorv = append(errorv, result.Err)
+			continue
+		}
+		if cresp == nil {
+			cresp, idx = result.Response, result.Index
+		}
+	}
+	c.TestKeys.SetControls(controls)
+
+	if cresp == nil {
+		if len(errorv) <= 0 {
+			return nil, 0, model.ErrNoAvailableTestHelpers
+		}
+		return nil, 0, errors.Join(errorv...)
+	}
+	return cresp, idx, nil
+}
+
 // This function determines whether we should start new
 // background measurements for previously unknown IP addrs.
 func (c *Control) maybeStartExtraMeasurements(ctx context.Context, thAddrs []string) {
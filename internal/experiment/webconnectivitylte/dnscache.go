@@ -31,6 +31,9 @@ type DNSCache struct {
 
 	// values contains already resolved values.
 	values map[string][]DNSEntry
+
+	// echConfigLists contains already resolved ECHConfigLists.
+	echConfigLists map[string][]byte
 }
 
 // Get gets values from the cache
@@ -48,10 +51,26 @@ func (c *DNSCache) Set(domain string, values []DNSEntry) {
 	c.mu.Unlock()
 }
 
+// GetECHConfigList gets the ECHConfigList from the cache
+func (c *DNSCache) GetECHConfigList(domain string) ([]byte, bool) {
+	c.mu.Lock()
+	value, found := c.echConfigLists[domain]
+	c.mu.Unlock()
+	return value, found
+}
+
+// SetECHConfigList inserts the ECHConfigList into the cache
+func (c *DNSCache) SetECHConfigList(domain string, value []byte) {
+	c.mu.Lock()
+	c.echConfigLists[domain] = value
+	c.mu.Unlock()
+}
+
 // NewDNSCache creates a new DNSCache instance.
 func NewDNSCache() *DNSCache {
 	return &DNSCache{
-		mu:     &sync.Mutex{},
-		values: map[string][]DNSEntry{},
+		mu:             &sync.Mutex{},
+		values:         map[string][]DNSEntry{},
+		echConfigLists: map[string][]byte{},
 	}
 }
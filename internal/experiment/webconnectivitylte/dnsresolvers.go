@@ -64,6 +64,14 @@ type DNSResolvers struct {
 	// CookieJar contains the OPTIONAL cookie jar, used for redirects.
 	CookieJar http.CookieJar
 
+	// EnableECH OPTIONALLY enables using Encrypted Client Hello for the
+	// secure flows when the domain's HTTPS record contains an ECH config.
+	EnableECH bool
+
+	// FetchAllEndpoints OPTIONALLY enables fetching the webpage using every
+	// reachable endpoint (see [Config] for more information).
+	FetchAllEndpoints bool
+
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
@@ -78,9 +86,17 @@ type DNSResolvers struct {
 	// empty, we are not going to try to contact any test helper.
 	TestHelpers []model.OOAPIService
 
+	// NumTestHelpers is the OPTIONAL number of test helpers to query
+	// in parallel (see [Control] for more information).
+	NumTestHelpers int64
+
 	// UDPAddress is the OPTIONAL address of the UDP resolver to use. If this
 	// field is not set we use a default one (e.g., `8.8.8.8:53`).
 	UDPAddress string
+
+	// echConfigList is the ECHConfigList used by secure flows, which
+	// Run sets before starting any flow when EnableECH is true.
+	echConfigList []byte
 }
 
 // Start starts this task in a background goroutine.
@@ -97,14 +113,16 @@ var MaybeSortAddresses = func(entries []DNSEntry) {
 	// nothing!
 }
 
-// run performs a DNS lookup and returns the looked up addrs
-func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
+// run performs a DNS lookup and returns the looked up addrs along with the
+// ECHConfigList, which is nil unless [DNSResolvers.shouldLookupECHConfigList].
+func (t *DNSResolvers) run(parentCtx context.Context) ([]DNSEntry, []byte) {
 	// create output channels for the lookup
 	systemOut := make(chan []string)
 	udpOut := make(chan []string)
 	httpsOut := make(chan []string)
 	whoamiSystemV4Out := make(chan []webconnectivityalgo.DNSWhoamiInfoEntry)
 	whoamiUDPv4Out := make(chan []webconnectivityalgo.DNSWhoamiInfoEntry)
+	echOut := make(chan []byte)
 
 	// TODO(https://github.com/ooni/probe/issues/1521): detecting DNS interception
 
@@ -116,12 +134,16 @@ func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
 	go t.lookupHostDNSOverHTTPS(parentCtx, httpsOut)
 	go t.whoamiSystemV4(parentCtx, whoamiSystemV4Out)
 	go t.whoamiUDPv4(parentCtx, udpAddress, whoamiUDPv4Out)
+	go t.maybeLookupECHConfigList(parentCtx, udpAddress, echOut)
 
 	// collect resulting IP addresses (which may be nil/empty lists)
 	systemAddrs := <-systemOut
 	udpAddrs := <-udpOut
 	httpsAddrs := <-httpsOut
 
+	// collect the ECHConfigList (which may be nil)
+	echConfigList := <-echOut
+
 	// collect whoami results (which also may be nil/empty)
 	whoamiSystemV4 := <-whoamiSystemV4Out
 	whoamiUDPv4 := <-whoamiUDPv4Out
@@ -161,37 +183,51 @@ func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
 	// allow specific users to sort addresses if needed
 	MaybeSortAddresses(entries)
 
-	return entries
+	return entries, echConfigList
 }
 
 // Run runs this task in the current goroutine.
 func (t *DNSResolvers) Run(parentCtx context.Context) {
 	var (
-		addresses []DNSEntry
-		found     bool
+		addresses     []DNSEntry
+		echConfigList []byte
+		found         bool
 	)
 
 	// attempt to use the dns cache
 	addresses, found = t.DNSCache.Get(t.Domain)
 
+	// also make sure we have the ECHConfigList when we need it, which may not be the
+	// case if we cached the addresses while following an http:// URL
+	if found && t.shouldLookupECHConfigList() {
+		echConfigList, found = t.DNSCache.GetECHConfigList(t.Domain)
+	}
+
 	if !found {
 		// fall back to performing a real dns lookup
-		addresses = t.run(parentCtx)
+		addresses, echConfigList = t.run(parentCtx)
 
 		// insert the addresses we just looked us into the cache
 		t.DNSCache.Set(t.Domain, addresses)
+		if t.shouldLookupECHConfigList() {
+			t.DNSCache.SetECHConfigList(t.Domain, echConfigList)
+		}
 
 		t.Logger.Infof("using resolved addrs: %+v", addresses)
 	} else {
 		t.Logger.Infof("using previously-cached addrs: %+v", addresses)
 	}
 
+	// use the ECHConfigList, if any, for secure flows
+	t.echConfigList = echConfigList
+
 	// create priority selector
 	ps := newPrioritySelector(parentCtx, t.ZeroTime, t.TestKeys, t.Logger, addresses)
 
 	// fan out a number of child async tasks to use the IP addrs
 	t.startCleartextFlows(parentCtx, ps, addresses)
 	t.startSecureFlows(parentCtx, ps, addresses)
+	t.maybeStartHappyEyeballsFlow(parentCtx, addresses)
 	t.maybeStartControlFlow(parentCtx, ps, addresses)
 }
 
@@ -246,6 +282,10 @@ func (t *DNSResolvers) lookupHostUDP(parentCtx context.Context, udpAddress strin
 	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
 	defer lookpCancel()
 
+	// keep reading duplicate responses, which typically indicate DNS injection, in
+	// the background for as long as waitForLateReplies is waiting for them
+	lookupCtx = netxlite.ContextWithDNSOverUDPDuplicatesWindow(lookupCtx, dnsLateRepliesTimeout)
+
 	// create trace's index
 	index := t.IDGenerator.NewIDForDNSOverUDP()
 
@@ -279,11 +319,66 @@ func (t *DNSResolvers) lookupHostUDP(parentCtx context.Context, udpAddress strin
 	go t.waitForLateReplies(parentCtx, trace)
 }
 
+// shouldLookupECHConfigList returns whether we should lookup the ECHConfigList,
+// which is the case when EnableECH is true and we are measuring an HTTPS URL.
+func (t *DNSResolvers) shouldLookupECHConfigList() bool {
+	return t.EnableECH && t.URL.Scheme == "https"
+}
+
+// maybeLookupECHConfigList queries the HTTPS record of the domain using the UDP
+// resolver and emits its ECHConfigList, if any, when [DNSResolvers.shouldLookupECHConfigList]
+// is true. Otherwise, it emits nil. This function must always emit an ouput on the [out]
+// channel to synchronize with the caller func.
+func (t *DNSResolvers) maybeLookupECHConfigList(parentCtx context.Context, udpAddress string, out chan<- []byte) {
+	if !t.shouldLookupECHConfigList() {
+		out <- nil
+		return
+	}
+
+	// create context with attached a timeout
+	const timeout = 4 * time.Second
+	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
+	defer lookpCancel()
+
+	// create trace's index
+	index := t.IDGenerator.NewIDForDNSOverUDP()
+
+	// create trace
+	trace := measurexlite.NewTrace(index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] lookup HTTPS record for %s using %s", index, t.Domain, udpAddress,
+	)
+
+	// runs the lookup
+	netx := &netxlite.Netx{}
+	dialer := netx.NewDialerWithoutResolver(t.Logger)
+	reso := trace.NewParallelUDPResolver(t.Logger, dialer, udpAddress)
+	https, err := reso.LookupHTTPS(lookupCtx, t.Domain)
+
+	// we store these queries along with the Do53 extension queries since the
+	// analysis assumes that the top-level queries are A and AAAA queries
+	t.TestKeys.WithTestKeysDo53(func(tkd *TestKeysDo53) {
+		tkd.Queries = append(tkd.Queries, trace.DNSLookupsFromRoundTrip()...)
+		tkd.NetworkEvents = append(tkd.NetworkEvents, trace.NetworkEvents()...)
+	})
+
+	ol.Stop(err)
+	if err != nil {
+		out <- nil
+		return
+	}
+	out <- https.Ech
+}
+
+// dnsLateRepliesTimeout is the time during which we wait for late DNS replies.
+const dnsLateRepliesTimeout = 500 * time.Millisecond
+
 // Waits for late DNS replies.
 func (t *DNSResolvers) waitForLateReplies(parentCtx context.Context, trace *measurexlite.Trace) {
 	defer t.WaitGroup.Done()
-	const lateTimeout = 500 * time.Millisecond
-	events := trace.DelayedDNSResponseWithTimeout(parentCtx, lateTimeout)
+	events := trace.DelayedDNSResponseWithTimeout(parentCtx, dnsLateRepliesTimeout)
 	t.TestKeys.AppendDNSLateReplies(events...)
 }
 
@@ -405,6 +500,8 @@ func (t *DNSResolvers) startCleartextFlows(
 			ZeroTime:                t.ZeroTime,
 			WaitGroup:               t.WaitGroup,
 			CookieJar:               t.CookieJar,
+			EnableECH:               t.EnableECH,
+			FetchAllEndpoints:       t.FetchAllEndpoints,
 			FollowRedirects:         t.URL.Scheme == "http",
 			HostHeader:              t.URL.Host,
 			PrioSelector:            ps,
@@ -457,6 +554,9 @@ func (t *DNSResolvers) startSecureFlows(
 			WaitGroup:               t.WaitGroup,
 			ALPN:                    []string{"h2", "http/1.1"},
 			CookieJar:               t.CookieJar,
+			ECHConfigList:           t.echConfigList,
+			EnableECH:               t.EnableECH,
+			FetchAllEndpoints:       t.FetchAllEndpoints && ps != nil, // only for https:// URLs (see Config)
 			FollowRedirects:         t.URL.Scheme == "https",
 			SNI:                     t.URL.Hostname(),
 			HostHeader:              t.URL.Host,
@@ -470,6 +570,55 @@ func (t *DNSResolvers) startSecureFlows(
 	}
 }
 
+// maybeStartHappyEyeballsFlow starts an happy eyeballs flow iff the system
+// resolver returned both IPv4 and IPv6 addresses for the domain.
+func (t *DNSResolvers) maybeStartHappyEyeballsFlow(ctx context.Context, addresses []DNSEntry) {
+	var (
+		addrs      []string
+		ipv4, ipv6 bool
+	)
+	for _, addr := range addresses {
+		if addr.Flags&DNSAddrFlagSystemResolver == 0 {
+			continue
+		}
+		if allowedToConnect(net.JoinHostPort(addr.Addr, "0")) != nil {
+			continue
+		}
+		isv6, err := netxlite.IsIPv6(addr.Addr)
+		if err != nil {
+			continue
+		}
+		ipv4, ipv6 = ipv4 || !isv6, ipv6 || isv6
+		addrs = append(addrs, addr.Addr)
+	}
+	if !ipv4 || !ipv6 {
+		// The happy eyeballs dialer would not tell us anything
+		// more than the cleartext and secure flows already do.
+		return
+	}
+	port := t.URL.Port()
+	if port == "" {
+		switch t.URL.Scheme {
+		case "http":
+			port = "80"
+		default:
+			port = "443"
+		}
+	}
+	task := &HappyEyeballsFlow{
+		Addresses:   addrs,
+		Depth:       t.Depth,
+		Domain:      t.Domain,
+		IDGenerator: t.IDGenerator,
+		Logger:      t.Logger,
+		Port:        port,
+		TestKeys:    t.TestKeys,
+		ZeroTime:    t.ZeroTime,
+		WaitGroup:   t.WaitGroup,
+	}
+	task.Start(ctx)
+}
+
 // maybeStartControlFlow starts the control flow iff .Session and .TestHelpers are set.
 func (t *DNSResolvers) maybeStartControlFlow(
 	ctx context.Context,
@@ -487,6 +636,7 @@ func (t *DNSResolvers) maybeStartControlFlow(
 			Addresses:                addrs,
 			ExtraMeasurementsStarter: t, // allows starting follow-up measurement flows
 			Logger:                   t.Logger,
+			NumTestHelpers:           t.NumTestHelpers,
 			PrioSelector:             ps,
 			TestKeys:                 t.TestKeys,
 			Session:                  t.Session,
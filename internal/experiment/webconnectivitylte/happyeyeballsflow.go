@@ -0,0 +1,104 @@
+package webconnectivitylte
+
+//
+// HappyEyeballsFlow
+//
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// Races IPv4 and IPv6 connect attempts using happy eyeballs (see RFC 8305).
+//
+// We use the result to determine whether a client using happy eyeballs, such as
+// a browser, would be able to connect using IPv4 when IPv6 fails, in which case
+// IPv6 connect failures do not prevent accessing the website.
+//
+// The zero value of this structure IS NOT valid and you MUST initialize
+// all the fields marked as MANDATORY before using this structure.
+type HappyEyeballsFlow struct {
+	// Addresses contains the MANDATORY IPv4 and IPv6 addresses to race.
+	Addresses []string
+
+	// Depth is the OPTIONAL current redirect depth.
+	Depth int64
+
+	// Domain is the MANDATORY domain we resolved.
+	Domain string
+
+	// IDGenerator is the MANDATORY atomic int64 to generate task IDs.
+	IDGenerator *IDGenerator
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// Port is the MANDATORY port to connect to.
+	Port string
+
+	// TestKeys is MANDATORY and contains the TestKeys.
+	TestKeys *TestKeys
+
+	// ZeroTime is the MANDATORY measurement's zero time.
+	ZeroTime time.Time
+
+	// WaitGroup is the MANDATORY wait group this task belongs to.
+	WaitGroup *sync.WaitGroup
+}
+
+// Start starts this task in a background goroutine.
+func (t *HappyEyeballsFlow) Start(ctx context.Context) {
+	t.WaitGroup.Add(1)
+	index := t.IDGenerator.NewIDForHappyEyeballs()
+	go func() {
+		defer t.WaitGroup.Done() // synchronize with the parent
+		_ = t.Run(ctx, index)
+	}()
+}
+
+// Run runs this task in the current goroutine.
+func (t *HappyEyeballsFlow) Run(parentCtx context.Context, index int64) error {
+	// create trace
+	trace := measurexlite.NewTrace(index, t.ZeroTime, "happy_eyeballs", fmt.Sprintf("depth=%d", t.Depth))
+
+	// start the operation logger
+	address := net.JoinHostPort(t.Domain, t.Port)
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] HappyEyeballs %s using %v", index, address, t.Addresses,
+	)
+
+	// make sure the dialer only uses the addresses we were given
+	reso := netxlite.MaybeWrapWithStaticDNSCache(
+		map[string][]string{t.Domain: t.Addresses},
+		&netxlite.NullResolver{},
+	)
+
+	// race the TCP connect attempts
+	const tcpTimeout = 10 * time.Second
+	tcpCtx, tcpCancel := context.WithTimeout(parentCtx, tcpTimeout)
+	defer tcpCancel()
+	tcpDialer := trace.NewDialerHappyEyeballs(t.Logger, reso)
+	tcpConn, err := tcpDialer.DialContext(tcpCtx, "tcp", address)
+
+	// Implementation note: each connect attempt is tagged with "happy_eyeballs", which
+	// allows the analysis to distinguish them from the endpoint flows' attempts.
+	t.TestKeys.AppendTCPConnectResults(trace.TCPConnects()...)
+	t.TestKeys.AppendNetworkEvents(trace.NetworkEvents()...)
+	t.TestKeys.AppendHappyEyeballsResults(trace.HappyEyeballs()...)
+	if err != nil {
+		ol.Stop(err)
+		return err
+	}
+	defer tcpConn.Close()
+
+	ol.Stop(nil)
+	return nil
+}
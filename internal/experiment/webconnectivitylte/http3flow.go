@@ -0,0 +1,217 @@
+package webconnectivitylte
+
+//
+// HTTP3Flow
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/quic-go/quic-go"
+)
+
+// Measures HTTP/3 endpoints.
+//
+// We only run this flow when fetching the webpage using all the endpoints
+// and an HTTPS endpoint advertises HTTP/3 support using Alt-Svc. Because this
+// flow does not follow redirects, we save the results in EndpointFetches.
+//
+// The zero value of this structure IS NOT valid and you MUST initialize
+// all the fields marked as MANDATORY before using this structure.
+type HTTP3Flow struct {
+	// Address is the MANDATORY UDP address to connect to.
+	Address string
+
+	// Depth is the OPTIONAL current redirect depth.
+	Depth int64
+
+	// IDGenerator is the MANDATORY atomic int64 to generate task IDs.
+	IDGenerator *IDGenerator
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// TestKeys is MANDATORY and contains the TestKeys.
+	TestKeys *TestKeys
+
+	// ZeroTime is the MANDATORY measurement's zero time.
+	ZeroTime time.Time
+
+	// WaitGroup is the MANDATORY wait group this task belongs to.
+	WaitGroup *sync.WaitGroup
+
+	// HostHeader is the MANDATORY host header to use.
+	HostHeader string
+
+	// Referer contains the OPTIONAL referer.
+	Referer string
+
+	// SNI is the MANDATORY SNI to use.
+	SNI string
+
+	// URLPath is the OPTIONAL URL path.
+	URLPath string
+
+	// URLRawQuery is the OPTIONAL URL raw query.
+	URLRawQuery string
+}
+
+// Start starts this task in a background goroutine.
+func (t *HTTP3Flow) Start(ctx context.Context) {
+	t.WaitGroup.Add(1)
+	index := t.IDGenerator.NewIDForEndpointHTTP3()
+	go func() {
+		defer t.WaitGroup.Done() // synchronize with the parent
+		_ = t.Run(ctx, index)
+	}()
+}
+
+// Run runs this task in the current goroutine.
+func (t *HTTP3Flow) Run(parentCtx context.Context, index int64) error {
+	if err := allowedToConnect(t.Address); err != nil {
+		t.Logger.Warnf("HTTP3Flow: %s", err.Error())
+		return err
+	}
+
+	// create trace
+	trace := measurexlite.NewTrace(index, t.ZeroTime, "http3", fmt.Sprintf("depth=%d", t.Depth))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] GET https://%s using %s/udp", index, t.HostHeader, t.Address,
+	)
+
+	// perform the QUIC handshake
+	//
+	// See https://github.com/ooni/probe/issues/2413 to understand
+	// why we're using nil to force netxlite to use the cached
+	// default Mozilla cert pool.
+	tlsConfig := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+		NextProtos: []string{"h3"},
+		RootCAs:    nil,
+		ServerName: t.SNI,
+	}
+	const quicTimeout = 10 * time.Second
+	quicCtx, quicCancel := context.WithTimeout(parentCtx, quicTimeout)
+	defer quicCancel()
+	quicDialer := trace.NewQUICDialerWithoutResolver(trace.NewUDPListener(), t.Logger)
+	quicConn, err := quicDialer.DialContext(quicCtx, t.Address, tlsConfig, &quic.Config{})
+	t.TestKeys.AppendEndpointFetchQUICHandshakes(trace.QUICHandshakes()...)
+	if err != nil {
+		ol.Stop(err)
+		return err
+	}
+	defer measurexlite.MaybeCloseQUICConn(quicConn)
+
+	// create HTTP transport
+	httpTransport := netxlite.NewHTTP3Transport(
+		t.Logger,
+		netxlite.NewSingleUseQUICDialer(quicConn),
+		tlsConfig,
+	)
+
+	// create HTTP request
+	const httpTimeout = 10 * time.Second
+	httpCtx, httpCancel := context.WithTimeout(parentCtx, httpTimeout)
+	defer httpCancel()
+	httpReq, err := t.newHTTPRequest(httpCtx)
+	if err != nil {
+		ol.Stop(err)
+		return err
+	}
+
+	// perform HTTP transaction
+	err = t.httpTransaction(httpCtx, httpTransport, httpReq, trace)
+	ol.Stop(err)
+	return err
+}
+
+// newHTTPRequest creates a new HTTP request.
+func (t *HTTP3Flow) newHTTPRequest(ctx context.Context) (*http.Request, error) {
+	httpURL := &url.URL{
+		Scheme:   "https",
+		Host:     t.HostHeader,
+		Path:     t.URLPath,
+		RawQuery: t.URLRawQuery,
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", httpURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Host", t.HostHeader)
+	httpReq.Header.Set("Accept", model.HTTPHeaderAccept)
+	httpReq.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	httpReq.Header.Set("Referer", t.Referer)
+	httpReq.Header.Set("User-Agent", model.HTTPHeaderUserAgent)
+	httpReq.Host = t.HostHeader
+	return httpReq, nil
+}
+
+// httpTransaction runs the HTTP transaction and saves the results.
+func (t *HTTP3Flow) httpTransaction(ctx context.Context,
+	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace) error {
+	const maxbody = 1 << 19
+	started := trace.TimeSince(trace.ZeroTime())
+	resp, err := txp.RoundTrip(req)
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		reader := io.LimitReader(resp.Body, maxbody)
+		body, err = netxlite.StreamAllContext(ctx, reader)
+	}
+	finished := trace.TimeSince(trace.ZeroTime())
+	ev := measurexlite.NewArchivalHTTPRequestResult(
+		trace.Index(),
+		started,
+		"udp",
+		t.Address,
+		"h3",
+		txp.Network(),
+		req,
+		resp,
+		maxbody,
+		body,
+		err,
+		finished,
+		trace.Tags()...,
+	)
+	t.TestKeys.AppendEndpointFetchRequests(ev)
+	return err
+}
+
+// http3AltSvcPort returns the UDP port that the Alt-Svc header of the given response
+// advertises for HTTP/3 or an empty string. We only consider alternative services using
+// the same host, because we want to fetch using the same IP address.
+func http3AltSvcPort(resp *http.Response) string {
+	// Syntax:
+	//
+	//     Alt-Svc: h3=":443"; ma=86400, h3-29=":443"; ma=86400
+	//
+	// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Alt-Svc
+	for _, entry := range strings.Split(resp.Header.Get("Alt-Svc"), ",") {
+		service, _, _ := strings.Cut(entry, ";")
+		protocol, authority, found := strings.Cut(service, "=")
+		if !found || strings.TrimSpace(protocol) != "h3" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(strings.Trim(strings.TrimSpace(authority), `"`))
+		if err != nil || host != "" || port == "" {
+			continue
+		}
+		return port
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package webconnectivitylte
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHTTP3AltSvcPort(t *testing.T) {
+	tests := []struct {
+		name   string
+		altsvc string
+		expect string
+	}{{
+		name:   "without Alt-Svc",
+		altsvc: "",
+		expect: "",
+	}, {
+		name:   "with HTTP/3 on the same host",
+		altsvc: `h3=":443"; ma=86400, h3-29=":443"; ma=86400`,
+		expect: "443",
+	}, {
+		name:   "with HTTP/3 on the same host but not as the first entry",
+		altsvc: `h2=":443"; ma=3600, h3=":8443"; ma=3600`,
+		expect: "8443",
+	}, {
+		name:   "with HTTP/3 on another host",
+		altsvc: `h3="alt.example.com:443"; ma=86400`,
+		expect: "",
+	}, {
+		name:   "with draft versions of HTTP/3 only",
+		altsvc: `h3-29=":443"; ma=86400`,
+		expect: "",
+	}, {
+		name:   "with clear",
+		altsvc: "clear",
+		expect: "",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.altsvc != "" {
+				resp.Header.Set("Alt-Svc", tt.altsvc)
+			}
+			if got := http3AltSvcPort(resp); got != tt.expect {
+				t.Fatal("expected", tt.expect, "got", got)
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ const (
 	idGeneratorDNSOverHTTPSOffset      = 30_000
 	idGeneratorEndpointCleartextOffset = 40_000
 	idGeneratorEndpointSecureOffset    = 50_000
+	idGeneratorHappyEyeballsOffset     = 60_000
+	idGeneratorEndpointHTTP3Offset     = 70_000
 )
 
 // IDGenerator helps with generating IDs that neatly fall into namespaces.
@@ -28,6 +30,12 @@ type IDGenerator struct {
 
 	// endpointSecure generates IDs for endpoints using HTTPS.
 	endpointSecure *atomic.Int64
+
+	// happyEyeballs generates IDs for happy eyeballs dials.
+	happyEyeballs *atomic.Int64
+
+	// endpointHTTP3 generates IDs for endpoints using HTTP/3.
+	endpointHTTP3 *atomic.Int64
 }
 
 // NewIDGenerator creates a new [*IDGenerator] instance.
@@ -38,6 +46,8 @@ func NewIDGenerator() *IDGenerator {
 		dnsOverHTTPS:      &atomic.Int64{},
 		endpointCleartext: &atomic.Int64{},
 		endpointSecure:    &atomic.Int64{},
+		happyEyeballs:     &atomic.Int64{},
+		endpointHTTP3:     &atomic.Int64{},
 	}
 }
 
@@ -65,3 +75,13 @@ func (idgen *IDGenerator) NewIDForEndpointCleartext() int64 {
 func (idgen *IDGenerator) NewIDForEndpointSecure() int64 {
 	return idgen.endpointSecure.Add(1) + idGeneratorEndpointSecureOffset
 }
+
+// NewIDForHappyEyeballs returns a new ID for an happy eyeballs dial.
+func (idgen *IDGenerator) NewIDForHappyEyeballs() int64 {
+	return idgen.happyEyeballs.Add(1) + idGeneratorHappyEyeballsOffset
+}
+
+// NewIDForEndpointHTTP3 returns a new ID for an HTTP/3 endpoint operation.
+func (idgen *IDGenerator) NewIDForEndpointHTTP3() int64 {
+	return idgen.endpointHTTP3.Add(1) + idGeneratorEndpointHTTP3Offset
+}
@@ -46,7 +46,7 @@ func (m *Measurer) ExperimentName() string {
 
 // ExperimentVersion implements model.ExperimentMeasurer.
 func (m *Measurer) ExperimentVersion() string {
-	return "0.5.28"
+	return "0.5.39"
 }
 
 // Run implements model.ExperimentMeasurer.
@@ -92,6 +92,14 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	// See https://github.com/ooni/probe/issues/2676
 	tk.ClientResolver = sess.ResolverIP()
 
+	// make sure the analysis knows the probe country code
+	tk.probeCC = measurement.ProbeCC
+
+	// only collect the per-endpoint fetches when the user asked for them
+	if m.Config.FetchAllEndpoints {
+		tk.EndpointFetches = NewTestKeysEndpointFetches()
+	}
+
 	// create variables required to run parallel tasks
 	wg := &sync.WaitGroup{}
 
@@ -126,9 +134,12 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		ZeroTime:                measurement.MeasurementStartTimeSaved,
 		WaitGroup:               wg,
 		CookieJar:               jar,
+		EnableECH:               m.Config.EnableECH,
+		FetchAllEndpoints:       m.Config.FetchAllEndpoints,
 		Referer:                 "",
 		Session:                 sess,
 		TestHelpers:             testhelpers,
+		NumTestHelpers:          m.Config.NumTestHelpers,
 		UDPAddress:              m.Config.DNSOverUDPResolver,
 	}
 	resos.Start(ctx)
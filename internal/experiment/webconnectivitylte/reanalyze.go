@@ -0,0 +1,24 @@
+package webconnectivitylte
+
+//
+// Reanalysis of previously collected measurements
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/minipipeline"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Reanalyze returns new [*TestKeys] containing only the results of running the current
+// analysis algorithm (e.g., Blocking, Accessible, and BlockingFlags) on the given container,
+// which you should obtain by calling [minipipeline.IngestWebMeasurement] on a previously
+// collected measurement. This allows to evaluate analysis changes using historical data.
+//
+// The analysis emits informational messages using the given logger, so pass
+// [model.DiscardLogger] when you are not interested in them.
+func Reanalyze(lookupper model.GeoIPASNLookupper,
+	container *minipipeline.WebObservationsContainer, logger model.Logger) *TestKeys {
+	tk := NewTestKeys()
+	tk.analysisClassicWebObservations(lookupper, container, logger)
+	return tk
+}
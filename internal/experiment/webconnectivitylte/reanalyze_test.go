@@ -0,0 +1,104 @@
+package webconnectivitylte
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/minipipeline"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestReanalyze(t *testing.T) {
+	// create a measurement for which the extended analysis has something to say
+	meas := &minipipeline.WebMeasurement{
+		Input: "http://www.example.com/",
+		TestKeys: optional.Some(&minipipeline.WebMeasurementTestKeys{
+			Queries: []*model.ArchivalDNSLookupResult{{
+				Answers: []model.ArchivalDNSAnswer{{
+					AnswerType: "A",
+					IPv4:       "10.0.0.1",
+				}},
+				Engine:        "getaddrinfo",
+				Hostname:      "www.example.com",
+				QueryType:     "ANY",
+				Tags:          []string{"depth=0"},
+				TransactionID: 1,
+			}},
+		}),
+	}
+	lookupper := mocks.NewGeoIPASNLookupper(map[string]*model.LocationASN{})
+	container := runtimex.Try1(minipipeline.IngestWebMeasurement(lookupper, meas))
+
+	// make sure the extended analysis uses the logger rather than the stdout
+	var messages []string
+	logger := &mocks.Logger{
+		MockInfof: func(format string, v ...any) {
+			messages = append(messages, fmt.Sprintf(format, v...))
+		},
+	}
+	tk := Reanalyze(lookupper, container, logger)
+
+	if tk.DNSFlags&AnalysisFlagDNSBogon == 0 {
+		t.Fatal("expected the bogon flag, got", tk.DNSFlags)
+	}
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], "Extended Analysis\n") {
+		t.Fatal("expected the extended analysis to be logged, got", messages)
+	}
+}
+
+func TestReanalyzeDNSInjection(t *testing.T) {
+	// newQuery creates a DNS-over-UDP query resolving to the given addr
+	newQuery := func(addr string) *model.ArchivalDNSLookupResult {
+		return &model.ArchivalDNSLookupResult{
+			Answers: []model.ArchivalDNSAnswer{{
+				AnswerType: "A",
+				IPv4:       addr,
+			}},
+			Engine:          "udp",
+			Hostname:        "www.example.com",
+			QueryType:       "A",
+			ResolverAddress: "8.8.8.8:53",
+			Tags:            []string{"depth=0"},
+			TransactionID:   2,
+		}
+	}
+
+	// newMeasurement creates a measurement with the given test keys
+	newMeasurement := func(tk *minipipeline.WebMeasurementTestKeys) *minipipeline.WebMeasurement {
+		return &minipipeline.WebMeasurement{
+			Input:    "http://www.example.com/",
+			TestKeys: optional.Some(tk),
+		}
+	}
+
+	t.Run("with duplicate responses attached to the queries", func(t *testing.T) {
+		query := newQuery("93.184.216.34")
+		query.DuplicateResponses = []*model.ArchivalDNSLookupResult{newQuery("10.10.34.35")}
+		meas := newMeasurement(&minipipeline.WebMeasurementTestKeys{
+			Queries: []*model.ArchivalDNSLookupResult{query},
+		})
+		lookupper := mocks.NewGeoIPASNLookupper(map[string]*model.LocationASN{})
+		container := runtimex.Try1(minipipeline.IngestWebMeasurement(lookupper, meas))
+		tk := Reanalyze(lookupper, container, model.DiscardLogger)
+		if tk.DNSFlags&AnalysisDNSFlagInjection == 0 {
+			t.Fatal("expected the injection flag, got", tk.DNSFlags)
+		}
+	})
+
+	t.Run("with duplicate responses saved by v0.5.29 and v0.5.30", func(t *testing.T) {
+		meas := newMeasurement(&minipipeline.WebMeasurementTestKeys{
+			Queries:                []*model.ArchivalDNSLookupResult{newQuery("93.184.216.34")},
+			XDNSDuplicateResponses: []*model.ArchivalDNSLookupResult{newQuery("10.10.34.35")},
+		})
+		lookupper := mocks.NewGeoIPASNLookupper(map[string]*model.LocationASN{})
+		container := runtimex.Try1(minipipeline.IngestWebMeasurement(lookupper, meas))
+		tk := Reanalyze(lookupper, container, model.DiscardLogger)
+		if tk.DNSFlags&AnalysisDNSFlagInjection == 0 {
+			t.Fatal("expected the injection flag, got", tk.DNSFlags)
+		}
+	})
+}
@@ -70,6 +70,18 @@ type SecureFlow struct {
 	// CookieJar contains the OPTIONAL cookie jar, used for redirects.
 	CookieJar http.CookieJar
 
+	// ECHConfigList is the OPTIONAL ECHConfigList to use for performing
+	// an Encrypted Client Hello TLS handshake.
+	ECHConfigList []byte
+
+	// EnableECH OPTIONALLY enables ECH when following redirects.
+	EnableECH bool
+
+	// FetchAllEndpoints OPTIONALLY allows this flow to fetch the webpage
+	// without following redirects when the PrioSelector does not allow it
+	// to fetch, in which case we save the results in EndpointFetches.
+	FetchAllEndpoints bool
+
 	// FollowRedirects is OPTIONAL and instructs this flow
 	// to follow HTTP redirects (if any).
 	FollowRedirects bool
@@ -163,9 +175,10 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	// why we're using nil to force netxlite to use the cached
 	// default Mozilla cert pool.
 	tlsConfig := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
-		NextProtos: t.alpn(),
-		RootCAs:    nil,
-		ServerName: tlsSNI,
+		EncryptedClientHelloConfigList: t.ECHConfigList,
+		NextProtos:                     t.alpn(),
+		RootCAs:                        nil,
+		ServerName:                     tlsSNI,
 	}
 	const tlsTimeout = 10 * time.Second
 	tlsCtx, tlsCancel := context.WithTimeout(parentCtx, tlsTimeout)
@@ -181,10 +194,15 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	tlsConnState := netxlite.MaybeTLSConnectionState(tlsConn)
 	alpn := tlsConnState.NegotiatedProtocol
 
-	// Determine whether we're allowed to fetch the webpage
+	// Determine whether we're allowed to fetch the webpage, which we always
+	// do when fetching using all the endpoints but without following redirects
+	endpointFetch := false
 	if t.PrioSelector == nil || !t.PrioSelector.permissionToFetch(t.Address) {
-		ol.Stop("stop after TLS handshake")
-		return errNotPermittedToFetch
+		if !t.FetchAllEndpoints {
+			ol.Stop("stop after TLS handshake")
+			return errNotPermittedToFetch
+		}
+		endpointFetch = true
 	}
 
 	// create HTTP transport
@@ -220,6 +238,7 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 		httpTransport,
 		httpReq,
 		trace,
+		endpointFetch,
 	)
 	if err != nil {
 		ol.Stop(err)
@@ -227,7 +246,12 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	}
 
 	// if enabled, follow possible redirects
-	t.maybeFollowRedirects(parentCtx, httpResp)
+	if !endpointFetch {
+		t.maybeFollowRedirects(parentCtx, httpResp)
+	}
+
+	// if enabled, also fetch using HTTP/3 when the endpoint supports it
+	t.maybeStartHTTP3Flow(parentCtx, tlsSNI, httpResp)
 
 	// ignore the response body
 	_ = httpRespBody
@@ -311,7 +335,8 @@ var ErrTooManyRedirects = errors.New("stopped after too many redirects")
 
 // httpTransaction runs the HTTP transaction and saves the results.
 func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn string,
-	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace) (*http.Response, []byte, error) {
+	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace,
+	endpointFetch bool) (*http.Response, []byte, error) {
 	const maxbody = 1 << 19
 	started := trace.TimeSince(trace.ZeroTime())
 
@@ -341,7 +366,7 @@ func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn
 	}
 	if err == nil && httpRedirectIsRedirect(resp) {
 		err = httpValidateRedirect(resp)
-		if err == nil && t.FollowRedirects && !t.NumRedirects.CanFollowOneMoreRedirect() {
+		if err == nil && t.FollowRedirects && !endpointFetch && !t.NumRedirects.CanFollowOneMoreRedirect() {
 			err = ErrTooManyRedirects
 		}
 	}
@@ -375,6 +400,12 @@ func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn
 		trace.Tags()...,
 	)
 
+	// Implementation note: we save the endpoint fetches separately to keep the
+	// requests compatible with Web Connectivity v0.4.
+	if endpointFetch {
+		t.TestKeys.AppendEndpointFetchRequests(ev)
+		return resp, body, err
+	}
 	t.TestKeys.PrependRequests(ev)
 	return resp, body, err
 }
@@ -400,6 +431,8 @@ func (t *SecureFlow) maybeFollowRedirects(ctx context.Context, resp *http.Respon
 			URL:                     location,
 			ZeroTime:                t.ZeroTime,
 			WaitGroup:               t.WaitGroup,
+			EnableECH:               t.EnableECH,
+			FetchAllEndpoints:       t.FetchAllEndpoints,
 			Referer:                 resp.Request.URL.String(),
 			Session:                 nil, // no need to issue another control request
 			TestHelpers:             nil, // ditto
@@ -408,3 +441,35 @@ func (t *SecureFlow) maybeFollowRedirects(ctx context.Context, resp *http.Respon
 		resolvers.Start(ctx)
 	}
 }
+
+// maybeStartHTTP3Flow fetches the webpage using HTTP/3 when we are fetching using
+// all the endpoints and the response advertises HTTP/3 support using Alt-Svc.
+func (t *SecureFlow) maybeStartHTTP3Flow(ctx context.Context, sni string, resp *http.Response) {
+	if !t.FetchAllEndpoints {
+		return
+	}
+	port := http3AltSvcPort(resp)
+	if port == "" {
+		return
+	}
+	addr, _, err := net.SplitHostPort(t.Address)
+	if err != nil {
+		return
+	}
+	t.Logger.Infof("HTTP/3 available at: %s/udp", net.JoinHostPort(addr, port))
+	task := &HTTP3Flow{
+		Address:     net.JoinHostPort(addr, port),
+		Depth:       t.Depth,
+		IDGenerator: t.IDGenerator,
+		Logger:      t.Logger,
+		TestKeys:    t.TestKeys,
+		ZeroTime:    t.ZeroTime,
+		WaitGroup:   t.WaitGroup,
+		HostHeader:  t.HostHeader,
+		Referer:     t.Referer,
+		SNI:         sni,
+		URLPath:     t.URLPath,
+		URLRawQuery: t.URLRawQuery,
+	}
+	task.Start(ctx)
+}
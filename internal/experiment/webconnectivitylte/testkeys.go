@@ -13,6 +13,8 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
 	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/minipipeline"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/optional"
 	"github.com/ooni/probe-cli/v3/internal/webconnectivityalgo"
@@ -46,7 +48,9 @@ type TestKeys struct {
 	Do53 *TestKeysDo53 `json:"x_do53"`
 
 	// DNSDuplicateResponses contains late/duplicate responses we didn't expect to receive from
-	// a resolver (which may raise eyebrows if they're different).
+	// a resolver (which may raise eyebrows if they're different) that we could not attach to
+	// any of the Queries. We attach the other ones to the DuplicateResponses field of the
+	// corresponding entry of Queries, which is where the analysis looks for DNS injection.
 	DNSDuplicateResponses []*model.ArchivalDNSLookupResult `json:"x_dns_duplicate_responses"`
 
 	// Queries contains DNS queries.
@@ -61,12 +65,30 @@ type TestKeys struct {
 	// TLSHandshakes contains TLS handshakes results.
 	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
 
+	// HappyEyeballs contains the results of racing IPv4 and IPv6 connect
+	// attempts for domains resolving to both address families.
+	HappyEyeballs []*model.ArchivalHappyEyeballsResult `json:"x_happy_eyeballs"`
+
+	// EndpointFetches contains the results of fetching the webpage using every
+	// reachable endpoint, which is nil unless Config.FetchAllEndpoints is true.
+	EndpointFetches *TestKeysEndpointFetches `json:"x_endpoint_fetches"`
+
 	// ControlRequest is the control request we sent.
 	ControlRequest *webconnectivity.ControlRequest `json:"x_control_request"`
 
 	// Control contains the TH's response.
 	Control *webconnectivity.ControlResponse `json:"control"`
 
+	// Controls contains the results of each TH when we query several
+	// THs in parallel, in which case Control contains the first successful
+	// response in TH order. This field is empty when we query a single TH.
+	Controls []*model.THResult `json:"x_controls"`
+
+	// ControlDisagreements contains flags describing how the THs disagree with each
+	// other (see [minipipeline.ControlDisagreementDNSFailure]) when we query several
+	// THs in parallel. This field is zero when we query a single TH.
+	ControlDisagreements int64 `json:"x_control_disagreements"`
+
 	// ConnPriorityLog explains why Web Connectivity chose to use a given
 	// ready-to-use HTTP(S) connection among many.
 	ConnPriorityLog []*ConnPriorityLogEntry `json:"x_conn_priority_log"`
@@ -96,12 +118,48 @@ type TestKeys struct {
 	// blocking = null, accessible = null measurements did
 	NullNullFlags int64 `json:"x_null_null_flags"`
 
+	// TLSFlags describes specific TLS anomalies we observed.
+	TLSFlags int64 `json:"x_tls_flags"`
+
+	// EndpointFetchFlags describes the anomalies we observed when fetching the
+	// webpage using every endpoint, which is zero unless Config.FetchAllEndpoints
+	// is true. Because these fetches do not follow redirects, we do not use them
+	// to compute BlockingFlags, Blocking, and Accessible.
+	EndpointFetchFlags int64 `json:"x_endpoint_fetch_flags"`
+
+	// BlockingType is "confirmed" when we matched a known blockpage fingerprint,
+	// "heuristic" when we think there is blocking without matching any known
+	// fingerprint, and null when we think there is no blocking.
+	BlockingType optional.Value[string] `json:"x_blocking_type"`
+
+	// BlockpageFingerprints contains the IDs of the blockpage fingerprints we matched.
+	BlockpageFingerprints []string `json:"x_blockpage_fingerprints"`
+
+	// BlockpageDBVersion is the version of the blockpage fingerprints database.
+	BlockpageDBVersion string `json:"x_blockpage_db_version"`
+
+	// AnalysisTrace is the ordered list of the rules that the analysis evaluated
+	// to set the blocking flags, Blocking, and Accessible, which allows to
+	// understand why we reached a given verdict.
+	AnalysisTrace minipipeline.DecisionTrace `json:"x_analysis_trace"`
+
 	// BodyProportion is the value used to compute BodyLength.
 	BodyProportion float64 `json:"body_proportion"`
 
 	// BodyLength match tells us whether the body length matches.
 	BodyLengthMatch optional.Value[bool] `json:"body_length_match"`
 
+	// BodySimilarity is the similarity between the probe's and the control's bodies,
+	// which is a number between 0 (completely different) and 1 (identical).
+	BodySimilarity optional.Value[float64] `json:"x_body_similarity"`
+
+	// BodySimilarityMatch tells us whether the bodies are similar enough.
+	BodySimilarityMatch optional.Value[bool] `json:"x_body_similarity_match"`
+
+	// BodyComparison tells us whether the HTTP diff compared the body similarity
+	// ("body_similarity") or, like v0.4, the body length ("body_length").
+	BodyComparison optional.Value[string] `json:"x_body_comparison"`
+
 	// HeadersMatch tells us whether the headers match.
 	HeadersMatch optional.Value[bool] `json:"headers_match"`
 
@@ -140,6 +198,10 @@ type TestKeys struct {
 	// mu provides mutual exclusion for accessing the test keys.
 	mu *sync.Mutex
 
+	// probeCC is the probe country code, which the analysis uses
+	// to select the blockpage fingerprints to match.
+	probeCC string
+
 	// testHelper is used to communicate the TH that worked to the main
 	// goroutine such that we can fill measurement.TestHelpers.
 	testHelper *model.OOAPIService
@@ -195,6 +257,39 @@ type TestKeysDo53 struct {
 	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
 }
 
+// TestKeysEndpointFetchesVersion is the current version of [TestKeysEndpointFetches].
+const TestKeysEndpointFetchesVersion = 1
+
+// TestKeysEndpointFetches contains the results of fetching the webpage using each
+// endpoint, which we collect when Config.FetchAllEndpoints is true. These fetches do
+// not follow redirects and the endpoint that was allowed to fetch the webpage does not
+// appear here, because it already appears inside the top-level requests, which we
+// keep compatible with Web Connectivity v0.4.
+//
+// Each request has the same transaction ID of the TCP connect and TLS handshake
+// stored at top level, or of the QUIC handshake stored in this structure.
+//
+// They are on a separate hierarchy to simplify processing.
+type TestKeysEndpointFetches struct {
+	// Version is the version of this data format.
+	Version int64 `json:"version"`
+
+	// QUICHandshakes contains QUIC handshakes results.
+	QUICHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshakes"`
+
+	// Requests contains HTTP results.
+	Requests []*model.ArchivalHTTPRequestResult `json:"requests"`
+}
+
+// NewTestKeysEndpointFetches creates a new instance of TestKeysEndpointFetches.
+func NewTestKeysEndpointFetches() *TestKeysEndpointFetches {
+	return &TestKeysEndpointFetches{
+		Version:        TestKeysEndpointFetchesVersion,
+		QUICHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{},
+		Requests:       []*model.ArchivalHTTPRequestResult{},
+	}
+}
+
 // AppendNetworkEvents appends to NetworkEvents.
 func (tk *TestKeys) AppendNetworkEvents(v ...*model.ArchivalNetworkEvent) {
 	tk.mu.Lock()
@@ -239,6 +334,33 @@ func (tk *TestKeys) AppendTLSHandshakes(v ...*model.ArchivalTLSOrQUICHandshakeRe
 	tk.mu.Unlock()
 }
 
+// AppendHappyEyeballsResults appends to HappyEyeballs.
+func (tk *TestKeys) AppendHappyEyeballsResults(v ...*model.ArchivalHappyEyeballsResult) {
+	tk.mu.Lock()
+	tk.HappyEyeballs = append(tk.HappyEyeballs, v...)
+	tk.mu.Unlock()
+}
+
+// AppendEndpointFetchRequests appends to EndpointFetches.Requests. This
+// method does nothing when EndpointFetches is nil.
+func (tk *TestKeys) AppendEndpointFetchRequests(v ...*model.ArchivalHTTPRequestResult) {
+	tk.mu.Lock()
+	if tk.EndpointFetches != nil {
+		tk.EndpointFetches.Requests = append(tk.EndpointFetches.Requests, v...)
+	}
+	tk.mu.Unlock()
+}
+
+// AppendEndpointFetchQUICHandshakes appends to EndpointFetches.QUICHandshakes. This
+// method does nothing when EndpointFetches is nil.
+func (tk *TestKeys) AppendEndpointFetchQUICHandshakes(v ...*model.ArchivalTLSOrQUICHandshakeResult) {
+	tk.mu.Lock()
+	if tk.EndpointFetches != nil {
+		tk.EndpointFetches.QUICHandshakes = append(tk.EndpointFetches.QUICHandshakes, v...)
+	}
+	tk.mu.Unlock()
+}
+
 // SetControlRequest sets the value of controlRequest.
 func (tk *TestKeys) SetControlRequest(v *webconnectivity.ControlRequest) {
 	tk.mu.Lock()
@@ -253,6 +375,13 @@ func (tk *TestKeys) SetControl(v *webconnectivity.ControlResponse) {
 	tk.mu.Unlock()
 }
 
+// SetControls sets the value of Controls.
+func (tk *TestKeys) SetControls(v []*model.THResult) {
+	tk.mu.Lock()
+	tk.Controls = v
+	tk.mu.Unlock()
+}
+
 // SetControlFailure sets the value of controlFailure.
 func (tk *TestKeys) SetControlFailure(err error) {
 	tk.mu.Lock()
@@ -341,7 +470,11 @@ func NewTestKeys() *TestKeys {
 		Requests:              []*model.ArchivalHTTPRequestResult{},
 		TCPConnect:            []*model.ArchivalTCPConnectResult{},
 		TLSHandshakes:         []*model.ArchivalTLSOrQUICHandshakeResult{},
+		HappyEyeballs:         []*model.ArchivalHappyEyeballsResult{},
+		EndpointFetches:       nil,
 		Control:               nil,
+		Controls:              []*model.THResult{},
+		ControlDisagreements:  0,
 		ConnPriorityLog:       []*ConnPriorityLogEntry{},
 		ControlFailure:        nil,
 		DNSFlags:              0,
@@ -350,8 +483,17 @@ func NewTestKeys() *TestKeys {
 		HTTPExperimentFailure: optional.None[string](),
 		BlockingFlags:         0,
 		NullNullFlags:         0,
+		TLSFlags:              0,
+		EndpointFetchFlags:    0,
+		BlockingType:          optional.None[string](),
+		BlockpageFingerprints: []string{},
+		BlockpageDBVersion:    "",
+		AnalysisTrace:         minipipeline.DecisionTrace{},
 		BodyProportion:        0,
 		BodyLengthMatch:       optional.None[bool](),
+		BodySimilarity:        optional.None[float64](),
+		BodySimilarityMatch:   optional.None[bool](),
+		BodyComparison:        optional.None[string](),
 		HeadersMatch:          optional.None[bool](),
 		StatusCodeMatch:       optional.None[bool](),
 		TitleMatch:            optional.None[bool](),
@@ -367,6 +509,9 @@ func NewTestKeys() *TestKeys {
 // Finalize performs any delayed computation on the test keys. This function
 // must be called from the measurer after all the tasks have completed.
 func (tk *TestKeys) Finalize(logger model.Logger) {
+	// attach the duplicate responses to the corresponding queries, such that
+	// the analysis can determine whether responses were injected
+	tk.DNSDuplicateResponses = measurexlite.AttachDNSDuplicateResponses(tk.Queries, tk.DNSDuplicateResponses...)
 	tk.analysisToplevel(logger)
 	// Note: sort.SliceStable is WAI when the input slice is nil
 	// as demonstrated by https://go.dev/play/p/znA4MyGFVHC
@@ -2,6 +2,7 @@ package kvstore
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -54,3 +55,11 @@ func (kvs *FS) Get(key string) ([]byte, error) {
 func (kvs *FS) Set(key string, value []byte) error {
 	return lockedfile.Write(kvs.filename(key), bytes.NewReader(value), 0600)
 }
+
+// Delete deletes a specific key. Deleting a nonexistent key is not an error.
+func (kvs *FS) Delete(key string) error {
+	if err := os.Remove(kvs.filename(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
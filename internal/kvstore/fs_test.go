@@ -49,6 +49,30 @@ func TestFileSystemNoSuchKey(t *testing.T) {
 	}
 }
 
+func TestFileSystemDelete(t *testing.T) {
+	dirpath := filepath.Join("testdata", "kvstore2")
+	if err := os.RemoveAll(dirpath); err != nil {
+		t.Fatal(err)
+	}
+	kvstore, err := NewFS(dirpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Set("antani", []byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Delete("antani"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvstore.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+	// deleting a nonexistent key is not an error
+	if err := kvstore.Delete("antani"); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestFileSystemWithFailure(t *testing.T) {
 	expect := errors.New("mocked error")
 	mkdir := func(path string, perm fs.FileMode) error {
@@ -45,3 +45,11 @@ func (kvs *Memory) Set(key string, value []byte) error {
 	kvs.m[key] = value
 	return nil
 }
+
+// Delete deletes a key from the key-value store.
+func (kvs *Memory) Delete(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	delete(kvs.m, key)
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 // Config contains configuration for creating new transports, dialers, etc. When
@@ -30,6 +31,7 @@ type Config struct {
 	ProxyURL            *url.URL             // default: no proxy
 	ReadWriteSaver      *tracex.Saver        // default: not saving I/O events
 	Saver               *tracex.Saver        // default: not saving non-I/O events
+	TLSClientHelloID    *utls.ClientHelloID  // default: use the stdlib
 	TLSConfig           *tls.Config          // default: attempt using h2
 	TLSDialer           model.TLSDialer      // default: dialer.TLSDialer
 }
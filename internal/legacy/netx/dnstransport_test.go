@@ -220,6 +220,32 @@ func TestNewDNSCLientDoTWithoutPort(t *testing.T) {
 	}
 }
 
+func TestNewDNSClientDoQ(t *testing.T) {
+	dnsclient, err := NewDNSClientWithOverrides(
+		Config{}, "doq://8.8.8.8", "", "dns.google", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := dnsclient.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	txp, ok := r.Transport().(*netxlite.DNSOverQUICTransport)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	if txp.Network() != "doq" {
+		t.Fatal("not the Network we expected")
+	}
+	if txp.Address() != "8.8.8.8:853" {
+		t.Fatal("expected default port to be added")
+	}
+	if txp.ServerName != "dns.google" {
+		t.Fatal("expected the SNI override to be used")
+	}
+	dnsclient.CloseIdleConnections()
+}
+
 func TestNewDNSCLientTCPWithoutPort(t *testing.T) {
 	c, err := NewDNSClientWithOverrides(
 		Config{}, "tcp://8.8.8.8", "", "8.8.8.8", "")
@@ -17,7 +17,11 @@ func NewTLSDialer(config Config) model.TLSDialer {
 	netx := &netxlite.Netx{}
 	logger := model.ValidLoggerOrDefault(config.Logger)
 	thx := netx.NewTLSHandshakerStdlib(logger)
-	thx = config.Saver.WrapTLSHandshaker(thx) // WAI even when config.Saver is nil
+	if config.TLSClientHelloID != nil {
+		thx = netx.NewTLSHandshakerUTLS(logger, config.TLSClientHelloID)
+	}
+	fingerprint := netxlite.UTLSFingerprintName(config.TLSClientHelloID)
+	thx = config.Saver.WrapTLSHandshakerWithFingerprint(thx, fingerprint) // WAI even when config.Saver is nil
 	tlsConfig := netxlite.ClonedTLSConfigOrNewEmptyConfig(config.TLSConfig)
 	return netxlite.NewTLSDialerWithConfig(config.Dialer, thx, tlsConfig)
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"crypto/tls"
 	"testing"
+	"time"
 
 	"github.com/ooni/netem"
 	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/testingx"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 func TestNewTLSDialer(t *testing.T) {
@@ -44,6 +46,31 @@ func TestNewTLSDialer(t *testing.T) {
 		}
 	})
 
+	t.Run("we can use a uTLS fingerprint", func(t *testing.T) {
+		ca := netem.MustNewCA()
+		cert := ca.MustNewTLSCertificate("www.example.com")
+		server := testingx.MustNewTLSServer(testingx.TLSHandlerHandshakeAndWriteText(cert, testingx.HTTPBlockpage451))
+		defer server.Close()
+		saver := &tracex.Saver{}
+		tdx := NewTLSDialer(Config{
+			Saver:            saver,
+			TLSClientHelloID: &utls.HelloFirefox_Auto,
+			TLSConfig: &tls.Config{
+				RootCAs:    ca.DefaultCertPool(),
+				ServerName: "www.example.com",
+			},
+		})
+		conn, err := tdx.DialTLSContext(context.Background(), "tcp", server.Endpoint())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		handshakes := tracex.NewTLSHandshakesList(time.Now(), saver.Read())
+		if len(handshakes) != 1 || handshakes[0].Fingerprint != "firefox" {
+			t.Fatal("unexpected handshakes", handshakes)
+		}
+	})
+
 	t.Run("we can skip TLS verification", func(t *testing.T) {
 		ca := netem.MustNewCA()
 		cert := ca.MustNewTLSCertificate("www.example.com")
@@ -11,7 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -131,13 +133,26 @@ type dnsQueryType string
 // NewDNSQueriesList returns a list of DNS queries.
 func NewDNSQueriesList(begin time.Time, events []Event) (out []DNSQueryEntry) {
 	// TODO(bassosimone): add support for CNAME lookups.
+	roundTrips := newDNSRoundTripsIndex(events)
 	for _, wrapper := range events {
 		if _, ok := wrapper.(*EventResolveDone); !ok {
 			continue
 		}
 		ev := wrapper.Value()
+		if ev.DNSQueryType != "" {
+			// this event comes from LookupRecords, so we know the query type
+			qtype := dnsQueryType(ev.DNSQueryType)
+			entry := qtype.makeQueryEntry(begin, ev)
+			qtype.maybeAddRoundTripInfo(&entry, roundTrips[dnsRoundTripKey(ev.Hostname, string(qtype))])
+			for _, record := range ev.DNSRecords {
+				entry.Answers = append(entry.Answers, makeAnswerEntryFromRecord(record))
+			}
+			out = append(out, entry)
+			continue
+		}
 		for _, qtype := range []dnsQueryType{"A", "AAAA"} {
 			entry := qtype.makeQueryEntry(begin, ev)
+			qtype.maybeAddRoundTripInfo(&entry, roundTrips[dnsRoundTripKey(ev.Hostname, string(qtype))])
 			for _, addr := range ev.Addresses {
 				if qtype.ipOfType(addr) {
 					entry.Answers = append(
@@ -160,6 +175,58 @@ func NewDNSQueriesList(begin time.Time, events []Event) (out []DNSQueryEntry) {
 	return
 }
 
+// dnsRoundTripKey returns the key used by newDNSRoundTripsIndex.
+func dnsRoundTripKey(domain, qtype string) string {
+	return dns.Fqdn(domain) + " " + qtype
+}
+
+// newDNSRoundTripsIndex maps each (domain, query type) pair to the last DNS round
+// trip for such a pair, such that we can augment the entries we create from the
+// resolve events with the EDNS0 options we sent and received.
+func newDNSRoundTripsIndex(events []Event) map[string]*EventValue {
+	out := make(map[string]*EventValue)
+	for _, wrapper := range events {
+		if _, ok := wrapper.(*EventDNSRoundTripDone); !ok {
+			continue
+		}
+		ev := wrapper.Value()
+		query := &dns.Msg{}
+		if err := query.Unpack(ev.DNSQuery); err != nil || len(query.Question) != 1 {
+			continue
+		}
+		question := query.Question[0]
+		out[dnsRoundTripKey(question.Name, dns.TypeToString[question.Qtype])] = ev
+	}
+	return out
+}
+
+// maybeAddRoundTripInfo adds to the entry the EDNS0 options of the
+// corresponding DNS round trip, if any.
+func (qtype dnsQueryType) maybeAddRoundTripInfo(entry *DNSQueryEntry, rtx *EventValue) {
+	if rtx == nil {
+		return
+	}
+	entry.EDNS0Sent = measurexlite.NewArchivalEDNS0(rtx.DNSQuery)
+	entry.EDNS0Received = measurexlite.NewArchivalEDNS0(rtx.DNSResponse)
+}
+
+// makeAnswerEntryFromRecord converts a record returned by LookupRecords to an answer.
+func makeAnswerEntryFromRecord(record *model.DNSRecord) DNSAnswerEntry {
+	ttl := record.TTL
+	answer := DNSAnswerEntry{
+		AnswerType: dns.TypeToString[record.Type],
+		Hostname:   record.Target,
+		TTL:        &ttl,
+		Value:      record.Value,
+	}
+	switch record.Type {
+	case dns.TypeA, dns.TypeAAAA:
+		answer = dnsQueryType(answer.AnswerType).makeAnswerEntry(record.Value)
+		answer.TTL = &ttl
+	}
+	return answer
+}
+
 func (qtype dnsQueryType) ipOfType(addr string) bool {
 	switch qtype {
 	case "A":
@@ -264,6 +331,7 @@ func NewTLSHandshakesList(begin time.Time, events []Event) (out []TLSHandshake)
 			Address:            ev.Address,
 			CipherSuite:        ev.TLSCipherSuite,
 			Failure:            ev.Err.ToFailure(),
+			Fingerprint:        ev.TLSFingerprint,
 			NegotiatedProtocol: ev.TLSNegotiatedProto,
 			NoTLSVerify:        ev.NoTLSVerify,
 			PeerCertificates:   tlsMakePeerCerts(ev.TLSPeerCerts),
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/gorilla/websocket"
+	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -383,6 +384,57 @@ func TestNewDNSQueriesList(t *testing.T) {
 			QueryType: "AAAA",
 			T:         0.2,
 		}},
+	}, {
+		name: "run with LookupRecords results",
+		args: args{
+			begin: begin,
+			events: []Event{&EventResolveDone{&EventValue{
+				Address:      "1.1.1.1:53",
+				DNSQueryType: "MX",
+				DNSRecords: []*model.DNSRecord{{
+					Name:   "example.com.",
+					Type:   dns.TypeMX,
+					TTL:    300,
+					Target: "mx.example.com.",
+					Value:  "10 mx.example.com.",
+				}},
+				Hostname: "example.com",
+				Proto:    "udp",
+				Time:     begin.Add(200 * time.Millisecond),
+			}}},
+		},
+		want: []DNSQueryEntry{{
+			Answers: []DNSAnswerEntry{{
+				AnswerType: "MX",
+				Hostname:   "mx.example.com.",
+				TTL:        func() *uint32 { v := uint32(300); return &v }(),
+				Value:      "10 mx.example.com.",
+			}},
+			Engine:          "udp",
+			Hostname:        "example.com",
+			QueryType:       "MX",
+			ResolverAddress: "1.1.1.1:53",
+			T:               0.2,
+		}},
+	}, {
+		name: "run with LookupRecords errors",
+		args: args{
+			begin: begin,
+			events: []Event{&EventResolveDone{&EventValue{
+				DNSQueryType: "TXT",
+				Err:          netxlite.FailureDNSNXDOMAINError,
+				Hostname:     "example.com",
+				Time:         begin.Add(200 * time.Millisecond),
+			}}},
+		},
+		want: []DNSQueryEntry{{
+			Answers: nil,
+			Failure: NewFailure(
+				&netxlite.ErrWrapper{Failure: netxlite.FailureDNSNXDOMAINError}),
+			Hostname:  "example.com",
+			QueryType: "TXT",
+			T:         0.2,
+		}},
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -394,6 +446,72 @@ func TestNewDNSQueriesList(t *testing.T) {
 	}
 }
 
+func TestNewDNSQueriesListWithEDNS0(t *testing.T) {
+	begin := time.Now()
+	encoder := &netxlite.DNSEncoderMiekg{
+		EDNS0: []dns.EDNS0{&dns.EDNS0_NSID{Code: dns.EDNS0NSID}},
+	}
+	rawQuery, err := encoder.Encode("dns.google.com", dns.TypeA, false).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := &dns.Msg{}
+	if err := query.Unpack(rawQuery); err != nil {
+		t.Fatal(err)
+	}
+	reply := &dns.Msg{}
+	reply.SetRcode(query, dns.RcodeServerFailure)
+	reply.SetEdns0(4096, false)
+	reply.IsEdns0().Option = append(reply.IsEdns0().Option, &dns.EDNS0_NSID{
+		Code: dns.EDNS0NSID,
+		Nsid: "6e73",
+	})
+	rawReply, err := reply.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{&EventDNSRoundTripDone{&EventValue{
+		Address:     "1.1.1.1:53",
+		DNSQuery:    rawQuery,
+		DNSResponse: rawReply,
+		Proto:       "udp",
+		Time:        begin.Add(90 * time.Millisecond),
+	}}, &EventDNSRoundTripDone{&EventValue{ // skipped because it's not a valid query
+		Address:  "1.1.1.1:53",
+		DNSQuery: []byte{0x00},
+		Proto:    "udp",
+		Time:     begin.Add(95 * time.Millisecond),
+	}}, &EventResolveDone{&EventValue{
+		Address:  "1.1.1.1:53",
+		Err:      netxlite.FailureDNSServerMisbehaving,
+		Hostname: "dns.google.com",
+		Proto:    "udp",
+		Time:     begin.Add(100 * time.Millisecond),
+	}}}
+	failure := netxlite.FailureDNSServerMisbehaving
+	expect := []DNSQueryEntry{{
+		EDNS0Received:   []model.ArchivalEDNS0{{Code: dns.EDNS0NSID, Name: "NSID", Value: "6e73"}},
+		EDNS0Sent:       []model.ArchivalEDNS0{{Code: dns.EDNS0NSID, Name: "NSID", Value: ""}},
+		Engine:          "udp",
+		Failure:         &failure,
+		Hostname:        "dns.google.com",
+		QueryType:       "A",
+		ResolverAddress: "1.1.1.1:53",
+		T:               0.1,
+	}, {
+		Engine:          "udp",
+		Failure:         &failure,
+		Hostname:        "dns.google.com",
+		QueryType:       "AAAA",
+		ResolverAddress: "1.1.1.1:53",
+		T:               0.1,
+	}}
+	got := NewDNSQueriesList(begin, events)
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestNewNetworkEventsList(t *testing.T) {
 	begin := time.Now()
 	type args struct {
@@ -514,6 +632,7 @@ func TestNewTLSHandshakesList(t *testing.T) {
 				NoTLSVerify:        false,
 				Proto:              "tcp",
 				TLSCipherSuite:     "SUITE",
+				TLSFingerprint:     "chrome",
 				TLSNegotiatedProto: "h2",
 				TLSPeerCerts: [][]byte{
 					[]byte("deadbeef"),
@@ -528,6 +647,7 @@ func TestNewTLSHandshakesList(t *testing.T) {
 			Address:            "131.252.210.176:443",
 			CipherSuite:        "SUITE",
 			Failure:            NewFailure(io.EOF),
+			Fingerprint:        "chrome",
 			NegotiatedProtocol: "h2",
 			NoTLSVerify:        false,
 			PeerCertificates: []model.ArchivalBinaryData{
@@ -9,6 +9,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -97,6 +98,31 @@ func (r *ResolverSaver) LookupNS(ctx context.Context, domain string) ([]*net.NS,
 	return r.Resolver.LookupNS(ctx, domain)
 }
 
+// LookupRecords implements Resolver.LookupRecords
+func (r *ResolverSaver) LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	start := time.Now()
+	r.Saver.Write(&EventResolveStart{&EventValue{
+		Address:      r.Resolver.Address(),
+		DNSQueryType: dns.TypeToString[qtype],
+		Hostname:     domain,
+		Proto:        r.Network(),
+		Time:         start,
+	}})
+	records, err := r.Resolver.LookupRecords(ctx, domain, qtype)
+	stop := time.Now()
+	r.Saver.Write(&EventResolveDone{&EventValue{
+		Address:      r.Resolver.Address(),
+		DNSQueryType: dns.TypeToString[qtype],
+		DNSRecords:   records,
+		Duration:     stop.Sub(start),
+		Err:          NewFailureStr(err),
+		Hostname:     domain,
+		Proto:        r.Network(),
+		Time:         stop,
+	}})
+	return records, err
+}
+
 // DNSTransportSaver is a DNS transport that saves events.
 type DNSTransportSaver struct {
 	// DNSTransport is the underlying DNS transport.
@@ -216,6 +216,52 @@ func TestResolverSaver(t *testing.T) {
 		}
 	})
 
+	t.Run("LookupRecords", func(t *testing.T) {
+		expected := errors.New("mocked")
+		saver := &Saver{}
+		child := &mocks.Resolver{
+			MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+				return nil, expected
+			},
+			MockAddress: func() string {
+				return "8.8.8.8:53"
+			},
+			MockNetwork: func() string {
+				return "udp"
+			},
+		}
+		reso := saver.WrapResolver(child)
+		records, err := reso.LookupRecords(context.Background(), "dns.google", dns.TypeTXT)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if len(records) != 0 {
+			t.Fatal("expected zero length array")
+		}
+		ev := saver.Read()
+		if len(ev) != 2 {
+			t.Fatal("expected number of events")
+		}
+		if ev[0].Value().Hostname != "dns.google" {
+			t.Fatal("unexpected Hostname")
+		}
+		if ev[0].Value().DNSQueryType != "TXT" {
+			t.Fatal("unexpected DNSQueryType")
+		}
+		if ev[0].Name() != "resolve_start" {
+			t.Fatal("unexpected name")
+		}
+		if ev[1].Value().Err != "unknown_failure: mocked" {
+			t.Fatal("unexpected Err", ev[1].Value().Err)
+		}
+		if ev[1].Value().DNSQueryType != "TXT" {
+			t.Fatal("unexpected DNSQueryType")
+		}
+		if ev[1].Name() != "resolve_done" {
+			t.Fatal("unexpected name")
+		}
+	})
+
 	t.Run("CloseIdleConnections", func(t *testing.T) {
 		var called bool
 		saver := &Saver{}
@@ -23,6 +23,10 @@ type TLSHandshakerSaver struct {
 
 	// Saver is the saver in which to save events.
 	Saver *Saver
+
+	// Fingerprint is the OPTIONAL name of the uTLS fingerprint used by the
+	// TLSHandshaker, which we include into the TLS handshake results.
+	Fingerprint string
 }
 
 // WrapTLSHandshaker wraps a model.TLSHandshaker with a SaverTLSHandshaker
@@ -40,6 +44,19 @@ func (s *Saver) WrapTLSHandshaker(thx model.TLSHandshaker) model.TLSHandshaker {
 	}
 }
 
+// WrapTLSHandshakerWithFingerprint is like WrapTLSHandshaker but also
+// records the name of the uTLS fingerprint used by the TLSHandshaker.
+func (s *Saver) WrapTLSHandshakerWithFingerprint(thx model.TLSHandshaker, fingerprint string) model.TLSHandshaker {
+	if s == nil {
+		return thx
+	}
+	return &TLSHandshakerSaver{
+		TLSHandshaker: thx,
+		Saver:         s,
+		Fingerprint:   fingerprint,
+	}
+}
+
 // Handshake implements model.TLSHandshaker.Handshake
 func (h *TLSHandshakerSaver) Handshake(
 	ctx context.Context, conn net.Conn, config *tls.Config) (model.TLSConn, error) {
@@ -64,6 +81,7 @@ func (h *TLSHandshakerSaver) Handshake(
 		NoTLSVerify:        config.InsecureSkipVerify,
 		Proto:              proto,
 		TLSCipherSuite:     netxlite.TLSCipherSuiteString(tstate.CipherSuite),
+		TLSFingerprint:     h.Fingerprint,
 		TLSNegotiatedProto: tstate.NegotiatedProtocol,
 		TLSNextProtos:      config.NextProtos,
 		TLSPeerCerts:       tlsPeerCerts(tstate, err),
@@ -21,6 +21,25 @@ func TestWrapTLSHandshaker(t *testing.T) {
 	}
 }
 
+func TestWrapTLSHandshakerWithFingerprint(t *testing.T) {
+	t.Run("with a nil saver", func(t *testing.T) {
+		var saver *Saver
+		thx := &mocks.TLSHandshaker{}
+		if saver.WrapTLSHandshakerWithFingerprint(thx, "chrome") != thx {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("with a non-nil saver", func(t *testing.T) {
+		saver := &Saver{}
+		thx := &mocks.TLSHandshaker{}
+		wrapped := saver.WrapTLSHandshakerWithFingerprint(thx, "chrome").(*TLSHandshakerSaver)
+		if wrapped.TLSHandshaker != thx || wrapped.Saver != saver || wrapped.Fingerprint != "chrome" {
+			t.Fatal("unexpected result")
+		}
+	})
+}
+
 func TestTLSHandshakerSaver(t *testing.T) {
 
 	t.Run("Handshake", func(t *testing.T) {
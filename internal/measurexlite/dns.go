@@ -6,9 +6,13 @@ package measurexlite
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
 	"net"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/miekg/dns"
@@ -92,6 +96,13 @@ func (r *resolverTrace) LookupNS(ctx context.Context, domain string) ([]*net.NS,
 	return r.r.LookupNS(netxlite.ContextWithTrace(ctx, r.tx), domain)
 }
 
+// LookupRecords implements model.Resolver.LookupRecords
+func (r *resolverTrace) LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	defer r.emiteResolveDone()
+	r.emitResolveStart()
+	return r.r.LookupRecords(netxlite.ContextWithTrace(ctx, r.tx), domain, qtype)
+}
+
 // NewStdlibResolver returns a trace-ware system resolver
 func (tx *Trace) NewStdlibResolver(logger model.DebugLogger) model.Resolver {
 	// Here we make sure that we're counting bytes sent and received.
@@ -103,11 +114,23 @@ func (tx *Trace) NewParallelUDPResolver(logger model.DebugLogger, dialer model.D
 	return tx.wrapResolver(tx.Netx.NewParallelUDPResolver(logger, dialer, address))
 }
 
+// NewParallelUDPResolverWithOptions is like NewParallelUDPResolver but allows to
+// configure the underlying [*netxlite.ParallelResolver] (e.g., to use DNSSEC).
+func (tx *Trace) NewParallelUDPResolverWithOptions(logger model.DebugLogger, dialer model.Dialer,
+	address string, options ...netxlite.ParallelResolverOption) model.Resolver {
+	return tx.wrapResolver(netxlite.NewParallelUDPResolverWithOptions(logger, dialer, address, options...))
+}
+
 // NewParallelDNSOverHTTPSResolver returns a trace-aware parallel DoH resolver
 func (tx *Trace) NewParallelDNSOverHTTPSResolver(logger model.DebugLogger, URL string) model.Resolver {
 	return tx.wrapResolver(tx.Netx.NewParallelDNSOverHTTPSResolver(logger, URL))
 }
 
+// NewParallelDNSOverQUICResolver returns a trace-aware parallel DoQ resolver
+func (tx *Trace) NewParallelDNSOverQUICResolver(logger model.DebugLogger, address string) model.Resolver {
+	return tx.wrapResolver(tx.Netx.NewParallelDNSOverQUICResolver(logger, address))
+}
+
 // OnDNSRoundTripForLookupHost implements model.Trace.OnDNSRoundTripForLookupHost
 func (tx *Trace) OnDNSRoundTripForLookupHost(started time.Time, reso model.Resolver, query model.DNSQuery,
 	response model.DNSResponse, addrs []string, err error, finished time.Time) {
@@ -147,8 +170,13 @@ type DNSNetworkAddresser interface {
 func NewArchivalDNSLookupResultFromRoundTrip(index int64, started time.Duration,
 	reso DNSNetworkAddresser, query model.DNSQuery, response model.DNSResponse,
 	addrs []string, err error, finished time.Duration, tags ...string) *model.ArchivalDNSLookupResult {
+	dnssecStatus, dnssecFailure := maybeDNSSECValidation(response)
 	return &model.ArchivalDNSLookupResult{
-		Answers:          newArchivalDNSAnswers(addrs, response),
+		Answers:          newArchivalDNSAnswers(query.Type(), addrs, response),
+		DNSSECFailure:    dnssecFailure,
+		DNSSECStatus:     dnssecStatus,
+		EDNS0Received:    maybeResponseEDNS0(response),
+		EDNS0Sent:        maybeQueryEDNS0(query),
 		Engine:           reso.Network(),
 		Failure:          NewFailure(err),
 		GetaddrinfoError: netxlite.ErrorToGetaddrinfoRetvalOrZero(err),
@@ -174,6 +202,76 @@ func maybeResponseRcode(resp model.DNSResponse) (out int64) {
 	return
 }
 
+// maybeDNSSECValidation returns the DNSSEC validation status and failure (when available).
+func maybeDNSSECValidation(resp model.DNSResponse) (status string, failure *string) {
+	if vr, ok := resp.(model.DNSSECValidatedResponse); ok {
+		if validation := vr.DNSSECValidation(); validation != nil {
+			status, failure = validation.Status, NewFailure(validation.Failure)
+		}
+	}
+	return
+}
+
+// maybeQueryEDNS0 returns the EDNS0 options sent with the query (when available).
+func maybeQueryEDNS0(query model.DNSQuery) []model.ArchivalEDNS0 {
+	rawQuery, err := query.Bytes()
+	if err != nil {
+		return nil
+	}
+	return NewArchivalEDNS0(rawQuery)
+}
+
+// maybeResponseEDNS0 returns the EDNS0 options returned with the response (when available).
+func maybeResponseEDNS0(resp model.DNSResponse) []model.ArchivalEDNS0 {
+	if resp == nil {
+		return nil
+	}
+	return NewArchivalEDNS0(resp.Bytes())
+}
+
+// archivalEDNS0Names maps the EDNS0 option codes we know about to their names.
+var archivalEDNS0Names = map[uint16]string{
+	dns.EDNS0LLQ:          "LLQ",
+	dns.EDNS0UL:           "UL",
+	dns.EDNS0NSID:         "NSID",
+	dns.EDNS0DAU:          "DAU",
+	dns.EDNS0DHU:          "DHU",
+	dns.EDNS0N3U:          "N3U",
+	dns.EDNS0SUBNET:       "ECS",
+	dns.EDNS0EXPIRE:       "EXPIRE",
+	dns.EDNS0COOKIE:       "COOKIE",
+	dns.EDNS0TCPKEEPALIVE: "TCP_KEEPALIVE",
+	dns.EDNS0PADDING:      "PADDING",
+	dns.EDNS0EDE:          "EDE",
+}
+
+// NewArchivalEDNS0 generates []model.ArchivalEDNS0 from the given raw DNS message.
+func NewArchivalEDNS0(rawMsg []byte) (out []model.ArchivalEDNS0) {
+	msg := &dns.Msg{}
+	if err := msg.Unpack(rawMsg); err != nil {
+		return
+	}
+	opt := msg.IsEdns0()
+	if opt == nil {
+		return
+	}
+	for _, option := range opt.Option {
+		entry := model.ArchivalEDNS0{
+			Code:  option.Option(),
+			Name:  archivalEDNS0Names[option.Option()],
+			Value: option.String(),
+		}
+		switch v := option.(type) {
+		case *dns.EDNS0_PADDING:
+			entry.Value = "" // the value is just a bunch of zeroes
+		case *dns.EDNS0_LOCAL:
+			entry.Value = hex.EncodeToString(v.Data) // avoid repeating the code
+		}
+		out = append(out, entry)
+	}
+	return
+}
+
 // maybeRawResponse returns either the raw response (when available) or nil.
 func maybeRawResponse(resp model.DNSResponse) (out []byte) {
 	if resp != nil {
@@ -182,8 +280,20 @@ func maybeRawResponse(resp model.DNSResponse) (out []byte) {
 	return
 }
 
-// newArchivalDNSAnswers generates []model.ArchivalDNSAnswer from [addrs] and [resp].
-func newArchivalDNSAnswers(addrs []string, resp model.DNSResponse) (out []model.ArchivalDNSAnswer) {
+// newArchivalDNSAnswers generates []model.ArchivalDNSAnswer from [qtype], [addrs] and [resp].
+//
+// Note that getaddrinfo lookups use [dns.TypeANY] as the query type.
+func newArchivalDNSAnswers(qtype uint16, addrs []string, resp model.DNSResponse) (out []model.ArchivalDNSAnswer) {
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA, dns.TypeHTTPS, dns.TypeANY:
+		return newArchivalDNSAnswersForLookupHost(addrs, resp)
+	default:
+		return newArchivalDNSAnswersFromRecords(resp)
+	}
+}
+
+// newArchivalDNSAnswersForLookupHost generates []model.ArchivalDNSAnswer from [addrs] and [resp].
+func newArchivalDNSAnswersForLookupHost(addrs []string, resp model.DNSResponse) (out []model.ArchivalDNSAnswer) {
 	// Design note: in principle we might want to extract everything from the
 	// response but, when we're called by netxlite, netxlite has already extracted
 	// the addresses to return them to the caller, so I think it's fine to keep
@@ -247,6 +357,43 @@ func newArchivalDNSAnswers(addrs []string, resp model.DNSResponse) (out []model.
 	return
 }
 
+// newArchivalDNSAnswersFromRecords generates []model.ArchivalDNSAnswer from all the
+// records inside [resp], which is what we want for queries such as TXT, MX, and SOA.
+func newArchivalDNSAnswersFromRecords(resp model.DNSResponse) (out []model.ArchivalDNSAnswer) {
+	if resp == nil {
+		return
+	}
+	records, err := resp.DecodeRecords()
+	if err != nil {
+		return
+	}
+	for _, record := range records {
+		ttl := record.TTL
+		answer := model.ArchivalDNSAnswer{
+			ASN:        0,
+			ASOrgName:  "",
+			AnswerType: dns.TypeToString[record.Type],
+			Hostname:   record.Target,
+			IPv4:       "",
+			IPv6:       "",
+			TTL:        &ttl,
+			Value:      record.Value,
+		}
+		switch record.Type {
+		case dns.TypeA, dns.TypeAAAA:
+			asn, org, _ := geoipx.LookupASN(record.Value, "") // error if not in the DB; returns sensible values on error
+			answer.ASN, answer.ASOrgName, answer.Value = int64(asn), org, ""
+			if record.Type == dns.TypeA {
+				answer.IPv4 = record.Value
+			} else {
+				answer.IPv6 = record.Value
+			}
+		}
+		out = append(out, answer)
+	}
+	return
+}
+
 // DNSLookupsFromRoundTrip drains the network events buffered inside the DNSLookup channel
 func (tx *Trace) DNSLookupsFromRoundTrip() (out []*model.ArchivalDNSLookupResult) {
 	for {
@@ -325,3 +472,60 @@ func (tx *Trace) DelayedDNSResponseWithTimeout(ctx context.Context,
 		}
 	}
 }
+
+// AttachDNSDuplicateResponses attaches each duplicate DNS response (see
+// [*Trace.DelayedDNSResponseWithTimeout]) to the DuplicateResponses field of the
+// first lookup result having the same transaction ID, resolver address, and query
+// type. This function returns the duplicate responses that it could not attach
+// to any lookup result, or an empty (i.e., not nil) list if it attached all of them.
+func AttachDNSDuplicateResponses(queries []*model.ArchivalDNSLookupResult,
+	duplicates ...*model.ArchivalDNSLookupResult) []*model.ArchivalDNSLookupResult {
+	key := func(query *model.ArchivalDNSLookupResult) string {
+		return fmt.Sprintf("%d %s %s", query.TransactionID, query.ResolverAddress, query.QueryType)
+	}
+	index := make(map[string]*model.ArchivalDNSLookupResult)
+	for _, query := range queries {
+		if _, found := index[key(query)]; !found {
+			index[key(query)] = query
+		}
+	}
+	unattached := []*model.ArchivalDNSLookupResult{}
+	for _, dup := range duplicates {
+		query, found := index[key(dup)]
+		if !found {
+			unattached = append(unattached, dup)
+			continue
+		}
+		query.DuplicateResponses = append(query.DuplicateResponses, dup)
+	}
+	return unattached
+}
+
+// DNSLookupResultHasInjection returns whether any of the duplicate responses attached
+// to the given lookup result (see [AttachDNSDuplicateResponses]) contains IP addresses
+// different from the ones in the lookup result, which is how DNS injection typically
+// manifests itself: the injected response arrives first and the legitimate one later.
+func DNSLookupResultHasInjection(query *model.ArchivalDNSLookupResult) bool {
+	addrs := dnsAnswersAddrs(query.Answers)
+	for _, dup := range query.DuplicateResponses {
+		if dnsAnswersAddrs(dup.Answers) != addrs {
+			return true
+		}
+	}
+	return false
+}
+
+// dnsAnswersAddrs returns a canonical representation of the addresses in the answers.
+func dnsAnswersAddrs(answers []model.ArchivalDNSAnswer) string {
+	var addrs []string
+	for _, answer := range answers {
+		switch answer.AnswerType {
+		case "A":
+			addrs = append(addrs, answer.IPv4)
+		case "AAAA":
+			addrs = append(addrs, answer.IPv6)
+		}
+	}
+	sort.Strings(addrs)
+	return strings.Join(addrs, " ")
+}
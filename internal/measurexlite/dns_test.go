@@ -54,6 +54,14 @@ func TestNewResolver(t *testing.T) {
 					Host: "1.1.1.1",
 				}}, nil
 			},
+			MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+				return []*model.DNSRecord{{
+					Name:  "example.com.",
+					Type:  qtype,
+					TTL:   300,
+					Value: `"v=spf1 -all"`,
+				}}, nil
+			},
 			MockCloseIdleConnections: func() {
 				called = true
 			},
@@ -114,6 +122,23 @@ func TestNewResolver(t *testing.T) {
 			}
 		})
 
+		t.Run("LookupRecords is correctly forwarded", func(t *testing.T) {
+			want := []*model.DNSRecord{{
+				Name:  "example.com.",
+				Type:  dns.TypeTXT,
+				TTL:   300,
+				Value: `"v=spf1 -all"`,
+			}}
+			ctx := context.Background()
+			got, err := resolver.LookupRecords(ctx, "example.com", dns.TypeTXT)
+			if err != nil {
+				t.Fatal("expected nil error")
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
 		t.Run("CloseIdleConnections is correctly forwarded", func(t *testing.T) {
 			resolver.CloseIdleConnections()
 			if !called {
@@ -311,6 +336,19 @@ func TestNewWrappedResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("NewParallelDNSOverQUICResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		resolver := trace.NewParallelDNSOverQUICResolver(model.DiscardLogger, "dns.adguard-dns.com:853")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "doq" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
 	t.Run("NewParallelUDPResolver works as intended", func(t *testing.T) {
 		zeroTime := time.Now()
 		trace := NewTrace(0, zeroTime)
@@ -325,6 +363,21 @@ func TestNewWrappedResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("NewParallelUDPResolverWithOptions works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := netxlite.NewDialerWithStdlibResolver(model.DiscardLogger)
+		resolver := trace.NewParallelUDPResolverWithOptions(
+			model.DiscardLogger, dialer, "1.1.1.1:53", netxlite.ParallelResolverOptionDNSSEC(true))
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "udp" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
 	t.Run("NewStdlibResolver works as intended", func(t *testing.T) {
 		zeroTime := time.Now()
 		trace := NewTrace(0, zeroTime)
@@ -404,6 +457,9 @@ func TestDelayedDNSResponseWithTimeout(t *testing.T) {
 				MockDomain: func() string {
 					return "dns.google.com"
 				},
+				MockBytes: func() ([]byte, error) {
+					return []byte{}, nil
+				},
 			}
 			addrs := []string{"1.1.1.1"}
 			finished := trace.TimeNow()
@@ -455,6 +511,9 @@ func TestDelayedDNSResponseWithTimeout(t *testing.T) {
 				MockDomain: func() string {
 					return "dns.google.com"
 				},
+				MockBytes: func() ([]byte, error) {
+					return []byte{}, nil
+				},
 			}
 			addrs := []string{"1.1.1.1"}
 			finished := trace.TimeNow()
@@ -504,6 +563,9 @@ func TestDelayedDNSResponseWithTimeout(t *testing.T) {
 				MockDomain: func() string {
 					return "dns.google.com"
 				},
+				MockBytes: func() ([]byte, error) {
+					return []byte{}, nil
+				},
 			}
 			addrs := []string{"1.1.1.1"}
 			finished := trace.TimeNow()
@@ -554,6 +616,9 @@ func TestDelayedDNSResponseWithTimeout(t *testing.T) {
 				MockDomain: func() string {
 					return "dns.google.com"
 				},
+				MockBytes: func() ([]byte, error) {
+					return []byte{}, nil
+				},
 			}
 			addrs := []string{"1.1.1.1"}
 			finished := trace.TimeNow()
@@ -725,10 +790,286 @@ func TestNewArchivalDNSAnswers(t *testing.T) {
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := newArchivalDNSAnswers(tt.addrs, tt.resp)
+			got := newArchivalDNSAnswers(dns.TypeA, tt.addrs, tt.resp)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestNewArchivalDNSAnswersFromRecords(t *testing.T) {
+	ttl := func(v uint32) *uint32 {
+		return &v
+	}
+	tests := []struct {
+		name     string
+		resp     model.DNSResponse
+		expected []model.ArchivalDNSAnswer
+	}{{
+		name:     "with nil response",
+		resp:     nil,
+		expected: nil,
+	}, {
+		name: "with DecodeRecords error",
+		resp: &mocks.DNSResponse{
+			MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+				return nil, errors.New("mocked error")
+			},
+		},
+		expected: nil,
+	}, {
+		name: "with CNAME chain and TXT record",
+		resp: &mocks.DNSResponse{
+			MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+				return []*model.DNSRecord{{
+					Name:   "www.example.com.",
+					Type:   dns.TypeCNAME,
+					TTL:    300,
+					Target: "example.com.",
+					Value:  "example.com.",
+				}, {
+					Name:  "example.com.",
+					Type:  dns.TypeTXT,
+					TTL:   60,
+					Value: `"v=spf1 -all"`,
+				}}, nil
+			},
+		},
+		expected: []model.ArchivalDNSAnswer{{
+			AnswerType: "CNAME",
+			Hostname:   "example.com.",
+			TTL:        ttl(300),
+			Value:      "example.com.",
+		}, {
+			AnswerType: "TXT",
+			TTL:        ttl(60),
+			Value:      `"v=spf1 -all"`,
+		}},
+	}, {
+		name: "with A and AAAA records",
+		resp: &mocks.DNSResponse{
+			MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+				return []*model.DNSRecord{{
+					Name:  "dns.google.",
+					Type:  dns.TypeA,
+					TTL:   10,
+					Value: "8.8.8.8",
+				}, {
+					Name:  "dns.google.",
+					Type:  dns.TypeAAAA,
+					TTL:   20,
+					Value: "2001:4860:4860::8844",
+				}}, nil
+			},
+		},
+		expected: []model.ArchivalDNSAnswer{{
+			ASN:        15169,
+			ASOrgName:  "Google LLC",
+			AnswerType: "A",
+			IPv4:       "8.8.8.8",
+			TTL:        ttl(10),
+		}, {
+			ASN:        15169,
+			ASOrgName:  "Google LLC",
+			AnswerType: "AAAA",
+			IPv6:       "2001:4860:4860::8844",
+			TTL:        ttl(20),
+		}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newArchivalDNSAnswers(dns.TypeTXT, nil, tt.resp)
 			if diff := cmp.Diff(tt.expected, got); diff != "" {
 				t.Fatal(diff)
 			}
 		})
 	}
 }
+
+func TestNewArchivalDNSAnswersForGetaddrinfo(t *testing.T) {
+	// getaddrinfo lookups use ANY as the query type and do not have a response
+	got := newArchivalDNSAnswers(dns.TypeANY, []string{"10.0.0.1", "fd00::1"}, nil)
+	expected := []model.ArchivalDNSAnswer{{
+		AnswerType: "A",
+		IPv4:       "10.0.0.1",
+	}, {
+		AnswerType: "AAAA",
+		IPv6:       "fd00::1",
+	}}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+// dnssecValidatedResponse is a model.DNSSECValidatedResponse for testing.
+type dnssecValidatedResponse struct {
+	*mocks.DNSResponse
+	validation *model.DNSSECValidation
+}
+
+func (r *dnssecValidatedResponse) DNSSECValidation() *model.DNSSECValidation {
+	return r.validation
+}
+
+func TestMaybeDNSSECValidation(t *testing.T) {
+	t.Run("with a response that was not validated", func(t *testing.T) {
+		status, failure := maybeDNSSECValidation(&mocks.DNSResponse{})
+		if status != "" || failure != nil {
+			t.Fatal("expected empty results")
+		}
+	})
+
+	t.Run("with a nil validation", func(t *testing.T) {
+		status, failure := maybeDNSSECValidation(&dnssecValidatedResponse{})
+		if status != "" || failure != nil {
+			t.Fatal("expected empty results")
+		}
+	})
+
+	t.Run("with a secure response", func(t *testing.T) {
+		resp := &dnssecValidatedResponse{
+			validation: &model.DNSSECValidation{
+				Status:  netxlite.DNSSECStatusSecure,
+				Failure: nil,
+			},
+		}
+		status, failure := maybeDNSSECValidation(resp)
+		if status != netxlite.DNSSECStatusSecure || failure != nil {
+			t.Fatal("unexpected results", status, failure)
+		}
+	})
+
+	t.Run("with a bogus response", func(t *testing.T) {
+		resp := &dnssecValidatedResponse{
+			validation: &model.DNSSECValidation{
+				Status:  netxlite.DNSSECStatusBogus,
+				Failure: netxlite.ErrDNSSECInvalidSignature,
+			},
+		}
+		status, failure := maybeDNSSECValidation(resp)
+		if status != netxlite.DNSSECStatusBogus {
+			t.Fatal("unexpected status", status)
+		}
+		if failure == nil || *failure != "unknown_failure: dnssec: invalid signature" {
+			t.Fatal("unexpected failure", failure)
+		}
+	})
+}
+
+func TestNewArchivalEDNS0(t *testing.T) {
+	t.Run("with an invalid message", func(t *testing.T) {
+		if out := NewArchivalEDNS0([]byte{0x00}); out != nil {
+			t.Fatal("expected nil output")
+		}
+	})
+
+	t.Run("with a message without OPT record", func(t *testing.T) {
+		query := &dns.Msg{}
+		query.SetQuestion("example.com.", dns.TypeA)
+		rawQuery, err := query.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := NewArchivalEDNS0(rawQuery); out != nil {
+			t.Fatal("expected nil output")
+		}
+	})
+
+	t.Run("with a message containing options", func(t *testing.T) {
+		ecs, err := netxlite.NewDNSEDNS0ClientSubnet("203.0.113.0/24")
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoder := &netxlite.DNSEncoderMiekg{
+			EDNS0: []dns.EDNS0{ecs, &dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0xca, 0xfe}}},
+		}
+		rawQuery, err := encoder.Encode("example.com", dns.TypeA, true).Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []model.ArchivalEDNS0{{
+			Code:  dns.EDNS0SUBNET,
+			Name:  "ECS",
+			Value: "203.0.113.0/24/0",
+		}, {
+			Code:  65001,
+			Name:  "",
+			Value: "cafe",
+		}, {
+			Code:  dns.EDNS0PADDING,
+			Name:  "PADDING",
+			Value: "",
+		}}
+		if diff := cmp.Diff(expect, NewArchivalEDNS0(rawQuery)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestAttachDNSDuplicateResponses(t *testing.T) {
+	// newLookup is a helper to create a DNS lookup result
+	newLookup := func(id int64, qtype string, addrs ...string) *model.ArchivalDNSLookupResult {
+		var answers []model.ArchivalDNSAnswer
+		for _, addr := range addrs {
+			answers = append(answers, model.ArchivalDNSAnswer{AnswerType: "A", IPv4: addr})
+		}
+		return &model.ArchivalDNSLookupResult{
+			Answers:         answers,
+			Engine:          "udp",
+			QueryType:       qtype,
+			ResolverAddress: "8.8.8.8:53",
+			TransactionID:   id,
+		}
+	}
+
+	t.Run("without duplicates", func(t *testing.T) {
+		queries := []*model.ArchivalDNSLookupResult{newLookup(1, "A", "93.184.216.34")}
+		unattached := AttachDNSDuplicateResponses(queries)
+		if unattached == nil || len(unattached) != 0 {
+			t.Fatal("expected an empty, non-nil list")
+		}
+		if queries[0].DuplicateResponses != nil {
+			t.Fatal("expected no duplicate responses")
+		}
+		if DNSLookupResultHasInjection(queries[0]) {
+			t.Fatal("expected no injection")
+		}
+	})
+
+	t.Run("with duplicates containing the same addresses in different order", func(t *testing.T) {
+		queries := []*model.ArchivalDNSLookupResult{newLookup(1, "A", "93.184.216.34", "93.184.216.35")}
+		dup := newLookup(1, "A", "93.184.216.35", "93.184.216.34")
+		_ = AttachDNSDuplicateResponses(queries, dup)
+		if diff := cmp.Diff([]*model.ArchivalDNSLookupResult{dup}, queries[0].DuplicateResponses); diff != "" {
+			t.Fatal(diff)
+		}
+		if DNSLookupResultHasInjection(queries[0]) {
+			t.Fatal("expected no injection")
+		}
+	})
+
+	t.Run("with duplicates containing different addresses", func(t *testing.T) {
+		queries := []*model.ArchivalDNSLookupResult{newLookup(1, "A", "10.10.34.35"), newLookup(2, "A", "8.8.8.8")}
+		dup := newLookup(1, "A", "93.184.216.34")
+		_ = AttachDNSDuplicateResponses(queries, dup)
+		if !DNSLookupResultHasInjection(queries[0]) {
+			t.Fatal("expected injection")
+		}
+		if DNSLookupResultHasInjection(queries[1]) {
+			t.Fatal("expected no injection")
+		}
+	})
+
+	t.Run("with duplicates for a query we don't know about", func(t *testing.T) {
+		queries := []*model.ArchivalDNSLookupResult{newLookup(1, "A", "10.10.34.35")}
+		dups := []*model.ArchivalDNSLookupResult{newLookup(1, "AAAA"), newLookup(2, "A")}
+		unattached := AttachDNSDuplicateResponses(queries, dups...)
+		if diff := cmp.Diff(dups, unattached); diff != "" {
+			t.Fatal(diff)
+		}
+		if queries[0].DuplicateResponses != nil {
+			t.Fatal("expected no duplicate responses")
+		}
+	})
+}
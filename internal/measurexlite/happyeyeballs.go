@@ -0,0 +1,71 @@
+package measurexlite
+
+//
+// Happy eyeballs dialer tracing
+//
+
+import (
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// NewDialerHappyEyeballs is equivalent to [netxlite.Netx.NewDialerHappyEyeballs]
+// except that it returns a model.Dialer that uses this trace.
+//
+// Each connect attempt is saved as a TCP connect result and a network event, and
+// the overall result is saved as an happy eyeballs result (see [Trace.HappyEyeballs]).
+func (tx *Trace) NewDialerHappyEyeballs(dl model.DebugLogger, r model.Resolver) model.Dialer {
+	return &dialerTrace{
+		d:  tx.Netx.NewDialerHappyEyeballs(dl, r),
+		tx: tx,
+	}
+}
+
+// OnHappyEyeballsDone implements model.Trace.OnHappyEyeballsDone.
+func (tx *Trace) OnHappyEyeballsDone(
+	started time.Time, network, domain, winner string, err error, finished time.Time) {
+	select {
+	case tx.happyEyeballs <- NewArchivalHappyEyeballsResult(
+		tx.Index(),
+		started.Sub(tx.ZeroTime()),
+		network,
+		domain,
+		winner,
+		err,
+		finished.Sub(tx.ZeroTime()),
+		tx.tags...,
+	):
+	default: // buffer is full
+	}
+}
+
+// NewArchivalHappyEyeballsResult generates a model.ArchivalHappyEyeballsResult
+// from the available information right after the happy eyeballs race is over.
+func NewArchivalHappyEyeballsResult(index int64, started time.Duration, network, domain,
+	winner string, err error, finished time.Duration, tags ...string) *model.ArchivalHappyEyeballsResult {
+	return &model.ArchivalHappyEyeballsResult{
+		Domain:        domain,
+		Network:       network,
+		Failure:       NewFailure(err),
+		Winner:        winner,
+		WinnerFamily:  netxlite.HappyEyeballsWinnerFamily(winner),
+		T0:            started.Seconds(),
+		T:             finished.Seconds(),
+		Tags:          copyAndNormalizeTags(tags),
+		TransactionID: index,
+	}
+}
+
+// HappyEyeballs drains the happy eyeballs results buffered inside the HappyEyeballs channel.
+func (tx *Trace) HappyEyeballs() (out []*model.ArchivalHappyEyeballsResult) {
+	for {
+		select {
+		case ev := <-tx.happyEyeballs:
+			out = append(out, ev)
+		default:
+			return // done
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package measurexlite
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestNewDialerHappyEyeballs(t *testing.T) {
+	t.Run("NewDialerHappyEyeballs creates a wrapped dialer", func(t *testing.T) {
+		underlying := &mocks.Dialer{}
+		reso := &mocks.Resolver{}
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		trace.Netx = &mocks.MeasuringNetwork{
+			MockNewDialerHappyEyeballs: func(dl model.DebugLogger, r model.Resolver) model.Dialer {
+				if r != reso {
+					panic("unexpected resolver")
+				}
+				return underlying
+			},
+		}
+		dialer := trace.NewDialerHappyEyeballs(model.DiscardLogger, reso)
+		dt := dialer.(*dialerTrace)
+		if dt.d != underlying {
+			t.Fatal("invalid dialer")
+		}
+		if dt.tx != trace {
+			t.Fatal("invalid trace")
+		}
+	})
+
+	t.Run("we trace each attempt and the winner", func(t *testing.T) {
+		// create a listener for accepting IPv4 connections
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+		_, port, err := net.SplitHostPort(listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// use a network where IPv6 is unreachable
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		trace.Netx = &netxlite.Netx{Underlying: &mocks.UnderlyingNetwork{
+			MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				if address == net.JoinHostPort("::1", port) {
+					return nil, errors.New("connect: network is unreachable")
+				}
+				return (&net.Dialer{}).DialContext(ctx, network, address)
+			},
+			MockDialTimeout: func() time.Duration {
+				return 10 * time.Second
+			},
+		}}
+		reso := &mocks.Resolver{
+			MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"127.0.0.1", "::1"}, nil
+			},
+		}
+
+		dialer := trace.NewDialerHappyEyeballs(model.DiscardLogger, reso)
+		conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("example.com", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+
+		if tcpConnects := trace.TCPConnects(); len(tcpConnects) != 2 {
+			t.Fatal("expected two TCP connect attempts, got", len(tcpConnects))
+		}
+		results := trace.HappyEyeballs()
+		if len(results) != 1 {
+			t.Fatal("expected a single result")
+		}
+		if results[0].Winner != net.JoinHostPort("127.0.0.1", port) {
+			t.Fatal("unexpected winner", results[0].Winner)
+		}
+		if results[0].WinnerFamily != "ipv4" {
+			t.Fatal("unexpected winner family", results[0].WinnerFamily)
+		}
+	})
+}
+
+func TestOnHappyEyeballsDone(t *testing.T) {
+	t.Run("when the buffer is not full", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(11, zeroTime, "antani")
+		started := zeroTime.Add(time.Second)
+		finished := started.Add(time.Second)
+		trace.OnHappyEyeballsDone(started, "tcp", "dns.google", "[2001:4860:4860::8888]:443", nil, finished)
+		expect := []*model.ArchivalHappyEyeballsResult{{
+			Domain:        "dns.google",
+			Network:       "tcp",
+			Failure:       nil,
+			Winner:        "[2001:4860:4860::8888]:443",
+			WinnerFamily:  "ipv6",
+			T0:            1,
+			T:             2,
+			Tags:          []string{"antani"},
+			TransactionID: 11,
+		}}
+		if diff := cmp.Diff(expect, trace.HappyEyeballs()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the buffer is full", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		err := netxlite.NewTopLevelGenericErrWrapper(netxlite.ECONNREFUSED)
+		for idx := 0; idx < 2*HappyEyeballsBufferSize; idx++ {
+			trace.OnHappyEyeballsDone(zeroTime, "tcp", "dns.google", "", err, zeroTime)
+		}
+		results := trace.HappyEyeballs()
+		if len(results) != HappyEyeballsBufferSize {
+			t.Fatal("unexpected number of results", len(results))
+		}
+		if results[0].Failure == nil || *results[0].Failure != netxlite.FailureConnectionRefused {
+			t.Fatal("unexpected failure")
+		}
+		if results[0].WinnerFamily != "" {
+			t.Fatal("unexpected winner family")
+		}
+	})
+}
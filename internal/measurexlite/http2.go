@@ -0,0 +1,41 @@
+package measurexlite
+
+//
+// HTTP/2 frames tracing
+//
+
+import (
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// OnHTTP2Frame implements model.Trace.OnHTTP2Frame.
+func (tx *Trace) OnHTTP2Frame(now time.Time, operation, network, remoteAddr string, frame *model.HTTP2Frame) {
+	if !tx.HTTP2FrameEvents {
+		return
+	}
+	t := now.Sub(tx.zeroTime)
+	select {
+	case tx.networkEvent <- NewArchivalHTTP2FrameNetworkEvent(
+		tx.index, t, operation, network, remoteAddr, frame, tx.tags...):
+	default: // buffer is full
+	}
+}
+
+// NewArchivalHTTP2FrameNetworkEvent creates a new model.ArchivalNetworkEvent
+// describing an HTTP/2 frame, where NumBytes is the frame payload length.
+func NewArchivalHTTP2FrameNetworkEvent(index int64, time time.Duration, operation, network,
+	address string, frame *model.HTTP2Frame, tags ...string) *model.ArchivalNetworkEvent {
+	ev := NewArchivalNetworkEvent(index, time, operation, network, address,
+		int(frame.Length), nil, time, tags...)
+	ev.HTTP2Frame = &model.ArchivalHTTP2Frame{
+		Type:         frame.Type,
+		Flags:        frame.Flags,
+		Length:       frame.Length,
+		StreamID:     frame.StreamID,
+		ErrCode:      frame.ErrCode,
+		LastStreamID: frame.LastStreamID,
+	}
+	return ev
+}
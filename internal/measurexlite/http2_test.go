@@ -0,0 +1,106 @@
+package measurexlite
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestOnHTTP2Frame(t *testing.T) {
+	frame := &model.HTTP2Frame{
+		Type:     "RST_STREAM",
+		Flags:    0,
+		Length:   4,
+		StreamID: 1,
+		ErrCode:  "PROTOCOL_ERROR",
+	}
+
+	t.Run("we do not collect frames by default", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		trace.OnHTTP2Frame(zeroTime.Add(time.Second), netxlite.HTTP2ReadFrameOperation,
+			"tcp", "1.1.1.1:443", frame)
+		if events := trace.NetworkEvents(); len(events) != 0 {
+			t.Fatal("expected no events, got", events)
+		}
+	})
+
+	t.Run("we collect frames when enabled", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(17, zeroTime, "antani")
+		trace.HTTP2FrameEvents = true
+		trace.OnHTTP2Frame(zeroTime.Add(time.Second), netxlite.HTTP2ReadFrameOperation,
+			"tcp", "1.1.1.1:443", frame)
+		expect := []*model.ArchivalNetworkEvent{{
+			Address:       "1.1.1.1:443",
+			Failure:       nil,
+			NumBytes:      4,
+			Operation:     netxlite.HTTP2ReadFrameOperation,
+			Proto:         "tcp",
+			T0:            1,
+			T:             1,
+			TransactionID: 17,
+			Tags:          []string{"antani"},
+			HTTP2Frame: &model.ArchivalHTTP2Frame{
+				Type:     "RST_STREAM",
+				Flags:    0,
+				Length:   4,
+				StreamID: 1,
+				ErrCode:  "PROTOCOL_ERROR",
+			},
+		}}
+		if diff := cmp.Diff(expect, trace.NetworkEvents()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we collect frames using netxlite.NewHTTP2Transport", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		defer srv.Close()
+		pool := x509.NewCertPool()
+		pool.AddCert(srv.Certificate())
+
+		trace := NewTrace(0, time.Now())
+		trace.HTTP2FrameEvents = true
+		tlsDialer := netxlite.NewTLSDialerWithConfig(
+			trace.NewDialerWithoutResolver(model.DiscardLogger),
+			trace.NewTLSHandshakerStdlib(model.DiscardLogger),
+			&tls.Config{NextProtos: []string{"h2"}, RootCAs: pool},
+		)
+		txp := netxlite.NewHTTP2Transport(model.DiscardLogger, tlsDialer)
+		defer txp.CloseIdleConnections()
+
+		ctx := netxlite.ContextWithTrace(context.Background(), trace)
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := txp.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		var found bool
+		for _, ev := range trace.NetworkEvents() {
+			if ev.Operation == netxlite.HTTP2ReadFrameOperation && ev.HTTP2Frame.Type == "HEADERS" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatal("did not find the HEADERS frame we expected")
+		}
+	})
+}
@@ -6,14 +6,17 @@ package measurexlite
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"net"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"golang.org/x/crypto/cryptobyte"
 )
 
 // NewTLSHandshakerStdlib is equivalent to netxlite.Netx.NewTLSHandshakerStdlib
@@ -27,6 +30,9 @@ func (tx *Trace) NewTLSHandshakerStdlib(dl model.DebugLogger) model.TLSHandshake
 
 // tlsHandshakerTrace is a trace-aware TLS handshaker.
 type tlsHandshakerTrace struct {
+	// fingerprint is the OPTIONAL name of the uTLS fingerprint in use.
+	fingerprint string
+
 	thx model.TLSHandshaker
 	tx  *Trace
 }
@@ -36,7 +42,11 @@ var _ model.TLSHandshaker = &tlsHandshakerTrace{}
 // Handshake implements model.TLSHandshaker.Handshake.
 func (thx *tlsHandshakerTrace) Handshake(
 	ctx context.Context, conn net.Conn, tlsConfig *tls.Config) (model.TLSConn, error) {
-	return thx.thx.Handshake(netxlite.ContextWithTrace(ctx, thx.tx), conn, tlsConfig)
+	var trace model.Trace = thx.tx
+	if thx.fingerprint != "" {
+		trace = &tlsFingerprintTrace{Trace: thx.tx, fingerprint: thx.fingerprint}
+	}
+	return thx.thx.Handshake(netxlite.ContextWithTrace(ctx, trace), conn, tlsConfig)
 }
 
 // OnTLSHandshakeStart implements model.Trace.OnTLSHandshakeStart.
@@ -61,10 +71,16 @@ func (tx *Trace) OnTLSHandshakeStart(now time.Time, remoteAddr string, config *t
 // OnTLSHandshakeDone implements model.Trace.OnTLSHandshakeDone.
 func (tx *Trace) OnTLSHandshakeDone(started time.Time, remoteAddr string, config *tls.Config,
 	state tls.ConnectionState, err error, finished time.Time) {
+	tx.onTLSHandshakeDone(started, remoteAddr, config, state, err, finished, "")
+}
+
+// onTLSHandshakeDone implements OnTLSHandshakeDone and also records the
+// name of the uTLS fingerprint we're using, if not empty.
+func (tx *Trace) onTLSHandshakeDone(started time.Time, remoteAddr string, config *tls.Config,
+	state tls.ConnectionState, err error, finished time.Time, fingerprint string) {
 	t := finished.Sub(tx.ZeroTime())
 
-	select {
-	case tx.tlsHandshake <- NewArchivalTLSOrQUICHandshakeResult(
+	result := NewArchivalTLSOrQUICHandshakeResult(
 		tx.Index(),
 		started.Sub(tx.ZeroTime()),
 		"tcp",
@@ -74,7 +90,11 @@ func (tx *Trace) OnTLSHandshakeDone(started time.Time, remoteAddr string, config
 		err,
 		t,
 		tx.tags...,
-	):
+	)
+	result.Fingerprint = fingerprint
+
+	select {
+	case tx.tlsHandshake <- result:
 	default: // buffer is full
 	}
 
@@ -100,7 +120,7 @@ func NewArchivalTLSOrQUICHandshakeResult(
 	index int64, started time.Duration, network string, address string, config *tls.Config,
 	state tls.ConnectionState, err error, finished time.Duration,
 	tags ...string) *model.ArchivalTLSOrQUICHandshakeResult {
-	return &model.ArchivalTLSOrQUICHandshakeResult{
+	result := &model.ArchivalTLSOrQUICHandshakeResult{
 		Network:            network,
 		Address:            address,
 		CipherSuite:        netxlite.TLSCipherSuiteString(state.CipherSuite),
@@ -115,6 +135,94 @@ func NewArchivalTLSOrQUICHandshakeResult(
 		TLSVersion:         netxlite.TLSVersionString(state.Version),
 		TransactionID:      index,
 	}
+	result.LeafSPKISHA256 = TLSLeafSPKISHA256(result.PeerCertificates)
+	maybeArchivalTLSECH(result, config, state, err)
+	return result
+}
+
+// These constants define the possible values of the ECHStatus
+// field of the [model.ArchivalTLSOrQUICHandshakeResult].
+const (
+	// TLSECHStatusAccepted indicates that the server accepted ECH.
+	TLSECHStatusAccepted = "accepted"
+
+	// TLSECHStatusRejected indicates that the server rejected ECH.
+	TLSECHStatusRejected = "rejected"
+)
+
+// maybeArchivalTLSECH fills the ECH-related fields of the given result when the
+// config contains an ECHConfigList. The status remains empty when the handshake
+// failed for reasons unrelated to ECH, since we cannot know what the server
+// would have done with the encrypted client hello.
+func maybeArchivalTLSECH(result *model.ArchivalTLSOrQUICHandshakeResult,
+	config *tls.Config, state tls.ConnectionState, err error) {
+	if len(config.EncryptedClientHelloConfigList) <= 0 {
+		return
+	}
+	result.ECHConfig = base64.StdEncoding.EncodeToString(config.EncryptedClientHelloConfigList)
+	result.OuterServerName = tlsECHPublicName(config.EncryptedClientHelloConfigList)
+
+	var echErr *tls.ECHRejectionError
+	switch {
+	case state.ECHAccepted:
+		result.ECHStatus = TLSECHStatusAccepted
+	case errors.As(err, &echErr):
+		result.ECHStatus = TLSECHStatusRejected
+		if len(echErr.RetryConfigList) > 0 {
+			result.ECHRetryConfigs = base64.StdEncoding.EncodeToString(echErr.RetryConfigList)
+		}
+	case err == nil:
+		result.ECHStatus = TLSECHStatusRejected
+	}
+}
+
+// tlsECHPublicName returns the public name used by the given ECHConfigList for
+// the outer ClientHello. Because we cannot know which config the TLS library
+// is going to select, we return an empty string when the list is malformed or
+// when the supported configs do not all share the same public name.
+func tlsECHPublicName(configList []byte) string {
+	const echVersion = 0xfe0d // draft-ietf-tls-esni-22
+	var (
+		list       cryptobyte.String
+		publicName string
+	)
+	input := cryptobyte.String(configList)
+	if !input.ReadUint16LengthPrefixed(&list) || !input.Empty() {
+		return ""
+	}
+	for !list.Empty() {
+		var (
+			version  uint16
+			contents cryptobyte.String
+		)
+		if !list.ReadUint16(&version) || !list.ReadUint16LengthPrefixed(&contents) {
+			return ""
+		}
+		if version != echVersion {
+			continue // the TLS library skips unknown versions
+		}
+		var (
+			configID      uint8
+			kemID         uint16
+			publicKey     cryptobyte.String
+			cipherSuites  cryptobyte.String
+			maxNameLength uint8
+			name          cryptobyte.String
+		)
+		if !contents.ReadUint8(&configID) ||
+			!contents.ReadUint16(&kemID) ||
+			!contents.ReadUint16LengthPrefixed(&publicKey) ||
+			!contents.ReadUint16LengthPrefixed(&cipherSuites) ||
+			!contents.ReadUint8(&maxNameLength) ||
+			!contents.ReadUint8LengthPrefixed(&name) {
+			return ""
+		}
+		if publicName != "" && publicName != string(name) {
+			return ""
+		}
+		publicName = string(name)
+	}
+	return publicName
 }
 
 // TLSPeerCerts extracts the certificates either from the list of certificates
@@ -151,6 +259,23 @@ func TLSPeerCerts(
 	return
 }
 
+// TLSLeafSPKISHA256 returns the base64-encoded SHA-256 hash of the SubjectPublicKeyInfo of the
+// leaf certificate, which is the first certificate in the given chain, using the same format used
+// for public key pinning (see RFC 7469). We hash the public key rather than the whole certificate
+// because a server's public key typically survives certificate renewals. This function returns
+// an empty string if the chain is empty or we cannot parse the leaf certificate.
+func TLSLeafSPKISHA256(certs []model.ArchivalBinaryData) string {
+	if len(certs) <= 0 {
+		return ""
+	}
+	leaf, err := x509.ParseCertificate(certs[0])
+	if err != nil {
+		return ""
+	}
+	digest := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
 // TLSHandshakes drains the network events buffered inside the TLSHandshake channel.
 func (tx *Trace) TLSHandshakes() (out []*model.ArchivalTLSOrQUICHandshakeResult) {
 	for {
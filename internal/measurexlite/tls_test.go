@@ -3,9 +3,15 @@ package measurexlite
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
+	"math/big"
 	"net"
 	"testing"
 	"time"
@@ -16,6 +22,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/testingx"
+	"golang.org/x/crypto/cryptobyte"
 )
 
 func TestNewTLSHandshakerStdlib(t *testing.T) {
@@ -294,7 +301,11 @@ func TestNewTLSHandshakerStdlib(t *testing.T) {
 			if len(got.PeerCertificates) != 2 {
 				t.Fatal("expected to see two certificates")
 			}
+			if got.LeafSPKISHA256 != TLSLeafSPKISHA256(got.PeerCertificates) {
+				t.Fatal("unexpected leaf SPKI hash")
+			}
 			got.PeerCertificates = []model.ArchivalBinaryData{} // see above
+			got.LeafSPKISHA256 = ""                             // ditto
 			if diff := cmp.Diff(expected, got); diff != "" {
 				t.Fatal(diff)
 			}
@@ -467,3 +478,255 @@ func TestTLSPeerCerts(t *testing.T) {
 		})
 	}
 }
+
+func TestTLSLeafSPKISHA256(t *testing.T) {
+	// newCertificate returns a new self-signed certificate.
+	newCertificate := func() *x509.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+			DNSNames:     []string{"www.example.com"},
+		}
+		raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	t.Run("with an empty chain", func(t *testing.T) {
+		if got := TLSLeafSPKISHA256(nil); got != "" {
+			t.Fatal("expected empty string, got", got)
+		}
+	})
+
+	t.Run("with an invalid leaf certificate", func(t *testing.T) {
+		certs := []model.ArchivalBinaryData{model.ArchivalBinaryData("deadbeef")}
+		if got := TLSLeafSPKISHA256(certs); got != "" {
+			t.Fatal("expected empty string, got", got)
+		}
+	})
+
+	t.Run("with a valid chain", func(t *testing.T) {
+		leaf, other := newCertificate(), newCertificate()
+		certs := []model.ArchivalBinaryData{leaf.Raw, other.Raw}
+		digest := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+		expect := base64.StdEncoding.EncodeToString(digest[:])
+		if got := TLSLeafSPKISHA256(certs); got != expect {
+			t.Fatal("expected", expect, "got", got)
+		}
+	})
+
+	t.Run("NewArchivalTLSOrQUICHandshakeResult uses the leaf certificate", func(t *testing.T) {
+		leaf := newCertificate()
+		state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+		result := NewArchivalTLSOrQUICHandshakeResult(
+			0, 0, "tcp", "93.184.216.34:443", &tls.Config{}, state, nil, time.Second)
+		if result.LeafSPKISHA256 == "" {
+			t.Fatal("expected a non-empty hash")
+		}
+		if result.LeafSPKISHA256 != TLSLeafSPKISHA256(result.PeerCertificates) {
+			t.Fatal("unexpected hash")
+		}
+	})
+}
+
+func TestNewArchivalTLSOrQUICHandshakeResultWithECH(t *testing.T) {
+	key := testingx.MustNewECHKey(1, "public.example.com")
+	retryKey := testingx.MustNewECHKey(2, "public.example.com")
+	config := &tls.Config{
+		EncryptedClientHelloConfigList: key.ConfigList,
+		ServerName:                     "www.example.com",
+	}
+	encodedConfig := base64.StdEncoding.EncodeToString(key.ConfigList)
+
+	t.Run("without ECH we do not set any ECH field", func(t *testing.T) {
+		result := NewArchivalTLSOrQUICHandshakeResult(
+			0, 0, "tcp", "1.1.1.1:443", &tls.Config{ServerName: "www.example.com"},
+			tls.ConnectionState{}, nil, time.Second,
+		)
+		if result.ECHConfig != "" || result.ECHStatus != "" || result.ECHRetryConfigs != "" || result.OuterServerName != "" {
+			t.Fatal("expected empty ECH fields", result)
+		}
+	})
+
+	t.Run("when the server accepts ECH", func(t *testing.T) {
+		state := tls.ConnectionState{ECHAccepted: true}
+		result := NewArchivalTLSOrQUICHandshakeResult(0, 0, "tcp", "1.1.1.1:443", config, state, nil, time.Second)
+		if result.ECHConfig != encodedConfig {
+			t.Fatal("unexpected ECHConfig", result.ECHConfig)
+		}
+		if result.ECHStatus != TLSECHStatusAccepted {
+			t.Fatal("unexpected ECHStatus", result.ECHStatus)
+		}
+		if result.ECHRetryConfigs != "" {
+			t.Fatal("unexpected ECHRetryConfigs", result.ECHRetryConfigs)
+		}
+		if result.OuterServerName != "public.example.com" {
+			t.Fatal("unexpected OuterServerName", result.OuterServerName)
+		}
+	})
+
+	t.Run("when the server rejects ECH providing retry configs", func(t *testing.T) {
+		err := &netxlite.ErrWrapper{
+			Failure:    netxlite.FailureSSLECHRejected,
+			Operation:  netxlite.TLSHandshakeOperation,
+			WrappedErr: &tls.ECHRejectionError{RetryConfigList: retryKey.ConfigList},
+		}
+		result := NewArchivalTLSOrQUICHandshakeResult(0, 0, "tcp", "1.1.1.1:443", config, tls.ConnectionState{}, err, time.Second)
+		if result.Failure == nil || *result.Failure != netxlite.FailureSSLECHRejected {
+			t.Fatal("unexpected Failure", result.Failure)
+		}
+		if result.ECHStatus != TLSECHStatusRejected {
+			t.Fatal("unexpected ECHStatus", result.ECHStatus)
+		}
+		if result.ECHRetryConfigs != base64.StdEncoding.EncodeToString(retryKey.ConfigList) {
+			t.Fatal("unexpected ECHRetryConfigs", result.ECHRetryConfigs)
+		}
+	})
+
+	t.Run("when the handshake fails for other reasons", func(t *testing.T) {
+		err := errors.New("mocked error")
+		result := NewArchivalTLSOrQUICHandshakeResult(0, 0, "tcp", "1.1.1.1:443", config, tls.ConnectionState{}, err, time.Second)
+		if result.ECHConfig != encodedConfig {
+			t.Fatal("unexpected ECHConfig", result.ECHConfig)
+		}
+		if result.ECHStatus != "" {
+			t.Fatal("unexpected ECHStatus", result.ECHStatus)
+		}
+	})
+
+	t.Run("we collect the desired data with a local TLS server", func(t *testing.T) {
+		ca := netem.MustNewCA()
+		serverConfig := ca.MustNewServerTLSConfig("www.example.com", "public.example.com")
+		serverConfig.EncryptedClientHelloKeys = []tls.EncryptedClientHelloKey{key.ServerKey}
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_ = conn.(*tls.Conn).Handshake()
+		}()
+
+		netx := &netxlite.Netx{}
+		dialer := netx.NewDialerWithoutResolver(model.DiscardLogger)
+		ctx := context.Background()
+		conn, err := dialer.DialContext(ctx, "tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		trace := NewTrace(0, time.Now())
+		thx := trace.NewTLSHandshakerStdlib(model.DiscardLogger)
+		tlsConfig := config.Clone()
+		tlsConfig.RootCAs = ca.DefaultCertPool()
+		tlsConn, err := thx.Handshake(ctx, conn, tlsConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tlsConn.Close()
+
+		events := trace.TLSHandshakes()
+		if len(events) != 1 {
+			t.Fatal("expected to see a single TLSHandshake event")
+		}
+		if events[0].ECHStatus != TLSECHStatusAccepted {
+			t.Fatal("unexpected ECHStatus", events[0].ECHStatus)
+		}
+	})
+}
+
+func TestTLSECHPublicName(t *testing.T) {
+	// newConfig creates a serialized ECHConfig with the given version and public name
+	newConfig := func(version uint16, publicName string) []byte {
+		builder := cryptobyte.NewBuilder(nil)
+		builder.AddUint16(version)
+		builder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(1)
+			b.AddUint16(0x0020)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(make([]byte, 32))
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(0x0001)
+				b.AddUint16(0x0001)
+			})
+			b.AddUint8(0)
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte(publicName))
+			})
+			b.AddUint16(0)
+		})
+		return builder.BytesOrPanic()
+	}
+
+	// newList creates a serialized ECHConfigList containing the given configs
+	newList := func(configs ...[]byte) []byte {
+		builder := cryptobyte.NewBuilder(nil)
+		builder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, config := range configs {
+				b.AddBytes(config)
+			}
+		})
+		return builder.BytesOrPanic()
+	}
+
+	type testcase struct {
+		name       string
+		configList []byte
+		expect     string
+	}
+
+	cases := []testcase{{
+		name:       "with an empty input",
+		configList: nil,
+		expect:     "",
+	}, {
+		name:       "with trailing garbage",
+		configList: append(newList(newConfig(0xfe0d, "a.example.com")), 0),
+		expect:     "",
+	}, {
+		name:       "with a truncated config",
+		configList: newList(newConfig(0xfe0d, "a.example.com")[:10]),
+		expect:     "",
+	}, {
+		name:       "with a single config",
+		configList: newList(newConfig(0xfe0d, "a.example.com")),
+		expect:     "a.example.com",
+	}, {
+		name:       "with configs sharing the same public name",
+		configList: newList(newConfig(0xfe0d, "a.example.com"), newConfig(0xfe0d, "a.example.com")),
+		expect:     "a.example.com",
+	}, {
+		name:       "with configs using distinct public names",
+		configList: newList(newConfig(0xfe0d, "a.example.com"), newConfig(0xfe0d, "b.example.com")),
+		expect:     "",
+	}, {
+		name:       "with unsupported config versions",
+		configList: newList(newConfig(0xfe0a, "b.example.com"), newConfig(0xfe0d, "a.example.com")),
+		expect:     "a.example.com",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tlsECHPublicName(tc.configList); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
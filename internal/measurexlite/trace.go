@@ -33,6 +33,13 @@ type Trace struct {
 	// sure you do that before you start measuring to avoid data races.
 	Netx model.MeasuringNetwork
 
+	// HTTP2FrameEvents OPTIONALLY enables collecting a network event for each HTTP/2 frame
+	// read or written by [netxlite.NewHTTP2Transport] when the trace is bound to the context
+	// of the HTTP request (see [netxlite.ContextWithTrace]). We disable this functionality by
+	// default because each HTTP/2 exchange produces many frames that could otherwise fill the
+	// network events buffer. Make sure you set this field before you start measuring.
+	HTTP2FrameEvents bool
+
 	// bytesReceivedMap maps a remote host with the bytes we received
 	// from such a remote host. Accessing this map requires one to
 	// additionally hold the bytesReceivedMu mutex.
@@ -54,6 +61,9 @@ type Trace struct {
 	// tcpConnect is MANDATORY and buffers TCP connect observations.
 	tcpConnect chan *model.ArchivalTCPConnectResult
 
+	// happyEyeballs is MANDATORY and buffers happy eyeballs observations.
+	happyEyeballs chan *model.ArchivalHappyEyeballsResult
+
 	// tlsHandshake is MANDATORY and buffers TLS handshake observations.
 	tlsHandshake chan *model.ArchivalTLSOrQUICHandshakeResult
 
@@ -85,6 +95,9 @@ const DelayedDNSResponseBufferSize = 8
 // TCPConnectBufferSize is the [*Trace] buffer size for TCP connect events.
 const TCPConnectBufferSize = 8
 
+// HappyEyeballsBufferSize is the [*Trace] buffer size for happy eyeballs events.
+const HappyEyeballsBufferSize = 8
+
 // TLSHandshakeBufferSize is the [*Trace] buffer size for TLS handshake events.
 const TLSHandshakeBufferSize = 8
 
@@ -109,6 +122,7 @@ func NewTrace(index int64, zeroTime time.Time, tags ...string) *Trace {
 	return &Trace{
 		index:            index,
 		Netx:             &netxlite.Netx{Underlying: nil}, // use the host network
+		HTTP2FrameEvents: false,
 		bytesReceivedMap: make(map[string]int64),
 		bytesReceivedMu:  &sync.Mutex{},
 		dnsLookup: make(
@@ -127,6 +141,10 @@ func NewTrace(index int64, zeroTime time.Time, tags ...string) *Trace {
 			chan *model.ArchivalTCPConnectResult,
 			TCPConnectBufferSize,
 		),
+		happyEyeballs: make(
+			chan *model.ArchivalHappyEyeballsResult,
+			HappyEyeballsBufferSize,
+		),
 		tlsHandshake: make(
 			chan *model.ArchivalTLSOrQUICHandshakeResult,
 			TLSHandshakeBufferSize,
@@ -119,6 +119,25 @@ func TestNewTrace(t *testing.T) {
 			}
 		})
 
+		t.Run("happyEyeballs has the expected buffer size", func(t *testing.T) {
+			ff := &testingx.FakeFiller{}
+			var idx int
+		Loop:
+			for {
+				ev := &model.ArchivalHappyEyeballsResult{}
+				ff.Fill(ev)
+				select {
+				case trace.happyEyeballs <- ev:
+					idx++
+				default:
+					break Loop
+				}
+			}
+			if idx != HappyEyeballsBufferSize {
+				t.Fatal("invalid happyEyeballs channel buffer size")
+			}
+		})
+
 		t.Run("tlsHandshake has the expected buffer size", func(t *testing.T) {
 			ff := &testingx.FakeFiller{}
 			var idx int
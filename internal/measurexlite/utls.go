@@ -5,15 +5,39 @@ package measurexlite
 //
 
 import (
+	"crypto/tls"
+	"time"
+
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	utls "gitlab.com/yawning/utls.git"
 )
 
 // NewTLSHandshakerUTLS is equivalent to netxlite.Netx.NewTLSHandshakerUTLS
 // except that it returns a model.TLSHandshaker that uses this trace.
+//
+// When the id belongs to the catalog of named fingerprints (see
+// [netxlite.UTLSFingerprintName]), we also include the fingerprint
+// name into the TLS handshake results.
 func (tx *Trace) NewTLSHandshakerUTLS(dl model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker {
 	return &tlsHandshakerTrace{
-		thx: tx.Netx.NewTLSHandshakerUTLS(dl, id),
-		tx:  tx,
+		fingerprint: netxlite.UTLSFingerprintName(id),
+		thx:         tx.Netx.NewTLSHandshakerUTLS(dl, id),
+		tx:          tx,
 	}
 }
+
+// tlsFingerprintTrace is a [*Trace] that records the name of
+// the uTLS fingerprint into the TLS handshake results.
+type tlsFingerprintTrace struct {
+	*Trace
+	fingerprint string
+}
+
+var _ model.Trace = &tlsFingerprintTrace{}
+
+// OnTLSHandshakeDone implements model.Trace.OnTLSHandshakeDone.
+func (tx *tlsFingerprintTrace) OnTLSHandshakeDone(started time.Time, remoteAddr string, config *tls.Config,
+	state tls.ConnectionState, err error, finished time.Time) {
+	tx.Trace.onTLSHandshakeDone(started, remoteAddr, config, state, err, finished, tx.fingerprint)
+}
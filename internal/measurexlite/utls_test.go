@@ -1,11 +1,16 @@
 package measurexlite
 
 import (
+	"context"
+	"crypto/tls"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/ooni/netem"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
 	utls "gitlab.com/yawning/utls.git"
 )
 
@@ -29,3 +34,51 @@ func TestNewTLSHandshakerUTLS(t *testing.T) {
 		}
 	})
 }
+
+func TestTLSHandshakerUTLSFingerprint(t *testing.T) {
+	ca := netem.MustNewCA()
+	cert := ca.MustNewTLSCertificate("www.example.com")
+	server := testingx.MustNewTLSServer(testingx.TLSHandlerHandshakeAndWriteText(cert, testingx.HTTPBlockpage451))
+	defer server.Close()
+
+	// handshake performs a TLS handshake using the given id and returns the results
+	handshake := func(id *utls.ClientHelloID) []*model.ArchivalTLSOrQUICHandshakeResult {
+		conn, err := net.Dial("tcp", server.Endpoint())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		trace := NewTrace(0, time.Now())
+		thx := trace.NewTLSHandshakerUTLS(model.DiscardLogger, id)
+		config := &tls.Config{
+			RootCAs:    ca.DefaultCertPool(),
+			ServerName: "www.example.com",
+		}
+		tlsConn, err := thx.Handshake(context.Background(), conn, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tlsConn.Close()
+		return trace.TLSHandshakes()
+	}
+
+	t.Run("we record the name of a fingerprint in the catalog", func(t *testing.T) {
+		events := handshake(&utls.HelloChrome_Auto)
+		if len(events) != 1 {
+			t.Fatal("expected to see a single TLSHandshake event")
+		}
+		if events[0].Fingerprint != "chrome" {
+			t.Fatal("unexpected fingerprint", events[0].Fingerprint)
+		}
+	})
+
+	t.Run("we do not record anything for other fingerprints", func(t *testing.T) {
+		events := handshake(&utls.HelloGolang)
+		if len(events) != 1 {
+			t.Fatal("expected to see a single TLSHandshake event")
+		}
+		if events[0].Fingerprint != "" {
+			t.Fatal("unexpected fingerprint", events[0].Fingerprint)
+		}
+	})
+}
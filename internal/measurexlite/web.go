@@ -4,7 +4,17 @@ package measurexlite
 // Code to process web results (e.g., from web connectivity)
 //
 
-import "regexp"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
 
 // webTitleRegexp is the regexp to extract the title
 //
@@ -20,3 +30,72 @@ func WebGetTitle(measurementBody string) string {
 	}
 	return v[1]
 }
+
+// WebBodySHA256 returns the hex-encoded SHA-256 of the given body.
+func WebBodySHA256(body []byte) string {
+	digest := sha256.Sum256(body)
+	return hex.EncodeToString(digest[:])
+}
+
+// webSimhashShingleSize is the number of consecutive words in each simhash feature.
+const webSimhashShingleSize = 3
+
+// WebBodySimhash returns the hex-encoded 64 bit simhash of the given body or an empty
+// string if the body does not contain any word. Unlike [WebBodySHA256], the simhash of
+// similar bodies (e.g., the same webpage with a different timestamp or CSRF token) only
+// differs by a few bits, which allows us to tell a dynamic webpage apart from a blockpage
+// that happens to have a similar length. See [WebSimhashSimilarity].
+//
+// We use as features the shingles of consecutive lowercase words, where a word is a
+// sequence of letters and digits, such that the markup also contributes to the hash.
+func WebBodySimhash(body []byte) string {
+	words := strings.FieldsFunc(strings.ToLower(string(body)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) <= 0 {
+		return ""
+	}
+
+	// count how many features have each bit set
+	var weights [64]int
+	count := max(1, len(words)-webSimhashShingleSize+1)
+	for idx := 0; idx < count; idx++ {
+		shingle := strings.Join(words[idx:min(idx+webSimhashShingleSize, len(words))], " ")
+		hasher := fnv.New64a()
+		_, _ = hasher.Write([]byte(shingle))
+		hash := hasher.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if hash&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	// the simhash has a bit set when most features have the bit set
+	var simhash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			simhash |= 1 << bit
+		}
+	}
+	return fmt.Sprintf("%016x", simhash)
+}
+
+// WebSimhashSimilarity returns the similarity between two simhashes returned by
+// [WebBodySimhash], which is a number between 0 (completely different) and 1 (same
+// simhash) computed from the number of bits that differ. Unrelated bodies have a
+// similarity close to 0.5. This function fails if either simhash is invalid.
+func WebSimhashSimilarity(left, right string) (float64, error) {
+	leftValue, err := strconv.ParseUint(left, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	rightValue, err := strconv.ParseUint(right, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	distance := bits.OnesCount64(leftValue ^ rightValue)
+	return 1 - float64(distance)/64, nil
+}
@@ -1,6 +1,8 @@
 package measurexlite
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -61,3 +63,94 @@ func TestWebGetTitle(t *testing.T) {
 		})
 	}
 }
+
+func TestWebBodySHA256(t *testing.T) {
+	// echo -n "" | sha256sum
+	const expect = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got := WebBodySHA256(nil); got != expect {
+		t.Fatal("unexpected digest", got)
+	}
+}
+
+func TestWebBodySimhash(t *testing.T) {
+	// newPage returns a webpage with the given title and a long body.
+	newPage := func(title, extra string) []byte {
+		var builder strings.Builder
+		builder.WriteString("<html><head><title>" + title + "</title></head><body>")
+		for idx := 0; idx < 64; idx++ {
+			builder.WriteString("<p>The quick brown fox jumps over the lazy dog " + strconv.Itoa(idx) + "</p>\n")
+		}
+		builder.WriteString(extra + "</body></html>")
+		return []byte(builder.String())
+	}
+
+	t.Run("we return an empty string without words", func(t *testing.T) {
+		if got := WebBodySimhash([]byte("<>  </>")); got != "" {
+			t.Fatal("expected empty string, got", got)
+		}
+	})
+
+	t.Run("we handle bodies shorter than a shingle", func(t *testing.T) {
+		if got := WebBodySimhash([]byte("blocked")); len(got) != 16 {
+			t.Fatal("unexpected simhash", got)
+		}
+	})
+
+	t.Run("similar bodies have similar simhashes", func(t *testing.T) {
+		left := WebBodySimhash(newPage("Example", "generated at 2024-05-01T10:00:00Z"))
+		right := WebBodySimhash(newPage("Example", "generated at 2024-05-01T10:00:01Z"))
+		similarity, err := WebSimhashSimilarity(left, right)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if similarity < 0.9 {
+			t.Fatal("expected similar simhashes", left, right, similarity)
+		}
+	})
+
+	t.Run("different bodies have different simhashes", func(t *testing.T) {
+		left := WebBodySimhash(newPage("Example", ""))
+		right := WebBodySimhash([]byte(
+			"<html><head><title>Access denied</title></head><body>" +
+				"<iframe src=\"http://10.10.34.35:80\" style=\"width: 100%; height: 100%\"></iframe>" +
+				"</body></html>"))
+		similarity, err := WebSimhashSimilarity(left, right)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if similarity > 0.8 {
+			t.Fatal("expected different simhashes", left, right, similarity)
+		}
+	})
+}
+
+func TestWebSimhashSimilarity(t *testing.T) {
+	t.Run("with equal simhashes", func(t *testing.T) {
+		similarity, err := WebSimhashSimilarity("00000000000000ff", "00000000000000ff")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if similarity != 1 {
+			t.Fatal("unexpected similarity", similarity)
+		}
+	})
+
+	t.Run("with opposite simhashes", func(t *testing.T) {
+		similarity, err := WebSimhashSimilarity("0000000000000000", "ffffffffffffffff")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if similarity != 0 {
+			t.Fatal("unexpected similarity", similarity)
+		}
+	})
+
+	t.Run("with invalid simhashes", func(t *testing.T) {
+		if _, err := WebSimhashSimilarity("antani", "00"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := WebSimhashSimilarity("00", "antani"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
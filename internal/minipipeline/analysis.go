@@ -3,6 +3,7 @@ package minipipeline
 import (
 	"sort"
 
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/optional"
@@ -108,12 +109,18 @@ func AnalyzeWebObservationsWithoutLinearAnalysis(
 	analysis.dnsComputeSuccessMetrics(lookupper, container)
 	analysis.dnsComputeSuccessMetricsClassic(lookupper, container)
 	analysis.dnsComputeFailureMetrics(container)
+	analysis.dnsComputeInjectionMetrics(container)
 
 	analysis.tcpComputeMetrics(container)
 	analysis.tlsComputeMetrics(container)
+	analysis.tlsComputeCertificateMetrics(container)
 	analysis.httpComputeFailureMetrics(container)
+	analysis.httpComputeRedirectChainMetrics(container)
 	analysis.httpComputeFinalResponseMetrics(container)
 
+	analysis.blockpageComputeMetrics(container)
+	analysis.httpComputeEndpointFetchMetrics(container)
+
 	return analysis
 }
 
@@ -149,6 +156,10 @@ type WebAnalysis struct {
 	// DNSLookupSuccessWithBogonAddresses contains DNS transactions with bogon IP addresses.
 	DNSLookupSuccessWithBogonAddresses Set[int64]
 
+	// DNSLookupSuccessWithBlockpageFingerprint contains DNS transactions returning IP
+	// addresses matching a fingerprint inside the [DefaultFingerprintDB].
+	DNSLookupSuccessWithBlockpageFingerprint Set[int64]
+
 	// DNSLookupSuccessWithInvalidAddressesClassic is like DNSLookupInvalid but the algorithm is more relaxed
 	// to be compatible with Web Connectivity v0.4's behavior.
 	DNSLookupSuccessWithInvalidAddressesClassic Set[int64]
@@ -174,6 +185,10 @@ type WebAnalysis struct {
 	// DNSLookupExpectedSuccess contains DNS transactions with expected successes.
 	DNSLookupExpectedSuccess Set[int64]
 
+	// DNSLookupInjection contains DNS transactions for which we received duplicate
+	// responses containing addresses different from the ones of the first response.
+	DNSLookupInjection Set[int64]
+
 	// TCPConnectExpectedFailure contains TCP connect transactions that failed
 	// consistently for the probe and the test helper.
 	TCPConnectExpectedFailure Set[int64]
@@ -228,6 +243,13 @@ type WebAnalysis struct {
 	// while checking for connectivity, as opposed to fetching a webpage.
 	TLSHandshakeUnexplainedFailureDuringConnectivityCheck Set[int64]
 
+	// TLSHandshakeCertificateDiffersFromControl contains TLS endpoint transactions where the
+	// leaf certificate public key differs from the one seen by the control for the same endpoint
+	// and server name, which may indicate a TLS MITM using a certificate trusted by the probe. Note
+	// that servers may legitimately use distinct keys (e.g., during a key rollover), so this set
+	// alone is not sufficient to conclude that there is interference.
+	TLSHandshakeCertificateDiffersFromControl Set[int64]
+
 	// HTTPRoundTripUnexpectedFailure contains HTTP endpoint transactions with unexpected failures.
 	HTTPRoundTripUnexpectedFailure Set[int64]
 
@@ -235,6 +257,23 @@ type WebAnalysis struct {
 	// failures for which there's no corresponding control info.
 	HTTPRoundTripUnexplainedFailure Set[int64]
 
+	// HTTPRedirectChainDivergence contains HTTP endpoint transactions where the probe received
+	// a response whose status code differs from the one the control received for the same URL
+	// while following redirects (e.g., because there is a blockpage instead of a redirect).
+	HTTPRedirectChainDivergence Set[int64]
+
+	// HTTPRedirectChainFirstDivergentHop contains the index inside the control's redirect
+	// chain of the first response for which the probe's response diverges.
+	HTTPRedirectChainFirstDivergentHop optional.Value[int64]
+
+	// HTTPRoundTripWithBlockpageFingerprint contains HTTP endpoint transactions whose
+	// response matches a fingerprint inside the [DefaultFingerprintDB].
+	HTTPRoundTripWithBlockpageFingerprint Set[int64]
+
+	// BlockpageFingerprints contains the IDs of all the fingerprints we matched. When this
+	// set is not empty, we have confirmed blocking rather than just suspecting blocking.
+	BlockpageFingerprints Set[string]
+
 	// HTTPFinalResponseSuccessTLSWithoutControl contains the ID of the final response
 	// transaction when the final response succeeded without control and with TLS.
 	HTTPFinalResponseSuccessTLSWithoutControl optional.Value[int64]
@@ -254,6 +293,13 @@ type WebAnalysis struct {
 	// HTTPFinalResponseDiffBodyProportionFactor is the body proportion factor.
 	HTTPFinalResponseDiffBodyProportionFactor optional.Value[float64]
 
+	// HTTPFinalResponseDiffBodySHA256Match returns whether the body SHA-256 matches.
+	HTTPFinalResponseDiffBodySHA256Match optional.Value[bool]
+
+	// HTTPFinalResponseDiffBodySimilarity is the similarity between the probe's and the
+	// control's bodies computed using [measurexlite.WebSimhashSimilarity].
+	HTTPFinalResponseDiffBodySimilarity optional.Value[float64]
+
 	// HTTPFinalResponseDiffStatusCodeMatch returns whether the status code matches.
 	HTTPFinalResponseDiffStatusCodeMatch optional.Value[bool]
 
@@ -264,6 +310,17 @@ type WebAnalysis struct {
 	// HTTPFinalResponseDiffUncommonHeadersIntersection contains the uncommon headers intersection.
 	HTTPFinalResponseDiffUncommonHeadersIntersection optional.Value[map[string]bool]
 
+	// HTTPEndpointFetchUnexpectedFailure contains the transactions fetching the webpage using
+	// a specific endpoint (see [WebObservationsContainer.EndpointFetches]) that failed when the
+	// control successfully fetched the webpage and did not fail connecting to the endpoint.
+	HTTPEndpointFetchUnexpectedFailure Set[int64]
+
+	// HTTPEndpointFetchDiff contains the transactions fetching the webpage using a specific
+	// endpoint whose final response has a different status code than the control's final
+	// response or conclusively matches a fingerprint inside the [DefaultFingerprintDB]
+	// (see [HTTPResponseBlockpageFingerprintIsConclusive]).
+	HTTPEndpointFetchDiff Set[int64]
+
 	// Linear contains the linear analysis. We only fill this field when using
 	// the [AnalyzeWebObservationsWithLinearAnalysis] constructor.
 	Linear []*WebObservation
@@ -382,6 +439,19 @@ func (wa *WebAnalysis) dnsComputeSuccessMetricsClassic(
 	}
 }
 
+func (wa *WebAnalysis) dnsComputeInjectionMetrics(c *WebObservationsContainer) {
+	var observations []*WebObservation
+	observations = append(observations, c.DNSLookupFailures...)
+	observations = append(observations, c.DNSLookupSuccesses...)
+	for _, obs := range observations {
+		// Implementation note: we consider all redirects because injection does
+		// not depend on the control and we want to know about all of them
+		if obs.DNSInjection.UnwrapOr(false) {
+			wa.DNSLookupInjection.Add(obs.DNSTransactionID.Unwrap())
+		}
+	}
+}
+
 func (wa *WebAnalysis) dnsComputeFailureMetrics(c *WebObservationsContainer) {
 	var already Set[int64]
 
@@ -483,7 +553,8 @@ func (wa *WebAnalysis) tcpComputeMetrics(c *WebObservationsContainer) {
 		// dials once we started following redirects should be treated differently
 		// since we know there's no control information beyond depth==0
 		if obs.TagDepth.IsNone() || obs.TagDepth.Unwrap() != 0 {
-			if utilsTCPConnectFailureSeemsMisconfiguredIPv6(obs) {
+			if utilsTCPConnectFailureSeemsMisconfiguredIPv6(obs) ||
+				utilsTCPConnectFailureHasIPv4Fallback(c, obs) {
 				continue
 			}
 			if obs.TCPConnectFailure.Unwrap() != "" {
@@ -522,7 +593,8 @@ func (wa *WebAnalysis) tcpComputeMetrics(c *WebObservationsContainer) {
 
 		// handle the case where only the probe fails
 		if obs.TCPConnectFailure.Unwrap() != "" {
-			if utilsTCPConnectFailureSeemsMisconfiguredIPv6(obs) {
+			if utilsTCPConnectFailureSeemsMisconfiguredIPv6(obs) ||
+				utilsTCPConnectFailureHasIPv4Fallback(c, obs) {
 				continue
 			}
 			switch {
@@ -592,6 +664,26 @@ func (wa *WebAnalysis) tlsComputeMetrics(c *WebObservationsContainer) {
 	}
 }
 
+func (wa *WebAnalysis) tlsComputeCertificateMetrics(c *WebObservationsContainer) {
+	for _, obs := range c.KnownTCPEndpoints {
+		// Implementation note: we don't need to restrict ourselves to depth==0 here
+		// because we only have control information when the control measured the same
+		// endpoint using the same server name, regardless of the redirect depth.
+
+		// handle the case where we're missing either the probe or the control certificate
+		if obs.TLSLeafSPKISHA256.IsNone() || obs.ControlTLSLeafSPKISHA256.IsNone() {
+			continue
+		}
+
+		// handle the case where the probe and the control see the same public key
+		if obs.TLSLeafSPKISHA256.Unwrap() == obs.ControlTLSLeafSPKISHA256.Unwrap() {
+			continue
+		}
+
+		wa.TLSHandshakeCertificateDiffersFromControl.Add(obs.EndpointTransactionID.Unwrap())
+	}
+}
+
 func (wa *WebAnalysis) httpComputeFailureMetrics(c *WebObservationsContainer) {
 	for _, obs := range c.KnownTCPEndpoints {
 		// Implementation note: here we don't limit the search to depth==0 because the
@@ -629,6 +721,87 @@ func (wa *WebAnalysis) httpComputeFailureMetrics(c *WebObservationsContainer) {
 	}
 }
 
+func (wa *WebAnalysis) httpComputeRedirectChainMetrics(c *WebObservationsContainer) {
+	for _, obs := range c.KnownTCPEndpoints {
+		// handle the case where there is no control information for this URL
+		if obs.ControlHTTPRedirectHopStatusCode.IsNone() {
+			continue
+		}
+
+		// handle the case where we did not receive a response
+		if obs.HTTPResponseStatusCode.IsNone() || obs.HTTPFailure.UnwrapOr("") != "" {
+			continue
+		}
+
+		// handle the case where the probe and the control agree
+		if obs.HTTPResponseStatusCode.Unwrap() == obs.ControlHTTPRedirectHopStatusCode.Unwrap() {
+			continue
+		}
+
+		wa.HTTPRedirectChainDivergence.Add(obs.EndpointTransactionID.Unwrap())
+
+		// keep track of the first hop at which we diverge
+		hop := obs.ControlHTTPRedirectHopIndex.Unwrap()
+		if wa.HTTPRedirectChainFirstDivergentHop.IsNone() || hop < wa.HTTPRedirectChainFirstDivergentHop.Unwrap() {
+			wa.HTTPRedirectChainFirstDivergentHop = optional.Some(hop)
+		}
+	}
+}
+
+func (wa *WebAnalysis) blockpageComputeMetrics(c *WebObservationsContainer) {
+	// Implementation note: we don't need to restrict ourselves to depth==0 here
+	// because a fingerprint match is conclusive regardless of the redirect depth,
+	// provided that the response was cleartext and the control did not match.
+	for _, obs := range c.DNSLookupSuccesses {
+		if obs.IPAddressBlockpageFingerprint.IsNone() {
+			continue
+		}
+		wa.DNSLookupSuccessWithBlockpageFingerprint.Add(obs.DNSTransactionID.Unwrap())
+		wa.BlockpageFingerprints.Add(obs.IPAddressBlockpageFingerprint.Unwrap())
+	}
+
+	for _, obs := range c.KnownTCPEndpoints {
+		if !HTTPResponseBlockpageFingerprintIsConclusive(obs) {
+			continue
+		}
+		wa.HTTPRoundTripWithBlockpageFingerprint.Add(obs.EndpointTransactionID.Unwrap())
+		wa.BlockpageFingerprints.Add(obs.HTTPResponseBlockpageFingerprint.Unwrap())
+	}
+}
+
+func (wa *WebAnalysis) httpComputeEndpointFetchMetrics(c *WebObservationsContainer) {
+	for _, obs := range c.EndpointFetches {
+		// handle the case where there is no control information
+		if obs.ControlHTTPFailure.IsNone() || obs.ControlHTTPFailure.Unwrap() != "" {
+			continue
+		}
+
+		// handle the case where the fetch failed
+		if obs.Failure.UnwrapOr("") != "" {
+			// the failure is expected when the control also failed using the endpoint
+			if obs.ControlTCPConnectFailure.UnwrapOr("") != "" || obs.ControlTLSHandshakeFailure.UnwrapOr("") != "" {
+				continue
+			}
+			wa.HTTPEndpointFetchUnexpectedFailure.Add(obs.EndpointTransactionID.Unwrap())
+			continue
+		}
+
+		// handle the case where the response matches a known blockpage
+		if HTTPResponseBlockpageFingerprintIsConclusive(obs) {
+			wa.HTTPEndpointFetchDiff.Add(obs.EndpointTransactionID.Unwrap())
+			continue
+		}
+
+		// we need a final HTTP response to compare with the control
+		if !obs.HTTPResponseIsFinal.UnwrapOr(false) || obs.ControlHTTPResponseStatusCode.IsNone() {
+			continue
+		}
+		if obs.HTTPResponseStatusCode.Unwrap() != obs.ControlHTTPResponseStatusCode.Unwrap() {
+			wa.HTTPEndpointFetchDiff.Add(obs.EndpointTransactionID.Unwrap())
+		}
+	}
+}
+
 func (wa *WebAnalysis) httpComputeFinalResponseMetrics(c *WebObservationsContainer) {
 	for _, obs := range c.KnownTCPEndpoints {
 		// we need a final HTTP response
@@ -664,6 +837,7 @@ func (wa *WebAnalysis) httpHandleFinalResponse(obs *WebObservation) {
 
 	// compute the HTTPDiff metrics
 	wa.httpDiffBodyProportionFactor(obs)
+	wa.httpDiffBodyHashes(obs)
 	wa.httpDiffStatusCodeMatch(obs)
 	wa.httpDiffUncommonHeadersIntersection(obs)
 	wa.httpDiffTitleDifferentLongWords(obs)
@@ -699,6 +873,41 @@ func (wa *WebAnalysis) httpDiffBodyProportionFactor(obs *WebObservation) int64 {
 	return 0
 }
 
+// httpDiffBodyHashes compares the SHA-256 and the simhash of the bodies.
+//
+// The return value--used for testing--is zero on success and negative in case of failure.
+func (wa *WebAnalysis) httpDiffBodyHashes(obs *WebObservation) int64 {
+	// we should only perform the comparison for a final response
+	if !obs.HTTPResponseIsFinal.UnwrapOr(false) {
+		return -1
+	}
+
+	// the body must not be truncated
+	if obs.HTTPResponseBodyIsTruncated.UnwrapOr(true) {
+		return -2
+	}
+
+	// we need the SHA-256 of both bodies, which we do not have when
+	// we're using a control that does not compute it
+	if obs.HTTPResponseBodySHA256.IsNone() || obs.ControlHTTPResponseBodySHA256.IsNone() {
+		return -3
+	}
+	match := obs.HTTPResponseBodySHA256.Unwrap() == obs.ControlHTTPResponseBodySHA256.Unwrap()
+	wa.HTTPFinalResponseDiffBodySHA256Match = optional.Some(match)
+
+	// we also need the simhash of both bodies, which is missing without words
+	if obs.HTTPResponseBodySimhash.IsNone() || obs.ControlHTTPResponseBodySimhash.IsNone() {
+		return -4
+	}
+	similarity, err := measurexlite.WebSimhashSimilarity(
+		obs.HTTPResponseBodySimhash.Unwrap(), obs.ControlHTTPResponseBodySimhash.Unwrap())
+	if err != nil {
+		return -5
+	}
+	wa.HTTPFinalResponseDiffBodySimilarity = optional.Some(similarity)
+	return 0
+}
+
 // httpDiffStatusCodeMatch computes whether the status code matches.
 //
 // The return value--used for testing--is zero on success and negative in case of failure.
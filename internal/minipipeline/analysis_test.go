@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/optional"
 )
 
@@ -140,6 +142,118 @@ func TestHTTPDiffBodyProportionFactor(t *testing.T) {
 	}
 }
 
+func TestHTTPDiffBodyHashes(t *testing.T) {
+	type testcase struct {
+		name                           string
+		HTTPResponseIsFinal            optional.Value[bool]
+		HTTPResponseBodyIsTruncated    optional.Value[bool]
+		HTTPResponseBodySHA256         optional.Value[string]
+		HTTPResponseBodySimhash        optional.Value[string]
+		ControlHTTPResponseBodySHA256  optional.Value[string]
+		ControlHTTPResponseBodySimhash optional.Value[string]
+		ExpectReturnValue              int64
+		ExpectSHA256Match              optional.Value[bool]
+		ExpectSimilarity               optional.Value[float64]
+	}
+
+	allcases := []testcase{{
+		name:                           "with non-final WebObservation",
+		HTTPResponseIsFinal:            optional.Some(false),
+		HTTPResponseBodyIsTruncated:    optional.None[bool](),
+		HTTPResponseBodySHA256:         optional.None[string](),
+		HTTPResponseBodySimhash:        optional.None[string](),
+		ControlHTTPResponseBodySHA256:  optional.None[string](),
+		ControlHTTPResponseBodySimhash: optional.None[string](),
+		ExpectReturnValue:              -1,
+		ExpectSHA256Match:              optional.None[bool](),
+		ExpectSimilarity:               optional.None[float64](),
+	}, {
+		name:                           "with truncated response body",
+		HTTPResponseIsFinal:            optional.Some(true),
+		HTTPResponseBodyIsTruncated:    optional.Some(true),
+		HTTPResponseBodySHA256:         optional.Some("deadbeef"),
+		HTTPResponseBodySimhash:        optional.Some("00000000000000ff"),
+		ControlHTTPResponseBodySHA256:  optional.Some("deadbeef"),
+		ControlHTTPResponseBodySimhash: optional.Some("00000000000000ff"),
+		ExpectReturnValue:              -2,
+		ExpectSHA256Match:              optional.None[bool](),
+		ExpectSimilarity:               optional.None[float64](),
+	}, {
+		name:                           "with missing control SHA256",
+		HTTPResponseIsFinal:            optional.Some(true),
+		HTTPResponseBodyIsTruncated:    optional.Some(false),
+		HTTPResponseBodySHA256:         optional.Some("deadbeef"),
+		HTTPResponseBodySimhash:        optional.Some("00000000000000ff"),
+		ControlHTTPResponseBodySHA256:  optional.None[string](),
+		ControlHTTPResponseBodySimhash: optional.None[string](),
+		ExpectReturnValue:              -3,
+		ExpectSHA256Match:              optional.None[bool](),
+		ExpectSimilarity:               optional.None[float64](),
+	}, {
+		name:                           "with missing simhash",
+		HTTPResponseIsFinal:            optional.Some(true),
+		HTTPResponseBodyIsTruncated:    optional.Some(false),
+		HTTPResponseBodySHA256:         optional.Some("deadbeef"),
+		HTTPResponseBodySimhash:        optional.None[string](),
+		ControlHTTPResponseBodySHA256:  optional.Some("abad1dea"),
+		ControlHTTPResponseBodySimhash: optional.Some("00000000000000ff"),
+		ExpectReturnValue:              -4,
+		ExpectSHA256Match:              optional.Some(false),
+		ExpectSimilarity:               optional.None[float64](),
+	}, {
+		name:                           "with invalid simhash",
+		HTTPResponseIsFinal:            optional.Some(true),
+		HTTPResponseBodyIsTruncated:    optional.Some(false),
+		HTTPResponseBodySHA256:         optional.Some("deadbeef"),
+		HTTPResponseBodySimhash:        optional.Some("antani"),
+		ControlHTTPResponseBodySHA256:  optional.Some("deadbeef"),
+		ControlHTTPResponseBodySimhash: optional.Some("00000000000000ff"),
+		ExpectReturnValue:              -5,
+		ExpectSHA256Match:              optional.Some(true),
+		ExpectSimilarity:               optional.None[float64](),
+	}, {
+		name:                           "successful case",
+		HTTPResponseIsFinal:            optional.Some(true),
+		HTTPResponseBodyIsTruncated:    optional.Some(false),
+		HTTPResponseBodySHA256:         optional.Some("deadbeef"),
+		HTTPResponseBodySimhash:        optional.Some("000000000000ffff"),
+		ControlHTTPResponseBodySHA256:  optional.Some("abad1dea"),
+		ControlHTTPResponseBodySimhash: optional.Some("00000000000000ff"),
+		ExpectReturnValue:              0,
+		ExpectSHA256Match:              optional.Some(false),
+		ExpectSimilarity:               optional.Some(0.875),
+	}}
+
+	for _, tc := range allcases {
+		t.Run(tc.name, func(t *testing.T) {
+			obs := &WebObservation{
+				HTTPResponseIsFinal:            tc.HTTPResponseIsFinal,
+				HTTPResponseBodyIsTruncated:    tc.HTTPResponseBodyIsTruncated,
+				HTTPResponseBodySHA256:         tc.HTTPResponseBodySHA256,
+				HTTPResponseBodySimhash:        tc.HTTPResponseBodySimhash,
+				ControlHTTPResponseBodySHA256:  tc.ControlHTTPResponseBodySHA256,
+				ControlHTTPResponseBodySimhash: tc.ControlHTTPResponseBodySimhash,
+			}
+
+			wa := &WebAnalysis{}
+			retval := wa.httpDiffBodyHashes(obs)
+			if diff := cmp.Diff(tc.ExpectReturnValue, retval); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if diff := cmp.Diff(tc.ExpectSHA256Match.UnwrapOr(false), wa.HTTPFinalResponseDiffBodySHA256Match.UnwrapOr(false)); diff != "" {
+				t.Fatal(diff)
+			}
+			if tc.ExpectSHA256Match.IsNone() != wa.HTTPFinalResponseDiffBodySHA256Match.IsNone() {
+				t.Fatal("unexpected SHA256 match presence")
+			}
+			if diff := cmp.Diff(tc.ExpectSimilarity.UnwrapOr(-1), wa.HTTPFinalResponseDiffBodySimilarity.UnwrapOr(-1)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
 func TestHTTPDiffStatusCodeMatch(t *testing.T) {
 	type testcase struct {
 		name                          string
@@ -482,3 +596,210 @@ func TestHTTPDiffTitleDifferentLongWords(t *testing.T) {
 		})
 	}
 }
+
+func TestTLSComputeCertificateMetrics(t *testing.T) {
+	// analyze ingests a TLS handshake and the corresponding control using the given
+	// leaf public key hashes and returns the resulting analysis.
+	analyze := func(probeSPKI, controlSPKI string) *WebAnalysis {
+		container := &WebObservationsContainer{
+			DNSLookupFailures: []*WebObservation{},
+			KnownTCPEndpoints: map[int64]*WebObservation{
+				1: {
+					IPAddress:             optional.Some("93.184.216.34"),
+					EndpointTransactionID: optional.Some(int64(1)),
+					EndpointPort:          optional.Some("443"),
+					EndpointAddress:       optional.Some("93.184.216.34:443"),
+					TagDepth:              optional.Some(int64(0)),
+				},
+			},
+			knownIPAddresses: map[string]*WebObservation{},
+		}
+		container.IngestTLSHandshakeEvents(&model.ArchivalTLSOrQUICHandshakeResult{
+			Address:        "93.184.216.34:443",
+			LeafSPKISHA256: probeSPKI,
+			ServerName:     "www.example.com",
+			TransactionID:  1,
+		})
+		thRequest := &model.THRequest{HTTPRequest: "https://www.example.com/"}
+		thResponse := &model.THResponse{
+			TLSHandshake: map[string]model.THTLSHandshakeResult{
+				"93.184.216.34:443": {
+					ServerName:     "www.example.com",
+					Status:         true,
+					LeafSPKISHA256: controlSPKI,
+				},
+			},
+		}
+		if err := container.IngestControlMessages(thRequest, thResponse); err != nil {
+			t.Fatal(err)
+		}
+		lookupper := mocks.NewGeoIPASNLookupper(map[string]*model.LocationASN{})
+		return AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
+	}
+
+	t.Run("when the certificates differ", func(t *testing.T) {
+		analysis := analyze("dGhlIHByb2JlIGtleQ==", "dGhlIGNvbnRyb2wga2V5")
+		if diff := cmp.Diff([]int64{1}, analysis.TLSHandshakeCertificateDiffersFromControl.Keys()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the certificates are equal", func(t *testing.T) {
+		analysis := analyze("dGhlIGNvbnRyb2wga2V5", "dGhlIGNvbnRyb2wga2V5")
+		if analysis.TLSHandshakeCertificateDiffersFromControl.Len() != 0 {
+			t.Fatal("expected no differences")
+		}
+	})
+
+	t.Run("when the control did not return any certificate", func(t *testing.T) {
+		analysis := analyze("dGhlIHByb2JlIGtleQ==", "")
+		if analysis.TLSHandshakeCertificateDiffersFromControl.Len() != 0 {
+			t.Fatal("expected no differences")
+		}
+	})
+}
+
+func TestHTTPComputeRedirectChainMetrics(t *testing.T) {
+	container := &WebObservationsContainer{
+		DNSLookupFailures: []*WebObservation{},
+		KnownTCPEndpoints: map[int64]*WebObservation{
+			1: {
+				EndpointTransactionID:  optional.Some(int64(1)),
+				IPAddress:              optional.Some("93.184.216.34"),
+				EndpointAddress:        optional.Some("93.184.216.34:80"),
+				HTTPRequestURL:         optional.Some("http://example.com/"),
+				HTTPFailure:            optional.Some(""),
+				HTTPResponseStatusCode: optional.Some(int64(302)),
+			},
+			2: {
+				EndpointTransactionID:  optional.Some(int64(2)),
+				IPAddress:              optional.Some("93.184.216.34"),
+				EndpointAddress:        optional.Some("93.184.216.34:80"),
+				HTTPRequestURL:         optional.Some("http://www.example.com/"),
+				HTTPFailure:            optional.Some(""),
+				HTTPResponseStatusCode: optional.Some(int64(200)),
+			},
+			3: {
+				EndpointTransactionID:  optional.Some(int64(3)),
+				IPAddress:              optional.Some("93.184.216.34"),
+				EndpointAddress:        optional.Some("93.184.216.34:80"),
+				HTTPRequestURL:         optional.Some("http://www.example.org/"),
+				HTTPFailure:            optional.Some(""),
+				HTTPResponseStatusCode: optional.Some(int64(200)),
+			},
+		},
+		knownIPAddresses: map[string]*WebObservation{},
+	}
+
+	thRequest := &model.THRequest{HTTPRequest: "http://example.com/"}
+	thResponse := &model.THResponse{
+		HTTPRequest: model.THHTTPRequestResult{
+			StatusCode: 200,
+			RedirectChain: []model.THHTTPHopResult{{
+				URL:        "http://example.com/",
+				StatusCode: 302,
+				Location:   "http://www.example.com/",
+			}, {
+				URL:        "http://www.example.com/",
+				StatusCode: 301,
+				Location:   "https://www.example.com/",
+			}, {
+				URL:        "https://www.example.com/",
+				StatusCode: 200,
+			}},
+		},
+	}
+	if err := container.IngestControlMessages(thRequest, thResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	if container.KnownTCPEndpoints[2].ControlHTTPRedirectHopLocation.UnwrapOr("") != "https://www.example.com/" {
+		t.Fatal("unexpected control location")
+	}
+	if !container.KnownTCPEndpoints[3].ControlHTTPRedirectHopIndex.IsNone() {
+		t.Fatal("expected no control information for an URL not in the chain")
+	}
+
+	lookupper := mocks.NewGeoIPASNLookupper(map[string]*model.LocationASN{})
+	analysis := AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
+	if diff := cmp.Diff([]int64{2}, analysis.HTTPRedirectChainDivergence.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if analysis.HTTPRedirectChainFirstDivergentHop.UnwrapOr(-1) != 1 {
+		t.Fatal("unexpected first divergent hop")
+	}
+}
+
+func TestDNSComputeInjectionMetrics(t *testing.T) {
+	// newLookup is a helper to create a DNS lookup result
+	newLookup := func(id int64, addrs ...string) *model.ArchivalDNSLookupResult {
+		var answers []model.ArchivalDNSAnswer
+		for _, addr := range addrs {
+			answers = append(answers, model.ArchivalDNSAnswer{AnswerType: "A", IPv4: addr})
+		}
+		return &model.ArchivalDNSLookupResult{
+			Answers:       answers,
+			Engine:        "udp",
+			Hostname:      "www.example.com",
+			QueryType:     "A",
+			Tags:          []string{"depth=0"},
+			TransactionID: id,
+		}
+	}
+
+	// withDuplicates attaches the given duplicates to the given lookup
+	withDuplicates := func(query *model.ArchivalDNSLookupResult, dups ...*model.ArchivalDNSLookupResult) *model.ArchivalDNSLookupResult {
+		query.DuplicateResponses = dups
+		return query
+	}
+
+	// failed returns the given lookup after marking it as failed
+	failed := func(query *model.ArchivalDNSLookupResult) *model.ArchivalDNSLookupResult {
+		failure := "dns_nxdomain_error"
+		query.Failure = &failure
+		return query
+	}
+
+	type testcase struct {
+		name    string
+		queries []*model.ArchivalDNSLookupResult
+		expect  []int64
+	}
+
+	testcases := []testcase{{
+		name:    "without duplicate responses",
+		queries: []*model.ArchivalDNSLookupResult{newLookup(1, "10.10.34.35")},
+		expect:  []int64{},
+	}, {
+		name: "with duplicate responses containing the same addresses in different order",
+		queries: []*model.ArchivalDNSLookupResult{
+			withDuplicates(newLookup(1, "93.184.216.34", "93.184.216.35"), newLookup(1, "93.184.216.35", "93.184.216.34")),
+		},
+		expect: []int64{},
+	}, {
+		name: "with duplicate responses containing different addresses",
+		queries: []*model.ArchivalDNSLookupResult{
+			withDuplicates(newLookup(1, "10.10.34.35"), newLookup(1, "93.184.216.34")),
+			newLookup(2, "8.8.8.8"),
+		},
+		expect: []int64{1},
+	}, {
+		name: "with an injected failure followed by a response containing addresses",
+		queries: []*model.ArchivalDNSLookupResult{
+			withDuplicates(failed(newLookup(1)), newLookup(1, "93.184.216.34")),
+		},
+		expect: []int64{1},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			container := NewWebObservationsContainer()
+			lookupper := mocks.NewGeoIPASNLookupper(map[string]*model.LocationASN{})
+			container.IngestDNSLookupEvents(lookupper, tc.queries...)
+			analysis := AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
+			if diff := cmp.Diff(tc.expect, analysis.DNSLookupInjection.Keys()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -21,6 +21,15 @@ func ClassicFilter(input *WebObservationsContainer) (output *WebObservationsCont
 		DNSLookupSuccesses: []*WebObservation{},
 		KnownTCPEndpoints:  map[int64]*WebObservation{},
 		knownIPAddresses:   map[string]*WebObservation{},
+
+		// Web Connectivity v0.4 only fetches a single webpage per redirect
+		EndpointFetches: map[int64]*WebObservation{},
+
+		// keep the happy eyeballs results
+		happyEyeballsIPv4Domains: input.happyEyeballsIPv4Domains,
+
+		// keep the probe country code
+		ProbeCC: input.ProbeCC,
 	}
 
 	// DNSLookupFailures
@@ -0,0 +1,303 @@
+package minipipeline
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+// These are the flags describing how test helpers disagree with each other.
+const (
+	// ControlDisagreementDNSFailure means that the DNS lookups performed by the
+	// test helpers did not all succeed or did not all fail in the same way.
+	ControlDisagreementDNSFailure = 1 << iota
+
+	// ControlDisagreementDNSAddrs means that two test helpers successfully
+	// resolved the domain to disjoint sets of IP addresses.
+	ControlDisagreementDNSAddrs
+
+	// ControlDisagreementTCPConnect means that the TCP connect attempts to an
+	// endpoint did not all succeed or did not all fail in the same way.
+	ControlDisagreementTCPConnect
+
+	// ControlDisagreementTLSHandshake is like ControlDisagreementTCPConnect
+	// but for the TLS handshakes with an endpoint.
+	ControlDisagreementTLSHandshake
+
+	// ControlDisagreementQUICHandshake is like ControlDisagreementTCPConnect
+	// but for the QUIC handshakes with an endpoint.
+	ControlDisagreementQUICHandshake
+
+	// ControlDisagreementHTTPFailure means that the test helpers did not all
+	// fetch the webpage or did not all fail in the same way.
+	ControlDisagreementHTTPFailure
+
+	// ControlDisagreementHTTPStatusCode means that the test helpers that fetched
+	// the webpage did not all see the same final status code.
+	ControlDisagreementHTTPStatusCode
+)
+
+// ControlDisagreements describes how several test helpers disagree with each other.
+type ControlDisagreements struct {
+	// Flags contains flags that apply to the whole measurement, i.e., the
+	// flags related to DNS lookups and to fetching the webpage.
+	Flags int64
+
+	// Endpoints maps an endpoint address to the flags that only apply to such
+	// an endpoint, i.e., the flags related to TCP, TLS, and QUIC.
+	Endpoints map[string]int64
+}
+
+// MergeControlResults merges the successful responses of several test helpers into a
+// single response, which considers an operation successful when any test helper succeeded
+// and otherwise uses the failure of the first test helper. This policy ensures that a test
+// helper that is itself experiencing censorship or rate limiting does not cause us to flag
+// as anomalous an operation that worked for the probe.
+//
+// This function also returns how the test helpers disagree with each other. When there are
+// no successful responses, it returns a nil response and empty disagreements.
+func MergeControlResults(results ...*model.THResult) (*model.THResponse, *ControlDisagreements) {
+	disagreements := &ControlDisagreements{
+		Flags:     0,
+		Endpoints: map[string]int64{},
+	}
+
+	var responses []*model.THResponse
+	for _, result := range results {
+		if result != nil && result.Failure == nil && result.Response != nil {
+			responses = append(responses, result.Response)
+		}
+	}
+	if len(responses) <= 0 {
+		return nil, disagreements
+	}
+
+	merged := &model.THResponse{
+		TCPConnect: controlMergeEndpoints(
+			responses, disagreements, ControlDisagreementTCPConnect,
+			func(resp *model.THResponse) map[string]model.THTCPConnectResult {
+				return resp.TCPConnect
+			},
+			func(result model.THTCPConnectResult) *string {
+				return result.Failure
+			},
+		),
+		TLSHandshake: controlMergeEndpoints(
+			responses, disagreements, ControlDisagreementTLSHandshake,
+			func(resp *model.THResponse) map[string]model.THTLSHandshakeResult {
+				return resp.TLSHandshake
+			},
+			func(result model.THTLSHandshakeResult) *string {
+				return result.Failure
+			},
+		),
+		QUICHandshake: controlMergeEndpoints(
+			responses, disagreements, ControlDisagreementQUICHandshake,
+			func(resp *model.THResponse) map[string]model.THTLSHandshakeResult {
+				return resp.QUICHandshake
+			},
+			func(result model.THTLSHandshakeResult) *string {
+				return result.Failure
+			},
+		),
+		HTTPRequest:  controlMergeHTTPRequest(responses, disagreements),
+		HTTP3Request: controlMergeHTTP3Request(responses),
+		DNS:          controlMergeDNS(responses, disagreements),
+		IPInfo:       controlMergeIPInfo(responses),
+		DNSResolvers: controlMergeDNSResolvers(responses),
+	}
+	return merged, disagreements
+}
+
+// controlMergeEndpoints merges the per-endpoint results of the test helpers.
+func controlMergeEndpoints[T any](
+	responses []*model.THResponse,
+	disagreements *ControlDisagreements,
+	flag int64,
+	getResults func(resp *model.THResponse) map[string]T,
+	getFailure func(result T) *string,
+) map[string]T {
+	merged := map[string]T{}
+	failures := map[string]Set[string]{}
+	for _, resp := range responses {
+		for endpoint, result := range getResults(resp) {
+			failure := getFailure(result)
+
+			// register the failure to determine whether the test helpers disagree
+			set := failures[endpoint]
+			set.Add(utilsStringPointerToString(failure))
+			failures[endpoint] = set
+
+			// use the first success or otherwise the first failure
+			previous, found := merged[endpoint]
+			if !found || (getFailure(previous) != nil && failure == nil) {
+				merged[endpoint] = result
+			}
+		}
+	}
+	for endpoint, set := range failures {
+		if set.Len() > 1 {
+			disagreements.Endpoints[endpoint] |= flag
+		}
+	}
+	return merged
+}
+
+// controlMergeDNS merges the DNS results of the test helpers.
+func controlMergeDNS(responses []*model.THResponse, disagreements *ControlDisagreements) model.THDNSResult {
+	var (
+		addrs     = []string{}
+		failures  = NewSet[string]()
+		resolved  = NewSet[string]()
+		successes []Set[string]
+	)
+	for _, resp := range responses {
+		failures.Add(utilsStringPointerToString(resp.DNS.Failure))
+		if resp.DNS.Failure != nil {
+			continue
+		}
+		for _, addr := range resp.DNS.Addrs {
+			if !resolved.Contains(addr) {
+				addrs = append(addrs, addr)
+			}
+			resolved.Add(addr)
+		}
+		successes = append(successes, NewSet(resp.DNS.Addrs...))
+	}
+
+	if failures.Len() > 1 {
+		disagreements.Flags |= ControlDisagreementDNSFailure
+	}
+	for idx := 0; idx < len(successes); idx++ {
+		for jdx := idx + 1; jdx < len(successes); jdx++ {
+			if !controlSetsIntersect(successes[idx], successes[jdx]) {
+				disagreements.Flags |= ControlDisagreementDNSAddrs
+			}
+		}
+	}
+
+	// use the union of the resolved addresses or otherwise the first failure
+	if len(successes) <= 0 {
+		return responses[0].DNS
+	}
+	return model.THDNSResult{
+		Failure: nil,
+		Addrs:   addrs,
+		ASNs:    nil, // not serialized
+	}
+}
+
+// controlMergeDNSResolvers merges the results of the additional resolvers used by the test
+// helpers, which we keep as is because each result already identifies its resolver.
+func controlMergeDNSResolvers(responses []*model.THResponse) (merged []model.THDNSResolverResult) {
+	for _, resp := range responses {
+		merged = append(merged, resp.DNSResolvers...)
+	}
+	return
+}
+
+// controlSetsIntersect returns whether the given sets have keys in common.
+func controlSetsIntersect(left, right Set[string]) bool {
+	for _, key := range left.Keys() {
+		if right.Contains(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// controlMergeHTTPRequest merges the HTTP results of the test helpers.
+func controlMergeHTTPRequest(
+	responses []*model.THResponse, disagreements *ControlDisagreements) model.THHTTPRequestResult {
+	var (
+		failures    = NewSet[string]()
+		merged      = responses[0].HTTPRequest
+		statusCodes = NewSet[int64]()
+		success     bool
+	)
+	for _, resp := range responses {
+		failures.Add(utilsStringPointerToString(resp.HTTPRequest.Failure))
+		if resp.HTTPRequest.Failure != nil {
+			continue
+		}
+		statusCodes.Add(resp.HTTPRequest.StatusCode)
+		if !success {
+			merged, success = resp.HTTPRequest, true
+		}
+	}
+	if failures.Len() > 1 {
+		disagreements.Flags |= ControlDisagreementHTTPFailure
+	}
+	if statusCodes.Len() > 1 {
+		disagreements.Flags |= ControlDisagreementHTTPStatusCode
+	}
+	return merged
+}
+
+// controlMergeHTTP3Request merges the optional HTTP3 results of the test helpers.
+func controlMergeHTTP3Request(responses []*model.THResponse) (merged *model.THHTTPRequestResult) {
+	for _, resp := range responses {
+		if resp.HTTP3Request == nil {
+			continue
+		}
+		if merged == nil || (merged.Failure != nil && resp.HTTP3Request.Failure == nil) {
+			merged = resp.HTTP3Request
+		}
+	}
+	return
+}
+
+// controlMergeIPInfo merges the IP addresses information of the test helpers.
+func controlMergeIPInfo(responses []*model.THResponse) map[string]*model.THIPInfo {
+	merged := map[string]*model.THIPInfo{}
+	for _, resp := range responses {
+		for addr, info := range resp.IPInfo {
+			if info == nil {
+				continue
+			}
+			previous, found := merged[addr]
+			if !found {
+				merged[addr] = &model.THIPInfo{ASN: info.ASN, Flags: info.Flags}
+				continue
+			}
+			previous.Flags |= info.Flags
+		}
+	}
+	return merged
+}
+
+// IngestControlResults is like [*WebObservationsContainer.IngestControlMessages] except
+// that it ingests the results of several test helpers queried in parallel. We merge the
+// successful responses using [MergeControlResults], ingest the merged response, and then
+// set the ControlDisagreements field of each observation. This method does nothing when
+// none of the test helpers succeeded. You MUST call this method last, after you've
+// ingested all the other measurement events.
+//
+// This method fails if req.HTTPRequest is not a valid serialized URL.
+func (c *WebObservationsContainer) IngestControlResults(req *model.THRequest, results ...*model.THResult) error {
+	resp, disagreements := MergeControlResults(results...)
+	if resp == nil {
+		return nil
+	}
+	if err := c.IngestControlMessages(req, resp); err != nil {
+		return err
+	}
+	c.controlMarkDisagreements(disagreements)
+	return nil
+}
+
+func (c *WebObservationsContainer) controlMarkDisagreements(disagreements *ControlDisagreements) {
+	for _, obs := range c.DNSLookupFailures {
+		obs.ControlDisagreements = optional.Some(disagreements.Flags)
+	}
+	for _, obs := range c.DNSLookupSuccesses {
+		obs.ControlDisagreements = optional.Some(disagreements.Flags)
+	}
+	for _, obs := range c.KnownTCPEndpoints {
+		flags := disagreements.Endpoints[obs.EndpointAddress.Unwrap()]
+		obs.ControlDisagreements = optional.Some(disagreements.Flags | flags)
+	}
+	for _, obs := range c.EndpointFetches {
+		flags := disagreements.Endpoints[obs.EndpointAddress.Unwrap()]
+		obs.ControlDisagreements = optional.Some(disagreements.Flags | flags)
+	}
+}
@@ -0,0 +1,245 @@
+package minipipeline
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+func TestMergeControlResults(t *testing.T) {
+	// newFailure returns a pointer to a copy of the given failure.
+	newFailure := func(failure string) *string {
+		return &failure
+	}
+
+	// newResponse returns a successful response using the given addrs and status code.
+	newResponse := func(statusCode int64, addrs ...string) *model.THResponse {
+		resp := &model.THResponse{
+			TCPConnect:    map[string]model.THTCPConnectResult{},
+			TLSHandshake:  map[string]model.THTLSHandshakeResult{},
+			QUICHandshake: map[string]model.THTLSHandshakeResult{},
+			HTTPRequest: model.THHTTPRequestResult{
+				StatusCode: statusCode,
+			},
+			DNS: model.THDNSResult{
+				Addrs: addrs,
+			},
+			IPInfo: map[string]*model.THIPInfo{},
+		}
+		for _, addr := range addrs {
+			resp.TCPConnect[addr+":443"] = model.THTCPConnectResult{Status: true}
+			resp.TLSHandshake[addr+":443"] = model.THTLSHandshakeResult{ServerName: "www.example.com", Status: true}
+			resp.IPInfo[addr] = &model.THIPInfo{ASN: 15133, Flags: model.THIPInfoFlagResolvedByTH}
+		}
+		return resp
+	}
+
+	t.Run("without successful results", func(t *testing.T) {
+		results := []*model.THResult{nil, {
+			Failure: newFailure(netxlite.FailureConnectionReset),
+		}}
+		resp, disagreements := MergeControlResults(results...)
+		if resp != nil {
+			t.Fatal("expected nil response")
+		}
+		if disagreements.Flags != 0 || len(disagreements.Endpoints) != 0 {
+			t.Fatal("expected no disagreements")
+		}
+	})
+
+	t.Run("with test helpers agreeing with each other", func(t *testing.T) {
+		results := []*model.THResult{{
+			Response: newResponse(200, "93.184.216.34", "93.184.216.35"),
+		}, {
+			Failure: newFailure(netxlite.FailureGenericTimeoutError),
+		}, {
+			Response: newResponse(200, "93.184.216.35", "93.184.216.36"),
+		}}
+		resp, disagreements := MergeControlResults(results...)
+		if disagreements.Flags != 0 || len(disagreements.Endpoints) != 0 {
+			t.Fatal("expected no disagreements", disagreements)
+		}
+		expectAddrs := []string{"93.184.216.34", "93.184.216.35", "93.184.216.36"}
+		if diff := cmp.Diff(expectAddrs, resp.DNS.Addrs); diff != "" {
+			t.Fatal(diff)
+		}
+		if len(resp.TCPConnect) != 3 || len(resp.TLSHandshake) != 3 || len(resp.IPInfo) != 3 {
+			t.Fatal("expected the union of the endpoints")
+		}
+		if resp.HTTPRequest.StatusCode != 200 {
+			t.Fatal("unexpected status code")
+		}
+	})
+
+	t.Run("with test helpers disagreeing with each other", func(t *testing.T) {
+		// the first TH is censored
+		first := newResponse(0, "10.10.34.35")
+		first.TCPConnect["10.10.34.35:443"] = model.THTCPConnectResult{
+			Failure: newFailure(netxlite.FailureGenericTimeoutError),
+		}
+		first.TLSHandshake = map[string]model.THTLSHandshakeResult{}
+		first.HTTPRequest.Failure = newFailure(netxlite.FailureGenericTimeoutError)
+		first.HTTP3Request = &model.THHTTPRequestResult{
+			Failure: newFailure(netxlite.FailureGenericTimeoutError),
+		}
+
+		// the second TH fails to resolve the domain
+		second := newResponse(403)
+		second.DNS.Failure = newFailure(netxlite.FailureDNSNXDOMAINError)
+
+		// the third TH works as intended
+		third := newResponse(200, "93.184.216.34")
+		third.TCPConnect["10.10.34.35:443"] = model.THTCPConnectResult{Status: true}
+		third.HTTP3Request = &model.THHTTPRequestResult{StatusCode: 200}
+		third.IPInfo["10.10.34.35"] = &model.THIPInfo{Flags: model.THIPInfoFlagIsBogon}
+
+		results := []*model.THResult{{Response: first}, {Response: second}, {Response: third}}
+		resp, disagreements := MergeControlResults(results...)
+
+		expectFlags := int64(ControlDisagreementDNSFailure | ControlDisagreementDNSAddrs |
+			ControlDisagreementHTTPFailure | ControlDisagreementHTTPStatusCode)
+		if disagreements.Flags != expectFlags {
+			t.Fatal("unexpected flags", disagreements.Flags)
+		}
+		expectEndpoints := map[string]int64{"10.10.34.35:443": ControlDisagreementTCPConnect}
+		if diff := cmp.Diff(expectEndpoints, disagreements.Endpoints); diff != "" {
+			t.Fatal(diff)
+		}
+
+		if resp.DNS.Failure != nil || len(resp.DNS.Addrs) != 2 {
+			t.Fatal("unexpected DNS result", resp.DNS)
+		}
+		if !resp.TCPConnect["10.10.34.35:443"].Status {
+			t.Fatal("expected the successful TCP connect result")
+		}
+		if resp.HTTPRequest.Failure != nil || resp.HTTPRequest.StatusCode != 403 {
+			t.Fatal("expected the first successful HTTP result", resp.HTTPRequest)
+		}
+		if resp.HTTP3Request == nil || resp.HTTP3Request.Failure != nil {
+			t.Fatal("expected the successful HTTP3 result")
+		}
+		if resp.IPInfo["10.10.34.35"].Flags != model.THIPInfoFlagResolvedByTH|model.THIPInfoFlagIsBogon {
+			t.Fatal("expected the union of the IP info flags")
+		}
+		if first.IPInfo["10.10.34.35"].Flags != model.THIPInfoFlagResolvedByTH {
+			t.Fatal("we should not modify the input")
+		}
+	})
+}
+
+func TestWebObservationsContainerIngestControlResults(t *testing.T) {
+	// newContainer returns a container with a DNS lookup and two endpoints.
+	newContainer := func() *WebObservationsContainer {
+		return &WebObservationsContainer{
+			DNSLookupFailures: []*WebObservation{},
+			DNSLookupSuccesses: []*WebObservation{{
+				DNSDomain: optional.Some("www.example.com"),
+				IPAddress: optional.Some("93.184.216.34"),
+			}},
+			KnownTCPEndpoints: map[int64]*WebObservation{
+				1: {
+					DNSDomain:       optional.Some("www.example.com"),
+					IPAddress:       optional.Some("93.184.216.34"),
+					EndpointAddress: optional.Some("93.184.216.34:443"),
+				},
+				2: {
+					DNSDomain:       optional.Some("www.example.com"),
+					IPAddress:       optional.Some("93.184.216.34"),
+					EndpointAddress: optional.Some("93.184.216.34:80"),
+				},
+			},
+			knownIPAddresses: map[string]*WebObservation{},
+		}
+	}
+
+	thRequest := &model.THRequest{
+		HTTPRequest: "https://www.example.com/",
+	}
+
+	failure := netxlite.FailureConnectionRefused
+	results := []*model.THResult{{
+		Response: &model.THResponse{
+			TCPConnect: map[string]model.THTCPConnectResult{
+				"93.184.216.34:443": {Status: false, Failure: &failure},
+			},
+			HTTPRequest: model.THHTTPRequestResult{StatusCode: 200},
+			DNS:         model.THDNSResult{Addrs: []string{"93.184.216.34"}},
+		},
+	}, {
+		Response: &model.THResponse{
+			TCPConnect: map[string]model.THTCPConnectResult{
+				"93.184.216.34:443": {Status: true},
+			},
+			HTTPRequest: model.THHTTPRequestResult{StatusCode: 200},
+			DNS:         model.THDNSResult{Addrs: []string{"93.184.216.34"}},
+		},
+	}}
+
+	t.Run("we mark the disagreements", func(t *testing.T) {
+		container := newContainer()
+		if err := container.IngestControlResults(thRequest, results...); err != nil {
+			t.Fatal(err)
+		}
+		if container.DNSLookupSuccesses[0].ControlDisagreements.UnwrapOr(-1) != 0 {
+			t.Fatal("unexpected DNS disagreements")
+		}
+		if container.KnownTCPEndpoints[1].ControlDisagreements.UnwrapOr(-1) != ControlDisagreementTCPConnect {
+			t.Fatal("unexpected endpoint disagreements")
+		}
+		if container.KnownTCPEndpoints[1].ControlTCPConnectFailure.UnwrapOr("x") != "" {
+			t.Fatal("expected the merged TCP connect result")
+		}
+		if container.KnownTCPEndpoints[2].ControlDisagreements.UnwrapOr(-1) != 0 {
+			t.Fatal("unexpected endpoint disagreements")
+		}
+	})
+
+	t.Run("we do nothing without successful results", func(t *testing.T) {
+		container := newContainer()
+		if err := container.IngestControlResults(thRequest, &model.THResult{Failure: &failure}); err != nil {
+			t.Fatal(err)
+		}
+		if !container.ControlExpectations.IsNone() {
+			t.Fatal("expected no control expectations")
+		}
+		if !container.KnownTCPEndpoints[1].ControlDisagreements.IsNone() {
+			t.Fatal("expected no disagreements")
+		}
+	})
+
+	t.Run("we fail with an invalid URL", func(t *testing.T) {
+		container := newContainer()
+		if err := container.IngestControlResults(&model.THRequest{HTTPRequest: "\t"}, results...); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("IngestWebMeasurement prefers the results of several test helpers", func(t *testing.T) {
+		meas := &WebMeasurement{
+			Input: "https://www.example.com/",
+			TestKeys: optional.Some(&WebMeasurementTestKeys{
+				Control:         optional.Some(results[0].Response),
+				XControlRequest: optional.Some(thRequest),
+				XControls:       results,
+			}),
+		}
+		container, err := IngestWebMeasurement(model.GeoIPASNLookupperFunc(geoipx.LookupASN), meas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectations := container.ControlExpectations.UnwrapOr(nil)
+		if expectations == nil {
+			t.Fatal("expected control expectations")
+		}
+		if diff := cmp.Diff([]string{"93.184.216.34"}, expectations.DNSAddresses.Keys()); diff != "" {
+			t.Fatal(diff)
+		}
+		if expectations.FinalResponseFailure.UnwrapOr("x") != "" {
+			t.Fatal("unexpected final response failure")
+		}
+	})
+}
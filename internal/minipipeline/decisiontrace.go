@@ -0,0 +1,89 @@
+package minipipeline
+
+//
+// Decision trace
+//
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+// DecisionTraceEntry is the evaluation of a rule by an algorithm that
+// uses a [*WebAnalysis] to determine whether there is blocking.
+type DecisionTraceEntry struct {
+	// Rule is the name of the rule we evaluated (e.g., "classic.blocking.1.2").
+	Rule string `json:"rule"`
+
+	// Inputs contains the values the rule depends on.
+	Inputs map[string]any `json:"inputs"`
+
+	// Outcome describes what happened because of the rule (e.g., "dns_blocking").
+	Outcome string `json:"outcome"`
+}
+
+// DecisionTrace is the ordered list of the rules that an algorithm evaluated to determine
+// whether there is blocking, which allows to understand the reason of a verdict, and
+// hence to debug false positives, without reading the algorithm's code.
+//
+// The zero value is ready to use.
+type DecisionTrace []*DecisionTraceEntry
+
+// Add appends a [*DecisionTraceEntry] to the [DecisionTrace].
+func (dt *DecisionTrace) Add(rule string, inputs map[string]any, outcome string) {
+	if inputs == nil {
+		inputs = map[string]any{}
+	}
+	*dt = append(*dt, &DecisionTraceEntry{
+		Rule:    rule,
+		Inputs:  inputs,
+		Outcome: outcome,
+	})
+}
+
+// WriteText writes a human readable representation of the [DecisionTrace] to the given writer.
+func (dt DecisionTrace) WriteText(w io.Writer) {
+	for idx, entry := range dt {
+		fmt.Fprintf(w, "#%d %s => %s\n", idx, entry.Rule, entry.Outcome)
+		keys := make([]string, 0, len(entry.Inputs))
+		for key := range entry.Inputs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "    %s: %v\n", key, entry.Inputs[key])
+		}
+	}
+}
+
+// DecisionTraceInputsFromObservation returns the [*WebObservation] fields that the
+// rules determining whether there is blocking commonly depend on, skipping the fields
+// that are None, for inclusion into a [*DecisionTraceEntry].
+func DecisionTraceInputsFromObservation(obs *WebObservation) map[string]any {
+	inputs := map[string]any{
+		"transaction_id": obs.TransactionID,
+		"type":           obs.Type,
+	}
+	decisionTraceMaybeAdd(inputs, "tag_depth", obs.TagDepth)
+	decisionTraceMaybeAdd(inputs, "failure", obs.Failure)
+	decisionTraceMaybeAdd(inputs, "endpoint_address", obs.EndpointAddress)
+	decisionTraceMaybeAdd(inputs, "http_response_status_code", obs.HTTPResponseStatusCode)
+	decisionTraceMaybeAdd(inputs, "http_response_blockpage_fingerprint", obs.HTTPResponseBlockpageFingerprint)
+	decisionTraceMaybeAdd(inputs, "tls_handshake_failure", obs.TLSHandshakeFailure)
+	decisionTraceMaybeAdd(inputs, "control_dns_lookup_failure", obs.ControlDNSLookupFailure)
+	decisionTraceMaybeAdd(inputs, "control_tcp_connect_failure", obs.ControlTCPConnectFailure)
+	decisionTraceMaybeAdd(inputs, "control_tls_handshake_failure", obs.ControlTLSHandshakeFailure)
+	decisionTraceMaybeAdd(inputs, "control_http_failure", obs.ControlHTTPFailure)
+	decisionTraceMaybeAdd(inputs, "control_http_response_blockpage_fingerprint", obs.ControlHTTPResponseBlockpageFingerprint)
+	return inputs
+}
+
+// decisionTraceMaybeAdd adds the given value to the inputs unless it is None.
+func decisionTraceMaybeAdd[T any](inputs map[string]any, key string, value optional.Value[T]) {
+	if !value.IsNone() {
+		inputs[key] = value.Unwrap()
+	}
+}
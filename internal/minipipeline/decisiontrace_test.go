@@ -0,0 +1,65 @@
+package minipipeline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+func TestDecisionTrace(t *testing.T) {
+	t.Run("Add appends entries in order", func(t *testing.T) {
+		var dt DecisionTrace
+		dt.Add("first", nil, "skip")
+		dt.Add("second", map[string]any{"transactions": []int64{1, 2}}, "dns_blocking")
+		expect := DecisionTrace{{
+			Rule:    "first",
+			Inputs:  map[string]any{},
+			Outcome: "skip",
+		}, {
+			Rule:    "second",
+			Inputs:  map[string]any{"transactions": []int64{1, 2}},
+			Outcome: "dns_blocking",
+		}}
+		if diff := cmp.Diff(expect, dt); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("WriteText sorts the inputs", func(t *testing.T) {
+		var dt DecisionTrace
+		dt.Add("classic.blocking.4.3", map[string]any{"failure": "connection_refused", "control_tcp_connect_failure": ""}, "tcp_ip")
+		var sb strings.Builder
+		dt.WriteText(&sb)
+		expect := "#0 classic.blocking.4.3 => tcp_ip\n" +
+			"    control_tcp_connect_failure: \n" +
+			"    failure: connection_refused\n"
+		if diff := cmp.Diff(expect, sb.String()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestDecisionTraceInputsFromObservation(t *testing.T) {
+	obs := &WebObservation{
+		TagDepth:                 optional.Some[int64](0),
+		Type:                     WebObservationTypeTCPConnect,
+		Failure:                  optional.Some(netxlite.FailureConnectionRefused),
+		TransactionID:            4,
+		EndpointAddress:          optional.Some("93.184.216.34:443"),
+		ControlTCPConnectFailure: optional.Some(""),
+	}
+	expect := map[string]any{
+		"transaction_id":              int64(4),
+		"type":                        WebObservationTypeTCPConnect,
+		"tag_depth":                   int64(0),
+		"failure":                     netxlite.FailureConnectionRefused,
+		"endpoint_address":            "93.184.216.34:443",
+		"control_tcp_connect_failure": "",
+	}
+	if diff := cmp.Diff(expect, DecisionTraceInputsFromObservation(obs)); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,166 @@
+package minipipeline
+
+//
+// Blockpage fingerprints
+//
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// These are the locations where a [*Fingerprint] applies.
+const (
+	// FingerprintLocationBody means the fingerprint pattern is a regular
+	// expression matching the HTTP response body.
+	FingerprintLocationBody = "body"
+
+	// FingerprintLocationDNS means the fingerprint pattern is an IP address
+	// returned by a DNS lookup.
+	FingerprintLocationDNS = "dns"
+
+	// FingerprintLocationHeader means the fingerprint pattern is a regular
+	// expression matching the value of the given HTTP response header.
+	FingerprintLocationHeader = "header"
+)
+
+// Fingerprint is an entry inside the [*FingerprintDB].
+type Fingerprint struct {
+	// ID uniquely identifies this fingerprint.
+	ID string `json:"id"`
+
+	// CC is the country code where this fingerprint was observed. We only match
+	// a fingerprint when the probe country code is the same, which reduces the
+	// chances of matching a legitimate webpage in another country.
+	CC string `json:"cc"`
+
+	// Location is one of the FingerprintLocationXXX constants.
+	Location string `json:"location"`
+
+	// Header is the header name when Location is [FingerprintLocationHeader].
+	Header string `json:"header,omitempty"`
+
+	// Pattern is either an IP address or a regular expression depending on Location.
+	Pattern string `json:"pattern"`
+
+	// re is the compiled regular expression for body and header fingerprints.
+	re *regexp.Regexp
+}
+
+// FingerprintDB is a database of known blockpage fingerprints. Matching a fingerprint
+// allows us to say that there is confirmed blocking as opposed to just suspecting blocking
+// based on the heuristics that compare the measurement with the control.
+//
+// The zero value is invalid; construct using [LoadFingerprintDB].
+type FingerprintDB struct {
+	// Version is the version of the database.
+	Version string `json:"version"`
+
+	// Fingerprints contains the fingerprints.
+	Fingerprints []*Fingerprint `json:"fingerprints"`
+
+	// dns maps an IP address to the corresponding fingerprints.
+	dns map[string][]*Fingerprint
+}
+
+// ErrInvalidFingerprint indicates that a fingerprint inside the database is invalid.
+var ErrInvalidFingerprint = errors.New("minipipeline: invalid fingerprint")
+
+// LoadFingerprintDB loads a [*FingerprintDB] from its JSON serialization.
+func LoadFingerprintDB(data []byte) (*FingerprintDB, error) {
+	var db FingerprintDB
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, err
+	}
+	db.dns = make(map[string][]*Fingerprint)
+	for _, fp := range db.Fingerprints {
+		if fp == nil || fp.ID == "" || fp.CC == "" || fp.Pattern == "" {
+			return nil, ErrInvalidFingerprint
+		}
+		switch fp.Location {
+		case FingerprintLocationDNS:
+			db.dns[fp.Pattern] = append(db.dns[fp.Pattern], fp)
+
+		case FingerprintLocationHeader, FingerprintLocationBody:
+			if fp.Location == FingerprintLocationHeader && fp.Header == "" {
+				return nil, fmt.Errorf("%w: %s: missing header", ErrInvalidFingerprint, fp.ID)
+			}
+			re, err := regexp.Compile(fp.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %s", ErrInvalidFingerprint, fp.ID, err.Error())
+			}
+			fp.re = re
+
+		default:
+			return nil, fmt.Errorf("%w: %s: unknown location %q", ErrInvalidFingerprint, fp.ID, fp.Location)
+		}
+	}
+	return &db, nil
+}
+
+//go:embed fingerprints.json
+var fingerprintsData []byte
+
+// DefaultFingerprintDB is the [*FingerprintDB] embedded into this package.
+var DefaultFingerprintDB = runtimex.Try1(LoadFingerprintDB(fingerprintsData))
+
+// MatchDNS returns the ID of the fingerprint for the given probe country code matching
+// the given IP address returned by a DNS lookup or None when there is no match.
+func (db *FingerprintDB) MatchDNS(probeCC, ipAddr string) optional.Value[string] {
+	for _, fp := range db.dns[ipAddr] {
+		if fp.appliesTo(probeCC) {
+			return optional.Some(fp.ID)
+		}
+	}
+	return optional.None[string]()
+}
+
+// MatchHTTP returns the ID of the first fingerprint for the given probe country code
+// matching the given HTTP response headers or body or None when there is no match.
+func (db *FingerprintDB) MatchHTTP(probeCC string,
+	headers map[string]model.ArchivalScrubbedMaybeBinaryString, body string) optional.Value[string] {
+	for _, fp := range db.Fingerprints {
+		if !fp.appliesTo(probeCC) {
+			continue
+		}
+		switch fp.Location {
+		case FingerprintLocationBody:
+			if fp.re.MatchString(body) {
+				return optional.Some(fp.ID)
+			}
+
+		case FingerprintLocationHeader:
+			for key, value := range headers {
+				if strings.EqualFold(key, fp.Header) && fp.re.MatchString(string(value)) {
+					return optional.Some(fp.ID)
+				}
+			}
+		}
+	}
+	return optional.None[string]()
+}
+
+// appliesTo returns whether we should match this fingerprint given the probe country code.
+func (fp *Fingerprint) appliesTo(probeCC string) bool {
+	return strings.EqualFold(fp.CC, probeCC)
+}
+
+// HTTPResponseBlockpageFingerprintIsConclusive returns whether the blockpage fingerprint
+// matched by the HTTP response inside the given [*WebObservation] allows us to say that
+// blocking is confirmed. To this end, the response must have been received over cleartext
+// HTTP, since a censor cannot forge a response inside a TLS session without triggering a
+// certificate error, and the control must not have matched the same fingerprint, since
+// in such a case we are measuring the website hosting the blockpage.
+func HTTPResponseBlockpageFingerprintIsConclusive(obs *WebObservation) bool {
+	return !obs.HTTPResponseBlockpageFingerprint.IsNone() &&
+		utilsHTTPRequestIsCleartext(obs) &&
+		obs.ControlHTTPResponseBlockpageFingerprint.IsNone()
+}
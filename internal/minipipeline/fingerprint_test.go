@@ -0,0 +1,194 @@
+package minipipeline
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+func TestLoadFingerprintDB(t *testing.T) {
+	t.Run("the default database is valid", func(t *testing.T) {
+		if DefaultFingerprintDB.Version == "" || len(DefaultFingerprintDB.Fingerprints) <= 0 {
+			t.Fatal("expected a non-empty versioned database")
+		}
+		ids := NewSet[string]()
+		for _, fp := range DefaultFingerprintDB.Fingerprints {
+			if ids.Contains(fp.ID) {
+				t.Fatal("duplicate fingerprint ID", fp.ID)
+			}
+			ids.Add(fp.ID)
+		}
+	})
+
+	t.Run("the default database patterns are anchored", func(t *testing.T) {
+		blockpage := `<html><iframe src="http://warning.or.kr/i1.html"></iframe></html>`
+		if DefaultFingerprintDB.MatchHTTP("KR", nil, blockpage).UnwrapOr("") != "http_kr_001" {
+			t.Fatal("expected a match")
+		}
+		legitimate := `<html><a href="https://warning.or.kr.example.com/">news</a></html>`
+		if !DefaultFingerprintDB.MatchHTTP("KR", nil, legitimate).IsNone() {
+			t.Fatal("expected no match")
+		}
+	})
+
+	t.Run("we fail with invalid JSON", func(t *testing.T) {
+		db, err := LoadFingerprintDB([]byte("{"))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if db != nil {
+			t.Fatal("expected nil database")
+		}
+	})
+
+	invalid := map[string]string{
+		"missing ID":       `{"fingerprints":[{"cc":"XX","location":"dns","pattern":"10.10.34.35"}]}`,
+		"missing CC":       `{"fingerprints":[{"id":"x","location":"dns","pattern":"10.10.34.35"}]}`,
+		"missing pattern":  `{"fingerprints":[{"id":"x","cc":"XX","location":"dns"}]}`,
+		"unknown location": `{"fingerprints":[{"id":"x","cc":"XX","location":"tls","pattern":"x"}]}`,
+		"missing header":   `{"fingerprints":[{"id":"x","cc":"XX","location":"header","pattern":"x"}]}`,
+		"invalid regexp":   `{"fingerprints":[{"id":"x","cc":"XX","location":"body","pattern":"("}]}`,
+	}
+	for name, data := range invalid {
+		t.Run("we fail with "+name, func(t *testing.T) {
+			db, err := LoadFingerprintDB([]byte(data))
+			if !errors.Is(err, ErrInvalidFingerprint) {
+				t.Fatal("unexpected error", err)
+			}
+			if db != nil {
+				t.Fatal("expected nil database")
+			}
+		})
+	}
+}
+
+func TestFingerprintDBMatch(t *testing.T) {
+	db, err := LoadFingerprintDB([]byte(`{
+		"version": "1",
+		"fingerprints": [
+			{"id": "dns_xx_001", "cc": "XX", "location": "dns", "pattern": "10.10.34.35"},
+			{"id": "http_xx_001", "cc": "XX", "location": "body", "pattern": "blocked by order"},
+			{"id": "http_xx_002", "cc": "XX", "location": "header", "header": "Location", "pattern": "^http://blockpage\\.xx/"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("MatchDNS", func(t *testing.T) {
+		if db.MatchDNS("XX", "10.10.34.35").UnwrapOr("") != "dns_xx_001" {
+			t.Fatal("expected a match")
+		}
+		if db.MatchDNS("xx", "10.10.34.35").UnwrapOr("") != "dns_xx_001" {
+			t.Fatal("expected a match regardless of the case of the probe CC")
+		}
+		if !db.MatchDNS("IT", "10.10.34.35").IsNone() {
+			t.Fatal("expected no match for another country")
+		}
+		if !db.MatchDNS("XX", "93.184.216.34").IsNone() {
+			t.Fatal("expected no match")
+		}
+	})
+
+	t.Run("MatchHTTP", func(t *testing.T) {
+		type testcase struct {
+			name    string
+			probeCC string
+			headers map[string]model.ArchivalScrubbedMaybeBinaryString
+			body    string
+			expect  string
+		}
+
+		cases := []testcase{{
+			name:    "with matching body",
+			probeCC: "XX",
+			headers: map[string]model.ArchivalScrubbedMaybeBinaryString{},
+			body:    "<html>this website has been blocked by order of the court</html>",
+			expect:  "http_xx_001",
+		}, {
+			name:    "with matching body and another probe CC",
+			probeCC: "IT",
+			headers: map[string]model.ArchivalScrubbedMaybeBinaryString{},
+			body:    "<html>this website has been blocked by order of the court</html>",
+			expect:  "",
+		}, {
+			name:    "with matching header regardless of its case",
+			probeCC: "XX",
+			headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
+				"location": "http://blockpage.xx/?site=example.com",
+			},
+			body:   "",
+			expect: "http_xx_002",
+		}, {
+			name:    "with matching value for another header",
+			probeCC: "XX",
+			headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
+				"Referer": "http://blockpage.xx/",
+			},
+			body:   "",
+			expect: "",
+		}, {
+			name:    "without any match",
+			probeCC: "XX",
+			headers: nil,
+			body:    "<html>Example Domain</html>",
+			expect:  "",
+		}}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				if got := db.MatchHTTP(tc.probeCC, tc.headers, tc.body).UnwrapOr(""); got != tc.expect {
+					t.Fatal("expected", tc.expect, "got", got)
+				}
+			})
+		}
+	})
+}
+
+func TestHTTPResponseBlockpageFingerprintIsConclusive(t *testing.T) {
+	type testcase struct {
+		name   string
+		obs    *WebObservation
+		expect bool
+	}
+
+	cases := []testcase{{
+		name: "without any match",
+		obs: &WebObservation{
+			HTTPRequestURL: optional.Some("http://www.example.com/"),
+		},
+		expect: false,
+	}, {
+		name: "with a match over cleartext HTTP",
+		obs: &WebObservation{
+			HTTPRequestURL:                   optional.Some("http://www.example.com/"),
+			HTTPResponseBlockpageFingerprint: optional.Some("http_kr_001"),
+		},
+		expect: true,
+	}, {
+		name: "with a match over HTTPS",
+		obs: &WebObservation{
+			HTTPRequestURL:                   optional.Some("https://www.example.com/"),
+			HTTPResponseBlockpageFingerprint: optional.Some("http_kr_001"),
+		},
+		expect: false,
+	}, {
+		name: "with a match that the control also matched",
+		obs: &WebObservation{
+			HTTPRequestURL:                          optional.Some("http://warning.or.kr/"),
+			HTTPResponseBlockpageFingerprint:        optional.Some("http_kr_001"),
+			ControlHTTPResponseBlockpageFingerprint: optional.Some("http_kr_001"),
+		},
+		expect: false,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := HTTPResponseBlockpageFingerprintIsConclusive(tc.obs); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
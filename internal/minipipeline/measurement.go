@@ -10,6 +10,9 @@ type WebMeasurement struct {
 	// Input contains the input we measured (a URL).
 	Input string `json:"input"`
 
+	// ProbeCC contains the country code of the probe.
+	ProbeCC string `json:"probe_cc"`
+
 	// TestKeys contains the test-specific measurements.
 	TestKeys optional.Value[*WebMeasurementTestKeys] `json:"test_keys"`
 }
@@ -39,9 +42,43 @@ type WebMeasurementTestKeys struct {
 	// TLSHandshakes contains the TLS handshakes results.
 	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
 
+	// HappyEyeballs contains the OPTIONAL happy eyeballs results.
+	HappyEyeballs []*model.ArchivalHappyEyeballsResult `json:"x_happy_eyeballs,omitempty"`
+
 	// QUICHandshakes contains the QUIC handshakes results.
 	QUICHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshakes"`
 
+	// XDNSDuplicateResponses contains the OPTIONAL duplicate DNS responses that are
+	// not attached to any entry of Queries, which happens with the measurements
+	// collected by Web Connectivity LTE v0.5.29 and v0.5.30. We attach them to
+	// the matching Queries entries when ingesting the measurement.
+	XDNSDuplicateResponses []*model.ArchivalDNSLookupResult `json:"x_dns_duplicate_responses,omitempty"`
+
 	// XControlRequest contains the OPTIONAL TH request.
 	XControlRequest optional.Value[*model.THRequest] `json:"x_control_request"`
+
+	// XControls contains the OPTIONAL results of querying several THs in parallel, in
+	// which case we ingest a merged view of their responses instead of Control.
+	XControls []*model.THResult `json:"x_controls,omitempty"`
+
+	// XAnalysisTrace contains the OPTIONAL ordered list of the rules evaluated by
+	// the probe to determine whether there is blocking.
+	XAnalysisTrace DecisionTrace `json:"x_analysis_trace,omitempty"`
+
+	// XEndpointFetches contains the OPTIONAL results of fetching the webpage
+	// using every endpoint rather than just the one with the highest priority.
+	XEndpointFetches optional.Value[*WebMeasurementEndpointFetches] `json:"x_endpoint_fetches"`
+}
+
+// WebMeasurementEndpointFetches contains the results of fetching the webpage using
+// every endpoint, which Web Connectivity LTE collects when configured to do so.
+type WebMeasurementEndpointFetches struct {
+	// Version is the version of this data format.
+	Version int64 `json:"version"`
+
+	// QUICHandshakes contains the QUIC handshakes results.
+	QUICHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshakes"`
+
+	// Requests contains HTTP request results.
+	Requests []*model.ArchivalHTTPRequestResult `json:"requests"`
 }
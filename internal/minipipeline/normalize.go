@@ -17,6 +17,7 @@ func NormalizeDNSLookupResults(values []*model.ArchivalDNSLookupResult) {
 		entry.T0 = 0
 		entry.T = 0
 		entry.RawResponse = nil
+		NormalizeDNSLookupResults(entry.DuplicateResponses)
 	}
 }
 
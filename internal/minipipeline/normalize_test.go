@@ -49,6 +49,30 @@ func TestNormalizeDNSLookupResults(t *testing.T) {
 			Engine:          "doh",
 			ResolverAddress: "https://dns.google/dns-query",
 		}},
+	}, {
+		name: "with duplicate responses",
+		inputGen: func() []*model.ArchivalDNSLookupResult {
+			return []*model.ArchivalDNSLookupResult{{
+				DuplicateResponses: []*model.ArchivalDNSLookupResult{{
+					Engine:      "udp",
+					RawResponse: []byte("0xdeadbeef"),
+					T0:          0.11,
+					T:           0.7,
+				}},
+				Engine:      "udp",
+				RawResponse: []byte("0xdeadbeef"),
+				T0:          0.11,
+				T:           0.4,
+			}}
+		},
+		expect: []*model.ArchivalDNSLookupResult{{
+			DuplicateResponses: []*model.ArchivalDNSLookupResult{{
+				Engine:          "udp",
+				ResolverAddress: "1.1.1.1:53",
+			}},
+			Engine:          "udp",
+			ResolverAddress: "1.1.1.1:53",
+		}},
 	}}
 
 	for _, tc := range cases {
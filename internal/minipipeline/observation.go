@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net"
 	"net/url"
+	"slices"
 	"strconv"
 
 	"github.com/ooni/probe-cli/v3/internal/measurexlite"
@@ -29,10 +30,27 @@ func IngestWebMeasurement(lookupper model.GeoIPASNLookupper, meas *WebMeasuremen
 	}
 
 	container := NewWebObservationsContainer()
-	container.IngestDNSLookupEvents(lookupper, tk.Queries...)
+	container.ProbeCC = meas.ProbeCC
+	container.IngestDNSLookupEvents(lookupper, webMeasurementQueries(tk)...)
 	container.IngestTCPConnectEvents(lookupper, tk.TCPConnect...)
 	container.IngestTLSHandshakeEvents(tk.TLSHandshakes...)
 	container.IngestHTTPRoundTripEvents(tk.Requests...)
+	container.IngestHappyEyeballsEvents(tk.HappyEyeballs...)
+
+	// ingest the results of fetching the webpage using every endpoint, if any
+	if fetches := tk.XEndpointFetches.UnwrapOr(nil); fetches != nil {
+		container.IngestEndpointFetchQUICHandshakeEvents(lookupper, fetches.QUICHandshakes...)
+		container.IngestEndpointFetchHTTPRoundTripEvents(fetches.Requests...)
+	}
+
+	// prefer the results of several test helpers when they are available
+	if !tk.XControlRequest.IsNone() && len(tk.XControls) > 0 {
+		// Implementation note: see below for the only error that can happen here.
+		if err := container.IngestControlResults(tk.XControlRequest.Unwrap(), tk.XControls...); err != nil {
+			return nil, err
+		}
+		return container, nil
+	}
 
 	// be defensive in case the control request or control are not defined
 	if !tk.XControlRequest.IsNone() && !tk.Control.IsNone() {
@@ -47,6 +65,23 @@ func IngestWebMeasurement(lookupper model.GeoIPASNLookupper, meas *WebMeasuremen
 	return container, nil
 }
 
+// webMeasurementQueries returns the queries to ingest, to which we attach the duplicate
+// responses that are not attached to any query yet. We do not modify the queries of the
+// given test keys and we return copies of the queries to which we attach responses.
+func webMeasurementQueries(tk *WebMeasurementTestKeys) []*model.ArchivalDNSLookupResult {
+	if len(tk.XDNSDuplicateResponses) <= 0 {
+		return tk.Queries
+	}
+	var queries []*model.ArchivalDNSLookupResult
+	for _, query := range tk.Queries {
+		clone := *query
+		clone.DuplicateResponses = slices.Clone(query.DuplicateResponses)
+		queries = append(queries, &clone)
+	}
+	measurexlite.AttachDNSDuplicateResponses(queries, tk.XDNSDuplicateResponses...)
+	return queries
+}
+
 // WebObservationType is the type of a [*WebObservation].
 type WebObservationType int64
 
@@ -143,6 +178,10 @@ type WebObservation struct {
 	// DNSResolvedAddrs contains the list of DNS-resolved addrs.
 	DNSResolvedAddrs optional.Value[Set[string]]
 
+	// DNSInjection is true when we received duplicate responses for the DNS lookup
+	// containing addresses different from the ones of the first response.
+	DNSInjection optional.Value[bool]
+
 	// The following fields are optional.Some in these cases:
 	//
 	// 1. when you process successful DNS lookup events from OONI measurements;
@@ -168,6 +207,10 @@ type WebObservation struct {
 	// IPAddressBogon is true if IPAddress is a bogon.
 	IPAddressBogon optional.Value[bool]
 
+	// IPAddressBlockpageFingerprint is the optional ID of the fingerprint inside the
+	// [DefaultFingerprintDB] matching the IPAddress returned by the DNS lookup.
+	IPAddressBlockpageFingerprint optional.Value[string]
+
 	// The following fields are optional.Some when you process the TCP
 	// connect events contained inside an OONI measurement:
 
@@ -196,6 +239,10 @@ type WebObservation struct {
 	// TLSServerName is the optional TLS server name used by the TLS handshake.
 	TLSServerName optional.Value[string]
 
+	// TLSLeafSPKISHA256 is the optional hash of the leaf certificate public key (see
+	// [measurexlite.TLSLeafSPKISHA256]), which is present only when we have certificates.
+	TLSLeafSPKISHA256 optional.Value[string]
+
 	// The following fields are optional.Some when you process the HTTP round
 	// trip events contained inside an OONI measurement:
 
@@ -214,6 +261,13 @@ type WebObservation struct {
 	// HTTPResponseBodyIsTruncated indicates whether the response body was truncated.
 	HTTPResponseBodyIsTruncated optional.Value[bool]
 
+	// HTTPResponseBodySHA256 is the hex-encoded SHA-256 of the response body.
+	HTTPResponseBodySHA256 optional.Value[string]
+
+	// HTTPResponseBodySimhash is the hex-encoded simhash of the response body (see
+	// [measurexlite.WebBodySimhash]), which is None when the body contains no words.
+	HTTPResponseBodySimhash optional.Value[string]
+
 	// HTTPResponseHeadersKeys contains maps response headers keys to true.
 	HTTPResponseHeadersKeys optional.Value[map[string]bool]
 
@@ -226,6 +280,12 @@ type WebObservation struct {
 	// HTTPResponseIsFinal is true if the status code is 2xx, 4xx, or 5xx.
 	HTTPResponseIsFinal optional.Value[bool]
 
+	// HTTPResponseBlockpageFingerprint is the optional ID of the fingerprint inside the
+	// [DefaultFingerprintDB] matching the response headers or body. Use the
+	// [HTTPResponseBlockpageFingerprintIsConclusive] function to determine whether
+	// this match allows us to say that blocking is confirmed.
+	HTTPResponseBlockpageFingerprint optional.Value[string]
+
 	// The following fields are optional.Some when you process the control information
 	// contained inside a measurement and there's information available:
 
@@ -246,6 +306,10 @@ type WebObservation struct {
 	// ControlTLSHandshakeFailure is the control's TLS handshake failure.
 	ControlTLSHandshakeFailure optional.Value[string]
 
+	// ControlTLSLeafSPKISHA256 is the hash of the leaf certificate public key seen by
+	// the control, which is present only when the control returned certificates.
+	ControlTLSLeafSPKISHA256 optional.Value[string]
+
 	// ControlHTTPFailure is the HTTP failure seen by the control.
 	ControlHTTPFailure optional.Value[string]
 
@@ -255,11 +319,39 @@ type WebObservation struct {
 	// ControlHTTPResponseBodyLength contains the control HTTP response body length.
 	ControlHTTPResponseBodyLength optional.Value[int64]
 
+	// ControlHTTPResponseBodySHA256 contains the SHA-256 of the control HTTP response body.
+	ControlHTTPResponseBodySHA256 optional.Value[string]
+
+	// ControlHTTPResponseBodySimhash contains the simhash of the control HTTP response body.
+	ControlHTTPResponseBodySimhash optional.Value[string]
+
 	// ControlHTTPResponseHeadersKeys contains the response headers keys.
 	ControlHTTPResponseHeadersKeys optional.Value[map[string]bool]
 
 	// ControlHTTPResponseTitle contains the title seen by the control.
 	ControlHTTPResponseTitle optional.Value[string]
+
+	// ControlHTTPResponseBlockpageFingerprint is the optional ID of the fingerprint inside
+	// the [DefaultFingerprintDB] matching the control response. Because the control does not
+	// send us the body, we only match the headers and we otherwise consider matching the
+	// fingerprint matched by the probe when the control body has the same SHA-256.
+	ControlHTTPResponseBlockpageFingerprint optional.Value[string]
+
+	// ControlHTTPRedirectHopIndex is the index of the response for the same URL inside
+	// the redirect chain followed by the control. This field is optional.Some only when the
+	// control returned the redirect chain and such a chain contains the same URL.
+	ControlHTTPRedirectHopIndex optional.Value[int64]
+
+	// ControlHTTPRedirectHopStatusCode is the status code of such a response.
+	ControlHTTPRedirectHopStatusCode optional.Value[int64]
+
+	// ControlHTTPRedirectHopLocation is the location of such a response.
+	ControlHTTPRedirectHopLocation optional.Value[string]
+
+	// ControlDisagreements contains flags describing how the test helpers disagree with each
+	// other (e.g., [ControlDisagreementDNSFailure]). This field is optional.Some only when we
+	// ingest the results of several test helpers using [*WebObservationsContainer.IngestControlResults].
+	ControlDisagreements optional.Value[int64]
 }
 
 // WebObservationsControlExpectations summarizes the expectations based on the control.
@@ -292,12 +384,28 @@ type WebObservationsContainer struct {
 	// KnownTCPEndpoints maps transaction IDs to TCP observations.
 	KnownTCPEndpoints map[int64]*WebObservation
 
+	// EndpointFetches maps transaction IDs to the observations of fetching the webpage
+	// using a specific TCP or QUIC endpoint, which Web Connectivity LTE collects when
+	// fetching the webpage using every endpoint. We keep these observations separate
+	// from KnownTCPEndpoints because these fetches do not follow redirects and hence
+	// we must not consider them when analyzing the final response.
+	EndpointFetches map[int64]*WebObservation
+
+	// ProbeCC is the country code of the probe, which we use to select the blockpage
+	// fingerprints to match. You MUST set this field before ingesting any event, otherwise
+	// we will not match any blockpage fingerprint.
+	ProbeCC string
+
 	// ControlExpectations summarizes the expectations we have based on the control results.
 	ControlExpectations optional.Value[*WebObservationsControlExpectations]
 
 	// knownIPAddresses is an internal field that maps an IP address to the
 	// corresponding DNS observation that discovered it.
 	knownIPAddresses map[string]*WebObservation
+
+	// happyEyeballsIPv4Domains is an internal field containing the domains for
+	// which an happy eyeballs dialer successfully connected using IPv4.
+	happyEyeballsIPv4Domains map[string]bool
 }
 
 // NewWebObservationsContainer constructs a [*WebObservationsContainer].
@@ -306,7 +414,10 @@ func NewWebObservationsContainer() *WebObservationsContainer {
 		DNSLookupFailures:  []*WebObservation{},
 		DNSLookupSuccesses: []*WebObservation{},
 		KnownTCPEndpoints:  map[int64]*WebObservation{},
+		EndpointFetches:    map[int64]*WebObservation{},
 		knownIPAddresses:   map[string]*WebObservation{},
+
+		happyEyeballsIPv4Domains: map[string]bool{},
 	}
 }
 
@@ -336,6 +447,7 @@ func (c *WebObservationsContainer) ingestDNSLookupFailures(evs ...*model.Archiva
 			DNSLookupFailure: failure,
 			DNSQueryType:     optional.Some(ev.QueryType),
 			DNSEngine:        optional.Some(ev.Engine),
+			DNSInjection:     optional.Some(measurexlite.DNSLookupResultHasInjection(ev)),
 			TagDepth:         utilsExtractTagDepth(ev.Tags),
 		}
 
@@ -366,12 +478,14 @@ func (c *WebObservationsContainer) ingestDNSLookupSuccesses(
 				DNSQueryType:     optional.Some(ev.QueryType),
 				DNSEngine:        optional.Some(ev.Engine),
 				DNSResolvedAddrs: optional.Some(addrs),
+				DNSInjection:     optional.Some(measurexlite.DNSLookupResultHasInjection(ev)),
 				IPAddressOrigin:  optional.Some(IPAddressOriginDNS),
 				IPAddress:        optional.Some(ipAddr),
 				IPAddressASN:     utilsGeoipxLookupASN(lookupper, ipAddr),
 				IPAddressBogon:   optional.Some(netxlite.IsBogon(ipAddr)),
 				TagDepth:         utilsExtractTagDepth(ev.Tags),
 			}
+			obs.IPAddressBlockpageFingerprint = DefaultFingerprintDB.MatchDNS(c.ProbeCC, ipAddr)
 
 			// add record
 			c.DNSLookupSuccesses = append(c.DNSLookupSuccesses, obs)
@@ -389,6 +503,12 @@ func (c *WebObservationsContainer) ingestDNSLookupSuccesses(
 func (c *WebObservationsContainer) IngestTCPConnectEvents(
 	lookupper model.GeoIPASNLookupper, evs ...*model.ArchivalTCPConnectResult) {
 	for _, ev := range evs {
+		// skip the happy eyeballs attempts, which share the same transaction ID
+		// and which we analyze using [IngestHappyEyeballsEvents]
+		if utilsTagsContainHappyEyeballs(ev.Tags) {
+			continue
+		}
+
 		// create or fetch a record
 		obs, found := c.knownIPAddresses[ev.IP]
 		if !found {
@@ -407,24 +527,25 @@ func (c *WebObservationsContainer) IngestTCPConnectEvents(
 		portString := strconv.Itoa(ev.Port)
 		failure := optional.Some(utilsStringPointerToString(ev.Status.Failure))
 		obs = &WebObservation{
-			Type:                  WebObservationTypeTCPConnect,
-			Failure:               failure,
-			TransactionID:         ev.TransactionID,
-			DNSTransactionID:      obs.DNSTransactionID,
-			DNSDomain:             obs.DNSDomain,
-			DNSLookupFailure:      obs.DNSLookupFailure,
-			DNSResolvedAddrs:      obs.DNSResolvedAddrs,
-			IPAddressOrigin:       obs.IPAddressOrigin,
-			IPAddress:             obs.IPAddress,
-			IPAddressASN:          obs.IPAddressASN,
-			IPAddressBogon:        obs.IPAddressBogon,
-			EndpointTransactionID: optional.Some(ev.TransactionID),
-			EndpointProto:         optional.Some("tcp"),
-			EndpointPort:          optional.Some(portString),
-			EndpointAddress:       optional.Some(net.JoinHostPort(ev.IP, portString)),
-			TCPConnectFailure:     failure,
-			TagDepth:              utilsExtractTagDepth(ev.Tags),
-			TagFetchBody:          utilsExtractTagFetchBody(ev.Tags),
+			Type:                          WebObservationTypeTCPConnect,
+			Failure:                       failure,
+			TransactionID:                 ev.TransactionID,
+			DNSTransactionID:              obs.DNSTransactionID,
+			DNSDomain:                     obs.DNSDomain,
+			DNSLookupFailure:              obs.DNSLookupFailure,
+			DNSResolvedAddrs:              obs.DNSResolvedAddrs,
+			IPAddressOrigin:               obs.IPAddressOrigin,
+			IPAddress:                     obs.IPAddress,
+			IPAddressASN:                  obs.IPAddressASN,
+			IPAddressBogon:                obs.IPAddressBogon,
+			IPAddressBlockpageFingerprint: obs.IPAddressBlockpageFingerprint,
+			EndpointTransactionID:         optional.Some(ev.TransactionID),
+			EndpointProto:                 optional.Some("tcp"),
+			EndpointPort:                  optional.Some(portString),
+			EndpointAddress:               optional.Some(net.JoinHostPort(ev.IP, portString)),
+			TCPConnectFailure:             failure,
+			TagDepth:                      utilsExtractTagDepth(ev.Tags),
+			TagFetchBody:                  utilsExtractTagFetchBody(ev.Tags),
 		}
 
 		// register the observation
@@ -448,6 +569,27 @@ func (c *WebObservationsContainer) IngestTLSHandshakeEvents(evs ...*model.Archiv
 		obs.Failure = failure
 		obs.TLSHandshakeFailure = failure
 		obs.TLSServerName = optional.Some(ev.ServerName)
+
+		// register the leaf public key hash, which we compute when reprocessing
+		// measurements collected before we started including it
+		spki := ev.LeafSPKISHA256
+		if spki == "" {
+			spki = measurexlite.TLSLeafSPKISHA256(ev.PeerCertificates)
+		}
+		if spki != "" {
+			obs.TLSLeafSPKISHA256 = optional.Some(spki)
+		}
+	}
+}
+
+// IngestHappyEyeballsEvents ingests happy eyeballs events from a OONI measurement. We use
+// these events to avoid flagging IPv6 TCP connect failures as unexpected for domains where
+// an happy eyeballs dialer (see RFC 8305) would have successfully fallen back to IPv4.
+func (c *WebObservationsContainer) IngestHappyEyeballsEvents(evs ...*model.ArchivalHappyEyeballsResult) {
+	for _, ev := range evs {
+		if ev.Failure == nil && ev.WinnerFamily == "ipv4" {
+			c.happyEyeballsIPv4Domains[ev.Domain] = true
+		}
 	}
 }
 
@@ -461,29 +603,131 @@ func (c *WebObservationsContainer) IngestHTTPRoundTripEvents(evs ...*model.Archi
 			continue
 		}
 
-		// start updating the record
+		// update the record
+		webObservationIngestHTTPRoundTrip(c.ProbeCC, obs, ev)
+	}
+}
+
+// webObservationIngestHTTPRoundTrip updates the given observation using the given event.
+func webObservationIngestHTTPRoundTrip(probeCC string, obs *WebObservation, ev *model.ArchivalHTTPRequestResult) {
+	// start updating the record
+	failure := optional.Some(utilsStringPointerToString(ev.Failure))
+	obs.Type = WebObservationTypeHTTPRoundTrip
+	obs.Failure = failure
+	obs.HTTPRequestURL = optional.Some(ev.Request.URL)
+	obs.HTTPFailure = failure
+
+	// consider the response authoritative only in case of success
+	if ev.Failure == nil {
+		obs.HTTPResponseStatusCode = optional.Some(ev.Response.Code)
+		obs.HTTPResponseBodyLength = optional.Some(int64(len(ev.Response.Body)))
+		obs.HTTPResponseBodyIsTruncated = optional.Some(ev.Response.BodyIsTruncated)
+		obs.HTTPResponseBodySHA256 = optional.Some(measurexlite.WebBodySHA256([]byte(ev.Response.Body)))
+		obs.HTTPResponseBodySimhash = utilsStringToOptional(measurexlite.WebBodySimhash([]byte(ev.Response.Body)))
+		obs.HTTPResponseHeadersKeys = utilsExtractHTTPHeaderKeys(ev.Response.Headers)
+		obs.HTTPResponseTitle = optional.Some(measurexlite.WebGetTitle(string(ev.Response.Body)))
+		obs.HTTPResponseLocation = utilsExtractHTTPLocation(ev.Response.Headers)
+		obs.HTTPResponseIsFinal = utilsDetermineWhetherHTTPResponseIsFinal(ev.Response.Code)
+		obs.HTTPResponseBlockpageFingerprint = DefaultFingerprintDB.MatchHTTP(
+			probeCC, ev.Response.Headers, string(ev.Response.Body))
+	}
+}
+
+// IngestEndpointFetchQUICHandshakeEvents ingests the QUIC handshake events that Web
+// Connectivity LTE collects when fetching the webpage using every endpoint. You MUST
+// ingest these events after DNS events and before endpoint fetch HTTP events.
+func (c *WebObservationsContainer) IngestEndpointFetchQUICHandshakeEvents(
+	lookupper model.GeoIPASNLookupper, evs ...*model.ArchivalTLSOrQUICHandshakeResult) {
+	for _, ev := range evs {
+		// obtain the IP address and the port
+		ipAddr, portString, err := net.SplitHostPort(ev.Address)
+		if err != nil {
+			continue
+		}
+
+		// create or fetch a record
+		obs, found := c.knownIPAddresses[ipAddr]
+		if !found {
+			obs = &WebObservation{
+				IPAddressOrigin: optional.None[string](), // we don't know!
+				IPAddress:       optional.Some(ipAddr),
+				IPAddressASN:    utilsGeoipxLookupASN(lookupper, ipAddr),
+				IPAddressBogon:  optional.Some(netxlite.IsBogon(ipAddr)),
+			}
+		}
+
+		// clone the record because the same IP address MAY belong
+		// to multiple endpoints across the same measurement
+		//
+		// while there also fill endpoint specific info
 		failure := optional.Some(utilsStringPointerToString(ev.Failure))
-		obs.Type = WebObservationTypeHTTPRoundTrip
-		obs.Failure = failure
-		obs.HTTPRequestURL = optional.Some(ev.Request.URL)
-		obs.HTTPFailure = failure
+		obs = &WebObservation{
+			Type:                          WebObservationTypeTLSHandshake,
+			Failure:                       failure,
+			TransactionID:                 ev.TransactionID,
+			DNSTransactionID:              obs.DNSTransactionID,
+			DNSDomain:                     obs.DNSDomain,
+			DNSLookupFailure:              obs.DNSLookupFailure,
+			DNSResolvedAddrs:              obs.DNSResolvedAddrs,
+			IPAddressOrigin:               obs.IPAddressOrigin,
+			IPAddress:                     obs.IPAddress,
+			IPAddressASN:                  obs.IPAddressASN,
+			IPAddressBogon:                obs.IPAddressBogon,
+			IPAddressBlockpageFingerprint: obs.IPAddressBlockpageFingerprint,
+			EndpointTransactionID:         optional.Some(ev.TransactionID),
+			EndpointProto:                 optional.Some("udp"),
+			EndpointPort:                  optional.Some(portString),
+			EndpointAddress:               optional.Some(net.JoinHostPort(ipAddr, portString)),
+			TLSHandshakeFailure:           failure,
+			TLSServerName:                 optional.Some(ev.ServerName),
+			TagDepth:                      utilsExtractTagDepth(ev.Tags),
+		}
+		spki := ev.LeafSPKISHA256
+		if spki == "" {
+			spki = measurexlite.TLSLeafSPKISHA256(ev.PeerCertificates)
+		}
+		if spki != "" {
+			obs.TLSLeafSPKISHA256 = optional.Some(spki)
+		}
 
-		// consider the response authoritative only in case of success
-		if ev.Failure == nil {
-			obs.HTTPResponseStatusCode = optional.Some(ev.Response.Code)
-			obs.HTTPResponseBodyLength = optional.Some(int64(len(ev.Response.Body)))
-			obs.HTTPResponseBodyIsTruncated = optional.Some(ev.Response.BodyIsTruncated)
-			obs.HTTPResponseHeadersKeys = utilsExtractHTTPHeaderKeys(ev.Response.Headers)
-			obs.HTTPResponseTitle = optional.Some(measurexlite.WebGetTitle(string(ev.Response.Body)))
-			obs.HTTPResponseLocation = utilsExtractHTTPLocation(ev.Response.Headers)
-			obs.HTTPResponseIsFinal = utilsDetermineWhetherHTTPResponseIsFinal(ev.Response.Code)
+		// register the observation
+		c.EndpointFetches[ev.TransactionID] = obs
+	}
+}
+
+// IngestEndpointFetchHTTPRoundTripEvents ingests the HTTP round trip events that Web
+// Connectivity LTE collects when fetching the webpage using every endpoint. We create
+// each observation by copying the corresponding TCP or QUIC endpoint observation and
+// we store it into EndpointFetches. You MUST ingest these events after ingesting the
+// TLS handshake events and the endpoint fetch QUIC handshake events.
+func (c *WebObservationsContainer) IngestEndpointFetchHTTPRoundTripEvents(evs ...*model.ArchivalHTTPRequestResult) {
+	for _, ev := range evs {
+		// find the corresponding obs, which we copy when it's a TCP endpoint
+		// because we do not want to modify the TCP observation
+		obs, found := c.EndpointFetches[ev.TransactionID]
+		if !found {
+			tcpObs, found := c.KnownTCPEndpoints[ev.TransactionID]
+			if !found {
+				continue
+			}
+			clone := *tcpObs
+			obs = &clone
+			c.EndpointFetches[ev.TransactionID] = obs
 		}
+
+		// update the record
+		webObservationIngestHTTPRoundTrip(c.ProbeCC, obs, ev)
 	}
 }
 
 // IngestControlMessages ingests the control request and response. You MUST call
 // this method last, after you've ingested all the other measurement events.
 //
+// When the control resolved the domain using additional resolvers, we consider as
+// resolved by the control the union of the addresses returned by the main lookup and
+// by the additional resolvers that succeeded, such that we do not flag as inconsistent
+// addresses that are legitimate but that the main resolver did not return.
+//
 // This method fails if req.HTTPRequest is not a valid serialized URL.
 func (c *WebObservationsContainer) IngestControlMessages(req *model.THRequest, resp *model.THResponse) error {
 	URL, err := url.Parse(req.HTTPRequest)
@@ -496,6 +740,8 @@ func (c *WebObservationsContainer) IngestControlMessages(req *model.THRequest, r
 	c.controlMatchDNSLookupResults(inputDomain, resp)
 	c.controlXrefTCPIPFailures(resp)
 	c.controlXrefTLSFailures(resp)
+	c.controlXrefEndpointFetches(resp)
+	c.controlXrefHTTPRedirectChain(resp)
 	c.controlSetHTTPFinalResponseExpectation(resp)
 
 	return nil
@@ -521,7 +767,7 @@ func (c *WebObservationsContainer) controlXrefDNSQueries(inputDomain string, res
 		}
 
 		// register the resolved IP addresses
-		obs.ControlDNSResolvedAddrs = optional.Some(NewSet(resp.DNS.Addrs...))
+		obs.ControlDNSResolvedAddrs = optional.Some(NewSet(resp.DNSResolvedAddrs()...))
 	}
 }
 
@@ -546,7 +792,7 @@ func (c *WebObservationsContainer) controlMatchDNSLookupResults(inputDomain stri
 			obs.IPAddressOrigin = optional.Some(IPAddressOriginTH)
 			obs.ControlDNSDomain = optional.Some(inputDomain)
 			obs.ControlDNSLookupFailure = optional.Some(utilsStringPointerToString(resp.DNS.Failure))
-			obs.ControlDNSResolvedAddrs = optional.Some(NewSet(resp.DNS.Addrs...))
+			obs.ControlDNSResolvedAddrs = optional.Some(NewSet(resp.DNSResolvedAddrs()...))
 			continue
 		}
 
@@ -565,7 +811,7 @@ func (c *WebObservationsContainer) controlMatchDNSLookupResults(inputDomain stri
 		}
 
 		// register the resolved IP addresses
-		obs.ControlDNSResolvedAddrs = optional.Some(NewSet(resp.DNS.Addrs...))
+		obs.ControlDNSResolvedAddrs = optional.Some(NewSet(resp.DNSResolvedAddrs()...))
 	}
 }
 
@@ -609,6 +855,65 @@ func (c *WebObservationsContainer) controlXrefTLSFailures(resp *model.THResponse
 
 		// save the corresponding control result
 		obs.ControlTLSHandshakeFailure = optional.Some(utilsStringPointerToString(tls.Failure))
+
+		// save the control's leaf public key hash, if available
+		if tls.LeafSPKISHA256 != "" {
+			obs.ControlTLSLeafSPKISHA256 = optional.Some(tls.LeafSPKISHA256)
+		}
+	}
+}
+
+func (c *WebObservationsContainer) controlXrefEndpointFetches(resp *model.THResponse) {
+	for _, obs := range c.EndpointFetches {
+		endpointAddress := obs.EndpointAddress.Unwrap()
+		serverName := obs.TLSServerName.UnwrapOr("")
+
+		// QUIC endpoints only have a QUIC handshake result
+		if obs.EndpointProto.UnwrapOr("") == "udp" {
+			if quic, found := resp.QUICHandshake[endpointAddress]; found && quic.ServerName == serverName {
+				obs.ControlTLSHandshakeFailure = optional.Some(utilsStringPointerToString(quic.Failure))
+			}
+			continue
+		}
+
+		// TCP endpoints have a TCP connect result and possibly a TLS handshake result
+		if tcp, found := resp.TCPConnect[endpointAddress]; found {
+			obs.ControlTCPConnectFailure = optional.Some(utilsStringPointerToString(tcp.Failure))
+		}
+		if tls, found := resp.TLSHandshake[endpointAddress]; found && serverName != "" && tls.ServerName == serverName {
+			obs.ControlTLSHandshakeFailure = optional.Some(utilsStringPointerToString(tls.Failure))
+			if tls.LeafSPKISHA256 != "" {
+				obs.ControlTLSLeafSPKISHA256 = optional.Some(tls.LeafSPKISHA256)
+			}
+		}
+	}
+}
+
+func (c *WebObservationsContainer) controlXrefHTTPRedirectChain(resp *model.THResponse) {
+	var observations []*WebObservation
+	for _, obs := range c.KnownTCPEndpoints {
+		observations = append(observations, obs)
+	}
+	for _, obs := range c.EndpointFetches {
+		observations = append(observations, obs)
+	}
+	for _, obs := range observations {
+		// skip entries without an HTTP request
+		if obs.HTTPRequestURL.IsNone() {
+			continue
+		}
+		requestURL := obs.HTTPRequestURL.Unwrap()
+
+		// search for the first response for the same URL
+		for idx, hop := range resp.HTTPRequest.RedirectChain {
+			if hop.URL != requestURL {
+				continue
+			}
+			obs.ControlHTTPRedirectHopIndex = optional.Some(int64(idx))
+			obs.ControlHTTPRedirectHopStatusCode = optional.Some(hop.StatusCode)
+			obs.ControlHTTPRedirectHopLocation = optional.Some(hop.Location)
+			break
+		}
 	}
 }
 
@@ -622,12 +927,15 @@ func (c *WebObservationsContainer) controlSetHTTPFinalResponseExpectation(resp *
 	for _, obs := range c.KnownTCPEndpoints {
 		observations = append(observations, obs)
 	}
+	for _, obs := range c.EndpointFetches {
+		observations = append(observations, obs)
+	}
 
 	// make sure we have a final expectation based on what the control observed, which
 	// is in turn necessary to figure out whether unexplained probe failures during redirects
 	// are expected or unexpected.
 	c.ControlExpectations = optional.Some(&WebObservationsControlExpectations{
-		DNSAddresses:         NewSet(resp.DNS.Addrs...),
+		DNSAddresses:         NewSet(resp.DNSResolvedAddrs()...),
 		FinalResponseFailure: optional.Some(utilsStringPointerToString(resp.HTTPRequest.Failure)),
 	})
 
@@ -642,7 +950,24 @@ func (c *WebObservationsContainer) controlSetHTTPFinalResponseExpectation(resp *
 
 		obs.ControlHTTPResponseStatusCode = optional.Some(resp.HTTPRequest.StatusCode)
 		obs.ControlHTTPResponseBodyLength = optional.Some(resp.HTTPRequest.BodyLength)
+		obs.ControlHTTPResponseBodySHA256 = utilsStringToOptional(resp.HTTPRequest.BodySHA256)
+		obs.ControlHTTPResponseBodySimhash = utilsStringToOptional(resp.HTTPRequest.BodySimhash)
 		obs.ControlHTTPResponseHeadersKeys = utilsExtractHTTPHeaderKeys(resp.HTTPRequest.Headers)
 		obs.ControlHTTPResponseTitle = optional.Some(resp.HTTPRequest.Title)
+		obs.ControlHTTPResponseBlockpageFingerprint = c.controlMatchBlockpageFingerprint(obs, resp)
+	}
+}
+
+func (c *WebObservationsContainer) controlMatchBlockpageFingerprint(
+	obs *WebObservation, resp *model.THResponse) optional.Value[string] {
+	// the control saw the same body, hence it would have matched the same fingerprint
+	if sha256 := obs.ControlHTTPResponseBodySHA256; !sha256.IsNone() &&
+		sha256.Unwrap() == obs.HTTPResponseBodySHA256.UnwrapOr("") {
+		return obs.HTTPResponseBlockpageFingerprint
+	}
+	headers := make(map[string]model.ArchivalScrubbedMaybeBinaryString)
+	for key, value := range resp.HTTPRequest.Headers {
+		headers[key] = model.ArchivalScrubbedMaybeBinaryString(value)
 	}
+	return DefaultFingerprintDB.MatchHTTP(c.ProbeCC, headers, "")
 }
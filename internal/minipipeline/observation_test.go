@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/geoipx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -56,6 +57,54 @@ func TestLoadWebObservations(t *testing.T) {
 			t.Fatal("expected nil container, got", container)
 		}
 	})
+
+	t.Run("we attach the duplicate responses that are not attached to any query", func(t *testing.T) {
+		query := &model.ArchivalDNSLookupResult{
+			Answers: []model.ArchivalDNSAnswer{{
+				AnswerType: "A",
+				IPv4:       "93.184.216.34",
+			}},
+			Engine:          "udp",
+			Hostname:        "www.example.com",
+			QueryType:       "A",
+			ResolverAddress: "8.8.8.8:53",
+			TransactionID:   1,
+		}
+		duplicate := &model.ArchivalDNSLookupResult{
+			Answers: []model.ArchivalDNSAnswer{{
+				AnswerType: "A",
+				IPv4:       "10.10.34.35",
+			}},
+			Engine:          "udp",
+			Hostname:        "www.example.com",
+			QueryType:       "A",
+			ResolverAddress: "8.8.8.8:53",
+			TransactionID:   1,
+		}
+		meas := &WebMeasurement{
+			Input: "https://www.example.com",
+			TestKeys: optional.Some(&WebMeasurementTestKeys{
+				Queries:                []*model.ArchivalDNSLookupResult{query},
+				XDNSDuplicateResponses: []*model.ArchivalDNSLookupResult{duplicate},
+			}),
+		}
+		container, err := IngestWebMeasurement(
+			model.GeoIPASNLookupperFunc(geoipx.LookupASN),
+			meas,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(container.DNSLookupSuccesses) != 1 {
+			t.Fatal("expected a single DNS lookup success")
+		}
+		if !container.DNSLookupSuccesses[0].DNSInjection.UnwrapOr(false) {
+			t.Fatal("expected to detect DNS injection")
+		}
+		if len(query.DuplicateResponses) != 0 {
+			t.Fatal("we should not modify the input queries")
+		}
+	})
 }
 
 func TestWebObservationsContainerIngestTLSHandshakeEvents(t *testing.T) {
@@ -210,4 +259,276 @@ func TestWebObservationsContainerIngestControlMessages(t *testing.T) {
 			t.Fatal("ControlDNSResolvedAddrs should be none")
 		}
 	})
+
+	t.Run("we include the addresses resolved by the additional control resolvers", func(t *testing.T) {
+		container := &WebObservationsContainer{
+			DNSLookupFailures: []*WebObservation{},
+			DNSLookupSuccesses: []*WebObservation{{
+				DNSDomain: optional.Some("dns.google"),
+				IPAddress: optional.Some("8.8.4.4"),
+			}},
+			KnownTCPEndpoints: map[int64]*WebObservation{
+				1: {
+					DNSDomain:             optional.Some("dns.google"),
+					IPAddress:             optional.Some("8.8.4.4"),
+					EndpointTransactionID: optional.Some(int64(1)),
+					EndpointPort:          optional.Some("443"),
+					EndpointAddress:       optional.Some("8.8.4.4:443"),
+				},
+			},
+			knownIPAddresses: map[string]*WebObservation{},
+		}
+
+		thRequest := &model.THRequest{
+			HTTPRequest: "https://dns.google/",
+		}
+
+		failure := netxlite.FailureGenericTimeoutError
+		thResponse := &model.THResponse{
+			DNS: model.THDNSResult{
+				Addrs: []string{"8.8.8.8"},
+			},
+			DNSResolvers: []model.THDNSResolverResult{{
+				Resolver: "udp://9.9.9.9:53",
+				Addrs:    []string{"8.8.4.4"},
+			}, {
+				Resolver: "dot://1.1.1.1:853",
+				Failure:  &failure,
+				Addrs:    []string{"10.10.34.35"},
+			}},
+		}
+
+		if err := container.IngestControlMessages(thRequest, thResponse); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := []string{"8.8.4.4", "8.8.8.8"}
+		for _, obs := range []*WebObservation{container.DNSLookupSuccesses[0], container.KnownTCPEndpoints[1]} {
+			if diff := cmp.Diff(expect, obs.ControlDNSResolvedAddrs.Unwrap().Keys()); diff != "" {
+				t.Fatal(diff)
+			}
+		}
+		if diff := cmp.Diff(expect, container.ControlExpectations.Unwrap().DNSAddresses.Keys()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestWebObservationsContainerIngestHappyEyeballsEvents(t *testing.T) {
+	// newContainer creates a container with a failed IPv6 TCP connect
+	// attempt for www.example.com happening while following redirects.
+	newContainer := func() *WebObservationsContainer {
+		container := NewWebObservationsContainer()
+		failure := netxlite.FailureGenericTimeoutError
+		container.IngestDNSLookupEvents(model.GeoIPASNLookupperFunc(geoipx.LookupASN), &model.ArchivalDNSLookupResult{
+			Answers: []model.ArchivalDNSAnswer{{
+				AnswerType: "AAAA",
+				IPv6:       "2001:db8::1",
+			}},
+			Engine:        "getaddrinfo",
+			Hostname:      "www.example.com",
+			QueryType:     "ANY",
+			TransactionID: 1,
+		})
+		container.IngestTCPConnectEvents(model.GeoIPASNLookupperFunc(geoipx.LookupASN), &model.ArchivalTCPConnectResult{
+			IP:   "2001:db8::1",
+			Port: 443,
+			Status: model.ArchivalTCPConnectStatus{
+				Failure: &failure,
+			},
+			Tags:          []string{"depth=1", "fetch_body=true"},
+			TransactionID: 2,
+		})
+		return container
+	}
+
+	t.Run("without happy eyeballs results we flag the failure", func(t *testing.T) {
+		wa := &WebAnalysis{}
+		wa.tcpComputeMetrics(ClassicFilter(newContainer()))
+		if !wa.TCPConnectUnexplainedFailure.Contains(2) {
+			t.Fatal("expected to see the failure")
+		}
+	})
+
+	t.Run("with happy eyeballs connecting using IPv4 we do not flag the failure", func(t *testing.T) {
+		container := newContainer()
+		container.IngestHappyEyeballsEvents(&model.ArchivalHappyEyeballsResult{
+			Domain:       "www.example.com",
+			Network:      "tcp",
+			Failure:      nil,
+			Winner:       "93.184.216.34:443",
+			WinnerFamily: "ipv4",
+		})
+		wa := &WebAnalysis{}
+		wa.tcpComputeMetrics(ClassicFilter(container))
+		if wa.TCPConnectUnexplainedFailure.Len() != 0 {
+			t.Fatal("expected to see no failures")
+		}
+	})
+
+	t.Run("we skip the happy eyeballs TCP connect attempts", func(t *testing.T) {
+		container := newContainer()
+		failure := netxlite.FailureGenericTimeoutError
+		container.IngestTCPConnectEvents(model.GeoIPASNLookupperFunc(geoipx.LookupASN), &model.ArchivalTCPConnectResult{
+			IP:   "2001:db8::1",
+			Port: 443,
+			Status: model.ArchivalTCPConnectStatus{
+				Failure: &failure,
+			},
+			Tags:          []string{"happy_eyeballs", "depth=0"},
+			TransactionID: 3,
+		})
+		if _, found := container.KnownTCPEndpoints[3]; found {
+			t.Fatal("expected to skip the happy eyeballs attempt")
+		}
+	})
+
+	t.Run("with happy eyeballs connecting using IPv6 we flag the failure", func(t *testing.T) {
+		container := newContainer()
+		container.IngestHappyEyeballsEvents(&model.ArchivalHappyEyeballsResult{
+			Domain:       "www.example.com",
+			Network:      "tcp",
+			Failure:      nil,
+			Winner:       "[2001:db8::2]:443",
+			WinnerFamily: "ipv6",
+		})
+		wa := &WebAnalysis{}
+		wa.tcpComputeMetrics(container)
+		if !wa.TCPConnectUnexplainedFailure.Contains(2) {
+			t.Fatal("expected to see the failure")
+		}
+	})
+}
+
+func TestWebObservationsContainerIngestEndpointFetchEvents(t *testing.T) {
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+	failure := netxlite.FailureGenericTimeoutError
+
+	// newMeasurement returns a measurement where we fetched the webpage using two TCP
+	// endpoints and where we failed to fetch the webpage using HTTP/3.
+	newMeasurement := func() *WebMeasurement {
+		return &WebMeasurement{
+			Input: "https://www.example.com/",
+			TestKeys: optional.Some(&WebMeasurementTestKeys{
+				Control: optional.Some(&model.THResponse{
+					TCPConnect: map[string]model.THTCPConnectResult{
+						"93.184.216.34:443": {Status: true},
+						"93.184.216.35:443": {Status: true},
+					},
+					QUICHandshake: map[string]model.THTLSHandshakeResult{
+						"93.184.216.34:443": {ServerName: "www.example.com", Status: true},
+					},
+					HTTPRequest: model.THHTTPRequestResult{StatusCode: 200},
+					DNS:         model.THDNSResult{Addrs: []string{"93.184.216.34", "93.184.216.35"}},
+				}),
+				Queries: []*model.ArchivalDNSLookupResult{{
+					Answers: []model.ArchivalDNSAnswer{{
+						AnswerType: "A",
+						IPv4:       "93.184.216.34",
+					}, {
+						AnswerType: "A",
+						IPv4:       "93.184.216.35",
+					}},
+					Engine:        "getaddrinfo",
+					Hostname:      "www.example.com",
+					QueryType:     "ANY",
+					TransactionID: 1,
+				}},
+				Requests: []*model.ArchivalHTTPRequestResult{{
+					Request:       model.ArchivalHTTPRequest{URL: "https://www.example.com/"},
+					Response:      model.ArchivalHTTPResponse{Code: 200},
+					Tags:          []string{"depth=0", "fetch_body=true"},
+					TransactionID: 2,
+				}},
+				TCPConnect: []*model.ArchivalTCPConnectResult{{
+					IP:            "93.184.216.34",
+					Port:          443,
+					Tags:          []string{"depth=0", "fetch_body=true"},
+					TransactionID: 2,
+				}, {
+					IP:            "93.184.216.35",
+					Port:          443,
+					Tags:          []string{"depth=0", "fetch_body=true"},
+					TransactionID: 3,
+				}},
+				XControlRequest: optional.Some(&model.THRequest{
+					HTTPRequest: "https://www.example.com/",
+				}),
+				XEndpointFetches: optional.Some(&WebMeasurementEndpointFetches{
+					Version: 1,
+					QUICHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{{
+						Address:       "93.184.216.34:443",
+						Failure:       &failure,
+						ServerName:    "www.example.com",
+						Tags:          []string{"http3", "depth=0"},
+						TransactionID: 4,
+					}},
+					Requests: []*model.ArchivalHTTPRequestResult{{
+						Request:       model.ArchivalHTTPRequest{URL: "https://www.example.com/"},
+						Response:      model.ArchivalHTTPResponse{Code: 403},
+						Tags:          []string{"depth=0", "fetch_body=true"},
+						TransactionID: 3,
+					}, {
+						Request:       model.ArchivalHTTPRequest{URL: "https://www.example.com/"},
+						Tags:          []string{"depth=0", "fetch_body=true"},
+						TransactionID: 1234, // unknown transaction
+					}},
+				}),
+			}),
+		}
+	}
+
+	t.Run("we ingest the endpoint fetches separately", func(t *testing.T) {
+		container, err := IngestWebMeasurement(lookupper, newMeasurement())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(container.EndpointFetches) != 2 {
+			t.Fatal("expected two endpoint fetches, got", len(container.EndpointFetches))
+		}
+
+		tcpObs := container.EndpointFetches[3]
+		if tcpObs.EndpointProto.UnwrapOr("") != "tcp" || tcpObs.HTTPResponseStatusCode.UnwrapOr(0) != 403 {
+			t.Fatal("unexpected TCP endpoint fetch", tcpObs)
+		}
+		if tcpObs.ControlHTTPResponseStatusCode.UnwrapOr(0) != 200 {
+			t.Fatal("expected to see the control status code")
+		}
+		if !container.KnownTCPEndpoints[3].HTTPResponseStatusCode.IsNone() {
+			t.Fatal("we should not modify the TCP endpoint observation")
+		}
+
+		quicObs := container.EndpointFetches[4]
+		if quicObs.EndpointProto.UnwrapOr("") != "udp" || quicObs.TLSHandshakeFailure.UnwrapOr("") != failure {
+			t.Fatal("unexpected QUIC endpoint fetch", quicObs)
+		}
+		if quicObs.DNSDomain.UnwrapOr("") != "www.example.com" {
+			t.Fatal("expected to know the domain we resolved")
+		}
+		if quicObs.ControlTLSHandshakeFailure.UnwrapOr("x") != "" {
+			t.Fatal("expected to see the control QUIC handshake result")
+		}
+
+		if len(ClassicFilter(container).EndpointFetches) != 0 {
+			t.Fatal("the classic filter should drop the endpoint fetches")
+		}
+	})
+
+	t.Run("the analysis flags failures and differences", func(t *testing.T) {
+		container, err := IngestWebMeasurement(lookupper, newMeasurement())
+		if err != nil {
+			t.Fatal(err)
+		}
+		wa := AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
+		if diff := cmp.Diff([]int64{4}, wa.HTTPEndpointFetchUnexpectedFailure.Keys()); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]int64{3}, wa.HTTPEndpointFetchDiff.Keys()); diff != "" {
+			t.Fatal(diff)
+		}
+		if wa.HTTPFinalResponseSuccessTLSWithControl.UnwrapOr(0) == 3 ||
+			wa.HTTPFinalResponseSuccessTCPWithControl.UnwrapOr(0) == 3 {
+			t.Fatal("the endpoint fetches should not be the final response")
+		}
+	})
 }
@@ -1,6 +1,8 @@
 package minipipeline
 
 import (
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -16,6 +18,14 @@ func utilsStringPointerToString(failure *string) (out string) {
 	return
 }
 
+// utilsStringToOptional returns None for an empty string and Some otherwise.
+func utilsStringToOptional(value string) optional.Value[string] {
+	if value == "" {
+		return optional.None[string]()
+	}
+	return optional.Some(value)
+}
+
 func utilsGeoipxLookupASN(lookupper model.GeoIPASNLookupper, ipAddress string) optional.Value[int64] {
 	if asn, _, err := lookupper.LookupASN(ipAddress); err == nil && asn > 0 {
 		return optional.Some(int64(asn))
@@ -101,6 +111,10 @@ func utilsExtractTagFetchBody(tags []string) (result optional.Value[bool]) {
 	return
 }
 
+func utilsTagsContainHappyEyeballs(tags []string) bool {
+	return slices.Contains(tags, "happy_eyeballs")
+}
+
 func utilsDNSLookupFailureIsDNSNoAnswerForAAAA(obs *WebObservation) bool {
 	return obs.DNSQueryType.UnwrapOr("") == "AAAA" &&
 		obs.DNSLookupFailure.UnwrapOr("") == netxlite.FailureDNSNoAnswer
@@ -124,3 +138,19 @@ func utilsTCPConnectFailureSeemsMisconfiguredIPv6(obs *WebObservation) bool {
 		return false
 	}
 }
+
+// utilsTCPConnectFailureHasIPv4Fallback returns whether this is a failed IPv6 TCP
+// connect attempt for a domain where an happy eyeballs dialer connected using IPv4,
+// meaning that the failure would not prevent a client from accessing the domain.
+func utilsTCPConnectFailureHasIPv4Fallback(c *WebObservationsContainer, obs *WebObservation) bool {
+	if obs.TCPConnectFailure.UnwrapOr("") == "" {
+		return false
+	}
+	isv6, err := netxlite.IsIPv6(obs.IPAddress.UnwrapOr(""))
+	return err == nil && isv6 && c.happyEyeballsIPv4Domains[obs.DNSDomain.UnwrapOr("")]
+}
+
+func utilsHTTPRequestIsCleartext(obs *WebObservation) bool {
+	URL, err := url.Parse(obs.HTTPRequestURL.UnwrapOr(""))
+	return err == nil && URL.Scheme == "http"
+}
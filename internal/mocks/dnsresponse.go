@@ -19,6 +19,7 @@ type DNSResponse struct {
 	MockDecodeLookupHost func() ([]string, error)
 	MockDecodeNS         func() ([]*net.NS, error)
 	MockDecodeCNAME      func() (string, error)
+	MockDecodeRecords    func() ([]*model.DNSRecord, error)
 }
 
 var _ model.DNSResponse = &DNSResponse{}
@@ -50,3 +51,7 @@ func (r *DNSResponse) DecodeNS() ([]*net.NS, error) {
 func (r *DNSResponse) DecodeCNAME() (string, error) {
 	return r.MockDecodeCNAME()
 }
+
+func (r *DNSResponse) DecodeRecords() ([]*model.DNSRecord, error) {
+	return r.MockDecodeRecords()
+}
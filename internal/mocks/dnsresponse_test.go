@@ -118,4 +118,20 @@ func TestDNSResponse(t *testing.T) {
 			t.Fatal("unexpected out")
 		}
 	})
+
+	t.Run("DecodeRecords", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &DNSResponse{
+			MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+				return nil, expected
+			},
+		}
+		out, err := r.DecodeRecords()
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if len(out) > 0 {
+			t.Fatal("unexpected out")
+		}
+	})
 }
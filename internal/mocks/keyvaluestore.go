@@ -7,6 +7,8 @@ type KeyValueStore struct {
 	MockGet func(key string) (value []byte, err error)
 
 	MockSet func(key string, value []byte) (err error)
+
+	MockDelete func(key string) (err error)
 }
 
 var _ model.KeyValueStore = &KeyValueStore{}
@@ -18,3 +20,7 @@ func (kvs *KeyValueStore) Get(key string) (value []byte, err error) {
 func (kvs *KeyValueStore) Set(key string, value []byte) (err error) {
 	return kvs.MockSet(key, value)
 }
+
+func (kvs *KeyValueStore) Delete(key string) (err error) {
+	return kvs.MockDelete(key)
+}
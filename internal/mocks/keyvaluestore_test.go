@@ -34,4 +34,17 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal("unexpected err", err)
 		}
 	})
+
+	t.Run("Delete", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		kvs := &KeyValueStore{
+			MockDelete: func(key string) (err error) {
+				return expect
+			},
+		}
+		err := kvs.Delete("antani")
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
 }
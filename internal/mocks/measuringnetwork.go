@@ -7,10 +7,14 @@ import (
 
 // MeasuringNetwork allows mocking [model.MeasuringNetwork].
 type MeasuringNetwork struct {
+	MockNewDialerHappyEyeballs func(dl model.DebugLogger, r model.Resolver) model.Dialer
+
 	MockNewDialerWithoutResolver func(dl model.DebugLogger, w ...model.DialerWrapper) model.Dialer
 
 	MockNewParallelDNSOverHTTPSResolver func(logger model.DebugLogger, URL string) model.Resolver
 
+	MockNewParallelDNSOverQUICResolver func(logger model.DebugLogger, address string) model.Resolver
+
 	MockNewParallelUDPResolver func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver
 
 	MockNewQUICDialerWithoutResolver func(listener model.UDPListener, logger model.DebugLogger, w ...model.QUICDialerWrapper) model.QUICDialer
@@ -26,6 +30,11 @@ type MeasuringNetwork struct {
 
 var _ model.MeasuringNetwork = &MeasuringNetwork{}
 
+// NewDialerHappyEyeballs implements model.MeasuringNetwork.
+func (mn *MeasuringNetwork) NewDialerHappyEyeballs(dl model.DebugLogger, r model.Resolver) model.Dialer {
+	return mn.MockNewDialerHappyEyeballs(dl, r)
+}
+
 // NewDialerWithoutResolver implements model.MeasuringNetwork.
 func (mn *MeasuringNetwork) NewDialerWithoutResolver(dl model.DebugLogger, w ...model.DialerWrapper) model.Dialer {
 	return mn.MockNewDialerWithoutResolver(dl, w...)
@@ -36,6 +45,11 @@ func (mn *MeasuringNetwork) NewParallelDNSOverHTTPSResolver(logger model.DebugLo
 	return mn.MockNewParallelDNSOverHTTPSResolver(logger, URL)
 }
 
+// NewParallelDNSOverQUICResolver implements model.MeasuringNetwork.
+func (mn *MeasuringNetwork) NewParallelDNSOverQUICResolver(logger model.DebugLogger, address string) model.Resolver {
+	return mn.MockNewParallelDNSOverQUICResolver(logger, address)
+}
+
 // NewParallelUDPResolver implements model.MeasuringNetwork.
 func (mn *MeasuringNetwork) NewParallelUDPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
 	return mn.MockNewParallelUDPResolver(logger, dialer, address)
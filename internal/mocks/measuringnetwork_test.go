@@ -8,6 +8,19 @@ import (
 )
 
 func TestMeasuringN(t *testing.T) {
+	t.Run("MockNewDialerHappyEyeballs", func(t *testing.T) {
+		expected := &Dialer{}
+		mn := &MeasuringNetwork{
+			MockNewDialerHappyEyeballs: func(dl model.DebugLogger, r model.Resolver) model.Dialer {
+				return expected
+			},
+		}
+		got := mn.NewDialerHappyEyeballs(nil, nil)
+		if expected != got {
+			t.Fatal("unexpected result")
+		}
+	})
+
 	t.Run("MockNewDialerWithoutResolver", func(t *testing.T) {
 		expected := &Dialer{}
 		mn := &MeasuringNetwork{
@@ -34,6 +47,19 @@ func TestMeasuringN(t *testing.T) {
 		}
 	})
 
+	t.Run("MockNewParallelDNSOverQUICResolver", func(t *testing.T) {
+		expected := &Resolver{}
+		mn := &MeasuringNetwork{
+			MockNewParallelDNSOverQUICResolver: func(logger model.DebugLogger, address string) model.Resolver {
+				return expected
+			},
+		}
+		got := mn.NewParallelDNSOverQUICResolver(nil, "")
+		if expected != got {
+			t.Fatal("unexpected result")
+		}
+	})
+
 	t.Run("MockNewParallelUDPResolver", func(t *testing.T) {
 		expected := &Resolver{}
 		mn := &MeasuringNetwork{
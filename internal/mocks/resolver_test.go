@@ -6,6 +6,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -95,4 +96,21 @@ func TestResolver(t *testing.T) {
 			t.Fatal("expected nil addr")
 		}
 	})
+
+	t.Run("LookupRecords", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &Resolver{
+			MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+				return nil, expected
+			},
+		}
+		ctx := context.Background()
+		records, err := r.LookupRecords(ctx, "dns.google", dns.TypeTXT)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if records != nil {
+			t.Fatal("expected nil records")
+		}
+	})
 }
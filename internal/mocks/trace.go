@@ -30,6 +30,9 @@ type Trace struct {
 	MockOnConnectDone func(
 		started time.Time, network, domain, remoteAddr string, err error, finished time.Time)
 
+	MockOnHappyEyeballsDone func(
+		started time.Time, network, domain, winner string, err error, finished time.Time)
+
 	MockOnTLSHandshakeStart func(now time.Time, remoteAddr string, config *tls.Config)
 
 	MockOnTLSHandshakeDone func(started time.Time, remoteAddr string, config *tls.Config,
@@ -39,6 +42,8 @@ type Trace struct {
 
 	MockOnQUICHandshakeDone func(started time.Time, remoteAddr string, qconn model.QUICConn,
 		config *tls.Config, err error, finished time.Time)
+
+	MockOnHTTP2Frame func(now time.Time, operation, network, remoteAddr string, frame *model.HTTP2Frame)
 }
 
 var _ model.Trace = &Trace{}
@@ -70,6 +75,11 @@ func (t *Trace) OnConnectDone(
 	t.MockOnConnectDone(started, network, domain, remoteAddr, err, finished)
 }
 
+func (t *Trace) OnHappyEyeballsDone(
+	started time.Time, network, domain, winner string, err error, finished time.Time) {
+	t.MockOnHappyEyeballsDone(started, network, domain, winner, err, finished)
+}
+
 func (t *Trace) OnTLSHandshakeStart(now time.Time, remoteAddr string, config *tls.Config) {
 	t.MockOnTLSHandshakeStart(now, remoteAddr, config)
 }
@@ -87,3 +97,7 @@ func (t *Trace) OnQUICHandshakeDone(started time.Time, remoteAddr string, qconn
 	config *tls.Config, err error, finished time.Time) {
 	t.MockOnQUICHandshakeDone(started, remoteAddr, qconn, config, err, finished)
 }
+
+func (t *Trace) OnHTTP2Frame(now time.Time, operation, network, remoteAddr string, frame *model.HTTP2Frame) {
+	t.MockOnHTTP2Frame(now, operation, network, remoteAddr, frame)
+}
@@ -115,6 +115,26 @@ func TestTrace(t *testing.T) {
 		}
 	})
 
+	t.Run("OnHappyEyeballsDone", func(t *testing.T) {
+		var called bool
+		tx := &Trace{
+			MockOnHappyEyeballsDone: func(started time.Time, network, domain, winner string, err error, finished time.Time) {
+				called = true
+			},
+		}
+		tx.OnHappyEyeballsDone(
+			time.Now(),
+			"tcp",
+			"dns.google",
+			"[2001:4860:4860::8888]:443",
+			nil,
+			time.Now(),
+		)
+		if !called {
+			t.Fatal("not called")
+		}
+	})
+
 	t.Run("OnTLSHandshakeStart", func(t *testing.T) {
 		var called bool
 		tx := &Trace{
@@ -180,4 +200,23 @@ func TestTrace(t *testing.T) {
 			t.Fatal("not called")
 		}
 	})
+
+	t.Run("OnHTTP2Frame", func(t *testing.T) {
+		var called bool
+		tx := &Trace{
+			MockOnHTTP2Frame: func(now time.Time, operation, network, remoteAddr string, frame *model.HTTP2Frame) {
+				called = true
+			},
+		}
+		tx.OnHTTP2Frame(
+			time.Now(),
+			"http2_read_frame",
+			"tcp",
+			"1.1.1.1:443",
+			&model.HTTP2Frame{},
+		)
+		if !called {
+			t.Fatal("not called")
+		}
+	})
 }
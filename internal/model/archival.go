@@ -179,21 +179,26 @@ func (value *ArchivalScrubbedMaybeBinaryString) UnmarshalJSON(rawData []byte) er
 //
 // See https://github.com/ooni/spec/blob/master/data-formats/df-002-dnst.md.
 type ArchivalDNSLookupResult struct {
-	Answers          []ArchivalDNSAnswer `json:"answers"`
-	Engine           string              `json:"engine"`
-	Failure          *string             `json:"failure"`
-	GetaddrinfoError int64               `json:"getaddrinfo_error,omitempty"`
-	Hostname         string              `json:"hostname"`
-	QueryType        string              `json:"query_type"`
-	RawResponse      []byte              `json:"raw_response,omitempty"`
-	Rcode            int64               `json:"rcode,omitempty"`
-	ResolverHostname *string             `json:"resolver_hostname"`
-	ResolverPort     *string             `json:"resolver_port"`
-	ResolverAddress  string              `json:"resolver_address"`
-	T0               float64             `json:"t0,omitempty"`
-	T                float64             `json:"t"`
-	Tags             []string            `json:"tags"`
-	TransactionID    int64               `json:"transaction_id,omitempty"`
+	Answers            []ArchivalDNSAnswer        `json:"answers"`
+	DNSSECFailure      *string                    `json:"dnssec_failure,omitempty"`
+	DNSSECStatus       string                     `json:"dnssec_status,omitempty"`
+	DuplicateResponses []*ArchivalDNSLookupResult `json:"duplicate_responses,omitempty"`
+	EDNS0Received      []ArchivalEDNS0            `json:"edns0_received,omitempty"`
+	EDNS0Sent          []ArchivalEDNS0            `json:"edns0_sent,omitempty"`
+	Engine             string                     `json:"engine"`
+	Failure            *string                    `json:"failure"`
+	GetaddrinfoError   int64                      `json:"getaddrinfo_error,omitempty"`
+	Hostname           string                     `json:"hostname"`
+	QueryType          string                     `json:"query_type"`
+	RawResponse        []byte                     `json:"raw_response,omitempty"`
+	Rcode              int64                      `json:"rcode,omitempty"`
+	ResolverHostname   *string                    `json:"resolver_hostname"`
+	ResolverPort       *string                    `json:"resolver_port"`
+	ResolverAddress    string                     `json:"resolver_address"`
+	T0                 float64                    `json:"t0,omitempty"`
+	T                  float64                    `json:"t"`
+	Tags               []string                   `json:"tags"`
+	TransactionID      int64                      `json:"transaction_id,omitempty"`
 }
 
 // ArchivalDNSAnswer is a DNS answer.
@@ -205,6 +210,14 @@ type ArchivalDNSAnswer struct {
 	IPv4       string  `json:"ipv4,omitempty"`
 	IPv6       string  `json:"ipv6,omitempty"`
 	TTL        *uint32 `json:"ttl"`
+	Value      string  `json:"value,omitempty"`
+}
+
+// ArchivalEDNS0 is an EDNS0 option sent or received along with a DNS message.
+type ArchivalEDNS0 struct {
+	Code  uint16 `json:"code"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
 }
 
 //
@@ -231,6 +244,21 @@ type ArchivalTCPConnectStatus struct {
 	Success bool    `json:"success"`
 }
 
+// ArchivalHappyEyeballsResult is the result of racing TCP connect attempts
+// using happy eyeballs (see RFC 8305). The individual connect attempts are
+// archived separately as [ArchivalTCPConnectResult].
+type ArchivalHappyEyeballsResult struct {
+	Domain        string   `json:"domain"`
+	Network       string   `json:"network"`
+	Failure       *string  `json:"failure"`
+	Winner        string   `json:"winner"`
+	WinnerFamily  string   `json:"winner_family"`
+	T0            float64  `json:"t0,omitempty"`
+	T             float64  `json:"t"`
+	Tags          []string `json:"tags"`
+	TransactionID int64    `json:"transaction_id,omitempty"`
+}
+
 //
 // TLS or QUIC handshake
 //
@@ -250,6 +278,10 @@ type ArchivalTLSOrQUICHandshakeResult struct {
 	ServerName         string               `json:"server_name"`
 	OuterServerName    string               `json:"outer_server_name,omitempty"`
 	ECHConfig          string               `json:"echconfig,omitempty"`
+	ECHStatus          string               `json:"ech_status,omitempty"`
+	ECHRetryConfigs    string               `json:"ech_retry_configs,omitempty"`
+	Fingerprint        string               `json:"fingerprint,omitempty"`
+	LeafSPKISHA256     string               `json:"x_leaf_spki_sha256,omitempty"`
 	T0                 float64              `json:"t0,omitempty"`
 	T                  float64              `json:"t"`
 	Tags               []string             `json:"tags"`
@@ -393,6 +425,20 @@ type ArchivalNetworkEvent struct {
 	T             float64  `json:"t"`
 	TransactionID int64    `json:"transaction_id,omitempty"`
 	Tags          []string `json:"tags,omitempty"`
+
+	// HTTP2Frame is only present for "http2_read_frame" and
+	// "http2_write_frame" operations.
+	HTTP2Frame *ArchivalHTTP2Frame `json:"http2_frame,omitempty"`
+}
+
+// ArchivalHTTP2Frame contains information about an HTTP/2 frame.
+type ArchivalHTTP2Frame struct {
+	Type         string `json:"type"`
+	Flags        uint8  `json:"flags"`
+	Length       uint32 `json:"length"`
+	StreamID     uint32 `json:"stream_id"`
+	ErrCode      string `json:"error_code,omitempty"`
+	LastStreamID uint32 `json:"last_stream_id,omitempty"`
 }
 
 //
@@ -14,4 +14,9 @@ type KeyValueStore interface {
 	// Set sets the value of the given key and returns
 	// whether the operation was successful or not.
 	Set(key string, value []byte) (err error)
+
+	// Delete deletes the given key and returns whether the
+	// operation was successful or not. Deleting a key that
+	// does not exist is not an error.
+	Delete(key string) (err error)
 }
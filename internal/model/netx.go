@@ -41,6 +41,11 @@ type DNSResponse interface {
 
 	// DecodeCNAME returns the first CNAME entry in this response.
 	DecodeCNAME() (string, error)
+
+	// DecodeRecords returns all the records inside the answer section, including
+	// the CNAME chain, provided that there is at least a record matching the
+	// original query type. Otherwise, this method returns an error.
+	DecodeRecords() ([]*DNSRecord, error)
 }
 
 // The DNSDecoder decodes DNS responses.
@@ -189,12 +194,55 @@ type HTTPSSvc struct {
 	Ech []byte
 }
 
+// DNSRecord is a generic DNS resource record.
+type DNSRecord struct {
+	// Name is the record owner name (e.g., "www.example.com.").
+	Name string
+
+	// Type is the record type (e.g., dns.TypeTXT).
+	Type uint16
+
+	// TTL is the record time to live in seconds.
+	TTL uint32
+
+	// Target contains the target hostname for record types that point to
+	// another name (e.g., CNAME, NS, MX, SRV, SVCB, HTTPS) and is empty otherwise.
+	Target string
+
+	// Value is the record data in presentation format (e.g.,
+	// "\"v=spf1 -all\"" for a TXT record or "10 mx.example.com." for MX).
+	Value string
+}
+
+// DNSSECValidation is the outcome of validating a DNS response using DNSSEC.
+type DNSSECValidation struct {
+	// Status is the validation status (e.g., "secure", "bogus").
+	Status string
+
+	// Failure is the reason why the Status is not "secure" or nil.
+	Failure error
+}
+
+// DNSSECValidatedResponse is a [DNSResponse] for which we also validated
+// the DNSSEC chain of trust. Resolvers only return this kind of response
+// when explicitly configured to request DNSSEC records.
+type DNSSECValidatedResponse interface {
+	DNSResponse
+
+	// DNSSECValidation returns the DNSSEC validation outcome.
+	DNSSECValidation() *DNSSECValidation
+}
+
 // MeasuringNetwork defines the constructors required for implementing OONI experiments. All
 // these constructors MUST guarantee proper error wrapping to map Go errors to OONI errors
 // as documented by the [netxlite] package. The [*netxlite.Netx] type is currently the default
 // implementation of this interface. This interface SHOULD always be implemented in terms of
 // an [UnderlyingNetwork] that allows to switch between the host network and [netemx].
 type MeasuringNetwork interface {
+	// NewDialerHappyEyeballs creates a [Dialer] with error wrapping that resolves domain
+	// names using the given [Resolver] and races connect attempts as described by RFC 8305.
+	NewDialerHappyEyeballs(dl DebugLogger, r Resolver) Dialer
+
 	// NewDialerWithoutResolver creates a [Dialer] with error wrapping and without an attached
 	// resolver, meaning that you MUST pass TCP or UDP endpoint addresses to this dialer.
 	//
@@ -205,6 +253,11 @@ type MeasuringNetwork interface {
 	// NewParallelDNSOverHTTPSResolver creates a new DNS-over-HTTPS resolver with error wrapping.
 	NewParallelDNSOverHTTPSResolver(logger DebugLogger, URL string) Resolver
 
+	// NewParallelDNSOverQUICResolver creates a new DNS-over-QUIC resolver with error wrapping.
+	//
+	// The address argument is the QUIC endpoint address (e.g., dns.adguard-dns.com:853).
+	NewParallelDNSOverQUICResolver(logger DebugLogger, address string) Resolver
+
 	// NewParallelUDPResolver creates a new Resolver using DNS-over-UDP
 	// that performs parallel A/AAAA lookups during LookupHost.
 	//
@@ -318,7 +371,9 @@ type Resolver interface {
 	//
 	// - doh: is a custom DNS-over-HTTPS resolver;
 	//
-	// - doh3: is a custom DNS-over-HTTP3 resolver.
+	// - doh3: is a custom DNS-over-HTTP3 resolver;
+	//
+	// - doq: is a custom DNS-over-QUIC resolver.
 	//
 	// See https://github.com/ooni/probe/issues/2029#issuecomment-1140805266
 	// for an explanation of why it would not be proper to call "netgo" the
@@ -337,6 +392,10 @@ type Resolver interface {
 
 	// LookupNS issues a NS query for a domain.
 	LookupNS(ctx context.Context, domain string) ([]*net.NS, error)
+
+	// LookupRecords issues a query for a domain using the given query type (e.g.,
+	// dns.TypeTXT) and returns all the records inside the answer section.
+	LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*DNSRecord, error)
 }
 
 // TLSConn is the interface representing a *tls.Conn compatible
@@ -492,6 +551,30 @@ type Trace interface {
 	OnConnectDone(
 		started time.Time, network, domain, remoteAddr string, err error, finished time.Time)
 
+	// OnHappyEyeballsDone is called when an happy eyeballs dialer (see RFC 8305) has
+	// finished racing connect attempts. We also call OnConnectDone for each attempt.
+	//
+	// Arguments:
+	//
+	// - started is when we started the first connect attempt;
+	//
+	// - network is the network we're using (one of "tcp" and "udp");
+	//
+	// - domain is the domain for which we're dialing. If the user dialed
+	// for an IP address and a port, then domain will be an IP address;
+	//
+	// - winner is the TCP endpoint of the connect attempt that won the race or
+	// an empty string if all the connect attempts failed;
+	//
+	// - err is the result of the race: either an error or nil;
+	//
+	// - finished is when the race terminated.
+	//
+	// The error passed to this function will always be wrapped such that the
+	// string returned by Error is an OONI error.
+	OnHappyEyeballsDone(
+		started time.Time, network, domain, winner string, err error, finished time.Time)
+
 	// OnTLSHandshakeStart is called when the TLS handshake starts.
 	//
 	// Arguments:
@@ -561,6 +644,45 @@ type Trace interface {
 	// string returned by Error is an OONI error.
 	OnQUICHandshakeDone(started time.Time, remoteAddr string, qconn QUICConn,
 		config *tls.Config, err error, finished time.Time)
+
+	// OnHTTP2Frame is called by HTTP/2 transports supporting tracing when
+	// they have finished reading or writing an HTTP/2 frame.
+	//
+	// Arguments:
+	//
+	// - now is the moment when we observed the whole frame;
+	//
+	// - operation is either "http2_read_frame" or "http2_write_frame";
+	//
+	// - network is the network we're using (e.g., "tcp");
+	//
+	// - remoteAddr is the TCP endpoint we're using (e.g., 8.8.8.8:443);
+	//
+	// - frame is the non-nil frame we observed.
+	OnHTTP2Frame(now time.Time, operation, network, remoteAddr string, frame *HTTP2Frame)
+}
+
+// HTTP2Frame contains information about an HTTP/2 frame.
+type HTTP2Frame struct {
+	// Type is the frame type (e.g., "SETTINGS", "RST_STREAM").
+	Type string
+
+	// Flags contains the frame flags.
+	Flags uint8
+
+	// Length is the length of the frame payload.
+	Length uint32
+
+	// StreamID is the stream ID or zero for connection-level frames.
+	StreamID uint32
+
+	// ErrCode is the error code (e.g., "PROTOCOL_ERROR") for RST_STREAM
+	// and GOAWAY frames and is empty for any other frame type.
+	ErrCode string
+
+	// LastStreamID is the last stream ID for GOAWAY frames and is zero
+	// for any other frame type.
+	LastStreamID uint32
 }
 
 // UDPLikeConn is a net.PacketConn with some extra functions
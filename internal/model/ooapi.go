@@ -88,10 +88,23 @@ type OOAPICheckInResultConfig struct {
 	// Features contains feature flags.
 	Features map[string]bool `json:"features"`
 
+	// Tactics OPTIONALLY contains signed dialing tactics.
+	Tactics *OOAPICheckInResultTactics `json:"tactics,omitempty"`
+
 	// TestHelpers contains test-helpers information.
 	TestHelpers map[string][]OOAPIService `json:"test_helpers"`
 }
 
+// OOAPICheckInResultTactics contains dialing tactics (e.g., bridges) for
+// communicating with the backend, signed by the backend.
+type OOAPICheckInResultTactics struct {
+	// Payload contains the serialized tactics.
+	Payload []byte `json:"payload"`
+
+	// Signature contains the ed25519 signature of the payload.
+	Signature []byte `json:"signature"`
+}
+
 // OOAPICheckReportIDResponse is the check-report-id API response.
 type OOAPICheckReportIDResponse struct {
 	Error string `json:"error"`
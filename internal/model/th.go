@@ -32,6 +32,14 @@ type THTLSHandshakeResult struct {
 	ServerName string  `json:"server_name"`
 	Status     bool    `json:"status"`
 	Failure    *string `json:"failure"`
+
+	// PeerCertificates OPTIONALLY contains the certificate chain sent by the
+	// server, which the test helper includes when it is available.
+	PeerCertificates []ArchivalBinaryData `json:"x_peer_certificates,omitempty"`
+
+	// LeafSPKISHA256 OPTIONALLY contains the base64-encoded SHA-256 hash
+	// of the SubjectPublicKeyInfo of the leaf certificate.
+	LeafSPKISHA256 string `json:"x_leaf_spki_sha256,omitempty"`
 }
 
 // THHTTPRequestResult is the result of the HTTP request
@@ -43,6 +51,38 @@ type THHTTPRequestResult struct {
 	Title                string            `json:"title"`
 	Headers              map[string]string `json:"headers"`
 	StatusCode           int64             `json:"status_code"`
+
+	// RedirectChain OPTIONALLY contains all the responses received by the test
+	// helper while following redirects, including the final response.
+	RedirectChain []THHTTPHopResult `json:"x_redirect_chain,omitempty"`
+
+	// BodySHA256 OPTIONALLY contains the hex-encoded SHA-256 of the body.
+	BodySHA256 string `json:"x_body_sha256,omitempty"`
+
+	// BodySimhash OPTIONALLY contains the hex-encoded simhash of the body, which
+	// allows to measure how similar the body is to the one seen by the probe.
+	BodySimhash string `json:"x_body_simhash,omitempty"`
+}
+
+// THHTTPHopResult is a response received by the control vantage
+// point while following redirects.
+type THHTTPHopResult struct {
+	// URL is the URL of the request that produced this response.
+	URL string `json:"url"`
+
+	// StatusCode is the response status code.
+	StatusCode int64 `json:"status_code"`
+
+	// Location is the value of the Location header, if any.
+	Location string `json:"location"`
+
+	// Headers contains the response headers.
+	Headers map[string]string `json:"headers"`
+
+	// BodyLength is the length of the body we read for the final response
+	// and the Content-Length (possibly -1) for all the other responses, since
+	// the HTTP client discards the bodies of redirect responses.
+	BodyLength int64 `json:"body_length"`
 }
 
 // TODO(bassosimone): ASNs is a private implementation detail of v0.4
@@ -58,6 +98,19 @@ type THDNSResult struct {
 	ASNs    []int64  `json:"-"` // not visible from the JSON
 }
 
+// THDNSResolverResult is the result of the DNS lookup performed
+// by the control vantage point using a specific resolver.
+type THDNSResolverResult struct {
+	// Resolver is the resolver URL (e.g., "https://dns.google/dns-query").
+	Resolver string `json:"resolver"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// Addrs contains the resolved addresses.
+	Addrs []string `json:"addrs"`
+}
+
 // THIPInfo contains information about IP addresses resolved either
 // by the probe or by the TH and processed by the TH.
 type THIPInfo struct {
@@ -94,4 +147,34 @@ type THResponse struct {
 	HTTP3Request  *THHTTPRequestResult            `json:"http3_request"` // optional!
 	DNS           THDNSResult                     `json:"dns"`
 	IPInfo        map[string]*THIPInfo            `json:"ip_info,omitempty"`
+
+	// DNSResolvers contains the OPTIONAL results of resolving the domain using
+	// additional resolvers, which the control may use to cross check DNS.
+	DNSResolvers []THDNSResolverResult `json:"x_dns_resolvers,omitempty"`
+}
+
+// DNSResolvedAddrs returns the addresses resolved by the control, which include
+// the addresses resolved by the additional resolvers that succeeded, if any.
+func (r *THResponse) DNSResolvedAddrs() (addrs []string) {
+	addrs = append(addrs, r.DNS.Addrs...)
+	for _, entry := range r.DNSResolvers {
+		if entry.Failure == nil {
+			addrs = append(addrs, entry.Addrs...)
+		}
+	}
+	return
+}
+
+// THResult is the result of querying a specific test helper. We archive this
+// structure when querying several test helpers in parallel, such that we can
+// later check whether they agree with each other.
+type THResult struct {
+	// TestHelper is the test helper we queried.
+	TestHelper *OOAPIService `json:"test_helper"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// Response is the response on success and nil on failure.
+	Response *THResponse `json:"response"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTHResponseDNSResolvedAddrs(t *testing.T) {
+	failure := "dns_nxdomain_error"
+	resp := &THResponse{
+		DNS: THDNSResult{
+			Addrs: []string{"8.8.8.8"},
+		},
+		DNSResolvers: []THDNSResolverResult{{
+			Resolver: "https://dns.google/dns-query",
+			Failure:  nil,
+			Addrs:    []string{"8.8.8.8", "8.8.4.4"},
+		}, {
+			Resolver: "udp://9.9.9.9",
+			Failure:  &failure,
+			Addrs:    []string{"1.1.1.1"},
+		}},
+	}
+	expect := []string{"8.8.8.8", "8.8.8.8", "8.8.4.4"}
+	if diff := cmp.Diff(expect, resp.DNSResolvedAddrs()); diff != "" {
+		t.Fatal(diff)
+	}
+}
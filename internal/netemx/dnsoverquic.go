@@ -0,0 +1,99 @@
+package netemx
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+)
+
+// DNSOverQUICServerFactory implements [NetStackServerFactory] for DNS-over-QUIC servers.
+//
+// When this factory constructs a [NetStackServer], it will use:
+//
+// 1. the [NetStackServerFactoryEnv.OtherResolversConfig] as DNS configuration;
+//
+// 2. the ServerNameMain and ServerNameExtras fields for the TLS certificate.
+//
+// Use this factory along with [QAEnvOptionNetStack] to create DNS-over-QUIC servers.
+type DNSOverQUICServerFactory struct {
+	// Ports is the MANDATORY list of ports where to listen.
+	Ports []int
+
+	// ServerNameMain is the MANDATORY server name we should configure.
+	ServerNameMain string
+
+	// ServerNameExtras contains OPTIONAL extra server names we should configure.
+	ServerNameExtras []string
+}
+
+var _ NetStackServerFactory = &DNSOverQUICServerFactory{}
+
+// MustNewServer implements NetStackServerFactory.
+func (f *DNSOverQUICServerFactory) MustNewServer(env NetStackServerFactoryEnv, stack *netem.UNetStack) NetStackServer {
+	return &dnsOverQUICServer{
+		closers:          []io.Closer{},
+		env:              env,
+		mu:               sync.Mutex{},
+		ports:            f.Ports,
+		serverNameMain:   f.ServerNameMain,
+		serverNameExtras: f.ServerNameExtras,
+		unet:             stack,
+	}
+}
+
+type dnsOverQUICServer struct {
+	closers          []io.Closer
+	env              NetStackServerFactoryEnv
+	mu               sync.Mutex
+	ports            []int
+	serverNameMain   string
+	serverNameExtras []string
+	unet             *netem.UNetStack
+}
+
+// Close implements NetStackServer.
+func (srv *dnsOverQUICServer) Close() error {
+	// make the method locked as requested by the documentation
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// close each of the closers
+	for _, closer := range srv.closers {
+		_ = closer.Close()
+	}
+
+	// be idempotent
+	srv.closers = []io.Closer{}
+	return nil
+}
+
+// MustStart implements NetStackServer.
+func (srv *dnsOverQUICServer) MustStart() {
+	// make the method locked as requested by the documentation
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// create the listening address
+	ipAddr := net.ParseIP(srv.unet.IPAddress())
+	runtimex.Assert(ipAddr != nil, "expected valid IP address")
+
+	// create TLS config for the server name
+	tlsConfig := srv.unet.MustNewServerTLSConfig(srv.serverNameMain, srv.serverNameExtras...)
+
+	// create the round tripper
+	rtx := testingx.NewDNSRoundTripperWithDNSConfig(srv.env.OtherResolversConfig())
+
+	for _, port := range srv.ports {
+		// create the listening socket
+		addr := &net.UDPAddr{IP: ipAddr, Port: port}
+		pconn := runtimex.Try1(srv.unet.ListenUDP("udp", addr))
+
+		// create and track the DNS-over-QUIC listener
+		server := testingx.MustNewDNSOverQUICListener(pconn, tlsConfig, rtx)
+		srv.closers = append(srv.closers, server)
+	}
+}
@@ -0,0 +1,37 @@
+package netemx
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestDNSOverQUICServerFactory(t *testing.T) {
+	env := MustNewQAEnv(
+		QAEnvOptionNetStack(AddressDNSGoogle8844, &DNSOverQUICServerFactory{
+			Ports:            []int{853},
+			ServerNameMain:   "dns.google",
+			ServerNameExtras: []string{},
+		}),
+	)
+	defer env.Close()
+
+	env.AddRecordToAllResolvers("dns.google", "", AddressDNSGoogle8844)
+	env.AddRecordToAllResolvers("www.example.com", "", AddressWwwExampleCom)
+
+	env.Do(func() {
+		netx := &netxlite.Netx{}
+		reso := netx.NewParallelDNSOverQUICResolver(log.Log, net.JoinHostPort("dns.google", "853"))
+		addrs, err := reso.LookupHost(context.Background(), "www.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{AddressWwwExampleCom}, addrs); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
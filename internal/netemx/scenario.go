@@ -10,7 +10,7 @@ import (
 )
 
 const (
-	// ScenarioRolePublicDNS means we should create DNS-over-HTTPS and DNS-over-UDP servers.
+	// ScenarioRolePublicDNS means we should create DNS-over-HTTPS, DNS-over-QUIC, and DNS-over-UDP servers.
 	ScenarioRolePublicDNS = iota
 
 	// ScenarioRoleWebServer means we should instantiate a webserver using a specific factory.
@@ -294,6 +294,11 @@ func MustNewScenario(config []*ScenarioDomainAddresses) *QAEnv {
 						ServerNameMain:   sad.ServerNameMain,
 						ServerNameExtras: sad.ServerNameExtras,
 					},
+					&DNSOverQUICServerFactory{
+						Ports:            []int{853},
+						ServerNameMain:   sad.ServerNameMain,
+						ServerNameExtras: sad.ServerNameExtras,
+					},
 				))
 			}
 
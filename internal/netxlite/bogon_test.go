@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 )
 
@@ -115,6 +116,18 @@ func TestBogonResolver(t *testing.T) {
 		}
 	})
 
+	t.Run("LookupRecords", func(t *testing.T) {
+		ctx := context.Background()
+		reso := &bogonResolver{}
+		records, err := reso.LookupRecords(ctx, "dns.google", dns.TypeTXT)
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("unexpected err", err)
+		}
+		if len(records) > 0 {
+			t.Fatal("expected empty records here")
+		}
+	})
+
 	t.Run("Network", func(t *testing.T) {
 		expected := "antani"
 		reso := &bogonResolver{
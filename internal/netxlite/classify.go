@@ -6,6 +6,7 @@ package netxlite
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -43,6 +44,9 @@ var ErrUnknown = errors.New(FailureUnknown)
 // The more specific classifiers will call this classifier if
 // they fail to find a mapping for the input error.
 //
+// Classifiers registered using [RegisterErrorClassifier] run either
+// before or after the builtin classification, depending on their priority.
+//
 // If everything else fails, this classifier returns a string
 // like "unknown_failure: XXX" where XXX has been scrubbed
 // so to remove any network endpoints from the original error string.
@@ -56,6 +60,11 @@ func ClassifyGenericError(err error) string {
 		return errwrapper.Error() // we've already wrapped it
 	}
 
+	// Give precedence to the classifiers registered with high priority.
+	if failure := classifyWithRegisteredClassifiers(err, true); failure != "" {
+		return failure
+	}
+
 	// Classify system errors first. We could use strings for many
 	// of them on Unix, but this would fail on Windows as described
 	// by https://github.com/ooni/probe/issues/1526.
@@ -75,6 +84,10 @@ func ClassifyGenericError(err error) string {
 		return FailureUnknown
 	}
 
+	if failure := classifyWithRegisteredClassifiers(err, false); failure != "" {
+		return failure
+	}
+
 	formatted := fmt.Sprintf("%s: %s", FailureUnknown, err.Error())
 	return scrubber.ScrubString(formatted) // scrub IP addresses in the error
 }
@@ -167,6 +180,10 @@ func ClassifyQUICHandshakeError(err error) string {
 		return errwrapper.Error() // we've already wrapped it
 	}
 
+	if failure := classifyWithRegisteredClassifiers(err, true); failure != "" {
+		return failure
+	}
+
 	var (
 		versionNegotiation *quic.VersionNegotiationError
 		statelessReset     *quic.StatelessResetError
@@ -214,8 +231,8 @@ func ClassifyQUICHandshakeError(err error) string {
 		// an internal error, search for a OONI error and, if
 		// found, just return such an error.
 		if transportError.ErrorCode == quic.InternalError {
-			if s := failuresMap[transportError.ErrorMessage]; s != "" {
-				return s
+			if isKnownFailure(transportError.ErrorMessage) {
+				return transportError.ErrorMessage
 			}
 		}
 	}
@@ -305,6 +322,10 @@ func ClassifyResolverError(err error) string {
 		return errwrapper.Error() // we've already wrapped it
 	}
 
+	if failure := classifyWithRegisteredClassifiers(err, true); failure != "" {
+		return failure
+	}
+
 	if errors.Is(err, ErrDNSBogon) {
 		return FailureDNSBogonError // not in MK
 	}
@@ -341,6 +362,10 @@ func ClassifyTLSHandshakeError(err error) string {
 		return errwrapper.Error() // we've already wrapped it
 	}
 
+	if failure := classifyWithRegisteredClassifiers(err, true); failure != "" {
+		return failure
+	}
+
 	var x509HostnameError x509.HostnameError
 	if errors.As(err, &x509HostnameError) {
 		// Test case: https://wrong.host.badssl.com/
@@ -357,6 +382,12 @@ func ClassifyTLSHandshakeError(err error) string {
 		// Test case: https://expired.badssl.com/
 		return FailureSSLInvalidCertificate
 	}
+	var echRejectionError *tls.ECHRejectionError
+	if errors.As(err, &echRejectionError) {
+		// The server did not accept our encrypted client hello: the error
+		// possibly contains the retry configs sent by the server.
+		return FailureSSLECHRejected
+	}
 
 	if strings.HasSuffix(err.Error(), "tls: unrecognized name") {
 		return FailureSSLInvalidHostname
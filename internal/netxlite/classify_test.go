@@ -2,6 +2,7 @@ package netxlite
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"io"
@@ -332,6 +333,13 @@ func TestClassifyTLSHandshakeError(t *testing.T) {
 		}
 	})
 
+	t.Run("for *tls.ECHRejectionError", func(t *testing.T) {
+		err := &tls.ECHRejectionError{}
+		if ClassifyTLSHandshakeError(err) != FailureSSLECHRejected {
+			t.Fatal("unexpected result")
+		}
+	})
+
 	t.Run("for 'tls: unrecognized name' error", func(t *testing.T) {
 		err := errors.New("tls: handshake failed: tls: unrecognized name")
 		if ClassifyTLSHandshakeError(err) != FailureSSLInvalidHostname {
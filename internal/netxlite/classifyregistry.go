@@ -0,0 +1,130 @@
+package netxlite
+
+//
+// Registering additional error classifiers
+//
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// ErrorClassifier maps a Go error to an OONI failure string. It returns an
+// empty string when it does not know how to classify the given error.
+type ErrorClassifier func(err error) string
+
+// ErrorClassifierPriorityBuiltin is the priority of the builtin classification
+// performed by this package. Registered classifiers with a greater priority run
+// before the builtin classification, thus they can override it, while the other
+// registered classifiers run after the builtin classification failed and before
+// we fall back to returning an "unknown_failure: XXX" string.
+const ErrorClassifierPriorityBuiltin = 0
+
+// errorClassifierEntry is an entry in the errorClassifiers registry.
+type errorClassifierEntry struct {
+	classifier ErrorClassifier
+	failures   []string
+	priority   int
+}
+
+var (
+	// errorClassifiers contains the registered classifiers sorted by priority.
+	errorClassifiers []*errorClassifierEntry
+
+	// errorClassifiersMu protects errorClassifiers.
+	errorClassifiersMu sync.RWMutex
+)
+
+// RegisterErrorClassifier registers a classifier to map errors to OONI failure strings
+// using the given priority (see [ErrorClassifierPriorityBuiltin]). We run classifiers with
+// higher priority first and classifiers with the same priority in registration order. The
+// failures argument lists all the failure strings the classifier may return, which we
+// include in the list returned by [FailureStrings].
+//
+// Registered classifiers run inside [ClassifyGenericError] as well as inside the more
+// specific classifiers (e.g., [ClassifyTLSHandshakeError]). You typically want to call
+// this function from the init function of the package that needs to classify errors.
+// Because registered classifiers are process-wide, they should only match errors that
+// the registering package's dependencies emit, lest they change the failures emitted
+// by unrelated experiments.
+//
+// This function panics if the classifier is nil or any failure string is empty.
+func RegisterErrorClassifier(priority int, classifier ErrorClassifier, failures ...string) {
+	runtimex.Assert(classifier != nil, "netxlite: nil classifier")
+	for _, failure := range failures {
+		runtimex.Assert(failure != "", "netxlite: empty failure string")
+	}
+	entry := &errorClassifierEntry{
+		classifier: classifier,
+		failures:   append([]string{}, failures...),
+		priority:   priority,
+	}
+	errorClassifiersMu.Lock()
+	defer errorClassifiersMu.Unlock()
+	// copy on write so that we never modify a slice someone else may be using
+	classifiers := append(append([]*errorClassifierEntry{}, errorClassifiers...), entry)
+	sort.SliceStable(classifiers, func(i, j int) bool {
+		return classifiers[i].priority > classifiers[j].priority
+	})
+	errorClassifiers = classifiers
+}
+
+// classifyWithRegisteredClassifiers classifies the given error using the registered
+// classifiers that run either before or after the builtin classification. This function
+// returns an empty string if none of the registered classifiers could classify the error.
+func classifyWithRegisteredClassifiers(err error, beforeBuiltin bool) string {
+	errorClassifiersMu.RLock()
+	defer errorClassifiersMu.RUnlock()
+	for _, entry := range errorClassifiers {
+		if (entry.priority > ErrorClassifierPriorityBuiltin) != beforeBuiltin {
+			continue
+		}
+		if failure := entry.classifier(err); failure != "" {
+			return failure
+		}
+	}
+	return ""
+}
+
+// isKnownFailure returns whether the given string is a failure string
+// defined by this package or by any registered classifier.
+func isKnownFailure(failure string) bool {
+	if failuresMap[failure] != "" {
+		return true
+	}
+	errorClassifiersMu.RLock()
+	defer errorClassifiersMu.RUnlock()
+	for _, entry := range errorClassifiers {
+		for _, value := range entry.failures {
+			if value == failure {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FailureStrings returns the sorted list of all the failure strings that the
+// classifiers in this package and the registered classifiers may return (see
+// [RegisterErrorClassifier]). The list includes [FailureUnknown], which we use
+// as is for [ErrUnknown] and otherwise as the prefix of unclassified errors.
+func FailureStrings() (out []string) {
+	unique := map[string]bool{FailureUnknown: true}
+	for failure := range failuresMap {
+		unique[failure] = true
+	}
+	errorClassifiersMu.RLock()
+	for _, entry := range errorClassifiers {
+		for _, failure := range entry.failures {
+			unique[failure] = true
+		}
+	}
+	errorClassifiersMu.RUnlock()
+	for failure := range unique {
+		out = append(out, failure)
+	}
+	sort.Strings(out)
+	return
+}
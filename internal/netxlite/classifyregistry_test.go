@@ -0,0 +1,163 @@
+package netxlite
+
+import (
+	"crypto/x509"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/quic-go/quic-go"
+)
+
+// withCleanErrorClassifiers runs the given function and then restores the
+// registered classifiers to the state before running the function.
+func withCleanErrorClassifiers(t *testing.T, fx func()) {
+	errorClassifiersMu.Lock()
+	saved := errorClassifiers
+	errorClassifiersMu.Unlock()
+	defer func() {
+		errorClassifiersMu.Lock()
+		errorClassifiers = saved
+		errorClassifiersMu.Unlock()
+	}()
+	fx()
+}
+
+func TestRegisterErrorClassifier(t *testing.T) {
+	errMocked := errors.New("mocked error")
+
+	// newClassifier returns a classifier mapping errMocked to the given failure.
+	newClassifier := func(failure string) ErrorClassifier {
+		return func(err error) string {
+			if errors.Is(err, errMocked) {
+				return failure
+			}
+			return ""
+		}
+	}
+
+	t.Run("we panic with a nil classifier", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected a panic")
+				}
+			}()
+			RegisterErrorClassifier(ErrorClassifierPriorityBuiltin, nil)
+		})
+	})
+
+	t.Run("we panic with an empty failure string", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected a panic")
+				}
+			}()
+			RegisterErrorClassifier(ErrorClassifierPriorityBuiltin, newClassifier("x"), "")
+		})
+	})
+
+	t.Run("we run classifiers after the builtin classification", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			RegisterErrorClassifier(ErrorClassifierPriorityBuiltin, newClassifier("mocked_failure"), "mocked_failure")
+			if failure := ClassifyGenericError(errMocked); failure != "mocked_failure" {
+				t.Fatal("unexpected failure", failure)
+			}
+			// the builtin classification takes precedence
+			err := errors.Join(errMocked, errors.New("i/o timeout"))
+			if failure := ClassifyGenericError(err); failure != FailureGenericTimeoutError {
+				t.Fatal("unexpected failure", failure)
+			}
+			if failure := ClassifyGenericError(errors.New("antani")); failure != "unknown_failure: antani" {
+				t.Fatal("unexpected failure", failure)
+			}
+		})
+	})
+
+	t.Run("we run classifiers before the builtin classification", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			RegisterErrorClassifier(ErrorClassifierPriorityBuiltin+1, newClassifier("mocked_failure"), "mocked_failure")
+			err := errors.Join(errMocked, x509.HostnameError{})
+			expect := map[string]func(error) string{
+				"ClassifyGenericError":       ClassifyGenericError,
+				"ClassifyQUICHandshakeError": ClassifyQUICHandshakeError,
+				"ClassifyResolverError":      ClassifyResolverError,
+				"ClassifyTLSHandshakeError":  ClassifyTLSHandshakeError,
+			}
+			for name, classify := range expect {
+				if failure := classify(err); failure != "mocked_failure" {
+					t.Fatal(name, "unexpected failure", failure)
+				}
+			}
+		})
+	})
+
+	t.Run("we sort classifiers by priority and then by registration order", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			RegisterErrorClassifier(-10, newClassifier("third"), "third")
+			RegisterErrorClassifier(10, newClassifier("second"), "second")
+			RegisterErrorClassifier(20, newClassifier("first"), "first")
+			RegisterErrorClassifier(10, newClassifier("fourth"), "fourth")
+			var got []int
+			for _, entry := range errorClassifiers {
+				got = append(got, entry.priority)
+			}
+			if !sort.SliceIsSorted(got, func(i, j int) bool { return got[i] > got[j] }) {
+				t.Fatal("not sorted", got)
+			}
+			if failure := ClassifyGenericError(errMocked); failure != "first" {
+				t.Fatal("unexpected failure", failure)
+			}
+			if failure := errorClassifiers[2].classifier(errMocked); failure != "fourth" {
+				t.Fatal("expected registration order for same priority, got", failure)
+			}
+		})
+	})
+
+	t.Run("QUIC handshake errors may wrap registered failures", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			RegisterErrorClassifier(ErrorClassifierPriorityBuiltin, newClassifier("mocked_failure"), "mocked_failure")
+			err := &quic.TransportError{
+				ErrorCode:    quic.InternalError,
+				ErrorMessage: "mocked_failure",
+			}
+			if failure := ClassifyQUICHandshakeError(err); failure != "mocked_failure" {
+				t.Fatal("unexpected failure", failure)
+			}
+		})
+	})
+}
+
+func TestFailureStrings(t *testing.T) {
+	t.Run("we include builtin failures and unknown_failure", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			failures := FailureStrings()
+			if len(failures) != len(failuresMap)+1 {
+				t.Fatal("unexpected number of failures", len(failures))
+			}
+			if !sort.StringsAreSorted(failures) {
+				t.Fatal("expected sorted failures")
+			}
+			index := sort.SearchStrings(failures, FailureUnknown)
+			if index >= len(failures) || failures[index] != FailureUnknown {
+				t.Fatal("did not find", FailureUnknown)
+			}
+		})
+	})
+
+	t.Run("we include the failures of registered classifiers", func(t *testing.T) {
+		withCleanErrorClassifiers(t, func() {
+			classifier := func(err error) string { return "" }
+			RegisterErrorClassifier(ErrorClassifierPriorityBuiltin, classifier, "mocked_failure", FailureEOFError)
+			failures := FailureStrings()
+			if len(failures) != len(failuresMap)+2 {
+				t.Fatal("unexpected number of failures", len(failures))
+			}
+			index := sort.SearchStrings(failures, "mocked_failure")
+			if index >= len(failures) || failures[index] != "mocked_failure" {
+				t.Fatal("did not find mocked_failure")
+			}
+		})
+	})
+}
@@ -0,0 +1,215 @@
+package netxlite
+
+//
+// Happy eyeballs dialer (see RFC 8305)
+//
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// HappyEyeballsConnectionAttemptDelay is the delay between starting two subsequent
+// connect attempts recommended by RFC 8305 Section 5.
+const HappyEyeballsConnectionAttemptDelay = 250 * time.Millisecond
+
+// NewDialerHappyEyeballs creates a [model.Dialer] that races connect attempts to
+// the resolved IP addresses as described by RFC 8305. The resolver argument is used
+// to resolve domain names and MUST NOT be nil.
+//
+// Unlike the dialer returned by [Netx.NewDialerWithResolver], which tries each
+// IP address sequentially starting with IPv4, this dialer interleaves the address
+// families starting with IPv6 and starts a new connect attempt either when the
+// previous attempt fails or after [HappyEyeballsConnectionAttemptDelay]. The first
+// successful attempt wins the race and we cancel all the other attempts.
+//
+// Each connect attempt calls the [model.Trace] OnConnectDone method, therefore
+// each attempt is traced separately. When the race is over, we also call the
+// [model.Trace] OnHappyEyeballsDone method to record which endpoint won.
+//
+// When all attempts fail, we reduce the errors like [Netx.NewDialerWithResolver] does.
+func (netx *Netx) NewDialerHappyEyeballs(dl model.DebugLogger, r model.Resolver) model.Dialer {
+	return &dialerLogger{
+		Dialer: &dialerHappyEyeballs{
+			Delay:    HappyEyeballsConnectionAttemptDelay,
+			Dialer:   netx.NewDialerWithoutResolver(dl),
+			Resolver: r,
+		},
+		DebugLogger: dl,
+	}
+}
+
+// dialerHappyEyeballs races connect attempts using happy eyeballs.
+type dialerHappyEyeballs struct {
+	// Delay is the connection attempt delay.
+	Delay time.Duration
+
+	// Dialer is the dialer for dialing IP endpoints.
+	Dialer model.Dialer
+
+	// Resolver is the resolver for domain names.
+	Resolver model.Resolver
+}
+
+var _ model.Dialer = &dialerHappyEyeballs{}
+
+// DialContext implements model.Dialer.DialContext.
+func (d *dialerHappyEyeballs) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	onlyhost, onlyport, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := d.lookupHost(ctx, onlyhost)
+	if err != nil {
+		return nil, err
+	}
+	trace := ContextTraceOrDefault(ctx)
+	started := trace.TimeNow()
+	conn, winner, err := d.race(ctx, network, happyEyeballsSortIPAddrs(addrs), onlyport)
+	finished := trace.TimeNow()
+	trace.OnHappyEyeballsDone(started, network, onlyhost, winner, err, finished)
+	return conn, err
+}
+
+// happyEyeballsAttempt is the result of a connect attempt.
+type happyEyeballsAttempt struct {
+	addr    string
+	address string
+	conn    net.Conn
+	err     error
+}
+
+// race races the connect attempts and returns the winning conn and endpoint.
+func (d *dialerHappyEyeballs) race(
+	ctx context.Context, network string, addrs []string, port string) (net.Conn, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the channel is buffered so attempts never block writing their result
+	attempts := make(chan *happyEyeballsAttempt, len(addrs))
+	var (
+		errorsmap = map[string]error{}
+		next      int
+		running   int
+		timer     <-chan time.Time
+	)
+
+	startNext := func() {
+		if next >= len(addrs) {
+			timer = nil // no more attempts to schedule
+			return
+		}
+		addr := addrs[next]
+		address := net.JoinHostPort(addr, port)
+		next++
+		running++
+		go func() {
+			conn, err := d.Dialer.DialContext(ctx, network, address)
+			attempts <- &happyEyeballsAttempt{addr: addr, address: address, conn: conn, err: err}
+		}()
+		timer = time.After(d.Delay)
+	}
+
+	for startNext(); running > 0; {
+		select {
+		case <-timer:
+			startNext()
+
+		case attempt := <-attempts:
+			running--
+			if attempt.err == nil {
+				go happyEyeballsCloseLateConns(attempts, running)
+				return attempt.conn, attempt.address, nil
+			}
+			errorsmap[attempt.addr] = attempt.err
+			startNext() // do not wait for the timer after a failure
+		}
+	}
+	return nil, "", happyEyeballsReduceErrors(addrs, errorsmap)
+}
+
+// happyEyeballsReduceErrors reduces the errors of the failed attempts. Because
+// quirkReduceErrors assumes that IPv4 addrs appear before IPv6 addrs while the
+// attempts fail in whatever order, we sort the errors using quirkSortIPAddrs to
+// obtain the same error the sequential dialer would return.
+func happyEyeballsReduceErrors(addrs []string, errorsmap map[string]error) error {
+	var errorslist []error
+	for _, addr := range quirkSortIPAddrs(addrs) {
+		if err, found := errorsmap[addr]; found {
+			errorslist = append(errorslist, err)
+			delete(errorsmap, addr) // handle duplicate addrs
+		}
+	}
+	return quirkReduceErrors(errorslist)
+}
+
+// happyEyeballsCloseLateConns closes the conns of the attempts that
+// were still running when another attempt won the race.
+func happyEyeballsCloseLateConns(attempts <-chan *happyEyeballsAttempt, running int) {
+	for ; running > 0; running-- {
+		if attempt := <-attempts; attempt.err == nil {
+			attempt.conn.Close()
+		}
+	}
+}
+
+// lookupHost ensures we correctly handle IP addresses.
+func (d *dialerHappyEyeballs) lookupHost(ctx context.Context, hostname string) ([]string, error) {
+	if net.ParseIP(hostname) != nil {
+		return []string{hostname}, nil
+	}
+	return d.Resolver.LookupHost(ctx, hostname)
+}
+
+// CloseIdleConnections implements model.Dialer.CloseIdleConnections.
+func (d *dialerHappyEyeballs) CloseIdleConnections() {
+	d.Dialer.CloseIdleConnections()
+	d.Resolver.CloseIdleConnections()
+}
+
+// happyEyeballsSortIPAddrs sorts IP addresses such that the address
+// families are interleaved starting with IPv6, as recommended by RFC 8305
+// Section 4, while preserving the relative order of the addresses
+// within each family. This function skips any input that is not a
+// valid IPv4 or IPv6 address.
+func happyEyeballsSortIPAddrs(addrs []string) (out []string) {
+	var ipv4, ipv6 []string
+	for _, addr := range addrs {
+		switch {
+		case net.ParseIP(addr) == nil:
+			// skip
+		case isIPv6(addr):
+			ipv6 = append(ipv6, addr)
+		default:
+			ipv4 = append(ipv4, addr)
+		}
+	}
+	for len(ipv4) > 0 || len(ipv6) > 0 {
+		if len(ipv6) > 0 {
+			out = append(out, ipv6[0])
+			ipv6 = ipv6[1:]
+		}
+		if len(ipv4) > 0 {
+			out = append(out, ipv4[0])
+			ipv4 = ipv4[1:]
+		}
+	}
+	return
+}
+
+// HappyEyeballsWinnerFamily returns the IP address family ("ipv4" or "ipv6") of the
+// given winner endpoint passed to the [model.Trace] OnHappyEyeballsDone method or an
+// empty string when the endpoint is not a valid IP endpoint (e.g., on failure).
+func HappyEyeballsWinnerFamily(winner string) string {
+	addr, _, err := net.SplitHostPort(winner)
+	if err != nil || net.ParseIP(addr) == nil {
+		return ""
+	}
+	if isIPv6(addr) {
+		return "ipv6"
+	}
+	return "ipv4"
+}
@@ -0,0 +1,360 @@
+package netxlite
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestNewDialerHappyEyeballs(t *testing.T) {
+	netx := &Netx{}
+	reso := &mocks.Resolver{}
+	d := netx.NewDialerHappyEyeballs(model.DiscardLogger, reso)
+	logger := d.(*dialerLogger)
+	if logger.DebugLogger != model.DiscardLogger {
+		t.Fatal("invalid logger")
+	}
+	dhe := logger.Dialer.(*dialerHappyEyeballs)
+	if dhe.Delay != HappyEyeballsConnectionAttemptDelay {
+		t.Fatal("invalid delay")
+	}
+	if dhe.Resolver != reso {
+		t.Fatal("invalid resolver")
+	}
+	logger = dhe.Dialer.(*dialerLogger)
+	_ = logger.Dialer.(*dialerResolverWithTracing)
+}
+
+func TestDialerHappyEyeballs(t *testing.T) {
+	// newResolver returns a resolver resolving to the given addrs.
+	newResolver := func(addrs ...string) model.Resolver {
+		return &mocks.Resolver{
+			MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+				return addrs, nil
+			},
+		}
+	}
+
+	// newConn returns a conn recording whether we closed it.
+	newConn := func(closed *atomic.Int64) net.Conn {
+		return &mocks.Conn{
+			MockClose: func() error {
+				closed.Add(1)
+				return nil
+			},
+		}
+	}
+
+	// newTrace returns a trace saving the winner and the error.
+	newTrace := func(winner *string, failure *error) model.Trace {
+		return &mocks.Trace{
+			MockTimeNow: time.Now,
+			MockOnHappyEyeballsDone: func(started time.Time, network, domain, w string, err error, finished time.Time) {
+				if network != "tcp" || domain != "www.example.com" {
+					panic("unexpected network or domain")
+				}
+				*winner, *failure = w, err
+			},
+		}
+	}
+
+	t.Run("with invalid address", func(t *testing.T) {
+		d := &dialerHappyEyeballs{}
+		conn, err := d.DialContext(context.Background(), "tcp", "www.example.com")
+		if err == nil || err.Error() != "address www.example.com: missing port in address" {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("with resolver failure", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		d := &dialerHappyEyeballs{
+			Resolver: &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return nil, expected
+				},
+			},
+		}
+		conn, err := d.DialContext(context.Background(), "tcp", "www.example.com:443")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("with IP address we do not use the resolver", func(t *testing.T) {
+		var closed atomic.Int64
+		d := &dialerHappyEyeballs{
+			Delay: time.Second,
+			Dialer: &mocks.Dialer{
+				MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					if address != "[::1]:443" {
+						panic("unexpected address")
+					}
+					return newConn(&closed), nil
+				},
+			},
+			Resolver: nil, // would crash if we used it
+		}
+		conn, err := d.DialContext(context.Background(), "tcp", "[::1]:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	})
+
+	t.Run("IPv4 wins when IPv6 fails immediately", func(t *testing.T) {
+		var closed atomic.Int64
+		d := &dialerHappyEyeballs{
+			Delay: time.Hour, // we should not wait after a failure
+			Dialer: &mocks.Dialer{
+				MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					if address == "[2001:db8::1]:443" {
+						return nil, NewTopLevelGenericErrWrapper(errors.New(FailureNetworkUnreachable))
+					}
+					return newConn(&closed), nil
+				},
+			},
+			Resolver: newResolver("93.184.216.34", "2001:db8::1"),
+		}
+		var (
+			winner  string
+			failure error
+		)
+		ctx := ContextWithTrace(context.Background(), newTrace(&winner, &failure))
+		conn, err := d.DialContext(ctx, "tcp", "www.example.com:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		if winner != "93.184.216.34:443" || failure != nil {
+			t.Fatal("unexpected winner or failure", winner, failure)
+		}
+		if HappyEyeballsWinnerFamily(winner) != "ipv4" {
+			t.Fatal("unexpected winner family")
+		}
+	})
+
+	t.Run("IPv4 wins when IPv6 is slow and we close late conns", func(t *testing.T) {
+		var closed atomic.Int64
+		ipv6done := make(chan any)
+		d := &dialerHappyEyeballs{
+			Delay: 10 * time.Millisecond,
+			Dialer: &mocks.Dialer{
+				MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					if address == "[2001:db8::1]:443" {
+						defer close(ipv6done)
+						<-ctx.Done() // we should be canceled by the winner
+						return newConn(&closed), nil
+					}
+					return newConn(&closed), nil
+				},
+			},
+			Resolver: newResolver("93.184.216.34", "2001:db8::1"),
+		}
+		var (
+			winner  string
+			failure error
+		)
+		ctx := ContextWithTrace(context.Background(), newTrace(&winner, &failure))
+		conn, err := d.DialContext(ctx, "tcp", "www.example.com:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if winner != "93.184.216.34:443" || failure != nil {
+			t.Fatal("unexpected winner or failure", winner, failure)
+		}
+		<-ipv6done
+		// the late conn should eventually be closed
+		for closed.Load() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+		conn.Close()
+	})
+
+	t.Run("IPv6 wins when it is fast enough", func(t *testing.T) {
+		var (
+			closed atomic.Int64
+			count  atomic.Int64
+		)
+		d := &dialerHappyEyeballs{
+			Delay: time.Hour,
+			Dialer: &mocks.Dialer{
+				MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					count.Add(1)
+					return newConn(&closed), nil
+				},
+			},
+			Resolver: newResolver("93.184.216.34", "2001:db8::1"),
+		}
+		var (
+			winner  string
+			failure error
+		)
+		ctx := ContextWithTrace(context.Background(), newTrace(&winner, &failure))
+		conn, err := d.DialContext(ctx, "tcp", "www.example.com:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		if winner != "[2001:db8::1]:443" || failure != nil {
+			t.Fatal("unexpected winner or failure", winner, failure)
+		}
+		if HappyEyeballsWinnerFamily(winner) != "ipv6" {
+			t.Fatal("unexpected winner family")
+		}
+		if count.Load() != 1 {
+			t.Fatal("expected a single attempt")
+		}
+	})
+
+	t.Run("when all attempts fail", func(t *testing.T) {
+		d := &dialerHappyEyeballs{
+			Delay: time.Millisecond,
+			Dialer: &mocks.Dialer{
+				MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					if address == "[2001:db8::1]:443" {
+						return nil, NewErrWrapper(ClassifyGenericError, ConnectOperation, ENETUNREACH)
+					}
+					return nil, NewErrWrapper(ClassifyGenericError, ConnectOperation, ECONNREFUSED)
+				},
+			},
+			Resolver: newResolver("93.184.216.34", "2001:db8::1"),
+		}
+		var (
+			winner  string
+			failure error
+		)
+		ctx := ContextWithTrace(context.Background(), newTrace(&winner, &failure))
+		conn, err := d.DialContext(ctx, "tcp", "www.example.com:443")
+		// the IPv6 attempt fails first but we want the same error the
+		// sequential dialer would return, i.e., the IPv4 one
+		if err == nil || err.Error() != FailureConnectionRefused {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+		if winner != "" || failure != err {
+			t.Fatal("unexpected winner or failure", winner, failure)
+		}
+		if HappyEyeballsWinnerFamily(winner) != "" {
+			t.Fatal("unexpected winner family")
+		}
+	})
+
+	t.Run("with no addresses", func(t *testing.T) {
+		d := &dialerHappyEyeballs{
+			Resolver: newResolver(),
+		}
+		conn, err := d.DialContext(context.Background(), "tcp", "www.example.com:443")
+		if !errors.Is(err, errReduceErrorsEmptyList) {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("CloseIdleConnections", func(t *testing.T) {
+		var calledDialer, calledResolver bool
+		d := &dialerHappyEyeballs{
+			Dialer: &mocks.Dialer{
+				MockCloseIdleConnections: func() {
+					calledDialer = true
+				},
+			},
+			Resolver: &mocks.Resolver{
+				MockCloseIdleConnections: func() {
+					calledResolver = true
+				},
+			},
+		}
+		d.CloseIdleConnections()
+		if !calledDialer || !calledResolver {
+			t.Fatal("not called")
+		}
+	})
+}
+
+func TestHappyEyeballsReduceErrors(t *testing.T) {
+	t.Run("we reduce errors using the IPv4-first order", func(t *testing.T) {
+		addrs := []string{"2001:db8::1", "93.184.216.34", "2001:db8::2", "93.184.216.35"}
+		errorsmap := map[string]error{
+			"2001:db8::1":   NewErrWrapper(ClassifyGenericError, ConnectOperation, ENETUNREACH),
+			"93.184.216.34": errors.New("mocked error"),
+			"2001:db8::2":   NewErrWrapper(ClassifyGenericError, ConnectOperation, ENETUNREACH),
+			"93.184.216.35": NewErrWrapper(ClassifyGenericError, ConnectOperation, ECONNREFUSED),
+		}
+		err := happyEyeballsReduceErrors(addrs, errorsmap)
+		if err == nil || err.Error() != FailureConnectionRefused {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with duplicate addrs", func(t *testing.T) {
+		addrs := []string{"93.184.216.34", "93.184.216.34"}
+		expect := errors.New("mocked error")
+		errorsmap := map[string]error{"93.184.216.34": expect}
+		err := happyEyeballsReduceErrors(addrs, errorsmap)
+		if err != expect {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestHappyEyeballsSortIPAddrs(t *testing.T) {
+	t.Run("with some addrs", func(t *testing.T) {
+		addrs := []string{
+			"192.0.2.1",
+			"2001:db8::1",
+			"example.com",
+			"192.0.2.2",
+			"192.0.2.3",
+			"2001:db8::2",
+		}
+		expected := []string{
+			"2001:db8::1",
+			"192.0.2.1",
+			"2001:db8::2",
+			"192.0.2.2",
+			"192.0.2.3",
+		}
+		if diff := cmp.Diff(expected, happyEyeballsSortIPAddrs(addrs)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with an empty list", func(t *testing.T) {
+		if happyEyeballsSortIPAddrs(nil) != nil {
+			t.Fatal("expected nil output")
+		}
+	})
+}
+
+func TestHappyEyeballsWinnerFamily(t *testing.T) {
+	expect := map[string]string{
+		"":                   "",
+		"8.8.8.8:443":        "ipv4",
+		"[2001:db8::1]:443":  "ipv6",
+		"www.example.com:80": "",
+		"8.8.8.8":            "",
+	}
+	for input, family := range expect {
+		if got := HappyEyeballsWinnerFamily(input); got != family {
+			t.Fatal("for", input, "expected", family, "got", got)
+		}
+	}
+}
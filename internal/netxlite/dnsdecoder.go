@@ -7,6 +7,7 @@ package netxlite
 import (
 	"errors"
 	"net"
+	"strings"
 
 	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -188,5 +189,55 @@ func (r *dnsResponse) DecodeCNAME() (string, error) {
 	return "", dnsDecoderWrapError(ErrOODNSNoAnswer)
 }
 
+// DecodeRecords implements model.DNSResponse.DecodeRecords.
+func (r *dnsResponse) DecodeRecords() ([]*model.DNSRecord, error) {
+	if err := r.rcodeToError(); err != nil {
+		return nil, err // error already wrapped
+	}
+	var (
+		out   []*model.DNSRecord
+		found bool
+	)
+	for _, answer := range r.msg.Answer {
+		header := answer.Header()
+		found = found || header.Rrtype == r.Query().Type()
+		out = append(out, &model.DNSRecord{
+			Name:   header.Name,
+			Type:   header.Rrtype,
+			TTL:    header.Ttl,
+			Target: dnsRecordTarget(answer),
+			Value:  strings.TrimPrefix(answer.String(), header.String()),
+		})
+	}
+	if !found {
+		return nil, dnsDecoderWrapError(ErrOODNSNoAnswer)
+	}
+	return out, nil
+}
+
+// dnsRecordTarget returns the hostname a record points to, if any.
+func dnsRecordTarget(answer dns.RR) string {
+	switch avalue := answer.(type) {
+	case *dns.CNAME:
+		return avalue.Target
+	case *dns.DNAME:
+		return avalue.Target
+	case *dns.NS:
+		return avalue.Ns
+	case *dns.PTR:
+		return avalue.Ptr
+	case *dns.MX:
+		return avalue.Mx
+	case *dns.SRV:
+		return avalue.Target
+	case *dns.SVCB:
+		return avalue.Target
+	case *dns.HTTPS:
+		return avalue.Target
+	default:
+		return ""
+	}
+}
+
 var _ model.DNSDecoder = &DNSDecoderMiekg{}
 var _ model.DNSResponse = &dnsResponse{}
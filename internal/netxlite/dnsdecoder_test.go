@@ -9,6 +9,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
@@ -607,6 +608,166 @@ func TestDNSDecoderMiekg(t *testing.T) {
 				}
 			})
 		})
+
+		t.Run("dnsResponse.DecodeRecords", func(t *testing.T) {
+			t.Run("with failure", func(t *testing.T) {
+				// Ensure that we're not trying to decode if rcode != 0
+				d := &DNSDecoderMiekg{}
+				queryID := dns.Id()
+				rawQuery := dnsGenQuery(dns.TypeTXT, queryID)
+				rawResponse := dnsGenReplyWithError(rawQuery, dns.RcodeRefused)
+				query := &mocks.DNSQuery{
+					MockID: func() uint16 {
+						return queryID
+					},
+				}
+				resp, err := d.DecodeResponse(rawResponse, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				records, err := resp.DecodeRecords()
+				if !errors.Is(err, ErrOODNSRefused) {
+					t.Fatal("unexpected err", err)
+				}
+				if len(records) > 0 {
+					t.Fatal("expected no records")
+				}
+			})
+
+			t.Run("without records matching the query type", func(t *testing.T) {
+				d := &DNSDecoderMiekg{}
+				queryID := dns.Id()
+				rawQuery := dnsGenQuery(dns.TypeTXT, queryID)
+				rawResponse := dnsGenRecordsReplySuccess(rawQuery, &dns.CNAME{
+					Hdr: dns.RR_Header{
+						Name:   dns.Fqdn("x.org"),
+						Rrtype: dns.TypeCNAME,
+						Class:  dns.ClassINET,
+						Ttl:    300,
+					},
+					Target: dns.Fqdn("y.org"),
+				})
+				query := &mocks.DNSQuery{
+					MockID: func() uint16 {
+						return queryID
+					},
+					MockType: func() uint16 {
+						return dns.TypeTXT
+					},
+				}
+				resp, err := d.DecodeResponse(rawResponse, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				records, err := resp.DecodeRecords()
+				if !errors.Is(err, ErrOODNSNoAnswer) {
+					t.Fatal("unexpected err", err)
+				}
+				if !dnsDecoderErrorIsWrapped(err) {
+					t.Fatal("unwrapped error", err)
+				}
+				if len(records) > 0 {
+					t.Fatal("expected no records")
+				}
+			})
+
+			t.Run("with records including a CNAME chain", func(t *testing.T) {
+				d := &DNSDecoderMiekg{}
+				queryID := dns.Id()
+				rawQuery := dnsGenQuery(dns.TypeMX, queryID)
+				rawResponse := dnsGenRecordsReplySuccess(rawQuery, &dns.CNAME{
+					Hdr: dns.RR_Header{
+						Name:   dns.Fqdn("x.org"),
+						Rrtype: dns.TypeCNAME,
+						Class:  dns.ClassINET,
+						Ttl:    300,
+					},
+					Target: dns.Fqdn("y.org"),
+				}, &dns.MX{
+					Hdr: dns.RR_Header{
+						Name:   dns.Fqdn("y.org"),
+						Rrtype: dns.TypeMX,
+						Class:  dns.ClassINET,
+						Ttl:    60,
+					},
+					Preference: 10,
+					Mx:         dns.Fqdn("mx.y.org"),
+				})
+				query := &mocks.DNSQuery{
+					MockID: func() uint16 {
+						return queryID
+					},
+					MockType: func() uint16 {
+						return dns.TypeMX
+					},
+				}
+				resp, err := d.DecodeResponse(rawResponse, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				records, err := resp.DecodeRecords()
+				if err != nil {
+					t.Fatal(err)
+				}
+				expect := []*model.DNSRecord{{
+					Name:   "x.org.",
+					Type:   dns.TypeCNAME,
+					TTL:    300,
+					Target: "y.org.",
+					Value:  "y.org.",
+				}, {
+					Name:   "y.org.",
+					Type:   dns.TypeMX,
+					TTL:    60,
+					Target: "mx.y.org.",
+					Value:  "10 mx.y.org.",
+				}}
+				if diff := cmp.Diff(expect, records); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+
+			t.Run("with TXT records", func(t *testing.T) {
+				d := &DNSDecoderMiekg{}
+				queryID := dns.Id()
+				rawQuery := dnsGenQuery(dns.TypeTXT, queryID)
+				rawResponse := dnsGenRecordsReplySuccess(rawQuery, &dns.TXT{
+					Hdr: dns.RR_Header{
+						Name:   dns.Fqdn("x.org"),
+						Rrtype: dns.TypeTXT,
+						Class:  dns.ClassINET,
+						Ttl:    3600,
+					},
+					Txt: []string{"v=spf1 -all"},
+				})
+				query := &mocks.DNSQuery{
+					MockID: func() uint16 {
+						return queryID
+					},
+					MockType: func() uint16 {
+						return dns.TypeTXT
+					},
+				}
+				resp, err := d.DecodeResponse(rawResponse, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				records, err := resp.DecodeRecords()
+				if err != nil {
+					t.Fatal(err)
+				}
+				expect := []*model.DNSRecord{{
+					Name:   "x.org.",
+					Type:   dns.TypeTXT,
+					TTL:    3600,
+					Target: "",
+					Value:  `"v=spf1 -all"`,
+				}}
+				if diff := cmp.Diff(expect, records); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		})
 	})
 }
 
@@ -755,6 +916,22 @@ func dnsGenHTTPSReplySuccess(rawQuery []byte, alpns, ipv4s, ipv6s []string) []by
 	return data
 }
 
+// dnsGenRecordsReplySuccess generates a successful reply containing the given records.
+func dnsGenRecordsReplySuccess(rawQuery []byte, records ...dns.RR) []byte {
+	query := new(dns.Msg)
+	err := query.Unpack(rawQuery)
+	runtimex.PanicOnError(err, "query.Unpack failed")
+	runtimex.Assert(len(query.Question) == 1, "expected just a single question")
+	reply := new(dns.Msg)
+	reply.Compress = true
+	reply.MsgHdr.RecursionAvailable = true
+	reply.SetReply(query)
+	reply.Answer = append(reply.Answer, records...)
+	data, err := reply.Pack()
+	runtimex.PanicOnError(err, "reply.Pack failed")
+	return data
+}
+
 // dnsGenNSReplySuccess generates a successful NS reply using the given names.
 func dnsGenNSReplySuccess(rawQuery []byte, names ...string) []byte {
 	query := new(dns.Msg)
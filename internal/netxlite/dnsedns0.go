@@ -0,0 +1,130 @@
+package netxlite
+
+//
+// Per-query EDNS0 options (e.g., client subnet, cookies)
+//
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// dnsEDNS0OptionsKey is the private type used to set/retrieve the context's EDNS0 options.
+type dnsEDNS0OptionsKey struct{}
+
+// ContextWithDNSEDNS0Options returns a new context that binds to the given EDNS0
+// options. The [*ParallelResolver] and the [*SerialResolver] include these options
+// into every query sent using the returned context. Calling this function with no
+// options returns a context where no extra options are configured.
+func ContextWithDNSEDNS0Options(ctx context.Context, options ...dns.EDNS0) context.Context {
+	return context.WithValue(ctx, dnsEDNS0OptionsKey{}, options)
+}
+
+// ContextDNSEDNS0Options returns the EDNS0 options bound to the context, if any.
+func ContextDNSEDNS0Options(ctx context.Context) []dns.EDNS0 {
+	options, _ := ctx.Value(dnsEDNS0OptionsKey{}).([]dns.EDNS0)
+	return options
+}
+
+// ErrInvalidEDNS0Option indicates that we cannot parse an EDNS0 option.
+var ErrInvalidEDNS0Option = errors.New("netxlite: invalid EDNS0 option")
+
+// NewDNSEDNS0ClientSubnet creates a new EDNS0 client subnet (ECS) option (RFC 7871) using
+// the given prefix in CIDR notation (e.g., "203.0.113.0/24" or "2001:db8::/56").
+func NewDNSEDNS0ClientSubnet(prefix string) (*dns.EDNS0_SUBNET, error) {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEDNS0Option, err.Error())
+	}
+	ones, _ := network.Mask.Size()
+	option := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: uint8(ones),
+		SourceScope:   0,
+		Address:       network.IP,
+	}
+	if network.IP.To4() == nil {
+		option.Family = 2
+	}
+	return option, nil
+}
+
+// dnsClientCookieSize is the size of the client cookie (RFC 7873 Sect. 4).
+const dnsClientCookieSize = 8
+
+// NewDNSEDNS0Cookie creates a new EDNS0 cookie option (RFC 7873) containing
+// a random client cookie and no server cookie.
+func NewDNSEDNS0Cookie() *dns.EDNS0_COOKIE {
+	cookie := make([]byte, dnsClientCookieSize)
+	_, _ = rand.Read(cookie) // never fails according to the documentation
+	return &dns.EDNS0_COOKIE{
+		Code:   dns.EDNS0COOKIE,
+		Cookie: hex.EncodeToString(cookie),
+	}
+}
+
+// ParseDNSEDNS0Options parses a space-separated list of EDNS0 options. We support
+// the following syntax for each option:
+//
+// - "ecs=PREFIX" adds a client subnet option for the given PREFIX (e.g., "ecs=203.0.113.0/24");
+//
+// - "cookie" adds a random client cookie, while "cookie=HEX" uses the given cookie;
+//
+// - "nsid" asks the server to return its name server identifier (RFC 5001);
+//
+// - "CODE" or "CODE=HEX" adds an arbitrary option using the given numeric
+// CODE and the hex-encoded data, if any (e.g., "65001=deadbeef").
+//
+// An empty string is a valid input and returns no options.
+func ParseDNSEDNS0Options(spec string) ([]dns.EDNS0, error) {
+	var options []dns.EDNS0
+	for _, entry := range strings.Fields(spec) {
+		option, err := parseDNSEDNS0Option(entry)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// parseDNSEDNS0Option parses a single option for ParseDNSEDNS0Options.
+func parseDNSEDNS0Option(entry string) (dns.EDNS0, error) {
+	name, value, hasValue := strings.Cut(entry, "=")
+	switch name {
+	case "ecs":
+		return NewDNSEDNS0ClientSubnet(value)
+
+	case "cookie":
+		if !hasValue {
+			return NewDNSEDNS0Cookie(), nil
+		}
+		if _, err := hex.DecodeString(value); err != nil || len(value) <= 0 {
+			return nil, fmt.Errorf("%w: invalid cookie: %s", ErrInvalidEDNS0Option, entry)
+		}
+		return &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: value}, nil
+
+	case "nsid":
+		return &dns.EDNS0_NSID{Code: dns.EDNS0NSID}, nil
+
+	default:
+		code, err := strconv.ParseUint(name, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown option: %s", ErrInvalidEDNS0Option, entry)
+		}
+		data, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid data: %s", ErrInvalidEDNS0Option, entry)
+		}
+		return &dns.EDNS0_LOCAL{Code: uint16(code), Data: data}, nil
+	}
+}
@@ -0,0 +1,128 @@
+package netxlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestContextDNSEDNS0Options(t *testing.T) {
+	t.Run("without configured options we get nil", func(t *testing.T) {
+		if options := ContextDNSEDNS0Options(context.Background()); options != nil {
+			t.Fatal("expected nil options")
+		}
+	})
+
+	t.Run("with configured options we get the expected options", func(t *testing.T) {
+		option := &dns.EDNS0_NSID{Code: dns.EDNS0NSID}
+		ctx := ContextWithDNSEDNS0Options(context.Background(), option)
+		options := ContextDNSEDNS0Options(ctx)
+		if len(options) != 1 || options[0] != option {
+			t.Fatal("unexpected options", options)
+		}
+	})
+}
+
+func TestNewDNSEDNS0ClientSubnet(t *testing.T) {
+	t.Run("with an IPv4 prefix", func(t *testing.T) {
+		option, err := NewDNSEDNS0ClientSubnet("203.0.113.17/24")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if option.Family != 1 || option.SourceNetmask != 24 || option.Address.String() != "203.0.113.0" {
+			t.Fatal("unexpected option", option)
+		}
+	})
+
+	t.Run("with an IPv6 prefix", func(t *testing.T) {
+		option, err := NewDNSEDNS0ClientSubnet("2001:db8::/56")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if option.Family != 2 || option.SourceNetmask != 56 || option.Address.String() != "2001:db8::" {
+			t.Fatal("unexpected option", option)
+		}
+	})
+
+	t.Run("with an invalid prefix", func(t *testing.T) {
+		option, err := NewDNSEDNS0ClientSubnet("203.0.113.17")
+		if !errors.Is(err, ErrInvalidEDNS0Option) {
+			t.Fatal("unexpected error", err)
+		}
+		if option != nil {
+			t.Fatal("expected nil option")
+		}
+	})
+}
+
+func TestNewDNSEDNS0Cookie(t *testing.T) {
+	first, second := NewDNSEDNS0Cookie(), NewDNSEDNS0Cookie()
+	if len(first.Cookie) != 2*dnsClientCookieSize {
+		t.Fatal("unexpected cookie length", len(first.Cookie))
+	}
+	if first.Cookie == second.Cookie {
+		t.Fatal("expected random cookies")
+	}
+}
+
+func TestParseDNSEDNS0Options(t *testing.T) {
+	t.Run("with an empty spec", func(t *testing.T) {
+		options, err := ParseDNSEDNS0Options("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(options) != 0 {
+			t.Fatal("expected no options")
+		}
+	})
+
+	t.Run("with all the supported options", func(t *testing.T) {
+		spec := "ecs=203.0.113.0/24 cookie cookie=0102030405060708 nsid 65001=cafe 65002"
+		options, err := ParseDNSEDNS0Options(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(options) != 6 {
+			t.Fatal("unexpected number of options", len(options))
+		}
+		if _, good := options[0].(*dns.EDNS0_SUBNET); !good {
+			t.Fatal("expected ECS option", options[0])
+		}
+		if opt, good := options[1].(*dns.EDNS0_COOKIE); !good || len(opt.Cookie) != 2*dnsClientCookieSize {
+			t.Fatal("expected random cookie option", options[1])
+		}
+		if opt, good := options[2].(*dns.EDNS0_COOKIE); !good || opt.Cookie != "0102030405060708" {
+			t.Fatal("expected fixed cookie option", options[2])
+		}
+		if _, good := options[3].(*dns.EDNS0_NSID); !good {
+			t.Fatal("expected NSID option", options[3])
+		}
+		if opt, good := options[4].(*dns.EDNS0_LOCAL); !good || opt.Code != 65001 || string(opt.Data) != "\xca\xfe" {
+			t.Fatal("expected local option with data", options[4])
+		}
+		if opt, good := options[5].(*dns.EDNS0_LOCAL); !good || opt.Code != 65002 || len(opt.Data) != 0 {
+			t.Fatal("expected local option without data", options[5])
+		}
+	})
+
+	for _, spec := range []string{
+		"ecs=antani",
+		"cookie=",
+		"cookie=zz",
+		"antani",
+		"70000=cafe",
+		"65001=zz",
+	} {
+		t.Run("with invalid spec "+spec, func(t *testing.T) {
+			options, err := ParseDNSEDNS0Options("nsid " + spec)
+			if !errors.Is(err, ErrInvalidEDNS0Option) {
+				t.Fatal("unexpected error", err)
+			}
+			if len(options) != 0 {
+				t.Fatal("expected no options")
+			}
+		})
+	}
+}
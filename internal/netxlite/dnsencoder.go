@@ -13,7 +13,20 @@ import (
 )
 
 // DNSEncoderMiekg uses github.com/miekg/dns to implement the Encoder.
-type DNSEncoderMiekg struct{}
+//
+// The zero value is ready to use and does not request DNSSEC records
+// unless we are also adding padding to the query.
+type DNSEncoderMiekg struct {
+	// DNSSEC OPTIONALLY causes the encoder to always set the DNSSEC OK (DO)
+	// bit and the checking disabled (CD) bit, so that the server returns the
+	// DNSSEC records even when they do not validate.
+	DNSSEC bool
+
+	// EDNS0 OPTIONALLY contains extra EDNS0 options (e.g., client subnet,
+	// cookies) to include into every query. When this field is not empty, the
+	// encoder always adds an OPT record to the query.
+	EDNS0 []dns.EDNS0
+}
 
 const (
 	// dnsPaddingDesiredBlockSize is the size that the padded query should be multiple of
@@ -30,7 +43,9 @@ const (
 func (e *DNSEncoderMiekg) Encode(domain string, qtype uint16, padding bool) model.DNSQuery {
 	return &dnsQuery{
 		bytesCalls:    &atomic.Int64{},
+		dnssec:        e.DNSSEC,
 		domain:        domain,
+		edns0:         e.EDNS0,
 		kind:          qtype,
 		id:            dns.Id(),
 		memoizedBytes: []byte{},
@@ -44,9 +59,15 @@ type dnsQuery struct {
 	// bytesCalls counts the calls to the bytes() method
 	bytesCalls *atomic.Int64
 
+	// dnssec indicates whether to request DNSSEC records.
+	dnssec bool
+
 	// domain is the domain.
 	domain string
 
+	// edns0 contains extra EDNS0 options.
+	edns0 []dns.EDNS0
+
 	// kind is the query type.
 	kind uint16
 
@@ -101,8 +122,16 @@ func (q *dnsQuery) bytes() ([]byte, error) {
 	query.RecursionDesired = true
 	query.Question = make([]dns.Question, 1)
 	query.Question[0] = question
+	if q.dnssec {
+		query.CheckingDisabled = true
+	}
+	if q.dnssec || q.padding || len(q.edns0) > 0 {
+		query.SetEdns0(dnsEDNS0MaxResponseSize, q.dnssec || (q.padding && dnsDNSSECEnabled))
+		query.IsEdns0().Option = append(query.IsEdns0().Option, q.edns0...)
+	}
 	if q.padding {
-		query.SetEdns0(dnsEDNS0MaxResponseSize, dnsDNSSECEnabled)
+		// Note: we add padding last such that its size accounts for the other options.
+		//
 		// Clients SHOULD pad queries to the closest multiple of
 		// 128 octets RFC8467#section-4.1. We inflate the query
 		// length by the size of the option (i.e. 4 octets). The
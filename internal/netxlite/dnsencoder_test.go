@@ -100,6 +100,71 @@ func TestDNSEncoderMiekg(t *testing.T) {
 		dnsValidateEncodedQueryBytes(t, data, byte(dns.TypeA), query.ID())
 	})
 
+	t.Run("encode DNSSEC", func(t *testing.T) {
+		for _, padding := range []bool{false, true} {
+			e := &DNSEncoderMiekg{DNSSEC: true}
+			query := e.Encode("x.org", dns.TypeA, padding)
+			data, err := query.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := &dns.Msg{}
+			if err := msg.Unpack(data); err != nil {
+				t.Fatal(err)
+			}
+			if !msg.CheckingDisabled {
+				t.Fatal("expected the CD bit to be set")
+			}
+			if len(msg.Extra) != 1 {
+				t.Fatal("expected exactly one OPT record")
+			}
+			opt := msg.IsEdns0()
+			if opt == nil || !opt.Do() {
+				t.Fatal("expected the DO bit to be set")
+			}
+			if padding && len(data)%dnsPaddingDesiredBlockSize != 0 {
+				t.Fatal("expected the query to be padded")
+			}
+		}
+	})
+
+	t.Run("encode EDNS0 options", func(t *testing.T) {
+		for _, padding := range []bool{false, true} {
+			ecs := runtimex.Try1(NewDNSEDNS0ClientSubnet("203.0.113.0/24"))
+			e := &DNSEncoderMiekg{EDNS0: []dns.EDNS0{ecs}}
+			query := e.Encode("x.org", dns.TypeA, padding)
+			data, err := query.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := &dns.Msg{}
+			if err := msg.Unpack(data); err != nil {
+				t.Fatal(err)
+			}
+			opt := msg.IsEdns0()
+			if opt == nil {
+				t.Fatal("expected an OPT record")
+			}
+			if opt.Do() != padding {
+				t.Fatal("unexpected DO bit value")
+			}
+			expectOptions := 1
+			if padding {
+				expectOptions++
+			}
+			if len(opt.Option) != expectOptions {
+				t.Fatal("unexpected number of options", len(opt.Option))
+			}
+			subnet, good := opt.Option[0].(*dns.EDNS0_SUBNET)
+			if !good || subnet.SourceNetmask != 24 || subnet.Address.String() != "203.0.113.0" {
+				t.Fatal("unexpected first option", opt.Option[0])
+			}
+			if padding && len(data)%dnsPaddingDesiredBlockSize != 0 {
+				t.Fatal("expected the query to be padded")
+			}
+		}
+	})
+
 	t.Run("encode padding", func(t *testing.T) {
 		// The purpose of this unit test is to make sure that for a wide
 		// array of values we obtain the right query size.
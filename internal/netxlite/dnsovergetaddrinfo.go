@@ -165,3 +165,7 @@ func (r *dnsOverGetaddrinfoResponse) DecodeCNAME() (string, error) {
 	}
 	return r.cname, nil
 }
+
+func (r *dnsOverGetaddrinfoResponse) DecodeRecords() ([]*model.DNSRecord, error) {
+	return nil, ErrNoDNSTransport
+}
@@ -347,6 +347,21 @@ func TestDNSOverGetaddrinfoResponse(t *testing.T) {
 		}
 	})
 
+	t.Run("DecodeRecords works as intended", func(t *testing.T) {
+		resp := &dnsOverGetaddrinfoResponse{
+			addrs: []string{},
+			cname: "",
+			query: nil,
+		}
+		out, err := resp.DecodeRecords()
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("unexpected err")
+		}
+		if len(out) != 0 {
+			t.Fatal("unexpected result")
+		}
+	})
+
 	t.Run("DecodeCNAME works as intended", func(t *testing.T) {
 		t.Run("on success", func(t *testing.T) {
 			resp := &dnsOverGetaddrinfoResponse{
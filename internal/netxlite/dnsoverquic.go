@@ -0,0 +1,162 @@
+package netxlite
+
+//
+// DNS-over-QUIC transport (RFC 9250)
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/quic-go/quic-go"
+)
+
+// DNSOverQUICTransport is a DNS-over-QUIC DNSTransport.
+//
+// Note: this implementation always creates a new QUIC connection for each query,
+// which is consistent with what [DNSOverTCPTransport] does. We send each query over
+// its own bidirectional stream as mandated by RFC 9250.
+type DNSOverQUICTransport struct {
+	// Decoder is the MANDATORY DNSDecoder to use.
+	Decoder model.DNSDecoder
+
+	// Dialer is the MANDATORY QUIC dialer to use.
+	Dialer model.QUICDialer
+
+	// Endpoint is the MANDATORY server's endpoint (e.g., dns.adguard-dns.com:853).
+	Endpoint string
+
+	// ServerName is the OPTIONAL SNI to use. When empty, the dialer
+	// will use the hostname contained inside of the Endpoint.
+	ServerName string
+}
+
+// NewUnwrappedDNSOverQUICTransport creates a new DNSOverQUICTransport
+// that has not been wrapped yet.
+//
+// Arguments:
+//
+// - dialer is any type that implements the QUICDialer interface;
+//
+// - address is the endpoint address (e.g., dns.adguard-dns.com:853).
+//
+// If the address contains a domain name, the dialer must be able to resolve
+// it and we will also use such a domain name as the SNI.
+func NewUnwrappedDNSOverQUICTransport(dialer model.QUICDialer, address string) *DNSOverQUICTransport {
+	return &DNSOverQUICTransport{
+		Decoder:    &DNSDecoderMiekg{},
+		Dialer:     dialer,
+		Endpoint:   address,
+		ServerName: "",
+	}
+}
+
+// dnsOverQUICNoError is the DOQ_NO_ERROR error code defined by RFC 9250.
+const dnsOverQUICNoError = quic.ApplicationErrorCode(0)
+
+// errQUICConnCannotOpenStreams indicates that we cannot open streams using the
+// given [model.QUICConn] because it does not wrap a [*quic.Conn].
+var errQUICConnCannotOpenStreams = errors.New("netxlite: QUIC conn cannot open streams")
+
+// errQueryTooSmall indicates the query does not even contain a message ID.
+var errQueryTooSmall = errors.New("oodns: query too small for this transport")
+
+// RoundTrip sends a query and receives a reply.
+func (t *DNSOverQUICTransport) RoundTrip(
+	ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+	rawQuery, err := query.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(rawQuery) > math.MaxUint16 {
+		return nil, errQueryTooLarge
+	}
+	if len(rawQuery) < 2 {
+		return nil, errQueryTooSmall
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	tlsConfig := &tls.Config{
+		NextProtos: []string{"doq"},
+		ServerName: t.ServerName,
+	}
+	qconn, err := t.Dialer.DialContext(ctx, t.Endpoint, tlsConfig, &quic.Config{})
+	if err != nil {
+		return nil, err
+	}
+	defer qconn.CloseWithError(dnsOverQUICNoError, "")
+	conn, err := quicConnForStreams(qconn)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+	// RFC 9250 Sect. 4.2.1 says the message ID MUST be zero. We restore
+	// the original ID before decoding, so the decoder can match it.
+	queryID := []byte{rawQuery[0], rawQuery[1]}
+	buf := []byte{byte(len(rawQuery) >> 8), byte(len(rawQuery)), 0, 0}
+	buf = append(buf, rawQuery[2:]...)
+	if _, err := stream.Write(buf); err != nil {
+		return nil, err
+	}
+	// RFC 9250 Sect. 4.2 says the client MUST send the STREAM FIN after the query.
+	if err := stream.Close(); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(stream, header); err != nil {
+		return nil, err
+	}
+	length := int(header[0])<<8 | int(header[1])
+	rawResponse := make([]byte, length)
+	if _, err := io.ReadFull(stream, rawResponse); err != nil {
+		return nil, err
+	}
+	copy(rawResponse, queryID) // no-op when the response is too short to be valid
+	return t.Decoder.DecodeResponse(rawResponse, query)
+}
+
+// quicConnForStreams unwraps a [model.QUICConn] to the concrete *quic.Conn
+// we need for opening streams, or returns an error if that's not possible.
+func quicConnForStreams(qconn model.QUICConn) (*quic.Conn, error) {
+	switch c := qconn.(type) {
+	case quicConnUnwrapper:
+		return c.unwrapForHTTP3(), nil
+	case *quic.Conn:
+		return c, nil
+	default:
+		return nil, errQUICConnCannotOpenStreams
+	}
+}
+
+// RequiresPadding returns true for DoQ according to RFC 9250.
+func (t *DNSOverQUICTransport) RequiresPadding() bool {
+	return true
+}
+
+// Network returns the transport network, i.e., "doq".
+func (t *DNSOverQUICTransport) Network() string {
+	return "doq"
+}
+
+// Address returns the upstream server endpoint (e.g., "dns.adguard-dns.com:853").
+func (t *DNSOverQUICTransport) Address() string {
+	return t.Endpoint
+}
+
+// CloseIdleConnections closes idle connections, if any.
+func (t *DNSOverQUICTransport) CloseIdleConnections() {
+	t.Dialer.CloseIdleConnections()
+}
+
+var _ model.DNSTransport = &DNSOverQUICTransport{}
@@ -0,0 +1,196 @@
+package netxlite
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+	"github.com/quic-go/quic-go"
+)
+
+func TestDNSOverQUICTransport(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		t.Run("cannot encode query", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return nil, expected
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("query too large", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, math.MaxUint16+1), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, errQueryTooLarge) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("query too small", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 1), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, errQueryTooSmall) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("dial failure", func(t *testing.T) {
+			mocked := errors.New("mocked error")
+			var gotConfig *tls.Config
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (model.QUICConn, error) {
+					gotConfig = tlsConfig
+					return nil, mocked
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, "9.9.9.9:853")
+			txp.ServerName = "dns.quad9.net"
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if diff := cmp.Diff([]string{"doq"}, gotConfig.NextProtos); diff != "" {
+				t.Fatal(diff)
+			}
+			if gotConfig.ServerName != "dns.quad9.net" {
+				t.Fatal("unexpected server name", gotConfig.ServerName)
+			}
+		})
+
+		t.Run("the conn cannot open streams", func(t *testing.T) {
+			var called bool
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (model.QUICConn, error) {
+					conn := &mocks.QUICConn{
+						MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+							called = true
+							return nil
+						},
+					}
+					return conn, nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, errQUICConnCannotOpenStreams) {
+				t.Fatal("not the error we expected", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if !called {
+				t.Fatal("did not close the conn")
+			}
+		})
+
+		t.Run("using a real server", func(t *testing.T) {
+			ca := netem.MustNewCA()
+			pconn := runtimex.Try1(net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}))
+			dnsConfig := netem.NewDNSConfig()
+			dnsConfig.AddRecord("dns.google", "", "8.8.8.8")
+			dnsRtx := testingx.NewDNSRoundTripperWithDNSConfig(dnsConfig)
+			listener := testingx.MustNewDNSOverQUICListener(
+				pconn, ca.MustNewServerTLSConfig("dns.google"), dnsRtx)
+			defer listener.Close()
+			netx := &Netx{}
+			child := netx.NewQUICDialerWithoutResolver(netx.NewUDPListener(), model.DiscardLogger)
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (model.QUICConn, error) {
+					tlsConfig = tlsConfig.Clone()
+					tlsConfig.RootCAs = ca.DefaultCertPool()
+					return child.DialContext(ctx, address, tlsConfig, quicConfig)
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, listener.LocalAddr().String())
+			txp.ServerName = "dns.google"
+			encoder := &DNSEncoderMiekg{}
+			query := encoder.Encode("dns.google.", dns.TypeA, true)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrs, err := resp.DecodeLookupHost()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(addrs, []string{"8.8.8.8"}); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+
+	t.Run("other functions okay", func(t *testing.T) {
+		var called bool
+		dialer := &mocks.QUICDialer{
+			MockCloseIdleConnections: func() {
+				called = true
+			},
+		}
+		const address = "9.9.9.9:853"
+		txp := NewUnwrappedDNSOverQUICTransport(dialer, address)
+		if txp.RequiresPadding() != true {
+			t.Fatal("invalid RequiresPadding")
+		}
+		if txp.Network() != "doq" {
+			t.Fatal("invalid Network")
+		}
+		if txp.Address() != address {
+			t.Fatal("invalid Address")
+		}
+		txp.CloseIdleConnections()
+		if !called {
+			t.Fatal("did not call CloseIdleConnections")
+		}
+	})
+}
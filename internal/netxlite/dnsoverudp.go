@@ -35,7 +35,11 @@ import (
 // make this code suitable to implement parasitic traceroute.
 //
 // This transport by default listens for additional responses after the first
-// one and makes them available using the context-configured trace.
+// one and makes them available using the context-configured trace. Because
+// injected responses usually arrive before the legitimate response, receiving
+// more than one response for the same query is a strong indication of DNS
+// injection. By default, we listen in the background until the I/O deadline
+// expires, but you can configure a different window (see DuplicatesWindow).
 type DNSOverUDPTransport struct {
 	// Decoder is the MANDATORY DNSDecoder to use.
 	Decoder model.DNSDecoder
@@ -43,6 +47,12 @@ type DNSOverUDPTransport struct {
 	// Dialer is the MANDATORY dialer used to create the conn.
 	Dialer model.Dialer
 
+	// DuplicatesWindow is the OPTIONAL time during which the background
+	// goroutine keeps reading duplicate responses after the first one. When
+	// zero, we use the window configured using [ContextWithDNSOverUDPDuplicatesWindow]
+	// and, if that is also zero, we keep reading until the I/O deadline.
+	DuplicatesWindow time.Duration
+
 	// Endpoint is the MANDATORY server's endpoint (e.g., 1.1.1.1:53)
 	Endpoint string
 
@@ -68,10 +78,11 @@ type DNSOverUDPTransport struct {
 // have less control over which IP address is being used.
 func NewUnwrappedDNSOverUDPTransport(dialer model.Dialer, address string) *DNSOverUDPTransport {
 	return &DNSOverUDPTransport{
-		Decoder:       &DNSDecoderMiekg{},
-		Dialer:        dialer,
-		Endpoint:      address,
-		lateResponses: nil, // not interested by default
+		Decoder:          &DNSDecoderMiekg{},
+		Dialer:           dialer,
+		DuplicatesWindow: 0, // use the context-configured window, if any
+		Endpoint:         address,
+		lateResponses:    nil, // not interested by default
 	}
 }
 
@@ -107,6 +118,9 @@ func (t *DNSOverUDPTransport) RoundTrip(
 		_ = conn.Close() // we still own the conn
 		return nil, err
 	}
+	if window := t.duplicatesWindow(ctx); window > 0 {
+		_ = conn.SetDeadline(time.Now().Add(window))
+	}
 	// start a goroutine to listen for any delayed DNS response and
 	// TRANSFER the conn's OWNERSHIP to such a goroutine.
 	go t.ownConnAndSendRecvLoop(ctx, conn, query, myaddr, joinedch)
@@ -146,7 +160,7 @@ func (t *DNSOverUDPTransport) ownConnAndSendRecvLoop(ctx context.Context, conn n
 	trace := ContextTraceOrDefault(ctx)
 	for {
 		started := trace.TimeNow()
-		resp, err := t.recv(query, conn)
+		rawResponse, err := t.read(conn)
 		finished := trace.TimeNow()
 		if err != nil {
 			// We are going to consider all errors as fatal for now until we
@@ -161,6 +175,12 @@ func (t *DNSOverUDPTransport) ownConnAndSendRecvLoop(ctx context.Context, conn n
 			// this seems how censorship is implemented in, e.g., China.
 			return
 		}
+		resp, err := t.Decoder.DecodeResponse(rawResponse, query)
+		if err != nil {
+			// This is not a response for our query (e.g., the query ID does
+			// not match) so we just ignore it and continue reading.
+			continue
+		}
 		// if there's testing code waiting to be unblocked because we
 		// received a delayed response, unblock it
 		select {
@@ -179,12 +199,40 @@ func (t *DNSOverUDPTransport) ownConnAndSendRecvLoop(ctx context.Context, conn n
 
 // recv receives a single response for the given query using the given conn.
 func (t *DNSOverUDPTransport) recv(query model.DNSQuery, conn net.Conn) (model.DNSResponse, error) {
+	rawResponse, err := t.read(conn)
+	if err != nil {
+		return nil, err
+	}
+	return t.Decoder.DecodeResponse(rawResponse, query)
+}
+
+// read reads a single raw response using the given conn.
+func (t *DNSOverUDPTransport) read(conn net.Conn) ([]byte, error) {
 	const maxmessagesize = 1 << 17
 	rawResponse := make([]byte, maxmessagesize)
 	count, err := conn.Read(rawResponse)
 	if err != nil {
 		return nil, err
 	}
-	rawResponse = rawResponse[:count]
-	return t.Decoder.DecodeResponse(rawResponse, query)
+	return rawResponse[:count], nil
+}
+
+// duplicatesWindow returns the duplicates window to use for the given context.
+func (t *DNSOverUDPTransport) duplicatesWindow(ctx context.Context) time.Duration {
+	if t.DuplicatesWindow > 0 {
+		return t.DuplicatesWindow
+	}
+	window, _ := ctx.Value(dnsOverUDPDuplicatesWindowKey{}).(time.Duration)
+	return window
+}
+
+// dnsOverUDPDuplicatesWindowKey is the private type used to set/retrieve the context's
+// window during which the DNS-over-UDP transport waits for duplicate responses.
+type dnsOverUDPDuplicatesWindowKey struct{}
+
+// ContextWithDNSOverUDPDuplicatesWindow returns a new context that causes any
+// [*DNSOverUDPTransport] with zero DuplicatesWindow to keep reading duplicate
+// responses in the background for the given window after the first response.
+func ContextWithDNSOverUDPDuplicatesWindow(ctx context.Context, window time.Duration) context.Context {
+	return context.WithValue(ctx, dnsOverUDPDuplicatesWindowKey{}, window)
 }
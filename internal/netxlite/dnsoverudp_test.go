@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"net"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -493,6 +494,171 @@ func TestDNSOverUDPTransport(t *testing.T) {
 		})
 	})
 
+	t.Run("collecting duplicate DNS responses", func(t *testing.T) {
+		dnsConfigGood := netem.NewDNSConfig()
+		dnsConfigGood.AddRecord("dns.google", "", "8.8.8.8")
+
+		dnsConfigBogus := netem.NewDNSConfig()
+		dnsConfigBogus.AddRecord("dns.google", "", "127.0.0.1")
+
+		// roundTrip performs a round trip and returns the addresses of the
+		// first response and of the duplicate response delivered to the trace
+		roundTrip := func(t *testing.T, ctx context.Context, window time.Duration) ([]string, []string) {
+			udpAddr := &net.UDPAddr{
+				IP:   net.IPv4(127, 0, 0, 1),
+				Port: 0,
+			}
+			listener := testingx.MustNewDNSSimulateGWFListener(
+				udpAddr, &testingx.DNSOverUDPListenerStdlib{}, dnsConfigBogus,
+				dnsConfigGood, testingx.DNSNumBogusResponses(1))
+			defer listener.Close()
+			netx := &Netx{}
+			dialer := netx.NewDialerWithoutResolver(model.DiscardLogger)
+			txp := NewUnwrappedDNSOverUDPTransport(dialer, listener.LocalAddr().String())
+			txp.DuplicatesWindow = window
+			duplicatesch := make(chan []string, 1)
+			tx := &mocks.Trace{
+				MockTimeNow: time.Now,
+				MockOnConnectDone: func(
+					started time.Time, network, domain, remoteAddr string, err error, finished time.Time) {
+					// nothing
+				},
+				MockMaybeWrapNetConn: func(conn net.Conn) net.Conn {
+					return conn
+				},
+				MockOnDelayedDNSResponse: func(started time.Time, txp model.DNSTransport, query model.DNSQuery,
+					response model.DNSResponse, addrs []string, err error, finished time.Time) error {
+					duplicatesch <- addrs
+					return nil
+				},
+			}
+			ctx = ContextWithTrace(ctx, tx)
+			encoder := &DNSEncoderMiekg{}
+			query := encoder.Encode("dns.google.", dns.TypeA, false)
+			resp, err := txp.RoundTrip(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrs, err := resp.DecodeLookupHost()
+			if err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case duplicates := <-duplicatesch:
+				return addrs, duplicates
+			case <-time.After(time.Second):
+				return addrs, nil
+			}
+		}
+
+		t.Run("we ignore responses we cannot decode", func(t *testing.T) {
+			messages := [][]byte{{1}, {2}, {3}}
+			var (
+				mu        sync.Mutex
+				deadlines []time.Time
+			)
+			txp := NewUnwrappedDNSOverUDPTransport(
+				&mocks.Dialer{
+					MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+						return &mocks.Conn{
+							MockSetDeadline: func(t time.Time) error {
+								mu.Lock()
+								deadlines = append(deadlines, t)
+								mu.Unlock()
+								return nil
+							},
+							MockWrite: func(b []byte) (int, error) {
+								return len(b), nil
+							},
+							MockRead: func(b []byte) (int, error) {
+								mu.Lock()
+								defer mu.Unlock()
+								if len(messages) <= 0 {
+									return 0, os.ErrDeadlineExceeded
+								}
+								count := copy(b, messages[0])
+								messages = messages[1:]
+								return count, nil
+							},
+							MockClose: func() error {
+								return nil
+							},
+							MockLocalAddr: func() net.Addr {
+								return &mocks.Addr{
+									MockNetwork: func() string {
+										return "udp"
+									},
+									MockString: func() string {
+										return "127.0.0.1:1345"
+									},
+								}
+							},
+						}, nil
+					},
+				}, "9.9.9.9:53",
+			)
+			txp.DuplicatesWindow = 100 * time.Millisecond
+			txp.lateResponses = make(chan any, 2)
+			expectedResp := &mocks.DNSResponse{
+				MockDecodeLookupHost: func() ([]string, error) {
+					return []string{"8.8.8.8"}, nil
+				},
+			}
+			txp.Decoder = &mocks.DNSDecoder{
+				MockDecodeResponse: func(data []byte, query model.DNSQuery) (model.DNSResponse, error) {
+					if data[0] == 2 {
+						return nil, ErrDNSReplyWithWrongQueryID
+					}
+					return expectedResp, nil
+				},
+			}
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp != expectedResp {
+				t.Fatal("unexpected response")
+			}
+			<-txp.lateResponses // the third message
+			select {
+			case <-txp.lateResponses:
+				t.Fatal("did not expect the second message to be a late response")
+			case <-time.After(200 * time.Millisecond):
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(deadlines) != 2 || !deadlines[1].Before(deadlines[0]) {
+				t.Fatal("unexpected deadlines", deadlines)
+			}
+		})
+
+		t.Run("with the window configured using the field", func(t *testing.T) {
+			addrs, duplicates := roundTrip(t, context.Background(), 500*time.Millisecond)
+			if diff := cmp.Diff([]string{"127.0.0.1"}, addrs); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff([]string{"8.8.8.8"}, duplicates); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("with the window configured using the context", func(t *testing.T) {
+			ctx := ContextWithDNSOverUDPDuplicatesWindow(context.Background(), 500*time.Millisecond)
+			addrs, duplicates := roundTrip(t, ctx, 0)
+			if diff := cmp.Diff([]string{"127.0.0.1"}, addrs); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff([]string{"8.8.8.8"}, duplicates); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+
 	t.Run("CloseIdleConnections", func(t *testing.T) {
 		var called bool
 		dialer := &mocks.Dialer{
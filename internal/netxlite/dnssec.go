@@ -0,0 +1,464 @@
+package netxlite
+
+//
+// DNSSEC validation
+//
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+const (
+	// DNSSECStatusSecure indicates that we validated the chain of trust
+	// from the answer up to one of the configured trust anchors.
+	DNSSECStatusSecure = "secure"
+
+	// DNSSECStatusInsecure indicates that the answer is signed but its
+	// zone is not linked to the parent zone using a DS record.
+	DNSSECStatusInsecure = "insecure"
+
+	// DNSSECStatusBogus indicates that the answer or some link in the
+	// chain of trust carries signatures that we cannot validate.
+	DNSSECStatusBogus = "bogus"
+
+	// DNSSECStatusUnsigned indicates that the answer does not contain
+	// any signature. This happens for answers from unsigned zones as well
+	// as when a middlebox strips the signatures. We do not attempt to prove
+	// the zone is unsigned using NSEC/NSEC3, so distinguishing these two cases
+	// requires comparing with a control measurement.
+	DNSSECStatusUnsigned = "unsigned"
+
+	// DNSSECStatusIndeterminate indicates that we could not fetch the
+	// records required to validate the chain of trust.
+	DNSSECStatusIndeterminate = "indeterminate"
+)
+
+var (
+	// ErrDNSSECNoSignatures indicates that an RRset is not signed.
+	ErrDNSSECNoSignatures = errors.New("dnssec: no signatures")
+
+	// ErrDNSSECInvalidSignature indicates that no signature validates an RRset.
+	ErrDNSSECInvalidSignature = errors.New("dnssec: invalid signature")
+
+	// ErrDNSSECInvalidSigner indicates that an RRset is signed by a zone
+	// that is not authoritative for the RRset.
+	ErrDNSSECInvalidSigner = errors.New("dnssec: invalid signer")
+
+	// ErrDNSSECNoDS indicates that a signed zone has no DS in the parent zone.
+	ErrDNSSECNoDS = errors.New("dnssec: no DS for signed zone")
+
+	// ErrDNSSECNoMatchingDS indicates that no DS matches the zone's DNSKEYs.
+	ErrDNSSECNoMatchingDS = errors.New("dnssec: no DS matching the zone's DNSKEYs")
+
+	// ErrDNSSECNoTrustAnchor indicates that no trust anchor matches the root DNSKEYs.
+	ErrDNSSECNoTrustAnchor = errors.New("dnssec: no trust anchor matching the root DNSKEYs")
+
+	// ErrDNSSECChainTooLong indicates that the chain of trust is too long.
+	ErrDNSSECChainTooLong = errors.New("dnssec: chain of trust too long")
+)
+
+// dnssecRootTrustAnchors contains the DS records of the root zone KSKs
+// published by IANA at https://data.iana.org/root-anchors/root-anchors.xml.
+var dnssecRootTrustAnchors = []string{
+	// KSK-2017
+	". 0 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+
+	// KSK-2024
+	". 0 IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// DNSSECRootTrustAnchors returns the bundled DS records of the root zone KSKs.
+func DNSSECRootTrustAnchors() (out []*dns.DS) {
+	for _, entry := range dnssecRootTrustAnchors {
+		rr := runtimex.Try1(dns.NewRR(entry))
+		out = append(out, rr.(*dns.DS))
+	}
+	return
+}
+
+// dnssecMaxChainLength is the maximum number of zones we're willing to traverse.
+const dnssecMaxChainLength = 16
+
+// DNSSECValidator validates the DNSSEC chain of trust of DNS responses.
+//
+// The validator fetches the DNSKEY and DS records it needs using the given
+// transport. For each RRset in the answer section, we verify the signatures
+// using the signer zone's DNSKEYs, we verify the DNSKEY RRset is self-signed,
+// and we link the zone to its parent using DS records until we reach the root
+// zone, whose DNSKEYs must match one of the trust anchors.
+//
+// The validator caches the DNSKEY and DS records it fetches, so you should
+// use a new validator for each lookup. It is safe to validate several responses
+// of the same lookup (e.g., A and AAAA) concurrently.
+type DNSSECValidator struct {
+	// TimeNow is the OPTIONAL function returning the current time, which
+	// we use to check the signatures validity period. If nil, we use [time.Now].
+	TimeNow func() time.Time
+
+	// TrustAnchors contains the OPTIONAL root zone DS records to use. If
+	// empty, we use the bundled [DNSSECRootTrustAnchors].
+	TrustAnchors []*dns.DS
+
+	// Txp is the MANDATORY transport to fetch DNSKEY and DS records.
+	Txp model.DNSTransport
+
+	// cache caches the results of fetch.
+	cache map[dnssecRRsetKey]*dnssecFetchResult
+
+	// mu provides mutual exclusion for cache.
+	mu sync.Mutex
+}
+
+// dnssecFetchResult is the result of fetching an RRset and its signatures.
+type dnssecFetchResult struct {
+	done  bool
+	err   error
+	mu    sync.Mutex
+	rrset []dns.RR
+	sigs  []*dns.RRSIG
+}
+
+// NewDNSSECValidator creates a new [*DNSSECValidator] using the given transport.
+func NewDNSSECValidator(txp model.DNSTransport) *DNSSECValidator {
+	return &DNSSECValidator{
+		TimeNow:      time.Now,
+		TrustAnchors: DNSSECRootTrustAnchors(),
+		Txp:          txp,
+	}
+}
+
+// Validate validates the given response. This method does not return an
+// error because failures are part of the returned [*model.DNSSECValidation].
+func (v *DNSSECValidator) Validate(ctx context.Context, response model.DNSResponse) *model.DNSSECValidation {
+	status, err := v.validate(ctx, response)
+	return &model.DNSSECValidation{
+		Status:  status,
+		Failure: err,
+	}
+}
+
+func (v *DNSSECValidator) validate(ctx context.Context, response model.DNSResponse) (string, error) {
+	msg := &dns.Msg{}
+	if err := msg.Unpack(response.Bytes()); err != nil {
+		return DNSSECStatusIndeterminate, err
+	}
+	rrsets, sigs := dnssecGroupRRsets(msg.Answer)
+	if len(rrsets) <= 0 {
+		return DNSSECStatusIndeterminate, ErrOODNSNoAnswer
+	}
+	// make sure we always report the same failure when several RRsets fail
+	zones := map[string]*dnssecZoneKeys{}
+	for _, key := range dnssecSortedRRsetKeys(rrsets) {
+		if status, err := v.validateRRset(ctx, zones, rrsets[key], sigs[key], 0); err != nil {
+			return status, err
+		}
+	}
+	return DNSSECStatusSecure, nil
+}
+
+// dnssecZoneKeys contains the validated DNSKEYs of a zone.
+type dnssecZoneKeys struct {
+	// err is the error that occurred when fetching and validating.
+	err error
+
+	// keys contains all the zone's DNSKEYs.
+	keys []*dns.DNSKEY
+
+	// signers contains the DNSKEYs that signed the DNSKEY RRset.
+	signers []*dns.DNSKEY
+
+	// status is the status to return when err is not nil.
+	status string
+}
+
+// validateRRset validates the given RRset at the given depth of the chain of trust. When
+// the signatures come from several signer zones, we try each zone in order of appearance
+// and the RRset is secure if any of them is. Otherwise, we return the first failure.
+func (v *DNSSECValidator) validateRRset(ctx context.Context, zones map[string]*dnssecZoneKeys,
+	rrset []dns.RR, sigs []*dns.RRSIG, depth int) (string, error) {
+	if len(sigs) <= 0 {
+		return DNSSECStatusUnsigned, ErrDNSSECNoSignatures
+	}
+	if depth >= dnssecMaxChainLength {
+		return DNSSECStatusIndeterminate, ErrDNSSECChainTooLong
+	}
+	var (
+		status string
+		err    error
+	)
+	for _, zone := range dnssecSignerZones(sigs) {
+		zoneStatus, zoneErr := v.validateRRsetWithZone(ctx, zones, rrset, dnssecSignedBy(sigs, zone), zone, depth)
+		if zoneErr == nil {
+			return DNSSECStatusSecure, nil
+		}
+		if err == nil {
+			status, err = zoneStatus, zoneErr
+		}
+	}
+	return status, err
+}
+
+// validateRRsetWithZone validates the given RRset using the signatures of the given zone.
+func (v *DNSSECValidator) validateRRsetWithZone(ctx context.Context, zones map[string]*dnssecZoneKeys,
+	rrset []dns.RR, sigs []*dns.RRSIG, zone string, depth int) (string, error) {
+	owner := dns.CanonicalName(rrset[0].Header().Name)
+	if !dns.IsSubDomain(zone, owner) {
+		return DNSSECStatusBogus, ErrDNSSECInvalidSigner
+	}
+	if rrset[0].Header().Rrtype == dns.TypeDS && zone == owner {
+		return DNSSECStatusBogus, ErrDNSSECInvalidSigner // a zone cannot sign its own DS
+	}
+	zk := zones[zone]
+	if zk == nil {
+		zk = v.fetchZoneKeys(ctx, zone)
+		zones[zone] = zk
+	}
+	if zk.err != nil {
+		return zk.status, zk.err
+	}
+	if err := v.verify(rrset, sigs, zk.keys); err != nil {
+		return DNSSECStatusBogus, err
+	}
+	if zone == "." {
+		if !dnssecAnyDSMatchesAnyKey(v.trustAnchors(), zk.signers) {
+			return DNSSECStatusBogus, ErrDNSSECNoTrustAnchor
+		}
+		return DNSSECStatusSecure, nil
+	}
+	ds, dssigs, err := v.fetch(ctx, zone, dns.TypeDS)
+	if err != nil {
+		return DNSSECStatusIndeterminate, err
+	}
+	if len(ds) <= 0 {
+		return DNSSECStatusInsecure, ErrDNSSECNoDS
+	}
+	if len(dssigs) <= 0 {
+		return DNSSECStatusBogus, ErrDNSSECNoSignatures
+	}
+	if !dnssecAnyDSMatchesAnyKey(dnssecFilterDS(ds), zk.signers) {
+		return DNSSECStatusBogus, ErrDNSSECNoMatchingDS
+	}
+	return v.validateRRset(ctx, zones, ds, dssigs, depth+1)
+}
+
+// fetchZoneKeys fetches the given zone's DNSKEYs and checks that the DNSKEY RRset is self-signed.
+func (v *DNSSECValidator) fetchZoneKeys(ctx context.Context, zone string) *dnssecZoneKeys {
+	rrset, sigs, err := v.fetch(ctx, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return &dnssecZoneKeys{err: err, status: DNSSECStatusIndeterminate}
+	}
+	if len(rrset) <= 0 {
+		return &dnssecZoneKeys{err: ErrOODNSNoAnswer, status: DNSSECStatusBogus}
+	}
+	if len(sigs) <= 0 {
+		return &dnssecZoneKeys{err: ErrDNSSECNoSignatures, status: DNSSECStatusBogus}
+	}
+	zk := &dnssecZoneKeys{}
+	for _, rr := range rrset {
+		zk.keys = append(zk.keys, rr.(*dns.DNSKEY)) // fetch guarantees the type
+	}
+	for _, key := range zk.keys {
+		if v.verify(rrset, sigs, []*dns.DNSKEY{key}) == nil {
+			zk.signers = append(zk.signers, key)
+		}
+	}
+	if len(zk.signers) <= 0 {
+		return &dnssecZoneKeys{err: ErrDNSSECInvalidSignature, status: DNSSECStatusBogus}
+	}
+	return zk
+}
+
+// verify returns nil if any of the signatures validates the RRset using any of the keys.
+func (v *DNSSECValidator) verify(rrset []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY) error {
+	now := v.timeNow()
+	for _, sig := range sigs {
+		if !sig.ValidityPeriod(now) {
+			continue
+		}
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+			if !strings.EqualFold(key.Header().Name, sig.SignerName) {
+				continue
+			}
+			if sig.Verify(key, rrset) == nil {
+				return nil
+			}
+		}
+	}
+	return ErrDNSSECInvalidSignature
+}
+
+// fetch fetches the RRset and signatures for the given name and type, reusing
+// the results of previous fetches, such that we only fetch each zone's DNSKEY
+// and DS records once when we validate several responses.
+func (v *DNSSECValidator) fetch(ctx context.Context, name string, qtype uint16) ([]dns.RR, []*dns.RRSIG, error) {
+	key := dnssecRRsetKey{name: dns.CanonicalName(name), qtype: qtype}
+	v.mu.Lock()
+	if v.cache == nil {
+		v.cache = make(map[dnssecRRsetKey]*dnssecFetchResult)
+	}
+	entry := v.cache[key]
+	if entry == nil {
+		entry = &dnssecFetchResult{}
+		v.cache[key] = entry
+	}
+	v.mu.Unlock()
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if !entry.done {
+		rrset, sigs, err := v.fetchWithoutCache(ctx, name, qtype)
+		if err != nil && ctx.Err() != nil {
+			// do not cache failures caused by this caller's context, such that
+			// other callers with a working context can fetch again
+			return nil, nil, err
+		}
+		entry.done, entry.rrset, entry.sigs, entry.err = true, rrset, sigs, err
+	}
+	return entry.rrset, entry.sigs, entry.err
+}
+
+// fetchWithoutCache is like fetch but always sends a query.
+func (v *DNSSECValidator) fetchWithoutCache(ctx context.Context, name string, qtype uint16) ([]dns.RR, []*dns.RRSIG, error) {
+	encoder := &DNSEncoderMiekg{DNSSEC: true}
+	query := encoder.Encode(name, qtype, v.Txp.RequiresPadding())
+	response, err := v.Txp.RoundTrip(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := &dns.Msg{}
+	if err := msg.Unpack(response.Bytes()); err != nil {
+		return nil, nil, err
+	}
+	switch msg.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+		// these are the rcodes we expect
+	default:
+		return nil, nil, ErrOODNSMisbehaving
+	}
+	rrsets, sigs := dnssecGroupRRsets(msg.Answer)
+	key := dnssecRRsetKey{name: dns.CanonicalName(name), qtype: qtype}
+	return rrsets[key], sigs[key], nil
+}
+
+func (v *DNSSECValidator) timeNow() time.Time {
+	if v.TimeNow != nil {
+		return v.TimeNow()
+	}
+	return time.Now()
+}
+
+func (v *DNSSECValidator) trustAnchors() []*dns.DS {
+	if len(v.TrustAnchors) > 0 {
+		return v.TrustAnchors
+	}
+	return DNSSECRootTrustAnchors()
+}
+
+// dnssecRRsetKey uniquely identifies an RRset.
+type dnssecRRsetKey struct {
+	name  string
+	qtype uint16
+}
+
+// dnssecGroupRRsets groups the given records into RRsets and their signatures.
+func dnssecGroupRRsets(records []dns.RR) (map[dnssecRRsetKey][]dns.RR, map[dnssecRRsetKey][]*dns.RRSIG) {
+	rrsets := map[dnssecRRsetKey][]dns.RR{}
+	sigs := map[dnssecRRsetKey][]*dns.RRSIG{}
+	for _, rr := range records {
+		name := dns.CanonicalName(rr.Header().Name)
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			key := dnssecRRsetKey{name: name, qtype: sig.TypeCovered}
+			sigs[key] = append(sigs[key], sig)
+			continue
+		}
+		key := dnssecRRsetKey{name: name, qtype: rr.Header().Rrtype}
+		rrsets[key] = append(rrsets[key], rr)
+	}
+	return rrsets, sigs
+}
+
+// dnssecSortedRRsetKeys returns the keys of the given RRsets sorted by name and type.
+func dnssecSortedRRsetKeys(rrsets map[dnssecRRsetKey][]dns.RR) (out []dnssecRRsetKey) {
+	for key := range rrsets {
+		out = append(out, key)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].name != out[j].name {
+			return out[i].name < out[j].name
+		}
+		return out[i].qtype < out[j].qtype
+	})
+	return
+}
+
+// dnssecSignerZones returns the distinct signer zones of the given signatures in order of appearance.
+func dnssecSignerZones(sigs []*dns.RRSIG) (out []string) {
+	seen := map[string]bool{}
+	for _, sig := range sigs {
+		zone := dns.CanonicalName(sig.SignerName)
+		if !seen[zone] {
+			seen[zone] = true
+			out = append(out, zone)
+		}
+	}
+	return
+}
+
+// dnssecSignedBy returns the signatures created by the given signer zone.
+func dnssecSignedBy(sigs []*dns.RRSIG, zone string) (out []*dns.RRSIG) {
+	for _, sig := range sigs {
+		if dns.CanonicalName(sig.SignerName) == zone {
+			out = append(out, sig)
+		}
+	}
+	return
+}
+
+// dnssecFilterDS converts a DS RRset to a list of DS records.
+func dnssecFilterDS(rrset []dns.RR) (out []*dns.DS) {
+	for _, rr := range rrset {
+		if ds, ok := rr.(*dns.DS); ok {
+			out = append(out, ds)
+		}
+	}
+	return
+}
+
+// dnssecAnyDSMatchesAnyKey returns whether any DS is the digest of any key.
+func dnssecAnyDSMatchesAnyKey(dss []*dns.DS, keys []*dns.DNSKEY) bool {
+	for _, ds := range dss {
+		for _, key := range keys {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+				continue
+			}
+			if computed := key.ToDS(ds.DigestType); computed != nil && strings.EqualFold(computed.Digest, ds.Digest) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dnssecValidatedResponse is a [model.DNSResponse] with a DNSSEC validation outcome.
+type dnssecValidatedResponse struct {
+	model.DNSResponse
+	validation *model.DNSSECValidation
+}
+
+var _ model.DNSSECValidatedResponse = &dnssecValidatedResponse{}
+
+// DNSSECValidation implements model.DNSSECValidatedResponse.
+func (r *dnssecValidatedResponse) DNSSECValidation() *model.DNSSECValidation {
+	return r.validation
+}
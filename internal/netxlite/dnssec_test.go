@@ -0,0 +1,450 @@
+package netxlite
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// dnssecTestZone is a signed zone used for testing.
+type dnssecTestZone struct {
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+// newDNSSECTestZone creates a new [*dnssecTestZone] with a fresh key.
+func newDNSSECTestZone(name string) *dnssecTestZone {
+	key := &dns.DNSKEY{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeDNSKEY,
+			Class:  dns.ClassINET,
+			Ttl:    3600,
+		},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv := runtimex.Try1(key.Generate(256))
+	return &dnssecTestZone{key: key, priv: priv.(crypto.Signer)}
+}
+
+// sign returns the signature of the given RRset.
+func (z *dnssecTestZone) sign(rrset ...dns.RR) *dns.RRSIG {
+	sig := &dns.RRSIG{
+		Hdr: dns.RR_Header{
+			Name:   rrset[0].Header().Name,
+			Rrtype: dns.TypeRRSIG,
+			Class:  dns.ClassINET,
+			Ttl:    rrset[0].Header().Ttl,
+		},
+		Algorithm:  z.key.Algorithm,
+		SignerName: z.key.Hdr.Name,
+		KeyTag:     z.key.KeyTag(),
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+	}
+	runtimex.Try0(sig.Sign(z.priv, rrset))
+	return sig
+}
+
+// ds returns the DS record for this zone.
+func (z *dnssecTestZone) ds() *dns.DS {
+	return z.key.ToDS(dns.SHA256)
+}
+
+// dnssecTestDB maps a query to the records of the answer section.
+type dnssecTestDB map[dnssecRRsetKey][]dns.RR
+
+// transport returns a transport that answers using the DB.
+func (db dnssecTestDB) transport() model.DNSTransport {
+	return &mocks.DNSTransport{
+		MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+			rawQuery := runtimex.Try1(query.Bytes())
+			msg := &dns.Msg{}
+			runtimex.Try0(msg.Unpack(rawQuery))
+			reply := &dns.Msg{}
+			reply.SetReply(msg)
+			reply.Answer = db[dnssecRRsetKey{name: msg.Question[0].Name, qtype: msg.Question[0].Qtype}]
+			rawReply := runtimex.Try1(reply.Pack())
+			return (&DNSDecoderMiekg{}).DecodeResponse(rawReply, query)
+		},
+		MockRequiresPadding: func() bool {
+			return false
+		},
+	}
+}
+
+// dnssecTestSetup contains the zones and the DB for testing.
+type dnssecTestSetup struct {
+	root   *dnssecTestZone
+	org    *dnssecTestZone
+	domain *dnssecTestZone
+	answer []dns.RR
+	db     dnssecTestDB
+}
+
+// newDNSSECTestSetup creates a signed hierarchy for example.org.
+func newDNSSECTestSetup() *dnssecTestSetup {
+	s := &dnssecTestSetup{
+		root:   newDNSSECTestZone("."),
+		org:    newDNSSECTestZone("org."),
+		domain: newDNSSECTestZone("example.org."),
+		db:     dnssecTestDB{},
+	}
+	for _, zone := range []*dnssecTestZone{s.root, s.org, s.domain} {
+		s.db[dnssecRRsetKey{name: zone.key.Hdr.Name, qtype: dns.TypeDNSKEY}] = []dns.RR{
+			zone.key, zone.sign(zone.key),
+		}
+	}
+	orgDS := s.org.ds()
+	s.db[dnssecRRsetKey{name: "org.", qtype: dns.TypeDS}] = []dns.RR{orgDS, s.root.sign(orgDS)}
+	domainDS := s.domain.ds()
+	s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeDS}] = []dns.RR{domainDS, s.org.sign(domainDS)}
+	a := &dns.A{
+		Hdr: dns.RR_Header{
+			Name:   "example.org.",
+			Rrtype: dns.TypeA,
+			Class:  dns.ClassINET,
+			Ttl:    300,
+		},
+		A: net.IPv4(93, 184, 216, 34),
+	}
+	s.answer = []dns.RR{a, s.domain.sign(a)}
+	return s
+}
+
+// response returns the response containing the given answer.
+func (s *dnssecTestSetup) response(answer ...dns.RR) model.DNSResponse {
+	query := (&DNSEncoderMiekg{DNSSEC: true}).Encode("example.org", dns.TypeA, false)
+	msg := &dns.Msg{}
+	runtimex.Try0(msg.Unpack(runtimex.Try1(query.Bytes())))
+	reply := &dns.Msg{}
+	reply.SetReply(msg)
+	reply.Answer = answer
+	return runtimex.Try1((&DNSDecoderMiekg{}).DecodeResponse(runtimex.Try1(reply.Pack()), query))
+}
+
+// validator returns a validator trusting the test root zone.
+func (s *dnssecTestSetup) validator() *DNSSECValidator {
+	v := NewDNSSECValidator(s.db.transport())
+	v.TrustAnchors = []*dns.DS{s.root.ds()}
+	return v
+}
+
+func TestDNSSECRootTrustAnchors(t *testing.T) {
+	anchors := DNSSECRootTrustAnchors()
+	if len(anchors) != 2 {
+		t.Fatal("expected two trust anchors")
+	}
+	for _, ds := range anchors {
+		if ds.Hdr.Name != "." || ds.DigestType != dns.SHA256 {
+			t.Fatal("unexpected trust anchor", ds)
+		}
+	}
+}
+
+func TestDNSSECValidator(t *testing.T) {
+	t.Run("with a secure chain of trust", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		result := s.validator().Validate(context.Background(), s.response(s.answer...))
+		if result.Failure != nil {
+			t.Fatal(result.Failure)
+		}
+		if result.Status != DNSSECStatusSecure {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with a response that is not valid", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		response := &mocks.DNSResponse{
+			MockBytes: func() []byte {
+				return nil
+			},
+		}
+		result := s.validator().Validate(context.Background(), response)
+		if result.Failure == nil {
+			t.Fatal("expected an error")
+		}
+		if result.Status != DNSSECStatusIndeterminate {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with an empty answer", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		result := s.validator().Validate(context.Background(), s.response())
+		if !errors.Is(result.Failure, ErrOODNSNoAnswer) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusIndeterminate {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with stripped signatures", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		result := s.validator().Validate(context.Background(), s.response(s.answer[0]))
+		if !errors.Is(result.Failure, ErrDNSSECNoSignatures) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusUnsigned {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with a forged answer", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		forged := dns.Copy(s.answer[0]).(*dns.A)
+		forged.A = net.IPv4(10, 10, 34, 35)
+		result := s.validator().Validate(context.Background(), s.response(forged, s.answer[1]))
+		if !errors.Is(result.Failure, ErrDNSSECInvalidSignature) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with an expired signature", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		v := s.validator()
+		v.TimeNow = func() time.Time {
+			return time.Now().Add(24 * time.Hour)
+		}
+		result := v.Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECInvalidSignature) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with a signer that is not authoritative", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		other := newDNSSECTestZone("example.com.")
+		result := s.validator().Validate(context.Background(), s.response(s.answer[0], other.sign(s.answer[0])))
+		if !errors.Is(result.Failure, ErrDNSSECInvalidSigner) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with an additional signature from a signer that is not authoritative", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		other := newDNSSECTestZone("example.com.")
+		answer := []dns.RR{s.answer[0], other.sign(s.answer[0]), s.answer[1]}
+		result := s.validator().Validate(context.Background(), s.response(answer...))
+		if result.Failure != nil {
+			t.Fatal(result.Failure)
+		}
+		if result.Status != DNSSECStatusSecure {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with several failing RRsets", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		forged := dns.Copy(s.answer[0]).(*dns.A)
+		forged.A = net.IPv4(10, 10, 34, 35)
+		unsigned := &dns.AAAA{
+			Hdr: dns.RR_Header{
+				Name:   "example.org.",
+				Rrtype: dns.TypeAAAA,
+				Class:  dns.ClassINET,
+				Ttl:    300,
+			},
+			AAAA: net.ParseIP("2606:2800:220:1:248:1893:25c8:1946"),
+		}
+		// the map iteration order is random, so make sure we repeatedly get the
+		// failure of the A RRset, which sorts before the AAAA RRset
+		for idx := 0; idx < 16; idx++ {
+			result := s.validator().Validate(context.Background(), s.response(unsigned, forged, s.answer[1]))
+			if !errors.Is(result.Failure, ErrDNSSECInvalidSignature) {
+				t.Fatal("unexpected error", result.Failure)
+			}
+			if result.Status != DNSSECStatusBogus {
+				t.Fatal("unexpected status", result.Status)
+			}
+		}
+	})
+
+	t.Run("without a DS in the parent zone", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		delete(s.db, dnssecRRsetKey{name: "example.org.", qtype: dns.TypeDS})
+		result := s.validator().Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECNoDS) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusInsecure {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with a DS not matching the zone's DNSKEYs", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		ds := newDNSSECTestZone("example.org.").ds()
+		s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeDS}] = []dns.RR{ds, s.org.sign(ds)}
+		result := s.validator().Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECNoMatchingDS) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with an unsigned DS", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeDS}] = []dns.RR{s.domain.ds()}
+		result := s.validator().Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECNoSignatures) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with unsigned DNSKEYs", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeDNSKEY}] = []dns.RR{s.domain.key}
+		result := s.validator().Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECNoSignatures) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with missing DNSKEYs", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		delete(s.db, dnssecRRsetKey{name: "example.org.", qtype: dns.TypeDNSKEY})
+		result := s.validator().Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrOODNSNoAnswer) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with DNSKEYs not signed by any of the zone's keys", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		other := newDNSSECTestZone("example.org.")
+		s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeDNSKEY}] = []dns.RR{
+			s.domain.key, other.sign(s.domain.key),
+		}
+		result := s.validator().Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECInvalidSignature) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with the bundled trust anchors", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		v := NewDNSSECValidator(s.db.transport())
+		result := v.Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECNoTrustAnchor) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusBogus {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("when the transport fails", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		expected := errors.New("mocked error")
+		v := s.validator()
+		v.Txp = &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				return nil, expected
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		result := v.Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, expected) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusIndeterminate {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("does not cache failures caused by the context", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		v := s.validator()
+		txp := s.db.transport().(*mocks.DNSTransport)
+		roundTrip := txp.MockRoundTrip
+		txp.MockRoundTrip = func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return roundTrip(ctx, query)
+		}
+		v.Txp = txp
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result := v.Validate(ctx, s.response(s.answer...))
+		if !errors.Is(result.Failure, context.Canceled) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		result = v.Validate(context.Background(), s.response(s.answer...))
+		if result.Failure != nil {
+			t.Fatal(result.Failure)
+		}
+		if result.Status != DNSSECStatusSecure {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("when the server returns an unexpected rcode", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		v := s.validator()
+		v.Txp = &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				rawQuery := runtimex.Try1(query.Bytes())
+				return (&DNSDecoderMiekg{}).DecodeResponse(
+					dnsGenReplyWithError(rawQuery, dns.RcodeServerFailure), query)
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		result := v.Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrOODNSMisbehaving) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+		if result.Status != DNSSECStatusIndeterminate {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+
+	t.Run("with the zero value", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		v := &DNSSECValidator{Txp: s.db.transport()}
+		result := v.Validate(context.Background(), s.response(s.answer...))
+		if !errors.Is(result.Failure, ErrDNSSECNoTrustAnchor) {
+			t.Fatal("unexpected error", result.Failure)
+		}
+	})
+}
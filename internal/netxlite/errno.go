@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-07-24 14:17:59.54585 +0530 IST m=+0.607093210
+// Generated: 2026-10-16 12:53:30.408762168 +0000 UTC m=+0.229241994
 
 package netxlite
 
@@ -50,6 +50,7 @@ const (
 	FailurePermissionDenied                = "permission_denied"
 	FailureProtocolNotSupported            = "protocol_not_supported"
 	FailureQUICIncompatibleVersion         = "quic_incompatible_version"
+	FailureSSLECHRejected                  = "ssl_ech_rejected"
 	FailureSSLFailedHandshake              = "ssl_failed_handshake"
 	FailureSSLInvalidCertificate           = "ssl_invalid_certificate"
 	FailureSSLInvalidHostname              = "ssl_invalid_hostname"
@@ -102,6 +103,7 @@ var failuresMap = map[string]string{
 	"permission_denied":                   "permission_denied",
 	"protocol_not_supported":              "protocol_not_supported",
 	"quic_incompatible_version":           "quic_incompatible_version",
+	"ssl_ech_rejected":                    "ssl_ech_rejected",
 	"ssl_failed_handshake":                "ssl_failed_handshake",
 	"ssl_invalid_certificate":             "ssl_invalid_certificate",
 	"ssl_invalid_hostname":                "ssl_invalid_hostname",
@@ -0,0 +1,240 @@
+package netxlite
+
+//
+// HTTP/2 code with frame tracing
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"golang.org/x/net/http2"
+)
+
+// ErrHTTP2NotNegotiated indicates that the server did not negotiate "h2" using ALPN.
+var ErrHTTP2NotNegotiated = errors.New("netxlite: server did not negotiate h2")
+
+// http2Transport is an HTTPTransport using the golang.org/x/net/http2 transport.
+type http2Transport struct {
+	child *http2.Transport
+}
+
+var _ model.HTTPTransport = &http2Transport{}
+
+// Network implements HTTPTransport.Network.
+func (txp *http2Transport) Network() string {
+	return "tcp"
+}
+
+// RoundTrip implements HTTPTransport.RoundTrip.
+func (txp *http2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return txp.child.RoundTrip(req)
+}
+
+// CloseIdleConnections implements HTTPTransport.CloseIdleConnections.
+func (txp *http2Transport) CloseIdleConnections() {
+	txp.child.CloseIdleConnections()
+}
+
+// NewHTTP2Transport creates a new HTTPTransport only speaking HTTP/2. The
+// tlsDialer argument MUST NOT be nil and MUST configure "h2" as ALPN, which
+// [NewTLSDialer] does by default for port 443. The returned transport fails with
+// [ErrHTTP2NotNegotiated] if the server does not negotiate "h2".
+//
+// Unlike [NewHTTPTransport], this transport uses HTTP/2 with any [model.TLSConn]
+// (e.g., connections created using uTLS), since it does not need a *tls.Conn.
+//
+// This transport calls the [model.Trace] OnHTTP2Frame method for each HTTP/2
+// frame it reads or writes. We use the trace bound to the context of the HTTP
+// request causing us to dial a new connection (see [ContextWithTrace]), so the
+// frames of a connection are reported to the trace that created it.
+//
+// Like [NewHTTPTransport], the returned transport disables compression and
+// enforces a read watchdog timeout (see https://github.com/ooni/probe/issues/1609).
+func NewHTTP2Transport(logger model.DebugLogger, tlsDialer model.TLSDialer) model.HTTPTransport {
+	tlsDialer = &httpTLSDialerWithReadTimeout{tlsDialer}
+	return WrapHTTPTransport(logger, &httpTransportConnectionsCloser{
+		HTTPTransport: &http2Transport{
+			child: &http2.Transport{
+				DialTLSContext: func(ctx context.Context, network, address string, _ *tls.Config) (net.Conn, error) {
+					conn, err := tlsDialer.DialTLSContext(ctx, network, address)
+					if err != nil {
+						return nil, err
+					}
+					return newHTTP2FrameTracingConn(conn, ContextTraceOrDefault(ctx))
+				},
+				// The following (1) reduces the number of headers that Go will
+				// automatically send for us and (2) ensures that we always receive
+				// back the true headers, such as Content-Length. This change is
+				// functional to OONI's goal of observing the network.
+				DisableCompression: true,
+			},
+		},
+		Dialer:    NewNullDialer(),
+		TLSDialer: tlsDialer,
+	})
+}
+
+// newHTTP2FrameTracingConn wraps the given conn to trace HTTP/2 frames. This function
+// takes ownership of the conn and closes it when it returns an error.
+func newHTTP2FrameTracingConn(conn net.Conn, trace model.Trace) (net.Conn, error) {
+	tlsConn, good := conn.(TLSConn)
+	if !good {
+		conn.Close()
+		return nil, ErrNotTLSConn
+	}
+	if tlsConn.ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+		conn.Close()
+		return nil, ErrHTTP2NotNegotiated
+	}
+	var network, address string
+	if addr := conn.RemoteAddr(); addr != nil {
+		network, address = addr.Network(), addr.String()
+	}
+	newTracer := func(operation string) func(frame *model.HTTP2Frame) {
+		return func(frame *model.HTTP2Frame) {
+			trace.OnHTTP2Frame(trace.TimeNow(), operation, network, address, frame)
+		}
+	}
+	return &http2FrameTracingConn{
+		TLSConn: tlsConn,
+		reader: &http2FrameParser{
+			skip:    0,
+			onFrame: newTracer(HTTP2ReadFrameOperation),
+		},
+		writer: &http2FrameParser{
+			skip:    len(http2.ClientPreface),
+			onFrame: newTracer(HTTP2WriteFrameOperation),
+		},
+	}, nil
+}
+
+// http2FrameTracingConn is a [TLSConn] tracing the HTTP/2 frames it reads and writes.
+//
+// The HTTP/2 transport reads using a single goroutine and serializes writes,
+// so it's safe to use distinct parsers without any locking.
+type http2FrameTracingConn struct {
+	TLSConn
+	reader *http2FrameParser
+	writer *http2FrameParser
+}
+
+// Read implements net.Conn.Read.
+func (c *http2FrameTracingConn) Read(b []byte) (int, error) {
+	count, err := c.TLSConn.Read(b)
+	c.reader.Parse(b[:count])
+	return count, err
+}
+
+// Write implements net.Conn.Write.
+func (c *http2FrameTracingConn) Write(b []byte) (int, error) {
+	count, err := c.TLSConn.Write(b)
+	c.writer.Parse(b[:count])
+	return count, err
+}
+
+// These constants are defined by RFC 9113.
+const (
+	http2FrameHeaderLen = 9
+
+	// http2FramePayloadPrefixLen is the size of the GOAWAY payload
+	// prefix containing the last stream ID and the error code.
+	http2FramePayloadPrefixLen = 8
+
+	http2StreamIDMask = 1<<31 - 1
+)
+
+// http2FrameParser incrementally parses a stream of HTTP/2 frames and calls
+// onFrame after consuming each frame. The zero value is invalid.
+type http2FrameParser struct {
+	// skip is the number of connection preface bytes we still need to skip.
+	skip int
+
+	// header buffers the header of the next frame.
+	header []byte
+
+	// frame is the frame we're parsing or nil when reading the header.
+	frame *model.HTTP2Frame
+
+	// frameType is the type of the frame we're parsing.
+	frameType http2.FrameType
+
+	// payload buffers the beginning of the frame payload.
+	payload []byte
+
+	// remaining is the number of payload bytes we have not seen yet.
+	remaining uint32
+
+	// onFrame is the MANDATORY function called for each frame.
+	onFrame func(frame *model.HTTP2Frame)
+}
+
+// Parse parses the given data, which MUST be the continuation of the
+// data passed to previous invocations of this method.
+func (p *http2FrameParser) Parse(data []byte) {
+	for len(data) > 0 {
+		switch {
+		case p.skip > 0:
+			count := min(p.skip, len(data))
+			p.skip -= count
+			data = data[count:]
+			continue
+
+		case p.frame == nil:
+			count := min(http2FrameHeaderLen-len(p.header), len(data))
+			p.header = append(p.header, data[:count]...)
+			data = data[count:]
+			if len(p.header) < http2FrameHeaderLen {
+				return
+			}
+			p.frameType = http2.FrameType(p.header[3])
+			p.frame = &model.HTTP2Frame{
+				Type:         p.frameType.String(),
+				Flags:        p.header[4],
+				Length:       uint32(p.header[0])<<16 | uint32(p.header[1])<<8 | uint32(p.header[2]),
+				StreamID:     binary.BigEndian.Uint32(p.header[5:]) & http2StreamIDMask,
+				ErrCode:      "",
+				LastStreamID: 0,
+			}
+			p.remaining = p.frame.Length
+			p.header = p.header[:0]
+
+		default:
+			count := min(int(p.remaining), len(data))
+			if room := http2FramePayloadPrefixLen - len(p.payload); room > 0 {
+				p.payload = append(p.payload, data[:min(room, count)]...)
+			}
+			p.remaining -= uint32(count)
+			data = data[count:]
+		}
+
+		if p.remaining <= 0 {
+			p.onFrame(p.finishFrame())
+		}
+	}
+}
+
+// finishFrame decodes the buffered payload and resets the parser state
+// to parse the next frame, returning the frame we have parsed.
+func (p *http2FrameParser) finishFrame() *model.HTTP2Frame {
+	frame := p.frame
+	switch p.frameType {
+	case http2.FrameRSTStream:
+		if len(p.payload) >= 4 {
+			frame.ErrCode = http2.ErrCode(binary.BigEndian.Uint32(p.payload)).String()
+		}
+	case http2.FrameGoAway:
+		if len(p.payload) >= 8 {
+			frame.LastStreamID = binary.BigEndian.Uint32(p.payload) & http2StreamIDMask
+			frame.ErrCode = http2.ErrCode(binary.BigEndian.Uint32(p.payload[4:])).String()
+		}
+	}
+	p.frame = nil
+	p.payload = p.payload[:0]
+	return frame
+}
@@ -0,0 +1,209 @@
+package netxlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"golang.org/x/net/http2"
+)
+
+func TestHTTP2FrameParser(t *testing.T) {
+	// serialize the frames we expect to parse
+	buff := &bytes.Buffer{}
+	buff.WriteString(http2.ClientPreface)
+	framer := http2.NewFramer(buff, nil)
+	if err := framer.WriteSettings(http2.Setting{ID: http2.SettingEnablePush, Val: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := framer.WriteSettingsAck(); err != nil {
+		t.Fatal(err)
+	}
+	if err := framer.WriteData(1, true, []byte("0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	if err := framer.WriteRSTStream(3, http2.ErrCodeRefusedStream); err != nil {
+		t.Fatal(err)
+	}
+	if err := framer.WriteGoAway(5, http2.ErrCodeProtocol, []byte("bye")); err != nil {
+		t.Fatal(err)
+	}
+	data := buff.Bytes()
+
+	expect := []*model.HTTP2Frame{{
+		Type:   "SETTINGS",
+		Flags:  0,
+		Length: 6,
+	}, {
+		Type:  "SETTINGS",
+		Flags: uint8(http2.FlagSettingsAck),
+	}, {
+		Type:     "DATA",
+		Flags:    uint8(http2.FlagDataEndStream),
+		Length:   16,
+		StreamID: 1,
+	}, {
+		Type:     "RST_STREAM",
+		Length:   4,
+		StreamID: 3,
+		ErrCode:  "REFUSED_STREAM",
+	}, {
+		Type:         "GOAWAY",
+		Length:       11,
+		ErrCode:      "PROTOCOL_ERROR",
+		LastStreamID: 5,
+	}}
+
+	// parse the data using chunks of different sizes
+	for _, chunkSize := range []int{1, 3, 7, len(data)} {
+		var frames []*model.HTTP2Frame
+		parser := &http2FrameParser{
+			skip: len(http2.ClientPreface),
+			onFrame: func(frame *model.HTTP2Frame) {
+				frames = append(frames, frame)
+			},
+		}
+		for chunk := data; len(chunk) > 0; {
+			count := min(chunkSize, len(chunk))
+			parser.Parse(chunk[:count])
+			chunk = chunk[count:]
+		}
+		if diff := cmp.Diff(expect, frames); diff != "" {
+			t.Fatal(chunkSize, diff)
+		}
+	}
+}
+
+func TestNewHTTP2Transport(t *testing.T) {
+	// newServer creates a TLS server optionally supporting HTTP/2.
+	newServer := func(enableHTTP2 bool) (*httptest.Server, *x509.CertPool) {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Bonsoir, Elliot!\n"))
+		}))
+		srv.EnableHTTP2 = enableHTTP2
+		srv.StartTLS()
+		pool := x509.NewCertPool()
+		pool.AddCert(srv.Certificate())
+		return srv, pool
+	}
+
+	// newTransport creates a new transport using the given cert pool.
+	newTransport := func(pool *x509.CertPool) model.HTTPTransport {
+		netx := &Netx{}
+		tlsDialer := NewTLSDialerWithConfig(
+			netx.NewDialerWithoutResolver(model.DiscardLogger),
+			netx.NewTLSHandshakerStdlib(model.DiscardLogger),
+			&tls.Config{
+				NextProtos: []string{"h2", "http/1.1"},
+				RootCAs:    pool,
+			},
+		)
+		return NewHTTP2Transport(model.DiscardLogger, tlsDialer)
+	}
+
+	t.Run("we trace the frames we read and write", func(t *testing.T) {
+		srv, pool := newServer(true)
+		defer srv.Close()
+		txp := newTransport(pool)
+		defer txp.CloseIdleConnections()
+
+		if txp.Network() != "tcp" {
+			t.Fatal("unexpected network", txp.Network())
+		}
+
+		var (
+			mu     sync.Mutex
+			frames = map[string][]string{}
+		)
+		trace := &mocks.Trace{
+			MockTimeNow: time.Now,
+			MockMaybeWrapNetConn: func(conn net.Conn) net.Conn {
+				return conn
+			},
+			MockOnConnectDone: func(started time.Time, network, domain, remoteAddr string, err error, finished time.Time) {
+				// nothing
+			},
+			MockOnTLSHandshakeStart: func(now time.Time, remoteAddr string, config *tls.Config) {
+				// nothing
+			},
+			MockOnTLSHandshakeDone: func(started time.Time, remoteAddr string, config *tls.Config,
+				state tls.ConnectionState, err error, finished time.Time) {
+				// nothing
+			},
+			MockOnHTTP2Frame: func(now time.Time, operation, network, remoteAddr string, frame *model.HTTP2Frame) {
+				if network != "tcp" || remoteAddr != srv.Listener.Addr().String() {
+					t.Error("unexpected network or address", network, remoteAddr)
+				}
+				mu.Lock()
+				frames[operation] = append(frames[operation], frame.Type)
+				mu.Unlock()
+			},
+		}
+
+		ctx := ContextWithTrace(context.Background(), trace)
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := txp.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.ProtoMajor != 2 {
+			t.Fatal("expected HTTP/2, got", resp.Proto)
+		}
+		if _, err := ReadAllContext(ctx, resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		has := func(operation, frameType string) bool {
+			for _, entry := range frames[operation] {
+				if entry == frameType {
+					return true
+				}
+			}
+			return false
+		}
+		for _, frameType := range []string{"SETTINGS", "HEADERS"} {
+			if !has(HTTP2WriteFrameOperation, frameType) {
+				t.Fatal("did not write", frameType, frames)
+			}
+			if !has(HTTP2ReadFrameOperation, frameType) {
+				t.Fatal("did not read", frameType, frames)
+			}
+		}
+	})
+
+	t.Run("we fail if the server does not negotiate h2", func(t *testing.T) {
+		srv, pool := newServer(false)
+		defer srv.Close()
+		txp := newTransport(pool)
+		defer txp.CloseIdleConnections()
+
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := txp.RoundTrip(req)
+		if !errors.Is(err, ErrHTTP2NotNegotiated) {
+			t.Fatal("unexpected error", err)
+		}
+		if resp != nil {
+			t.Fatal("expected nil response")
+		}
+	})
+}
@@ -43,6 +43,12 @@ const (
 	// WriteToOperation is when we write to an UDP socket.
 	WriteToOperation = "write_to"
 
+	// HTTP2ReadFrameOperation is when we read an HTTP/2 frame.
+	HTTP2ReadFrameOperation = "http2_read_frame"
+
+	// HTTP2WriteFrameOperation is when we write an HTTP/2 frame.
+	HTTP2WriteFrameOperation = "http2_write_frame"
+
 	// UnknownOperation is when we cannot determine the operation.
 	UnknownOperation = "unknown"
 
@@ -117,3 +117,8 @@ func (r *cacheResolver) LookupHTTPS(ctx context.Context, domain string) (*model.
 func (r *cacheResolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, error) {
 	return nil, ErrNoDNSTransport
 }
+
+// LookupRecords implements model.Resolver.LookupRecords.
+func (r *cacheResolver) LookupRecords(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	return nil, ErrNoDNSTransport
+}
@@ -36,6 +36,13 @@ func (netx *Netx) NewParallelDNSOverHTTPSResolver(logger model.DebugLogger, URL
 	return WrapResolver(logger, NewUnwrappedParallelResolver(txp))
 }
 
+// NewParallelDNSOverQUICResolver implements [model.MeasuringNetwork].
+func (netx *Netx) NewParallelDNSOverQUICResolver(logger model.DebugLogger, address string) model.Resolver {
+	dialer := netx.NewQUICDialerWithResolver(netx.NewUDPListener(), logger, netx.NewStdlibResolver(logger))
+	txp := wrapDNSTransport(NewUnwrappedDNSOverQUICTransport(dialer, address))
+	return WrapResolver(logger, NewUnwrappedParallelResolver(txp))
+}
+
 func (netx *Netx) newUnwrappedStdlibResolver() model.Resolver {
 	return &resolverSystem{
 		t: wrapDNSTransport(netx.newDNSOverGetaddrinfoTransport()),
@@ -72,8 +79,17 @@ func NewSerialUDPResolver(logger model.DebugLogger, dialer model.Dialer, address
 
 // NewParallelUDPResolver implements [model.MeasuringNetwork].
 func (netx *Netx) NewParallelUDPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+	return NewParallelUDPResolverWithOptions(logger, dialer, address)
+}
+
+// NewParallelUDPResolverWithOptions is like [*Netx.NewParallelUDPResolver] but allows
+// to configure the underlying [*ParallelResolver] using the given options (e.g., to
+// request DNSSEC records using [ParallelResolverOptionDNSSEC]).
+func NewParallelUDPResolverWithOptions(logger model.DebugLogger, dialer model.Dialer,
+	address string, options ...ParallelResolverOption) model.Resolver {
 	return WrapResolver(logger, NewUnwrappedParallelResolver(
 		wrapDNSTransport(NewUnwrappedDNSOverUDPTransport(dialer, address)),
+		options...,
 	))
 }
 
@@ -151,6 +167,11 @@ func (r *resolverSystem) LookupNS(
 	return nil, ErrNoDNSTransport
 }
 
+func (r *resolverSystem) LookupRecords(
+	ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	return nil, ErrNoDNSTransport
+}
+
 // resolverLogger is a resolver that emits events
 type resolverLogger struct {
 	Resolver model.Resolver
@@ -218,6 +239,22 @@ func (r *resolverLogger) LookupNS(
 	return ns, nil
 }
 
+func (r *resolverLogger) LookupRecords(
+	ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	prefix := fmt.Sprintf("resolve[%s] %s with %s (%s)",
+		dns.TypeToString[qtype], domain, r.Network(), r.Address())
+	r.Logger.Debugf("%s...", prefix)
+	start := time.Now()
+	records, err := r.Resolver.LookupRecords(ctx, domain, qtype)
+	elapsed := time.Since(start)
+	if err != nil {
+		r.Logger.Debugf("%s... %s in %s", prefix, err, elapsed)
+		return nil, err
+	}
+	r.Logger.Debugf("%s... %d records in %s", prefix, len(records), elapsed)
+	return records, nil
+}
+
 // resolverIDNA supports resolving Internationalized Domain Names.
 //
 // See RFC3492 for more information.
@@ -286,6 +323,15 @@ func (r *resolverIDNA) LookupNS(
 	return r.Resolver.LookupNS(ctx, host)
 }
 
+func (r *resolverIDNA) LookupRecords(
+	ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	host, err := idnax.ToASCII(domain)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolver.LookupRecords(ctx, host, qtype)
+}
+
 // ResolverShortCircuitIPAddr recognizes when the input hostname is an
 // IP address and returns it immediately to the caller.
 type ResolverShortCircuitIPAddr struct {
@@ -338,6 +384,14 @@ func (r *ResolverShortCircuitIPAddr) LookupNS(
 	return r.Resolver.LookupNS(ctx, hostname)
 }
 
+func (r *ResolverShortCircuitIPAddr) LookupRecords(
+	ctx context.Context, hostname string, qtype uint16) ([]*model.DNSRecord, error) {
+	if net.ParseIP(hostname) != nil {
+		return nil, ErrDNSIPAddress
+	}
+	return r.Resolver.LookupRecords(ctx, hostname, qtype)
+}
+
 // IsIPv6 returns true if the given candidate is a valid IP address
 // representation and such representation is IPv6.
 func IsIPv6(candidate string) (bool, error) {
@@ -390,6 +444,11 @@ func (r *NullResolver) LookupNS(
 	return nil, ErrNoResolver
 }
 
+func (r *NullResolver) LookupRecords(
+	ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	return nil, ErrNoResolver
+}
+
 // resolverErrWrapper is a Resolver that knows about wrapping errors.
 type resolverErrWrapper struct {
 	Resolver model.Resolver
@@ -434,3 +493,12 @@ func (r *resolverErrWrapper) LookupNS(
 	}
 	return out, nil
 }
+
+func (r *resolverErrWrapper) LookupRecords(
+	ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+	out, err := r.Resolver.LookupRecords(ctx, domain, qtype)
+	if err != nil {
+		return nil, NewErrWrapper(ClassifyResolverError, ResolveOperation, err)
+	}
+	return out, nil
+}
@@ -92,6 +92,24 @@ func TestNewParallelDNSOverHTTPSResolver(t *testing.T) {
 	}
 }
 
+func TestNewParallelDNSOverQUICResolver(t *testing.T) {
+	netx := &Netx{}
+	resolver := netx.NewParallelDNSOverQUICResolver(log.Log, "dns.adguard-dns.com:853")
+	idnaReso := resolver.(*resolverIDNA)
+	logger := idnaReso.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*ResolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverQUICTransport)
+	if dnsTxp.Address() != "dns.adguard-dns.com:853" {
+		t.Fatal("invalid address")
+	}
+}
+
 func TestResolverSystem(t *testing.T) {
 	t.Run("Network", func(t *testing.T) {
 		expected := "antani"
@@ -209,6 +227,17 @@ func TestResolverSystem(t *testing.T) {
 		}
 	})
 
+	t.Run("LookupRecords", func(t *testing.T) {
+		r := &resolverSystem{}
+		records, err := r.LookupRecords(context.Background(), "x.org", dns.TypeTXT)
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("not the error we expected")
+		}
+		if len(records) != 0 {
+			t.Fatal("expected no results")
+		}
+	})
+
 	t.Run("uses a context-injected custom trace (success case)", func(t *testing.T) {
 		var (
 			onLookupCalled     bool
@@ -566,6 +595,81 @@ func TestResolverLogger(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("LookupRecords", func(t *testing.T) {
+		t.Run("with success", func(t *testing.T) {
+			var count int
+			lo := &mocks.Logger{
+				MockDebugf: func(format string, v ...interface{}) {
+					count++
+				},
+			}
+			expected := []*model.DNSRecord{{
+				Name:  "dns.google.",
+				Type:  dns.TypeTXT,
+				TTL:   300,
+				Value: `"v=spf1 -all"`,
+			}}
+			r := &resolverLogger{
+				Logger: lo,
+				Resolver: &mocks.Resolver{
+					MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+						return expected, nil
+					},
+					MockNetwork: func() string {
+						return "udp"
+					},
+					MockAddress: func() string {
+						return "8.8.8.8:53"
+					},
+				},
+			}
+			records, err := r.LookupRecords(context.Background(), "dns.google", dns.TypeTXT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, records); diff != "" {
+				t.Fatal(diff)
+			}
+			if count != 2 {
+				t.Fatal("unexpected count")
+			}
+		})
+
+		t.Run("with failure", func(t *testing.T) {
+			var count int
+			lo := &mocks.Logger{
+				MockDebugf: func(format string, v ...interface{}) {
+					count++
+				},
+			}
+			expected := errors.New("mocked error")
+			r := &resolverLogger{
+				Logger: lo,
+				Resolver: &mocks.Resolver{
+					MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+						return nil, expected
+					},
+					MockNetwork: func() string {
+						return "udp"
+					},
+					MockAddress: func() string {
+						return "8.8.8.8:53"
+					},
+				},
+			}
+			records, err := r.LookupRecords(context.Background(), "dns.google", dns.TypeTXT)
+			if !errors.Is(err, expected) {
+				t.Fatal("not the error we expected", err)
+			}
+			if records != nil {
+				t.Fatal("expected nil records here")
+			}
+			if count != 2 {
+				t.Fatal("unexpected count")
+			}
+		})
+	})
 }
 
 func TestResolverIDNA(t *testing.T) {
@@ -803,6 +907,52 @@ func TestResolverIDNA(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("LookupRecords", func(t *testing.T) {
+		t.Run("with valid IDNA in input", func(t *testing.T) {
+			expected := []*model.DNSRecord{{
+				Name:  "xn--d1acpjx3f.xn--p1ai.",
+				Type:  dns.TypeMX,
+				TTL:   300,
+				Value: "10 mx.yandex.ru.",
+			}}
+			r := &resolverIDNA{
+				Resolver: &mocks.Resolver{
+					MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+						if domain != "xn--d1acpjx3f.xn--p1ai" {
+							return nil, errors.New("passed invalid domain")
+						}
+						return expected, nil
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "яндекс.рф", dns.TypeMX)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, records); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("with invalid punycode", func(t *testing.T) {
+			r := &resolverIDNA{Resolver: &mocks.Resolver{
+				MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+					return nil, errors.New("should not happen")
+				},
+			}}
+			// See https://www.farsightsecurity.com/blog/txt-record/punycode-20180711/
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "xn--0000h", dns.TypeMX)
+			if err == nil || !strings.HasPrefix(err.Error(), "idna: invalid label") {
+				t.Fatal("not the error we expected")
+			}
+			if records != nil {
+				t.Fatal("expected no response here")
+			}
+		})
+	})
 }
 
 func TestResolverShortCircuitIPAddr(t *testing.T) {
@@ -956,6 +1106,44 @@ func TestResolverShortCircuitIPAddr(t *testing.T) {
 		})
 	})
 
+	t.Run("LookupRecords", func(t *testing.T) {
+		t.Run("with IP addr", func(t *testing.T) {
+			r := &ResolverShortCircuitIPAddr{
+				Resolver: &mocks.Resolver{
+					MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+						return nil, errors.New("mocked error")
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "8.8.8.8", dns.TypeTXT)
+			if !errors.Is(err, ErrDNSIPAddress) {
+				t.Fatal("unexpected error", err)
+			}
+			if len(records) > 0 {
+				t.Fatal("invalid result")
+			}
+		})
+
+		t.Run("with domain", func(t *testing.T) {
+			r := &ResolverShortCircuitIPAddr{
+				Resolver: &mocks.Resolver{
+					MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+						return nil, errors.New("mocked error")
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "dns.google", dns.TypeTXT)
+			if err == nil || err.Error() != "mocked error" {
+				t.Fatal("not the error we expected", err)
+			}
+			if len(records) > 0 {
+				t.Fatal("invalid result")
+			}
+		})
+	})
+
 	t.Run("Network", func(t *testing.T) {
 		child := &mocks.Resolver{
 			MockNetwork: func() string {
@@ -1077,6 +1265,18 @@ func TestNullResolver(t *testing.T) {
 			t.Fatal("unexpected result")
 		}
 	})
+
+	t.Run("LookupRecords", func(t *testing.T) {
+		r := &NullResolver{}
+		ctx := context.Background()
+		records, err := r.LookupRecords(ctx, "dns.google", dns.TypeTXT)
+		if !errors.Is(err, ErrNoResolver) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(records) > 0 {
+			t.Fatal("unexpected result")
+		}
+	})
 }
 
 func TestResolverErrWrapper(t *testing.T) {
@@ -1246,4 +1446,49 @@ func TestResolverErrWrapper(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("LookupRecords", func(t *testing.T) {
+		t.Run("on success", func(t *testing.T) {
+			expected := []*model.DNSRecord{{
+				Name:  "antani.local.",
+				Type:  dns.TypeSOA,
+				TTL:   300,
+				Value: "ns.antani.local. admin.antani.local. 1 7200 3600 1209600 3600",
+			}}
+			reso := &resolverErrWrapper{
+				Resolver: &mocks.Resolver{
+					MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+						return expected, nil
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := reso.LookupRecords(ctx, "antani.local", dns.TypeSOA)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, records); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("on failure", func(t *testing.T) {
+			expected := io.EOF
+			reso := &resolverErrWrapper{
+				Resolver: &mocks.Resolver{
+					MockLookupRecords: func(ctx context.Context, domain string, qtype uint16) ([]*model.DNSRecord, error) {
+						return nil, expected
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := reso.LookupRecords(ctx, "", dns.TypeSOA)
+			if err == nil || err.Error() != FailureEOFError {
+				t.Fatal("unexpected err", err)
+			}
+			if len(records) > 0 {
+				t.Fatal("unexpected records")
+			}
+		})
+	})
 }
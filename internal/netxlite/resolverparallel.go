@@ -18,18 +18,68 @@ import (
 // You should probably use NewUnwrappedParallelResolver to
 // create a new instance of this type.
 type ParallelResolver struct {
+	// DNSSEC OPTIONALLY causes this resolver to request DNSSEC records and
+	// to validate the chain of trust of the responses. The validation outcome
+	// is available to the [model.Trace] because the responses passed to
+	// OnDNSRoundTripForLookupHost implement [model.DNSSECValidatedResponse].
+	DNSSEC bool
+
 	// Txp is the MANDATORY underlying DNS transport.
 	Txp model.DNSTransport
 }
 
 var _ model.Resolver = &ParallelResolver{}
 
+// ParallelResolverOption is an option you can pass to NewUnwrappedParallelResolver.
+type ParallelResolverOption func(r *ParallelResolver)
+
+// ParallelResolverOptionDNSSEC allows to configure the resolver to request
+// DNSSEC records and to validate the chain of trust of the responses.
+func ParallelResolverOptionDNSSEC(value bool) ParallelResolverOption {
+	return func(r *ParallelResolver) {
+		r.DNSSEC = value
+	}
+}
+
 // UnwrappedParallelResolver creates a new ParallelResolver instance. This instance is
 // not wrapped and you should wrap if before using it.
-func NewUnwrappedParallelResolver(t model.DNSTransport) *ParallelResolver {
-	return &ParallelResolver{
+func NewUnwrappedParallelResolver(t model.DNSTransport, options ...ParallelResolverOption) *ParallelResolver {
+	r := &ParallelResolver{
 		Txp: t,
 	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// newEncoder creates a new encoder honouring the resolver configuration
+// and the EDNS0 options bound to the context, if any.
+func (r *ParallelResolver) newEncoder(ctx context.Context) *DNSEncoderMiekg {
+	return &DNSEncoderMiekg{DNSSEC: r.DNSSEC, EDNS0: ContextDNSEDNS0Options(ctx)}
+}
+
+// newDNSSECValidator returns a new validator when we're configured to use DNSSEC and
+// nil otherwise. We use a single validator for each lookup, such that we fetch the
+// DNSKEY and DS records of each zone once, even when the lookup sends several queries.
+func (r *ParallelResolver) newDNSSECValidator() *DNSSECValidator {
+	if !r.DNSSEC {
+		return nil
+	}
+	return NewDNSSECValidator(r.Txp)
+}
+
+// parallelResolverMaybeValidateDNSSEC validates the response's chain of trust when
+// the validator is not nil and otherwise returns the original response.
+func parallelResolverMaybeValidateDNSSEC(
+	ctx context.Context, validator *DNSSECValidator, response model.DNSResponse) model.DNSResponse {
+	if validator == nil {
+		return response
+	}
+	return &dnssecValidatedResponse{
+		DNSResponse: response,
+		validation:  validator.Validate(ctx, response),
+	}
 }
 
 // Transport returns the transport being used.
@@ -54,10 +104,11 @@ func (r *ParallelResolver) CloseIdleConnections() {
 
 // LookupHost performs an A lookup in parallel with an AAAA lookup.
 func (r *ParallelResolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
+	validator := r.newDNSSECValidator()
 	ach := make(chan *parallelResolverResult)
-	go r.lookupHost(ctx, hostname, dns.TypeA, ach)
+	go r.lookupHost(ctx, validator, hostname, dns.TypeA, ach)
 	aaaach := make(chan *parallelResolverResult)
-	go r.lookupHost(ctx, hostname, dns.TypeAAAA, aaaach)
+	go r.lookupHost(ctx, validator, hostname, dns.TypeAAAA, aaaach)
 	ares := <-ach
 	aaaares := <-aaaach
 	if ares.err != nil && aaaares.err != nil {
@@ -78,7 +129,7 @@ func (r *ParallelResolver) LookupHost(ctx context.Context, hostname string) ([]s
 // LookupHTTPS implements Resolver.LookupHTTPS.
 func (r *ParallelResolver) LookupHTTPS(
 	ctx context.Context, hostname string) (*model.HTTPSSvc, error) {
-	encoder := &DNSEncoderMiekg{}
+	encoder := r.newEncoder(ctx)
 	trace := ContextTraceOrDefault(ctx)
 	query := encoder.Encode(hostname, dns.TypeHTTPS, r.Txp.RequiresPadding())
 	started := trace.TimeNow()
@@ -88,6 +139,7 @@ func (r *ParallelResolver) LookupHTTPS(
 		trace.OnDNSRoundTripForLookupHost(started, r, query, response, []string{}, err, finished)
 		return nil, err
 	}
+	response = parallelResolverMaybeValidateDNSSEC(ctx, r.newDNSSECValidator(), response)
 	trace.OnDNSRoundTripForLookupHost(started, r, query, response, []string{}, err, finished)
 	return response.DecodeHTTPS()
 }
@@ -100,9 +152,9 @@ type parallelResolverResult struct {
 }
 
 // lookupHost issues a lookup host query for the specified qtype (e.g., dns.A).
-func (r *ParallelResolver) lookupHost(ctx context.Context, hostname string,
-	qtype uint16, out chan<- *parallelResolverResult) {
-	encoder := &DNSEncoderMiekg{}
+func (r *ParallelResolver) lookupHost(ctx context.Context, validator *DNSSECValidator,
+	hostname string, qtype uint16, out chan<- *parallelResolverResult) {
+	encoder := r.newEncoder(ctx)
 	trace := ContextTraceOrDefault(ctx)
 	query := encoder.Encode(hostname, qtype, r.Txp.RequiresPadding())
 	started := trace.TimeNow()
@@ -116,6 +168,7 @@ func (r *ParallelResolver) lookupHost(ctx context.Context, hostname string,
 		}
 		return
 	}
+	response = parallelResolverMaybeValidateDNSSEC(ctx, validator, response)
 	addrs, err := response.DecodeLookupHost()
 	trace.OnDNSRoundTripForLookupHost(started, r, query, response, addrs, err, finished)
 	out <- &parallelResolverResult{
@@ -127,7 +180,7 @@ func (r *ParallelResolver) lookupHost(ctx context.Context, hostname string,
 // LookupNS implements Resolver.LookupNS.
 func (r *ParallelResolver) LookupNS(
 	ctx context.Context, hostname string) ([]*net.NS, error) {
-	encoder := &DNSEncoderMiekg{}
+	encoder := r.newEncoder(ctx)
 	query := encoder.Encode(hostname, dns.TypeNS, r.Txp.RequiresPadding())
 	response, err := r.Txp.RoundTrip(ctx, query)
 	if err != nil {
@@ -135,3 +188,21 @@ func (r *ParallelResolver) LookupNS(
 	}
 	return response.DecodeNS()
 }
+
+// LookupRecords implements Resolver.LookupRecords.
+func (r *ParallelResolver) LookupRecords(
+	ctx context.Context, hostname string, qtype uint16) ([]*model.DNSRecord, error) {
+	encoder := r.newEncoder(ctx)
+	trace := ContextTraceOrDefault(ctx)
+	query := encoder.Encode(hostname, qtype, r.Txp.RequiresPadding())
+	started := trace.TimeNow()
+	response, err := r.Txp.RoundTrip(ctx, query)
+	finished := trace.TimeNow()
+	if err != nil {
+		trace.OnDNSRoundTripForLookupHost(started, r, query, response, []string{}, err, finished)
+		return nil, err
+	}
+	response = parallelResolverMaybeValidateDNSSEC(ctx, r.newDNSSECValidator(), response)
+	trace.OnDNSRoundTripForLookupHost(started, r, query, response, []string{}, err, finished)
+	return response.DecodeRecords()
+}
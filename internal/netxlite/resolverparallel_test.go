@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/testingx"
 )
 
@@ -276,6 +278,225 @@ func TestParallelResolver(t *testing.T) {
 		})
 	})
 
+	t.Run("LookupRecords", func(t *testing.T) {
+		t.Run("for round-trip error", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			r := &ParallelResolver{
+				Txp: &mocks.DNSTransport{
+					MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+						return nil, expected
+					},
+					MockRequiresPadding: func() bool {
+						return false
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "example.com", dns.TypeTXT)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if records != nil {
+				t.Fatal("unexpected result")
+			}
+		})
+
+		t.Run("for decode error", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			r := &ParallelResolver{
+				Txp: &mocks.DNSTransport{
+					MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+						response := &mocks.DNSResponse{
+							MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+								return nil, expected
+							},
+						}
+						return response, nil
+					},
+					MockRequiresPadding: func() bool {
+						return false
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "example.com", dns.TypeTXT)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if records != nil {
+				t.Fatal("unexpected result")
+			}
+		})
+
+		t.Run("for success", func(t *testing.T) {
+			expected := []*model.DNSRecord{{
+				Name:  "example.com.",
+				Type:  dns.TypeTXT,
+				TTL:   300,
+				Value: `"v=spf1 -all"`,
+			}}
+			var gotType uint16
+			r := &ParallelResolver{
+				Txp: &mocks.DNSTransport{
+					MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+						gotType = query.Type()
+						response := &mocks.DNSResponse{
+							MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+								return expected, nil
+							},
+						}
+						return response, nil
+					},
+					MockRequiresPadding: func() bool {
+						return false
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "example.com", dns.TypeTXT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, records); diff != "" {
+				t.Fatal(diff)
+			}
+			if gotType != dns.TypeTXT {
+				t.Fatal("unexpected query type", gotType)
+			}
+		})
+	})
+
+	t.Run("with EDNS0 options from the context", func(t *testing.T) {
+		mocked := errors.New("mocked error")
+		var rawQueries [][]byte
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				rawQuery, err := query.Bytes()
+				if err != nil {
+					return nil, err
+				}
+				rawQueries = append(rawQueries, rawQuery)
+				return nil, mocked
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		r := NewUnwrappedParallelResolver(txp)
+		option := &dns.EDNS0_NSID{Code: dns.EDNS0NSID}
+		ctx := ContextWithDNSEDNS0Options(context.Background(), option)
+		if _, err := r.LookupNS(ctx, "example.com"); !errors.Is(err, mocked) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(rawQueries) != 1 {
+			t.Fatal("expected a single query")
+		}
+		msg := &dns.Msg{}
+		if err := msg.Unpack(rawQueries[0]); err != nil {
+			t.Fatal(err)
+		}
+		opt := msg.IsEdns0()
+		if opt == nil || len(opt.Option) != 1 || opt.Option[0].Option() != dns.EDNS0NSID {
+			t.Fatal("expected the NSID option", opt)
+		}
+	})
+
+	t.Run("with DNSSEC", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeA}] = s.answer
+		r := NewUnwrappedParallelResolver(s.db.transport())
+		r.DNSSEC = true
+		var validations []*model.DNSSECValidation
+		tx := &mocks.Trace{
+			MockTimeNow: time.Now,
+			MockOnDNSRoundTripForLookupHost: func(started time.Time, reso model.Resolver, query model.DNSQuery,
+				response model.DNSResponse, addrs []string, err error, finished time.Time) {
+				if query.Type() != dns.TypeA {
+					return
+				}
+				if vr, ok := response.(model.DNSSECValidatedResponse); ok {
+					validations = append(validations, vr.DNSSECValidation())
+				}
+			},
+		}
+		ctx := ContextWithTrace(context.Background(), tx)
+		addrs, err := r.LookupHost(ctx, "example.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"93.184.216.34"}, addrs); diff != "" {
+			t.Fatal(diff)
+		}
+		if len(validations) != 1 {
+			t.Fatal("expected a single validation")
+		}
+		// we're using our own root zone, so the bundled trust anchors don't match
+		if validations[0].Status != DNSSECStatusBogus ||
+			!errors.Is(validations[0].Failure, ErrDNSSECNoTrustAnchor) {
+			t.Fatal("unexpected validation", validations[0])
+		}
+	})
+
+	t.Run("with ParallelResolverOptionDNSSEC", func(t *testing.T) {
+		s := newDNSSECTestSetup()
+		s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeA}] = s.answer
+		aaaa := &dns.AAAA{
+			Hdr: dns.RR_Header{
+				Name:   "example.org.",
+				Rrtype: dns.TypeAAAA,
+				Class:  dns.ClassINET,
+				Ttl:    300,
+			},
+			AAAA: net.ParseIP("2606:2800:220:1:248:1893:25c8:1946"),
+		}
+		s.db[dnssecRRsetKey{name: "example.org.", qtype: dns.TypeAAAA}] = []dns.RR{aaaa, s.domain.sign(aaaa)}
+		underlying := s.db.transport()
+		var (
+			mu     sync.Mutex
+			counts = make(map[dnssecRRsetKey]int)
+		)
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				msg := &dns.Msg{}
+				if err := msg.Unpack(runtimex.Try1(query.Bytes())); err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				counts[dnssecRRsetKey{name: msg.Question[0].Name, qtype: msg.Question[0].Qtype}]++
+				mu.Unlock()
+				return underlying.RoundTrip(ctx, query)
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		r := NewUnwrappedParallelResolver(txp, ParallelResolverOptionDNSSEC(true))
+		if !r.DNSSEC {
+			t.Fatal("expected DNSSEC to be enabled")
+		}
+		addrs, err := r.LookupHost(context.Background(), "example.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != 2 {
+			t.Fatal("unexpected addrs", addrs)
+		}
+
+		// the A and AAAA validations share the same chain of trust, which we
+		// must only fetch once per zone during a single lookup
+		for key, count := range counts {
+			if key.qtype != dns.TypeDNSKEY && key.qtype != dns.TypeDS {
+				continue
+			}
+			if count != 1 {
+				t.Fatal("fetched", key, "more than once:", count)
+			}
+		}
+		if counts[dnssecRRsetKey{name: ".", qtype: dns.TypeDNSKEY}] != 1 {
+			t.Fatal("expected to fetch the root DNSKEY", counts)
+		}
+	})
+
 	t.Run("uses a context-injected custom trace (success case)", func(t *testing.T) {
 		var (
 			onLookupACalled        bool
@@ -85,7 +85,7 @@ func (r *SerialResolver) LookupHost(ctx context.Context, hostname string) ([]str
 // LookupHTTPS implements Resolver.LookupHTTPS.
 func (r *SerialResolver) LookupHTTPS(
 	ctx context.Context, hostname string) (*model.HTTPSSvc, error) {
-	encoder := &DNSEncoderMiekg{}
+	encoder := &DNSEncoderMiekg{EDNS0: ContextDNSEDNS0Options(ctx)}
 	query := encoder.Encode(hostname, dns.TypeHTTPS, r.Txp.RequiresPadding())
 	response, err := r.Txp.RoundTrip(ctx, query)
 	if err != nil {
@@ -126,7 +126,7 @@ func (r *SerialResolver) lookupHostWithRetry(
 // qtype (dns.A or dns.AAAA) without retrying on failure.
 func (r *SerialResolver) lookupHostWithoutRetry(
 	ctx context.Context, hostname string, qtype uint16) ([]string, error) {
-	encoder := &DNSEncoderMiekg{}
+	encoder := &DNSEncoderMiekg{EDNS0: ContextDNSEDNS0Options(ctx)}
 	query := encoder.Encode(hostname, qtype, r.Txp.RequiresPadding())
 	response, err := r.Txp.RoundTrip(ctx, query)
 	if err != nil {
@@ -138,7 +138,7 @@ func (r *SerialResolver) lookupHostWithoutRetry(
 // LookupNS implements Resolver.LookupNS.
 func (r *SerialResolver) LookupNS(
 	ctx context.Context, hostname string) ([]*net.NS, error) {
-	encoder := &DNSEncoderMiekg{}
+	encoder := &DNSEncoderMiekg{EDNS0: ContextDNSEDNS0Options(ctx)}
 	query := encoder.Encode(hostname, dns.TypeNS, r.Txp.RequiresPadding())
 	response, err := r.Txp.RoundTrip(ctx, query)
 	if err != nil {
@@ -146,3 +146,15 @@ func (r *SerialResolver) LookupNS(
 	}
 	return response.DecodeNS()
 }
+
+// LookupRecords implements Resolver.LookupRecords.
+func (r *SerialResolver) LookupRecords(
+	ctx context.Context, hostname string, qtype uint16) ([]*model.DNSRecord, error) {
+	encoder := &DNSEncoderMiekg{EDNS0: ContextDNSEDNS0Options(ctx)}
+	query := encoder.Encode(hostname, qtype, r.Txp.RequiresPadding())
+	response, err := r.Txp.RoundTrip(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return response.DecodeRecords()
+}
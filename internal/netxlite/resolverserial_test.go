@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -45,6 +46,41 @@ func TestSerialResolver(t *testing.T) {
 		}
 	})
 
+	t.Run("with EDNS0 options from the context", func(t *testing.T) {
+		mocked := errors.New("mocked error")
+		var rawQueries [][]byte
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				rawQuery, err := query.Bytes()
+				if err != nil {
+					return nil, err
+				}
+				rawQueries = append(rawQueries, rawQuery)
+				return nil, mocked
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		r := NewUnwrappedSerialResolver(txp)
+		option := &dns.EDNS0_NSID{Code: dns.EDNS0NSID}
+		ctx := ContextWithDNSEDNS0Options(context.Background(), option)
+		if _, err := r.LookupNS(ctx, "example.com"); !errors.Is(err, mocked) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(rawQueries) != 1 {
+			t.Fatal("expected a single query")
+		}
+		msg := &dns.Msg{}
+		if err := msg.Unpack(rawQueries[0]); err != nil {
+			t.Fatal(err)
+		}
+		opt := msg.IsEdns0()
+		if opt == nil || len(opt.Option) != 1 || opt.Option[0].Option() != dns.EDNS0NSID {
+			t.Fatal("expected the NSID option", opt)
+		}
+	})
+
 	t.Run("LookupHost", func(t *testing.T) {
 		t.Run("RoundTrip error", func(t *testing.T) {
 			mocked := errors.New("mocked error")
@@ -291,4 +327,92 @@ func TestSerialResolver(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("LookupRecords", func(t *testing.T) {
+		t.Run("for round-trip error", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			r := &SerialResolver{
+				Txp: &mocks.DNSTransport{
+					MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+						return nil, expected
+					},
+					MockRequiresPadding: func() bool {
+						return false
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "example.com", dns.TypeTXT)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if records != nil {
+				t.Fatal("unexpected result")
+			}
+		})
+
+		t.Run("for decode error", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			r := &SerialResolver{
+				Txp: &mocks.DNSTransport{
+					MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+						response := &mocks.DNSResponse{
+							MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+								return nil, expected
+							},
+						}
+						return response, nil
+					},
+					MockRequiresPadding: func() bool {
+						return false
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "example.com", dns.TypeTXT)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if records != nil {
+				t.Fatal("unexpected result")
+			}
+		})
+
+		t.Run("for success", func(t *testing.T) {
+			expected := []*model.DNSRecord{{
+				Name:  "example.com.",
+				Type:  dns.TypeTXT,
+				TTL:   300,
+				Value: `"v=spf1 -all"`,
+			}}
+			var gotType uint16
+			r := &SerialResolver{
+				Txp: &mocks.DNSTransport{
+					MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+						gotType = query.Type()
+						response := &mocks.DNSResponse{
+							MockDecodeRecords: func() ([]*model.DNSRecord, error) {
+								return expected, nil
+							},
+						}
+						return response, nil
+					},
+					MockRequiresPadding: func() bool {
+						return false
+					},
+				},
+			}
+			ctx := context.Background()
+			records, err := r.LookupRecords(ctx, "example.com", dns.TypeTXT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, records); diff != "" {
+				t.Fatal(diff)
+			}
+			if gotType != dns.TypeTXT {
+				t.Fatal("unexpected query type", gotType)
+			}
+		})
+	})
 }
@@ -256,6 +256,8 @@ func (h *tlsHandshakerConfigurable) Handshake(
 //
 // - DynamicRecordSizingDisabled
 //
+// - EncryptedClientHelloConfigList
+//
 // - InsecureSkipVerify
 //
 // - MaxVersion
@@ -269,13 +271,14 @@ func (h *tlsHandshakerConfigurable) Handshake(
 // - ServerName
 func NewClientConnStdlib(conn net.Conn, config *tls.Config) (*tls.Conn, error) {
 	supportedFields := map[string]bool{
-		"DynamicRecordSizingDisabled": true,
-		"InsecureSkipVerify":          true,
-		"MaxVersion":                  true,
-		"MinVersion":                  true,
-		"NextProtos":                  true,
-		"RootCAs":                     true,
-		"ServerName":                  true,
+		"DynamicRecordSizingDisabled":    true,
+		"EncryptedClientHelloConfigList": true,
+		"InsecureSkipVerify":             true,
+		"MaxVersion":                     true,
+		"MinVersion":                     true,
+		"NextProtos":                     true,
+		"RootCAs":                        true,
+		"ServerName":                     true,
 	}
 	value := reflect.ValueOf(config).Elem()
 	kind := value.Type()
@@ -292,13 +295,14 @@ func NewClientConnStdlib(conn net.Conn, config *tls.Config) (*tls.Conn, error) {
 		return nil, err
 	}
 	ourConfig := &tls.Config{
-		DynamicRecordSizingDisabled: config.DynamicRecordSizingDisabled,
-		InsecureSkipVerify:          config.InsecureSkipVerify,
-		MaxVersion:                  config.MaxVersion,
-		MinVersion:                  config.MinVersion,
-		NextProtos:                  config.NextProtos,
-		RootCAs:                     config.RootCAs,
-		ServerName:                  config.ServerName,
+		DynamicRecordSizingDisabled:    config.DynamicRecordSizingDisabled,
+		EncryptedClientHelloConfigList: config.EncryptedClientHelloConfigList,
+		InsecureSkipVerify:             config.InsecureSkipVerify,
+		MaxVersion:                     config.MaxVersion,
+		MinVersion:                     config.MinVersion,
+		NextProtos:                     config.NextProtos,
+		RootCAs:                        config.RootCAs,
+		ServerName:                     config.ServerName,
 	}
 	return tls.Client(conn, ourConfig), nil
 }
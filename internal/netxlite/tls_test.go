@@ -227,6 +227,72 @@ func TestTLSHandshakerConfigurable(t *testing.T) {
 			}
 		})
 
+		t.Run("with encrypted client hello", func(t *testing.T) {
+			ca := netem.MustNewCA()
+			key := testingx.MustNewECHKey(1, "public.example.com")
+
+			// handshake performs a handshake with a server using the given ECH keys
+			handshake := func(serverKeys []tls.EncryptedClientHelloKey) (model.TLSConn, error) {
+				serverConfig := ca.MustNewServerTLSConfig("www.example.com", "public.example.com")
+				serverConfig.EncryptedClientHelloKeys = serverKeys
+				listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer listener.Close()
+				go func() {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+					_ = conn.(*tls.Conn).Handshake()
+				}()
+				conn, err := net.Dial("tcp", listener.Addr().String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				handshaker := &tlsHandshakerConfigurable{}
+				config := &tls.Config{
+					EncryptedClientHelloConfigList: key.ConfigList,
+					RootCAs:                        ca.DefaultCertPool(),
+					ServerName:                     "www.example.com",
+				}
+				tlsConn, err := handshaker.Handshake(context.Background(), conn, config)
+				if err != nil {
+					conn.Close()
+				}
+				return tlsConn, err
+			}
+
+			t.Run("when the server accepts ECH", func(t *testing.T) {
+				tlsConn, err := handshake([]tls.EncryptedClientHelloKey{key.ServerKey})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer tlsConn.Close()
+				if !tlsConn.ConnectionState().ECHAccepted {
+					t.Fatal("expected ECH to be accepted")
+				}
+			})
+
+			t.Run("when the server rejects ECH", func(t *testing.T) {
+				otherKey := testingx.MustNewECHKey(2, "public.example.com")
+				tlsConn, err := handshake([]tls.EncryptedClientHelloKey{otherKey.ServerKey})
+				var errWrapper *ErrWrapper
+				if !errors.As(err, &errWrapper) || errWrapper.Failure != FailureSSLECHRejected {
+					t.Fatal("unexpected error", err)
+				}
+				var echErr *tls.ECHRejectionError
+				if !errors.As(err, &echErr) || len(echErr.RetryConfigList) <= 0 {
+					t.Fatal("expected to see the retry configs", err)
+				}
+				if tlsConn != nil {
+					t.Fatal("expected nil tlsConn here")
+				}
+			})
+		})
+
 		t.Run("sets default root CA", func(t *testing.T) {
 			expected := errors.New("mocked error")
 			var gotTLSConfig *tls.Config
@@ -79,6 +79,12 @@ func (*traceDefault) OnConnectDone(
 	// nothing
 }
 
+// OnHappyEyeballsDone implements model.Trace.OnHappyEyeballsDone.
+func (*traceDefault) OnHappyEyeballsDone(
+	started time.Time, network, domain, winner string, err error, finished time.Time) {
+	// nothing
+}
+
 // OnTLSHandshakeStart implements model.Trace.OnTLSHandshakeStart.
 func (*traceDefault) OnTLSHandshakeStart(now time.Time, remoteAddr string, config *tls.Config) {
 	// nothing
@@ -98,3 +104,8 @@ func (*traceDefault) OnQUICHandshakeDone(started time.Time, remoteAddr string, q
 	config *tls.Config, err error, finished time.Time) {
 	// nothing
 }
+
+// OnHTTP2Frame implements model.Trace.OnHTTP2Frame.
+func (*traceDefault) OnHTTP2Frame(now time.Time, operation, network, remoteAddr string, frame *model.HTTP2Frame) {
+	// nothing
+}
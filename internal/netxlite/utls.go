@@ -81,6 +81,11 @@ func NewUTLSConn(conn net.Conn, config *tls.Config, cid *utls.ClientHelloID) (*U
 		ServerName:                  config.ServerName,
 	}
 	tlsConn := utls.UClient(conn, uConfig, *cid)
+	if newSpec, found := utlsCustomSpecs[*cid]; found {
+		if err := tlsConn.ApplyPreset(newSpec(config.NextProtos)); err != nil {
+			return nil, err
+		}
+	}
 	oconn := &UTLSConn{
 		UConn:             tlsConn,
 		testableHandshake: nil,
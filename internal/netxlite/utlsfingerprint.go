@@ -0,0 +1,243 @@
+package netxlite
+
+//
+// Catalog of named TLS fingerprints for the uTLS handshaker
+//
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	utls "gitlab.com/yawning/utls.git"
+)
+
+// ErrUnknownUTLSFingerprint indicates that we don't know the requested TLS fingerprint.
+var ErrUnknownUTLSFingerprint = errors.New("netxlite: unknown uTLS fingerprint")
+
+// UTLSHelloAndroidOkHttp is a custom [utls.ClientHelloID] mimicking the ClientHello
+// sent by OkHttp on Android 11, which yawning/utls does not provide.
+var UTLSHelloAndroidOkHttp = utls.ClientHelloID{
+	Client:  utls.HelloCustom.Client,
+	Version: "android-11-okhttp",
+	Seed:    nil,
+}
+
+// UTLSHelloSafari is a custom [utls.ClientHelloID] mimicking the ClientHello
+// sent by Safari 16.0 on macOS, which yawning/utls does not provide.
+var UTLSHelloSafari = utls.ClientHelloID{
+	Client:  utls.HelloCustom.Client,
+	Version: "safari-16.0",
+	Seed:    nil,
+}
+
+// utlsFingerprints maps the name of each fingerprint to the corresponding
+// ClientHelloID. Please, keep this map sorted by name.
+var utlsFingerprints = map[string]*utls.ClientHelloID{
+	"android-okhttp": &UTLSHelloAndroidOkHttp,
+	"chrome":         &utls.HelloChrome_Auto,
+	"firefox":        &utls.HelloFirefox_Auto,
+	"ios":            &utls.HelloIOS_Auto,
+	"randomized":     &utls.HelloRandomized,
+	"safari":         &UTLSHelloSafari,
+}
+
+// UTLSFingerprintNames returns the sorted list of the names of the TLS
+// fingerprints you can use with [NewUTLSClientHelloIDFromName].
+func UTLSFingerprintNames() (out []string) {
+	for name := range utlsFingerprints {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return
+}
+
+// NewUTLSClientHelloIDFromName returns the [utls.ClientHelloID] to pass
+// to [Netx.NewTLSHandshakerUTLS] for using the fingerprint with the given
+// name or [ErrUnknownUTLSFingerprint] if we don't know such a name.
+func NewUTLSClientHelloIDFromName(name string) (*utls.ClientHelloID, error) {
+	id, found := utlsFingerprints[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownUTLSFingerprint, name)
+	}
+	return id, nil
+}
+
+// UTLSFingerprintName returns the name of the fingerprint corresponding to the
+// given ClientHelloID, or an empty string if the ClientHelloID is not in our
+// catalog. We compare pointers, hence this function only works as intended
+// for the values returned by [NewUTLSClientHelloIDFromName] or for pointers
+// to the same ClientHelloID variables we use (e.g., &utls.HelloChrome_Auto).
+func UTLSFingerprintName(id *utls.ClientHelloID) string {
+	for name, entry := range utlsFingerprints {
+		if entry == id {
+			return name
+		}
+	}
+	return ""
+}
+
+// utlsCustomSpecs maps custom ClientHelloIDs to the factory of the corresponding
+// spec. We need a factory because utls modifies the spec's extensions, so
+// we cannot share the same spec between distinct connections. The factory
+// takes in input the config's NextProtos to build the ALPN extension.
+var utlsCustomSpecs = map[utls.ClientHelloID]func(nextProtos []string) *utls.ClientHelloSpec{
+	UTLSHelloAndroidOkHttp: newUTLSAndroidOkHttpSpec,
+	UTLSHelloSafari:        newUTLSSafariSpec,
+}
+
+// newUTLSALPNExtension creates the ALPN extension for the given NextProtos
+// using "h2" and "http/1.1" when the list is empty, like utls does when
+// building the spec of the randomized fingerprint.
+func newUTLSALPNExtension(nextProtos []string) *utls.ALPNExtension {
+	if len(nextProtos) <= 0 {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
+	return &utls.ALPNExtension{AlpnProtocols: nextProtos}
+}
+
+// newUTLSAndroidOkHttpSpec creates the spec for [UTLSHelloAndroidOkHttp].
+func newUTLSAndroidOkHttpSpec(nextProtos []string) *utls.ClientHelloSpec {
+	return &utls.ClientHelloSpec{
+		CipherSuites: []uint16{
+			utls.TLS_AES_128_GCM_SHA256,
+			utls.TLS_AES_256_GCM_SHA384,
+			utls.TLS_CHACHA20_POLY1305_SHA256,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			utls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			utls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			utls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			utls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			utls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		},
+		CompressionMethods: []uint8{
+			0, // no compression
+		},
+		Extensions: []utls.TLSExtension{
+			&utls.SNIExtension{},
+			&utls.UtlsExtendedMasterSecretExtension{},
+			&utls.RenegotiationInfoExtension{Renegotiation: utls.RenegotiateOnceAsClient},
+			&utls.SupportedCurvesExtension{Curves: []utls.CurveID{
+				utls.X25519,
+				utls.CurveP256,
+				utls.CurveP384,
+			}},
+			&utls.SupportedPointsExtension{SupportedPoints: []uint8{
+				0, // uncompressed
+			}},
+			&utls.SessionTicketExtension{},
+			newUTLSALPNExtension(nextProtos),
+			&utls.StatusRequestExtension{},
+			&utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{
+				utls.ECDSAWithP256AndSHA256,
+				utls.PSSWithSHA256,
+				utls.PKCS1WithSHA256,
+				utls.ECDSAWithP384AndSHA384,
+				utls.PSSWithSHA384,
+				utls.PKCS1WithSHA384,
+				utls.PSSWithSHA512,
+				utls.PKCS1WithSHA512,
+				utls.PKCS1WithSHA1,
+			}},
+			&utls.KeyShareExtension{KeyShares: []utls.KeyShare{
+				{Group: utls.X25519},
+			}},
+			&utls.PSKKeyExchangeModesExtension{Modes: []uint8{
+				1, // psk_dhe_ke
+			}},
+			&utls.SupportedVersionsExtension{Versions: []uint16{
+				utls.VersionTLS13,
+				utls.VersionTLS12,
+			}},
+		},
+	}
+}
+
+// newUTLSSafariSpec creates the spec for [UTLSHelloSafari].
+func newUTLSSafariSpec(nextProtos []string) *utls.ClientHelloSpec {
+	return &utls.ClientHelloSpec{
+		CipherSuites: []uint16{
+			utls.GREASE_PLACEHOLDER,
+			utls.TLS_AES_128_GCM_SHA256,
+			utls.TLS_AES_256_GCM_SHA384,
+			utls.TLS_CHACHA20_POLY1305_SHA256,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			utls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			utls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			utls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			utls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			utls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			0xc008, // TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA
+			utls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+			utls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		},
+		CompressionMethods: []uint8{
+			0, // no compression
+		},
+		Extensions: []utls.TLSExtension{
+			&utls.UtlsGREASEExtension{},
+			&utls.SNIExtension{},
+			&utls.UtlsExtendedMasterSecretExtension{},
+			&utls.RenegotiationInfoExtension{Renegotiation: utls.RenegotiateOnceAsClient},
+			&utls.SupportedCurvesExtension{Curves: []utls.CurveID{
+				utls.CurveID(utls.GREASE_PLACEHOLDER),
+				utls.X25519,
+				utls.CurveP256,
+				utls.CurveP384,
+				utls.CurveP521,
+			}},
+			&utls.SupportedPointsExtension{SupportedPoints: []uint8{
+				0, // uncompressed
+			}},
+			newUTLSALPNExtension(nextProtos),
+			&utls.StatusRequestExtension{},
+			&utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{
+				utls.ECDSAWithP256AndSHA256,
+				utls.PSSWithSHA256,
+				utls.PKCS1WithSHA256,
+				utls.ECDSAWithP384AndSHA384,
+				utls.ECDSAWithSHA1,
+				utls.PSSWithSHA384,
+				utls.PSSWithSHA384,
+				utls.PKCS1WithSHA384,
+				utls.PSSWithSHA512,
+				utls.PKCS1WithSHA512,
+				utls.PKCS1WithSHA1,
+			}},
+			&utls.SCTExtension{},
+			&utls.KeyShareExtension{KeyShares: []utls.KeyShare{
+				{Group: utls.CurveID(utls.GREASE_PLACEHOLDER), Data: []byte{0}},
+				{Group: utls.X25519},
+			}},
+			&utls.PSKKeyExchangeModesExtension{Modes: []uint8{
+				1, // psk_dhe_ke
+			}},
+			&utls.SupportedVersionsExtension{Versions: []uint16{
+				utls.GREASE_PLACEHOLDER,
+				utls.VersionTLS13,
+				utls.VersionTLS12,
+				utls.VersionTLS11,
+				utls.VersionTLS10,
+			}},
+			&utls.CompressCertificateExtension{Algorithms: []utls.CertCompressionAlgo{
+				utls.CertCompressionZlib,
+			}},
+			&utls.UtlsGREASEExtension{},
+			&utls.UtlsPaddingExtension{GetPaddingLen: utls.BoringPaddingStyle},
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package netxlite
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+	utls "gitlab.com/yawning/utls.git"
+)
+
+func TestUTLSFingerprintNames(t *testing.T) {
+	expect := []string{"android-okhttp", "chrome", "firefox", "ios", "randomized", "safari"}
+	if diff := cmp.Diff(expect, UTLSFingerprintNames()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestNewUTLSClientHelloIDFromName(t *testing.T) {
+	t.Run("with a known name", func(t *testing.T) {
+		id, err := NewUTLSClientHelloIDFromName("chrome")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != &utls.HelloChrome_Auto {
+			t.Fatal("unexpected ClientHelloID", id)
+		}
+	})
+
+	t.Run("with an unknown name", func(t *testing.T) {
+		id, err := NewUTLSClientHelloIDFromName("netscape")
+		if !errors.Is(err, ErrUnknownUTLSFingerprint) {
+			t.Fatal("unexpected error", err)
+		}
+		if id != nil {
+			t.Fatal("expected nil ClientHelloID")
+		}
+	})
+}
+
+func TestUTLSFingerprintName(t *testing.T) {
+	t.Run("we can map back each name", func(t *testing.T) {
+		for _, name := range UTLSFingerprintNames() {
+			id, err := NewUTLSClientHelloIDFromName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := UTLSFingerprintName(id); got != name {
+				t.Fatal("expected", name, "got", got)
+			}
+		}
+	})
+
+	t.Run("with a ClientHelloID that is not in the catalog", func(t *testing.T) {
+		if got := UTLSFingerprintName(&utls.HelloGolang); got != "" {
+			t.Fatal("expected empty string, got", got)
+		}
+	})
+}
+
+func TestUTLSFingerprintsHandshake(t *testing.T) {
+	ca := netem.MustNewCA()
+	cert := ca.MustNewTLSCertificate("www.example.com")
+	server := testingx.MustNewTLSServer(testingx.TLSHandlerHandshakeAndWriteText(cert, testingx.HTTPBlockpage451))
+	defer server.Close()
+
+	for _, name := range UTLSFingerprintNames() {
+		t.Run(name, func(t *testing.T) {
+			id, err := NewUTLSClientHelloIDFromName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := net.Dial("tcp", server.Endpoint())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			netx := &Netx{}
+			thx := netx.NewTLSHandshakerUTLS(model.DiscardLogger, id)
+			config := &tls.Config{
+				RootCAs:    ca.DefaultCertPool(),
+				ServerName: "www.example.com",
+			}
+			tlsConn, err := thx.Handshake(context.Background(), conn, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tlsConn.Close()
+			if !tlsConn.ConnectionState().HandshakeComplete {
+				t.Fatal("expected the handshake to be complete")
+			}
+		})
+	}
+}
+
+func TestUTLSCustomSpecsALPN(t *testing.T) {
+	// findALPN returns the ALPN protocols inside the given spec
+	findALPN := func(spec *utls.ClientHelloSpec) []string {
+		for _, ext := range spec.Extensions {
+			if alpn, ok := ext.(*utls.ALPNExtension); ok {
+				return alpn.AlpnProtocols
+			}
+		}
+		t.Fatal("the spec does not contain the ALPN extension")
+		return nil
+	}
+
+	for id, newSpec := range utlsCustomSpecs {
+		t.Run(id.Version, func(t *testing.T) {
+			t.Run("we use the given NextProtos", func(t *testing.T) {
+				expect := []string{"http/1.1"}
+				if diff := cmp.Diff(expect, findALPN(newSpec(expect))); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+
+			t.Run("we use h2 and http/1.1 with empty NextProtos", func(t *testing.T) {
+				expect := []string{"h2", "http/1.1"}
+				if diff := cmp.Diff(expect, findALPN(newSpec(nil))); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package oohelperd
+
+//
+// Caching and coalescing of measurements
+//
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+const (
+	// handlerCacheDefaultMaxEntries is the default maximum number of cached responses.
+	handlerCacheDefaultMaxEntries = 4096
+
+	// handlerCacheDefaultTTL is the default amount of time for which we cache a response.
+	handlerCacheDefaultTTL = time.Minute
+)
+
+// handlerCache caches the responses produced by [measure] and coalesces concurrent
+// identical requests such that we only perform a single measurement for them.
+//
+// We only cache successful responses. Requests coalesced with a failed measurement
+// see the same failure, but subsequent requests will trigger a new measurement.
+//
+// The zero value is invalid; construct using [newHandlerCache].
+type handlerCache struct {
+	// entries maps a key to the corresponding element in fifo.
+	entries map[string]*list.Element
+
+	// fifo contains *handlerCacheEntry sorted by insertion time, such that the
+	// oldest entry, which is also the first to expire, is at the front.
+	fifo *list.List
+
+	// inflight contains the measurements currently in progress.
+	inflight map[string]*handlerCacheCall
+
+	// maxEntries is the maximum number of entries.
+	maxEntries int
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// timeNow is the function to get the current time.
+	timeNow func() time.Time
+
+	// ttl is the amount of time for which we cache a response.
+	ttl time.Duration
+}
+
+// handlerCacheEntry is an entry inside the [handlerCache].
+type handlerCacheEntry struct {
+	expires time.Time
+	key     string
+	resp    *ctrlResponse
+}
+
+// handlerCacheCall is a measurement in progress.
+type handlerCacheCall struct {
+	done chan any
+	err  error
+	resp *ctrlResponse
+}
+
+// newHandlerCache creates a new [handlerCache] containing at most the given
+// number of entries and caching each response for the given TTL.
+func newHandlerCache(maxEntries int, ttl time.Duration) *handlerCache {
+	runtimex.Assert(maxEntries > 0, "oohelperd: maxEntries must be positive")
+	return &handlerCache{
+		entries:    map[string]*list.Element{},
+		fifo:       list.New(),
+		inflight:   map[string]*handlerCacheCall{},
+		maxEntries: maxEntries,
+		mu:         sync.Mutex{},
+		timeNow:    time.Now,
+		ttl:        ttl,
+	}
+}
+
+// handlerCacheKeyInfo is the normalized [ctrlRequest] we use to compute the cache key.
+type handlerCacheKeyInfo struct {
+	HTTPRequest        string              `json:"http_request"`
+	HTTPRequestHeaders map[string][]string `json:"http_request_headers"`
+	TCPConnect         []string            `json:"tcp_connect"`
+	XQUICEnabled       bool                `json:"x_quic_enabled"`
+}
+
+// handlerCacheKey returns the cache key for the given request. Requests only differing
+// by the case of the header names or by the order and duplicates of the endpoints to
+// measure map to the same key, since they produce equivalent measurements.
+func handlerCacheKey(creq *ctrlRequest) string {
+	info := &handlerCacheKeyInfo{
+		HTTPRequest:        creq.HTTPRequest,
+		HTTPRequestHeaders: map[string][]string{},
+		TCPConnect:         slices.Compact(slices.Sorted(slices.Values(creq.TCPConnect))),
+		XQUICEnabled:       creq.XQUICEnabled,
+	}
+	for key, values := range creq.HTTPRequestHeaders {
+		key = http.CanonicalHeaderKey(key)
+		info.HTTPRequestHeaders[key] = append(info.HTTPRequestHeaders[key], values...)
+	}
+	// Note: json.Marshal sorts the map keys, so the serialization is stable.
+	data, err := json.Marshal(info)
+	runtimex.PanicOnError(err, "json.Marshal failed")
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// Do returns the cached response for the given request, if any, otherwise it waits
+// for an identical measurement in progress, if any, otherwise it calls fx to perform
+// the measurement. We run fx in a background goroutine using a context that is not
+// canceled when the given context is canceled, since other requests may be waiting for
+// its result. We return early with the context error when the given context is done.
+func (c *handlerCache) Do(ctx context.Context, creq *ctrlRequest,
+	fx func(ctx context.Context) (*ctrlResponse, error)) (*ctrlResponse, error) {
+	key := handlerCacheKey(creq)
+
+	c.mu.Lock()
+	if resp := c.getLocked(key); resp != nil {
+		c.mu.Unlock()
+		metricCacheRequestsCount.WithLabelValues("hit").Inc()
+		return resp, nil
+	}
+	call, found := c.inflight[key]
+	if !found {
+		call = &handlerCacheCall{done: make(chan any)}
+		c.inflight[key] = call
+		go c.run(context.WithoutCancel(ctx), key, call, fx)
+	}
+	c.mu.Unlock()
+
+	if found {
+		metricCacheRequestsCount.WithLabelValues("coalesced").Inc()
+	} else {
+		metricCacheRequestsCount.WithLabelValues("miss").Inc()
+	}
+
+	select {
+	case <-call.done:
+		return call.resp, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run performs the measurement and stores its result.
+func (c *handlerCache) run(ctx context.Context, key string,
+	call *handlerCacheCall, fx func(ctx context.Context) (*ctrlResponse, error)) {
+	defer close(call.done)
+	call.resp, call.err = fx(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		c.putLocked(key, call.resp)
+	}
+}
+
+// getLocked returns the cached response for the given key or nil. This
+// method assumes that the caller is holding the mutex.
+func (c *handlerCache) getLocked(key string) *ctrlResponse {
+	c.expireLocked()
+	elem, found := c.entries[key]
+	if !found {
+		return nil
+	}
+	return elem.Value.(*handlerCacheEntry).resp
+}
+
+// putLocked caches the response for the given key. This method
+// assumes that the caller is holding the mutex.
+func (c *handlerCache) putLocked(key string, resp *ctrlResponse) {
+	if elem, found := c.entries[key]; found {
+		c.removeLocked(elem)
+	}
+	c.expireLocked()
+	for c.fifo.Len() >= c.maxEntries {
+		c.removeLocked(c.fifo.Front())
+	}
+	entry := &handlerCacheEntry{
+		expires: c.timeNow().Add(c.ttl),
+		key:     key,
+		resp:    resp,
+	}
+	c.entries[key] = c.fifo.PushBack(entry)
+	metricCacheEntries.Set(float64(c.fifo.Len()))
+}
+
+// expireLocked removes the expired entries. This method assumes
+// that the caller is holding the mutex.
+func (c *handlerCache) expireLocked() {
+	now := c.timeNow()
+	for elem := c.fifo.Front(); elem != nil; elem = c.fifo.Front() {
+		if now.Before(elem.Value.(*handlerCacheEntry).expires) {
+			break
+		}
+		c.removeLocked(elem)
+	}
+	metricCacheEntries.Set(float64(c.fifo.Len()))
+}
+
+// removeLocked removes the given element. This method assumes that
+// the caller is holding the mutex.
+func (c *handlerCache) removeLocked(elem *list.Element) {
+	entry := c.fifo.Remove(elem).(*handlerCacheEntry)
+	delete(c.entries, entry.key)
+}
@@ -0,0 +1,244 @@
+package oohelperd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestHandlerCacheKey(t *testing.T) {
+	base := &ctrlRequest{
+		HTTPRequest: "https://www.example.com/",
+		HTTPRequestHeaders: map[string][]string{
+			"Accept":     {"*/*"},
+			"User-Agent": {"Mozilla/5.0"},
+		},
+		TCPConnect:   []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		XQUICEnabled: false,
+	}
+
+	t.Run("equivalent requests map to the same key", func(t *testing.T) {
+		other := &ctrlRequest{
+			HTTPRequest: "https://www.example.com/",
+			HTTPRequestHeaders: map[string][]string{
+				"accept":     {"*/*"},
+				"user-agent": {"Mozilla/5.0"},
+			},
+			TCPConnect: []string{
+				"[2606:2800:220:1:248:1893:25c8:1946]:443",
+				"93.184.216.34:443",
+				"93.184.216.34:443",
+			},
+			XQUICEnabled: false,
+		}
+		if handlerCacheKey(base) != handlerCacheKey(other) {
+			t.Fatal("expected the same key")
+		}
+	})
+
+	t.Run("different requests map to different keys", func(t *testing.T) {
+		mutators := map[string]func(creq *ctrlRequest){
+			"URL": func(creq *ctrlRequest) {
+				creq.HTTPRequest = "http://www.example.com/"
+			},
+			"headers": func(creq *ctrlRequest) {
+				creq.HTTPRequestHeaders = map[string][]string{"Accept": {"*/*"}}
+			},
+			"endpoints": func(creq *ctrlRequest) {
+				creq.TCPConnect = []string{"93.184.216.34:443"}
+			},
+			"QUIC": func(creq *ctrlRequest) {
+				creq.XQUICEnabled = true
+			},
+		}
+		for name, mutate := range mutators {
+			other := *base
+			mutate(&other)
+			if handlerCacheKey(base) == handlerCacheKey(&other) {
+				t.Fatal(name, "expected different keys")
+			}
+		}
+	})
+
+	t.Run("we do not modify the request", func(t *testing.T) {
+		creq := &ctrlRequest{TCPConnect: []string{"b", "a", "a"}}
+		_ = handlerCacheKey(creq)
+		if len(creq.TCPConnect) != 3 || creq.TCPConnect[0] != "b" {
+			t.Fatal("modified the request", creq.TCPConnect)
+		}
+	})
+}
+
+func TestHandlerCache(t *testing.T) {
+	// newMeasure returns a measure function that counts its invocations.
+	newMeasure := func(count *atomic.Int64, err error) func(ctx context.Context) (*ctrlResponse, error) {
+		return func(ctx context.Context) (*ctrlResponse, error) {
+			count.Add(1)
+			if err != nil {
+				return nil, err
+			}
+			return &ctrlResponse{}, nil
+		}
+	}
+
+	// newRequest returns a new request for the given URL.
+	newRequest := func(URL string) *ctrlRequest {
+		return &ctrlRequest{HTTPRequest: URL}
+	}
+
+	t.Run("we cache successful responses until they expire", func(t *testing.T) {
+		now := time.Now()
+		cache := newHandlerCache(10, time.Minute)
+		cache.timeNow = func() time.Time { return now }
+		var count atomic.Int64
+		creq := newRequest("https://www.example.com/")
+
+		resp1, err := cache.Do(context.Background(), creq, newMeasure(&count, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp2, err := cache.Do(context.Background(), creq, newMeasure(&count, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp1 != resp2 || count.Load() != 1 {
+			t.Fatal("expected a cached response")
+		}
+
+		now = now.Add(time.Minute)
+		resp3, err := cache.Do(context.Background(), creq, newMeasure(&count, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp3 == resp1 || count.Load() != 2 {
+			t.Fatal("expected a new measurement")
+		}
+	})
+
+	t.Run("we do not cache failures", func(t *testing.T) {
+		cache := newHandlerCache(10, time.Minute)
+		expected := errors.New("mocked error")
+		var count atomic.Int64
+		creq := newRequest("https://www.example.com/")
+		for idx := 0; idx < 2; idx++ {
+			resp, err := cache.Do(context.Background(), creq, newMeasure(&count, expected))
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected error", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response")
+			}
+		}
+		if count.Load() != 2 || len(cache.entries) != 0 {
+			t.Fatal("expected no caching")
+		}
+	})
+
+	t.Run("we evict the oldest entries when full", func(t *testing.T) {
+		cache := newHandlerCache(2, time.Minute)
+		var count atomic.Int64
+		for _, URL := range []string{"https://a.com/", "https://b.com/", "https://c.com/"} {
+			if _, err := cache.Do(context.Background(), newRequest(URL), newMeasure(&count, nil)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if cache.fifo.Len() != 2 || len(cache.entries) != 2 {
+			t.Fatal("unexpected number of entries")
+		}
+		if _, found := cache.entries[handlerCacheKey(newRequest("https://a.com/"))]; found {
+			t.Fatal("expected the oldest entry to be evicted")
+		}
+		if _, found := cache.entries[handlerCacheKey(newRequest("https://c.com/"))]; !found {
+			t.Fatal("expected the newest entry to be cached")
+		}
+	})
+
+	t.Run("we coalesce concurrent identical requests", func(t *testing.T) {
+		cache := newHandlerCache(10, time.Minute)
+		var count atomic.Int64
+		unblock := make(chan any)
+		measure := func(ctx context.Context) (*ctrlResponse, error) {
+			count.Add(1)
+			<-unblock
+			return &ctrlResponse{}, nil
+		}
+		creq := newRequest("https://www.example.com/")
+
+		const concurrency = 8
+		wg := &sync.WaitGroup{}
+		responses := make(chan *ctrlResponse, concurrency)
+		for idx := 0; idx < concurrency; idx++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := cache.Do(context.Background(), creq, measure)
+				if err != nil {
+					panic(err)
+				}
+				responses <- resp
+			}()
+		}
+
+		// wait for the measurement to start before unblocking it
+		for count.Load() < 1 {
+			time.Sleep(time.Millisecond)
+		}
+		close(unblock)
+		wg.Wait()
+		close(responses)
+
+		first := <-responses
+		for resp := range responses {
+			if resp != first {
+				t.Fatal("expected the same response")
+			}
+		}
+		if count.Load() != 1 {
+			t.Fatal("expected a single measurement, got", count.Load())
+		}
+	})
+
+	t.Run("we return early when the context is done", func(t *testing.T) {
+		cache := newHandlerCache(10, time.Minute)
+		unblock := make(chan any)
+		measured := make(chan context.Context, 1)
+		measure := func(ctx context.Context) (*ctrlResponse, error) {
+			<-unblock
+			measured <- ctx
+			return &ctrlResponse{}, nil
+		}
+		creq := newRequest("https://www.example.com/")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		resp, err := cache.Do(ctx, creq, measure)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+		if resp != nil {
+			t.Fatal("expected nil response")
+		}
+
+		// the measurement should continue with a context that is not canceled
+		close(unblock)
+		if err := (<-measured).Err(); err != nil {
+			t.Fatal("unexpected context error", err)
+		}
+
+		// and we should either wait for it or use its cached result
+		resp, err = cache.Do(context.Background(), creq, func(ctx context.Context) (*model.THResponse, error) {
+			panic("should not be called")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil {
+			t.Fatal("expected non-nil response")
+		}
+	})
+}
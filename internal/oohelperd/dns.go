@@ -85,6 +85,88 @@ func dnsDo(ctx context.Context, config *dnsConfig) {
 	}
 }
 
+// dnsResolversConfig contains configuration for the [dnsResolversDo] function.
+type dnsResolversConfig struct {
+	// Domain is the MANDATORY domain to resolve.
+	Domain string
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// NewResolverFromURL is the MANDATORY factory to create a new resolver from its URL.
+	NewResolverFromURL func(logger model.Logger, URL string) (model.Resolver, error)
+
+	// Out is the MANDATORY channel where we publish the results.
+	Out chan []model.THDNSResolverResult
+
+	// URLs contains the MANDATORY URLs of the resolvers to use.
+	URLs []string
+
+	// Wg is MANDATORY and allows [dnsResolversDo] to synchronize with the caller.
+	Wg *sync.WaitGroup
+}
+
+// dnsResolversDo resolves the domain using each of the resolvers specified by the
+// given [dnsResolversConfig] in parallel and publishes all the results, which are
+// sorted like the URLs of the resolvers, using the Out channel.
+//
+// Unlike [dnsDo], we do not map failures to the strings used by the legacy TH
+// because there is no legacy TH behavior to emulate for this functionality.
+func dnsResolversDo(ctx context.Context, config *dnsResolversConfig) {
+	// make sure the caller knows when we're done
+	defer config.Wg.Done()
+
+	// perform all the lookups in parallel
+	results := make([]model.THDNSResolverResult, len(config.URLs))
+	wg := &sync.WaitGroup{}
+	for idx, URL := range config.URLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[idx] = dnsResolverDo(ctx, config, URL)
+		}()
+	}
+	wg.Wait()
+
+	// emit the results
+	config.Out <- results
+}
+
+// dnsResolverDo resolves the domain using the resolver with the given URL.
+func dnsResolverDo(ctx context.Context, config *dnsResolversConfig, URL string) model.THDNSResolverResult {
+	// make sure this micro-measurement is bounded in time
+	const timeout = 4 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// create a temporary resolver for this micro-measurement
+	reso, err := config.NewResolverFromURL(config.Logger, URL)
+	if err != nil {
+		return model.THDNSResolverResult{
+			Resolver: URL,
+			Failure:  newfailure(err),
+			Addrs:    []string{},
+		}
+	}
+	defer reso.CloseIdleConnections()
+
+	// perform and log the actual DNS lookup
+	ol := logx.NewOperationLogger(config.Logger, "DNSLookup %s using %s", config.Domain, URL)
+	addrs, err := reso.LookupHost(ctx, config.Domain)
+	ol.Stop(err)
+
+	// make sure we return an empty slice on failure for consistency with [dnsDo]
+	if addrs == nil {
+		addrs = []string{}
+	}
+
+	return model.THDNSResolverResult{
+		Resolver: URL,
+		Failure:  newfailure(err),
+		Addrs:    addrs,
+	}
+}
+
 // dnsMapFailure attempts to map netxlite failures to the strings
 // used by the original OONI test helper.
 //
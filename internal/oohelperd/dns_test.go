@@ -200,3 +200,62 @@ func TestDNSDo(t *testing.T) {
 		})
 	}
 }
+
+func TestDNSResolversDo(t *testing.T) {
+	// newResolverFromURL is a factory returning mocked resolvers depending on the URL.
+	newResolverFromURL := func(logger model.Logger, URL string) (model.Resolver, error) {
+		switch URL {
+		case "https://dns.google/dns-query":
+			return &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return []string{"8.8.8.8", "8.8.4.4"}, nil
+				},
+				MockCloseIdleConnections: func() {
+					// nothing
+				},
+			}, nil
+		case "udp://9.9.9.9":
+			return &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return nil, errors.New(netxlite.DNSNoSuchHostSuffix)
+				},
+				MockCloseIdleConnections: func() {
+					// nothing
+				},
+			}, nil
+		default:
+			return nil, ErrUnsupportedResolverURL
+		}
+	}
+
+	urls := []string{"https://dns.google/dns-query", "udp://9.9.9.9", "ftp://1.1.1.1"}
+	out := make(chan []model.THDNSResolverResult, 1)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	dnsResolversDo(context.Background(), &dnsResolversConfig{
+		Domain:             "dns.google",
+		Logger:             model.DiscardLogger,
+		NewResolverFromURL: newResolverFromURL,
+		Out:                out,
+		URLs:               urls,
+		Wg:                 wg,
+	})
+	wg.Wait()
+
+	expect := []model.THDNSResolverResult{{
+		Resolver: "https://dns.google/dns-query",
+		Failure:  nil,
+		Addrs:    []string{"8.8.8.8", "8.8.4.4"},
+	}, {
+		Resolver: "udp://9.9.9.9",
+		Failure:  stringPointerForString(netxlite.FailureDNSNXDOMAINError),
+		Addrs:    []string{},
+	}, {
+		Resolver: "ftp://1.1.1.1",
+		Failure:  stringPointerForString("unknown_failure: " + ErrUnsupportedResolverURL.Error()),
+		Addrs:    []string{},
+	}}
+	if diff := cmp.Diff(expect, <-out); diff != "" {
+		t.Fatal(diff)
+	}
+}
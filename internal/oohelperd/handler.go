@@ -7,10 +7,13 @@ package oohelperd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"os"
 	"strings"
 	"sync/atomic"
@@ -32,12 +35,21 @@ const maxAcceptableBodySize = 1 << 24
 //
 // The zero value is invalid; construct using [NewHandler].
 type Handler struct {
+	// DNSResolvers OPTIONALLY contains the URLs of additional resolvers that we use
+	// to resolve the domain, such that clients can cross check DNS results using
+	// several upstreams (see [newResolverFromURL] for the supported URLs).
+	DNSResolvers []string
+
 	// EnableQUIC OPTIONALLY enables QUIC.
 	EnableQUIC bool
 
 	// baseLogger is the MANDATORY logger to use.
 	baseLogger model.Logger
 
+	// cache is the OPTIONAL cache for responses. When nil, we perform
+	// a new measurement for each incoming request.
+	cache *handlerCache
+
 	// countRequests is the MANDATORY count of the number of
 	// requests that are currently in flight.
 	countRequests *atomic.Int64
@@ -67,6 +79,9 @@ type Handler struct {
 	// newResolver is the MANDATORY factory for creating a new resolver.
 	newResolver func(model.Logger) model.Resolver
 
+	// newResolverFromURL is the MANDATORY factory for creating a new resolver from its URL.
+	newResolverFromURL func(logger model.Logger, URL string) (model.Resolver, error)
+
 	// newTLSHandshaker is the MANDATORY factory for creating a new TLS handshaker.
 	newTLSHandshaker func(model.Logger) model.TLSHandshaker
 }
@@ -76,11 +91,25 @@ var _ http.Handler = &Handler{}
 // enableQUIC allows to control whether to enable QUIC by using environment variables.
 var enableQUIC = (os.Getenv("OOHELPERD_ENABLE_QUIC") == "1")
 
+// dnsResolvers allows to configure additional resolvers by using environment variables, which
+// should contain a comma separated list of resolver URLs (e.g., "https://dns.quad9.net/dns-query,
+// dot://1.1.1.1:853,udp://9.9.9.9:53").
+var dnsResolvers = handlerParseDNSResolvers(os.Getenv("OOHELPERD_DNS_RESOLVERS"))
+
+// disableCache allows to disable caching responses by using environment variables.
+var disableCache = (os.Getenv("OOHELPERD_DISABLE_CACHE") == "1")
+
 // NewHandler constructs the [handler].
 func NewHandler(logger model.Logger, netx *netxlite.Netx) *Handler {
+	var cache *handlerCache
+	if !disableCache {
+		cache = newHandlerCache(handlerCacheDefaultMaxEntries, handlerCacheDefaultTTL)
+	}
 	return &Handler{
+		DNSResolvers:      dnsResolvers,
 		EnableQUIC:        enableQUIC,
 		baseLogger:        logger,
+		cache:             cache,
 		countRequests:     &atomic.Int64{},
 		indexer:           &atomic.Int64{},
 		maxAcceptableBody: maxAcceptableBodySize,
@@ -117,6 +146,10 @@ func NewHandler(logger model.Logger, netx *netxlite.Netx) *Handler {
 			return newResolver(logger, netx)
 		},
 
+		newResolverFromURL: func(logger model.Logger, URL string) (model.Resolver, error) {
+			return newResolverFromURL(logger, netx, URL)
+		},
+
 		newTLSHandshaker: func(logger model.Logger) model.TLSHandshaker {
 			return netx.NewTLSHandshakerStdlib(logger)
 		},
@@ -200,13 +233,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// measure the given input
-	started := time.Now()
-	cresp, err := h.measure(req.Context(), h, &creq)
-	elapsed := time.Since(started)
-
-	// track the time required to produce a response
-	metricWCTaskDurationSeconds.Observe(elapsed.Seconds())
+	// measure the given input or use the cache
+	cresp, err := h.measureOrUseCache(req.Context(), &creq)
 
 	// handle the case of fundamental failure
 	if err != nil {
@@ -226,6 +254,27 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	_, _ = w.Write(data)
 }
 
+// measureOrUseCache measures the given input unless we have a cached response or
+// there is already an identical measurement in progress whose result we can use.
+func (h *Handler) measureOrUseCache(ctx context.Context, creq *ctrlRequest) (*ctrlResponse, error) {
+	if h.cache == nil {
+		return h.measureAndTrackDuration(ctx, creq)
+	}
+	return h.cache.Do(ctx, creq, func(ctx context.Context) (*ctrlResponse, error) {
+		return h.measureAndTrackDuration(ctx, creq)
+	})
+}
+
+// measureAndTrackDuration measures the given input and tracks the time required to
+// do that, such that cached responses do not affect the measurement duration metric.
+func (h *Handler) measureAndTrackDuration(ctx context.Context, creq *ctrlRequest) (*ctrlResponse, error) {
+	started := time.Now()
+	cresp, err := h.measure(ctx, h, creq)
+	elapsed := time.Since(started)
+	metricWCTaskDurationSeconds.Observe(elapsed.Seconds())
+	return cresp, err
+}
+
 // newResolver creates a new [model.Resolver] suitable for serving
 // requests coming from ooniprobe clients.
 func newResolver(logger model.Logger, netx *netxlite.Netx) model.Resolver {
@@ -236,6 +285,57 @@ func newResolver(logger model.Logger, netx *netxlite.Netx) model.Resolver {
 	return resolver
 }
 
+// ErrUnsupportedResolverURL indicates that we do not support the given resolver URL.
+var ErrUnsupportedResolverURL = errors.New("oohelperd: unsupported resolver URL")
+
+// newResolverFromURL creates a new [model.Resolver] using the given URL, whose scheme must
+// be "https" for DNS-over-HTTPS (e.g., "https://dns.google/dns-query"), "dot" for DNS-over-TLS
+// (e.g., "dot://1.1.1.1:853"), or "udp" for DNS-over-UDP (e.g., "udp://8.8.8.8:53"). When the
+// URL does not contain a port, we use the default port for the protocol.
+func newResolverFromURL(logger model.Logger, netx *netxlite.Netx, URL string) (model.Resolver, error) {
+	parsed, err := url.Parse(URL)
+	if err != nil {
+		return nil, err
+	}
+	switch parsed.Scheme {
+	case "https":
+		return netx.NewParallelDNSOverHTTPSResolver(logger, URL), nil
+
+	case "dot":
+		address := handlerEndpointWithDefaultPort(parsed.Host, "853")
+		dialer := netx.NewDialerWithResolver(logger, netx.NewStdlibResolver(logger))
+		tlsDialer := netxlite.NewTLSDialer(dialer, netx.NewTLSHandshakerStdlib(logger))
+		txp := netxlite.NewUnwrappedDNSOverTLSTransport(tlsDialer.DialTLSContext, address)
+		return netxlite.WrapResolver(logger, netxlite.NewUnwrappedParallelResolver(txp)), nil
+
+	case "udp":
+		address := handlerEndpointWithDefaultPort(parsed.Host, "53")
+		return netx.NewParallelUDPResolver(logger, netx.NewDialerWithoutResolver(logger), address), nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedResolverURL, URL)
+	}
+}
+
+// handlerEndpointWithDefaultPort returns an endpoint using the given host and port
+// unless the given host already is an endpoint, in which case we return it.
+func handlerEndpointWithDefaultPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+// handlerParseDNSResolvers parses a comma separated list of resolver URLs.
+func handlerParseDNSResolvers(value string) (out []string) {
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+	return
+}
+
 // newCookieJar is the factory for constructing a new cookier jar.
 func newCookieJar() *cookiejar.Jar {
 	// Implementation note: the [cookiejar.New] function always returns a
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -269,3 +270,125 @@ func TestNewHandlerEnableQUIC(t *testing.T) {
 		t.Fatal("expected to see false here (is the the environment variable OOHELPERD_ENABLE_QUIC set?!)")
 	}
 }
+
+func TestHandlerUsesCache(t *testing.T) {
+	// serve performs a round trip with the handler and returns the status code.
+	serve := func(handler *Handler) int {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:8080/", strings.NewReader(simpleRequestForHandler))
+		if err != nil {
+			t.Fatal(err)
+		}
+		statusCode := 200
+		rw := &mocks.HTTPResponseWriter{
+			MockHeader: func() http.Header {
+				return http.Header{}
+			},
+			MockWrite: func(b []byte) (int, error) {
+				return len(b), nil
+			},
+			MockWriteHeader: func(code int) {
+				statusCode = code
+			},
+		}
+		handler.ServeHTTP(rw, req)
+		return statusCode
+	}
+
+	t.Run("with cache", func(t *testing.T) {
+		var count int
+		handler := NewHandler(log.Log, &netxlite.Netx{})
+		if handler.cache == nil {
+			t.Fatal("expected a cache (is the environment variable OOHELPERD_DISABLE_CACHE set?!)")
+		}
+		handler.measure = func(ctx context.Context, config *Handler, creq *model.THRequest) (*model.THResponse, error) {
+			count++
+			return &model.THResponse{}, nil
+		}
+		for idx := 0; idx < 3; idx++ {
+			if code := serve(handler); code != 200 {
+				t.Fatal("unexpected status code", code)
+			}
+		}
+		if count != 1 {
+			t.Fatal("expected a single measurement, got", count)
+		}
+	})
+
+	t.Run("without cache", func(t *testing.T) {
+		var count int
+		handler := NewHandler(log.Log, &netxlite.Netx{})
+		handler.cache = nil
+		handler.measure = func(ctx context.Context, config *Handler, creq *model.THRequest) (*model.THResponse, error) {
+			count++
+			return &model.THResponse{}, nil
+		}
+		for idx := 0; idx < 3; idx++ {
+			if code := serve(handler); code != 200 {
+				t.Fatal("unexpected status code", code)
+			}
+		}
+		if count != 3 {
+			t.Fatal("expected three measurements, got", count)
+		}
+	})
+}
+
+func TestNewResolverFromURL(t *testing.T) {
+	netx := &netxlite.Netx{}
+
+	t.Run("with supported URLs", func(t *testing.T) {
+		for _, URL := range []string{
+			"https://dns.google/dns-query",
+			"dot://1.1.1.1",
+			"dot://[2606:4700:4700::1111]:853",
+			"udp://9.9.9.9",
+			"udp://9.9.9.9:53",
+		} {
+			reso, err := newResolverFromURL(log.Log, netx, URL)
+			if err != nil {
+				t.Fatal(URL, err)
+			}
+			if reso == nil {
+				t.Fatal(URL, "expected non-nil resolver")
+			}
+			reso.CloseIdleConnections()
+		}
+	})
+
+	t.Run("with unsupported URLs", func(t *testing.T) {
+		for _, URL := range []string{"ftp://1.1.1.1", "\t"} {
+			reso, err := newResolverFromURL(log.Log, netx, URL)
+			if err == nil {
+				t.Fatal(URL, "expected an error")
+			}
+			if reso != nil {
+				t.Fatal(URL, "expected nil resolver")
+			}
+		}
+	})
+}
+
+func TestHandlerEndpointWithDefaultPort(t *testing.T) {
+	expect := map[string]string{
+		"1.1.1.1":                     "1.1.1.1:853",
+		"1.1.1.1:8853":                "1.1.1.1:8853",
+		"[2606:4700:4700::1111]":      "[2606:4700:4700::1111]:853",
+		"[2606:4700:4700::1111]:8853": "[2606:4700:4700::1111]:8853",
+		"dns.google":                  "dns.google:853",
+	}
+	for input, output := range expect {
+		if got := handlerEndpointWithDefaultPort(input, "853"); got != output {
+			t.Fatal("for", input, "expected", output, "got", got)
+		}
+	}
+}
+
+func TestHandlerParseDNSResolvers(t *testing.T) {
+	if out := handlerParseDNSResolvers(""); len(out) != 0 {
+		t.Fatal("expected no resolvers", out)
+	}
+	out := handlerParseDNSResolvers(" https://dns.google/dns-query,, udp://9.9.9.9 ,")
+	if diff := cmp.Diff([]string{"https://dns.google/dns-query", "udp://9.9.9.9"}, out); diff != "" {
+		t.Fatal(diff)
+	}
+}
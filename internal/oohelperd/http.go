@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -126,10 +127,7 @@ func httpDo(ctx context.Context, config *httpConfig) {
 	defer resp.Body.Close()
 
 	// copy headers
-	headers := make(map[string]string)
-	for k := range resp.Header {
-		headers[k] = resp.Header.Get(k)
-	}
+	headers := httpCopyHeaders(resp.Header)
 
 	// read the body up within a given maximum limit
 	// TODO(bassosimone): do we need to compute whether the body was truncated?
@@ -151,7 +149,45 @@ func httpDo(ctx context.Context, config *httpConfig) {
 		StatusCode:           int64(resp.StatusCode),
 		Headers:              headers,
 		Title:                measurexlite.WebGetTitle(string(data)),
+		RedirectChain:        httpRedirectChain(resp, int64(len(data))),
+		BodySHA256:           measurexlite.WebBodySHA256(data),
+		BodySimhash:          measurexlite.WebBodySimhash(data),
+	}
+}
+
+// httpCopyHeaders copies the first value of each header.
+func httpCopyHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for k := range header {
+		headers[k] = header.Get(k)
+	}
+	return headers
+}
+
+// httpRedirectChain returns all the responses in the redirect chain that terminates with
+// the given final response, sorted in the order in which we received them. We use the given
+// body length for the final response and the Content-Length for the other responses.
+func httpRedirectChain(final *http.Response, bodyLength int64) (out []model.THHTTPHopResult) {
+	// The default std lib behavior is to stop redirecting after 10 consecutive requests.
+	// Defensively we stop walking the chain after 11 responses.
+	for resp := final; resp != nil && len(out) < 11; {
+		request := resp.Request
+		runtimex.Assert(request != nil, "expected resp.Request != nil")
+		hop := model.THHTTPHopResult{
+			URL:        request.URL.String(),
+			StatusCode: int64(resp.StatusCode),
+			Location:   resp.Header.Get("Location"),
+			Headers:    httpCopyHeaders(resp.Header),
+			BodyLength: resp.ContentLength,
+		}
+		if resp == final {
+			hop.BodyLength = bodyLength
+		}
+		out = append(out, hop)
+		resp = request.Response
 	}
+	slices.Reverse(out)
+	return
 }
 
 // Discovers an H3 endpoint by inspecting the Alt-Svc header in the first request-response pair
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -71,6 +73,87 @@ func TestHTTPDoWithHTTPTransportFailure(t *testing.T) {
 	}
 }
 
+func TestHTTPDoWithRedirects(t *testing.T) {
+	// redirect returns an handler redirecting to the given location.
+	redirect := func(location string, statusCode int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", location)
+			w.WriteHeader(statusCode)
+			w.Write([]byte("redirect"))
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", redirect("/a", http.StatusFound))
+	mux.HandleFunc("/a", redirect("/b", http.StatusMovedPermanently))
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>Hello</title>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	wg := new(sync.WaitGroup)
+	httpch := make(chan ctrlHTTPResponse, 1)
+	wg.Add(1)
+	go httpDo(ctx, &httpConfig{
+		Headers:           nil,
+		Logger:            model.DiscardLogger,
+		MaxAcceptableBody: 1 << 24,
+		NewClient: func(model.Logger) model.HTTPClient {
+			return &http.Client{}
+		},
+		Out: httpch,
+		URL: server.URL + "/",
+		Wg:  wg,
+	})
+	// wait for measurement steps to complete
+	wg.Wait()
+	resp := <-httpch
+	if resp.Failure != nil {
+		t.Fatal(*resp.Failure)
+	}
+
+	// we only care about the fields that we can predict
+	type hop struct {
+		URL        string
+		StatusCode int64
+		Location   string
+		BodyLength int64
+	}
+	var got []hop
+	for _, entry := range resp.RedirectChain {
+		got = append(got, hop{entry.URL, entry.StatusCode, entry.Location, entry.BodyLength})
+	}
+	expect := []hop{{
+		URL:        server.URL + "/",
+		StatusCode: 302,
+		Location:   "/a",
+		BodyLength: 8,
+	}, {
+		URL:        server.URL + "/a",
+		StatusCode: 301,
+		Location:   "/b",
+		BodyLength: 8,
+	}, {
+		URL:        server.URL + "/b",
+		StatusCode: 200,
+		Location:   "",
+		BodyLength: 20,
+	}}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// make sure we hash the final response body
+	if resp.BodySHA256 != measurexlite.WebBodySHA256([]byte("<title>Hello</title>")) {
+		t.Fatal("unexpected body SHA256", resp.BodySHA256)
+	}
+	if resp.BodySimhash != measurexlite.WebBodySimhash([]byte("<title>Hello</title>")) {
+		t.Fatal("unexpected body simhash", resp.BodySimhash)
+	}
+}
+
 func newErrWrapper(failure, operation string) error {
 	return &netxlite.ErrWrapper{
 		Failure:    failure,
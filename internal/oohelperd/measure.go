@@ -42,6 +42,7 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 
 	// dns: start
 	dnsch := make(chan ctrlDNSResult, 1)
+	dnsresolversch := make(chan []model.THDNSResolverResult, 1)
 	if net.ParseIP(URL.Hostname()) == nil {
 		wg.Add(1)
 		go dnsDo(ctx, &dnsConfig{
@@ -51,6 +52,19 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 			Out:         dnsch,
 			Wg:          wg,
 		})
+
+		// optionally cross check using additional resolvers
+		if len(config.DNSResolvers) > 0 {
+			wg.Add(1)
+			go dnsResolversDo(ctx, &dnsResolversConfig{
+				Domain:             URL.Hostname(),
+				Logger:             logger,
+				NewResolverFromURL: config.newResolverFromURL,
+				Out:                dnsresolversch,
+				URLs:               config.DNSResolvers,
+				Wg:                 wg,
+			})
+		}
 	}
 
 	// wait for DNS measurements to complete
@@ -77,9 +91,14 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 			ASNs:    []int64{}, // unused by the TH and not serialized
 		}
 	}
+	select {
+	case cresp.DNSResolvers = <-dnsresolversch:
+	default:
+		// we did not use additional resolvers
+	}
 
 	// obtain IP info and figure out the endpoints measurement plan
-	cresp.IPInfo = newIPInfo(creq, cresp.DNS.Addrs)
+	cresp.IPInfo = newIPInfo(creq, cresp.DNSResolvedAddrs())
 	endpoints := ipInfoToEndpoints(logger, URL, cresp.IPInfo)
 
 	// tcpconnect: start over all the endpoints
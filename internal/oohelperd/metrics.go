@@ -73,4 +73,17 @@ var (
 		Help:       "Summarizes the time to complete the HTTP measurement task (in seconds)",
 		Objectives: metricsSummaryObjectives(),
 	})
+
+	// metricCacheRequestsCount counts the requests served by the cache by result, which
+	// is one of "hit", "miss", and "coalesced" (i.e., waited for a measurement in progress).
+	metricCacheRequestsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oohelperd_cache_requests_count",
+		Help: "Total number of requests served by the cache by result",
+	}, []string{"result"})
+
+	// metricCacheEntries gauges the number of entries in the cache.
+	metricCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "oohelperd_cache_entries_gauge",
+		Help: "The number of responses currently cached",
+	})
 )
@@ -90,9 +90,18 @@ func quicDo(ctx context.Context, config *quicConfig) {
 	defer measurexlite.MaybeCloseQUICConn(quicConn)
 	ol.Stop(err)
 
+	// obtain the certificates like we do for TLS
+	var state tls.ConnectionState
+	if quicConn != nil {
+		state = quicConn.ConnectionState().TLS
+	}
+	peerCerts := measurexlite.TLSPeerCerts(state, err)
+
 	out.QUIC = ctrlQUICResult{
-		ServerName: config.URLHostname,
-		Status:     err == nil,
-		Failure:    newfailure(err),
+		ServerName:       config.URLHostname,
+		Status:           err == nil,
+		Failure:          newfailure(err),
+		PeerCertificates: peerCerts,
+		LeafSPKISHA256:   measurexlite.TLSLeafSPKISHA256(peerCerts),
 	}
 }
@@ -140,6 +140,14 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 
 	// perform the handshake
 	tlsConn, err := thx.Handshake(ctx, conn, tlsConfig)
+
+	// obtain the certificates, which are also available on failure when the
+	// server sent a certificate that we could not verify
+	var state tls.ConnectionState
+	if tlsConn != nil {
+		state = tlsConn.ConnectionState()
+	}
+	peerCerts := measurexlite.TLSPeerCerts(state, err)
 	_ = measurexlite.MaybeClose(tlsConn)
 
 	// publish time required to handshake
@@ -150,9 +158,11 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 
 	// we're good and we can fill the result
 	out.TLS = &ctrlTLSResult{
-		ServerName: config.URLHostname,
-		Status:     err == nil,
-		Failure:    newfailure(err),
+		ServerName:       config.URLHostname,
+		Status:           err == nil,
+		Failure:          newfailure(err),
+		PeerCertificates: peerCerts,
+		LeafSPKISHA256:   measurexlite.TLSLeafSPKISHA256(peerCerts),
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/ooni/probe-cli/v3/internal/checkincache"
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
 	"github.com/ooni/probe-cli/v3/internal/httpclientx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/urlx"
@@ -12,7 +13,8 @@ import (
 // CheckIn function is called by probes asking if there are tests to be run
 // The config argument contains the mandatory settings.
 // This function will additionally update the [checkincache] such that we
-// track selected parts of the check-in API response.
+// track selected parts of the check-in API response, and store the signed
+// tactics for [enginenetx], or delete the stored ones if there are none.
 // Returns the list of tests to run and the URLs, on success,
 // or an explanatory error, in case of failure.
 func (c Client) CheckIn(
@@ -45,5 +47,10 @@ func (c Client) CheckIn(
 	// it would only work more poorly, but it does not seem worth it
 	// crippling it entirely if we cannot write into the kvstore
 	_ = checkincache.Store(c.KVStore, resp)
+
+	// likewise, store the tactics signed by the backend, if any, such that the
+	// next enginenetx.Network we create uses them to reach the backend, or delete
+	// the stored tactics when the backend did not send any
+	_ = enginenetx.StoreCheckInTactics(c.KVStore, resp.Conf.Tactics)
 	return resp, nil
 }
@@ -485,6 +485,9 @@ func TestCheckIn(t *testing.T) {
 			MockSet: func(key string, value []byte) error {
 				return errors.New("mocked error")
 			},
+			MockDelete: func(key string) error {
+				return errors.New("mocked error")
+			},
 		}
 		client.KVStore = brokenKvStore
 
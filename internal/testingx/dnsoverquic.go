@@ -0,0 +1,135 @@
+package testingx
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/quic-go/quic-go"
+)
+
+// DNSOverQUICListener is a DNS-over-QUIC listener implementing RFC 9250. The zero
+// value of this struct is invalid, please use [MustNewDNSOverQUICListener].
+type DNSOverQUICListener struct {
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	listener  *quic.Listener
+	pconn     net.PacketConn
+	rtx       DNSRoundTripper
+	wg        sync.WaitGroup
+}
+
+// MustNewDNSOverQUICListener creates a new [DNSOverQUICListener] using the given
+// [net.PacketConn], [*tls.Config], and [DNSRoundTripper]. We will override the
+// NextProtos field of a clone of the [*tls.Config] to be {"doq"}. The listener
+// takes ownership of the [net.PacketConn] and closes it when done.
+func MustNewDNSOverQUICListener(pconn net.PacketConn, config *tls.Config, rtx DNSRoundTripper) *DNSOverQUICListener {
+	config = config.Clone()
+	config.NextProtos = []string{"doq"}
+	listener := runtimex.Try1(quic.Listen(pconn, config, &quic.Config{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	dl := &DNSOverQUICListener{
+		cancel:    cancel,
+		closeOnce: sync.Once{},
+		listener:  listener,
+		pconn:     pconn,
+		rtx:       rtx,
+		wg:        sync.WaitGroup{},
+	}
+	dl.wg.Add(1)
+	go dl.mainloop(ctx)
+	return dl
+}
+
+// LocalAddr returns the listener address.
+func (dl *DNSOverQUICListener) LocalAddr() net.Addr {
+	return dl.listener.Addr()
+}
+
+// Close implements io.Closer.
+func (dl *DNSOverQUICListener) Close() (err error) {
+	dl.closeOnce.Do(func() {
+		// close the listener to interrupt Accept
+		err = dl.listener.Close()
+
+		// cancel the context to interrupt connections and the round tripper
+		dl.cancel()
+
+		// wait for the background goroutines to join
+		dl.wg.Wait()
+
+		// close the underlying conn, which we own
+		_ = dl.pconn.Close()
+	})
+	return err
+}
+
+func (dl *DNSOverQUICListener) mainloop(ctx context.Context) {
+	// synchronize with Close
+	defer dl.wg.Done()
+
+	for {
+		// accept the next connection or stop when we're closed
+		conn, err := dl.listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+
+		// handle the connection in the background
+		dl.wg.Add(1)
+		go dl.serveConn(ctx, conn)
+	}
+}
+
+func (dl *DNSOverQUICListener) serveConn(ctx context.Context, conn *quic.Conn) {
+	// synchronize with Close
+	defer dl.wg.Done()
+
+	// make sure we close the connection when done
+	defer conn.CloseWithError(0, "")
+
+	for {
+		// accept the next stream or stop when the conn is closed
+		stream, err := conn.AcceptStream(ctx)
+		if err != nil {
+			return
+		}
+
+		// serve the stream in the background
+		dl.wg.Add(1)
+		go dl.serveStream(ctx, stream)
+	}
+}
+
+func (dl *DNSOverQUICListener) serveStream(ctx context.Context, stream *quic.Stream) {
+	// synchronize with Close
+	defer dl.wg.Done()
+
+	// make sure we close the stream when done
+	defer stream.Close()
+
+	// read the length-prefixed query, which the client terminates using FIN
+	rawQuery, err := io.ReadAll(stream)
+	if err != nil || len(rawQuery) < 2 {
+		return
+	}
+	length := int(rawQuery[0])<<8 | int(rawQuery[1])
+	rawQuery = rawQuery[2:]
+	if length != len(rawQuery) {
+		return
+	}
+
+	// perform the round trip and ignore messages causing errors
+	rawResp, err := dl.rtx.RoundTrip(ctx, rawQuery)
+	if err != nil || len(rawResp) > 0xffff {
+		return
+	}
+
+	// emit the length-prefixed response and ignore errors
+	buf := []byte{byte(len(rawResp) >> 8), byte(len(rawResp))}
+	buf = append(buf, rawResp...)
+	_, _ = stream.Write(buf)
+}
@@ -0,0 +1,89 @@
+package testingx
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/quic-go/quic-go"
+)
+
+func TestDNSOverQUICListener(t *testing.T) {
+	ca := netem.MustNewCA()
+
+	newListener := func() *DNSOverQUICListener {
+		pconn := runtimex.Try1(net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}))
+		config := netem.NewDNSConfig()
+		config.AddRecord("example.com", "", "93.184.216.34")
+		rtx := NewDNSRoundTripperWithDNSConfig(config)
+		return MustNewDNSOverQUICListener(pconn, ca.MustNewServerTLSConfig("dns.google"), rtx)
+	}
+
+	t.Run("we can perform a round trip", func(t *testing.T) {
+		listener := newListener()
+		defer listener.Close()
+
+		tlsConfig := &tls.Config{
+			NextProtos: []string{"doq"},
+			RootCAs:    ca.DefaultCertPool(),
+			ServerName: "dns.google",
+		}
+		ctx := context.Background()
+		conn, err := quic.DialAddr(ctx, listener.LocalAddr().String(), tlsConfig, &quic.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.CloseWithError(0, "")
+
+		stream, err := conn.OpenStreamSync(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := &dns.Msg{}
+		query.SetQuestion("example.com.", dns.TypeA)
+		query.Id = 0
+		rawQuery := runtimex.Try1(query.Pack())
+		buf := []byte{byte(len(rawQuery) >> 8), byte(len(rawQuery))}
+		buf = append(buf, rawQuery...)
+		if _, err := stream.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		rawResp, err := io.ReadAll(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rawResp) < 2 || int(rawResp[0])<<8|int(rawResp[1]) != len(rawResp)-2 {
+			t.Fatal("invalid length-prefixed response")
+		}
+		resp := &dns.Msg{}
+		if err := resp.Unpack(rawResp[2:]); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Answer) != 1 {
+			t.Fatal("expected a single answer")
+		}
+		a, ok := resp.Answer[0].(*dns.A)
+		if !ok || a.A.String() != "93.184.216.34" {
+			t.Fatal("unexpected answer", resp.Answer[0])
+		}
+	})
+
+	t.Run("Close is idempotent", func(t *testing.T) {
+		listener := newListener()
+		if err := listener.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := listener.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -17,7 +17,9 @@ import (
 //
 // Caveat: this kind of fillter does not support filling interfaces
 // and channels and other complex types. The current behavior when this
-// kind of data types is encountered is to just ignore them.
+// kind of data types is encountered is to just ignore them. Likewise,
+// we leave empty slices and maps of recursive types (e.g., a struct
+// containing a slice of pointers to itself) to avoid infinite recursion.
 //
 // This struct is quite limited in scope and we can fill only the
 // structures you typically send over as JSONs.
@@ -82,7 +84,7 @@ func (ff *FakeFiller) getRandomSmallPositiveInt() int {
 	return int(rnd.Int63n(8)) + 1 // safe cast
 }
 
-func (ff *FakeFiller) doFill(v reflect.Value) {
+func (ff *FakeFiller) doFill(v reflect.Value, visiting map[reflect.Type]bool) {
 	for v.Type().Kind() == reflect.Ptr {
 		if v.IsNil() {
 			// if the pointer is nil, allocate an element
@@ -113,15 +115,20 @@ func (ff *FakeFiller) doFill(v reflect.Value) {
 				time.Duration(ff.getRandomSmallPositiveInt()) * time.Second)))
 			return
 		}
+		visiting[v.Type()] = true
 		for idx := 0; idx < v.NumField(); idx++ {
-			ff.doFill(v.Field(idx)) // visit all fields
+			ff.doFill(v.Field(idx), visiting) // visit all fields
 		}
+		delete(visiting, v.Type())
 	case reflect.Slice:
 		kind := v.Type().Elem()
+		if fakeFillIsVisiting(kind, visiting) {
+			return // avoid infinite recursion
+		}
 		total := ff.getRandomSmallPositiveInt()
 		for idx := 0; idx < total; idx++ {
 			value := reflect.New(kind) // make a new element
-			ff.doFill(value)
+			ff.doFill(value, visiting)
 			v.Set(reflect.Append(v, value.Elem())) // append to slice
 		}
 	case reflect.Map:
@@ -129,17 +136,29 @@ func (ff *FakeFiller) doFill(v reflect.Value) {
 			panic("fakefill: we only support string key types")
 		}
 		v.Set(reflect.MakeMap(v.Type())) // we need to init the map
-		total := ff.getRandomSmallPositiveInt()
 		kind := v.Type().Elem()
+		if fakeFillIsVisiting(kind, visiting) {
+			return // avoid infinite recursion
+		}
+		total := ff.getRandomSmallPositiveInt()
 		for idx := 0; idx < total; idx++ {
 			value := reflect.New(kind)
-			ff.doFill(value)
+			ff.doFill(value, visiting)
 			v.SetMapIndex(reflect.ValueOf(ff.getRandomString()), value.Elem())
 		}
 	}
 }
 
+// fakeFillIsVisiting returns whether the given type, once we strip
+// all the pointers, is a struct type we're currently filling.
+func fakeFillIsVisiting(kind reflect.Type, visiting map[reflect.Type]bool) bool {
+	for kind.Kind() == reflect.Ptr {
+		kind = kind.Elem()
+	}
+	return visiting[kind]
+}
+
 // Fill fills the input structure or pointer with random data.
 func (ff *FakeFiller) Fill(in interface{}) {
-	ff.doFill(reflect.ValueOf(in))
+	ff.doFill(reflect.ValueOf(in), make(map[reflect.Type]bool))
 }
@@ -142,3 +142,27 @@ func TestFakeFillSkipsPrivateTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestFakeFillAvoidsInfiniteRecursion(t *testing.T) {
+	// define a structure referencing itself
+	type node struct {
+		Name     string
+		Children []*node
+		Index    map[string]*node
+	}
+
+	// fake-fill the node
+	var root node
+	ff := &FakeFiller{}
+	ff.Fill(&root)
+
+	// make sure we've filled the fields that are not recursive
+	if root.Name == "" {
+		t.Fatal("expected a non-empty name")
+	}
+
+	// make sure we did not recurse into the node type
+	if len(root.Children) != 0 || len(root.Index) != 0 {
+		t.Fatal("expected empty recursive fields")
+	}
+}
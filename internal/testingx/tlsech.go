@@ -0,0 +1,76 @@
+package testingx
+
+//
+// Encrypted Client Hello (ECH) keys for test servers
+//
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// ECHKey contains an ECH configuration and the corresponding private key.
+type ECHKey struct {
+	// ConfigList is the serialized ECHConfigList that clients should
+	// use, which contains a single ECHConfig.
+	ConfigList []byte
+
+	// ServerKey is the key to add to the server's [tls.Config]
+	// EncryptedClientHelloKeys field to enable ECH.
+	ServerKey tls.EncryptedClientHelloKey
+}
+
+// These constants are defined by RFC 9180 and by draft-ietf-tls-esni-22.
+const (
+	echVersion           = 0xfe0d
+	echKEMX25519SHA256   = 0x0020
+	echKDFHKDFSHA256     = 0x0001
+	echAEADAES128GCM     = 0x0001
+	echMaximumNameLength = 0
+)
+
+// MustNewECHKey creates a new [*ECHKey] using a random X25519 key, the given config
+// ID, and the given public name, which is the name the client uses in the outer
+// ClientHello and which the server's certificate must be valid for. This function
+// PANICS in case of failure.
+func MustNewECHKey(configID uint8, publicName string) *ECHKey {
+	privateKey := runtimex.Try1(ecdh.X25519().GenerateKey(rand.Reader))
+
+	builder := cryptobyte.NewBuilder(nil)
+	builder.AddUint16(echVersion)
+	builder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(configID)
+		b.AddUint16(echKEMX25519SHA256)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(privateKey.PublicKey().Bytes())
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(echKDFHKDFSHA256)
+			b.AddUint16(echAEADAES128GCM)
+		})
+		b.AddUint8(echMaximumNameLength)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(publicName))
+		})
+		b.AddUint16(0) // no extensions
+	})
+	config := builder.BytesOrPanic()
+
+	listBuilder := cryptobyte.NewBuilder(nil)
+	listBuilder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(config)
+	})
+
+	return &ECHKey{
+		ConfigList: listBuilder.BytesOrPanic(),
+		ServerKey: tls.EncryptedClientHelloKey{
+			Config:      config,
+			PrivateKey:  privateKey.Bytes(),
+			SendAsRetry: true,
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package testingx
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestMustNewECHKey(t *testing.T) {
+	ca := netem.MustNewCA()
+
+	// handshake performs a handshake with a server configured with the given keys
+	handshake := func(keys []tls.EncryptedClientHelloKey, configList []byte) (tls.ConnectionState, error) {
+		serverConfig := ca.MustNewServerTLSConfig("www.example.com", "public.example.com")
+		serverConfig.EncryptedClientHelloKeys = keys
+		listener := runtimex.Try1(tls.Listen("tcp", "127.0.0.1:0", serverConfig))
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_ = conn.(*tls.Conn).Handshake()
+		}()
+		clientConfig := &tls.Config{
+			EncryptedClientHelloConfigList: configList,
+			RootCAs:                        ca.DefaultCertPool(),
+			ServerName:                     "www.example.com",
+		}
+		conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+		if err != nil {
+			return tls.ConnectionState{}, err
+		}
+		defer conn.Close()
+		return conn.ConnectionState(), nil
+	}
+
+	t.Run("the server accepts ECH using the generated key", func(t *testing.T) {
+		key := MustNewECHKey(7, "public.example.com")
+		state, err := handshake([]tls.EncryptedClientHelloKey{key.ServerKey}, key.ConfigList)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !state.ECHAccepted {
+			t.Fatal("expected ECH to be accepted")
+		}
+	})
+
+	t.Run("the server rejects ECH using another key and sends retry configs", func(t *testing.T) {
+		serverKey, clientKey := MustNewECHKey(7, "public.example.com"), MustNewECHKey(8, "public.example.com")
+		_, err := handshake([]tls.EncryptedClientHelloKey{serverKey.ServerKey}, clientKey.ConfigList)
+		var echErr *tls.ECHRejectionError
+		if !errors.As(err, &echErr) {
+			t.Fatal("unexpected error", err)
+		}
+		if string(echErr.RetryConfigList) != string(serverKey.ConfigList) {
+			t.Fatal("unexpected retry configs")
+		}
+	})
+}
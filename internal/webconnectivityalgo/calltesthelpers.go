@@ -2,6 +2,7 @@ package webconnectivityalgo
 
 import (
 	"context"
+	"sync"
 
 	"github.com/ooni/probe-cli/v3/internal/httpclientx"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -51,3 +52,69 @@ func CallWebConnectivityTestHelper(ctx context.Context, creq *model.THRequest,
 	// return the results to the web connectivity caller
 	return cresp, idx, nil
 }
+
+// WebConnectivityTestHelperResult is the result of calling a specific test helper.
+type WebConnectivityTestHelperResult struct {
+	// Err is the error that occurred or nil.
+	Err error
+
+	// Index is the index of the test helper we called.
+	Index int
+
+	// Response is the response on success and nil on failure.
+	Response *model.THResponse
+}
+
+// CallWebConnectivityTestHelpersInParallel is like [CallWebConnectivityTestHelper] except
+// that it calls at most count test helpers in parallel and returns all their results. We use
+// this functionality to cross check the test helpers, since a test helper whose network is
+// itself censoring or rate limiting would otherwise yield misleading results.
+//
+// We skip the test helpers we cannot call (e.g., the ones whose type is not "https" or
+// "cloudfront"), hence the Index of each result refers to the testhelpers list.
+//
+// The returned list contains the results sorted by test helper index and is empty if the
+// list of test helpers is empty or count is not positive. Each result contains either
+// the response or the error, which won't be wrapped, so you need to wrap it yourself.
+func CallWebConnectivityTestHelpersInParallel(ctx context.Context, creq *model.THRequest,
+	testhelpers []model.OOAPIService, count int, sess model.ExperimentSession) []*WebConnectivityTestHelperResult {
+	// select the first count test helpers we can call, remembering their index
+	type indexedEndpoint struct {
+		endpoint *httpclientx.Endpoint
+		index    int
+	}
+	var endpoints []indexedEndpoint
+	for idx, th := range testhelpers {
+		if len(endpoints) >= count {
+			break
+		}
+		for _, epnt := range httpclientx.NewEndpointFromModelOOAPIServices(th) {
+			endpoints = append(endpoints, indexedEndpoint{endpoint: epnt, index: idx})
+		}
+	}
+
+	// create the configuration for performing the HTTP calls
+	config := &httpclientx.Config{
+		Authorization: "", // not needed
+		Client:        sess.DefaultHTTPClient(),
+		Logger:        sess.Logger(),
+		UserAgent:     sess.UserAgent(),
+	}
+
+	// perform the HTTP API calls in parallel
+	results := make([]*WebConnectivityTestHelperResult, len(endpoints))
+	wg := &sync.WaitGroup{}
+	for idx, epnt := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cresp, err := httpclientx.PostJSON[*model.THRequest, *model.THResponse](ctx, epnt.endpoint, creq, config)
+			runtimex.Assert((cresp == nil) != (err == nil), "expected either a response or an error")
+			results[idx] = &WebConnectivityTestHelperResult{Err: err, Index: epnt.index, Response: cresp}
+		}()
+	}
+	wg.Wait()
+
+	// return the results to the web connectivity caller
+	return results
+}
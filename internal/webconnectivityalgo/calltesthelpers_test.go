@@ -304,3 +304,108 @@ func TestSessionCallWebConnectivityTestHelper(t *testing.T) {
 		}
 	})
 }
+
+// This function tests the [CallWebConnectivityTestHelpersInParallel] function.
+func TestCallWebConnectivityTestHelpersInParallel(t *testing.T) {
+	// create a new session only initializing the fields that
+	// are going to matter for running this specific test
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+		MockDefaultHTTPClient: func() model.HTTPClient {
+			return http.DefaultClient
+		},
+		MockUserAgent: func() string {
+			return model.HTTPHeaderUserAgent
+		},
+	}
+
+	// create a local test server that always resets the connection
+	server1 := testingx.MustNewHTTPServer(testingx.HTTPHandlerReset())
+	defer server1.Close()
+
+	// create local test servers returning different status codes
+	server2 := testingx.MustNewHTTPServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"http_request":{"status_code":200}}`))
+	}))
+	defer server2.Close()
+	server3 := testingx.MustNewHTTPServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"http_request":{"status_code":403}}`))
+	}))
+	defer server3.Close()
+
+	// create the list of test helpers to use
+	testhelpers := []model.OOAPIService{{
+		Address: server1.URL,
+		Type:    "https",
+	}, {
+		Address: server2.URL,
+		Type:    "https",
+	}, {
+		Address: server3.URL,
+		Type:    "https",
+	}}
+
+	t.Run("when there are no available test helpers", func(t *testing.T) {
+		results := CallWebConnectivityTestHelpersInParallel(context.Background(), &model.THRequest{}, nil, 3, sess)
+		if len(results) != 0 {
+			t.Fatal("expected no results")
+		}
+	})
+
+	t.Run("when count is not positive", func(t *testing.T) {
+		results := CallWebConnectivityTestHelpersInParallel(context.Background(), &model.THRequest{}, testhelpers, -1, sess)
+		if len(results) != 0 {
+			t.Fatal("expected no results")
+		}
+	})
+
+	t.Run("we only call count test helpers", func(t *testing.T) {
+		results := CallWebConnectivityTestHelpersInParallel(context.Background(), &model.THRequest{}, testhelpers, 2, sess)
+		if len(results) != 2 {
+			t.Fatal("expected two results")
+		}
+		if !errors.Is(results[0].Err, netxlite.ECONNRESET) || results[0].Response != nil {
+			t.Fatal("unexpected first result", results[0])
+		}
+		if results[1].Err != nil || results[1].Response.HTTPRequest.StatusCode != 200 {
+			t.Fatal("unexpected second result", results[1])
+		}
+	})
+
+	t.Run("we return all the results sorted by index", func(t *testing.T) {
+		results := CallWebConnectivityTestHelpersInParallel(context.Background(), &model.THRequest{}, testhelpers, 10, sess)
+		if len(results) != 3 {
+			t.Fatal("expected three results")
+		}
+		for idx, result := range results {
+			if result.Index != idx {
+				t.Fatal("unexpected index", result.Index)
+			}
+		}
+		if results[2].Err != nil || results[2].Response.HTTPRequest.StatusCode != 403 {
+			t.Fatal("unexpected third result", results[2])
+		}
+	})
+
+	t.Run("we skip the test helpers we cannot call", func(t *testing.T) {
+		mixed := []model.OOAPIService{{
+			Address: "https://www.example.com/",
+			Type:    "onion",
+		}, testhelpers[1], {
+			Address: "https://www.example.org/",
+			Type:    "onion",
+		}, testhelpers[2]}
+		results := CallWebConnectivityTestHelpersInParallel(context.Background(), &model.THRequest{}, mixed, 2, sess)
+		if len(results) != 2 {
+			t.Fatal("expected two results")
+		}
+		if results[0].Index != 1 || results[0].Err != nil || results[0].Response.HTTPRequest.StatusCode != 200 {
+			t.Fatal("unexpected first result", results[0])
+		}
+		if results[1].Index != 3 || results[1].Err != nil || results[1].Response.HTTPRequest.StatusCode != 403 {
+			t.Fatal("unexpected second result", results[1])
+		}
+	})
+}
@@ -92,7 +92,7 @@ func badSSLWithUnknownAuthorityWithInconsistentDNS() *TestCase {
 			DNSConsistency:        "inconsistent",
 			HTTPExperimentFailure: "ssl_unknown_authority",
 			XStatus:               9248, // StatusExperimentHTTP | StatusAnomalyTLSHandshake | StatusAnomalyDNS
-			XDNSFlags:             4,    // AnalysisDNSFlagUnexpectedAddrs
+			XDNSFlags:             12,   // AnalysisDNSFlagUnexpectedAddrs | AnalysisDNSFlagInjection
 			XBlockingFlags:        33,   // AnalysisBlockingFlagSuccess | AnalysisBlockingFlagDNSBlocking
 			XNullNullFlags:        4,    // AnalysisFlagNullNullExpectedTLSHandshakeFailure
 			Accessible:            false,
@@ -34,7 +34,7 @@ func dnsHijackingToProxyWithHTTPURL() *TestCase {
 			HeadersMatch:    true,
 			TitleMatch:      true,
 			XStatus:         2,  // StatusSuccessCleartext
-			XDNSFlags:       4,  // AnalysisDNSFlagUnexpectedAddrs
+			XDNSFlags:       12, // AnalysisDNSFlagUnexpectedAddrs | AnalysisDNSFlagInjection
 			XBlockingFlags:  33, // AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagSuccess
 			Accessible:      true,
 			Blocking:        false,
@@ -71,7 +71,7 @@ func dnsHijackingToProxyWithHTTPSURL() *TestCase {
 			HeadersMatch:    true,
 			TitleMatch:      true,
 			XStatus:         1,  // StatusSuccessSecure
-			XDNSFlags:       4,  // AnalysisDNSFlagUnexpectedAddrs
+			XDNSFlags:       12, // AnalysisDNSFlagUnexpectedAddrs | AnalysisDNSFlagInjection
 			XBlockingFlags:  33, // AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagSuccess
 			Accessible:      true,
 			Blocking:        false,
@@ -99,7 +99,7 @@ func dnsHijackingToLocalhostWithHTTP() *TestCase {
 		ExpectErr: false,
 		ExpectTestKeys: &TestKeys{
 			DNSConsistency: "inconsistent",
-			XDNSFlags:      5,  // AnalysisFlagDNSBogon | AnalysisDNSFlagUnexpectedAddrs
+			XDNSFlags:      13, // AnalysisFlagDNSBogon | AnalysisDNSFlagUnexpectedAddrs | AnalysisDNSFlagInjection
 			XBlockingFlags: 33, // AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagSuccess
 			Accessible:     false,
 			Blocking:       "dns",
@@ -127,7 +127,7 @@ func dnsHijackingToLocalhostWithHTTPS() *TestCase {
 		ExpectErr: false,
 		ExpectTestKeys: &TestKeys{
 			DNSConsistency: "inconsistent",
-			XDNSFlags:      5,  // AnalysisFlagDNSBogon | AnalysisDNSFlagUnexpectedAddrs
+			XDNSFlags:      13, // AnalysisFlagDNSBogon | AnalysisDNSFlagUnexpectedAddrs | AnalysisDNSFlagInjection
 			XBlockingFlags: 33, // AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagSuccess
 			Accessible:     false,
 			Blocking:       "dns",
@@ -34,6 +34,7 @@ func ghostDNSBlockingWithHTTP() *TestCase {
 		ExpectTestKeys: &TestKeys{
 			DNSExperimentFailure: nil,
 			DNSConsistency:       "inconsistent",
+			XDNSFlags:            8,  // AnalysisDNSFlagInjection
 			XBlockingFlags:       16, // AnalysisBlockingFlagHTTPDiff
 			XNullNullFlags:       18, // AnalysisFlagNullNullExpectedTCPConnectFailure | AnalysisFlagNullNullUnexpectedDNSLookupSuccess
 			XStatus:              16, // StatusAnomalyControlFailure
@@ -72,6 +73,7 @@ func ghostDNSBlockingWithHTTPS() *TestCase {
 			DNSExperimentFailure:  nil,
 			DNSConsistency:        "inconsistent",
 			HTTPExperimentFailure: "connection_refused",
+			XDNSFlags:             8,    // AnalysisDNSFlagInjection
 			XNullNullFlags:        18,   // AnalysisFlagNullNullExpectedTCPConnectFailure | AnalysisFlagNullNullUnexpectedDNSLookupSuccess
 			XStatus:               4256, // StatusExperimentConnect | StatusAnomalyDNS | StatusAnomalyConnect
 			Accessible:            false,
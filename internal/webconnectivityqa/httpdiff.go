@@ -91,7 +91,7 @@ func httpDiffWithInconsistentDNS() *TestCase {
 			HeadersMatch:          false,
 			TitleMatch:            false,
 			XStatus:               96, // StatusAnomalyHTTPDiff | StatusAnomalyDNS
-			XDNSFlags:             4,  // AnalysisDNSFlagUnexpectedAddrs
+			XDNSFlags:             12, // AnalysisDNSFlagUnexpectedAddrs | AnalysisDNSFlagInjection
 			XBlockingFlags:        17, // AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagHTTPDiff
 			Accessible:            false,
 			Blocking:              "dns",
@@ -182,8 +182,8 @@ func redirectWithConsistentDNSAndThenNXDOMAIN() *TestCase {
 			DNSConsistency:        "consistent",
 			HTTPExperimentFailure: "dns_nxdomain_error",
 			XStatus:               8224, // StatusExperimentHTTP | StatusAnomalyDNS
-			XDNSFlags:             0,
-			XBlockingFlags:        1, // AnalysisBlockingFlagDNSBlocking
+			XDNSFlags:             8,    // AnalysisDNSFlagInjection
+			XBlockingFlags:        1,    // AnalysisBlockingFlagDNSBlocking
 			Accessible:            false,
 			Blocking:              "dns",
 		},
@@ -67,7 +67,7 @@ func tcpBlockingConnectionRefusedWithInconsistentDNS() *TestCase {
 			DNSConsistency:        "inconsistent",
 			HTTPExperimentFailure: "connection_refused",
 			XStatus:               4256, // StatusExperimentConnect | StatusAnomalyConnect | StatusAnomalyDNS
-			XDNSFlags:             4,    // AnalysisDNSFlagUnexpectedAddrs
+			XDNSFlags:             12,   // AnalysisDNSFlagUnexpectedAddrs | AnalysisDNSFlagInjection
 			XNullNullFlags:        2,    // AnalysisFlagNullNullExpectedTCPConnectFailure
 			XBlockingFlags:        35,   // AnalysisBlockingFlagSuccess | AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagTCPIPBlocking
 			Accessible:            false,
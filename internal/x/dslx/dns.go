@@ -9,7 +9,9 @@ import (
 	"errors"
 	"time"
 
+	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 // DomainName is a domain name to resolve.
@@ -25,6 +27,16 @@ func DNSLookupOptionTags(value ...string) DNSLookupOption {
 	}
 }
 
+// DNSLookupOptionEDNS0 allows to set EDNS0 options (e.g., client subnet,
+// cookies) to include into the queries sent by [DNSLookupUDP]. Use the
+// constructors in netxlite, such as [netxlite.NewDNSEDNS0ClientSubnet]
+// and [netxlite.ParseDNSEDNS0Options], to create these options.
+func DNSLookupOptionEDNS0(value ...dns.EDNS0) DNSLookupOption {
+	return func(dis *DomainToResolve) {
+		dis.EDNS0 = append(dis.EDNS0, value...)
+	}
+}
+
 // NewDomainToResolve creates input for performing DNS lookups. The only mandatory
 // argument is the domain name to resolve. You can also supply optional
 // values by passing options to this function.
@@ -49,6 +61,10 @@ type DomainToResolve struct {
 	// Domain is the MANDATORY domain name to lookup.
 	Domain string
 
+	// EDNS0 contains OPTIONAL EDNS0 options to include into DNS queries. We
+	// only honour this field for lookups using a DNS transport.
+	EDNS0 []dns.EDNS0
+
 	// Tags contains OPTIONAL tags to tag observations.
 	Tags []string
 }
@@ -150,6 +166,11 @@ func DNSLookupUDP(rt Runtime, endpoint string) Func[*DomainToResolve, *ResolvedA
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
+		// include the EDNS0 options, if any
+		if len(input.EDNS0) > 0 {
+			ctx = netxlite.ContextWithDNSEDNS0Options(ctx, input.EDNS0...)
+		}
+
 		// create the resolver
 		resolver := trace.NewParallelUDPResolver(
 			rt.Logger(),
@@ -9,8 +9,10 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 /*
@@ -18,6 +20,7 @@ Test cases:
 - New domain to resolve:
   - with empty domain
   - with options
+  - with EDNS0 options
 */
 func TestNewDomainToResolve(t *testing.T) {
 	t.Run("New domain to resolve", func(t *testing.T) {
@@ -42,6 +45,17 @@ func TestNewDomainToResolve(t *testing.T) {
 				t.Fatal(diff)
 			}
 		})
+
+		t.Run("with EDNS0 options", func(t *testing.T) {
+			option := &dns.EDNS0_NSID{Code: dns.EDNS0NSID}
+			domainToResolve := NewDomainToResolve(
+				DomainName("www.example.com"),
+				DNSLookupOptionEDNS0(option),
+			)
+			if len(domainToResolve.EDNS0) != 1 || domainToResolve.EDNS0[0] != option {
+				t.Fatal("unexpected EDNS0 options", domainToResolve.EDNS0)
+			}
+		})
 	})
 }
 
@@ -127,6 +141,7 @@ Test cases:
   - with nil resolver
   - with lookup error
   - with success
+  - with EDNS0 options
 */
 func TestLookupUDP(t *testing.T) {
 	t.Run("Apply dnsLookupUDPFunc", func(t *testing.T) {
@@ -206,5 +221,36 @@ func TestLookupUDP(t *testing.T) {
 				t.Fatal("unexpected addresses")
 			}
 		})
+
+		t.Run("with EDNS0 options", func(t *testing.T) {
+			option := &dns.EDNS0_NSID{Code: dns.EDNS0NSID}
+			var got []dns.EDNS0
+			rt := NewRuntimeMeasurexLite(model.DiscardLogger, time.Now(), RuntimeMeasurexLiteOptionMeasuringNetwork(&mocks.MeasuringNetwork{
+				MockNewParallelUDPResolver: func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+					return &mocks.Resolver{
+						MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+							got = netxlite.ContextDNSEDNS0Options(ctx)
+							return []string{"93.184.216.34"}, nil
+						},
+					}
+				},
+				MockNewDialerWithoutResolver: func(dl model.DebugLogger, w ...model.DialerWrapper) model.Dialer {
+					return &mocks.Dialer{
+						MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+							panic("should not be called")
+						},
+					}
+				},
+			}))
+			f := DNSLookupUDP(rt, "1.1.1.1:53")
+			input := NewDomainToResolve("example.com", DNSLookupOptionEDNS0(option))
+			res := f.Apply(context.Background(), NewMaybeWithValue(input))
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %s", res.Error)
+			}
+			if len(got) != 1 || got[0] != option {
+				t.Fatal("unexpected EDNS0 options", got)
+			}
+		})
 	})
 }
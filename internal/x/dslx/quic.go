@@ -42,7 +42,7 @@ func QUICHandshake(rt Runtime, options ...TLSHandshakeOption) Func[*Endpoint, *Q
 		defer cancel()
 
 		// handshake
-		quicConn, err := quicDialer.DialContext(ctx, input.Address, config, &quic.Config{})
+		quicConn, err := quicDialer.DialContext(ctx, input.Address, config.Config, &quic.Config{})
 
 		var closerConn io.Closer
 		var tlsState tls.ConnectionState
@@ -75,7 +75,7 @@ func QUICHandshake(rt Runtime, options ...TLSHandshakeOption) Func[*Endpoint, *Q
 			QUICConn:  quicConn,
 			Domain:    input.Domain,
 			Network:   input.Network,
-			TLSConfig: config,
+			TLSConfig: config.Config,
 			TLSState:  tlsState,
 			Trace:     trace,
 		}
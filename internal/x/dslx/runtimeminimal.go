@@ -8,6 +8,7 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 // MinimalRuntimeOption is an option for configuring the [*MinimalRuntime].
@@ -170,6 +171,11 @@ func (tx *minimalTrace) NewTLSHandshakerStdlib(dl model.DebugLogger) model.TLSHa
 	return tx.netx.NewTLSHandshakerStdlib(dl)
 }
 
+// NewTLSHandshakerUTLS implements Trace.
+func (tx *minimalTrace) NewTLSHandshakerUTLS(dl model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker {
+	return tx.netx.NewTLSHandshakerUTLS(dl, id)
+}
+
 // NewUDPListener implements Trace
 func (tx *minimalTrace) NewUDPListener() model.UDPListener {
 	return tx.netx.NewUDPListener()
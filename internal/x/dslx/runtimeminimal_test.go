@@ -10,6 +10,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/quic-go/quic-go"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 /*
@@ -195,6 +196,13 @@ func TestMinimalRuntime(t *testing.T) {
 			}
 		})
 
+		t.Run("NewTLSHandshakerUTLS", func(t *testing.T) {
+			out := trace.NewTLSHandshakerUTLS(model.DiscardLogger, &utls.HelloChrome_Auto)
+			if out == nil {
+				t.Fatal("expected non-nil pointer")
+			}
+		})
+
 		t.Run("QUICHandshakes", func(t *testing.T) {
 			out := trace.QUICHandshakes()
 			if out == nil || len(out) != 0 {
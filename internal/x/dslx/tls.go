@@ -17,32 +17,61 @@ import (
 )
 
 // TLSHandshakeOption is an option you can pass to TLSHandshake.
-type TLSHandshakeOption func(config *tls.Config)
+type TLSHandshakeOption func(config *TLSHandshakeConfig)
+
+// TLSHandshakeConfig is the configuration modified by [TLSHandshakeOption]. We export
+// this type so that packages using dslx can write their own options.
+type TLSHandshakeConfig struct {
+	// Config is the TLS config to use.
+	*tls.Config
+
+	// Fingerprint is the OPTIONAL name of the uTLS fingerprint to use. When
+	// empty, we use the stdlib to perform the TLS handshake.
+	Fingerprint string
+}
+
+// TLSHandshakeOptionECHConfigList configures the ECHConfigList to use for performing
+// an Encrypted Client Hello handshake, which is usually obtained from the HTTPS
+// record of the domain. Note that [QUICHandshake] does not support ECH yet.
+func TLSHandshakeOptionECHConfigList(value []byte) TLSHandshakeOption {
+	return func(config *TLSHandshakeConfig) {
+		config.EncryptedClientHelloConfigList = value
+	}
+}
+
+// TLSHandshakeOptionFingerprint configures the name of the uTLS fingerprint to
+// use (e.g., "chrome"). See [netxlite.UTLSFingerprintNames] for the available names. Note
+// that [QUICHandshake] ignores this option since uTLS does not support QUIC.
+func TLSHandshakeOptionFingerprint(value string) TLSHandshakeOption {
+	return func(config *TLSHandshakeConfig) {
+		config.Fingerprint = value
+	}
+}
 
 // TLSHandshakeOptionInsecureSkipVerify controls whether TLS verification is enabled.
 func TLSHandshakeOptionInsecureSkipVerify(value bool) TLSHandshakeOption {
-	return func(config *tls.Config) {
+	return func(config *TLSHandshakeConfig) {
 		config.InsecureSkipVerify = value
 	}
 }
 
 // TLSHandshakeOptionNextProto allows to configure the ALPN protocols.
 func TLSHandshakeOptionNextProto(value []string) TLSHandshakeOption {
-	return func(config *tls.Config) {
+	return func(config *TLSHandshakeConfig) {
 		config.NextProtos = value
 	}
 }
 
 // TLSHandshakeOptionRootCAs allows to configure custom root CAs.
 func TLSHandshakeOptionRootCAs(value *x509.CertPool) TLSHandshakeOption {
-	return func(config *tls.Config) {
+	return func(config *TLSHandshakeConfig) {
 		config.RootCAs = value
 	}
 }
 
 // TLSHandshakeOptionServerName allows to configure the SNI to use.
 func TLSHandshakeOptionServerName(value string) TLSHandshakeOption {
-	return func(config *tls.Config) {
+	return func(config *TLSHandshakeConfig) {
 		config.ServerName = value
 	}
 }
@@ -67,7 +96,11 @@ func TLSHandshake(rt Runtime, options ...TLSHandshakeOption) Func[*TCPConnection
 		)
 
 		// obtain the handshaker for use
-		handshaker := trace.NewTLSHandshakerStdlib(rt.Logger())
+		handshaker, err := tlsNewHandshaker(trace, rt.Logger(), config.Fingerprint)
+		if err != nil {
+			ol.Stop(err)
+			return nil, err
+		}
 
 		// setup
 		const timeout = 10 * time.Second
@@ -75,7 +108,7 @@ func TLSHandshake(rt Runtime, options ...TLSHandshakeOption) Func[*TCPConnection
 		defer cancel()
 
 		// handshake
-		conn, err := handshaker.Handshake(ctx, input.Conn, config)
+		conn, err := handshaker.Handshake(ctx, input.Conn, config.Config)
 
 		// possibly register established conn for late close
 		rt.MaybeTrackConn(conn)
@@ -104,6 +137,19 @@ func TLSHandshake(rt Runtime, options ...TLSHandshakeOption) Func[*TCPConnection
 	})
 }
 
+// tlsNewHandshaker creates a TLS handshaker using the uTLS fingerprint with the given
+// name or the stdlib, when the name is empty. It fails if the name is unknown.
+func tlsNewHandshaker(trace Trace, logger model.Logger, fingerprint string) (model.TLSHandshaker, error) {
+	if fingerprint == "" {
+		return trace.NewTLSHandshakerStdlib(logger), nil
+	}
+	id, err := netxlite.NewUTLSClientHelloIDFromName(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return trace.NewTLSHandshakerUTLS(logger, id), nil
+}
+
 // tlsNewConfig is an utility function to create a new TLS config.
 //
 // Arguments:
@@ -117,15 +163,18 @@ func TLSHandshake(rt Runtime, options ...TLSHandshakeOption) Func[*TCPConnection
 // - logger is the logger to use;
 //
 // - options contains options to modify the TLS handshake defaults.
-func tlsNewConfig(address string, defaultALPN []string, domain string, logger model.Logger, options ...TLSHandshakeOption) *tls.Config {
+func tlsNewConfig(address string, defaultALPN []string, domain string, logger model.Logger, options ...TLSHandshakeOption) *TLSHandshakeConfig {
 	// See https://github.com/ooni/probe/issues/2413 to understand
 	// why we're using nil to force netxlite to use the cached
 	// default Mozilla cert pool.
-	config := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
-		NextProtos:         append([]string{}, defaultALPN...),
-		InsecureSkipVerify: false,
-		RootCAs:            nil,
-		ServerName:         tlsServerName(address, domain, logger),
+	config := &TLSHandshakeConfig{
+		Config: &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+			NextProtos:         append([]string{}, defaultALPN...),
+			InsecureSkipVerify: false,
+			RootCAs:            nil,
+			ServerName:         tlsServerName(address, domain, logger),
+		},
+		Fingerprint: "",
 	}
 	for _, option := range options {
 		option(config)
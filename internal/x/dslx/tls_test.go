@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"io"
 	"net"
 	"sync/atomic"
@@ -13,6 +14,8 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 func TestTLSNewConfig(t *testing.T) {
@@ -39,12 +42,20 @@ func TestTLSNewConfig(t *testing.T) {
 
 		config := tlsNewConfig(
 			"1.1.1.1:443", []string{"h2", "http/1.1"}, "sni", model.DiscardLogger,
+			TLSHandshakeOptionECHConfigList([]byte{0, 1, 2}),
+			TLSHandshakeOptionFingerprint("chrome"),
 			TLSHandshakeOptionInsecureSkipVerify(true),
 			TLSHandshakeOptionNextProto([]string{"h2"}),
 			TLSHandshakeOptionServerName("example.domain"),
 			TLSHandshakeOptionRootCAs(certpool),
 		)
 
+		if diff := cmp.Diff([]byte{0, 1, 2}, config.EncryptedClientHelloConfigList); diff != "" {
+			t.Fatal(diff)
+		}
+		if config.Fingerprint != "chrome" {
+			t.Fatalf("unexpected %s, expected %s, got %s", "Fingerprint", "chrome", config.Fingerprint)
+		}
 		if !config.InsecureSkipVerify {
 			t.Fatalf("unexpected %s, expected %v, got %v", "InsecureSkipVerify", true, config.InsecureSkipVerify)
 		}
@@ -58,6 +69,22 @@ func TestTLSNewConfig(t *testing.T) {
 			t.Fatalf("unexpected %s, expected %v, got %v", "RootCAs", nil, config.RootCAs)
 		}
 	})
+
+	t.Run("with a custom option", func(t *testing.T) {
+		var customOption TLSHandshakeOption = func(config *TLSHandshakeConfig) {
+			config.MinVersion = tls.VersionTLS13
+			config.Fingerprint = "firefox"
+		}
+
+		config := tlsNewConfig("1.1.1.1:443", []string{"h2", "http/1.1"}, "sni", model.DiscardLogger, customOption)
+
+		if config.MinVersion != tls.VersionTLS13 {
+			t.Fatalf("unexpected %s, expected %v, got %v", "MinVersion", tls.VersionTLS13, config.MinVersion)
+		}
+		if config.Fingerprint != "firefox" {
+			t.Fatalf("unexpected %s, expected %s, got %s", "Fingerprint", "firefox", config.Fingerprint)
+		}
+	})
 }
 
 /*
@@ -68,15 +95,19 @@ Test cases:
   - with success
   - with sni
   - with options
+  - with fingerprint
+
+- With unknown fingerprint
 */
 func TestTLSHandshake(t *testing.T) {
 	t.Run("Apply tlsHandshakeFunc", func(t *testing.T) {
 		wasClosed := false
 
 		type configOptions struct {
-			sni        string
-			address    string
-			nextProtos []string
+			sni         string
+			address     string
+			nextProtos  []string
+			fingerprint string
 		}
 		tcpConn := mocks.Conn{
 			MockClose: func() error {
@@ -143,16 +174,33 @@ func TestTLSHandshake(t *testing.T) {
 				expectErr:  nil,
 				closed:     true,
 			},
+			"with fingerprint": {
+				config:     configOptions{fingerprint: "firefox"},
+				handshaker: goodHandshaker,
+				expectConn: tlsConn,
+				expectErr:  nil,
+				closed:     true,
+			},
 		}
 
 		for name, tt := range tests {
 			t.Run(name, func(t *testing.T) {
 				rt := NewMinimalRuntime(model.DiscardLogger, time.Now(), MinimalRuntimeOptionMeasuringNetwork(&mocks.MeasuringNetwork{
 					MockNewTLSHandshakerStdlib: func(logger model.DebugLogger) model.TLSHandshaker {
+						if tt.config.fingerprint != "" {
+							t.Fatal("expected to use uTLS")
+						}
+						return tt.handshaker
+					},
+					MockNewTLSHandshakerUTLS: func(logger model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker {
+						if netxlite.UTLSFingerprintName(id) != tt.config.fingerprint {
+							t.Fatal("unexpected ClientHelloID", id)
+						}
 						return tt.handshaker
 					},
 				}))
 				tlsHandshake := TLSHandshake(rt,
+					TLSHandshakeOptionFingerprint(tt.config.fingerprint),
 					TLSHandshakeOptionNextProto(tt.config.nextProtos),
 					TLSHandshakeOptionServerName(tt.config.sni),
 				)
@@ -184,6 +232,25 @@ func TestTLSHandshake(t *testing.T) {
 			wasClosed = false
 		}
 	})
+
+	t.Run("With unknown fingerprint", func(t *testing.T) {
+		rt := NewMinimalRuntime(model.DiscardLogger, time.Now())
+		defer rt.Close()
+		tlsHandshake := TLSHandshake(rt, TLSHandshakeOptionFingerprint("netscape"))
+		tcpConn := TCPConnection{
+			Address: "1.2.3.4:567",
+			Conn:    &mocks.Conn{},
+			Network: "tcp",
+			Trace:   rt.NewTrace(1, time.Time{}),
+		}
+		res := tlsHandshake.Apply(context.Background(), NewMaybeWithValue(&tcpConn))
+		if !errors.Is(res.Error, netxlite.ErrUnknownUTLSFingerprint) {
+			t.Fatalf("unexpected error: %v", res.Error)
+		}
+		if res.State != nil {
+			t.Fatal("expected nil state")
+		}
+	})
 }
 
 /*
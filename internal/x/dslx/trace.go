@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 // Trace collects [*Observations] using tracing. Specific implementations
@@ -45,6 +46,10 @@ type Trace interface {
 	// except that it returns a model.TLSHandshaker that uses this trace.
 	NewTLSHandshakerStdlib(dl model.DebugLogger) model.TLSHandshaker
 
+	// NewTLSHandshakerUTLS is equivalent to netxlite.Netx.NewTLSHandshakerUTLS
+	// except that it returns a model.TLSHandshaker that uses this trace.
+	NewTLSHandshakerUTLS(dl model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker
+
 	// NetworkEvents returns all the network events collected so far.
 	NetworkEvents() (out []*model.ArchivalNetworkEvent)
 